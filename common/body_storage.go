@@ -13,6 +13,7 @@ import (
 // BodyStorage 请求体存储接口
 type BodyStorage interface {
 	io.ReadSeeker
+	io.ReaderAt
 	io.Closer
 	// Bytes 获取全部内容
 	Bytes() ([]byte, error)
@@ -53,6 +54,13 @@ func (m *memoryStorage) Read(p []byte) (n int, err error) {
 	return m.reader.Read(p)
 }
 
+func (m *memoryStorage) ReadAt(p []byte, off int64) (n int, err error) {
+	if atomic.LoadInt32(&m.closed) == 1 {
+		return 0, ErrStorageClosed
+	}
+	return m.reader.ReadAt(p, off)
+}
+
 func (m *memoryStorage) Seek(offset int64, whence int) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -175,6 +183,14 @@ func (d *diskStorage) Read(p []byte) (n int, err error) {
 	return d.file.Read(p)
 }
 
+// ReadAt 不移动文件读写位置，可与 Read/Seek 并发使用
+func (d *diskStorage) ReadAt(p []byte, off int64) (n int, err error) {
+	if atomic.LoadInt32(&d.closed) == 1 {
+		return 0, ErrStorageClosed
+	}
+	return d.file.ReadAt(p, off)
+}
+
 func (d *diskStorage) Seek(offset int64, whence int) (int64, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -281,13 +297,10 @@ func CreateBodyStorageFromReader(reader io.Reader, contentLength int64, maxBytes
 	}
 
 	// 使用内存读取
-	data, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	data, err := readAllLimited(reader, maxBytes)
 	if err != nil {
 		return nil, err
 	}
-	if int64(len(data)) > maxBytes {
-		return nil, ErrRequestBodyTooLarge
-	}
 
 	storage, err := CreateBodyStorage(data)
 	if err != nil {
@@ -302,6 +315,44 @@ func CreateBodyStorageFromReader(reader io.Reader, contentLength int64, maxBytes
 	return storage, nil
 }
 
+// CreateUploadBodyStorageFromReader 为 multipart 文件上传创建存储：长度未知或超过磁盘缓存阈值时直接写入磁盘缓存文件
+// （不受磁盘缓存开关限制），避免 GB 级音频等大文件整体驻留内存
+func CreateUploadBodyStorageFromReader(reader io.Reader, contentLength int64, maxBytes int64) (BodyStorage, error) {
+	if contentLength > 0 && contentLength < GetDiskCacheThresholdBytes() {
+		return CreateBodyStorageFromReader(reader, contentLength, maxBytes)
+	}
+	storage, err := newDiskStorageFromReader(reader, maxBytes, GetDiskCachePath())
+	if err != nil {
+		if IsRequestBodyTooLargeError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("disk storage creation failed: %w", err)
+	}
+	IncrementDiskCacheHits()
+	return storage, nil
+}
+
+// CreateMemoryBodyStorageFromReader 从 Reader 创建仅内存的存储，不使用磁盘缓存
+func CreateMemoryBodyStorageFromReader(reader io.Reader, maxBytes int64) (BodyStorage, error) {
+	data, err := readAllLimited(reader, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	IncrementMemoryCacheHits()
+	return newMemoryStorage(data), nil
+}
+
+func readAllLimited(reader io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrRequestBodyTooLarge
+	}
+	return data, nil
+}
+
 // ReaderOnly wraps an io.Reader to hide io.Closer, preventing http.NewRequest
 // from type-asserting io.ReadCloser and closing the underlying BodyStorage.
 func ReaderOnly(r io.Reader) io.Reader {
@@ -125,6 +125,16 @@ var SMTPAccount = ""
 var SMTPFrom = ""
 var SMTPToken = ""
 
+// EmailProvider 邮件发送渠道：smtp、ses、sendgrid、mailgun
+var EmailProvider = EmailProviderSMTP
+var SESRegion = ""
+var SESAccessKeyId = ""
+var SESSecretAccessKey = ""
+var SendGridApiKey = ""
+var MailgunDomain = ""
+var MailgunApiKey = ""
+var MailgunApiBase = ""
+
 var GitHubClientId = ""
 var GitHubClientSecret = ""
 var LinuxDOClientId = ""
@@ -33,10 +33,13 @@ func getSMTPAuth() smtp.Auth {
 	return smtp.PlainAuth("", SMTPAccount, SMTPToken, SMTPServer)
 }
 
-func SendEmail(subject string, receiver string, content string) error {
-	if SMTPFrom == "" { // for compatibility
-		SMTPFrom = SMTPAccount
-	}
+type smtpEmailProvider struct{}
+
+func (p *smtpEmailProvider) Name() string {
+	return EmailProviderSMTP
+}
+
+func (p *smtpEmailProvider) Send(subject string, receivers []string, content string) error {
 	id, err2 := generateMessageID()
 	if err2 != nil {
 		return err2
@@ -44,6 +47,7 @@ func SendEmail(subject string, receiver string, content string) error {
 	if SMTPServer == "" && SMTPAccount == "" {
 		return fmt.Errorf("SMTP 服务器未配置")
 	}
+	receiver := strings.Join(receivers, ";")
 	encodedSubject := fmt.Sprintf("=?UTF-8?B?%s?=", base64.StdEncoding.EncodeToString([]byte(subject)))
 	mail := []byte(fmt.Sprintf("To: %s\r\n"+
 		"From: %s <%s>\r\n"+
@@ -54,7 +58,6 @@ func SendEmail(subject string, receiver string, content string) error {
 		receiver, SystemName, SMTPFrom, encodedSubject, time.Now().Format(time.RFC1123Z), id, content))
 	auth := getSMTPAuth()
 	addr := fmt.Sprintf("%s:%d", SMTPServer, SMTPPort)
-	to := strings.Split(receiver, ";")
 	var err error
 	if SMTPPort == 465 || SMTPSSLEnabled {
 		tlsConfig := &tls.Config{
@@ -76,8 +79,7 @@ func SendEmail(subject string, receiver string, content string) error {
 		if err = client.Mail(SMTPFrom); err != nil {
 			return err
 		}
-		receiverEmails := strings.Split(receiver, ";")
-		for _, receiver := range receiverEmails {
+		for _, receiver := range receivers {
 			if err = client.Rcpt(receiver); err != nil {
 				return err
 			}
@@ -95,10 +97,31 @@ func SendEmail(subject string, receiver string, content string) error {
 			return err
 		}
 	} else {
-		err = smtp.SendMail(addr, auth, SMTPFrom, to, mail)
+		err = smtp.SendMail(addr, auth, SMTPFrom, receivers, mail)
+	}
+	return err
+}
+
+func SendEmail(subject string, receiver string, content string) error {
+	if SMTPFrom == "" { // for compatibility
+		SMTPFrom = SMTPAccount
+	}
+	provider, err := GetEmailProvider(EmailProvider)
+	if err != nil {
+		return err
+	}
+	receivers := make([]string, 0)
+	for _, item := range strings.Split(receiver, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			receivers = append(receivers, item)
+		}
+	}
+	if len(receivers) == 0 {
+		return fmt.Errorf("收件人不能为空")
 	}
+	err = provider.Send(subject, receivers, content)
 	if err != nil {
-		SysError(fmt.Sprintf("failed to send email to %s: %v", receiver, err))
+		SysError(fmt.Sprintf("failed to send email to %s via %s: %v", receiver, provider.Name(), err))
 	}
 	return err
 }
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type mailgunEmailProvider struct{}
+
+func (p *mailgunEmailProvider) Name() string {
+	return EmailProviderMailgun
+}
+
+func (p *mailgunEmailProvider) Send(subject string, receivers []string, content string) error {
+	if MailgunDomain == "" || MailgunApiKey == "" {
+		return fmt.Errorf("Mailgun 未配置")
+	}
+	if SMTPFrom == "" {
+		return fmt.Errorf("发件人邮箱未配置")
+	}
+	apiBase := strings.TrimSuffix(MailgunApiBase, "/")
+	if apiBase == "" {
+		// 欧洲区域需设置为 https://api.eu.mailgun.net
+		apiBase = "https://api.mailgun.net"
+	}
+	form := url.Values{}
+	form.Set("from", formatEmailSender())
+	for _, receiver := range receivers {
+		form.Add("to", receiver)
+	}
+	form.Set("subject", subject)
+	form.Set("html", content)
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", apiBase, url.PathEscape(MailgunDomain))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", MailgunApiKey)
+	return doEmailProviderRequest(req)
+}
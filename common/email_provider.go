@@ -0,0 +1,67 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderSES      = "ses"
+	EmailProviderSendGrid = "sendgrid"
+	EmailProviderMailgun  = "mailgun"
+)
+
+// EmailSender 邮件发送渠道实现，SMTPFrom 作为所有渠道的发件地址
+type EmailSender interface {
+	Name() string
+	Send(subject string, receivers []string, content string) error
+}
+
+var emailProviders = map[string]EmailSender{
+	EmailProviderSMTP:     &smtpEmailProvider{},
+	EmailProviderSES:      &sesEmailProvider{},
+	EmailProviderSendGrid: &sendGridEmailProvider{},
+	EmailProviderMailgun:  &mailgunEmailProvider{},
+}
+
+var emailHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+func GetEmailProvider(name string) (EmailSender, error) {
+	if name == "" {
+		name = EmailProviderSMTP
+	}
+	provider, ok := emailProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的邮件服务商: %s", name)
+	}
+	return provider, nil
+}
+
+func IsValidEmailProvider(name string) bool {
+	_, err := GetEmailProvider(name)
+	return err == nil
+}
+
+func formatEmailSender() string {
+	if SystemName == "" {
+		return SMTPFrom
+	}
+	return fmt.Sprintf("%s <%s>", SystemName, SMTPFrom)
+}
+
+func doEmailProviderRequest(req *http.Request) error {
+	resp, err := emailHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(bytes.TrimSpace(body)))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type capturedEmailRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newEmailProviderServer 记录收到的请求，并以指定状态码与响应体回复
+func newEmailProviderServer(t *testing.T, status int, respBody string) (*httptest.Server, *capturedEmailRequest) {
+	captured := &capturedEmailRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		captured.method = r.Method
+		captured.path = r.URL.Path
+		captured.header = r.Header.Clone()
+		captured.body = body
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(respBody))
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func withEmailSender(t *testing.T) {
+	savedFrom, savedName := SMTPFrom, SystemName
+	SMTPFrom, SystemName = "noreply@example.com", "Gateway"
+	t.Cleanup(func() { SMTPFrom, SystemName = savedFrom, savedName })
+}
+
+func TestSendGridEmailProvider(t *testing.T) {
+	withEmailSender(t)
+	savedUrl, savedKey := sendGridApiUrl, SendGridApiKey
+	t.Cleanup(func() { sendGridApiUrl, SendGridApiKey = savedUrl, savedKey })
+	SendGridApiKey = "sg-key"
+
+	server, captured := newEmailProviderServer(t, http.StatusAccepted, "")
+	sendGridApiUrl = server.URL + "/v3/mail/send"
+	provider := &sendGridEmailProvider{}
+	require.NoError(t, provider.Send("Hello", []string{"a@example.com", "b@example.com"}, "<p>hi</p>"))
+
+	require.Equal(t, http.MethodPost, captured.method)
+	require.Equal(t, "/v3/mail/send", captured.path)
+	require.Equal(t, "Bearer sg-key", captured.header.Get("Authorization"))
+	require.Equal(t, "application/json", captured.header.Get("Content-Type"))
+	require.JSONEq(t, `{
+		"personalizations":[{"to":[{"email":"a@example.com"},{"email":"b@example.com"}]}],
+		"from":{"email":"noreply@example.com","name":"Gateway"},
+		"subject":"Hello",
+		"content":[{"type":"text/html","value":"<p>hi</p>"}]
+	}`, string(captured.body))
+
+	errServer, _ := newEmailProviderServer(t, http.StatusUnauthorized, `{"errors":[{"message":"bad key"}]}`)
+	sendGridApiUrl = errServer.URL
+	err := provider.Send("Hello", []string{"a@example.com"}, "x")
+	require.ErrorContains(t, err, "status code: 401")
+	require.ErrorContains(t, err, "bad key")
+
+	SendGridApiKey = ""
+	require.Error(t, provider.Send("Hello", []string{"a@example.com"}, "x"))
+}
+
+func TestMailgunEmailProvider(t *testing.T) {
+	withEmailSender(t)
+	savedBase, savedDomain, savedKey := MailgunApiBase, MailgunDomain, MailgunApiKey
+	t.Cleanup(func() { MailgunApiBase, MailgunDomain, MailgunApiKey = savedBase, savedDomain, savedKey })
+	MailgunDomain, MailgunApiKey = "mg.example.com", "mg-key"
+
+	server, captured := newEmailProviderServer(t, http.StatusOK, `{"id":"<1@mg>","message":"Queued"}`)
+	MailgunApiBase = server.URL + "/"
+	provider := &mailgunEmailProvider{}
+	require.NoError(t, provider.Send("Hello", []string{"a@example.com", "b@example.com"}, "<p>hi</p>"))
+
+	require.Equal(t, http.MethodPost, captured.method)
+	require.Equal(t, "/v3/mg.example.com/messages", captured.path)
+	user, pass, ok := (&http.Request{Header: captured.header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "api", user)
+	require.Equal(t, "mg-key", pass)
+	form, err := url.ParseQuery(string(captured.body))
+	require.NoError(t, err)
+	require.Equal(t, "Gateway <noreply@example.com>", form.Get("from"))
+	require.Equal(t, []string{"a@example.com", "b@example.com"}, form["to"])
+	require.Equal(t, "Hello", form.Get("subject"))
+	require.Equal(t, "<p>hi</p>", form.Get("html"))
+
+	errServer, _ := newEmailProviderServer(t, http.StatusForbidden, "Forbidden")
+	MailgunApiBase = errServer.URL
+	err = provider.Send("Hello", []string{"a@example.com"}, "x")
+	require.ErrorContains(t, err, "status code: 403")
+	require.ErrorContains(t, err, "Forbidden")
+}
+
+func TestSESEmailProvider(t *testing.T) {
+	withEmailSender(t)
+	savedFormat := sesEndpointFormat
+	savedRegion, savedId, savedSecret := SESRegion, SESAccessKeyId, SESSecretAccessKey
+	t.Cleanup(func() {
+		sesEndpointFormat = savedFormat
+		SESRegion, SESAccessKeyId, SESSecretAccessKey = savedRegion, savedId, savedSecret
+	})
+	SESRegion, SESAccessKeyId, SESSecretAccessKey = "us-east-1", "AKIDEXAMPLE", "secret"
+
+	server, captured := newEmailProviderServer(t, http.StatusOK, `{"MessageId":"1"}`)
+	sesEndpointFormat = server.URL + "/%s"
+	provider := &sesEmailProvider{}
+	require.NoError(t, provider.Send("Hello", []string{"a@example.com"}, "<p>hi</p>"))
+
+	require.Equal(t, http.MethodPost, captured.method)
+	require.Equal(t, "/us-east-1/v2/email/outbound-emails", captured.path)
+	authorization := captured.header.Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+	require.Contains(t, authorization, "/us-east-1/ses/aws4_request")
+	require.NotEmpty(t, captured.header.Get("X-Amz-Date"))
+	require.JSONEq(t, `{
+		"FromEmailAddress":"Gateway <noreply@example.com>",
+		"Destination":{"ToAddresses":["a@example.com"]},
+		"Content":{"Simple":{
+			"Subject":{"Data":"Hello","Charset":"UTF-8"},
+			"Body":{"Html":{"Data":"<p>hi</p>","Charset":"UTF-8"}}
+		}}
+	}`, string(captured.body))
+
+	errServer, _ := newEmailProviderServer(t, http.StatusBadRequest, `{"message":"Email address is not verified."}`)
+	sesEndpointFormat = errServer.URL + "/%s"
+	err := provider.Send("Hello", []string{"a@example.com"}, "x")
+	require.ErrorContains(t, err, "status code: 400")
+	require.ErrorContains(t, err, "not verified")
+
+	SESRegion = ""
+	require.Error(t, provider.Send("Hello", []string{"a@example.com"}, "x"))
+}
+
+func TestGetEmailProvider(t *testing.T) {
+	provider, err := GetEmailProvider("")
+	require.NoError(t, err)
+	require.Equal(t, EmailProviderSMTP, provider.Name())
+	for _, name := range []string{EmailProviderSES, EmailProviderSendGrid, EmailProviderMailgun} {
+		provider, err := GetEmailProvider(name)
+		require.NoError(t, err)
+		require.Equal(t, name, provider.Name())
+	}
+	_, err = GetEmailProvider("postmark")
+	require.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// sendGridApiUrl SendGrid v3 发信接口地址，测试时可替换
+var sendGridApiUrl = "https://api.sendgrid.com/v3/mail/send"
+
+type sendGridEmailProvider struct{}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridMailRequest struct {
+	Personalizations []struct {
+		To []sendGridAddress `json:"to"`
+	} `json:"personalizations"`
+	From    sendGridAddress `json:"from"`
+	Subject string          `json:"subject"`
+	Content []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"content"`
+}
+
+func (p *sendGridEmailProvider) Name() string {
+	return EmailProviderSendGrid
+}
+
+func (p *sendGridEmailProvider) Send(subject string, receivers []string, content string) error {
+	if SendGridApiKey == "" {
+		return fmt.Errorf("SendGrid API Key 未配置")
+	}
+	if SMTPFrom == "" {
+		return fmt.Errorf("发件人邮箱未配置")
+	}
+	var payload sendGridMailRequest
+	to := make([]sendGridAddress, 0, len(receivers))
+	for _, receiver := range receivers {
+		to = append(to, sendGridAddress{Email: receiver})
+	}
+	payload.Personalizations = append(payload.Personalizations, struct {
+		To []sendGridAddress `json:"to"`
+	}{To: to})
+	payload.From = sendGridAddress{Email: SMTPFrom, Name: SystemName}
+	payload.Subject = subject
+	payload.Content = append(payload.Content, struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}{Type: "text/html", Value: content})
+	body, err := Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sendGridApiUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+SendGridApiKey)
+	return doEmailProviderRequest(req)
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// sesEndpointFormat SES v2 接口地址，%s 为区域，测试时可替换
+var sesEndpointFormat = "https://email.%s.amazonaws.com"
+
+// sesEmailProvider 通过 SES v2 SendEmail 接口发送，使用 SigV4 签名
+type sesEmailProvider struct{}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Html sesContent `json:"Html"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+func (p *sesEmailProvider) Name() string {
+	return EmailProviderSES
+}
+
+func (p *sesEmailProvider) Send(subject string, receivers []string, content string) error {
+	if SESRegion == "" || SESAccessKeyId == "" || SESSecretAccessKey == "" {
+		return fmt.Errorf("AWS SES 未配置")
+	}
+	if SMTPFrom == "" {
+		return fmt.Errorf("发件人邮箱未配置")
+	}
+	var payload sesSendEmailRequest
+	payload.FromEmailAddress = formatEmailSender()
+	payload.Destination.ToAddresses = receivers
+	payload.Content.Simple.Subject = sesContent{Data: subject, Charset: "UTF-8"}
+	payload.Content.Simple.Body.Html = sesContent{Data: content, Charset: "UTF-8"}
+	body, err := Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(sesEndpointFormat, SESRegion) + "/v2/email/outbound-emails"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	hash := sha256.Sum256(body)
+	credentials := aws.Credentials{
+		AccessKeyID:     SESAccessKeyId,
+		SecretAccessKey: SESSecretAccessKey,
+	}
+	if err = v4.NewSigner().SignHTTP(context.Background(), credentials, req, hex.EncodeToString(hash[:]), "ses", SESRegion, time.Now()); err != nil {
+		return err
+	}
+	return doEmailProviderRequest(req)
+}
@@ -1,7 +1,7 @@
 package common
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"mime"
@@ -48,7 +48,7 @@ func GetRequestBody(c *gin.Context) (io.Seeker, error) {
 	cached, exists := c.Get(KeyRequestBody)
 	if exists && cached != nil {
 		if b, ok := cached.([]byte); ok {
-			bs, err := CreateBodyStorage(b)
+			bs, err := createBodyStorageFor(c, b)
 			if err != nil {
 				return nil, err
 			}
@@ -57,21 +57,26 @@ func GetRequestBody(c *gin.Context) (io.Seeker, error) {
 		}
 	}
 
-	maxMB := constant.MaxRequestBodyMB
-	if maxMB <= 0 {
-		maxMB = 128 // 默认 128MB
-	}
-	maxBytes := int64(maxMB) << 20
+	maxBytes := RequestBodyMaxBytes(c.Request)
 
 	contentLength := c.Request.ContentLength
 
 	// 使用新的存储系统
-	storage, err := CreateBodyStorageFromReader(c.Request.Body, contentLength, maxBytes)
+	var storage BodyStorage
+	var err error
+	if IsNoStore(c) {
+		// 合规模式下请求体只保存在内存中，不写入磁盘缓存
+		storage, err = CreateMemoryBodyStorageFromReader(c.Request.Body, maxBytes)
+	} else if IsMultipartRequest(c.Request) {
+		storage, err = CreateUploadBodyStorageFromReader(c.Request.Body, contentLength, maxBytes)
+	} else {
+		storage, err = CreateBodyStorageFromReader(c.Request.Body, contentLength, maxBytes)
+	}
 	_ = c.Request.Body.Close()
 
 	if err != nil {
 		if IsRequestBodyTooLargeError(err) {
-			return nil, errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d MB", maxMB))
+			return nil, errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d MB", maxBytes>>20))
 		}
 		return nil, err
 	}
@@ -82,6 +87,40 @@ func GetRequestBody(c *gin.Context) (io.Seeker, error) {
 	return storage, nil
 }
 
+// IsMultipartRequest 是否为 multipart/form-data 请求
+func IsMultipartRequest(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Content-Type"), gin.MIMEMultipartPOSTForm)
+}
+
+// RequestBodyMaxBytes 返回请求体允许的最大字节数，multipart 文件上传使用单独的上限 MaxUploadBodyMB
+func RequestBodyMaxBytes(req *http.Request) int64 {
+	maxMB := constant.MaxRequestBodyMB
+	if maxMB <= 0 {
+		maxMB = 128 // 默认 128MB
+	}
+	if IsMultipartRequest(req) && constant.MaxUploadBodyMB > maxMB {
+		maxMB = constant.MaxUploadBodyMB
+	}
+	return int64(maxMB) << 20
+}
+
+// RequestAsksNoStore 判断客户端是否通过 Cache-Control: no-store 要求不保留请求内容
+func RequestAsksNoStore(req *http.Request) bool {
+	for _, value := range req.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsNoStore 判断当前请求是否处于合规模式（不落盘、不记录请求与响应内容）
+func IsNoStore(c *gin.Context) bool {
+	return GetContextKeyBool(c, constant.ContextKeyNoStore)
+}
+
 // GetBodyStorage 获取请求体存储对象（用于需要多次读取的场景）
 func GetBodyStorage(c *gin.Context) (BodyStorage, error) {
 	seeker, err := GetRequestBody(c)
@@ -95,6 +134,24 @@ func GetBodyStorage(c *gin.Context) (BodyStorage, error) {
 	return bs, nil
 }
 
+// ReplaceBodyStorage 用新的内容替换已缓存的请求体，后续读取将得到替换后的数据
+func ReplaceBodyStorage(c *gin.Context, data []byte) error {
+	bs, err := createBodyStorageFor(c, data)
+	if err != nil {
+		return err
+	}
+	CleanupBodyStorage(c)
+	c.Set(KeyBodyStorage, bs)
+	return nil
+}
+
+func createBodyStorageFor(c *gin.Context, data []byte) (BodyStorage, error) {
+	if IsNoStore(c) {
+		return newMemoryStorage(data), nil
+	}
+	return CreateBodyStorage(data)
+}
+
 // CleanupBodyStorage 清理请求体存储（应在请求结束时调用）
 func CleanupBodyStorage(c *gin.Context) {
 	if storage, exists := c.Get(KeyBodyStorage); exists && storage != nil {
@@ -110,20 +167,24 @@ func UnmarshalBodyReusable(c *gin.Context, v any) error {
 	if err != nil {
 		return err
 	}
-	requestBody, err := storage.Bytes()
-	if err != nil {
-		return err
-	}
 	contentType := c.Request.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "application/json") {
-		err = Unmarshal(requestBody, v)
-	} else if strings.Contains(contentType, gin.MIMEPOSTForm) {
-		err = parseFormData(requestBody, v)
-	} else if strings.Contains(contentType, gin.MIMEMultipartPOSTForm) {
-		err = parseMultipartFormData(c, requestBody, v)
+	if strings.Contains(contentType, gin.MIMEMultipartPOSTForm) {
+		// multipart 请求逐个读取 part，跳过文件内容，避免大文件整体载入内存
+		err = parseMultipartFormData(c, storage, v)
 	} else {
-		// skip for now
-		// TODO: someday non json request have variant model, we will need to implementation this
+		var requestBody []byte
+		requestBody, err = storage.Bytes()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(contentType, "application/json") {
+			err = Unmarshal(requestBody, v)
+		} else if strings.Contains(contentType, gin.MIMEPOSTForm) {
+			err = parseFormData(requestBody, v)
+		} else {
+			// skip for now
+			// TODO: someday non json request have variant model, we will need to implementation this
+		}
 	}
 	if err != nil {
 		return err
@@ -234,31 +295,17 @@ func init() {
 	}
 }
 
+// ParseMultipartFormReusable 解析 multipart 表单，超过内存上限的文件由标准库写入临时文件，
+// 调用方使用完毕后应调用 form.RemoveAll 清理
 func ParseMultipartFormReusable(c *gin.Context) (*multipart.Form, error) {
 	storage, err := GetBodyStorage(c)
 	if err != nil {
 		return nil, err
 	}
-	requestBody, err := storage.Bytes()
-	if err != nil {
-		return nil, err
-	}
-
-	// Use the original Content-Type saved on first call to avoid boundary
-	// mismatch when callers overwrite c.Request.Header after multipart rebuild.
-	var contentType string
-	if saved, ok := c.Get("_original_multipart_ct"); ok {
-		contentType = saved.(string)
-	} else {
-		contentType = c.Request.Header.Get("Content-Type")
-		c.Set("_original_multipart_ct", contentType)
-	}
-	boundary, err := parseBoundary(contentType)
+	reader, err := newMultipartReaderReusable(c, storage)
 	if err != nil {
 		return nil, err
 	}
-
-	reader := multipart.NewReader(bytes.NewReader(requestBody), boundary)
 	form, err := reader.ReadForm(multipartMemoryLimit())
 	if err != nil {
 		return nil, err
@@ -303,30 +350,68 @@ func parseFormData(data []byte, v any) error {
 	return processFormMap(formMap, v)
 }
 
-func parseMultipartFormData(c *gin.Context, data []byte, v any) error {
-	var contentType string
+// originalMultipartContentType 返回首次解析时保存的 Content-Type，避免调用方重建 multipart 并覆盖请求头后边界不一致
+func originalMultipartContentType(c *gin.Context) string {
 	if saved, ok := c.Get("_original_multipart_ct"); ok {
-		contentType = saved.(string)
-	} else {
-		contentType = c.Request.Header.Get("Content-Type")
-		c.Set("_original_multipart_ct", contentType)
+		return saved.(string)
+	}
+	contentType := c.Request.Header.Get("Content-Type")
+	c.Set("_original_multipart_ct", contentType)
+	return contentType
+}
+
+// newMultipartReaderReusable 基于请求体存储创建独立的 multipart 流式读取器，不移动存储的读取位置，也不将请求体整体载入内存
+func newMultipartReaderReusable(c *gin.Context, storage BodyStorage) (*multipart.Reader, error) {
+	boundary, err := parseBoundary(originalMultipartContentType(c))
+	if err != nil {
+		return nil, err
 	}
-	boundary, err := parseBoundary(contentType)
+	return multipart.NewReader(io.NewSectionReader(storage, 0, storage.Size()), boundary), nil
+}
+
+// parseMultipartFormData 仅读取 multipart 中的普通字段，文件 part 直接跳过
+func parseMultipartFormData(c *gin.Context, storage BodyStorage, v any) error {
+	reader, err := newMultipartReaderReusable(c, storage)
 	if err != nil {
 		if errors.Is(err, errBoundaryNotFound) {
+			data, readErr := storage.Bytes()
+			if readErr != nil {
+				return readErr
+			}
 			return Unmarshal(data, v) // Fallback to JSON
 		}
 		return err
 	}
 
-	reader := multipart.NewReader(bytes.NewReader(data), boundary)
-	form, err := reader.ReadForm(multipartMemoryLimit())
-	if err != nil {
-		return err
+	values := make(map[string][]string)
+	remaining := multipartMemoryLimit()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := part.FormName()
+		if name == "" || part.FileName() != "" {
+			_ = part.Close()
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, remaining+1))
+		_ = part.Close()
+		if err != nil {
+			return err
+		}
+		remaining -= int64(len(value))
+		if remaining < 0 {
+			return multipart.ErrMessageTooLarge
+		}
+		values[name] = append(values[name], string(value))
 	}
-	defer form.RemoveAll()
+
 	formMap := make(map[string]any)
-	for key, vals := range form.Value {
+	for key, vals := range values {
 		if len(vals) == 1 {
 			formMap[key] = vals[0]
 		} else {
@@ -337,6 +422,25 @@ func parseMultipartFormData(c *gin.Context, data []byte, v any) error {
 	return processFormMap(formMap, v)
 }
 
+// NewMultipartStream 以流式方式构造 multipart 请求体：write 在独立协程中依次写入各 part，读取端按需拉取，
+// 大文件不会整体驻留内存。读取端关闭或 ctx 结束时写入协程随之退出
+func NewMultipartStream(ctx context.Context, write func(writer *multipart.Writer) error) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	stop := context.AfterFunc(ctx, func() {
+		_ = pr.CloseWithError(ctx.Err())
+	})
+	go func() {
+		defer stop()
+		err := write(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	return pr, writer.FormDataContentType()
+}
+
 var errBoundaryNotFound = errors.New("multipart boundary not found")
 
 // parseBoundary extracts the multipart boundary from the Content-Type header using mime.ParseMediaType
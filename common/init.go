@@ -135,6 +135,8 @@ func initConstantEnv() {
 	constant.StreamScannerMaxBufferMB = GetEnvOrDefault("STREAM_SCANNER_MAX_BUFFER_MB", 128)
 	// MaxRequestBodyMB 请求体最大大小（解压后），用于防止超大请求/zip bomb导致内存暴涨
 	constant.MaxRequestBodyMB = GetEnvOrDefault("MAX_REQUEST_BODY_MB", 128)
+	// MaxUploadBodyMB multipart 文件上传（音频转写、文件等）的请求体最大大小，大文件写入磁盘缓存后流式转发
+	constant.MaxUploadBodyMB = GetEnvOrDefault("MAX_UPLOAD_BODY_MB", 2048)
 	// ForceStreamOption 覆盖请求参数，强制返回usage信息
 	constant.ForceStreamOption = GetEnvOrDefaultBool("FORCE_STREAM_OPTION", true)
 	constant.CountToken = GetEnvOrDefaultBool("CountToken", true)
@@ -148,6 +150,34 @@ func initConstantEnv() {
 	constant.GenerateDefaultToken = GetEnvOrDefaultBool("GENERATE_DEFAULT_TOKEN", false)
 	// 是否启用错误日志
 	constant.ErrorLogEnabled = GetEnvOrDefaultBool("ERROR_LOG_ENABLED", false)
+	// 是否启用 gRPC 中继接口（同端口，需 HTTP/2）
+	constant.GrpcRelayEnabled = GetEnvOrDefaultBool("GRPC_RELAY_ENABLED", false)
+	// 是否启用 WebSocket 流式 chat completions 接口
+	constant.ChatWebSocketEnabled = GetEnvOrDefaultBool("CHAT_WEBSOCKET_ENABLED", false)
+	// 监听地址，逗号分隔，支持 tcp / tcp4 / tcp6 / unix，为空时监听 PORT 端口
+	constant.ListenAddrs = GetEnvOrDefaultString("LISTEN_ADDRS", "")
+	// Unix socket 文件权限（八进制），默认 0660
+	constant.UnixSocketMode = 0o660
+	if mode := GetEnvOrDefaultString("UNIX_SOCKET_MODE", ""); mode != "" {
+		if parsed, err := strconv.ParseUint(mode, 8, 32); err == nil {
+			constant.UnixSocketMode = uint32(parsed)
+		} else {
+			SysError(fmt.Sprintf("invalid UNIX_SOCKET_MODE %q, using default 0660", mode))
+		}
+	}
+	// 下游 TLS：证书文件，或按域名自动签发证书（ACME），配置后 TCP 监听地址改为 HTTPS 并支持 HTTP/2
+	constant.TLSCertFile = GetEnvOrDefaultString("TLS_CERT_FILE", "")
+	constant.TLSKeyFile = GetEnvOrDefaultString("TLS_KEY_FILE", "")
+	constant.TLSAutocertDomains = nil
+	for _, domain := range strings.Split(GetEnvOrDefaultString("TLS_AUTOCERT_DOMAINS", ""), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			constant.TLSAutocertDomains = append(constant.TLSAutocertDomains, domain)
+		}
+	}
+	constant.TLSAutocertCacheDir = GetEnvOrDefaultString("TLS_AUTOCERT_CACHE_DIR", "./data/autocert")
+	constant.TLSAutocertEmail = GetEnvOrDefaultString("TLS_AUTOCERT_EMAIL", "")
+	// 配置 TLS 后在相同端口的 UDP 上同时提供 HTTP/3（QUIC），并通过 Alt-Svc 响应头通告
+	constant.HTTP3Enabled = GetEnvOrDefaultBool("HTTP3_ENABLED", false)
 	// 任务轮询时查询的最大数量
 	constant.TaskQueryLimit = GetEnvOrDefault("TASK_QUERY_LIMIT", 1000)
 	// 异步任务超时时间（分钟），超过此时间未完成的任务将被标记为失败并退款。0 表示禁用。
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newMultipartTestContext(t *testing.T, fileSize int) *gin.Context {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("language", "en"))
+	part, err := writer.CreateFormFile("file", "audio.mp3")
+	require.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte{0x42}, fileSize))
+	require.NoError(t, err)
+	// 模型字段位于文件之后，仍需能读取
+	require.NoError(t, writer.WriteField("model", "whisper-1"))
+	require.NoError(t, writer.Close())
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+func TestUnmarshalBodyReusableMultipartSkipsFiles(t *testing.T) {
+	c := newMultipartTestContext(t, 1<<20)
+
+	var req struct {
+		Model    string `json:"model"`
+		Language string `json:"language"`
+	}
+	require.NoError(t, UnmarshalBodyReusable(c, &req))
+	require.Equal(t, "whisper-1", req.Model)
+	require.Equal(t, "en", req.Language)
+
+	// 表单仍可完整解析，文件内容未被消费
+	form, err := ParseMultipartFormReusable(c)
+	require.NoError(t, err)
+	defer form.RemoveAll()
+	require.Len(t, form.File["file"], 1)
+	require.Equal(t, int64(1<<20), form.File["file"][0].Size)
+}
+
+func TestNewMultipartStream(t *testing.T) {
+	reader, contentType := NewMultipartStream(context.Background(), func(writer *multipart.Writer) error {
+		if err := writer.WriteField("model", "whisper-1"); err != nil {
+			return err
+		}
+		part, err := writer.CreateFormFile("file", "audio.mp3")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, strings.NewReader("audio-bytes"))
+		return err
+	})
+	defer reader.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", reader)
+	req.Header.Set("Content-Type", contentType)
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	require.Equal(t, "whisper-1", req.FormValue("model"))
+	file, _, err := req.FormFile("file")
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, "audio-bytes", string(data))
+}
+
+func TestNewMultipartStreamStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	reader, _ := NewMultipartStream(ctx, func(writer *multipart.Writer) error {
+		part, err := writer.CreateFormFile("file", "audio.mp3")
+		if err != nil {
+			done <- err
+			return err
+		}
+		_, err = part.Write(bytes.Repeat([]byte{0x42}, 1<<20))
+		done <- err
+		return err
+	})
+	defer reader.Close()
+
+	cancel()
+	require.Error(t, <-done)
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestAsksNoStore(t *testing.T) {
+	cases := map[string]bool{
+		"":                         false,
+		"no-cache":                 false,
+		"no-store":                 true,
+		"No-Store":                 true,
+		"private, no-store":        true,
+		"max-age=0,no-store,extra": true,
+	}
+	for value, expected := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if value != "" {
+			req.Header.Set("Cache-Control", value)
+		}
+		require.Equal(t, expected, RequestAsksNoStore(req), value)
+	}
+}
+
+func TestNoStoreBodyStorageStaysInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := []byte(`{"model":"gpt-4o","messages":[]}`)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	SetContextKey(c, constant.ContextKeyNoStore, true)
+
+	storage, err := GetBodyStorage(c)
+	require.NoError(t, err)
+	require.False(t, storage.IsDisk())
+	data, err := storage.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, body, data)
+
+	require.NoError(t, ReplaceBodyStorage(c, []byte(`{}`)))
+	storage, err = GetBodyStorage(c)
+	require.NoError(t, err)
+	require.False(t, storage.IsDisk())
+	CleanupBodyStorage(c)
+}
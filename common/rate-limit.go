@@ -68,3 +68,18 @@ func (l *InMemoryRateLimiter) Request(key string, maxRequestNum int, duration in
 	}
 	return true
 }
+
+// RetryAfter 返回 key 在窗口内再次允许请求前需等待的秒数，未达到限制时返回 0
+func (l *InMemoryRateLimiter) RetryAfter(key string, maxRequestNum int, duration int64) int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	queue, ok := l.store[key]
+	if !ok || len(*queue) < maxRequestNum || len(*queue) == 0 {
+		return 0
+	}
+	wait := (*queue)[0] + duration - time.Now().Unix()
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -9,6 +9,12 @@ const (
 
 	ContextKeyOriginalModel    ContextKey = "original_model"
 	ContextKeyRequestStartTime ContextKey = "request_start_time"
+	// ContextKeyRequestedModelAlias 客户端请求的全局模型别名，ContextKeyOriginalModel 为解析后的模型
+	ContextKeyRequestedModelAlias ContextKey = "requested_model_alias"
+	// ContextKeyExperimentId / ContextKeyExperimentArm 请求命中的 A/B 实验及分组，ContextKeyExperimentParamOverride 为分组的参数覆盖
+	ContextKeyExperimentId            ContextKey = "experiment_id"
+	ContextKeyExperimentArm           ContextKey = "experiment_arm"
+	ContextKeyExperimentParamOverride ContextKey = "experiment_param_override"
 
 	/* token related keys */
 	ContextKeyTokenUnlimited         ContextKey = "token_unlimited_quota"
@@ -63,6 +69,31 @@ const (
 	// It is not returned to end users, but can be persisted into consume/error logs for debugging.
 	ContextKeyAdminRejectReason ContextKey = "admin_reject_reason"
 
+	// ContextKeyModerationFlags stores moderation verdicts with the "flag" or "block" action, persisted into consume and error logs.
+	ContextKeyModerationFlags ContextKey = "moderation_flags"
+	// ContextKeyResponseCache stores the response cache status ("hit", "miss", "semantic_hit" or "semantic_miss") of the request, persisted into consume logs.
+	ContextKeyResponseCache ContextKey = "response_cache"
+	// ContextKeyPendingCachedResponse stores a response cache hit that is replayed only after request-side safety checks pass.
+	ContextKeyPendingCachedResponse ContextKey = "pending_cached_response"
+	// ContextKeyPiiMapping stores placeholder -> original value pairs for reversible PII redaction.
+	ContextKeyPiiMapping ContextKey = "pii_mapping"
+	// ContextKeyBlocklistHits stores names of blocklist rules hit by this request, persisted into consume logs.
+	ContextKeyBlocklistHits ContextKey = "blocklist_hits"
+	// ContextKeyPromptInjection stores the prompt injection risk score of this request, persisted into consume logs.
+	ContextKeyPromptInjection ContextKey = "prompt_injection"
+	// ContextKeyImageSafetyVerdicts stores flagged generated images of this request, persisted into consume logs.
+	ContextKeyImageSafetyVerdicts ContextKey = "image_safety_verdicts"
+	// ContextKeyAbusePatternHits stores abuse patterns flagged in this request, persisted into consume logs.
+	ContextKeyAbusePatternHits ContextKey = "abuse_pattern_hits"
+	// ContextKeySecretLeakTypes stores credential types detected in this request, persisted into consume logs.
+	ContextKeySecretLeakTypes ContextKey = "secret_leak_types"
+	// ContextKeyNoStore marks compliance (no-store) traffic whose request/response bodies must not be persisted.
+	ContextKeyNoStore ContextKey = "no_store"
+	// ContextKeyConsumedQuota accumulates the quota billed by consume logs of this request, stored in idempotency records.
+	ContextKeyConsumedQuota ContextKey = "consumed_quota"
+	// ContextKeyRetryHint stores which limit rejected the request and when to retry, surfaced in error responses.
+	ContextKeyRetryHint ContextKey = "retry_hint"
+
 	// ContextKeyLanguage stores the user's language preference for i18n
 	ContextKeyLanguage ContextKey = "language"
 	ContextKeyIsStream ContextKey = "is_stream"
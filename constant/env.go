@@ -10,11 +10,22 @@ var GetMediaToken bool
 var GetMediaTokenNotStream bool
 var UpdateTask bool
 var MaxRequestBodyMB int
+var MaxUploadBodyMB int
 var AzureDefaultAPIVersion string
 var NotifyLimitCount int
 var NotificationLimitDurationMinute int
 var GenerateDefaultToken bool
 var ErrorLogEnabled bool
+var GrpcRelayEnabled bool
+var ChatWebSocketEnabled bool
+var ListenAddrs string
+var UnixSocketMode uint32
+var TLSCertFile string
+var TLSKeyFile string
+var TLSAutocertDomains []string
+var TLSAutocertCacheDir string
+var TLSAutocertEmail string
+var HTTP3Enabled bool
 var TaskQueryLimit int
 var TaskTimeoutMinutes int
 
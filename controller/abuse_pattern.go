@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxAbusePatternUploadBytes = 10 << 20
+
+// GetAbusePatterns 返回特征库概况与管理员上传的特征
+func GetAbusePatterns(c *gin.Context) {
+	setting := moderation_setting.GetAbusePatternSetting()
+	common.ApiSuccess(c, gin.H{
+		"builtin_count":       len(moderation_setting.GetBuiltinAbusePatterns()),
+		"feed_count":          len(setting.FeedPatterns),
+		"feed_last_sync_time": setting.FeedLastSyncTime,
+		"active_count":        len(moderation_setting.GetActiveAbusePatterns()),
+		"patterns":            setting.Patterns,
+	})
+}
+
+// UploadAbusePatterns 上传特征库，请求体为 JSON 数组或每行一条的纯文本；
+// mode=append 时追加到现有特征，默认整体替换
+func UploadAbusePatterns(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAbusePatternUploadBytes))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	patterns, err := moderation_setting.ParseAbusePatterns(data)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if c.Query("mode") == "append" {
+		patterns = append(append([]moderation_setting.AbusePattern{}, moderation_setting.GetAbusePatternSetting().Patterns...), patterns...)
+	}
+	if err = service.SaveAbusePatterns(patterns); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{"count": len(patterns)})
+}
+
+// SyncAbusePatternFeed 立即从远程特征源同步
+func SyncAbusePatternFeed(c *gin.Context) {
+	count, err := service.SyncAbusePatternFeed(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	common.ApiSuccess(c, gin.H{"count": count})
+}
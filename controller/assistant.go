@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+const (
+	assistantDefaultBeta = "assistants=v2"
+	assistantListMax     = 100
+)
+
+// assistantRoute 从请求路径中解析出的资源信息
+type assistantRoute struct {
+	// Collection 为 assistants 或 threads
+	Collection string
+	// ResourceId 路径中引用的助手或线程 ID，创建与列表请求为空
+	ResourceId string
+	// CreatesRun 该请求会创建新的运行（/threads/runs 或 /threads/{id}/runs）
+	CreatesRun bool
+}
+
+func parseAssistantRoute(method string, path string) assistantRoute {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/v1/"), "/"), "/")
+	route := assistantRoute{Collection: segments[0]}
+	if len(segments) > 1 && !(route.Collection == "threads" && segments[1] == "runs") {
+		route.ResourceId = segments[1]
+	}
+	if method == http.MethodPost && route.Collection == "threads" {
+		route.CreatesRun = (len(segments) == 2 && segments[1] == "runs") ||
+			(len(segments) == 3 && segments[2] == "runs")
+	}
+	return route
+}
+
+func writeAssistantError(c *gin.Context, status int, code string, message string) {
+	service.WriteOpenAIError(c, status, types.OpenAIError{
+		Message: message,
+		Type:    "invalid_request_error",
+		Code:    code,
+	})
+}
+
+// RelayAssistants 透传 Assistants/Threads/Runs 请求。资源在创建时固定到所选渠道，
+// 之后引用该资源的请求都转发到同一渠道，并校验资源属于当前用户
+func RelayAssistants(c *gin.Context) {
+	if !operation_setting.GetAssistantSetting().Enabled {
+		writeAssistantError(c, http.StatusForbidden, "assistants_disabled", "assistants api is not enabled")
+		return
+	}
+	route := parseAssistantRoute(c.Request.Method, c.Request.URL.Path)
+	if route.Collection == "assistants" && route.ResourceId == "" && c.Request.Method == http.MethodGet {
+		listAssistants(c)
+		return
+	}
+	var body []byte
+	if c.Request.Method == http.MethodPost {
+		storage, err := common.GetBodyStorage(c)
+		if err == nil {
+			body, err = storage.Bytes()
+		}
+		if err != nil {
+			writeAssistantError(c, http.StatusBadRequest, "invalid_request", "failed to read request body")
+			return
+		}
+	}
+	channel, ok := resolveAssistantChannel(c, route, body)
+	if !ok {
+		return
+	}
+	if body != nil {
+		// 请求中引用的网关文件需先上传到资源所在渠道
+		var err error
+		body, err = service.ReplaceFileIdsForUpstream(c.Request.Context(), c.GetInt("id"), channel, body)
+		if err != nil {
+			writeAssistantError(c, http.StatusBadGateway, "upstream_file_error", err.Error())
+			return
+		}
+	}
+	binding := &service.AssistantBinding{
+		ChannelId: channel.Id,
+		UserId:    c.GetInt("id"),
+		TokenId:   c.GetInt("token_id"),
+		Group:     common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+	}
+	if route.CreatesRun {
+		preConsumed, err := service.PreConsumeAssistantRun(c)
+		if err != nil {
+			writeAssistantError(c, http.StatusForbidden, "insufficient_user_quota", err.Error())
+			return
+		}
+		binding.PreConsumedQuota = preConsumed
+		// 上游未返回运行时退还预扣额度
+		defer service.RefundUnclaimedAssistantPreConsume(binding)
+	}
+	resp, err := forwardAssistantRequest(c, channel, c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+	if err != nil {
+		writeAssistantError(c, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		streamAssistantResponse(c, binding, resp)
+		return
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeAssistantError(c, http.StatusBadGateway, "upstream_error", "failed to read upstream response")
+		return
+	}
+	respBody = service.RestoreUserFileIds(channel.Id, respBody)
+	if resp.StatusCode < http.StatusMultipleChoices {
+		service.ObserveAssistantResponse(c, binding, respBody)
+	}
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+}
+
+// resolveAssistantChannel 确定请求要转发的渠道：引用已有资源时使用其所在渠道，
+// 创建助手时按模型选择渠道，创建线程时跟随请求中或用户最近的助手
+func resolveAssistantChannel(c *gin.Context, route assistantRoute, body []byte) (*model.Channel, bool) {
+	userId := c.GetInt("id")
+	channelId := 0
+	if route.ResourceId != "" {
+		object := model.AssistantObjectThread
+		if route.Collection == "assistants" {
+			object = model.AssistantObjectAssistant
+		}
+		resource, ok := getOwnedAssistantResource(c, userId, route.ResourceId, object)
+		if !ok {
+			return nil, false
+		}
+		channelId = resource.ChannelId
+	}
+	if assistantId := gjson.GetBytes(body, "assistant_id").String(); assistantId != "" {
+		assistant, ok := getOwnedAssistantResource(c, userId, assistantId, model.AssistantObjectAssistant)
+		if !ok {
+			return nil, false
+		}
+		if channelId != 0 && channelId != assistant.ChannelId {
+			writeAssistantError(c, http.StatusBadRequest, "channel_mismatch",
+				fmt.Sprintf("assistant %s and thread %s were created on different upstream channels", assistantId, route.ResourceId))
+			return nil, false
+		}
+		channelId = assistant.ChannelId
+	}
+	if modelName := gjson.GetBytes(body, "model").String(); modelName != "" && !checkAssistantModelAllowed(c, modelName) {
+		return nil, false
+	}
+	if channelId == 0 {
+		return selectAssistantChannel(c, route, body)
+	}
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil || channel.Status != common.ChannelStatusEnabled {
+		writeAssistantError(c, http.StatusServiceUnavailable, "channel_unavailable",
+			"the upstream channel holding this resource is no longer available")
+		return nil, false
+	}
+	return channel, true
+}
+
+func getOwnedAssistantResource(c *gin.Context, userId int, resourceId string, object string) (*model.AssistantResource, bool) {
+	resource, err := model.GetAssistantResource(resourceId)
+	if err != nil || resource.UserId != userId || resource.Object != object {
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiError(c, err)
+			return nil, false
+		}
+		writeAssistantError(c, http.StatusNotFound, "not_found", fmt.Sprintf("No %s found with id '%s'.", object, resourceId))
+		return nil, false
+	}
+	return resource, true
+}
+
+func checkAssistantModelAllowed(c *gin.Context, modelName string) bool {
+	if !common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		return true
+	}
+	limit, _ := common.GetContextKey(c, constant.ContextKeyTokenModelLimit)
+	tokenModelLimit, _ := limit.(map[string]bool)
+	if !tokenModelLimit[ratio_setting.FormatMatchingModelName(modelName)] {
+		writeAssistantError(c, http.StatusForbidden, "model_not_allowed", fmt.Sprintf("this token is not allowed to use model %s", modelName))
+		return false
+	}
+	return true
+}
+
+// selectAssistantChannel 为新资源选择渠道，只支持 OpenAI 类型的渠道
+func selectAssistantChannel(c *gin.Context, route assistantRoute, body []byte) (*model.Channel, bool) {
+	modelName := gjson.GetBytes(body, "model").String()
+	if route.Collection == "assistants" && modelName == "" {
+		writeAssistantError(c, http.StatusBadRequest, "model_required", "model is required")
+		return nil, false
+	}
+	if route.Collection == "threads" {
+		// 线程需要与之后运行它的助手位于同一渠道，默认跟随用户最近创建的助手
+		assistants, err := model.GetUserAssistantResources(c.GetInt("id"), model.AssistantObjectAssistant, 0, 1)
+		if err == nil && len(assistants) > 0 {
+			if channel, err := model.CacheGetChannel(assistants[0].ChannelId); err == nil && channel.Status == common.ChannelStatusEnabled {
+				return channel, true
+			}
+		}
+		if modelName == "" {
+			modelName = operation_setting.GetAssistantSetting().DefaultModel
+		}
+	}
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	channel, err := model.GetRandomSatisfiedChannel(group, modelName, 0)
+	if err != nil || channel == nil {
+		writeAssistantError(c, http.StatusServiceUnavailable, "model_not_found",
+			fmt.Sprintf("no available channel for model %s under group %s", modelName, group))
+		return nil, false
+	}
+	if channel.Type != constant.ChannelTypeOpenAI {
+		writeAssistantError(c, http.StatusBadRequest, "unsupported_channel",
+			fmt.Sprintf("model %s is not served by an OpenAI channel, assistants api is unavailable", modelName))
+		return nil, false
+	}
+	return channel, true
+}
+
+func forwardAssistantRequest(c *gin.Context, channel *model.Channel, method string, path string, rawQuery string, body []byte) (*http.Response, error) {
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	url := strings.TrimSuffix(channel.GetBaseURL(), "/") + path
+	if rawQuery != "" {
+		url += "?" + rawQuery
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	beta := c.GetHeader("OpenAI-Beta")
+	if beta == "" {
+		beta = assistantDefaultBeta
+	}
+	req.Header.Set("OpenAI-Beta", beta)
+	if accept := c.GetHeader("Accept"); accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	client, err := service.GetHttpClientWithProxy(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// streamAssistantResponse 逐行转发 SSE 事件，同时观察事件中的运行对象以便记录与计费
+func streamAssistantResponse(c *gin.Context, binding *service.AssistantBinding, resp *http.Response) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(resp.StatusCode)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, `"file-`) {
+			line = string(service.RestoreUserFileIds(binding.ChannelId, []byte(line)))
+		}
+		if _, err := io.WriteString(c.Writer, line+"\n"); err != nil {
+			return
+		}
+		if line == "" {
+			c.Writer.Flush()
+			continue
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			data = strings.TrimSpace(data)
+			if data != "" && data != "[DONE]" {
+				service.ObserveAssistantResponse(c, binding, []byte(data))
+			}
+		}
+	}
+	c.Writer.Flush()
+}
+
+// listAssistants 上游账号可能被多个用户共享，列表只返回当前用户通过网关创建的助手
+func listAssistants(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > assistantListMax {
+		limit = 20
+	}
+	resources, err := model.GetUserAssistantResources(c.GetInt("id"), model.AssistantObjectAssistant, 0, limit+1)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	hasMore := len(resources) > limit
+	if hasMore {
+		resources = resources[:limit]
+	}
+	data := make([]json.RawMessage, 0, len(resources))
+	for _, resource := range resources {
+		channel, err := model.CacheGetChannel(resource.ChannelId)
+		if err != nil {
+			continue
+		}
+		resp, err := forwardAssistantRequest(c, channel, http.MethodGet, "/v1/assistants/"+resource.ResourceId, "", nil)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && resp.StatusCode == http.StatusOK {
+			data = append(data, body)
+		}
+	}
+	result := gin.H{"object": "list", "data": data, "has_more": hasMore}
+	if len(resources) > 0 {
+		result["first_id"] = resources[0].ResourceId
+		result["last_id"] = resources[len(resources)-1].ResourceId
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAssistantRoute(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   assistantRoute
+	}{
+		{http.MethodPost, "/v1/assistants", assistantRoute{Collection: "assistants"}},
+		{http.MethodDelete, "/v1/assistants/asst_1", assistantRoute{Collection: "assistants", ResourceId: "asst_1"}},
+		{http.MethodPost, "/v1/threads", assistantRoute{Collection: "threads"}},
+		{http.MethodPost, "/v1/threads/runs", assistantRoute{Collection: "threads", CreatesRun: true}},
+		{http.MethodPost, "/v1/threads/thread_1/runs", assistantRoute{Collection: "threads", ResourceId: "thread_1", CreatesRun: true}},
+		{http.MethodGet, "/v1/threads/thread_1/runs", assistantRoute{Collection: "threads", ResourceId: "thread_1"}},
+		{http.MethodPost, "/v1/threads/thread_1/runs/run_1/cancel", assistantRoute{Collection: "threads", ResourceId: "thread_1"}},
+		{http.MethodPost, "/v1/threads/thread_1/messages", assistantRoute{Collection: "threads", ResourceId: "thread_1"}},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, parseAssistantRoute(tc.method, tc.path), tc.path)
+	}
+}
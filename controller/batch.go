@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const batchOutputPageSize = 500
+
+// batchResponse 批次对象，字段与 OpenAI Batch API 保持一致
+type batchResponse struct {
+	*model.Batch
+	Object           string         `json:"object"`
+	CompletionWindow string         `json:"completion_window"`
+	OutputFileId     string         `json:"output_file_id,omitempty"`
+	ErrorFileId      string         `json:"error_file_id,omitempty"`
+	RequestCounts    map[string]int `json:"request_counts"`
+}
+
+func newBatchResponse(batch *model.Batch) batchResponse {
+	resp := batchResponse{
+		Batch:            batch,
+		Object:           "batch",
+		CompletionWindow: fmt.Sprintf("%dh", (batch.ExpiresAt-batch.CreatedAt)/3600),
+		RequestCounts: map[string]int{
+			"total":     batch.TotalCount,
+			"completed": batch.CompletedCount,
+			"failed":    batch.FailedCount,
+		},
+	}
+	if batch.CompletedCount > 0 {
+		resp.OutputFileId = batchOutputFileId(batch, batchOutputFileSuffix)
+	}
+	if batch.FailedCount > 0 {
+		resp.ErrorFileId = batchOutputFileId(batch, batchErrorFileSuffix)
+	}
+	return resp
+}
+
+func writeBatchError(c *gin.Context, status int, code string, message string) {
+	service.WriteOpenAIError(c, status, types.OpenAIError{
+		Message: message,
+		Type:    "invalid_request_error",
+		Code:    code,
+	})
+}
+
+func checkBatchEnabled(c *gin.Context) bool {
+	if !operation_setting.GetBatchSetting().Enabled {
+		writeBatchError(c, http.StatusForbidden, "batch_disabled", "batch inference is not enabled")
+		return false
+	}
+	return true
+}
+
+// CreateBatch 创建离线批量推理任务，支持两种方式：
+// 与 OpenAI 一致的 JSON（input_file_id、endpoint、completion_window），或直接 multipart 上传（file 与 endpoint）
+func CreateBatch(c *gin.Context) {
+	if !checkBatchEnabled(c) {
+		return
+	}
+	setting := operation_setting.GetBatchSetting()
+	var (
+		endpoint    string
+		inputFileId string
+		input       io.Reader
+	)
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var req struct {
+			InputFileId      string `json:"input_file_id"`
+			Endpoint         string `json:"endpoint"`
+			CompletionWindow string `json:"completion_window"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if req.CompletionWindow != "" && req.CompletionWindow != fmt.Sprintf("%dh", setting.CompletionWindowHours) {
+			writeBatchError(c, http.StatusBadRequest, "invalid_completion_window", fmt.Sprintf("completion_window must be %dh", setting.CompletionWindowHours))
+			return
+		}
+		file, err := model.GetUserFile(c.GetInt("id"), req.InputFileId, false)
+		if err != nil || file.Purpose != batchFilePurpose {
+			writeBatchError(c, http.StatusBadRequest, "invalid_input_file", "input file not found")
+			return
+		}
+		content, err := service.LoadUserFileContent(c.Request.Context(), file)
+		if err != nil {
+			writeBatchError(c, http.StatusInternalServerError, "invalid_input_file", "failed to read input file")
+			return
+		}
+		endpoint = req.Endpoint
+		inputFileId = file.FileId
+		input = bytes.NewReader(content)
+	} else {
+		endpoint = c.PostForm("endpoint")
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			writeBatchError(c, http.StatusBadRequest, "invalid_file", "file is required")
+			return
+		}
+		if !checkBatchFileSize(c, fileHeader.Size) {
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			writeBatchError(c, http.StatusBadRequest, "invalid_file", err.Error())
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+	if !service.BatchEndpoints[endpoint] {
+		writeBatchError(c, http.StatusBadRequest, "invalid_endpoint", fmt.Sprintf("endpoint %q is not supported for batches", endpoint))
+		return
+	}
+	items, err := service.ParseBatchInput(input, endpoint, setting.MaxLines)
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, "invalid_batch_input", err.Error())
+		return
+	}
+
+	now := common.GetTimestamp()
+	batch := &model.Batch{
+		BatchId:     "batch_" + common.GetRandomString(24),
+		UserId:      c.GetInt("id"),
+		TokenId:     c.GetInt("token_id"),
+		ClientIp:    c.ClientIP(),
+		InputFileId: inputFileId,
+		Endpoint:    endpoint,
+		Status:      model.BatchStatusInProgress,
+		TotalCount:  len(items),
+		CreatedAt:   now,
+		ExpiresAt:   now + int64(time.Duration(setting.CompletionWindowHours)*time.Hour/time.Second),
+	}
+	if err := model.CreateBatch(batch, items); err != nil {
+		common.SysError("failed to create batch: " + err.Error())
+		writeBatchError(c, http.StatusInternalServerError, "create_batch_failed", "failed to create batch")
+		return
+	}
+	c.JSON(http.StatusOK, newBatchResponse(batch))
+}
+
+func checkBatchFileSize(c *gin.Context, size int64) bool {
+	setting := operation_setting.GetBatchSetting()
+	maxBytes := int64(setting.MaxInputSizeMB) << 20
+	if maxBytes > 0 && size > maxBytes {
+		writeBatchError(c, http.StatusRequestEntityTooLarge, "file_too_large", fmt.Sprintf("batch input exceeds %d MB", setting.MaxInputSizeMB))
+		return false
+	}
+	return true
+}
+
+// ListBatches 列出当前用户最近的批次
+func ListBatches(c *gin.Context) {
+	if !checkBatchEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	batches, err := model.GetUserBatches(c.GetInt("id"), limit)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, "list_batches_failed", err.Error())
+		return
+	}
+	data := make([]batchResponse, 0, len(batches))
+	for _, batch := range batches {
+		data = append(data, newBatchResponse(batch))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+func getUserBatch(c *gin.Context) (*model.Batch, bool) {
+	batch, err := model.GetUserBatch(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeBatchError(c, http.StatusNotFound, "batch_not_found", "batch not found")
+		} else {
+			writeBatchError(c, http.StatusInternalServerError, "get_batch_failed", err.Error())
+		}
+		return nil, false
+	}
+	return batch, true
+}
+
+// GetBatch 查询批次状态与进度
+func GetBatch(c *gin.Context) {
+	if !checkBatchEnabled(c) {
+		return
+	}
+	batch, ok := getUserBatch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newBatchResponse(batch))
+}
+
+// CancelBatch 取消批次，已完成的请求结果仍可下载
+func CancelBatch(c *gin.Context) {
+	if !checkBatchEnabled(c) {
+		return
+	}
+	batch, ok := getUserBatch(c)
+	if !ok {
+		return
+	}
+	if batch.Status == model.BatchStatusInProgress {
+		if err := model.CancelBatch(batch); err != nil {
+			writeBatchError(c, http.StatusInternalServerError, "cancel_batch_failed", err.Error())
+			return
+		}
+		if batch, ok = getUserBatch(c); !ok {
+			return
+		}
+	}
+	c.JSON(http.StatusOK, newBatchResponse(batch))
+}
+
+// GetBatchOutput 以 JSONL 下载已结束请求的结果，批次执行中也可下载部分结果
+func GetBatchOutput(c *gin.Context) {
+	if !checkBatchEnabled(c) {
+		return
+	}
+	batch, ok := getUserBatch(c)
+	if !ok {
+		return
+	}
+	writeBatchOutput(c, batch, batch.BatchId+"_output.jsonl")
+}
+
+func writeBatchOutput(c *gin.Context, batch *model.Batch, filename string, statuses ...int) {
+	c.Header("Content-Type", "application/jsonl")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+	writer := bufio.NewWriter(c.Writer)
+	afterLine := -1
+	for {
+		items, err := model.GetFinishedBatchItems(batch.Id, afterLine, batchOutputPageSize, statuses...)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to read batch %s output: %s", batch.BatchId, err.Error()))
+			break
+		}
+		for _, item := range items {
+			data, err := common.Marshal(service.NewBatchOutputLine(batch, item))
+			if err != nil {
+				continue
+			}
+			if _, err := writer.Write(append(data, '\n')); err != nil {
+				return
+			}
+			afterLine = item.LineIndex
+		}
+		if len(items) < batchOutputPageSize {
+			break
+		}
+	}
+	_ = writer.Flush()
+}
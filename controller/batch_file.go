@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	batchFilePurpose      = "batch"
+	batchOutputFileSuffix = "-output"
+	batchErrorFileSuffix  = "-errors"
+	fileListDefaultLimit  = 100
+	fileListMaxLimit      = 1000
+)
+
+// fileResponse 文件对象，字段与 OpenAI Files API 保持一致
+type fileResponse struct {
+	*model.UserFile
+	Object string `json:"object"`
+}
+
+// batchOutputFileId 批次结果以虚拟文件的形式提供，文件 ID 由批次 ID 派生
+func batchOutputFileId(batch *model.Batch, suffix string) string {
+	return "file-" + batch.BatchId + suffix
+}
+
+// parseBatchOutputFileId 解析结果文件 ID，返回批次 ID 与后缀
+func parseBatchOutputFileId(fileId string) (string, string, bool) {
+	if !strings.HasPrefix(fileId, "file-batch_") {
+		return "", "", false
+	}
+	for _, suffix := range []string{batchOutputFileSuffix, batchErrorFileSuffix} {
+		if strings.HasSuffix(fileId, suffix) {
+			return strings.TrimSuffix(strings.TrimPrefix(fileId, "file-"), suffix), suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// checkFilesEnabled 开启文件存储后支持全部用途；仅开启批量推理时只能上传 batch 输入文件
+func checkFilesEnabled(c *gin.Context) bool {
+	if !operation_setting.GetFileSetting().Enabled && !operation_setting.GetBatchSetting().Enabled {
+		writeBatchError(c, http.StatusForbidden, "files_disabled", "files api is not enabled")
+		return false
+	}
+	return true
+}
+
+// UploadFile 上传文件（multipart：file 与 purpose），受单文件大小与用户存储配额限制
+func UploadFile(c *gin.Context) {
+	if !checkFilesEnabled(c) {
+		return
+	}
+	purpose := c.PostForm("purpose")
+	if !service.UserFilePurposes[purpose] {
+		writeBatchError(c, http.StatusBadRequest, "invalid_purpose", fmt.Sprintf("purpose %q is not supported", purpose))
+		return
+	}
+	if purpose != batchFilePurpose && !operation_setting.GetFileSetting().Enabled {
+		writeBatchError(c, http.StatusBadRequest, "invalid_purpose", "only purpose \"batch\" is supported")
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, "invalid_file", "file is required")
+		return
+	}
+	if maxMB := operation_setting.GetFileSetting().MaxFileSizeMB; maxMB > 0 && fileHeader.Size > int64(maxMB)<<20 {
+		writeBatchError(c, http.StatusRequestEntityTooLarge, "file_too_large", fmt.Sprintf("file exceeds %d MB", maxMB))
+		return
+	}
+	if purpose == batchFilePurpose && !checkBatchFileSize(c, fileHeader.Size) {
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+	defer file.Close()
+	userFile := &model.UserFile{
+		FileId:   service.NewUserFileId(),
+		UserId:   c.GetInt("id"),
+		Filename: fileHeader.Filename,
+		Purpose:  purpose,
+	}
+	if err := service.SaveUserFile(c.Request.Context(), userFile, file, fileHeader.Size); err != nil {
+		if errors.Is(err, service.ErrUserFileQuotaExceeded) {
+			writeBatchError(c, http.StatusForbidden, "storage_quota_exceeded",
+				fmt.Sprintf("file storage quota of %d MB exceeded", operation_setting.GetFileSetting().UserQuotaMB))
+			return
+		}
+		writeBatchError(c, http.StatusInternalServerError, "upload_file_failed", "failed to save file")
+		return
+	}
+	c.JSON(http.StatusOK, fileResponse{UserFile: userFile, Object: "file"})
+}
+
+// ListFiles 列出当前用户上传的文件，可按 purpose 过滤
+func ListFiles(c *gin.Context) {
+	if !checkFilesEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(fileListDefaultLimit)))
+	if limit <= 0 || limit > fileListMaxLimit {
+		limit = fileListDefaultLimit
+	}
+	files, err := model.GetUserFiles(c.GetInt("id"), c.Query("purpose"), limit)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, "list_files_failed", err.Error())
+		return
+	}
+	data := make([]fileResponse, 0, len(files))
+	for _, file := range files {
+		data = append(data, fileResponse{UserFile: file, Object: "file"})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// getFileOrBatchOutput 获取上传的文件，或由批次派生的结果文件
+func getFileOrBatchOutput(c *gin.Context) (*model.UserFile, *model.Batch, string, bool) {
+	fileId := c.Param("id")
+	userId := c.GetInt("id")
+	if batchId, suffix, ok := parseBatchOutputFileId(fileId); ok {
+		batch, err := model.GetUserBatch(userId, batchId)
+		if err == nil {
+			return &model.UserFile{
+				FileId:    fileId,
+				Filename:  batchId + strings.ReplaceAll(suffix, "-", "_") + ".jsonl",
+				Purpose:   "batch_output",
+				CreatedAt: batch.CreatedAt,
+			}, batch, suffix, true
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			writeBatchError(c, http.StatusInternalServerError, "get_file_failed", err.Error())
+			return nil, nil, "", false
+		}
+	}
+	file, err := model.GetUserFile(userId, fileId, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeBatchError(c, http.StatusNotFound, "file_not_found", "file not found")
+		} else {
+			writeBatchError(c, http.StatusInternalServerError, "get_file_failed", err.Error())
+		}
+		return nil, nil, "", false
+	}
+	return file, nil, "", true
+}
+
+// GetFile 获取文件信息
+func GetFile(c *gin.Context) {
+	if !checkFilesEnabled(c) {
+		return
+	}
+	file, _, _, ok := getFileOrBatchOutput(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, fileResponse{UserFile: file, Object: "file"})
+}
+
+// GetFileContent 下载文件内容：上传的文件原样返回，结果文件分别只包含成功或失败的请求
+func GetFileContent(c *gin.Context) {
+	if !checkFilesEnabled(c) {
+		return
+	}
+	file, batch, suffix, ok := getFileOrBatchOutput(c)
+	if !ok {
+		return
+	}
+	if batch == nil {
+		content, err := service.LoadUserFileContent(c.Request.Context(), file)
+		if err != nil {
+			writeBatchError(c, http.StatusInternalServerError, "get_file_failed", "failed to read file content")
+			return
+		}
+		contentType := "application/octet-stream"
+		if file.Purpose == batchFilePurpose {
+			contentType = "application/jsonl"
+		}
+		c.Data(http.StatusOK, contentType, content)
+		return
+	}
+	status := model.BatchItemStatusSucceeded
+	if suffix == batchErrorFileSuffix {
+		status = model.BatchItemStatusFailed
+	}
+	writeBatchOutput(c, batch, file.Filename, status)
+}
+
+// DeleteFile 删除上传的文件及其在渠道上的副本，已创建的批次不受影响
+func DeleteFile(c *gin.Context) {
+	if !checkFilesEnabled(c) {
+		return
+	}
+	fileId := c.Param("id")
+	file, err := model.GetUserFile(c.GetInt("id"), fileId, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeBatchError(c, http.StatusNotFound, "file_not_found", "file not found")
+		} else {
+			writeBatchError(c, http.StatusInternalServerError, "delete_file_failed", err.Error())
+		}
+		return
+	}
+	if err := service.RemoveUserFile(c.Request.Context(), file); err != nil {
+		writeBatchError(c, http.StatusInternalServerError, "delete_file_failed", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      fileId,
+		"object":  "file",
+		"deleted": true,
+	})
+}
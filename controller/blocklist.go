@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetBlocklistStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetBlocklistStats(),
+	})
+}
+
+// ResetBlocklistStats 清空命中计数，可通过 rule_name 指定单条规则
+func ResetBlocklistStats(c *gin.Context) {
+	service.ResetBlocklistStats(strings.TrimSpace(c.Query("rule_name")))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/model"
 	relaychannel "github.com/QuantumNous/new-api/relay/channel"
 	"github.com/QuantumNous/new-api/relay/channel/gemini"
@@ -66,6 +67,44 @@ func clearChannelInfo(channel *model.Channel) {
 		channel.ChannelInfo.MultiKeyDisabledReason = nil
 		channel.ChannelInfo.MultiKeyDisabledTime = nil
 	}
+	maskChannelTLSClientKey(channel)
+}
+
+// maskChannelTLSClientKey 与渠道密钥一样不向前端返回 TLS 客户端私钥，以占位符代替
+func maskChannelTLSClientKey(channel *model.Channel) {
+	if channel.Setting == nil || *channel.Setting == "" {
+		return
+	}
+	setting := dto.ChannelSettings{}
+	if err := common.UnmarshalJsonStr(*channel.Setting, &setting); err != nil || setting.TLSClientKey == "" {
+		return
+	}
+	setting.TLSClientKey = optionSecretMask
+	channel.SetSetting(setting)
+}
+
+// restoreChannelTLSClientKey 更新渠道时 TLS 客户端私钥仍为占位符的，还原为已保存的私钥
+func restoreChannelTLSClientKey(channel *model.Channel) error {
+	if channel.Setting == nil || *channel.Setting == "" {
+		return nil
+	}
+	setting := dto.ChannelSettings{}
+	if err := common.UnmarshalJsonStr(*channel.Setting, &setting); err != nil || setting.TLSClientKey != optionSecretMask {
+		return nil
+	}
+	originChannel, err := model.GetChannelById(channel.Id, false)
+	if err != nil {
+		return err
+	}
+	setting.TLSClientKey = ""
+	if originChannel.Setting != nil && *originChannel.Setting != "" {
+		originSetting := dto.ChannelSettings{}
+		if err := common.UnmarshalJsonStr(*originChannel.Setting, &originSetting); err == nil {
+			setting.TLSClientKey = originSetting.TLSClientKey
+		}
+	}
+	channel.SetSetting(setting)
+	return nil
 }
 
 func GetAllChannels(c *gin.Context) {
@@ -435,6 +474,22 @@ func validateChannel(channel *model.Channel, isAdd bool) error {
 	if err := channel.ValidateSettings(); err != nil {
 		return fmt.Errorf("渠道额外设置[channel setting] 格式错误：%s", err.Error())
 	}
+	channelSetting := channel.GetSetting()
+	if err := service.ValidateProxyURL(channelSetting.Proxy); err != nil {
+		return fmt.Errorf("渠道代理地址格式错误：%s", err.Error())
+	}
+	if _, err := service.ChannelTLSConfig(channelSetting); err != nil {
+		return fmt.Errorf("渠道 TLS 证书配置错误：%s", err.Error())
+	}
+	if channel.OtherSettings != "" {
+		var otherSettings dto.ChannelOtherSettings
+		if err := common.UnmarshalJsonStr(channel.OtherSettings, &otherSettings); err != nil {
+			return fmt.Errorf("渠道其他设置格式错误：%s", err.Error())
+		}
+		if err := otherSettings.ValidateUpstreamTimeouts(); err != nil {
+			return fmt.Errorf("渠道上游超时配置错误：%s", err.Error())
+		}
+	}
 
 	// 如果是添加操作，检查 channel 和 key 是否为空
 	if isAdd {
@@ -560,73 +615,71 @@ func getVertexArrayKeys(keys string) ([]string, error) {
 	return cleanKeys, nil
 }
 
-func AddChannel(c *gin.Context) {
-	addChannelRequest := AddChannelRequest{}
-	err := c.ShouldBindJSON(&addChannelRequest)
-	if err != nil {
-		common.ApiError(c, err)
-		return
-	}
-
-	// 使用统一的校验函数
-	if err := validateChannel(addChannelRequest.Channel, true); err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
-		return
-	}
-
-	addChannelRequest.Channel.CreatedTime = common.GetTimestamp()
-	keys := make([]string, 0)
+// normalizeAddChannelKeys 按添加模式整理渠道密钥，返回待创建渠道各自的密钥：
+// multi_to_single 合并为一个多 Key 渠道，batch 拆分为多个渠道，Vertex（非 API Key 模式）按 JSON 数组解析
+func normalizeAddChannelKeys(addChannelRequest *AddChannelRequest) ([]string, error) {
+	channel := addChannelRequest.Channel
+	isVertexJsonKey := channel.Type == constant.ChannelTypeVertexAi && channel.GetOtherSettings().VertexKeyType != dto.VertexKeyTypeAPIKey
 	switch addChannelRequest.Mode {
 	case "multi_to_single":
-		addChannelRequest.Channel.ChannelInfo.IsMultiKey = true
-		addChannelRequest.Channel.ChannelInfo.MultiKeyMode = addChannelRequest.MultiKeyMode
-		if addChannelRequest.Channel.Type == constant.ChannelTypeVertexAi && addChannelRequest.Channel.GetOtherSettings().VertexKeyType != dto.VertexKeyTypeAPIKey {
-			array, err := getVertexArrayKeys(addChannelRequest.Channel.Key)
+		channel.ChannelInfo.IsMultiKey = true
+		channel.ChannelInfo.MultiKeyMode = addChannelRequest.MultiKeyMode
+		if isVertexJsonKey {
+			array, err := getVertexArrayKeys(channel.Key)
 			if err != nil {
-				c.JSON(http.StatusOK, gin.H{
-					"success": false,
-					"message": err.Error(),
-				})
-				return
+				return nil, err
 			}
-			addChannelRequest.Channel.ChannelInfo.MultiKeySize = len(array)
-			addChannelRequest.Channel.Key = strings.Join(array, "\n")
+			channel.ChannelInfo.MultiKeySize = len(array)
+			channel.Key = strings.Join(array, "\n")
 		} else {
 			cleanKeys := make([]string, 0)
-			for _, key := range strings.Split(addChannelRequest.Channel.Key, "\n") {
+			for _, key := range strings.Split(channel.Key, "\n") {
 				if key == "" {
 					continue
 				}
 				key = strings.TrimSpace(key)
 				cleanKeys = append(cleanKeys, key)
 			}
-			addChannelRequest.Channel.ChannelInfo.MultiKeySize = len(cleanKeys)
-			addChannelRequest.Channel.Key = strings.Join(cleanKeys, "\n")
+			channel.ChannelInfo.MultiKeySize = len(cleanKeys)
+			channel.Key = strings.Join(cleanKeys, "\n")
 		}
-		keys = []string{addChannelRequest.Channel.Key}
+		return []string{channel.Key}, nil
 	case "batch":
-		if addChannelRequest.Channel.Type == constant.ChannelTypeVertexAi && addChannelRequest.Channel.GetOtherSettings().VertexKeyType != dto.VertexKeyTypeAPIKey {
+		if isVertexJsonKey {
 			// multi json
-			keys, err = getVertexArrayKeys(addChannelRequest.Channel.Key)
-			if err != nil {
-				c.JSON(http.StatusOK, gin.H{
-					"success": false,
-					"message": err.Error(),
-				})
-				return
-			}
-		} else {
-			keys = strings.Split(addChannelRequest.Channel.Key, "\n")
+			return getVertexArrayKeys(channel.Key)
 		}
+		return strings.Split(channel.Key, "\n"), nil
 	case "single":
-		keys = []string{addChannelRequest.Channel.Key}
+		return []string{channel.Key}, nil
 	default:
+		return nil, fmt.Errorf("不支持的添加模式")
+	}
+}
+
+func AddChannel(c *gin.Context) {
+	addChannelRequest := AddChannelRequest{}
+	err := c.ShouldBindJSON(&addChannelRequest)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	// 使用统一的校验函数
+	if err := validateChannel(addChannelRequest.Channel, true); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	addChannelRequest.Channel.CreatedTime = common.GetTimestamp()
+	keys, err := normalizeAddChannelKeys(&addChannelRequest)
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "不支持的添加模式",
+			"message": err.Error(),
 		})
 		return
 	}
@@ -844,6 +897,10 @@ func UpdateChannel(c *gin.Context) {
 		return
 	}
 
+	if err := restoreChannelTLSClientKey(&channel.Channel); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	// 使用统一的校验函数
 	if err := validateChannel(&channel.Channel, false); err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -967,6 +1024,91 @@ func UpdateChannel(c *gin.Context) {
 	return
 }
 
+// UpsertChannel 以渠道名称为标识幂等地创建或更新渠道，便于基础设施即代码工具收敛期望状态。
+// 请求体与添加渠道一致（mode 默认为 single，不支持会产生多个同名渠道的 batch）。
+// 名称不存在时按添加渠道的规则创建；存在唯一同名渠道时以请求内容整体覆盖配置（key 为空则保留原密钥）；存在多个同名渠道时拒绝。
+func UpsertChannel(c *gin.Context) {
+	upsertRequest := AddChannelRequest{}
+	if err := c.ShouldBindJSON(&upsertRequest); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if upsertRequest.Channel == nil {
+		common.ApiErrorMsg(c, "channel cannot be empty")
+		return
+	}
+	if upsertRequest.Mode == "" {
+		upsertRequest.Mode = "single"
+	}
+	if upsertRequest.Mode == "batch" {
+		common.ApiErrorMsg(c, "upsert 不支持 batch 模式")
+		return
+	}
+	channel := upsertRequest.Channel
+	channel.Name = strings.TrimSpace(channel.Name)
+	if channel.Name == "" {
+		common.ApiErrorI18n(c, i18n.MsgNameCannotBeEmpty)
+		return
+	}
+	existing, err := model.GetChannelsByName(channel.Name)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(existing) > 1 {
+		common.ApiErrorI18n(c, i18n.MsgNameAmbiguous, map[string]any{"Count": len(existing), "Name": channel.Name})
+		return
+	}
+	created := len(existing) == 0
+	if !created {
+		channel.Id = existing[0].Id
+		if err := restoreChannelTLSClientKey(channel); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	if err := validateChannel(channel, created); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if !created {
+		// 保留运行时的多 Key 状态，仅按请求切换多 Key 模式
+		channel.ChannelInfo = existing[0].ChannelInfo
+	}
+	if created || channel.Key != "" {
+		keys, err := normalizeAddChannelKeys(&upsertRequest)
+		if err != nil {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+		channel.Key = keys[0]
+	} else if upsertRequest.Mode == "multi_to_single" {
+		channel.ChannelInfo.IsMultiKey = true
+		channel.ChannelInfo.MultiKeyMode = upsertRequest.MultiKeyMode
+	}
+	if created {
+		channel.Id = 0
+		channel.CreatedTime = common.GetTimestamp()
+		err = channel.Insert()
+	} else {
+		channel.Id = existing[0].Id
+		channel.CreatedTime = existing[0].CreatedTime
+		err = channel.Replace()
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.InitChannelCache()
+	service.ResetProxyClientCache()
+	channel.Key = ""
+	clearChannelInfo(channel)
+	common.ApiSuccess(c, gin.H{
+		"created": created,
+		"channel": channel,
+	})
+}
+
 func FetchModels(c *gin.Context) {
 	var req struct {
 		BaseURL string `json:"base_url"`
@@ -1207,7 +1349,7 @@ func CopyChannel(c *gin.Context) {
 // MultiKeyManageRequest represents the request for multi-key management operations
 type MultiKeyManageRequest struct {
 	ChannelId int    `json:"channel_id"`
-	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_key", "delete_disabled_keys", "get_key_status"
+	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_key", "delete_disabled_keys", "get_key_status", "clear_key_cooldowns"
 	KeyIndex  *int   `json:"key_index,omitempty"` // for disable_key, enable_key, and delete_key actions
 	Page      int    `json:"page,omitempty"`      // for get_key_status pagination
 	PageSize  int    `json:"page_size,omitempty"` // for get_key_status pagination
@@ -1225,6 +1367,7 @@ type MultiKeyStatusResponse struct {
 	EnabledCount        int `json:"enabled_count"`
 	ManualDisabledCount int `json:"manual_disabled_count"`
 	AutoDisabledCount   int `json:"auto_disabled_count"`
+	CoolingDownCount    int `json:"cooling_down_count"`
 }
 
 type KeyStatus struct {
@@ -1233,6 +1376,8 @@ type KeyStatus struct {
 	DisabledTime int64  `json:"disabled_time,omitempty"`
 	Reason       string `json:"reason,omitempty"`
 	KeyPreview   string `json:"key_preview"` // first 10 chars of key for identification
+	// 冷却中的 Key 的冷却状态，冷却期间轮询跳过该 Key
+	Cooldown *model.ChannelKeyCooldown `json:"cooldown,omitempty"`
 }
 
 // ManageMultiKeys handles multi-key management operations
@@ -1280,7 +1425,7 @@ func ManageMultiKeys(c *gin.Context) {
 		}
 
 		// Statistics for all keys (unchanged by filtering)
-		var enabledCount, manualDisabledCount, autoDisabledCount int
+		var enabledCount, manualDisabledCount, autoDisabledCount, coolingDownCount int
 
 		// Build all key status data first
 		var allKeyStatusList []KeyStatus
@@ -1320,12 +1465,18 @@ func ManageMultiKeys(c *gin.Context) {
 				keyPreview = key[:10] + "..."
 			}
 
+			cooldown := model.GetChannelKeyCooldown(channel.Id, key)
+			if cooldown != nil && status == 1 {
+				coolingDownCount++
+			}
+
 			allKeyStatusList = append(allKeyStatusList, KeyStatus{
 				Index:        i,
 				Status:       status,
 				DisabledTime: disabledTime,
 				Reason:       reason,
 				KeyPreview:   keyPreview,
+				Cooldown:     cooldown,
 			})
 		}
 
@@ -1376,6 +1527,7 @@ func ManageMultiKeys(c *gin.Context) {
 				EnabledCount:        enabledCount,        // Overall statistics
 				ManualDisabledCount: manualDisabledCount, // Overall statistics
 				AutoDisabledCount:   autoDisabledCount,   // Overall statistics
+				CoolingDownCount:    coolingDownCount,    // Overall statistics
 			},
 		})
 		return
@@ -1465,6 +1617,15 @@ func ManageMultiKeys(c *gin.Context) {
 		})
 		return
 
+	case "clear_key_cooldowns":
+		clearedCount := model.ClearChannelKeyCooldowns(channel.Id)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": fmt.Sprintf("已解除 %d 个密钥的冷却", clearedCount),
+			"data":    clearedCount,
+		})
+		return
+
 	case "enable_all_keys":
 		// 清空所有禁用状态，使所有密钥回到默认启用状态
 		var enabledCount int
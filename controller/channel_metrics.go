@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"net/http"
+
+	channelmetrics "github.com/QuantumNous/new-api/pkg/channel_metrics"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelMetrics 返回各渠道+模型在路由滚动窗口内的延迟分位数与错误率，以及本节点各模型的排队请求数
+func GetChannelMetrics(c *gin.Context) {
+	routingSetting := operation_setting.GetChannelRoutingSetting()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"mode":           routingSetting.Mode,
+			"window_seconds": int(routingSetting.Window().Seconds()),
+			"items":          channelmetrics.Snapshot(routingSetting.Window()),
+			"queue_waiting":  service.GetModelQueueWaiting(),
+		},
+	})
+}
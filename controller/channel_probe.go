@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	channelmetrics "github.com/QuantumNous/new-api/pkg/channel_metrics"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const channelProbeErrorMaxLength = 500
+
+var (
+	channelProbeOnce    sync.Once
+	channelProbeRunning atomic.Bool
+	channelProbeLastRun atomic.Int64
+)
+
+// StartChannelProbeTask 启动渠道定时拨测，仅在主节点运行
+func StartChannelProbeTask() {
+	channelProbeOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				probeSetting := operation_setting.GetChannelProbeSetting()
+				if !probeSetting.Enabled {
+					continue
+				}
+				if time.Since(time.Unix(channelProbeLastRun.Load(), 0)) < probeSetting.Interval() {
+					continue
+				}
+				runChannelProbes(probeSetting)
+			}
+		})
+	})
+}
+
+func runChannelProbes(probeSetting *operation_setting.ChannelProbeSetting) {
+	if !channelProbeRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer channelProbeRunning.Store(false)
+	channelProbeLastRun.Store(time.Now().Unix())
+
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		common.SysError("failed to load channels for probing: " + err.Error())
+		return
+	}
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		probeChannel(channel, probeSetting)
+	}
+	if probeSetting.RetentionDays > 0 {
+		before := time.Now().AddDate(0, 0, -probeSetting.RetentionDays).Unix()
+		if _, err := model.DeleteChannelProbeLogsBefore(before); err != nil {
+			common.SysError("failed to clean channel probe logs: " + err.Error())
+		}
+	}
+}
+
+// channelProbeModels 返回渠道需要拨测的模型，去重并按配置限制数量
+func channelProbeModels(channel *model.Channel, limit int) []string {
+	models := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range channel.GetModels() {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		models = append(models, name)
+		if limit > 0 && len(models) >= limit {
+			break
+		}
+	}
+	return models
+}
+
+func probeChannel(channel *model.Channel, probeSetting *operation_setting.ChannelProbeSetting) {
+	threshold := probeSetting.GetFailureThreshold()
+	probed := 0
+	allSucceeded := true
+	var tripped *types.NewAPIError
+	var trippedModel string
+	var channelKey string
+	for _, modelName := range channelProbeModels(channel, probeSetting.MaxModelsPerChannel) {
+		start := time.Now()
+		result := testChannel(channel, modelName, "", shouldUseStreamForAutomaticChannelTest(channel), probeSetting.Prompt)
+		latency := time.Since(start)
+		if result.newAPIError == nil && result.localErr != nil {
+			// 渠道类型不支持测试等本地错误，与渠道健康无关
+			continue
+		}
+		probed++
+		success := result.newAPIError == nil
+		probeLog := &model.ChannelProbeLog{
+			ChannelId: channel.Id,
+			Model:     modelName,
+			Success:   success,
+			LatencyMs: latency.Milliseconds(),
+		}
+		if !success {
+			allSucceeded = false
+			probeLog.StatusCode = result.newAPIError.StatusCode
+			probeLog.Error = truncateProbeError(result.newAPIError.MaskSensitiveError())
+		}
+		if err := model.RecordChannelProbe(probeLog); err != nil {
+			common.SysError("failed to record channel probe: " + err.Error())
+		}
+		channelmetrics.Record(channel.Id, modelName, latency, success)
+
+		failures, err := model.UpdateChannelProbeFailures(channel.Id, modelName, success)
+		if err != nil {
+			common.SysError("failed to update channel probe state: " + err.Error())
+		} else if !success && failures >= threshold && tripped == nil {
+			tripped = result.newAPIError
+			trippedModel = modelName
+			if result.context != nil {
+				channelKey = common.GetContextKeyString(result.context, constant.ContextKeyChannelKey)
+			}
+		}
+		time.Sleep(common.RequestInterval)
+	}
+
+	if tripped != nil {
+		reason := fmt.Sprintf("模型 %s 连续 %d 次拨测失败：%s", trippedModel, threshold, tripped.MaskSensitiveError())
+		handleChannelProbeFailure(channel, channelKey, reason, probeSetting)
+		return
+	}
+	if probed > 0 && allSucceeded {
+		restored, err := model.RestoreChannelPriority(channel.Id)
+		if err != nil {
+			common.SysError("failed to restore channel priority: " + err.Error())
+		} else if restored {
+			common.SysLog(fmt.Sprintf("渠道「%s」（#%d）拨测恢复，已还原优先级", channel.Name, channel.Id))
+			model.InitChannelCache()
+		}
+	}
+}
+
+func handleChannelProbeFailure(channel *model.Channel, channelKey string, reason string, probeSetting *operation_setting.ChannelProbeSetting) {
+	switch probeSetting.Action {
+	case operation_setting.ChannelProbeActionLowerPriority:
+		penalized, err := model.PenalizeChannelPriority(channel.Id, probeSetting.PriorityPenalty)
+		if err != nil {
+			common.SysError("failed to lower channel priority: " + err.Error())
+			return
+		}
+		if penalized {
+			common.SysLog(fmt.Sprintf("渠道「%s」（#%d）优先级已降低 %d，原因：%s", channel.Name, channel.Id, probeSetting.PriorityPenalty, reason))
+			model.InitChannelCache()
+		}
+	default:
+		service.DisableChannel(*types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, channelKey, channel.GetAutoBan()), reason)
+	}
+}
+
+func truncateProbeError(message string) string {
+	runes := []rune(message)
+	if len(runes) <= channelProbeErrorMaxLength {
+		return message
+	}
+	return string(runes[:channelProbeErrorMaxLength])
+}
+
+// GetChannelProbeLogs 分页查看渠道拨测记录，可按 channel_id 筛选
+func GetChannelProbeLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	logs, total, err := model.GetChannelProbeLogs(channelId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetChannelProbeStates 查看各渠道模型的连续失败次数与降权状态
+func GetChannelProbeStates(c *gin.Context) {
+	states, err := model.GetChannelProbeStates()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, states)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const channelRecoveryTickInterval = 15 * time.Second
+
+// channelRecoveryState 记录自动禁用渠道的重试进度，仅保存在主节点内存中
+type channelRecoveryState struct {
+	attempts int
+	nextAt   time.Time
+}
+
+var channelRecoveryOnce sync.Once
+
+// StartChannelRecoveryTask 启动自动禁用渠道的恢复检测，仅在主节点运行
+func StartChannelRecoveryTask() {
+	channelRecoveryOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			states := make(map[int]*channelRecoveryState)
+			ticker := time.NewTicker(channelRecoveryTickInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				recoverySetting := operation_setting.GetChannelRecoverySetting()
+				if !recoverySetting.Enabled {
+					clear(states)
+					continue
+				}
+				runChannelRecovery(states, recoverySetting, time.Now())
+			}
+		})
+	})
+}
+
+func runChannelRecovery(states map[int]*channelRecoveryState, recoverySetting *operation_setting.ChannelRecoverySetting, now time.Time) {
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		common.SysError("failed to load channels for recovery: " + err.Error())
+		return
+	}
+	disabled := make(map[int]bool)
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusAutoDisabled {
+			continue
+		}
+		disabled[channel.Id] = true
+		state, ok := states[channel.Id]
+		if !ok {
+			// 新发现的禁用渠道等待首个退避间隔后再测试，避免刚禁用就被立即启用
+			states[channel.Id] = &channelRecoveryState{nextAt: now.Add(recoverySetting.BackoffDelay(0))}
+			continue
+		}
+		if now.Before(state.nextAt) {
+			continue
+		}
+		if recoverChannel(channel, state.attempts+1) {
+			delete(states, channel.Id)
+			continue
+		}
+		state.attempts++
+		state.nextAt = time.Now().Add(recoverySetting.BackoffDelay(state.attempts))
+		time.Sleep(common.RequestInterval)
+	}
+	// 已被启用、手动禁用或删除的渠道不再跟踪
+	for channelId := range states {
+		if !disabled[channelId] {
+			delete(states, channelId)
+		}
+	}
+}
+
+// recoverChannel 重新测试自动禁用的渠道，通过后启用并通知
+func recoverChannel(channel *model.Channel, attempt int) bool {
+	result := testChannel(channel, "", "", shouldUseStreamForAutomaticChannelTest(channel), "")
+	if result.newAPIError != nil || result.localErr != nil {
+		common.SysLog(fmt.Sprintf("渠道「%s」（#%d）第 %d 次恢复检测未通过", channel.Name, channel.Id, attempt))
+		return false
+	}
+	common.SysLog(fmt.Sprintf("渠道「%s」（#%d）第 %d 次恢复检测通过，重新启用", channel.Name, channel.Id, attempt))
+	service.EnableChannel(channel.Id, common.GetContextKeyString(result.context, constant.ContextKeyChannelKey), channel.Name)
+	return true
+}
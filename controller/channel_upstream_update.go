@@ -632,9 +632,8 @@ func runChannelUpstreamModelUpdateTaskOnce() {
 			))
 			return
 		}
-		service.NotifyUpstreamModelUpdateWatchers(
-			"上游模型巡检通知",
-			buildUpstreamModelUpdateTaskNotificationContent(
+		service.NotifyUpstreamModelUpdateWatchers(map[string]any{
+			"Summary": buildUpstreamModelUpdateTaskNotificationContent(
 				checkedChannels,
 				changedChannels,
 				detectedAddModels,
@@ -645,7 +644,7 @@ func runChannelUpstreamModelUpdateTaskOnce() {
 				addModelSamples,
 				removeModelSamples,
 			),
-		)
+		})
 	}
 }
 
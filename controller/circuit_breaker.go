@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	circuitbreaker "github.com/QuantumNous/new-api/pkg/circuit_breaker"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCircuitBreakers 返回各渠道+模型的熔断器状态
+func GetCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"enabled": operation_setting.GetCircuitBreakerSetting().Enabled,
+			"items":   circuitbreaker.Snapshot(),
+		},
+	})
+}
+
+type resetCircuitBreakerRequest struct {
+	ChannelId int    `json:"channel_id"`
+	Model     string `json:"model"`
+}
+
+// ResetCircuitBreaker 手动关闭熔断器，model 为空时关闭渠道所有模型的熔断器
+func ResetCircuitBreaker(c *gin.Context) {
+	var req resetCircuitBreakerRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.ChannelId <= 0 {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	circuitbreaker.Reset(req.ChannelId, req.Model)
+	common.ApiSuccess(c, nil)
+}
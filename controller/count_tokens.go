@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CountClaudeTokens 兼容 Anthropic 的 /v1/messages/count_tokens，在本地估算输入 token 数，不请求上游也不计费
+func CountClaudeTokens(c *gin.Context) {
+	var request dto.ClaudeRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"type": "error",
+			"error": types.ClaudeError{
+				Type:    "invalid_request_error",
+				Message: "invalid request body: " + err.Error(),
+			},
+		})
+		return
+	}
+	if request.Model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"type": "error",
+			"error": types.ClaudeError{
+				Type:    "invalid_request_error",
+				Message: "model: field required",
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"input_tokens": service.CountClaudeRequestTokens(&request),
+	})
+}
+
+// CountGeminiTokens 兼容 Gemini 原生的 models/{model}:countTokens，在本地估算输入 token 数，不请求上游也不计费。
+// 请求可直接传 contents，也可包裹在 generateContentRequest 中
+func CountGeminiTokens(c *gin.Context) {
+	var request dto.GeminiChatRequest
+	err := common.UnmarshalBodyReusable(c, &request)
+	if err == nil && len(request.Contents) == 0 {
+		var wrapped struct {
+			GenerateContentRequest *dto.GeminiChatRequest `json:"generateContentRequest"`
+		}
+		if err = common.UnmarshalBodyReusable(c, &wrapped); err == nil && wrapped.GenerateContentRequest != nil {
+			request = *wrapped.GenerateContentRequest
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "invalid request body: " + err.Error(),
+				"status":  "INVALID_ARGUMENT",
+			},
+		})
+		return
+	}
+	modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+	c.JSON(http.StatusOK, gin.H{
+		"totalTokens": service.CountGeminiRequestTokens(&request, modelName),
+	})
+}
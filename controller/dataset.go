@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const datasetExportPageSize = 500
+
+func parseDatasetFilter(c *gin.Context) *model.DatasetSampleFilter {
+	filter := &model.DatasetSampleFilter{
+		ModelName: c.Query("model_name"),
+		Tag:       c.Query("tag"),
+	}
+	filter.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	filter.Status, _ = strconv.Atoi(c.Query("status"))
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if v, err := strconv.Atoi(c.Query("min_rating")); err == nil {
+		filter.MinRating = &v
+	}
+	if v, err := strconv.Atoi(c.Query("max_rating")); err == nil {
+		filter.MaxRating = &v
+	}
+	return filter
+}
+
+// GetDatasetSamples 按模型、用户、评分、标签、状态与时间筛选采集到的对话
+func GetDatasetSamples(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	samples, total, err := model.SearchDatasetSamples(parseDatasetFilter(c), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(samples)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetDatasetSample 获取单条对话的完整内容
+func GetDatasetSample(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	sample, err := model.GetDatasetSampleById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, sample)
+}
+
+// UpdateDatasetSample 审阅对话：修改标签、状态、备注，或提交清洗后的消息
+func UpdateDatasetSample(c *gin.Context) {
+	var req model.DatasetSample
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	sample, err := model.GetDatasetSampleById(req.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(req.Messages) > 0 {
+		cleaned := &model.DatasetSample{Messages: req.Messages}
+		if _, err := service.FormatDatasetSample(cleaned, service.DatasetFormatOpenAI); err != nil {
+			common.ApiErrorMsg(c, "消息格式无效，应为 [{\"role\": \"...\", \"content\": \"...\"}]")
+			return
+		}
+		sample.Messages = req.Messages
+	}
+	if req.Status != 0 {
+		if !isValidDatasetSampleStatus(req.Status) {
+			common.ApiErrorMsg(c, "无效的状态")
+			return
+		}
+		sample.Status = req.Status
+	}
+	if req.TagList != nil {
+		sample.TagList = req.TagList
+	}
+	sample.Note = req.Note
+	if err := sample.UpdateReview(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, sample)
+}
+
+func isValidDatasetSampleStatus(status int) bool {
+	return status == model.DatasetSampleStatusPending ||
+		status == model.DatasetSampleStatusApproved ||
+		status == model.DatasetSampleStatusRejected
+}
+
+type datasetBatchRequest struct {
+	Ids    []int `json:"ids"`
+	Status int   `json:"status"`
+}
+
+// BatchUpdateDatasetSamples 批量设置审阅状态
+func BatchUpdateDatasetSamples(c *gin.Context) {
+	var req datasetBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Ids) == 0 {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if !isValidDatasetSampleStatus(req.Status) {
+		common.ApiErrorMsg(c, "无效的状态")
+		return
+	}
+	if err := model.UpdateDatasetSamplesStatus(req.Ids, req.Status); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, len(req.Ids))
+}
+
+// DeleteDatasetSamples 批量删除对话
+func DeleteDatasetSamples(c *gin.Context) {
+	var req datasetBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Ids) == 0 {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if err := model.DeleteDatasetSamples(req.Ids); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, len(req.Ids))
+}
+
+// ExportDatasetSamples 按筛选条件导出 JSONL，format 可选 openai（默认）或 sharegpt，
+// 未指定 status 时只导出已通过审阅的对话
+func ExportDatasetSamples(c *gin.Context) {
+	format := c.DefaultQuery("format", service.DatasetFormatOpenAI)
+	if format != service.DatasetFormatOpenAI && format != service.DatasetFormatShareGPT {
+		common.ApiErrorMsg(c, "不支持的导出格式")
+		return
+	}
+	filter := parseDatasetFilter(c)
+	if filter.Status == 0 {
+		filter.Status = model.DatasetSampleStatusApproved
+	}
+	c.Header("Content-Type", "application/jsonl")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dataset_%s_%s.jsonl", format, time.Now().Format("20060102150405")))
+	c.Status(http.StatusOK)
+	writer := bufio.NewWriter(c.Writer)
+	afterId := 0
+	for {
+		samples, err := model.GetDatasetSamplesAfter(filter, afterId, datasetExportPageSize)
+		if err != nil {
+			common.SysError("failed to export dataset: " + err.Error())
+			break
+		}
+		for _, sample := range samples {
+			afterId = sample.Id
+			line, err := service.FormatDatasetSample(sample, format)
+			if err != nil {
+				continue
+			}
+			if _, err := writer.Write(append(line, '\n')); err != nil {
+				return
+			}
+		}
+		if len(samples) < datasetExportPageSize {
+			break
+		}
+	}
+	_ = writer.Flush()
+}
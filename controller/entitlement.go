@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEntitlements 返回各可选模块的授权状态
+func GetEntitlements(c *gin.Context) {
+	common.ApiSuccess(c, entitlement.Status())
+}
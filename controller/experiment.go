@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetExperiments 获取全部 A/B 实验
+func GetExperiments(c *gin.Context) {
+	experiments, err := model.GetAllExperiments()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, experiments)
+}
+
+// CreateExperiment 创建 A/B 实验
+func CreateExperiment(c *gin.Context) {
+	var experiment model.Experiment
+	if err := c.ShouldBindJSON(&experiment); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	experiment.Id = 0
+	if experiment.Status == 0 {
+		experiment.Status = model.ExperimentStatusEnabled
+	}
+	if !validateExperiment(c, &experiment) {
+		return
+	}
+	if err := experiment.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	reloadExperiments()
+	common.ApiSuccess(c, &experiment)
+}
+
+// UpdateExperiment 更新 A/B 实验
+func UpdateExperiment(c *gin.Context) {
+	var experiment model.Experiment
+	if err := c.ShouldBindJSON(&experiment); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if experiment.Id == 0 {
+		common.ApiErrorMsg(c, "缺少实验 ID")
+		return
+	}
+	if _, err := model.GetExperimentById(experiment.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !validateExperiment(c, &experiment) {
+		return
+	}
+	if err := experiment.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	reloadExperiments()
+	common.ApiSuccess(c, &experiment)
+}
+
+// DeleteExperiment 删除 A/B 实验及其统计数据
+func DeleteExperiment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteExperimentById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	reloadExperiments()
+	common.ApiSuccess(c, nil)
+}
+
+// GetExperimentReport 获取实验各分组的对比指标，可通过 start_timestamp / end_timestamp 限定时间范围
+func GetExperimentReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	experiment, err := model.GetExperimentById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	arms, err := service.GetExperimentReport(experiment, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"experiment": experiment,
+		"arms":       arms,
+	})
+}
+
+func validateExperiment(c *gin.Context, experiment *model.Experiment) bool {
+	if err := experiment.Validate(); err != nil {
+		common.ApiError(c, err)
+		return false
+	}
+	if experiment.Status != model.ExperimentStatusEnabled {
+		return true
+	}
+	conflict, err := model.HasEnabledExperimentConflict(experiment.Id, experiment.TokenId, experiment.ModelName)
+	if err != nil {
+		common.ApiError(c, err)
+		return false
+	}
+	if conflict {
+		common.ApiErrorMsg(c, "该令牌与模型已存在启用中的实验")
+		return false
+	}
+	return true
+}
+
+func reloadExperiments() {
+	if err := service.ReloadExperiments(); err != nil {
+		common.SysError("failed to reload experiments: " + err.Error())
+	}
+}
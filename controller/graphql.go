@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/graphql"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	adminGraphQLSchema     *graphql.Schema
+	adminGraphQLSchemaOnce sync.Once
+)
+
+// graphqlPage 分页查询结果，对应 XxxPage 类型
+type graphqlPage struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+func newGraphQLPageObject(name string, item *graphql.Object) *graphql.Object {
+	return graphql.NewObject(name).
+		AddField("items", &graphql.Field{Type: item, MinRole: common.RoleAdminUser, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			return source.(*graphqlPage).Items, nil
+		}}).
+		AddStructFields(graphqlPage{}, common.RoleAdminUser, nil, "items")
+}
+
+// graphqlPageInfo 从 page / page_size 参数构造分页信息，规则与 REST 接口一致
+func graphqlPageInfo(args map[string]any) *common.PageInfo {
+	pageInfo := &common.PageInfo{
+		Page:     graphql.ArgInt(args, "page", 1),
+		PageSize: graphql.ArgInt(args, "page_size", common.ItemsPerPage),
+	}
+	if pageInfo.Page < 1 {
+		pageInfo.Page = 1
+	}
+	if pageInfo.PageSize <= 0 {
+		pageInfo.PageSize = common.ItemsPerPage
+	}
+	if pageInfo.PageSize > 100 {
+		pageInfo.PageSize = 100
+	}
+	return pageInfo
+}
+
+func resolveUserTokens(userId int, args map[string]any) (any, error) {
+	pageInfo := graphqlPageInfo(args)
+	return model.GetAllUserTokens(userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+}
+
+// buildAdminGraphQLSchema 定义管理端数据模型，敏感字段仅超级管理员可见
+func buildAdminGraphQLSchema() *graphql.Schema {
+	admin, root := common.RoleAdminUser, common.RoleRootUser
+
+	token := graphql.NewObject("Token").
+		AddStructFields(model.Token{}, admin, nil, "key").
+		AddField("key", &graphql.Field{MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			return source.(*model.Token).GetMaskedKey(), nil
+		}})
+
+	user := graphql.NewObject("User").
+		AddStructFields(model.User{}, admin, map[string]int{
+			"setting":         root,
+			"stripe_customer": root,
+			"github_id":       root,
+			"discord_id":      root,
+			"oidc_id":         root,
+			"wechat_id":       root,
+			"telegram_id":     root,
+			"linux_do_id":     root,
+		}, "password", "original_password", "verification_code", "access_token").
+		AddField("tokens", &graphql.Field{Type: token, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			return resolveUserTokens(source.(*model.User).Id, args)
+		}})
+
+	channel := graphql.NewObject("Channel").
+		AddStructFields(model.Channel{}, admin, map[string]int{
+			"openai_organization": root,
+			"param_override":      root,
+			"header_override":     root,
+			"setting":             root,
+			"settings":            root,
+		}, "key")
+
+	log := graphql.NewObject("Log").AddStructFields(model.Log{}, admin, nil)
+	quotaData := graphql.NewObject("QuotaData").AddStructFields(model.QuotaData{}, admin, nil)
+	stat := graphql.NewObject("Stat").AddStructFields(model.Stat{}, admin, nil)
+
+	query := graphql.NewObject("Query").
+		AddField("users", &graphql.Field{Type: newGraphQLPageObject("UserPage", user), MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			pageInfo := graphqlPageInfo(args)
+			keyword, group := graphql.ArgString(args, "keyword"), graphql.ArgString(args, "group")
+			var users []*model.User
+			var total int64
+			var err error
+			if keyword != "" || group != "" {
+				users, total, err = model.SearchUsers(keyword, group, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+			} else {
+				users, total, err = model.GetAllUsers(pageInfo)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &graphqlPage{Items: users, Total: total}, nil
+		}}).
+		AddField("user", &graphql.Field{Type: user, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			id, err := graphql.RequireArgInt(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			return model.GetUserById(id, false)
+		}}).
+		AddField("tokens", &graphql.Field{Type: token, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			userId, err := graphql.RequireArgInt(args, "user_id")
+			if err != nil {
+				return nil, err
+			}
+			return resolveUserTokens(userId, args)
+		}}).
+		AddField("channels", &graphql.Field{Type: channel, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			pageInfo := graphqlPageInfo(args)
+			return model.GetAllChannels(pageInfo.GetStartIdx(), pageInfo.GetPageSize(), false, false)
+		}}).
+		AddField("channel", &graphql.Field{Type: channel, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			id, err := graphql.RequireArgInt(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			return model.GetChannelById(id, false)
+		}}).
+		AddField("logs", &graphql.Field{Type: newGraphQLPageObject("LogPage", log), MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			pageInfo := graphqlPageInfo(args)
+			logs, total, err := model.GetAllLogs(graphql.ArgInt(args, "type", 0),
+				int64(graphql.ArgInt(args, "start_timestamp", 0)), int64(graphql.ArgInt(args, "end_timestamp", 0)),
+				graphql.ArgString(args, "model_name"), graphql.ArgString(args, "username"), graphql.ArgString(args, "token_name"),
+				pageInfo.GetStartIdx(), pageInfo.GetPageSize(), graphql.ArgInt(args, "channel", 0), graphql.ArgString(args, "group"),
+				graphql.ArgString(args, "request_id"), graphql.ArgString(args, "upstream_request_id"))
+			if err != nil {
+				return nil, err
+			}
+			return &graphqlPage{Items: logs, Total: total}, nil
+		}}).
+		AddField("usage", &graphql.Field{Type: quotaData, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			return model.GetAllQuotaDates(int64(graphql.ArgInt(args, "start_timestamp", 0)), int64(graphql.ArgInt(args, "end_timestamp", 0)),
+				graphql.ArgString(args, "username"))
+		}}).
+		AddField("usage_stat", &graphql.Field{Type: stat, MinRole: admin, Resolve: func(ctx *graphql.Context, source any, args map[string]any) (any, error) {
+			stat, err := model.SumUsedQuota(graphql.ArgInt(args, "type", 0),
+				int64(graphql.ArgInt(args, "start_timestamp", 0)), int64(graphql.ArgInt(args, "end_timestamp", 0)),
+				graphql.ArgString(args, "model_name"), graphql.ArgString(args, "username"), graphql.ArgString(args, "token_name"),
+				graphql.ArgInt(args, "channel", 0), graphql.ArgString(args, "group"))
+			if err != nil {
+				return nil, err
+			}
+			return &stat, nil
+		}})
+
+	return &graphql.Schema{Query: query}
+}
+
+func getAdminGraphQLSchema() *graphql.Schema {
+	adminGraphQLSchemaOnce.Do(func() {
+		adminGraphQLSchema = buildAdminGraphQLSchema()
+	})
+	return adminGraphQLSchema
+}
+
+// AdminGraphQL 以 GraphQL 查询管理端数据（用户、令牌、渠道、日志与用量汇总），字段按请求者角色授权
+func AdminGraphQL(c *gin.Context) {
+	var req graphql.Request
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []*graphql.Error{{Message: "invalid request body: " + err.Error()}}})
+		return
+	}
+	data, errs := getAdminGraphQLSchema().Execute(req, c.GetInt("role"), c)
+	resp := gin.H{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthStatusOk       = "ok"
+	healthStatusError    = "error"
+	healthStatusDisabled = "disabled"
+	healthCheckTimeout   = 3 * time.Second
+)
+
+type dependencyHealth struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// checkDependency 执行单项依赖检查，失败原因只写入系统日志，避免在未鉴权的探针接口中暴露内部信息
+func checkDependency(ctx context.Context, name string, check func(context.Context) error) dependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	result := dependencyHealth{
+		Status:    healthStatusOk,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = healthStatusError
+		common.SysError("health check " + name + " failed: " + err.Error())
+	}
+	return result
+}
+
+func checkRedis(ctx context.Context) error {
+	return common.RDB.Ping(ctx).Err()
+}
+
+func respondHealth(c *gin.Context, checks map[string]dependencyHealth) {
+	status := healthStatusOk
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status == healthStatusError {
+			status = healthStatusError
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+	c.JSON(code, gin.H{
+		"status":  status,
+		"version": common.Version,
+		"checks":  checks,
+	})
+}
+
+func coreDependencyChecks(c *gin.Context) map[string]dependencyHealth {
+	ctx := c.Request.Context()
+	checks := map[string]dependencyHealth{
+		"database": checkDependency(ctx, "database", model.CheckDatabase),
+		"redis":    {Status: healthStatusDisabled},
+	}
+	if common.RedisEnabled {
+		checks["redis"] = checkDependency(ctx, "redis", checkRedis)
+	}
+	return checks
+}
+
+// Healthz 存活探针：检查数据库与 Redis 连接
+func Healthz(c *gin.Context) {
+	respondHealth(c, coreDependencyChecks(c))
+}
+
+// Readyz 就绪探针：在存活检查基础上确认请求日志可写，任一依赖异常时返回 503
+func Readyz(c *gin.Context) {
+	checks := coreDependencyChecks(c)
+	checks["log_db"] = checkDependency(c.Request.Context(), "log_db", model.CheckLogWritable)
+	respondHealth(c, checks)
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserInvoice 生成当前用户的月度账单，month 为 YYYY-MM（默认当月），format 可选 json（默认）、csv、pdf
+func GetUserInvoice(c *gin.Context) {
+	period, err := service.ParseInvoicePeriod(c.Query("month"))
+	if err != nil {
+		common.ApiErrorMsg(c, "账单月份格式应为 YYYY-MM")
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		common.ApiErrorMsg(c, "不支持的导出格式")
+		return
+	}
+	invoice, err := service.BuildMonthlyInvoice(c.GetInt("id"), c.GetString("username"), period)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("invoice_%s.%s", invoice.Period, format)
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Status(http.StatusOK)
+		if err := service.WriteInvoiceCSV(c.Writer, invoice); err != nil {
+			common.SysError("failed to write invoice csv: " + err.Error())
+		}
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, "application/pdf", service.RenderInvoicePDF(invoice))
+	default:
+		common.ApiSuccess(c, invoice)
+	}
+}
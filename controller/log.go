@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
@@ -10,6 +13,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	exportLogsMaxRows   = 100000
+	exportLogsBatchSize = 1000
+)
+
 func GetAllLogs(c *gin.Context) {
 	pageInfo := common.GetPageQuery(c)
 	logType, _ := strconv.Atoi(c.Query("type"))
@@ -171,3 +179,76 @@ func DeleteHistoryLogs(c *gin.Context) {
 	})
 	return
 }
+
+// ExportLogs 以 CSV 格式流式导出审计日志，筛选参数与 GetAllLogs 一致，单次最多导出 exportLogsMaxRows 条
+func ExportLogs(c *gin.Context) {
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	username := c.Query("username")
+	tokenName := c.Query("token_name")
+	modelName := c.Query("model_name")
+	channel, _ := strconv.Atoi(c.Query("channel"))
+	group := c.Query("group")
+	requestId := c.Query("request_id")
+	upstreamRequestId := c.Query("upstream_request_id")
+
+	var writer *csv.Writer
+	// 首批数据读取成功后再写响应头，查询失败时仍可返回 JSON 错误
+	startCSV := func() {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=logs_%d.csv", common.GetTimestamp()))
+		c.Status(http.StatusOK)
+		writer = csv.NewWriter(c.Writer)
+		_ = writer.Write(exportLogsColumns)
+	}
+	err := model.ExportAllLogs(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel, group, requestId, upstreamRequestId, exportLogsMaxRows, exportLogsBatchSize, func(logs []*model.Log) error {
+		if writer == nil {
+			startCSV()
+		}
+		for _, log := range logs {
+			if err := writer.Write(exportLogRecord(log)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		c.Writer.Flush()
+		return writer.Error()
+	})
+	if writer == nil {
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		startCSV()
+		writer.Flush()
+		return
+	}
+	if err != nil {
+		// 响应已开始发送，只能中断并记录
+		common.SysError("failed to export logs: " + err.Error())
+	}
+}
+
+var exportLogsColumns = []string{"id", "created_at", "type", "user_id", "username", "token_name", "model_name", "quota", "prompt_tokens", "completion_tokens", "use_time", "channel", "group", "ip", "request_id", "content"}
+
+func exportLogRecord(log *model.Log) []string {
+	return []string{
+		strconv.Itoa(log.Id),
+		time.Unix(log.CreatedAt, 0).UTC().Format(time.RFC3339),
+		strconv.Itoa(log.Type),
+		strconv.Itoa(log.UserId),
+		log.Username,
+		log.TokenName,
+		log.ModelName,
+		strconv.Itoa(log.Quota),
+		strconv.Itoa(log.PromptTokens),
+		strconv.Itoa(log.CompletionTokens),
+		strconv.Itoa(log.UseTime),
+		strconv.Itoa(log.ChannelId),
+		log.Group,
+		log.Ip,
+		log.RequestId,
+		log.Content,
+	}
+}
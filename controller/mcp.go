@@ -0,0 +1,373 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/graphql"
+	"github.com/QuantumNous/new-api/pkg/mcp"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// MCP 以 Model Context Protocol（Streamable HTTP，无状态）暴露网关能力：模型调用与已注册的上游 MCP 工具。
+// 模型调用在内部转发给同一个 engine 的中继接口，鉴权、限流与计费与直接调用 HTTP 接口一致。
+// 该端点使用 API 令牌鉴权，不提供管理类工具，管理类工具见 AdminMCP
+func MCP(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !system_setting.GetMCPSetting().Enabled {
+			c.JSON(http.StatusNotFound, mcp.ErrorResponse(mcp.CodeInvalidRequest, "mcp endpoint is disabled"))
+			return
+		}
+		serveMCP(c, func(withTools bool) *mcp.Server {
+			return newMCPServer(c, engine, withTools)
+		})
+	}
+}
+
+// AdminMCP 管理类 MCP 端点，与后台管理接口一样要求管理员登录会话或访问令牌（AdminAuth），并受后台访问 IP 白名单限制
+func AdminMCP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setting := system_setting.GetMCPSetting()
+		if !setting.Enabled || !setting.AdminToolsEnabled {
+			c.JSON(http.StatusNotFound, mcp.ErrorResponse(mcp.CodeInvalidRequest, "mcp admin endpoint is disabled"))
+			return
+		}
+		if c.GetInt("role") < common.RoleAdminUser || !middleware.IsAdminAccessAllowed(c) {
+			c.JSON(http.StatusForbidden, mcp.ErrorResponse(mcp.CodeInvalidRequest, "admin access denied"))
+			return
+		}
+		serveMCP(c, func(withTools bool) *mcp.Server {
+			server := mcp.NewServer(mcp.Implementation{Name: "new-api-admin", Version: common.Version})
+			server.Instructions = "Use admin_query to inspect the gateway and admin_set_channel_status to manage channels."
+			addMCPAdminTools(c, server)
+			return server
+		})
+	}
+}
+
+// serveMCP 处理单条 JSON-RPC 消息；newServer 的参数表示本次请求是否需要完整的工具集
+func serveMCP(c *gin.Context, newServer func(withTools bool) *mcp.Server) {
+	if c.Request.Method != http.MethodPost {
+		// 不支持服务端主动推送的 SSE 流与会话
+		c.Header("Allow", http.MethodPost)
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	var req mcp.Request
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, mcp.ErrorResponse(mcp.CodeParseError, "invalid JSON-RPC message: "+err.Error()))
+		return
+	}
+	withTools := req.Method == "tools/list" || req.Method == "tools/call"
+	resp := newServer(withTools).Handle(c.Request.Context(), &req)
+	if resp == nil {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// newMCPServer 构造本次请求的工具集；withTools 为 false 时（如 initialize、ping）跳过上游工具的查询
+func newMCPServer(c *gin.Context, engine *gin.Engine, withTools bool) *mcp.Server {
+	server := mcp.NewServer(mcp.Implementation{Name: "new-api", Version: common.Version})
+	server.Instructions = "Use list_models to discover available models and chat to invoke them."
+
+	server.AddTool(mcp.Tool{
+		Name:        "list_models",
+		Description: "List the models available to the current API key.",
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+		status, body := dispatchInternalRequest(engine, c, http.MethodGet, "/v1/models", nil)
+		if status != http.StatusOK {
+			return nil, internalRequestError(status, body)
+		}
+		models := make([]string, 0)
+		for _, id := range gjson.GetBytes(body, "data.#.id").Array() {
+			models = append(models, id.String())
+		}
+		return mcp.JSONResult(map[string]any{"models": models})
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "chat",
+		Description: "Send a chat completion request to a model managed by the gateway. Provide either prompt or messages. mcp_servers lets the model call tools of the named upstream MCP servers registered on the gateway.",
+		InputSchema: mcp.ObjectSchema(map[string]any{
+			"model":       map[string]any{"type": "string", "description": "Model name, see list_models"},
+			"prompt":      map[string]any{"type": "string", "description": "User message"},
+			"system":      map[string]any{"type": "string", "description": "Optional system prompt"},
+			"messages":    map[string]any{"type": "array", "description": "OpenAI chat messages, used instead of prompt", "items": map[string]any{"type": "object"}},
+			"max_tokens":  map[string]any{"type": "integer"},
+			"temperature": map[string]any{"type": "number"},
+			"mcp_servers": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}, "model"),
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+		return mcpChat(ctx, c, engine, args)
+	})
+	if !withTools {
+		return server
+	}
+
+	for _, upstream := range service.ListMCPUpstreamTools(c.Request.Context(), nil) {
+		name := upstream.Tool.Name
+		server.AddTool(upstream.Tool, func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+			return service.CallMCPUpstreamTool(ctx, name, args)
+		})
+	}
+	return server
+}
+
+type mcpChatArgs struct {
+	Model       string            `json:"model"`
+	Prompt      string            `json:"prompt"`
+	System      string            `json:"system"`
+	Messages    []json.RawMessage `json:"messages"`
+	MaxTokens   *int              `json:"max_tokens"`
+	Temperature *float64          `json:"temperature"`
+	McpServers  []string          `json:"mcp_servers"`
+}
+
+type mcpToolInvocation struct {
+	Name    string `json:"name"`
+	IsError bool   `json:"is_error"`
+}
+
+// mcpChat 调用模型；指定 mcp_servers 时把上游工具作为 function 传给模型，并在网关侧执行工具调用，直到模型给出最终回复
+func mcpChat(ctx context.Context, c *gin.Context, engine *gin.Engine, raw json.RawMessage) (*mcp.CallToolResult, error) {
+	var args mcpChatArgs
+	if err := common.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	messages := make([]any, 0, len(args.Messages)+2)
+	if args.System != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": args.System})
+	}
+	for _, message := range args.Messages {
+		messages = append(messages, message)
+	}
+	if args.Prompt != "" {
+		messages = append(messages, map[string]any{"role": "user", "content": args.Prompt})
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("prompt or messages is required")
+	}
+
+	request := map[string]any{"model": args.Model, "stream": false}
+	if args.MaxTokens != nil {
+		request["max_tokens"] = *args.MaxTokens
+	}
+	if args.Temperature != nil {
+		request["temperature"] = *args.Temperature
+	}
+	offered := make(map[string]bool)
+	if len(args.McpServers) > 0 {
+		upstreamTools := service.ListMCPUpstreamTools(ctx, args.McpServers)
+		if len(upstreamTools) == 0 {
+			return nil, errors.New("no tools available from the requested mcp servers")
+		}
+		tools := make([]map[string]any, 0, len(upstreamTools))
+		for _, upstream := range upstreamTools {
+			offered[upstream.Tool.Name] = true
+			tools = append(tools, map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name":        upstream.Tool.Name,
+					"description": upstream.Tool.Description,
+					"parameters":  upstream.Tool.InputSchema,
+				},
+			})
+		}
+		request["tools"] = tools
+	}
+
+	maxRounds := system_setting.GetMCPSetting().MaxToolRounds
+	usage := map[string]int64{}
+	invocations := make([]mcpToolInvocation, 0)
+	for round := 0; ; round++ {
+		request["messages"] = messages
+		body, err := common.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		status, resp := dispatchInternalRequest(engine, c, http.MethodPost, "/v1/chat/completions", body)
+		if status != http.StatusOK {
+			return nil, internalRequestError(status, resp)
+		}
+		for _, key := range []string{"prompt_tokens", "completion_tokens", "total_tokens"} {
+			usage[key] += gjson.GetBytes(resp, "usage."+key).Int()
+		}
+		message := gjson.GetBytes(resp, "choices.0.message")
+		toolCalls := message.Get("tool_calls").Array()
+		if len(toolCalls) == 0 || len(offered) == 0 {
+			result := mcp.TextResult(message.Get("content").String())
+			result.StructuredContent = map[string]any{
+				"model":         gjson.GetBytes(resp, "model").String(),
+				"content":       message.Get("content").String(),
+				"finish_reason": gjson.GetBytes(resp, "choices.0.finish_reason").String(),
+				"usage":         usage,
+				"tool_calls":    invocations,
+			}
+			return result, nil
+		}
+		if round >= maxRounds {
+			return nil, fmt.Errorf("model did not finish within %d tool rounds", maxRounds)
+		}
+
+		messages = append(messages, json.RawMessage(message.Raw))
+		for _, toolCall := range toolCalls {
+			name := toolCall.Get("function.name").String()
+			arguments := toolCall.Get("function.arguments").String()
+			if arguments == "" {
+				arguments = "{}"
+			}
+			var content string
+			var isError bool
+			if !offered[name] {
+				content, isError = "Error: unknown tool "+name, true
+			} else if toolResult, err := service.CallMCPUpstreamTool(ctx, name, json.RawMessage(arguments)); err != nil {
+				content, isError = "Error: "+err.Error(), true
+			} else {
+				content, isError = toolResult.Text(), toolResult.IsError
+			}
+			invocations = append(invocations, mcpToolInvocation{Name: name, IsError: isError})
+			messages = append(messages, map[string]any{
+				"role":         "tool",
+				"tool_call_id": toolCall.Get("id").String(),
+				"content":      content,
+			})
+		}
+	}
+}
+
+// addMCPAdminTools 注册管理类工具，调用方须已通过 AdminAuth 鉴权
+func addMCPAdminTools(c *gin.Context, server *mcp.Server) {
+	userId := c.GetInt("id")
+	role := c.GetInt("role")
+
+	server.AddTool(mcp.Tool{
+		Name:        "admin_query",
+		Description: "Run a read-only GraphQL query against the admin API (users, tokens, channels, logs, usage).",
+		InputSchema: mcp.ObjectSchema(map[string]any{
+			"query":     map[string]any{"type": "string"},
+			"variables": map[string]any{"type": "object"},
+		}, "query"),
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+		var req graphql.Request
+		if err := common.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		data, errs := getAdminGraphQLSchema().Execute(req, role, c)
+		result, err := mcp.JSONResult(map[string]any{"data": data, "errors": errs})
+		if err != nil {
+			return nil, err
+		}
+		result.IsError = len(errs) > 0
+		return result, nil
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "admin_set_channel_status",
+		Description: "Enable or disable a channel.",
+		InputSchema: mcp.ObjectSchema(map[string]any{
+			"channel_id": map[string]any{"type": "integer"},
+			"enabled":    map[string]any{"type": "boolean"},
+		}, "channel_id", "enabled"),
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+		var params struct {
+			ChannelId int  `json:"channel_id"`
+			Enabled   bool `json:"enabled"`
+		}
+		if err := common.Unmarshal(args, &params); err != nil {
+			return nil, err
+		}
+		channel, err := model.GetChannelById(params.ChannelId, false)
+		if err != nil {
+			return nil, err
+		}
+		status := common.ChannelStatusManuallyDisabled
+		if params.Enabled {
+			status = common.ChannelStatusEnabled
+		}
+		changed := model.UpdateChannelStatus(channel.Id, "", status, fmt.Sprintf("set via MCP by user %d", userId))
+		if changed {
+			model.RecordLog(userId, model.LogTypeManage, fmt.Sprintf("通过 MCP 将渠道 %s（#%d）状态设置为 %d", channel.Name, channel.Id, status))
+		}
+		return mcp.JSONResult(map[string]any{"channel_id": channel.Id, "status": status, "changed": changed})
+	})
+}
+
+// dispatchInternalRequest 复用当前请求的鉴权头，向同一个 engine 发起内部请求并返回状态码与响应体
+func dispatchInternalRequest(engine *gin.Engine, c *gin.Context, method string, path string, body []byte) (int, []byte) {
+	req := c.Request.Clone(c.Request.Context())
+	req.Method = method
+	req.URL.Path = path
+	req.URL.RawPath = ""
+	req.URL.RawQuery = ""
+	req.RequestURI = path
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Del("Content-Encoding")
+	// 内部响应直接交给 gjson 解析，不能被响应压缩中间件按客户端的 Accept-Encoding 压缩
+	req.Header.Del("Accept-Encoding")
+	req.Header.Del(mcp.SessionHeader)
+	req.Header.Del(mcp.ProtocolVersionHeader)
+
+	writer := &bufferedResponseWriter{header: make(http.Header)}
+	engine.ServeHTTP(writer, req)
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	return writer.status, writer.buf.Bytes()
+}
+
+func internalRequestError(status int, body []byte) error {
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		message = gjson.GetBytes(body, "message").String()
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return fmt.Errorf("request failed with status %d: %s", status, message)
+}
+
+// bufferedResponseWriter 缓存内部请求的完整响应
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+func (w *bufferedResponseWriter) Flush() {}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// enableMCPForTest 开启 MCP 端点与管理类工具，结束后恢复原配置
+func enableMCPForTest(t *testing.T) {
+	t.Helper()
+	setting := system_setting.GetMCPSetting()
+	original := *setting
+	t.Cleanup(func() { *setting = original })
+	setting.Enabled = true
+	setting.AdminToolsEnabled = true
+	setting.UpstreamServers = nil
+}
+
+func postMCP(engine *gin.Engine, path string, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func mcpToolNames(t *testing.T, body []byte) []string {
+	t.Helper()
+	names := make([]string, 0)
+	for _, name := range gjson.GetBytes(body, "result.tools.#.name").Array() {
+		names = append(names, name.String())
+	}
+	return names
+}
+
+func TestMCPAdminToolsRequireAdminEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enableMCPForTest(t)
+
+	role := common.RoleCommonUser
+	engine := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("id", 1)
+		c.Set("role", role)
+	}
+	engine.POST("/mcp", setUser, MCP(engine))
+	engine.POST("/api/mcp", setUser, AdminMCP())
+	listTools := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+
+	// API 令牌鉴权的 MCP 端点不提供管理类工具，即使令牌属于管理员
+	role = common.RoleRootUser
+	recorder := postMCP(engine, "/mcp", listTools, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	names := mcpToolNames(t, recorder.Body.Bytes())
+	require.Contains(t, names, "chat")
+	require.NotContains(t, names, "admin_query")
+	require.NotContains(t, names, "admin_set_channel_status")
+
+	recorder = postMCP(engine, "/api/mcp", listTools, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.ElementsMatch(t, []string{"admin_query", "admin_set_channel_status"}, mcpToolNames(t, recorder.Body.Bytes()))
+
+	role = common.RoleCommonUser
+	recorder = postMCP(engine, "/api/mcp", listTools, nil)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	system_setting.GetMCPSetting().AdminToolsEnabled = false
+	role = common.RoleRootUser
+	recorder = postMCP(engine, "/api/mcp", listTools, nil)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestMCPInternalRequestIgnoresAcceptEncoding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enableMCPForTest(t)
+	generalSetting := operation_setting.GetGeneralSetting()
+	originalGeneral := *generalSetting
+	t.Cleanup(func() { *generalSetting = originalGeneral })
+	generalSetting.ResponseCompressionEnabled = true
+	generalSetting.ResponseCompressionMinBytes = 1
+
+	engine := gin.New()
+	engine.Use(middleware.ResponseCompression())
+	// 足够多的模型，保证压缩后的内容不再以明文出现
+	models := make([]gin.H, 0, 200)
+	for i := 0; i < 200; i++ {
+		models = append(models, gin.H{"id": fmt.Sprintf("model-%d", i), "object": "model", "owned_by": "custom"})
+	}
+	engine.GET("/v1/models", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"object": "list", "data": models})
+	})
+	engine.POST("/mcp", MCP(engine))
+
+	header := http.Header{"Accept-Encoding": []string{"gzip"}}
+	// 确认中间件确实会压缩该接口的响应
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+
+	recorder = postMCP(engine, "/mcp", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_models","arguments":{}}}`, header)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.Bytes()
+	if recorder.Header().Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		body, err = io.ReadAll(reader)
+		require.NoError(t, err)
+	}
+	require.False(t, gjson.GetBytes(body, "result.isError").Bool(), string(body))
+	text := gjson.GetBytes(body, "result.content.0.text").String()
+	require.Contains(t, text, `"model-199"`)
+}
@@ -8,10 +8,12 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/QuantumNous/new-api/setting/console_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
@@ -130,6 +132,20 @@ func GetStatus(c *gin.Context) {
 		data["faq"] = console_setting.GetFAQ()
 	}
 
+	// 通过租户自定义域名访问时覆盖品牌信息
+	if tenant := system_setting.GetTenantByHost(c.Request.Host); tenant != nil {
+		data["tenant"] = tenant.Name
+		if tenant.SystemName != "" {
+			data["system_name"] = tenant.SystemName
+		}
+		if tenant.Logo != "" {
+			data["logo"] = tenant.Logo
+		}
+		if tenant.Footer != "" {
+			data["footer_html"] = tenant.Footer
+		}
+	}
+
 	// Add enabled custom OAuth providers
 	customProviders := oauth.GetEnabledCustomProviders()
 	if len(customProviders) > 0 {
@@ -167,6 +183,14 @@ func GetStatus(c *gin.Context) {
 }
 
 func GetNotice(c *gin.Context) {
+	if tenant := system_setting.GetTenantByHost(c.Request.Host); tenant != nil && tenant.Announcement != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    tenant.Announcement,
+		})
+		return
+	}
 	common.OptionMapRWMutex.RLock()
 	defer common.OptionMapRWMutex.RUnlock()
 	c.JSON(http.StatusOK, gin.H{
@@ -283,11 +307,16 @@ func SendEmailVerification(c *gin.Context) {
 	}
 	code := common.GenerateVerificationCode(6)
 	common.RegisterVerificationCodeWithKey(email, code, common.EmailVerificationPurpose)
-	subject := fmt.Sprintf("%s邮箱验证邮件", common.SystemName)
-	content := fmt.Sprintf("<p>您好，你正在进行%s邮箱验证。</p>"+
-		"<p>您的验证码为: <strong>%s</strong></p>"+
-		"<p>验证码 %d 分钟内有效，如果不是本人操作，请忽略。</p>", common.SystemName, code, common.VerificationValidMinutes)
-	err := common.SendEmail(subject, email, content)
+	subject, content, err := service.RenderNotifyTemplate(service.NotifyTemplateEmailVerification, i18n.GetLangFromContext(c), map[string]any{
+		"SystemName":   common.SystemName,
+		"Code":         code,
+		"ValidMinutes": common.VerificationValidMinutes,
+	}, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	err = common.SendEmail(subject, email, content)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -312,12 +341,14 @@ func SendPasswordResetEmail(c *gin.Context) {
 		code := common.GenerateVerificationCode(0)
 		common.RegisterVerificationCodeWithKey(email, code, common.PasswordResetPurpose)
 		link := fmt.Sprintf("%s/user/reset?email=%s&token=%s", system_setting.ServerAddress, email, code)
-		subject := fmt.Sprintf("%s密码重置", common.SystemName)
-		content := fmt.Sprintf("<p>您好，你正在进行%s密码重置。</p>"+
-			"<p>点击 <a href='%s'>此处</a> 进行密码重置。</p>"+
-			"<p>如果链接无法点击，请尝试点击下面的链接或将其复制到浏览器中打开：<br> %s </p>"+
-			"<p>重置链接 %d 分钟内有效，如果不是本人操作，请忽略。</p>", common.SystemName, link, link, common.VerificationValidMinutes)
-		err := common.SendEmail(subject, email, content)
+		subject, content, err := service.RenderNotifyTemplate(service.NotifyTemplatePasswordReset, i18n.GetLangFromContext(c), map[string]any{
+			"SystemName":   common.SystemName,
+			"Link":         link,
+			"ValidMinutes": common.VerificationValidMinutes,
+		}, true)
+		if err == nil {
+			err = common.SendEmail(subject, email, content)
+		}
 		if err != nil {
 			logger.LogError(c.Request.Context(), fmt.Sprintf("failed to send password reset email to %s: %s", email, err.Error()))
 		}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
@@ -109,7 +111,25 @@ func init() {
 	})
 }
 
-func ListModels(c *gin.Context, modelType int) {
+// getTokenModelGroups 返回用户分组与令牌实际使用的分组，令牌分组为 auto 时为自动分组列表
+func getTokenModelGroups(c *gin.Context) (string, []string, error) {
+	userGroup, err := model.GetUserGroup(c.GetInt("id"), false)
+	if err != nil {
+		return "", nil, errors.New("get user group failed")
+	}
+	tokenGroup := common.GetContextKeyString(c, constant.ContextKeyTokenGroup)
+	switch tokenGroup {
+	case "":
+		return userGroup, []string{userGroup}, nil
+	case "auto":
+		return userGroup, service.GetUserAutoGroup(userGroup), nil
+	default:
+		return userGroup, []string{tokenGroup}, nil
+	}
+}
+
+// getTokenAvailableModels 返回当前令牌可用的模型：受令牌模型限制、分组与租户约束
+func getTokenAvailableModels(c *gin.Context) ([]dto.OpenAIModels, error) {
 	userOpenAiModels := make([]dto.OpenAIModels, 0)
 
 	acceptUnsetRatioModel := operation_setting.SelfUseModeEnabled
@@ -152,35 +172,27 @@ func ListModels(c *gin.Context, modelType int) {
 			}
 		}
 	} else {
-		userId := c.GetInt("id")
-		userGroup, err := model.GetUserGroup(userId, false)
+		_, groups, err := getTokenModelGroups(c)
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "get user group failed",
-			})
-			return
-		}
-		group := userGroup
-		tokenGroup := common.GetContextKeyString(c, constant.ContextKeyTokenGroup)
-		if tokenGroup != "" {
-			group = tokenGroup
+			return nil, err
 		}
 		var models []string
-		if tokenGroup == "auto" {
-			for _, autoGroup := range service.GetUserAutoGroup(userGroup) {
-				groupModels := model.GetGroupEnabledModels(autoGroup)
-				for _, g := range groupModels {
-					if !common.StringsContains(models, g) {
-						models = append(models, g)
-					}
+		for _, group := range groups {
+			for _, g := range model.GetGroupEnabledModels(group) {
+				if !common.StringsContains(models, g) {
+					models = append(models, g)
 				}
 			}
-		} else {
-			models = model.GetGroupEnabledModels(group)
+		}
+		// 全局模型别名按解析后的模型计费，无需单独配置倍率
+		aliases := service.AvailableModelAliases(models)
+		for _, alias := range aliases {
+			if !common.StringsContains(models, alias) {
+				models = append(models, alias)
+			}
 		}
 		for _, modelName := range models {
-			if !acceptUnsetRatioModel {
+			if !acceptUnsetRatioModel && !common.StringsContains(aliases, modelName) {
 				if !helper.HasModelBillingConfig(modelName) {
 					continue
 				}
@@ -200,6 +212,28 @@ func ListModels(c *gin.Context, modelType int) {
 		}
 	}
 
+	if tenant := system_setting.GetTenantByGroup(common.GetContextKeyString(c, constant.ContextKeyUserGroup), common.GetContextKeyString(c, constant.ContextKeyUsingGroup)); tenant != nil {
+		tenantModels := make([]dto.OpenAIModels, 0, len(userOpenAiModels))
+		for _, m := range userOpenAiModels {
+			if tenant.IsModelAllowed(m.Id) {
+				tenantModels = append(tenantModels, m)
+			}
+		}
+		userOpenAiModels = tenantModels
+	}
+	return userOpenAiModels, nil
+}
+
+func ListModels(c *gin.Context, modelType int) {
+	userOpenAiModels, err := getTokenAvailableModels(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	switch modelType {
 	case constant.ChannelTypeAnthropic:
 		useranthropicModels := make([]dto.AnthropicModel, len(userOpenAiModels))
@@ -230,6 +264,22 @@ func ListModels(c *gin.Context, modelType int) {
 			"nextPageToken": nil,
 		})
 	default:
+		if c.Query("include_metadata") == "true" {
+			userGroup, groups, err := getTokenModelGroups(c)
+			if err != nil {
+				c.JSON(http.StatusOK, gin.H{
+					"success": false,
+					"message": err.Error(),
+				})
+				return
+			}
+			c.JSON(200, gin.H{
+				"success": true,
+				"data":    service.BuildModelsWithMetadata(userOpenAiModels, userGroup, groups),
+				"object":  "list",
+			})
+			return
+		}
 		c.JSON(200, gin.H{
 			"success": true,
 			"data":    userOpenAiModels,
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type notifyTemplateRequest struct {
+	Name    string `json:"name"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+func validateNotifyTemplateTarget(name string, locale string) error {
+	if !service.IsValidNotifyTemplateName(name) {
+		return fmt.Errorf("未知的通知模板: %s", name)
+	}
+	if !i18n.IsSupported(locale) || i18n.NormalizeLang(locale) != locale {
+		return fmt.Errorf("不支持的语言: %s", locale)
+	}
+	return nil
+}
+
+func saveNotifyTemplates(templates map[string]map[string]system_setting.NotifyTemplate) error {
+	data, err := common.Marshal(templates)
+	if err != nil {
+		return err
+	}
+	return model.UpdateOption("notify_template_setting.templates", string(data))
+}
+
+// GetNotifyTemplates 列出全部通知模板（内置默认值与自定义覆盖）
+func GetNotifyTemplates(c *gin.Context) {
+	common.ApiSuccess(c, service.ListNotifyTemplates())
+}
+
+// UpdateNotifyTemplate 保存某个模板在指定语言下的自定义内容
+func UpdateNotifyTemplate(c *gin.Context) {
+	var req notifyTemplateRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if err := validateNotifyTemplateTarget(req.Name, req.Locale); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	tpl := system_setting.NotifyTemplate{Subject: req.Subject, Content: req.Content}
+	if _, _, err := service.RenderNotifyTemplateText(tpl, service.SampleNotifyTemplateData(req.Name), service.IsHTMLNotifyTemplate(req.Name, "")); err != nil {
+		common.ApiErrorMsg(c, "模板语法错误: "+err.Error())
+		return
+	}
+	templates := system_setting.CloneNotifyTemplates()
+	if templates[req.Name] == nil {
+		templates[req.Name] = make(map[string]system_setting.NotifyTemplate)
+	}
+	templates[req.Name][req.Locale] = tpl
+	if err := saveNotifyTemplates(templates); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, tpl)
+}
+
+// ResetNotifyTemplate 删除自定义内容，恢复为内置模板
+func ResetNotifyTemplate(c *gin.Context) {
+	name := c.Query("name")
+	locale := c.Query("locale")
+	if err := validateNotifyTemplateTarget(name, locale); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	templates := system_setting.CloneNotifyTemplates()
+	if locales, ok := templates[name]; ok {
+		delete(locales, locale)
+		if len(locales) == 0 {
+			delete(templates, name)
+		}
+	}
+	if err := saveNotifyTemplates(templates); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// PreviewNotifyTemplate 使用示例数据渲染模板；未提供内容时渲染当前生效的模板
+func PreviewNotifyTemplate(c *gin.Context) {
+	var req notifyTemplateRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if err := validateNotifyTemplateTarget(req.Name, req.Locale); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	data := service.SampleNotifyTemplateData(req.Name)
+	html := service.IsHTMLNotifyTemplate(req.Name, "")
+	var subject, content string
+	var err error
+	if req.Subject == "" && req.Content == "" {
+		subject, content, err = service.RenderNotifyTemplate(req.Name, req.Locale, data, html)
+	} else {
+		subject, content, err = service.RenderNotifyTemplateText(system_setting.NotifyTemplate{
+			Subject: req.Subject,
+			Content: req.Content,
+		}, data, html)
+	}
+	if err != nil {
+		common.ApiErrorMsg(c, "模板语法错误: "+err.Error())
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"subject": subject,
+		"content": content,
+	})
+}
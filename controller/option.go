@@ -6,9 +6,15 @@ import (
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/billing_setting"
 	"github.com/QuantumNous/new-api/setting/console_setting"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/setting/system_setting"
@@ -74,13 +80,8 @@ func GetOptions(c *gin.Context) {
 	optionValues := make(map[string]string)
 	common.OptionMapRWMutex.Lock()
 	for k, v := range common.OptionMap {
-		value := common.Interface2String(v)
-		isSensitiveKey := strings.HasSuffix(k, "Token") ||
-			strings.HasSuffix(k, "Secret") ||
-			strings.HasSuffix(k, "Key") ||
-			strings.HasSuffix(k, "secret") ||
-			strings.HasSuffix(k, "api_key")
-		if isSensitiveKey && !isVisiblePublicKeyOption(k) {
+		value := maskOptionSecrets(common.Interface2String(v))
+		if isSensitiveOptionKey(k) && !isVisiblePublicKeyOption(k) {
 			continue
 		}
 		options = append(options, &model.Option{
@@ -132,6 +133,15 @@ func UpdateOption(c *gin.Context) {
 	default:
 		option.Value = fmt.Sprintf("%v", option.Value)
 	}
+	// GetOptions 会隐藏 JSON 配置中的密钥，提交时把未修改的占位符还原为原值
+	common.OptionMapRWMutex.RLock()
+	currentValue := common.OptionMap[option.Key]
+	common.OptionMapRWMutex.RUnlock()
+	option.Value = restoreOptionSecrets(option.Value.(string), currentValue)
+	if strings.HasPrefix(option.Key, "tenant_setting.") && !entitlement.IsEnabled(entitlement.FeatureMultiTenancy) {
+		common.ApiErrorI18n(c, i18n.MsgFeatureNotEntitled, map[string]any{"Feature": entitlement.FeatureMultiTenancy})
+		return
+	}
 	switch option.Key {
 	case "GitHubOAuthEnabled":
 		if option.Value == "true" && common.GitHubClientId == "" {
@@ -305,6 +315,293 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "EmailProvider":
+		if !common.IsValidEmailProvider(option.Value.(string)) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "不支持的邮件服务商",
+			})
+			return
+		}
+	case "admin_access_setting.allowed_ips", "admin_access_setting.trusted_proxies":
+		err = system_setting.ValidateIpList(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "moderation_setting.steps":
+		err = moderation_setting.ValidateSteps(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "moderation_setting.group_policies":
+		err = moderation_setting.ValidatePolicies(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "moderation_setting.default_policy":
+		err = moderation_setting.ValidatePolicy(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "pii_setting.default_policy":
+		err = moderation_setting.ValidatePiiPolicy(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "pii_setting.group_policies":
+		err = moderation_setting.ValidatePiiPolicies(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "blocklist_setting.rules":
+		err = moderation_setting.ValidateBlocklistRules(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "prompt_injection_setting.extra_rules":
+		err = moderation_setting.ValidatePromptInjectionRules(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "prompt_injection_setting.group_thresholds":
+		err = moderation_setting.ValidatePromptInjectionThresholds(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "billing_setting.volume_pricing":
+		err = billing_setting.ValidateVolumePricing(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "billing_setting.time_pricing":
+		err = billing_setting.ValidateTimePricing(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "billing_setting.time_pricing_timezone":
+		err = billing_setting.ValidateTimePricingTimezone(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "policy_hook_setting.url":
+		err = system_setting.ValidatePolicyHookUrl(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "image_safety_setting.default_action":
+		err = moderation_setting.ValidateImageSafetyAction(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "image_safety_setting.group_actions":
+		err = moderation_setting.ValidateImageSafetyGroupActions(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "abuse_pattern_setting.action":
+		err = moderation_setting.ValidateAbusePatternAction(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "request_dedup_setting.billing_policy":
+		err = operation_setting.ValidateRequestDedupBillingPolicy(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "channel_probe_setting.action":
+		err = operation_setting.ValidateChannelProbeAction(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "secret_scan_setting.action":
+		err = moderation_setting.ValidateSecretScanAction(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "secret_scan_setting.group_actions":
+		err = moderation_setting.ValidateSecretScanGroupActions(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "safety_level_setting.default_level":
+		err = moderation_setting.ValidateSafetyLevel(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "safety_level_setting.group_levels":
+		err = moderation_setting.ValidateSafetyLevelMap(option.Value.(string), false)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "safety_level_setting.gemini_thresholds", "safety_level_setting.azure_policy_ids", "safety_level_setting.claude_system_prompts":
+		err = moderation_setting.ValidateSafetyLevelMap(option.Value.(string), true)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "plugin_setting.plugins":
+		err = service.ValidatePlugins(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "model_capability_setting.capabilities":
+		err = model_setting.ValidateModelCapabilities(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "mcp_setting.upstream_servers":
+		err = system_setting.ValidateMCPUpstreamServers(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "model_alias_setting.aliases":
+		err = model_setting.ValidateModelAliases(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "model_alias_setting.rules":
+		err = model_setting.ValidateModelAliasRules(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "system_prompt_setting.rules":
+		err = operation_setting.ValidateSystemPromptRules(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "tenant_setting.tenants":
+		err = system_setting.ValidateTenants(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "event_webhook_setting.endpoints":
+		err = system_setting.ValidateEventWebhookEndpoints(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "console_setting.uptime_kuma_groups":
 		err = console_setting.ValidateConsoleSettings(option.Value.(string), "UptimeKumaGroups")
 		if err != nil {
@@ -315,6 +612,18 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
+	// 值未变化时跳过写入，使重复提交相同配置成为幂等的空操作
+	common.OptionMapRWMutex.RLock()
+	current, exists := common.OptionMap[option.Key]
+	common.OptionMapRWMutex.RUnlock()
+	if exists && current == option.Value.(string) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    gin.H{"changed": false},
+		})
+		return
+	}
 	err = model.UpdateOption(option.Key, option.Value.(string))
 	if err != nil {
 		common.ApiError(c, err)
@@ -323,6 +632,59 @@ func UpdateOption(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    gin.H{"changed": true},
 	})
 	return
 }
+
+type testEmailRequest struct {
+	Receiver string `json:"receiver"`
+}
+
+// SendTestEmail 使用当前配置的邮件服务商发送一封测试邮件
+func SendTestEmail(c *gin.Context) {
+	var req testEmailRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if err := common.Validate.Var(req.Receiver, "required,email"); err != nil {
+		common.ApiErrorMsg(c, "无效的邮箱地址")
+		return
+	}
+	provider := common.EmailProvider
+	if provider == "" {
+		provider = common.EmailProviderSMTP
+	}
+	subject, content, err := service.RenderNotifyTemplate(service.NotifyTemplateTestEmail, i18n.GetLangFromContext(c), map[string]any{
+		"SystemName": common.SystemName,
+		"Provider":   provider,
+	}, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := common.SendEmail(subject, req.Receiver, content); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// SendTestEventWebhook 向指定的事件 webhook 端点同步发送一条测试事件
+func SendTestEventWebhook(c *gin.Context) {
+	var endpoint system_setting.EventWebhookEndpoint
+	if err := common.DecodeJson(c.Request.Body, &endpoint); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if err := system_setting.ValidateEventWebhookUrl(endpoint.Url); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.SendTestEventWebhook(endpoint); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// optionSecretMask 嵌套在 JSON 配置中的密钥返回给前端时的占位符，保存时会被还原为原值
+const optionSecretMask = "******"
+
+// isSensitiveOptionField 判断 JSON 配置中的字段是否为密钥类字段
+func isSensitiveOptionField(name string) bool {
+	name = strings.ToLower(name)
+	return name == "key" ||
+		name == "token" ||
+		name == "authorization" ||
+		strings.HasSuffix(name, "secret") ||
+		strings.HasSuffix(name, "_key") ||
+		strings.HasSuffix(name, "apikey") ||
+		strings.HasSuffix(name, "password")
+}
+
+// isSensitiveOptionKey 判断配置项是否为密钥，密钥配置项不返回给前端；
+// 结构化配置（如 file_setting.s3_secret_key）按最后一段字段名判断
+func isSensitiveOptionKey(key string) bool {
+	if strings.HasSuffix(key, "Token") ||
+		strings.HasSuffix(key, "Secret") ||
+		strings.HasSuffix(key, "Key") ||
+		strings.HasSuffix(key, "secret") ||
+		strings.HasSuffix(key, "api_key") {
+		return true
+	}
+	if index := strings.LastIndex(key, "."); index >= 0 {
+		return isSensitiveOptionField(key[index+1:])
+	}
+	return false
+}
+
+func parseOptionJson(value string) (any, bool) {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var parsed any
+	if err := common.UnmarshalJsonStr(trimmed, &parsed); err != nil {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// maskOptionSecrets 将 JSON 配置中非空的密钥字段替换为占位符，非 JSON 值原样返回
+func maskOptionSecrets(value string) string {
+	parsed, ok := parseOptionJson(value)
+	if !ok || !maskSecretFields(parsed) {
+		return value
+	}
+	masked, err := common.Marshal(parsed)
+	if err != nil {
+		return value
+	}
+	return string(masked)
+}
+
+func maskSecretFields(node any) bool {
+	changed := false
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if str, ok := child.(string); ok && str != "" && isSensitiveOptionField(key) {
+				v[key] = optionSecretMask
+				changed = true
+				continue
+			}
+			if maskSecretFields(child) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if maskSecretFields(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// restoreOptionSecrets 将提交值中仍为占位符的密钥字段还原为当前保存的值
+func restoreOptionSecrets(value string, current string) string {
+	if !strings.Contains(value, optionSecretMask) {
+		return value
+	}
+	parsed, ok := parseOptionJson(value)
+	if !ok {
+		return value
+	}
+	currentParsed, _ := parseOptionJson(current)
+	restoreSecretFields(parsed, currentParsed)
+	restored, err := common.Marshal(parsed)
+	if err != nil {
+		return value
+	}
+	return string(restored)
+}
+
+func restoreSecretFields(node any, current any) {
+	switch v := node.(type) {
+	case map[string]any:
+		currentMap, _ := current.(map[string]any)
+		for key, child := range v {
+			if str, ok := child.(string); ok && str == optionSecretMask && isSensitiveOptionField(key) {
+				// 找不到原值时清空，避免把占位符当作密钥保存
+				original, _ := currentMap[key].(string)
+				v[key] = original
+				continue
+			}
+			restoreSecretFields(child, currentMap[key])
+		}
+	case []any:
+		currentList, _ := current.([]any)
+		for i, child := range v {
+			restoreSecretFields(child, matchOptionListItem(child, currentList, i))
+		}
+	}
+}
+
+// matchOptionListItem 在原列表中找到与提交项对应的元素：优先按 id、name、url 匹配，避免增删条目后错位，否则按下标匹配
+func matchOptionListItem(item any, currentList []any, index int) any {
+	if itemMap, ok := item.(map[string]any); ok {
+		for _, identity := range []string{"id", "name", "url"} {
+			value, ok := itemMap[identity]
+			if !ok || value == nil || value == "" {
+				continue
+			}
+			for _, candidate := range currentList {
+				if candidateMap, ok := candidate.(map[string]any); ok && candidateMap[identity] == value {
+					return candidate
+				}
+			}
+			return nil
+		}
+	}
+	if index < len(currentList) {
+		return currentList[index]
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskOptionSecrets(t *testing.T) {
+	value := `{"enabled":true,"endpoints":[{"name":"ops","url":"https://a.example.com","secret":"s1","events":["token.exhausted"]},{"name":"audit","url":"https://b.example.com","secret":""}]}`
+	masked := maskOptionSecrets(value)
+	require.JSONEq(t, `{"enabled":true,"endpoints":[{"name":"ops","url":"https://a.example.com","secret":"******","events":["token.exhausted"]},{"name":"audit","url":"https://b.example.com","secret":""}]}`, masked)
+
+	steps := `[{"type":"openai","api_key":"sk-xxx","base_url":"https://api.example.com"},{"type":"keyword","keywords":["a"]}]`
+	require.JSONEq(t, `[{"type":"openai","api_key":"******","base_url":"https://api.example.com"},{"type":"keyword","keywords":["a"]}]`, maskOptionSecrets(steps))
+
+	// 非 JSON 或不含密钥的值原样返回
+	require.Equal(t, "plain-value", maskOptionSecrets("plain-value"))
+	require.Equal(t, `{"a": 1}`, maskOptionSecrets(`{"a": 1}`))
+}
+
+func TestMaskModerationStepSecrets(t *testing.T) {
+	steps := `[{"name":"omni","type":"openai","api_key":"sk-live","model":"omni-moderation-latest"},{"name":"hook","type":"http","url":"https://mod.example.com","headers":{"Authorization":"Bearer abc","X-Trace":"1"}}]`
+	masked := maskOptionSecrets(steps)
+	require.NotContains(t, masked, "sk-live")
+	require.NotContains(t, masked, "Bearer abc")
+	require.JSONEq(t, `[{"name":"omni","type":"openai","api_key":"******","model":"omni-moderation-latest"},{"name":"hook","type":"http","url":"https://mod.example.com","headers":{"Authorization":"******","X-Trace":"1"}}]`, masked)
+	require.JSONEq(t, steps, restoreOptionSecrets(masked, steps))
+}
+
+func TestRestoreOptionSecrets(t *testing.T) {
+	current := `{"endpoints":[{"name":"ops","url":"https://a.example.com","secret":"s1"},{"name":"audit","url":"https://b.example.com","secret":"s2"}]}`
+
+	t.Run("placeholder restored", func(t *testing.T) {
+		submitted := maskOptionSecrets(current)
+		require.JSONEq(t, current, restoreOptionSecrets(submitted, current))
+	})
+
+	t.Run("reordered list matched by name", func(t *testing.T) {
+		submitted := `{"endpoints":[{"name":"audit","url":"https://b.example.com","secret":"******"},{"name":"ops","url":"https://a.example.com","secret":"******"}]}`
+		require.JSONEq(t, `{"endpoints":[{"name":"audit","url":"https://b.example.com","secret":"s2"},{"name":"ops","url":"https://a.example.com","secret":"s1"}]}`, restoreOptionSecrets(submitted, current))
+	})
+
+	t.Run("new secret kept and unknown placeholder cleared", func(t *testing.T) {
+		submitted := `{"endpoints":[{"name":"ops","url":"https://a.example.com","secret":"rotated"},{"name":"new","url":"https://c.example.com","secret":"******"}]}`
+		require.JSONEq(t, `{"endpoints":[{"name":"ops","url":"https://a.example.com","secret":"rotated"},{"name":"new","url":"https://c.example.com","secret":""}]}`, restoreOptionSecrets(submitted, current))
+	})
+
+	t.Run("value without placeholder untouched", func(t *testing.T) {
+		require.Equal(t, `{"a":"******x"}`, restoreOptionSecrets(`{"a":"******x"}`, current))
+		require.Equal(t, "abc", restoreOptionSecrets("abc", current))
+	})
+}
+
+func TestIsSensitiveOptionKey(t *testing.T) {
+	for _, key := range []string{"GitHubClientSecret", "TelegramBotToken", "TurnstileSecretKey", "file_setting.s3_access_key", "file_setting.s3_secret_key", "oidc.client_secret"} {
+		require.True(t, isSensitiveOptionKey(key), key)
+	}
+	for _, key := range []string{"ServerAddress", "file_setting.s3_bucket", "file_setting.storage_type", "realtime_setting.max_sessions_per_token"} {
+		require.False(t, isSensitiveOptionKey(key), key)
+	}
+}
+
+func TestIsSensitiveOptionField(t *testing.T) {
+	for _, name := range []string{"key", "Token", "secret", "client_secret", "api_key", "ApiKey", "s3_secret_key", "password", "Authorization"} {
+		require.True(t, isSensitiveOptionField(name), name)
+	}
+	for _, name := range []string{"name", "url", "keywords", "base_url", "events"} {
+		require.False(t, isSensitiveOptionField(name), name)
+	}
+}
+
+func TestGetOptionsHidesFileStorageKeys(t *testing.T) {
+	common.OptionMapRWMutex.Lock()
+	original := common.OptionMap
+	common.OptionMap = map[string]string{
+		"file_setting.s3_bucket":     "uploads",
+		"file_setting.s3_access_key": "AKIAEXAMPLE",
+		"file_setting.s3_secret_key": "wJalrXUtnFEMI",
+	}
+	common.OptionMapRWMutex.Unlock()
+	t.Cleanup(func() {
+		common.OptionMapRWMutex.Lock()
+		common.OptionMap = original
+		common.OptionMapRWMutex.Unlock()
+	})
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/option/", nil)
+	GetOptions(c)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "file_setting.s3_bucket")
+	require.NotContains(t, recorder.Body.String(), "s3_access_key")
+	require.NotContains(t, recorder.Body.String(), "AKIAEXAMPLE")
+	require.NotContains(t, recorder.Body.String(), "wJalrXUtnFEMI")
+}
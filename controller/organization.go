@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createOrganizationRequest struct {
+	Name    string `json:"name"`
+	OwnerId int    `json:"owner_id"`
+}
+
+type organizationQuotaRequest struct {
+	Quota int `json:"quota"`
+}
+
+type inviteOrganizationMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type updateOrganizationMemberRequest struct {
+	Role        string   `json:"role"`
+	ModelLimits []string `json:"model_limits"`
+}
+
+func isValidOrganizationRole(role string) bool {
+	return role == model.OrganizationRoleMember || role == model.OrganizationRoleAdmin
+}
+
+// GetOrganizations 管理员分页查看所有组织
+func GetOrganizations(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	orgs, total, err := model.GetAllOrganizations(pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// CreateOrganization 管理员创建组织并指定组织管理员
+func CreateOrganization(c *gin.Context) {
+	var req createOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 128 {
+		common.ApiErrorMsg(c, "组织名称不能为空且不能超过 128 个字符")
+		return
+	}
+	if _, err := model.GetUserById(req.OwnerId, false); err != nil {
+		common.ApiErrorMsg(c, "组织管理员用户不存在")
+		return
+	}
+	org := &model.Organization{Name: req.Name}
+	if err := model.CreateOrganizationWithOwner(org, req.OwnerId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+// UpdateOrganization 管理员修改组织名称与状态
+func UpdateOrganization(c *gin.Context) {
+	var req model.Organization
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	org, err := model.GetOrganizationById(req.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		org.Name = name
+	}
+	if req.Status != 0 {
+		if req.Status != model.OrganizationStatusEnabled && req.Status != model.OrganizationStatusDisabled {
+			common.ApiErrorMsg(c, "无效的状态")
+			return
+		}
+		org.Status = req.Status
+	}
+	if err := org.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+// AdjustOrganizationQuota 管理员为组织额度池充值或扣减
+func AdjustOrganizationQuota(c *gin.Context) {
+	orgId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req organizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quota == 0 {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if err := model.AdjustOrganizationQuota(orgId, req.Quota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, "调整组织 #"+strconv.Itoa(orgId)+" 额度池 "+strconv.Itoa(req.Quota))
+	common.ApiSuccess(c, nil)
+}
+
+// getSelfOrganizationMember 返回当前用户所在组织，requireAdmin 时要求是组织管理员
+func getSelfOrganizationMember(c *gin.Context, requireAdmin bool) (*model.OrganizationMember, *model.Organization, bool) {
+	member, err := model.GetActiveOrganizationMember(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, nil, false
+	}
+	if member == nil {
+		common.ApiErrorMsg(c, "未加入任何组织")
+		return nil, nil, false
+	}
+	if requireAdmin && !member.IsAdmin() {
+		common.ApiErrorMsg(c, "仅组织管理员可执行此操作")
+		return nil, nil, false
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, nil, false
+	}
+	if requireAdmin && org.Status != model.OrganizationStatusEnabled {
+		common.ApiErrorMsg(c, "组织已被禁用")
+		return nil, nil, false
+	}
+	return member, org, true
+}
+
+// GetSelfOrganization 查看当前用户所在组织及其角色
+func GetSelfOrganization(c *gin.Context) {
+	member, err := model.GetActiveOrganizationMember(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member == nil {
+		common.ApiSuccess(c, nil)
+		return
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"organization": org,
+		"member":       member,
+	})
+}
+
+// GetOrganizationInvitations 查看当前用户收到的组织邀请
+func GetOrganizationInvitations(c *gin.Context) {
+	invitations, err := model.GetOrganizationInvitations(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, invitations)
+}
+
+// AcceptOrganizationInvitation 接受组织邀请
+func AcceptOrganizationInvitation(c *gin.Context) {
+	orgId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.AcceptOrganizationInvitation(orgId, c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// RejectOrganizationInvitation 拒绝组织邀请
+func RejectOrganizationInvitation(c *gin.Context) {
+	orgId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	member, err := model.GetOrganizationMember(orgId, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member.Status != model.OrganizationMemberStatusInvited {
+		common.ApiErrorMsg(c, "邀请不存在")
+		return
+	}
+	if err := model.RemoveOrganizationMember(orgId, member.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// LeaveOrganization 成员退出组织，组织管理员需先移交管理员身份
+func LeaveOrganization(c *gin.Context) {
+	member, _, ok := getSelfOrganizationMember(c, false)
+	if !ok {
+		return
+	}
+	if member.IsAdmin() {
+		common.ApiErrorMsg(c, "组织管理员不能直接退出组织")
+		return
+	}
+	if err := model.RemoveOrganizationMember(member.OrganizationId, member.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetOrganizationMembers 组织管理员查看成员与邀请
+func GetOrganizationMembers(c *gin.Context) {
+	_, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(org.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+// InviteOrganizationMember 组织管理员按用户名邀请成员
+func InviteOrganizationMember(c *gin.Context) {
+	self, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	var req inviteOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Role == "" {
+		req.Role = model.OrganizationRoleMember
+	}
+	if req.Username == "" || !isValidOrganizationRole(req.Role) {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	member, err := model.InviteOrganizationMember(org.Id, req.Username, req.Role, self.UserId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, member)
+}
+
+// UpdateOrganizationMember 组织管理员修改成员角色与可用模型
+func UpdateOrganizationMember(c *gin.Context) {
+	self, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req updateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	member, err := model.GetOrganizationMember(org.Id, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Role != "" {
+		if !isValidOrganizationRole(req.Role) {
+			common.ApiErrorMsg(c, "无效的角色")
+			return
+		}
+		if member.UserId == self.UserId && req.Role != model.OrganizationRoleAdmin {
+			common.ApiErrorMsg(c, "不能取消自己的组织管理员身份")
+			return
+		}
+		member.Role = req.Role
+	}
+	if req.ModelLimits != nil {
+		models := make([]string, 0, len(req.ModelLimits))
+		for _, name := range req.ModelLimits {
+			if name = strings.TrimSpace(name); name != "" {
+				models = append(models, name)
+			}
+		}
+		member.ModelLimits = strings.Join(models, ",")
+	}
+	if err := model.UpdateOrganizationMember(member); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, member)
+}
+
+// RemoveOrganizationMember 组织管理员移除成员或撤回邀请
+func RemoveOrganizationMember(c *gin.Context) {
+	self, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if userId == self.UserId {
+		common.ApiErrorMsg(c, "不能移除自己")
+		return
+	}
+	if err := model.RemoveOrganizationMember(org.Id, userId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// AllocateOrganizationMemberQuota 组织管理员从额度池向成员分配额度，quota 为负时回收
+func AllocateOrganizationMemberQuota(c *gin.Context) {
+	self, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req organizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quota == 0 {
+		common.ApiErrorMsg(c, "参数错误")
+		return
+	}
+	if err := model.AllocateOrganizationQuota(org.Id, userId, req.Quota, self.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetOrganizationUsage 组织管理员按成员查看汇总用量，可按时间范围筛选
+func GetOrganizationUsage(c *gin.Context) {
+	_, org, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	usages, err := model.GetOrganizationUsage(org.Id, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"organization": org,
+		"members":      usages,
+	})
+}
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	playgroundShareDefaultHours = 24 * 7
+	playgroundShareMaxHours     = 24 * 90
+)
+
+func validatePlaygroundSession(session *model.PlaygroundSession) error {
+	session.Title = strings.TrimSpace(session.Title)
+	if session.Title == "" {
+		session.Title = "Untitled"
+	}
+	if len(session.Title) > 128 {
+		return errors.New("标题不能超过 128 个字符")
+	}
+	if len(session.Messages) == 0 || session.Messages[0] != '[' {
+		return errors.New("messages 必须为数组")
+	}
+	return nil
+}
+
+// GetPlaygroundSessions 获取当前用户保存的操练场对话列表
+func GetPlaygroundSessions(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	sessions, total, err := model.GetUserPlaygroundSessions(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(sessions)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetPlaygroundSession 获取单个对话的完整内容，用于回放
+func GetPlaygroundSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	session, err := model.GetUserPlaygroundSession(c.GetInt("id"), id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, session)
+}
+
+// CreatePlaygroundSession 保存操练场对话
+func CreatePlaygroundSession(c *gin.Context) {
+	var session model.PlaygroundSession
+	if err := c.ShouldBindJSON(&session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := validatePlaygroundSession(&session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	session.Id = 0
+	session.UserId = c.GetInt("id")
+	session.ShareCode = ""
+	session.ShareExpiresAt = 0
+	if err := session.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &session)
+}
+
+// UpdatePlaygroundSession 更新已保存的对话
+func UpdatePlaygroundSession(c *gin.Context) {
+	var session model.PlaygroundSession
+	if err := c.ShouldBindJSON(&session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if session.Id == 0 {
+		common.ApiErrorMsg(c, "缺少对话 ID")
+		return
+	}
+	userId := c.GetInt("id")
+	if _, err := model.GetUserPlaygroundSession(userId, session.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := validatePlaygroundSession(&session); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	session.UserId = userId
+	if err := session.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	updated, err := model.GetUserPlaygroundSession(userId, session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, updated)
+}
+
+// DeletePlaygroundSession 删除对话，分享链接随之失效
+func DeletePlaygroundSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteUserPlaygroundSession(c.GetInt("id"), id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// SharePlaygroundSession 生成带有效期的只读分享链接，重复调用会更换分享码
+func SharePlaygroundSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req struct {
+		ExpiresInHours int `json:"expires_in_hours"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	if req.ExpiresInHours <= 0 {
+		req.ExpiresInHours = playgroundShareDefaultHours
+	}
+	if req.ExpiresInHours > playgroundShareMaxHours {
+		common.ApiErrorMsg(c, "分享有效期不能超过 90 天")
+		return
+	}
+	userId := c.GetInt("id")
+	if _, err := model.GetUserPlaygroundSession(userId, id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	shareCode := common.GetRandomString(32)
+	expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour).Unix()
+	if err := model.SetPlaygroundSessionShare(userId, id, shareCode, expiresAt); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"share_code": shareCode,
+		"expires_at": expiresAt,
+	})
+}
+
+// UnsharePlaygroundSession 撤销分享链接
+func UnsharePlaygroundSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.SetPlaygroundSessionShare(c.GetInt("id"), id, "", 0); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetSharedPlaygroundSession 无需登录，通过分享码只读回放对话
+func GetSharedPlaygroundSession(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		common.ApiErrorMsg(c, "分享链接无效")
+		return
+	}
+	session, err := model.GetSharedPlaygroundSession(code, common.GetTimestamp())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorMsg(c, "分享链接不存在或已过期")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"title":      session.Title,
+		"model":      session.Model,
+		"messages":   session.Messages,
+		"params":     session.Params,
+		"expires_at": session.ShareExpiresAt,
+	})
+}
+
+// GetPlaygroundPrompts 获取当前用户的提示词预设
+func GetPlaygroundPrompts(c *gin.Context) {
+	prompts, err := model.GetUserPlaygroundPrompts(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, prompts)
+}
+
+func validatePlaygroundPrompt(c *gin.Context, prompt *model.PlaygroundPrompt) bool {
+	prompt.Name = strings.TrimSpace(prompt.Name)
+	if prompt.Name == "" || len(prompt.Name) > 64 {
+		common.ApiErrorMsg(c, "预设名称不能为空且不超过 64 个字符")
+		return false
+	}
+	if dup, err := model.IsPlaygroundPromptNameDuplicated(prompt.UserId, prompt.Id, prompt.Name); err != nil {
+		common.ApiError(c, err)
+		return false
+	} else if dup {
+		common.ApiErrorMsg(c, "预设名称已存在")
+		return false
+	}
+	return true
+}
+
+// CreatePlaygroundPrompt 创建提示词预设
+func CreatePlaygroundPrompt(c *gin.Context) {
+	var prompt model.PlaygroundPrompt
+	if err := c.ShouldBindJSON(&prompt); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	prompt.Id = 0
+	prompt.UserId = c.GetInt("id")
+	if !validatePlaygroundPrompt(c, &prompt) {
+		return
+	}
+	if err := prompt.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &prompt)
+}
+
+// UpdatePlaygroundPrompt 更新提示词预设
+func UpdatePlaygroundPrompt(c *gin.Context) {
+	var prompt model.PlaygroundPrompt
+	if err := c.ShouldBindJSON(&prompt); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if prompt.Id == 0 {
+		common.ApiErrorMsg(c, "缺少预设 ID")
+		return
+	}
+	prompt.UserId = c.GetInt("id")
+	if _, err := model.GetUserPlaygroundPrompt(prompt.UserId, prompt.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !validatePlaygroundPrompt(c, &prompt) {
+		return
+	}
+	if err := prompt.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &prompt)
+}
+
+// DeletePlaygroundPrompt 删除提示词预设
+func DeletePlaygroundPrompt(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteUserPlaygroundPrompt(c.GetInt("id"), id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -1,9 +1,12 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/billing_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
@@ -73,6 +76,10 @@ func GetPricing(c *gin.Context) {
 		"supported_endpoint": model.GetSupportedEndpointMap(),
 		"auto_groups":        service.GetUserAutoGroup(group),
 		"pricing_version":    "a42d372ccf0b5dd13ecf71203521f9d2",
+		"time_pricing": gin.H{
+			"timezone": billing_setting.GetTimePricingTimezone(),
+			"rules":    billing_setting.GetTimePricingRules(),
+		},
 	})
 }
 
@@ -99,3 +106,18 @@ func ResetModelRatio(c *gin.Context) {
 		"message": "重置模型倍率成功",
 	})
 }
+
+// GetModelCatalog 返回当前用户可用的模型目录，包含能力元数据与当前价格，供客户端模型选择器使用
+func GetModelCatalog(c *gin.Context) {
+	var group string
+	if userId := c.GetInt("id"); userId != 0 {
+		if user, err := model.GetUserCache(userId); err == nil {
+			group = user.Group
+		}
+	}
+	catalog := service.BuildModelCatalog(group, service.GetUserUsableGroups(group), time.Now())
+	common.ApiSuccess(c, gin.H{
+		"models":  catalog,
+		"vendors": model.GetVendors(),
+	})
+}
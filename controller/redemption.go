@@ -81,6 +81,9 @@ func AddRedemption(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
 		return
 	}
+	if !validateRedemptionPlan(c, redemption.PlanId) {
+		return
+	}
 	var keys []string
 	for i := 0; i < redemption.Count; i++ {
 		key := common.GetUUID()
@@ -91,6 +94,7 @@ func AddRedemption(c *gin.Context) {
 			CreatedTime: common.GetTimestamp(),
 			Quota:       redemption.Quota,
 			ExpiredTime: redemption.ExpiredTime,
+			PlanId:      redemption.PlanId,
 		}
 		err = cleanRedemption.Insert()
 		if err != nil {
@@ -144,10 +148,14 @@ func UpdateRedemption(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"success": false, "message": msg})
 			return
 		}
+		if !validateRedemptionPlan(c, redemption.PlanId) {
+			return
+		}
 		// If you add more fields, please also update redemption.Update()
 		cleanRedemption.Name = redemption.Name
 		cleanRedemption.Quota = redemption.Quota
 		cleanRedemption.ExpiredTime = redemption.ExpiredTime
+		cleanRedemption.PlanId = redemption.PlanId
 	}
 	if statusOnly != "" {
 		cleanRedemption.Status = redemption.Status
@@ -185,3 +193,19 @@ func validateExpiredTime(c *gin.Context, expired int64) (bool, string) {
 	}
 	return true, ""
 }
+
+// validateRedemptionPlan 兑换码绑定额度套餐时检查套餐是否存在
+func validateRedemptionPlan(c *gin.Context, planId int) bool {
+	if planId < 0 {
+		common.ApiErrorMsg(c, "无效的套餐ID")
+		return false
+	}
+	if planId == 0 {
+		return true
+	}
+	if _, err := model.GetSubscriptionPlanById(planId); err != nil {
+		common.ApiErrorMsg(c, "套餐不存在")
+		return false
+	}
+	return true
+}
@@ -15,14 +15,20 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
+	channelmetrics "github.com/QuantumNous/new-api/pkg/channel_metrics"
+	circuitbreaker "github.com/QuantumNous/new-api/pkg/circuit_breaker"
 	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	prommetrics "github.com/QuantumNous/new-api/pkg/prom_metrics"
+	trafficstream "github.com/QuantumNous/new-api/pkg/traffic_stream"
 	"github.com/QuantumNous/new-api/relay"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -35,7 +41,7 @@ import (
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
 	var err *types.NewAPIError
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		err = relay.ImageHelper(c, info)
 	case relayconstant.RelayModeAudioSpeech:
 		fallthrough
@@ -77,7 +83,16 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	)
 
 	if relayFormat == types.RelayFormatOpenAIRealtime {
-		var err error
+		release, err := service.AcquireRealtimeSession(c.GetInt("token_id"), c.GetInt("id"))
+		if err != nil {
+			service.WriteOpenAIError(c, http.StatusTooManyRequests, types.OpenAIError{
+				Message: err.Error(),
+				Type:    "new_api_error",
+				Code:    "too_many_realtime_sessions",
+			})
+			return
+		}
+		defer release()
 		ws, err = upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			helper.WssError(c, ws, types.NewError(err, types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry()).ToOpenAIError())
@@ -89,23 +104,37 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	defer func() {
 		if newAPIError != nil {
 			logger.LogError(c, fmt.Sprintf("relay error: %s", newAPIError.Error()))
+			model.RecordExperimentError(c)
 			newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
+			if operation_setting.GetGeneralSetting().ProviderErrorMappingEnabled {
+				newAPIError.MapProviderError()
+			}
 			switch relayFormat {
 			case types.RelayFormatOpenAIRealtime:
 				helper.WssError(c, ws, newAPIError.ToOpenAIError())
 			case types.RelayFormatClaude:
+				service.WriteRetryHintHeaders(c, newAPIError.StatusCode, string(newAPIError.GetErrorCode()))
 				c.JSON(newAPIError.StatusCode, gin.H{
 					"type":  "error",
 					"error": newAPIError.ToClaudeError(),
 				})
 			default:
-				c.JSON(newAPIError.StatusCode, gin.H{
-					"error": newAPIError.ToOpenAIError(),
-				})
+				service.WriteOpenAIError(c, newAPIError.StatusCode, newAPIError.ToOpenAIError())
 			}
 		}
 	}()
 
+	if err := service.RedactRequestPii(c); err != nil {
+		newAPIError = types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+		return
+	}
+	if newAPIError = service.ApplyRequestBlocklist(c); newAPIError != nil {
+		return
+	}
+	if newAPIError = service.ScanRequestSecrets(c); newAPIError != nil {
+		return
+	}
+
 	request, err := helper.GetAndValidateRequest(c, relayFormat)
 	if err != nil {
 		// Map "request body too large" to 413 so clients can handle it correctly
@@ -125,9 +154,12 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	needSensitiveCheck := setting.ShouldCheckPromptSensitive()
 	needCountToken := constant.CountToken
-	// Avoid building huge CombineText (strings.Join) when token counting and sensitive check are both disabled.
+	needModeration := service.ShouldModerate(relayInfo.UsingGroup, moderation_setting.StageInput)
+	needInjectionCheck := service.ShouldCheckPromptInjection()
+	needAbuseCheck := moderation_setting.GetAbusePatternSetting().Enabled
+	// Avoid building huge CombineText (strings.Join) when token counting and all content checks are disabled.
 	var meta *types.TokenCountMeta
-	if needSensitiveCheck || needCountToken {
+	if needSensitiveCheck || needCountToken || needModeration || needInjectionCheck || needAbuseCheck {
 		meta = request.GetTokenCountMeta()
 	} else {
 		meta = fastTokenCountMetaForPricing(request)
@@ -142,6 +174,25 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	if needModeration && meta != nil {
+		if newAPIError = service.ModerateInput(c, relayInfo.UsingGroup, relayInfo.OriginModelName, meta.CombineText); newAPIError != nil {
+			recordModerationBlock(c, relayInfo, newAPIError)
+			return
+		}
+	}
+
+	if needInjectionCheck && meta != nil {
+		if newAPIError = service.CheckPromptInjection(c, relayInfo.UsingGroup, meta.CombineText); newAPIError != nil {
+			return
+		}
+	}
+
+	if needAbuseCheck && meta != nil {
+		if newAPIError = service.CheckAbusePatterns(c, meta.CombineText); newAPIError != nil {
+			return
+		}
+	}
+
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
@@ -156,8 +207,19 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		return
 	}
 
+	if system_setting.ShouldCallPolicyHook(relayInfo.UsingGroup) {
+		if newAPIError = applyPolicyHook(c, relayInfo, request, tokens, meta, &priceData); newAPIError != nil {
+			return
+		}
+	}
+
 	// common.SetContextKey(c, constant.ContextKeyTokenCountMeta, meta)
 
+	// 缓存命中的请求同样需要通过上面的请求侧检查，检查通过后才返回缓存结果
+	if service.ReplayPendingCachedResponse(c) {
+		return
+	}
+
 	if priceData.FreeModel {
 		logger.LogInfo(c, fmt.Sprintf("模型 %s 免费，跳过预扣费", relayInfo.OriginModelName))
 	} else {
@@ -186,6 +248,8 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	}
 	relayInfo.RetryIndex = 0
 	relayInfo.LastError = nil
+	queueTicket := service.NewModelQueueTicket(relayInfo.OriginModelName)
+	defer queueTicket.Leave()
 
 	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
 		relayInfo.RetryIndex = retryParam.GetRetry()
@@ -196,9 +260,34 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 			break
 		}
 
+		releaseConcurrency, concurrencyErr := service.AcquireChannelConcurrency(c, channel)
+		if concurrencyErr != nil {
+			logger.LogWarn(c, concurrencyErr.Error())
+			newAPIError = concurrencyErr
+			retryParam.AddBusyChannel(channel.Id)
+			if retryParam.GetRetry() < common.RetryTimes && shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
+				continue
+			}
+			// 可选渠道并发均已满，进入模型队列等待名额释放后重新选择
+			wakeUp, queueErr := queueTicket.Wait(c)
+			if queueErr != nil {
+				newAPIError = queueErr
+				break
+			}
+			if !wakeUp {
+				break
+			}
+			retryParam.BusyChannelIds = nil
+			retryParam.SetRetry(0)
+			retryParam.ResetRetryNextTry()
+			continue
+		}
+		queueTicket.Leave()
+
 		addUsedChannel(c, channel.Id)
 		bodyStorage, bodyErr := common.GetBodyStorage(c)
 		if bodyErr != nil {
+			releaseConcurrency()
 			// Ensure consistent 413 for oversized bodies even when error occurs later (e.g., retry path)
 			if common.IsRequestBodyTooLargeError(bodyErr) || errors.Is(bodyErr, common.ErrRequestBodyTooLarge) {
 				newAPIError = types.NewErrorWithStatusCode(bodyErr, types.ErrorCodeReadRequestBodyFailed, http.StatusRequestEntityTooLarge, types.ErrOptionWithSkipRetry())
@@ -209,17 +298,10 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 		c.Request.Body = io.NopCloser(bodyStorage)
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
-		}
+		attemptStart := time.Now()
+		newAPIError = relayAttempt(c, relayFormat, relayInfo, releaseConcurrency)
 
+		recordChannelSample(relayInfo, channel.Id, attemptStart, newAPIError)
 		if newAPIError == nil {
 			relayInfo.LastError = nil
 			return
@@ -229,6 +311,7 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		relayInfo.LastError = newAPIError
 
 		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
+		retryParam.AddFailedChannel(channel.Id)
 
 		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
 			break
@@ -244,6 +327,20 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		gopool.Go(func() {
 			perfmetrics.RecordRelaySample(relayInfo, false, 0)
 		})
+		if threshold := system_setting.GetEventWebhookSetting().LargeRequestTokenThreshold; threshold > 0 && tokens >= threshold {
+			service.EmitEvent(service.EventLargeRequestFailed, map[string]any{
+				"request_id":         requestId,
+				"user_id":            relayInfo.UserId,
+				"token_id":           relayInfo.TokenId,
+				"model":              relayInfo.OriginModelName,
+				"prompt_tokens":      tokens,
+				"channels":           useChannel,
+				"status_code":        newAPIError.StatusCode,
+				"error_code":         newAPIError.GetErrorCode(),
+				"error":              newAPIError.MaskSensitiveError(),
+				"pre_consumed_quota": priceData.QuotaToPreConsume,
+			})
+		}
 	}
 }
 
@@ -254,6 +351,21 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// relayAttempt 使用当前选中的渠道执行一次转发，结束时（包括处理过程中 panic）释放渠道并发名额
+func relayAttempt(c *gin.Context, relayFormat types.RelayFormat, relayInfo *relaycommon.RelayInfo, releaseConcurrency func()) *types.NewAPIError {
+	defer releaseConcurrency()
+	switch relayFormat {
+	case types.RelayFormatOpenAIRealtime:
+		return relay.WssHelper(c, relayInfo)
+	case types.RelayFormatClaude:
+		return relay.ClaudeHelper(c, relayInfo)
+	case types.RelayFormatGemini:
+		return geminiRelayHandler(c, relayInfo)
+	default:
+		return relayHandler(c, relayInfo)
+	}
+}
+
 func addUsedChannel(c *gin.Context, channelId int) {
 	useChannel := c.GetStringSlice("use_channel")
 	useChannel = append(useChannel, fmt.Sprintf("%d", channelId))
@@ -321,6 +433,31 @@ func getChannel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service
 	return channel, nil
 }
 
+// recordChannelSample 记录本次尝试的渠道延迟与结果，供延迟优先路由与熔断器使用；流式请求以首字延迟计，
+// 不重试的错误（如请求参数错误）与渠道无关，不计入统计
+func recordChannelSample(info *relaycommon.RelayInfo, channelId int, attemptStart time.Time, newAPIError *types.NewAPIError) {
+	total := time.Since(attemptStart)
+	var ttfb time.Duration
+	if info.IsStream && info.FirstResponseTime.After(attemptStart) {
+		ttfb = info.FirstResponseTime.Sub(attemptStart)
+	}
+	statusCode := 0
+	if newAPIError != nil {
+		statusCode = newAPIError.StatusCode
+	}
+	prommetrics.RecordRelayAttempt(channelId, info.OriginModelName, statusCode, total, ttfb)
+
+	if newAPIError != nil && types.IsSkipRetryError(newAPIError) && !types.IsChannelError(newAPIError) {
+		return
+	}
+	latency := total
+	if ttfb > 0 {
+		latency = ttfb
+	}
+	channelmetrics.Record(channelId, info.OriginModelName, latency, newAPIError == nil)
+	circuitbreaker.Record(channelId, info.OriginModelName, newAPIError == nil)
+}
+
 func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) bool {
 	if openaiErr == nil {
 		return false
@@ -328,6 +465,10 @@ func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) b
 	if service.ShouldSkipRetryAfterChannelAffinityFailure(c) {
 		return false
 	}
+	// 已向客户端输出数据（如流式响应中途出错）时无法再切换渠道重放请求
+	if c.Writer.Written() {
+		return false
+	}
 	if types.IsChannelError(openaiErr) {
 		return true
 	}
@@ -357,11 +498,14 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 	logger.LogError(c, fmt.Sprintf("channel error (channel #%d, status code: %d): %s", channelError.ChannelId, err.StatusCode, err.Error()))
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
 	// do not use context to get channel info, there may be inconsistent channel info when processing asynchronously
-	if service.ShouldDisableChannel(err) && channelError.AutoBan {
+	// 多 Key 渠道命中冷却状态码时仅冷却当前 Key，不禁用
+	cooledDown := service.CooldownChannelKey(channelError, err)
+	if !cooledDown && service.ShouldDisableChannel(err) && channelError.AutoBan {
 		gopool.Go(func() {
 			service.DisableChannel(channelError, err.ErrorWithStatusCode())
 		})
 	}
+	publishTrafficError(c, channelError.ChannelId, err)
 
 	if constant.ErrorLogEnabled && types.IsRecordErrorLog(err) {
 		// 保存错误日志到mysql中
@@ -395,11 +539,60 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 			startTime = time.Now()
 		}
 		useTimeSeconds := int(time.Since(startTime).Seconds())
-		model.RecordErrorLog(c, userId, channelId, modelName, tokenName, err.MaskSensitiveErrorWithStatusCode(), tokenId, useTimeSeconds, common.GetContextKeyBool(c, constant.ContextKeyIsStream), userGroup, other)
+		content := err.MaskSensitiveErrorWithStatusCode()
+		if common.IsNoStore(c) {
+			// 上游错误信息可能回显请求内容，合规模式下只记录错误码
+			other["no_store"] = true
+			content = fmt.Sprintf("status_code=%d, error_code=%s", err.StatusCode, err.GetErrorCode())
+		}
+		model.RecordErrorLog(c, userId, channelId, modelName, tokenName, content, tokenId, useTimeSeconds, common.GetContextKeyBool(c, constant.ContextKeyIsStream), userGroup, other)
 	}
 
 }
 
+// recordModerationBlock 将被输入审核拦截的请求及审核结果写入错误日志
+func recordModerationBlock(c *gin.Context, relayInfo *relaycommon.RelayInfo, err *types.NewAPIError) {
+	if !constant.ErrorLogEnabled {
+		return
+	}
+	other := map[string]interface{}{
+		"error_type":       err.GetErrorType(),
+		"error_code":       err.GetErrorCode(),
+		"status_code":      err.StatusCode,
+		"moderation_flags": service.GetModerationFlags(c),
+	}
+	if c.Request != nil && c.Request.URL != nil {
+		other["request_path"] = c.Request.URL.Path
+	}
+	model.RecordErrorLog(c, relayInfo.UserId, 0, relayInfo.OriginModelName, c.GetString("token_name"), err.MaskSensitiveErrorWithStatusCode(),
+		c.GetInt("token_id"), 0, relayInfo.IsStream, relayInfo.UsingGroup, other)
+}
+
+// publishTrafficError 向管理员实时流量推送一次失败的渠道尝试
+func publishTrafficError(c *gin.Context, channelId int, err *types.NewAPIError) {
+	startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime)
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+	message := err.MaskSensitiveError()
+	if common.IsNoStore(c) {
+		message = string(err.GetErrorCode())
+	}
+	trafficstream.Publish(trafficstream.Event{
+		RequestId:  c.GetString(common.RequestIdKey),
+		UserId:     c.GetInt("id"),
+		Username:   c.GetString("username"),
+		TokenName:  c.GetString("token_name"),
+		Model:      c.GetString("original_model"),
+		Group:      c.GetString("group"),
+		ChannelId:  channelId,
+		StatusCode: err.StatusCode,
+		LatencyMs:  time.Since(startTime).Milliseconds(),
+		IsStream:   common.GetContextKeyBool(c, constant.ContextKeyIsStream),
+		Error:      message,
+	})
+}
+
 func RelayMidjourney(c *gin.Context) {
 	relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatMjProxy, nil, nil)
 
@@ -450,9 +643,7 @@ func RelayNotImplemented(c *gin.Context) {
 		Param:   "",
 		Code:    "api_not_implemented",
 	}
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": err,
-	})
+	service.WriteOpenAIError(c, http.StatusNotImplemented, err)
 }
 
 func RelayNotFound(c *gin.Context) {
@@ -462,9 +653,7 @@ func RelayNotFound(c *gin.Context) {
 		Param:   "",
 		Code:    "",
 	}
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": err,
-	})
+	service.WriteOpenAIError(c, http.StatusNotFound, err)
 }
 
 func RelayTaskFetch(c *gin.Context) {
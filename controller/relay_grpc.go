@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/grpcrelay"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// RelayGrpc 将 gRPC 调用转换为对应的 HTTP 中继请求，交由同一个 engine 处理（鉴权、分发、计费与 HTTP 完全一致），
+// 再把 JSON 响应或 SSE 事件按 gRPC 帧写回
+func RelayGrpc(engine *gin.Engine, path string, stream bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contentType := c.ContentType()
+		if contentType != grpcrelay.ContentType && contentType != grpcrelay.ContentType+"+proto" {
+			c.String(http.StatusUnsupportedMediaType, "unsupported content type: %s", contentType)
+			return
+		}
+		maxMB := constant.MaxRequestBodyMB
+		if maxMB <= 0 {
+			maxMB = 128
+		}
+		msg, err := grpcrelay.ReadFrame(c.Request.Body, int64(maxMB)<<20)
+		if err != nil {
+			code := grpcrelay.CodeInvalidArgument
+			if err == grpcrelay.ErrCompressedMessage {
+				code = grpcrelay.CodeUnimplemented
+			}
+			writeGrpcStatus(c.Writer, code, err.Error())
+			return
+		}
+		body, err := grpcrelay.DecodePayload(msg)
+		if err == nil && (stream || gjson.GetBytes(body, "stream").Bool()) {
+			body, err = sjson.SetBytes(body, "stream", stream)
+		}
+		if err != nil {
+			writeGrpcStatus(c.Writer, grpcrelay.CodeInvalidArgument, err.Error())
+			return
+		}
+
+		req := c.Request.Clone(c.Request.Context())
+		req.Method = http.MethodPost
+		req.URL.Path = path
+		req.URL.RawPath = ""
+		req.RequestURI = path
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Del("Te")
+
+		writer := &grpcResponseWriter{header: make(http.Header), stream: stream, out: c.Writer}
+		engine.ServeHTTP(writer, req)
+		writer.finish()
+	}
+}
+
+// grpcResponseWriter 截获内部 HTTP 响应：非流式响应整体作为一条消息，流式响应的每个 SSE data 事件作为一条消息
+type grpcResponseWriter struct {
+	header  http.Header
+	status  int
+	stream  bool
+	out     gin.ResponseWriter
+	buf     bytes.Buffer
+	started bool
+}
+
+func (w *grpcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *grpcResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.buf.Write(p)
+	if w.isEventStream() {
+		w.flushEvents(false)
+	}
+	return len(p), nil
+}
+
+func (w *grpcResponseWriter) Flush() {
+	if w.isEventStream() {
+		w.flushEvents(false)
+	}
+}
+
+func (w *grpcResponseWriter) isEventStream() bool {
+	return w.stream && w.status == http.StatusOK &&
+		strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+}
+
+// flushEvents 将缓冲区中完整的 SSE 行转换为 gRPC 消息，final 为 true 时同时处理末尾不完整的行
+func (w *grpcResponseWriter) flushEvents(final bool) {
+	data := w.buf.Bytes()
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if final {
+		end = len(data)
+	}
+	if end == 0 {
+		return
+	}
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(line[len("data:"):])
+		if len(payload) == 0 || string(payload) == "[DONE]" {
+			continue
+		}
+		w.writeMessage(payload)
+	}
+	rest := append([]byte(nil), data[end:]...)
+	w.buf.Reset()
+	w.buf.Write(rest)
+}
+
+func (w *grpcResponseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.out.Header().Set("Content-Type", grpcrelay.ContentType)
+	w.out.WriteHeader(http.StatusOK)
+}
+
+func (w *grpcResponseWriter) writeMessage(json []byte) {
+	w.start()
+	if err := grpcrelay.WriteFrame(w.out, grpcrelay.EncodePayload(json)); err == nil {
+		w.out.Flush()
+	}
+}
+
+func (w *grpcResponseWriter) finish() {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	code := grpcrelay.CodeFromHTTPStatus(status)
+	message := ""
+	switch {
+	case w.isEventStream():
+		w.flushEvents(true)
+	case code == grpcrelay.CodeOK:
+		w.writeMessage(w.buf.Bytes())
+	default:
+		body := w.buf.Bytes()
+		message = gjson.GetBytes(body, "error.message").String()
+		if message == "" {
+			message = gjson.GetBytes(body, "message").String()
+		}
+		if message == "" {
+			message = http.StatusText(status)
+		}
+	}
+	w.start()
+	writeGrpcTrailers(w.out, code, message)
+}
+
+func writeGrpcStatus(out gin.ResponseWriter, code int, message string) {
+	out.Header().Set("Content-Type", grpcrelay.ContentType)
+	out.WriteHeader(http.StatusOK)
+	writeGrpcTrailers(out, code, message)
+}
+
+func writeGrpcTrailers(out gin.ResponseWriter, code int, message string) {
+	out.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		out.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcrelay.EncodeMessage(message))
+	}
+}
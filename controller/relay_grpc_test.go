@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/pkg/grpcrelay"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// newGrpcRelayTestServer 以 HTTP/2 启动 engine，内部 HTTP 接口由 handler 模拟
+func newGrpcRelayTestServer(t *testing.T, handler gin.HandlerFunc) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", handler)
+	prefix := "/" + grpcrelay.ServiceName + "/"
+	engine.POST(prefix+"ChatCompletions", RelayGrpc(engine, "/v1/chat/completions", false))
+	engine.POST(prefix+"StreamChatCompletions", RelayGrpc(engine, "/v1/chat/completions", true))
+
+	server := httptest.NewUnstartedServer(engine)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+type grpcTestResult struct {
+	resp     *http.Response
+	messages []string
+}
+
+func callGrpcRelay(t *testing.T, server *httptest.Server, method string, request string) grpcTestResult {
+	var frame bytes.Buffer
+	require.NoError(t, grpcrelay.WriteFrame(&frame, grpcrelay.EncodePayload([]byte(request))))
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/"+grpcrelay.ServiceName+"/"+method, &frame)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", grpcrelay.ContentType)
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("Authorization", "Bearer sk-test")
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	result := grpcTestResult{resp: resp}
+	for {
+		msg, err := grpcrelay.ReadFrame(resp.Body, 1<<20)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		payload, err := grpcrelay.DecodePayload(msg)
+		require.NoError(t, err)
+		result.messages = append(result.messages, string(payload))
+	}
+	// 读完响应体后 Trailer 才可用
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return result
+}
+
+func TestRelayGrpcUnary(t *testing.T) {
+	var innerBody []byte
+	var innerHeader http.Header
+	server := newGrpcRelayTestServer(t, func(c *gin.Context) {
+		innerBody, _ = io.ReadAll(c.Request.Body)
+		innerHeader = c.Request.Header.Clone()
+		c.JSON(http.StatusOK, gin.H{"id": "chatcmpl-1", "choices": []gin.H{{"message": gin.H{"content": "hi"}}}})
+	})
+
+	result := callGrpcRelay(t, server, "ChatCompletions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`)
+	require.Equal(t, "application/json", innerHeader.Get("Content-Type"))
+	require.Equal(t, "Bearer sk-test", innerHeader.Get("Authorization"))
+	require.Equal(t, "gpt-4o", gjson.GetBytes(innerBody, "model").String())
+	require.Equal(t, "hello", gjson.GetBytes(innerBody, "messages.0.content").String())
+	require.False(t, gjson.GetBytes(innerBody, "stream").Bool())
+	require.Equal(t, http.StatusOK, result.resp.StatusCode)
+	require.Equal(t, grpcrelay.ContentType, result.resp.Header.Get("Content-Type"))
+	require.Len(t, result.messages, 1)
+	require.Equal(t, "chatcmpl-1", gjson.Get(result.messages[0], "id").String())
+	require.Equal(t, "0", result.resp.Trailer.Get("Grpc-Status"))
+	require.Empty(t, result.resp.Trailer.Get("Grpc-Message"))
+}
+
+func TestRelayGrpcStreaming(t *testing.T) {
+	var innerBody []byte
+	server := newGrpcRelayTestServer(t, func(c *gin.Context) {
+		innerBody, _ = io.ReadAll(c.Request.Body)
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"he\"}}]}\n\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"llo\"}}]}\n\ndata: [DONE]\n\n")
+	})
+
+	result := callGrpcRelay(t, server, "StreamChatCompletions", `{"model":"gpt-4o","messages":[]}`)
+	require.True(t, gjson.GetBytes(innerBody, "stream").Bool())
+	require.Len(t, result.messages, 2)
+	require.Equal(t, "he", gjson.Get(result.messages[0], "choices.0.delta.content").String())
+	require.Equal(t, "llo", gjson.Get(result.messages[1], "choices.0.delta.content").String())
+	require.Equal(t, "0", result.resp.Trailer.Get("Grpc-Status"))
+}
+
+func TestRelayGrpcErrorTrailers(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    gin.H
+		code    string
+		message string
+	}{
+		{"rate limited", http.StatusTooManyRequests, gin.H{"error": gin.H{"message": "slow down: 100%"}}, "8", "slow down: 100%25"},
+		{"unauthorized", http.StatusUnauthorized, gin.H{"message": "invalid token"}, "16", "invalid token"},
+		{"upstream unavailable", http.StatusServiceUnavailable, gin.H{}, "14", "Service Unavailable"},
+		{"internal", http.StatusInternalServerError, gin.H{"error": gin.H{"message": "boom"}}, "13", "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGrpcRelayTestServer(t, func(c *gin.Context) {
+				c.JSON(tt.status, tt.body)
+			})
+			result := callGrpcRelay(t, server, "ChatCompletions", `{"model":"gpt-4o"}`)
+			// gRPC 错误始终以 HTTP 200 返回，状态在 Trailer 中
+			require.Equal(t, http.StatusOK, result.resp.StatusCode)
+			require.Empty(t, result.messages)
+			require.Equal(t, tt.code, result.resp.Trailer.Get("Grpc-Status"))
+			require.Equal(t, tt.message, result.resp.Trailer.Get("Grpc-Message"))
+		})
+	}
+}
+
+func TestRelayGrpcRejectsInvalidRequests(t *testing.T) {
+	called := false
+	server := newGrpcRelayTestServer(t, func(c *gin.Context) {
+		called = true
+	})
+
+	resp, err := server.Client().Post(server.URL+"/"+grpcrelay.ServiceName+"/ChatCompletions", "application/json", bytes.NewReader([]byte(`{}`)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	// 压缩标志位不受支持
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/"+grpcrelay.ServiceName+"/ChatCompletions", bytes.NewReader([]byte{1, 0, 0, 0, 0}))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", grpcrelay.ContentType)
+	resp, err = server.Client().Do(req)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	require.Equal(t, "12", resp.Trailer.Get("Grpc-Status"))
+	require.False(t, called)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// applyPolicyHook 在预扣费与转发之前调用外部策略服务，按决策放行、拒绝或改写模型。
+// 改写模型时会重新选择渠道并重新计算价格。
+func applyPolicyHook(c *gin.Context, relayInfo *relaycommon.RelayInfo, request dto.Request, tokens int, meta *types.TokenCountMeta, priceData *types.PriceData) *types.NewAPIError {
+	decision, err := service.EvaluatePolicyHook(c, service.PolicyHookRequest{
+		RequestId:             c.GetString(common.RequestIdKey),
+		UserId:                relayInfo.UserId,
+		Username:              common.GetContextKeyString(c, constant.ContextKeyUserName),
+		Group:                 relayInfo.UsingGroup,
+		TokenId:               relayInfo.TokenId,
+		TokenName:             c.GetString("token_name"),
+		Model:                 relayInfo.OriginModelName,
+		RelayFormat:           string(relayInfo.RelayFormat),
+		Path:                  c.Request.URL.Path,
+		IsStream:              relayInfo.IsStream,
+		ClientIp:              c.ClientIP(),
+		EstimatedPromptTokens: tokens,
+		EstimatedQuota:        priceData.QuotaToPreConsume,
+		EstimatedCostUSD:      float64(priceData.QuotaToPreConsume) / common.QuotaPerUnit,
+	})
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("policy hook failed, decision %s: %s", decision.Decision, err.Error()))
+	}
+
+	switch decision.Decision {
+	case service.PolicyDecisionDeny:
+		message := "request denied by policy"
+		if decision.Reason != "" {
+			message = fmt.Sprintf("%s: %s", message, decision.Reason)
+		}
+		return types.NewErrorWithStatusCode(fmt.Errorf("%s", message), types.ErrorCodeAccessDenied, http.StatusForbidden, types.ErrOptionWithSkipRetry())
+	case service.PolicyDecisionModify:
+		if decision.Model == relayInfo.OriginModelName {
+			return nil
+		}
+		logger.LogInfo(c, fmt.Sprintf("policy hook rewrote model %s -> %s: %s", relayInfo.OriginModelName, decision.Model, decision.Reason))
+		return rerouteToModel(c, relayInfo, request, decision.Model, tokens, meta, priceData)
+	}
+	return nil
+}
+
+func rerouteToModel(c *gin.Context, relayInfo *relaycommon.RelayInfo, request dto.Request, modelName string, tokens int, meta *types.TokenCountMeta, priceData *types.PriceData) *types.NewAPIError {
+	channel, selectGroup, err := service.CacheGetRandomSatisfiedChannel(&service.RetryParam{
+		Ctx:        c,
+		ModelName:  modelName,
+		TokenGroup: relayInfo.TokenGroup,
+		Retry:      common.GetPointer(0),
+	})
+	if err != nil || channel == nil {
+		return types.NewError(fmt.Errorf("分组 %s 下模型 %s 的可用渠道不存在（policy）", selectGroup, modelName), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
+	}
+	if apiErr := middleware.SetupContextForSelectedChannel(c, channel, modelName); apiErr != nil {
+		return apiErr
+	}
+	relayInfo.OriginModelName = modelName
+	request.SetModelName(modelName)
+
+	// 透传模式直接转发原始请求体，需同步改写其中的 model 字段
+	if storage, err := common.GetBodyStorage(c); err == nil {
+		if body, err := storage.Bytes(); err == nil && gjson.GetBytes(body, "model").Exists() {
+			if body, err = sjson.SetBytes(body, "model", modelName); err == nil {
+				_ = common.ReplaceBodyStorage(c, body)
+			}
+		}
+	}
+
+	newPriceData, err := helper.ModelPriceHelper(c, relayInfo, tokens, meta)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeModelPriceError, types.ErrOptionWithStatusCode(http.StatusBadRequest))
+	}
+	*priceData = newPriceData
+	return nil
+}
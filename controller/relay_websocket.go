@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/sjson"
+)
+
+// ChatWebSocketSubprotocol 客户端通过 Sec-WebSocket-Protocol 传递密钥时需同时声明该协议
+const ChatWebSocketSubprotocol = "chat-completions"
+
+var chatWebSocketUpgrader = websocket.Upgrader{
+	Subprotocols: []string{ChatWebSocketSubprotocol},
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// RelayChatWebSocket 通过 WebSocket 提供流式 chat completions：客户端每发送一条请求 JSON，服务端以文本消息逐条返回
+// 与 SSE 相同的 chunk，最后发送 [DONE]；出错时返回错误 JSON。请求交由同一个 engine 处理，鉴权、分发与计费与 HTTP 完全一致
+func RelayChatWebSocket(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conn, err := chatWebSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			logger.LogError(c, "chat websocket upgrade failed: "+err.Error())
+			return
+		}
+		defer conn.Close()
+		maxMB := constant.MaxRequestBodyMB
+		if maxMB <= 0 {
+			maxMB = 128
+		}
+		conn.SetReadLimit(int64(maxMB) << 20)
+
+		for {
+			messageType, body, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType != websocket.TextMessage {
+				continue
+			}
+			if body, err = sjson.SetBytes(body, "stream", true); err != nil {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"error":{"message":"invalid request body","type":"invalid_request_error","param":null,"code":null}}`))
+				continue
+			}
+
+			req := c.Request.Clone(c.Request.Context())
+			req.Method = http.MethodPost
+			req.URL.Path = "/v1/chat/completions"
+			req.URL.RawPath = ""
+			req.URL.RawQuery = ""
+			req.RequestURI = req.URL.Path
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "text/event-stream")
+			for key := range req.Header {
+				if strings.HasPrefix(key, "Sec-Websocket-") {
+					req.Header.Del(key)
+				}
+			}
+			req.Header.Del("Upgrade")
+			req.Header.Del("Connection")
+
+			writer := &wsStreamWriter{header: make(http.Header), conn: conn}
+			engine.ServeHTTP(writer, req)
+			if err = writer.finish(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsStreamWriter 将内部 HTTP 响应中的 SSE data 事件逐条转发为 WebSocket 文本消息，非流式响应（如错误）整体作为一条消息
+type wsStreamWriter struct {
+	header http.Header
+	status int
+	conn   *websocket.Conn
+	buf    bytes.Buffer
+	err    error
+}
+
+func (w *wsStreamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *wsStreamWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *wsStreamWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.buf.Write(p)
+	if w.isEventStream() {
+		w.flushEvents(false)
+	}
+	return len(p), w.err
+}
+
+func (w *wsStreamWriter) Flush() {
+	if w.isEventStream() {
+		w.flushEvents(false)
+	}
+}
+
+func (w *wsStreamWriter) isEventStream() bool {
+	return w.status == http.StatusOK && strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+}
+
+// flushEvents 转发缓冲区中完整的 data 行，final 为 true 时同时处理末尾不完整的行；客户端断开后返回写入错误以终止上游读取
+func (w *wsStreamWriter) flushEvents(final bool) {
+	data := w.buf.Bytes()
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if final {
+		end = len(data)
+	}
+	if end == 0 {
+		return
+	}
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(line[len("data:"):])
+		if len(payload) == 0 || w.err != nil {
+			continue
+		}
+		w.err = w.conn.WriteMessage(websocket.TextMessage, payload)
+	}
+	rest := append([]byte(nil), data[end:]...)
+	w.buf.Reset()
+	w.buf.Write(rest)
+}
+
+func (w *wsStreamWriter) finish() error {
+	if w.isEventStream() {
+		w.flushEvents(true)
+		return w.err
+	}
+	if w.err != nil || w.buf.Len() == 0 {
+		return w.err
+	}
+	return w.conn.WriteMessage(websocket.TextMessage, bytes.TrimSpace(w.buf.Bytes()))
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestRelayChatWebSocketForwardsStreamChunks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		require.Equal(t, "Bearer sk-test", c.GetHeader("Authorization"))
+		require.Empty(t, c.GetHeader("Upgrade"))
+		body, _ := io.ReadAll(c.Request.Body)
+		if gjson.GetBytes(body, "model").String() == "missing" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": gin.H{"message": "no channel"}})
+			return
+		}
+		require.True(t, gjson.GetBytes(body, "stream").Bool())
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 2; i++ {
+			fmt.Fprintf(c.Writer, "data: {\"id\":\"chunk-%d\"}\n\n", i)
+			c.Writer.Flush()
+		}
+		fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	})
+	engine.GET("/v1/chat/completions/ws", RelayChatWebSocket(engine))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	header := http.Header{"Authorization": []string{"Bearer sk-test"}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/chat/completions/ws", header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"model":"gpt-4o","messages":[]}`)))
+	for _, want := range []string{`{"id":"chunk-0"}`, `{"id":"chunk-1"}`, `[DONE]`} {
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, want, string(message))
+	}
+
+	// 同一连接上的后续请求，错误以 JSON 消息返回
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"model":"missing","messages":[]}`)))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, "no channel", gjson.GetBytes(message, "error.message").String())
+}
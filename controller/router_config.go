@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportRouterConfig 导出当前令牌可用模型的客户端路由配置（LiteLLM model_list 或通用 YAML），
+// 用于混合部署时快速生成客户端配置；base_url 参数可覆盖默认的服务器地址
+func ExportRouterConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", service.RouterConfigFormatLiteLLM)
+	if format != service.RouterConfigFormatLiteLLM && format != service.RouterConfigFormatGeneric {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": types.OpenAIError{
+				Message: "unsupported format: " + format,
+				Type:    "invalid_request_error",
+				Code:    "invalid_format",
+			},
+		})
+		return
+	}
+	models, err := getTokenAvailableModels(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	baseUrl := c.DefaultQuery("base_url", system_setting.ServerAddress)
+	apiKey := "sk-" + c.GetString("token_key")
+	data, err := service.BuildRouterConfig(format, baseUrl, apiKey, models, model.GetSupportedEndpointMap())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
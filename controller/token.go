@@ -164,51 +164,59 @@ func GetTokenUsage(c *gin.Context) {
 	})
 }
 
-func AddToken(c *gin.Context) {
-	token := model.Token{}
-	err := c.ShouldBindJSON(&token)
-	if err != nil {
-		common.ApiError(c, err)
-		return
-	}
+// validateTokenRequest 校验令牌名称长度与额度范围，失败时已写入错误响应
+func validateTokenRequest(c *gin.Context, token *model.Token) bool {
 	if len(token.Name) > 50 {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
-		return
+		return false
+	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.DailyQuotaLimit < 0 || token.MonthlyBudgetQuota < 0 {
+		common.ApiErrorMsg(c, "令牌限流配置不能为负数")
+		return false
+	}
+	if token.SemanticCacheThreshold < 0 || token.SemanticCacheThreshold > 1 {
+		common.ApiErrorMsg(c, "语义缓存相似度阈值必须在 0 到 1 之间")
+		return false
 	}
 	// 非无限额度时，检查额度值是否超出有效范围
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
-			return
+			return false
 		}
 		maxQuotaValue := int((1000000000 * common.QuotaPerUnit))
 		if token.RemainQuota > maxQuotaValue {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaExceedMax, map[string]any{"Max": maxQuotaValue})
-			return
+			return false
 		}
 	}
+	return true
+}
+
+// createUserToken 检查令牌数量上限并生成密钥创建令牌，失败时已写入错误响应
+func createUserToken(c *gin.Context, userId int, token *model.Token) (*model.Token, bool) {
 	// 检查用户令牌数量是否已达上限
 	maxTokens := operation_setting.GetMaxUserTokens()
-	count, err := model.CountUserTokens(c.GetInt("id"))
+	count, err := model.CountUserTokens(userId)
 	if err != nil {
 		common.ApiError(c, err)
-		return
+		return nil, false
 	}
 	if int(count) >= maxTokens {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": fmt.Sprintf("已达到最大令牌数量限制 (%d)", maxTokens),
 		})
-		return
+		return nil, false
 	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
 		common.SysLog("failed to generate token key: " + err.Error())
-		return
+		return nil, false
 	}
-	cleanToken := model.Token{
-		UserId:             c.GetInt("id"),
+	cleanToken := &model.Token{
+		UserId:             userId,
 		Name:               token.Name,
 		Key:                key,
 		CreatedTime:        common.GetTimestamp(),
@@ -221,12 +229,34 @@ func AddToken(c *gin.Context) {
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
+		NoStore:            token.NoStore,
+		RpmLimit:           token.RpmLimit,
+		TpmLimit:           token.TpmLimit,
+		DailyQuotaLimit:    token.DailyQuotaLimit,
+		MonthlyBudgetQuota: token.MonthlyBudgetQuota,
+
+		SemanticCacheThreshold: token.SemanticCacheThreshold,
+	}
+	if err := cleanToken.Insert(); err != nil {
+		common.ApiError(c, err)
+		return nil, false
 	}
-	err = cleanToken.Insert()
+	return cleanToken, true
+}
+
+func AddToken(c *gin.Context) {
+	token := model.Token{}
+	err := c.ShouldBindJSON(&token)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	if !validateTokenRequest(c, &token) {
+		return
+	}
+	if _, ok := createUserToken(c, c.GetInt("id"), &token); !ok {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -256,21 +286,9 @@ func UpdateToken(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	if len(token.Name) > 50 {
-		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
+	if !validateTokenRequest(c, &token) {
 		return
 	}
-	if !token.UnlimitedQuota {
-		if token.RemainQuota < 0 {
-			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
-			return
-		}
-		maxQuotaValue := int((1000000000 * common.QuotaPerUnit))
-		if token.RemainQuota > maxQuotaValue {
-			common.ApiErrorI18n(c, i18n.MsgTokenQuotaExceedMax, map[string]any{"Max": maxQuotaValue})
-			return
-		}
-	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		common.ApiError(c, err)
@@ -299,6 +317,12 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.NoStore = token.NoStore
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.DailyQuotaLimit = token.DailyQuotaLimit
+		cleanToken.MonthlyBudgetQuota = token.MonthlyBudgetQuota
+		cleanToken.SemanticCacheThreshold = token.SemanticCacheThreshold
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -312,6 +336,69 @@ func UpdateToken(c *gin.Context) {
 	})
 }
 
+// UpsertToken 以令牌名称为标识幂等地创建或更新当前用户的令牌，存在多个同名令牌时拒绝。
+// 返回脱敏后的令牌，完整密钥仍需通过 POST /api/token/:id/key 获取。
+func UpsertToken(c *gin.Context) {
+	userId := c.GetInt("id")
+	token := model.Token{}
+	if err := c.ShouldBindJSON(&token); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		common.ApiErrorI18n(c, i18n.MsgNameCannotBeEmpty)
+		return
+	}
+	if !validateTokenRequest(c, &token) {
+		return
+	}
+	existing, err := model.GetUserTokensByName(userId, token.Name)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(existing) > 1 {
+		common.ApiErrorI18n(c, i18n.MsgNameAmbiguous, map[string]any{"Count": len(existing), "Name": token.Name})
+		return
+	}
+	if len(existing) == 1 {
+		cleanToken := existing[0]
+		cleanToken.ExpiredTime = token.ExpiredTime
+		cleanToken.RemainQuota = token.RemainQuota
+		cleanToken.UnlimitedQuota = token.UnlimitedQuota
+		cleanToken.ModelLimitsEnabled = token.ModelLimitsEnabled
+		cleanToken.ModelLimits = token.ModelLimits
+		cleanToken.AllowIps = token.AllowIps
+		cleanToken.Group = token.Group
+		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.NoStore = token.NoStore
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.DailyQuotaLimit = token.DailyQuotaLimit
+		cleanToken.MonthlyBudgetQuota = token.MonthlyBudgetQuota
+		cleanToken.SemanticCacheThreshold = token.SemanticCacheThreshold
+		if err := cleanToken.Update(); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, gin.H{
+			"created": false,
+			"token":   buildMaskedTokenResponse(cleanToken),
+		})
+		return
+	}
+
+	cleanToken, ok := createUserToken(c, userId, &token)
+	if !ok {
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"created": true,
+		"token":   buildMaskedTokenResponse(cleanToken),
+	})
+}
+
 type TokenBatch struct {
 	Ids []int `json:"ids"`
 }
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	trafficstream "github.com/QuantumNous/new-api/pkg/traffic_stream"
+
+	"github.com/gin-gonic/gin"
+)
+
+const trafficStreamHeartbeat = 15 * time.Second
+
+type trafficStreamFilter struct {
+	model      string
+	channelId  int
+	userId     int
+	onlyErrors bool
+}
+
+func (f trafficStreamFilter) match(event trafficstream.Event) bool {
+	if f.model != "" && event.Model != f.model {
+		return false
+	}
+	if f.channelId != 0 && event.ChannelId != f.channelId {
+		return false
+	}
+	if f.userId != 0 && event.UserId != f.userId {
+		return false
+	}
+	if f.onlyErrors && event.Success {
+		return false
+	}
+	return true
+}
+
+// StreamTraffic 管理员通过 SSE 实时查看请求事件，可按 model、channel_id、user_id 筛选，only_errors=true 时只推送失败请求
+func StreamTraffic(c *gin.Context) {
+	filter := trafficStreamFilter{
+		model:      c.Query("model"),
+		onlyErrors: c.Query("only_errors") == "true",
+	}
+	filter.channelId, _ = strconv.Atoi(c.Query("channel_id"))
+	filter.userId, _ = strconv.Atoi(c.Query("user_id"))
+
+	sub := trafficstream.Subscribe()
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(trafficStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if !filter.match(event) {
+				continue
+			}
+			data, err := common.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: request\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
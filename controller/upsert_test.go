@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type upsertChannelResponse struct {
+	Created bool          `json:"created"`
+	Channel model.Channel `json:"channel"`
+}
+
+type upsertTokenResponse struct {
+	Created bool              `json:"created"`
+	Token   tokenResponseItem `json:"token"`
+}
+
+func setupUpsertChannelTestDB(t *testing.T) *gorm.DB {
+	db := openTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Channel{}, &model.Ability{}))
+	return db
+}
+
+func callUpsertChannel(t *testing.T, body any) (tokenAPIResponse, upsertChannelResponse) {
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPut, "/api/channel/upsert", body, 1)
+	UpsertChannel(ctx)
+	response := decodeAPIResponse(t, recorder)
+	var data upsertChannelResponse
+	if response.Success {
+		require.NoError(t, common.Unmarshal(response.Data, &data))
+	}
+	return response, data
+}
+
+func loadChannel(t *testing.T, db *gorm.DB, id int) model.Channel {
+	var channel model.Channel
+	require.NoError(t, db.First(&channel, id).Error)
+	return channel
+}
+
+func TestUpsertChannelCreatesMultiKeyChannel(t *testing.T) {
+	db := setupUpsertChannelTestDB(t)
+
+	response, data := callUpsertChannel(t, map[string]any{
+		"mode":           "multi_to_single",
+		"multi_key_mode": constant.MultiKeyModePolling,
+		"channel": map[string]any{
+			"name":   " infra-openai ",
+			"type":   constant.ChannelTypeOpenAI,
+			"key":    "sk-a\n sk-b \n\nsk-c",
+			"models": "gpt-4o",
+			"group":  "default",
+		},
+	})
+	require.True(t, response.Success, response.Message)
+	require.True(t, data.Created)
+	require.Empty(t, data.Channel.Key)
+
+	stored := loadChannel(t, db, data.Channel.Id)
+	require.Equal(t, "infra-openai", stored.Name)
+	require.Equal(t, "sk-a\nsk-b\nsk-c", stored.Key)
+	require.True(t, stored.ChannelInfo.IsMultiKey)
+	require.Equal(t, 3, stored.ChannelInfo.MultiKeySize)
+	require.Equal(t, constant.MultiKeyModePolling, stored.ChannelInfo.MultiKeyMode)
+
+	var abilities int64
+	require.NoError(t, db.Model(&model.Ability{}).Where("channel_id = ?", stored.Id).Count(&abilities).Error)
+	require.EqualValues(t, 1, abilities)
+}
+
+func TestUpsertChannelCreatesVertexJsonKeys(t *testing.T) {
+	db := setupUpsertChannelTestDB(t)
+
+	response, data := callUpsertChannel(t, map[string]any{
+		"mode": "multi_to_single",
+		"channel": map[string]any{
+			"name":  "vertex",
+			"type":  constant.ChannelTypeVertexAi,
+			"key":   `[{"type":"service_account","project_id":"p1"},{"type":"service_account","project_id":"p2"}]`,
+			"other": `{"default":"us-central1"}`,
+		},
+	})
+	require.True(t, response.Success, response.Message)
+
+	stored := loadChannel(t, db, data.Channel.Id)
+	require.Equal(t, 2, stored.ChannelInfo.MultiKeySize)
+	require.Equal(t, `{"project_id":"p1","type":"service_account"}`+"\n"+`{"project_id":"p2","type":"service_account"}`, stored.Key)
+
+	response, _ = callUpsertChannel(t, map[string]any{
+		"mode": "multi_to_single",
+		"channel": map[string]any{
+			"name":  "vertex-bad",
+			"type":  constant.ChannelTypeVertexAi,
+			"key":   `not-json`,
+			"other": `{"default":"us-central1"}`,
+		},
+	})
+	require.False(t, response.Success)
+}
+
+func TestUpsertChannelUpdateWritesZeroValues(t *testing.T) {
+	db := setupUpsertChannelTestDB(t)
+	existing := &model.Channel{
+		Name:     "infra",
+		Type:     constant.ChannelTypeOpenAI,
+		Key:      "sk-original",
+		Status:   common.ChannelStatusEnabled,
+		Models:   "gpt-4o,gpt-4o-mini",
+		Group:    "default",
+		Priority: lo.ToPtr(int64(5)),
+		Weight:   lo.ToPtr(uint(10)),
+		Tag:      lo.ToPtr("prod"),
+	}
+	require.NoError(t, existing.Insert())
+
+	response, data := callUpsertChannel(t, map[string]any{
+		"channel": map[string]any{
+			"name":     "infra",
+			"type":     constant.ChannelTypeOpenAI,
+			"status":   common.ChannelStatusManuallyDisabled,
+			"models":   "gpt-4o",
+			"group":    "default",
+			"priority": 0,
+			"weight":   0,
+		},
+	})
+	require.True(t, response.Success, response.Message)
+	require.False(t, data.Created)
+	require.Equal(t, existing.Id, data.Channel.Id)
+
+	stored := loadChannel(t, db, existing.Id)
+	require.Equal(t, "sk-original", stored.Key)
+	require.Equal(t, common.ChannelStatusManuallyDisabled, stored.Status)
+	require.Equal(t, "gpt-4o", stored.Models)
+	require.EqualValues(t, 0, *stored.Priority)
+	require.EqualValues(t, 0, *stored.Weight)
+	require.Nil(t, stored.Tag)
+
+	var abilities int64
+	require.NoError(t, db.Model(&model.Ability{}).Where("channel_id = ?", existing.Id).Count(&abilities).Error)
+	require.EqualValues(t, 1, abilities)
+}
+
+func TestUpsertChannelRejectsBatchAndAmbiguousName(t *testing.T) {
+	setupUpsertChannelTestDB(t)
+
+	response, _ := callUpsertChannel(t, map[string]any{
+		"mode":    "batch",
+		"channel": map[string]any{"name": "dup", "type": constant.ChannelTypeOpenAI, "key": "a\nb"},
+	})
+	require.False(t, response.Success)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, (&model.Channel{Name: "dup", Type: constant.ChannelTypeOpenAI, Key: "k", Group: "default"}).Insert())
+	}
+	response, _ = callUpsertChannel(t, map[string]any{
+		"channel": map[string]any{"name": "dup", "type": constant.ChannelTypeOpenAI, "key": "k"},
+	})
+	require.False(t, response.Success)
+}
+
+func callUpsertToken(t *testing.T, body any, userID int) (tokenAPIResponse, upsertTokenResponse) {
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPut, "/api/token/upsert", body, userID)
+	UpsertToken(ctx)
+	response := decodeAPIResponse(t, recorder)
+	var data upsertTokenResponse
+	if response.Success {
+		require.NoError(t, common.Unmarshal(response.Data, &data))
+	}
+	return response, data
+}
+
+func TestUpsertTokenCreatesThenUpdates(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+
+	response, created := callUpsertToken(t, map[string]any{
+		"name":            "ci",
+		"remain_quota":    100,
+		"unlimited_quota": false,
+		"expired_time":    -1,
+	}, 1)
+	require.True(t, response.Success, response.Message)
+	require.True(t, created.Created)
+
+	var stored model.Token
+	require.NoError(t, db.First(&stored, created.Token.ID).Error)
+	require.NotEmpty(t, stored.Key)
+	require.NotEqual(t, stored.Key, created.Token.Key)
+	require.Equal(t, 100, stored.RemainQuota)
+
+	response, updated := callUpsertToken(t, map[string]any{
+		"name":            "ci",
+		"remain_quota":    0,
+		"unlimited_quota": true,
+		"expired_time":    -1,
+	}, 1)
+	require.True(t, response.Success, response.Message)
+	require.False(t, updated.Created)
+	require.Equal(t, created.Token.ID, updated.Token.ID)
+
+	require.NoError(t, db.First(&stored, created.Token.ID).Error)
+	require.Equal(t, 0, stored.RemainQuota)
+	require.True(t, stored.UnlimitedQuota)
+
+	// 其他用户的同名令牌互不影响
+	response, other := callUpsertToken(t, map[string]any{"name": "ci", "unlimited_quota": true}, 2)
+	require.True(t, response.Success, response.Message)
+	require.True(t, other.Created)
+	require.NotEqual(t, created.Token.ID, other.Token.ID)
+}
+
+func TestUpsertTokenValidatesQuota(t *testing.T) {
+	setupTokenControllerTestDB(t)
+
+	response, _ := callUpsertToken(t, map[string]any{"name": "ci", "remain_quota": -1}, 1)
+	require.False(t, response.Success)
+}
@@ -14,11 +14,14 @@ import (
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
 
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -91,6 +94,10 @@ func Login(c *gin.Context) {
 
 // setup session & cookies and then return user info
 func setupLogin(user *model.User, c *gin.Context) {
+	if user.Role >= common.RoleAdminUser && !middleware.IsAdminAccessAllowed(c) {
+		common.ApiErrorI18n(c, i18n.MsgAuthAdminIpNotAllowed)
+		return
+	}
 	model.UpdateUserLastLoginAt(user.Id)
 	session := sessions.Default(c)
 	session.Set("id", user.Id)
@@ -185,6 +192,9 @@ func Register(c *gin.Context) {
 	if common.EmailVerificationEnabled {
 		cleanUser.Email = user.Email
 	}
+	if tenant := system_setting.GetTenantByHost(c.Request.Host); tenant != nil && tenant.Group != "" {
+		cleanUser.Group = tenant.Group
+	}
 	if err := cleanUser.Insert(inviterId); err != nil {
 		common.ApiError(c, err)
 		return
@@ -196,6 +206,7 @@ func Register(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgUserRegisterFailed)
 		return
 	}
+	emitUserRegisteredEvent(&insertedUser, "password")
 	// 生成默认令牌
 	if constant.GenerateDefaultToken {
 		key, err := common.GenerateKey()
@@ -232,6 +243,17 @@ func Register(c *gin.Context) {
 	return
 }
 
+// emitUserRegisteredEvent 触发用户注册事件 webhook，source 为注册方式（password、github 等）
+func emitUserRegisteredEvent(user *model.User, source string) {
+	service.EmitEvent(service.EventUserRegistered, map[string]any{
+		"user_id":    user.Id,
+		"username":   user.Username,
+		"email":      user.Email,
+		"inviter_id": user.InviterId,
+		"source":     source,
+	})
+}
+
 func GetAllUsers(c *gin.Context) {
 	pageInfo := common.GetPageQuery(c)
 	users, total, err := model.GetAllUsers(pageInfo)
@@ -623,6 +645,55 @@ func AdminClearUserBinding(c *gin.Context) {
 	})
 }
 
+type updateUserSafetyLevelRequest struct {
+	SafetyLevel string `json:"safety_level"`
+}
+
+// UpdateUserSafetyLevel 设置用户的安全等级，空字符串表示跟随分组/默认等级
+func UpdateUserSafetyLevel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	var req updateUserSafetyLevelRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if err := moderation_setting.ValidateSafetyLevel(req.SafetyLevel); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	user, err := model.GetUserById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	myRole := c.GetInt("role")
+	if myRole <= user.Role && myRole != common.RoleRootUser {
+		common.ApiErrorI18n(c, i18n.MsgUserNoPermissionSameLevel)
+		return
+	}
+
+	setting := user.GetSetting()
+	setting.SafetyLevel = req.SafetyLevel
+	user.SetSetting(setting)
+	if err := user.Update(false); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	model.RecordLog(user.Id, model.LogTypeManage, fmt.Sprintf("admin set safety level of user %s to %q", user.Username, req.SafetyLevel))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "success",
+	})
+}
+
 func UpdateSelf(c *gin.Context) {
 	var requestData map[string]interface{}
 	err := json.NewDecoder(c.Request.Body).Decode(&requestData)
@@ -1120,6 +1191,11 @@ type UpdateUserSettingRequest struct {
 	GotifyUrl                        string  `json:"gotify_url,omitempty"`
 	GotifyToken                      string  `json:"gotify_token,omitempty"`
 	GotifyPriority                   int     `json:"gotify_priority,omitempty"`
+	TelegramBotToken                 string  `json:"telegram_bot_token,omitempty"`
+	TelegramChatId                   string  `json:"telegram_chat_id,omitempty"`
+	SlackWebhookUrl                  string  `json:"slack_webhook_url,omitempty"`
+	MonthlyBudgetQuota               int     `json:"monthly_budget_quota,omitempty"`
+	BudgetAlertThresholds            []int   `json:"budget_alert_thresholds,omitempty"`
 	UpstreamModelUpdateNotifyEnabled *bool   `json:"upstream_model_update_notify_enabled,omitempty"`
 	AcceptUnsetModelRatioModel       bool    `json:"accept_unset_model_ratio_model"`
 	RecordIpLog                      bool    `json:"record_ip_log"`
@@ -1133,7 +1209,8 @@ func UpdateUserSetting(c *gin.Context) {
 	}
 
 	// 验证预警类型
-	if req.QuotaWarningType != dto.NotifyTypeEmail && req.QuotaWarningType != dto.NotifyTypeWebhook && req.QuotaWarningType != dto.NotifyTypeBark && req.QuotaWarningType != dto.NotifyTypeGotify {
+	if req.QuotaWarningType != dto.NotifyTypeEmail && req.QuotaWarningType != dto.NotifyTypeWebhook && req.QuotaWarningType != dto.NotifyTypeBark && req.QuotaWarningType != dto.NotifyTypeGotify &&
+		req.QuotaWarningType != dto.NotifyTypeTelegram && req.QuotaWarningType != dto.NotifyTypeSlack {
 		common.ApiErrorI18n(c, i18n.MsgSettingInvalidType)
 		return
 	}
@@ -1206,6 +1283,34 @@ func UpdateUserSetting(c *gin.Context) {
 		}
 	}
 
+	// 如果是Telegram类型，验证机器人令牌和会话ID
+	if req.QuotaWarningType == dto.NotifyTypeTelegram {
+		if strings.TrimSpace(req.TelegramBotToken) == "" || strings.TrimSpace(req.TelegramChatId) == "" {
+			common.ApiErrorI18n(c, i18n.MsgSettingTelegramEmpty)
+			return
+		}
+	}
+
+	// 如果是Slack类型，验证Incoming Webhook地址
+	if req.QuotaWarningType == dto.NotifyTypeSlack {
+		if _, err := url.ParseRequestURI(req.SlackWebhookUrl); err != nil || !strings.HasPrefix(req.SlackWebhookUrl, "https://") {
+			common.ApiErrorI18n(c, i18n.MsgSettingSlackUrlInvalid)
+			return
+		}
+	}
+
+	// 验证预算与提醒阈值
+	if req.MonthlyBudgetQuota < 0 {
+		common.ApiErrorI18n(c, i18n.MsgSettingBudgetInvalid)
+		return
+	}
+	for _, threshold := range req.BudgetAlertThresholds {
+		if threshold <= 0 || threshold > 100 {
+			common.ApiErrorI18n(c, i18n.MsgSettingBudgetInvalid)
+			return
+		}
+	}
+
 	userId := c.GetInt("id")
 	user, err := model.GetUserById(userId, true)
 	if err != nil {
@@ -1225,6 +1330,9 @@ func UpdateUserSetting(c *gin.Context) {
 		UpstreamModelUpdateNotifyEnabled: upstreamModelUpdateNotifyEnabled,
 		AcceptUnsetRatioModel:            req.AcceptUnsetModelRatioModel,
 		RecordIpLog:                      req.RecordIpLog,
+		SafetyLevel:                      existingSettings.SafetyLevel,
+		MonthlyBudgetQuota:               req.MonthlyBudgetQuota,
+		BudgetAlertThresholds:            req.BudgetAlertThresholds,
 	}
 
 	// 如果是webhook类型,添加webhook相关设置
@@ -1257,6 +1365,17 @@ func UpdateUserSetting(c *gin.Context) {
 		}
 	}
 
+	// 如果是Telegram类型，添加Telegram配置到设置中
+	if req.QuotaWarningType == dto.NotifyTypeTelegram {
+		settings.TelegramBotToken = strings.TrimSpace(req.TelegramBotToken)
+		settings.TelegramChatId = strings.TrimSpace(req.TelegramChatId)
+	}
+
+	// 如果是Slack类型，添加Slack Webhook地址到设置中
+	if req.QuotaWarningType == dto.NotifyTypeSlack {
+		settings.SlackWebhookUrl = req.SlackWebhookUrl
+	}
+
 	// 更新用户设置
 	user.SetSetting(settings)
 	if err := user.Update(false); err != nil {
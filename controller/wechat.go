@@ -103,6 +103,7 @@ func WeChatAuth(c *gin.Context) {
 				})
 				return
 			}
+			emitUserRegisteredEvent(&user, "wechat")
 		} else {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
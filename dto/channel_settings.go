@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"errors"
+	"fmt"
+)
+
 type ChannelSettings struct {
 	ForceFormat            bool   `json:"force_format,omitempty"`
 	ThinkingToContent      bool   `json:"thinking_to_content,omitempty"`
@@ -7,6 +12,10 @@ type ChannelSettings struct {
 	PassThroughBodyEnabled bool   `json:"pass_through_body_enabled,omitempty"`
 	SystemPrompt           string `json:"system_prompt,omitempty"`
 	SystemPromptOverride   bool   `json:"system_prompt_override,omitempty"`
+	// 上游 TLS 配置（PEM 格式）：客户端证书与私钥用于双向 TLS 认证，CA 证书用于校验自签名或企业内部签发的上游证书
+	TLSClientCert string `json:"tls_client_cert,omitempty"`
+	TLSClientKey  string `json:"tls_client_key,omitempty"`
+	TLSCACert     string `json:"tls_ca_cert,omitempty"`
 }
 
 type VertexKeyType string
@@ -23,24 +32,123 @@ const (
 	AwsKeyTypeApiKey AwsKeyType = "api_key"
 )
 
+// AzureDeployment Azure 渠道中模型对应的部署，ApiVersion 为空时使用渠道的 API 版本
+type AzureDeployment struct {
+	Name       string `json:"name"`
+	ApiVersion string `json:"api_version,omitempty"`
+}
+
 type ChannelOtherSettings struct {
-	AzureResponsesVersion                 string        `json:"azure_responses_version,omitempty"`
-	VertexKeyType                         VertexKeyType `json:"vertex_key_type,omitempty"` // "json" or "api_key"
-	OpenRouterEnterprise                  *bool         `json:"openrouter_enterprise,omitempty"`
-	ClaudeBetaQuery                       bool          `json:"claude_beta_query,omitempty"`         // Claude 渠道是否强制追加 ?beta=true
-	AllowServiceTier                      bool          `json:"allow_service_tier,omitempty"`        // 是否允许 service_tier 透传（默认过滤以避免额外计费）
-	AllowInferenceGeo                     bool          `json:"allow_inference_geo,omitempty"`       // 是否允许 inference_geo 透传（仅 Claude，默认过滤以满足数据驻留合规
-	AllowSpeed                            bool          `json:"allow_speed,omitempty"`               // 是否允许 speed 透传（仅 Claude，默认过滤以避免意外切换推理速度模式）
-	AllowSafetyIdentifier                 bool          `json:"allow_safety_identifier,omitempty"`   // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
-	DisableStore                          bool          `json:"disable_store,omitempty"`             // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
-	AllowIncludeObfuscation               bool          `json:"allow_include_obfuscation,omitempty"` // 是否允许 stream_options.include_obfuscation 透传（默认过滤以避免关闭流混淆保护）
-	AwsKeyType                            AwsKeyType    `json:"aws_key_type,omitempty"`
-	UpstreamModelUpdateCheckEnabled       bool          `json:"upstream_model_update_check_enabled,omitempty"`        // 是否检测上游模型更新
-	UpstreamModelUpdateAutoSyncEnabled    bool          `json:"upstream_model_update_auto_sync_enabled,omitempty"`    // 是否自动同步上游模型更新
-	UpstreamModelUpdateLastCheckTime      int64         `json:"upstream_model_update_last_check_time,omitempty"`      // 上次检测时间
-	UpstreamModelUpdateLastDetectedModels []string      `json:"upstream_model_update_last_detected_models,omitempty"` // 上次检测到的可加入模型
-	UpstreamModelUpdateLastRemovedModels  []string      `json:"upstream_model_update_last_removed_models,omitempty"`  // 上次检测到的可删除模型
-	UpstreamModelUpdateIgnoredModels      []string      `json:"upstream_model_update_ignored_models,omitempty"`       // 手动忽略的模型
+	AzureResponsesVersion                 string                     `json:"azure_responses_version,omitempty"`
+	AzureDeployments                      map[string]AzureDeployment `json:"azure_deployments,omitempty"` // Azure 模型名到部署的映射，未配置的模型以模型名作为部署名
+	VertexKeyType                         VertexKeyType              `json:"vertex_key_type,omitempty"`   // "json" or "api_key"
+	OpenRouterEnterprise                  *bool                      `json:"openrouter_enterprise,omitempty"`
+	ClaudeBetaQuery                       bool                       `json:"claude_beta_query,omitempty"`         // Claude 渠道是否强制追加 ?beta=true
+	AllowServiceTier                      bool                       `json:"allow_service_tier,omitempty"`        // 是否允许 service_tier 透传（默认过滤以避免额外计费）
+	AllowInferenceGeo                     bool                       `json:"allow_inference_geo,omitempty"`       // 是否允许 inference_geo 透传（仅 Claude，默认过滤以满足数据驻留合规
+	AllowSpeed                            bool                       `json:"allow_speed,omitempty"`               // 是否允许 speed 透传（仅 Claude，默认过滤以避免意外切换推理速度模式）
+	AllowSafetyIdentifier                 bool                       `json:"allow_safety_identifier,omitempty"`   // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
+	DisableStore                          bool                       `json:"disable_store,omitempty"`             // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
+	AllowIncludeObfuscation               bool                       `json:"allow_include_obfuscation,omitempty"` // 是否允许 stream_options.include_obfuscation 透传（默认过滤以避免关闭流混淆保护）
+	AwsKeyType                            AwsKeyType                 `json:"aws_key_type,omitempty"`
+	HuggingFaceUseGenerate                bool                       `json:"huggingface_use_generate,omitempty"`                   // Hugging Face 渠道的对话请求改走 TGI /generate 接口（适用于不支持 Messages API 的旧版 TGI）
+	MaxConcurrency                        int                        `json:"max_concurrency,omitempty"`                            // 渠道同时处理的最大请求数（0 不限制），超出时排队或切换到其他渠道
+	ConcurrencyQueueSeconds               int                        `json:"concurrency_queue_seconds,omitempty"`                  // 并发名额已满时排队等待的最长时间（秒），0 表示直接切换到其他渠道
+	EmbeddingMaxBatchSize                 int                        `json:"embedding_max_batch_size,omitempty"`                   // Embeddings 单次上游请求的最大输入条数，超过时自动拆分（0 使用默认值）
+	UpstreamModelUpdateCheckEnabled       bool                       `json:"upstream_model_update_check_enabled,omitempty"`        // 是否检测上游模型更新
+	UpstreamModelUpdateAutoSyncEnabled    bool                       `json:"upstream_model_update_auto_sync_enabled,omitempty"`    // 是否自动同步上游模型更新
+	UpstreamModelUpdateLastCheckTime      int64                      `json:"upstream_model_update_last_check_time,omitempty"`      // 上次检测时间
+	UpstreamModelUpdateLastDetectedModels []string                   `json:"upstream_model_update_last_detected_models,omitempty"` // 上次检测到的可加入模型
+	UpstreamModelUpdateLastRemovedModels  []string                   `json:"upstream_model_update_last_removed_models,omitempty"`  // 上次检测到的可删除模型
+	UpstreamModelUpdateIgnoredModels      []string                   `json:"upstream_model_update_ignored_models,omitempty"`       // 手动忽略的模型
+
+	UpstreamTimeouts      *UpstreamTimeouts           `json:"upstream_timeouts,omitempty"`       // 渠道级上游超时，未配置的项沿用全局配置
+	ModelUpstreamTimeouts map[string]UpstreamTimeouts `json:"model_upstream_timeouts,omitempty"` // 模型级上游超时，覆盖渠道级配置中的非零项
+}
+
+// UpstreamTimeouts 上游请求的超时配置（秒），0 表示沿用全局配置
+type UpstreamTimeouts struct {
+	ConnectTimeout        int `json:"connect_timeout,omitempty"`         // 建立连接（含 TLS 握手）的超时
+	ResponseHeaderTimeout int `json:"response_header_timeout,omitempty"` // 请求发出后等待响应头的超时
+	StreamIdleTimeout     int `json:"stream_idle_timeout,omitempty"`     // 流式响应中两次收到数据的最长间隔，默认 STREAMING_TIMEOUT
+	TotalTimeout          int `json:"total_timeout,omitempty"`           // 整个请求（含读取响应体）的截止时间，默认 RELAY_TIMEOUT
+}
+
+func (t UpstreamTimeouts) IsZero() bool {
+	return t == UpstreamTimeouts{}
+}
+
+// Validate 超时不能为负数
+func (t UpstreamTimeouts) Validate() error {
+	if t.ConnectTimeout < 0 || t.ResponseHeaderTimeout < 0 || t.StreamIdleTimeout < 0 || t.TotalTimeout < 0 {
+		return errors.New("upstream timeouts must not be negative")
+	}
+	return nil
+}
+
+// merge 用 override 中的非零项覆盖当前配置
+func (t UpstreamTimeouts) merge(override UpstreamTimeouts) UpstreamTimeouts {
+	if override.ConnectTimeout > 0 {
+		t.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.ResponseHeaderTimeout > 0 {
+		t.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+	if override.StreamIdleTimeout > 0 {
+		t.StreamIdleTimeout = override.StreamIdleTimeout
+	}
+	if override.TotalTimeout > 0 {
+		t.TotalTimeout = override.TotalTimeout
+	}
+	return t
+}
+
+// GetUpstreamTimeouts 返回渠道级超时叠加模型级超时后的配置，模型按顺序查找第一个已配置的
+func (s *ChannelOtherSettings) GetUpstreamTimeouts(models ...string) UpstreamTimeouts {
+	var timeouts UpstreamTimeouts
+	if s == nil {
+		return timeouts
+	}
+	if s.UpstreamTimeouts != nil {
+		timeouts = *s.UpstreamTimeouts
+	}
+	for _, model := range models {
+		if override, ok := s.ModelUpstreamTimeouts[model]; ok {
+			return timeouts.merge(override)
+		}
+	}
+	return timeouts
+}
+
+// ValidateUpstreamTimeouts 校验渠道级与模型级超时配置
+func (s *ChannelOtherSettings) ValidateUpstreamTimeouts() error {
+	if s == nil {
+		return nil
+	}
+	if s.UpstreamTimeouts != nil {
+		if err := s.UpstreamTimeouts.Validate(); err != nil {
+			return err
+		}
+	}
+	for model, timeouts := range s.ModelUpstreamTimeouts {
+		if err := timeouts.Validate(); err != nil {
+			return fmt.Errorf("model %s: %w", model, err)
+		}
+	}
+	return nil
+}
+
+// GetAzureDeployment 按顺序查找模型对应的 Azure 部署，返回第一个配置了部署名的映射
+func (s *ChannelOtherSettings) GetAzureDeployment(models ...string) (AzureDeployment, bool) {
+	if s == nil {
+		return AzureDeployment{}, false
+	}
+	for _, model := range models {
+		if deployment, ok := s.AzureDeployments[model]; ok && deployment.Name != "" {
+			return deployment, true
+		}
+	}
+	return AzureDeployment{}, false
 }
 
 func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUpstreamTimeouts(t *testing.T) {
+	var empty *ChannelOtherSettings
+	require.True(t, empty.GetUpstreamTimeouts("gpt-4o").IsZero())
+
+	settings := &ChannelOtherSettings{
+		UpstreamTimeouts: &UpstreamTimeouts{ConnectTimeout: 5, ResponseHeaderTimeout: 30, TotalTimeout: 120},
+		ModelUpstreamTimeouts: map[string]UpstreamTimeouts{
+			"o1": {ResponseHeaderTimeout: 600, TotalTimeout: 1800},
+		},
+	}
+	require.Equal(t, UpstreamTimeouts{ConnectTimeout: 5, ResponseHeaderTimeout: 30, TotalTimeout: 120}, settings.GetUpstreamTimeouts("gpt-4o"))
+	// 模型级配置只覆盖非零项，按顺序匹配第一个已配置的模型
+	require.Equal(t, UpstreamTimeouts{ConnectTimeout: 5, ResponseHeaderTimeout: 600, TotalTimeout: 1800}, settings.GetUpstreamTimeouts("o1-mapped", "o1"))
+
+	require.NoError(t, settings.ValidateUpstreamTimeouts())
+	settings.ModelUpstreamTimeouts["o1"] = UpstreamTimeouts{StreamIdleTimeout: -1}
+	require.Error(t, settings.ValidateUpstreamTimeouts())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// RerankRequest 兼容 Jina 与 Cohere 两种格式的重排序请求，documents 可以是字符串或对象
 type RerankRequest struct {
 	Documents       []any  `json:"documents"`
 	Query           string `json:"query"`
@@ -16,6 +17,10 @@ type RerankRequest struct {
 	ReturnDocuments *bool  `json:"return_documents,omitempty"`
 	MaxChunkPerDoc  *int   `json:"max_chunk_per_doc,omitempty"`
 	OverLapTokens   *int   `json:"overlap_tokens,omitempty"`
+	// Cohere 格式字段
+	RankFields      []string `json:"rank_fields,omitempty"`
+	MaxChunksPerDoc *int     `json:"max_chunks_per_doc,omitempty"`
+	MaxTokensPerDoc *int     `json:"max_tokens_per_doc,omitempty"`
 }
 
 func (r *RerankRequest) IsStream(c *gin.Context) bool {
@@ -10,17 +10,25 @@ type UserSetting struct {
 	GotifyUrl                        string  `json:"gotify_url,omitempty"`                           // GotifyUrl Gotify服务器地址
 	GotifyToken                      string  `json:"gotify_token,omitempty"`                         // GotifyToken Gotify应用令牌
 	GotifyPriority                   int     `json:"gotify_priority"`                                // GotifyPriority Gotify消息优先级
+	TelegramBotToken                 string  `json:"telegram_bot_token,omitempty"`                   // TelegramBotToken Telegram机器人令牌
+	TelegramChatId                   string  `json:"telegram_chat_id,omitempty"`                     // TelegramChatId Telegram会话ID
+	SlackWebhookUrl                  string  `json:"slack_webhook_url,omitempty"`                    // SlackWebhookUrl Slack Incoming Webhook地址
+	MonthlyBudgetQuota               int     `json:"monthly_budget_quota,omitempty"`                 // MonthlyBudgetQuota 每月消费预算，0 表示使用系统默认预算
+	BudgetAlertThresholds            []int   `json:"budget_alert_thresholds,omitempty"`              // BudgetAlertThresholds 预算提醒百分比阈值，为空时使用系统默认
 	UpstreamModelUpdateNotifyEnabled bool    `json:"upstream_model_update_notify_enabled,omitempty"` // 是否接收上游模型更新定时检测通知（仅管理员）
 	AcceptUnsetRatioModel            bool    `json:"accept_unset_model_ratio_model,omitempty"`       // AcceptUnsetRatioModel 是否接受未设置价格的模型
 	RecordIpLog                      bool    `json:"record_ip_log,omitempty"`                        // 是否记录请求和错误日志IP
 	SidebarModules                   string  `json:"sidebar_modules,omitempty"`                      // SidebarModules 左侧边栏模块配置
 	BillingPreference                string  `json:"billing_preference,omitempty"`                   // BillingPreference 扣费策略（订阅/钱包）
 	Language                         string  `json:"language,omitempty"`                             // Language 用户语言偏好 (zh, en)
+	SafetyLevel                      string  `json:"safety_level,omitempty"`                         // SafetyLevel 安全等级（仅管理员可设置）
 }
 
 var (
-	NotifyTypeEmail   = "email"   // Email 邮件
-	NotifyTypeWebhook = "webhook" // Webhook
-	NotifyTypeBark    = "bark"    // Bark 推送
-	NotifyTypeGotify  = "gotify"  // Gotify 推送
+	NotifyTypeEmail    = "email"    // Email 邮件
+	NotifyTypeWebhook  = "webhook"  // Webhook
+	NotifyTypeBark     = "bark"     // Bark 推送
+	NotifyTypeGotify   = "gotify"   // Gotify 推送
+	NotifyTypeTelegram = "telegram" // Telegram 机器人
+	NotifyTypeSlack    = "slack"    // Slack Incoming Webhook
 )
@@ -214,6 +214,11 @@ func normalizeLang(lang string) string {
 	}
 }
 
+// NormalizeLang normalizes a language code to one of the supported languages
+func NormalizeLang(lang string) string {
+	return normalizeLang(lang)
+}
+
 // SupportedLanguages returns a list of supported language codes
 func SupportedLanguages() []string {
 	return []string{LangZhCN, LangZhTW, LangEn}
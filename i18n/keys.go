@@ -26,6 +26,12 @@ const (
 	MsgAlreadyExists     = "common.already_exists"
 	MsgNameCannotBeEmpty = "common.name_cannot_be_empty"
 	MsgBatchTooMany      = "common.batch_too_many"
+	MsgNameAmbiguous     = "common.name_ambiguous"
+)
+
+// Entitlement messages
+const (
+	MsgFeatureNotEntitled = "entitlement.feature_not_entitled"
 )
 
 // Auth middleware messages
@@ -38,6 +44,7 @@ const (
 	MsgAuthUserIdMismatch        = "auth.user_id_mismatch"
 	MsgAuthUserBanned            = "auth.user_banned"
 	MsgAuthInsufficientPrivilege = "auth.insufficient_privilege"
+	MsgAuthAdminIpNotAllowed     = "auth.admin_ip_not_allowed"
 )
 
 // Token related messages
@@ -246,6 +253,9 @@ const (
 	MsgSettingGotifyTokenEmpty = "setting.gotify_token_empty"
 	MsgSettingGotifyUrlInvalid = "setting.gotify_url_invalid"
 	MsgSettingUrlMustHttp      = "setting.url_must_http"
+	MsgSettingTelegramEmpty    = "setting.telegram_empty"
+	MsgSettingSlackUrlInvalid  = "setting.slack_url_invalid"
+	MsgSettingBudgetInvalid    = "setting.budget_invalid"
 	MsgSettingSaved            = "setting.saved"
 )
 
@@ -310,6 +320,8 @@ const (
 	MsgDistributorAffinityChannelDisabled = "distributor.affinity_channel_disabled"
 	MsgDistributorTokenNoModelAccess      = "distributor.token_no_model_access"
 	MsgDistributorTokenModelForbidden     = "distributor.token_model_forbidden"
+	MsgDistributorTenantModelForbidden    = "distributor.tenant_model_forbidden"
+	MsgDistributorOrgModelForbidden       = "distributor.org_model_forbidden"
 	MsgDistributorModelNameRequired       = "distributor.model_name_required"
 	MsgDistributorInvalidPlayground       = "distributor.invalid_playground_request"
 	MsgDistributorGroupAccessDenied       = "distributor.group_access_denied"
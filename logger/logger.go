@@ -94,6 +94,14 @@ func LogDebug(ctx context.Context, msg string, args ...any) {
 	}
 }
 
+// LogDebugBody 输出包含请求或响应内容的调试日志，合规模式（no-store）的请求不输出
+func LogDebugBody(ctx context.Context, msg string, args ...any) {
+	if c, ok := ctx.(*gin.Context); ok && common.IsNoStore(c) {
+		return
+	}
+	LogDebug(ctx, msg, args...)
+}
+
 func logHelper(ctx context.Context, level string, msg string) {
 	id := ctx.Value(common.RequestIdKey)
 	if id == nil {
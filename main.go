@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"log"
@@ -19,7 +21,10 @@ import (
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+	"github.com/QuantumNous/new-api/pkg/listener"
 	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
@@ -31,6 +36,7 @@ import (
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/quic-go/quic-go/http3"
 
 	_ "net/http/pprof"
 )
@@ -113,12 +119,27 @@ func main() {
 
 	go controller.AutomaticallyTestChannels()
 
+	// Scheduled synthetic channel probes
+	controller.StartChannelProbeTask()
+
+	// Exponential-backoff recovery for auto-disabled channels
+	controller.StartChannelRecoveryTask()
+
 	// Codex credential auto-refresh check every 10 minutes, refresh when expires within 1 day
 	service.StartCodexCredentialAutoRefreshTask()
 
 	// Subscription quota reset task (daily/weekly/monthly/custom)
 	service.StartSubscriptionQuotaResetTask()
 
+	// Abuse pattern remote feed sync task
+	service.StartAbusePatternFeedSyncTask()
+
+	// Stripe metered usage reporting task
+	service.StartStripeMeteringTask()
+
+	// Assistants run settlement task
+	service.StartAssistantRunSettlementTask()
+
 	// Wire task polling adaptor factory (breaks service -> relay import cycle)
 	service.GetTaskAdaptorFunc = func(platform constant.TaskPlatform) service.TaskPollingAdaptor {
 		a := relay.GetTaskAdaptor(platform)
@@ -158,6 +179,13 @@ func main() {
 		common.SysError(fmt.Sprintf("start pyroscope error : %v", err))
 	}
 
+	err = tracing.Init(context.Background())
+	if err != nil {
+		common.SysError(fmt.Sprintf("init tracing error : %v", err))
+	} else if tracing.Enabled() {
+		common.SysLog("OpenTelemetry tracing enabled")
+	}
+
 	// Initialize HTTP server
 	server := gin.New()
 	server.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
@@ -172,6 +200,7 @@ func main() {
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
 	server.Use(middleware.RequestId())
+	server.Use(middleware.Tracing())
 	server.Use(middleware.PoweredBy())
 	server.Use(middleware.I18n())
 	middleware.SetUpLogger(server)
@@ -196,6 +225,10 @@ func main() {
 		ClassicBuildFS:   classicBuildFS,
 		ClassicIndexPage: classicIndexPage,
 	})
+
+	// 离线批量推理在进程内经由完整的路由与中间件执行
+	service.SetBatchRelayHandler(server)
+	service.StartBatchInferenceTask()
 	var port = os.Getenv("PORT")
 	if port == "" {
 		port = strconv.Itoa(*common.Port)
@@ -204,12 +237,84 @@ func main() {
 	// Log startup success message
 	common.LogStartupSuccess(startTime, port)
 
-	err = server.Run(":" + port)
+	listenAddrs := constant.ListenAddrs
+	if listenAddrs == "" {
+		listenAddrs = ":" + port
+	}
+	specs, err := listener.ParseList(listenAddrs)
+	if err != nil {
+		common.FatalLog("invalid LISTEN_ADDRS: " + err.Error())
+	}
+
+	tlsConfig, err := listener.NewTLSConfig(listener.TLSOptions{
+		CertFile:         constant.TLSCertFile,
+		KeyFile:          constant.TLSKeyFile,
+		AutocertDomains:  constant.TLSAutocertDomains,
+		AutocertCacheDir: constant.TLSAutocertCacheDir,
+		AutocertEmail:    constant.TLSAutocertEmail,
+	})
+	if err != nil {
+		common.FatalLog("invalid TLS config: " + err.Error())
+	}
+
+	// gRPC 中继接口需要 HTTP/2，明文部署时启用 h2c
+	server.UseH2C = constant.GrpcRelayEnabled
+	if constant.HTTP3Enabled && tlsConfig == nil {
+		common.SysError("HTTP3_ENABLED requires TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAINS, HTTP/3 disabled")
+	}
+	err = serveListeners(server.Handler(), specs, tlsConfig)
 	if err != nil {
 		common.FatalLog("failed to start HTTP server: " + err.Error())
 	}
 }
 
+// serveListeners 在所有监听地址上提供服务，任一监听器出错即返回。
+// 配置了 TLS 时 TCP 地址使用 HTTPS（自动协商 HTTP/2），启用 HTTP3_ENABLED 时同端口 UDP 上提供 HTTP/3；
+// Unix socket 仍为明文，由本机反向代理负责 TLS
+func serveListeners(handler http.Handler, specs []listener.Spec, tlsConfig *tls.Config) error {
+	errCh := make(chan error, 2*len(specs))
+	tlsHandler := handler
+	var h3 *http3.Server
+	if tlsConfig != nil && constant.HTTP3Enabled {
+		h3 = listener.NewHTTP3Server(handler, tlsConfig)
+		tlsHandler = listener.AltSvcHandler(handler, h3)
+	}
+	for _, spec := range specs {
+		ln, err := listener.Listen(spec, os.FileMode(constant.UnixSocketMode))
+		if err != nil {
+			return err
+		}
+		h := handler
+		if spec.IsUnix() {
+			h = listener.UnixSocketHandler(handler)
+		}
+		common.SysLog("listening on " + spec.String())
+		srv := &http.Server{Handler: h}
+		if tlsConfig != nil && !spec.IsUnix() {
+			if h3 != nil {
+				conn, err := listener.ListenPacket(spec)
+				if err != nil {
+					return err
+				}
+				common.SysLog("listening on " + spec.String() + " (HTTP/3)")
+				go func() {
+					errCh <- h3.Serve(conn)
+				}()
+			}
+			srv.Handler = tlsHandler
+			srv.TLSConfig = tlsConfig.Clone()
+			go func() {
+				errCh <- srv.ServeTLS(ln, "", "")
+			}()
+			continue
+		}
+		go func() {
+			errCh <- srv.Serve(ln)
+		}()
+	}
+	return <-errCh
+}
+
 func InjectUmamiAnalytics() {
 	analyticsInjectBuilder := &strings.Builder{}
 	if os.Getenv("UMAMI_WEBSITE_ID") != "" {
@@ -268,6 +373,8 @@ func InitResources() error {
 	// 加载环境变量
 	common.InitEnv()
 
+	entitlement.Init()
+
 	logger.SetupLogger()
 
 	// Initialize model settings
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/listener"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminClientIP resolves the client IP used for admin access checks.
+// The configured header is only trusted when the direct peer is a trusted proxy,
+// so clients cannot spoof their address by sending the header themselves.
+func adminClientIP(c *gin.Context, setting *system_setting.AdminAccessSetting) net.IP {
+	if listener.IsUnixSocketRequest(c.Request) {
+		// Unix socket 没有对端 IP，补上的回环地址不可信；只有显式配置了客户端 IP 请求头时，
+		// 才按本机反向代理传递的地址判断，否则视为无法识别来源
+		if setting.ClientIpHeader == "" {
+			return nil
+		}
+		return forwardedClientIP(c.Request.Header.Get(setting.ClientIpHeader), setting.TrustedProxies, nil)
+	}
+	remoteIp := net.ParseIP(c.RemoteIP())
+	if remoteIp == nil || setting.ClientIpHeader == "" || len(setting.TrustedProxies) == 0 {
+		return remoteIp
+	}
+	if !common.IsIpInCIDRList(remoteIp, setting.TrustedProxies) {
+		return remoteIp
+	}
+	return forwardedClientIP(c.Request.Header.Get(setting.ClientIpHeader), setting.TrustedProxies, remoteIp)
+}
+
+// forwardedClientIP 从可信代理传递的请求头中解析客户端地址，请求头缺失或格式错误时返回 fallback
+func forwardedClientIP(headerValue string, trustedProxies []string, fallback net.IP) net.IP {
+	if headerValue == "" {
+		return fallback
+	}
+	// X-Forwarded-For 形如 "client, proxy1, proxy2"，从右往左取第一个非可信代理地址
+	hops := strings.Split(headerValue, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return fallback
+		}
+		if i == 0 || !common.IsIpInCIDRList(ip, trustedProxies) {
+			return ip
+		}
+	}
+	return fallback
+}
+
+// IsAdminAccessAllowed reports whether the current request may reach admin
+// routes according to admin_access_setting.
+func IsAdminAccessAllowed(c *gin.Context) bool {
+	setting := system_setting.GetAdminAccessSetting()
+	if !setting.Enabled || len(setting.AllowedIps) == 0 {
+		return true
+	}
+	ip := adminClientIP(c, setting)
+	if ip == nil {
+		return false
+	}
+	return common.IsIpInCIDRList(ip, setting.AllowedIps)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/pkg/listener"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminAccessContext(remoteAddr string, headers map[string]string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/option/", nil)
+	c.Request.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		c.Request.Header.Set(key, value)
+	}
+	return c
+}
+
+func TestAdminClientIP(t *testing.T) {
+	setting := &system_setting.AdminAccessSetting{
+		ClientIpHeader: "X-Forwarded-For",
+		TrustedProxies: []string{"10.0.0.0/8", "172.16.0.1"},
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       string
+	}{
+		{"no header", "10.0.0.5:1234", "", "10.0.0.5"},
+		{"trusted proxy single hop", "10.0.0.5:1234", "203.0.113.7", "203.0.113.7"},
+		{"skip trusted hops from the right", "10.0.0.5:1234", "203.0.113.7, 172.16.0.1, 10.1.2.3", "203.0.113.7"},
+		{"spoofed leftmost entry ignored", "10.0.0.5:1234", "192.168.1.10, 198.51.100.4, 10.1.2.3", "198.51.100.4"},
+		{"all hops trusted falls back to leftmost", "10.0.0.5:1234", "10.2.2.2, 10.1.1.1", "10.2.2.2"},
+		{"malformed hop uses peer", "10.0.0.5:1234", "not-an-ip", "10.0.0.5"},
+		{"untrusted peer header ignored", "198.51.100.9:1234", "192.168.1.10", "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.header != "" {
+				headers["X-Forwarded-For"] = tt.header
+			}
+			c := newAdminAccessContext(tt.remoteAddr, headers)
+			require.Equal(t, tt.want, adminClientIP(c, setting).String())
+		})
+	}
+
+	t.Run("header disabled without trusted proxies", func(t *testing.T) {
+		c := newAdminAccessContext("10.0.0.5:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+		require.Equal(t, "10.0.0.5", adminClientIP(c, &system_setting.AdminAccessSetting{ClientIpHeader: "X-Forwarded-For"}).String())
+	})
+}
+
+func TestIsAdminAccessAllowed(t *testing.T) {
+	setting := system_setting.GetAdminAccessSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+
+	*setting = system_setting.AdminAccessSetting{
+		Enabled:        true,
+		AllowedIps:     []string{"192.168.1.0/24", "203.0.113.7"},
+		ClientIpHeader: "X-Forwarded-For",
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       bool
+	}{
+		{"allowed cidr direct", "192.168.1.20:5000", "", true},
+		{"allowed single ip via trusted proxy", "10.0.0.5:5000", "203.0.113.7", true},
+		{"denied direct", "198.51.100.4:5000", "", false},
+		{"denied via trusted proxy", "10.0.0.5:5000", "198.51.100.4", false},
+		{"spoofed header from untrusted peer denied", "198.51.100.4:5000", "192.168.1.20", false},
+		{"spoofed leftmost hop behind proxy denied", "10.0.0.5:5000", "192.168.1.20, 198.51.100.4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.header != "" {
+				headers["X-Forwarded-For"] = tt.header
+			}
+			require.Equal(t, tt.want, IsAdminAccessAllowed(newAdminAccessContext(tt.remoteAddr, headers)))
+		})
+	}
+
+	setting.Enabled = false
+	require.True(t, IsAdminAccessAllowed(newAdminAccessContext("198.51.100.4:5000", nil)))
+}
+
+func TestIsAdminAccessAllowedUnixSocket(t *testing.T) {
+	setting := system_setting.GetAdminAccessSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+
+	*setting = system_setting.AdminAccessSetting{
+		Enabled:    true,
+		AllowedIps: []string{"127.0.0.1", "203.0.113.7"},
+	}
+	unixSocketRequest := func(header string) *gin.Context {
+		var c *gin.Context
+		listener.UnixSocketHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, _ = gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = r
+		})).ServeHTTP(httptest.NewRecorder(), func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/api/option/", nil)
+			req.RemoteAddr = "@"
+			if header != "" {
+				req.Header.Set("X-Forwarded-For", header)
+			}
+			return req
+		}())
+		return c
+	}
+
+	// 补上的回环地址不能命中白名单
+	require.False(t, IsAdminAccessAllowed(unixSocketRequest("")))
+	require.False(t, IsAdminAccessAllowed(unixSocketRequest("203.0.113.7")))
+
+	// 显式配置客户端 IP 请求头后按反向代理传递的地址判断
+	setting.ClientIpHeader = "X-Forwarded-For"
+	require.True(t, IsAdminAccessAllowed(unixSocketRequest("203.0.113.7")))
+	require.False(t, IsAdminAccessAllowed(unixSocketRequest("198.51.100.4")))
+	require.False(t, IsAdminAccessAllowed(unixSocketRequest("")))
+}
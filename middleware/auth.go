@@ -136,6 +136,14 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	if minRole >= common.RoleAdminUser && !IsAdminAccessAllowed(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": common.TranslateMessage(c, i18n.MsgAuthAdminIpNotAllowed),
+		})
+		c.Abort()
+		return
+	}
 	if !validUserInfo(username.(string), role.(int)) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -424,8 +432,14 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	} else {
 		c.Set("token_model_limit_enabled", false)
 	}
+	c.Set("token_rpm_limit", token.RpmLimit)
+	c.Set("token_tpm_limit", token.TpmLimit)
+	c.Set("token_daily_quota_limit", token.DailyQuotaLimit)
+	c.Set("token_monthly_budget_quota", token.MonthlyBudgetQuota)
+	c.Set("token_semantic_cache_threshold", token.SemanticCacheThreshold)
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyNoStore, token.NoStore || common.RequestAsksNoStore(c.Request))
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
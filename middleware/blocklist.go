@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlocklistOutput 对模型输出执行黑名单规则，需在 Distribute 之后使用以获取分组信息。
+// 非流式响应整体改写，命中 reject 规则时返回错误；流式响应逐个分片替换，reject 降级为记录。
+func BlocklistOutput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		if len(moderation_setting.BlocklistRulesFor(group, moderation_setting.StageOutput)) == 0 {
+			c.Next()
+			return
+		}
+		outcome := &service.BlocklistOutcome{}
+		original := c.Writer
+		writer := &moderationWriter{
+			ResponseWriter: original,
+			status:         http.StatusOK,
+			streamTransform: func(data []byte) []byte {
+				return service.ApplyBlocklistSSE(group, data, outcome)
+			},
+		}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if writer.mode == moderationStreaming {
+			_ = service.HandleBlocklistOutcome(c, moderation_setting.StageOutput, outcome, false)
+			return
+		}
+		if writer.finishPassthrough(original) {
+			return
+		}
+		body := writer.buffer.Bytes()
+		if rewritten, changed, err := service.ApplyBlocklistJSON(group, moderation_setting.StageOutput, body, outcome); err == nil && changed {
+			body = rewritten
+		}
+		if apiErr := service.HandleBlocklistOutcome(c, moderation_setting.StageOutput, outcome, true); apiErr != nil {
+			service.WriteOpenAIError(c, apiErr.StatusCode, apiErr.ToOpenAIError())
+			return
+		}
+		original.Header().Del("Content-Length")
+		original.WriteHeader(writer.status)
+		_, _ = original.Write(body)
+	}
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingZstd   = "zstd"
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+
+	responseCompressionDefaultMinBytes = 1024
+)
+
+// 服务端偏好顺序：压缩率与速度综合 zstd 最优，gzip 兼容性最好
+var responseEncodings = []string{encodingZstd, encodingBrotli, encodingGzip}
+
+// 仅压缩文本类响应，音频、图片等已压缩内容原样返回
+var compressibleContentTypes = []string{
+	"application/json",
+	"application/x-ndjson",
+	"application/jsonl",
+	"application/xml",
+	"text/plain",
+	"text/html",
+	"text/csv",
+}
+
+var (
+	gzipWriterPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriterPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 4)
+	}}
+	zstdWriterPool = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+type resettableWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func acquireEncoder(encoding string, dst io.Writer) (resettableWriter, *sync.Pool) {
+	var pool *sync.Pool
+	switch encoding {
+	case encodingZstd:
+		pool = &zstdWriterPool
+	case encodingBrotli:
+		pool = &brotliWriterPool
+	default:
+		pool = &gzipWriterPool
+	}
+	w := pool.Get().(resettableWriter)
+	w.Reset(dst)
+	return w, pool
+}
+
+// negotiateEncoding 按 Accept-Encoding 的 q 值选择编码，q 值相同时按服务端偏好顺序，无可用编码返回空
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range responseEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+func isCompressibleContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, t := range compressibleContentTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter 缓冲响应开头，达到阈值后再决定是否压缩；在此之前 Flush 视为流式响应，直接透传
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minBytes int
+	buffer   []byte
+	decided  bool
+	encoder  resettableWriter
+	pool     *sync.Pool
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	header := w.ResponseWriter.Header()
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	return isCompressibleContentType(header.Get("Content-Type"))
+}
+
+func (w *compressWriter) decide(compress bool) error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+	if compress && w.shouldCompress() {
+		header := w.ResponseWriter.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder, w.pool = acquireEncoder(w.encoding, w.ResponseWriter)
+	}
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	data := w.buffer
+	w.buffer = nil
+	_, err := w.writeDecided(data)
+	return err
+}
+
+func (w *compressWriter) writeDecided(data []byte) (int, error) {
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		return w.writeDecided(data)
+	}
+	w.buffer = append(w.buffer, data...)
+	if len(w.buffer) >= w.minBytes {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buffer) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	_ = w.decide(false)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Flush() {
+	_ = w.decide(false)
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	_ = w.decide(false)
+	return w.ResponseWriter.Hijack()
+}
+
+// finish 写出不足阈值的缓冲内容并结束压缩流
+func (w *compressWriter) finish() {
+	_ = w.decide(false)
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+// ResponseCompression 按 Accept-Encoding 协商 zstd / br / gzip 压缩较大的非流式响应（如 embeddings、模型列表、批处理结果）。
+// SSE、WebSocket 及在达到阈值前主动 Flush 的响应不压缩
+func ResponseCompression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetGeneralSetting()
+		if !setting.ResponseCompressionEnabled || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+		minBytes := setting.ResponseCompressionMinBytes
+		if minBytes <= 0 {
+			minBytes = responseCompressionDefaultMinBytes
+		}
+		original := c.Writer
+		writer := &compressWriter{ResponseWriter: original, encoding: encoding, minBytes: minBytes}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
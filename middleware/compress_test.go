@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip, deflate, br, zstd", encodingZstd},
+		{"gzip, br", encodingBrotli},
+		{"gzip", encodingGzip},
+		{"zstd;q=0.5, br;q=0.8, gzip", encodingGzip},
+		{"ZSTD;q=0.9, gzip;q=0.9", encodingZstd},
+		{"*", encodingZstd},
+		{"zstd;q=0, *;q=0.5", encodingBrotli},
+		{"identity", ""},
+		{"gzip;q=0", ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, negotiateEncoding(tt.header), tt.header)
+	}
+}
+
+func newCompressTestEngine(t *testing.T, minBytes int) *gin.Engine {
+	setting := operation_setting.GetGeneralSetting()
+	original := *setting
+	setting.ResponseCompressionEnabled = true
+	setting.ResponseCompressionMinBytes = minBytes
+	t.Cleanup(func() { *setting = original })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ResponseCompression())
+	return engine
+}
+
+func doCompressRequest(engine *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decodeCompressed(t *testing.T, encoding string, data []byte) string {
+	var reader io.Reader
+	switch encoding {
+	case encodingGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		reader = gz
+	case encodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(data))
+	case encodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		defer zr.Close()
+		reader = zr
+	}
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func TestResponseCompressionEncodesLargeJSON(t *testing.T) {
+	engine := newCompressTestEngine(t, 64)
+	payload := `{"data":"` + strings.Repeat("embedding ", 100) + `"}`
+	engine.GET("/json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(payload))
+	})
+
+	for _, encoding := range []string{encodingZstd, encodingBrotli, encodingGzip} {
+		t.Run(encoding, func(t *testing.T) {
+			recorder := doCompressRequest(engine, "/json", map[string]string{"Accept-Encoding": encoding})
+			require.Equal(t, encoding, recorder.Header().Get("Content-Encoding"))
+			require.Contains(t, recorder.Header().Values("Vary"), "Accept-Encoding")
+			require.Less(t, recorder.Body.Len(), len(payload))
+			require.Equal(t, payload, decodeCompressed(t, encoding, recorder.Body.Bytes()))
+		})
+	}
+}
+
+func TestResponseCompressionSkipsSmallOrIncompressible(t *testing.T) {
+	engine := newCompressTestEngine(t, 1024)
+	engine.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	engine.GET("/audio", func(c *gin.Context) {
+		c.Data(http.StatusOK, "audio/mpeg", bytes.Repeat([]byte{0xff}, 4096))
+	})
+	engine.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", encodingGzip)
+		c.Data(http.StatusOK, "application/json", bytes.Repeat([]byte("a"), 4096))
+	})
+
+	recorder := doCompressRequest(engine, "/small", map[string]string{"Accept-Encoding": "gzip"})
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.JSONEq(t, `{"ok":true}`, recorder.Body.String())
+
+	recorder = doCompressRequest(engine, "/audio", map[string]string{"Accept-Encoding": "gzip"})
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, 4096, recorder.Body.Len())
+
+	// 已编码的响应不重复压缩
+	recorder = doCompressRequest(engine, "/encoded", map[string]string{"Accept-Encoding": "zstd"})
+	require.Equal(t, encodingGzip, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, strings.Repeat("a", 4096), recorder.Body.String())
+
+	recorder = doCompressRequest(engine, "/small", nil)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+}
+
+func TestResponseCompressionPassesThroughStreams(t *testing.T) {
+	engine := newCompressTestEngine(t, 16)
+	stream := "data: " + strings.Repeat("x", 64) + "\n\n"
+	engine.GET("/sse", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("data: {}\n\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString(stream)
+		c.Writer.Flush()
+	})
+	engine.GET("/flushed-json", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString(stream)
+	})
+
+	recorder := doCompressRequest(engine, "/sse", map[string]string{"Accept-Encoding": "gzip"})
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "data: {}\n\n"+stream, recorder.Body.String())
+
+	// 阈值前主动 Flush 的响应按流式处理
+	recorder = doCompressRequest(engine, "/flushed-json", map[string]string{"Accept-Encoding": "gzip"})
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, stream, recorder.Body.String())
+
+	// 客户端声明接受 SSE 时整体跳过
+	recorder = doCompressRequest(engine, "/sse", map[string]string{"Accept-Encoding": "gzip", "Accept": "text/event-stream"})
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "data: {}\n\n"+stream, recorder.Body.String())
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// datasetCaptureWriter 透传响应，同时保留一份副本用于数据集采集
+type datasetCaptureWriter struct {
+	gin.ResponseWriter
+	buffer   bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *datasetCaptureWriter) Write(data []byte) (int, error) {
+	if !w.overflow {
+		if w.limit > 0 && w.buffer.Len()+len(data) > w.limit {
+			w.overflow = true
+			w.buffer.Reset()
+		} else {
+			w.buffer.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *datasetCaptureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// DatasetCapture 按采样率采集聊天补全的请求与回复，需在 Distribute 之后使用以获取模型与分组
+func DatasetCapture() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path != "/v1/chat/completions" || !service.ShouldCaptureDataset(c) {
+			c.Next()
+			return
+		}
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		body, err := storage.Bytes()
+		if err != nil {
+			c.Next()
+			return
+		}
+		// 请求体可能在后续处理中被改写，先保留原始内容
+		body = bytes.Clone(body)
+
+		original := c.Writer
+		writer := &datasetCaptureWriter{ResponseWriter: original, limit: 4 << 20}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if writer.overflow || writer.Status() < 200 || writer.Status() >= 300 {
+			return
+		}
+		var reply string
+		if strings.HasPrefix(writer.Header().Get("Content-Type"), "text/event-stream") {
+			reply = extractStreamOutputText(writer.buffer.Bytes())
+		} else {
+			reply = extractOutputText(writer.buffer.Bytes())
+		}
+		service.CaptureDatasetSample(c, body, strings.TrimSpace(reply))
+	}
+}
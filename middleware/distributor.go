@@ -17,6 +17,7 @@ import (
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +37,13 @@ func Distribute() func(c *gin.Context) {
 			abortWithOpenAiMessage(c, http.StatusBadRequest, i18n.T(c, i18n.MsgDistributorInvalidRequest, map[string]any{"Error": err.Error()}))
 			return
 		}
+		// 客户端请求的模型名，A/B 实验分组与全局别名可能将其替换为其他模型
+		requestedModel := modelRequest.Model
+		modelRequest.Model = service.AssignExperiment(c, modelRequest.Model)
+		if resolved, aliased := service.ResolveModelAlias(modelRequest.Model, modelAvailableForUsingGroup(c)); aliased {
+			common.SetContextKey(c, constant.ContextKeyRequestedModelAlias, modelRequest.Model)
+			modelRequest.Model = resolved
+		}
 		if ok {
 			id, err := strconv.Atoi(channelId.(string))
 			if err != nil {
@@ -68,11 +76,23 @@ func Distribute() func(c *gin.Context) {
 					tokenModelLimit = map[string]bool{}
 				}
 				matchName := ratio_setting.FormatMatchingModelName(modelRequest.Model) // match gpts & thinking-*
-				if _, ok := tokenModelLimit[matchName]; !ok {
+				// 令牌允许客户端请求的模型时，实验分组或别名替换后的模型同样允许
+				if _, ok := tokenModelLimit[matchName]; !ok && !tokenModelLimit[ratio_setting.FormatMatchingModelName(requestedModel)] {
 					abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorTokenModelForbidden, map[string]any{"Model": modelRequest.Model}))
 					return
 				}
 			}
+			if tenant := system_setting.GetTenantByGroup(common.GetContextKeyString(c, constant.ContextKeyUserGroup), common.GetContextKeyString(c, constant.ContextKeyUsingGroup)); tenant != nil && !tenant.IsModelAllowed(ratio_setting.FormatMatchingModelName(modelRequest.Model)) {
+				abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorTenantModelForbidden, map[string]any{"Model": modelRequest.Model}))
+				return
+			}
+			// 组织管理员为成员设置的可用模型
+			if orgModelLimit, err := model.GetOrganizationModelLimits(c.GetInt("id")); err != nil {
+				common.SysError("failed to get organization model limits: " + err.Error())
+			} else if orgModelLimit != nil && !orgModelLimit[ratio_setting.FormatMatchingModelName(modelRequest.Model)] && !orgModelLimit[ratio_setting.FormatMatchingModelName(requestedModel)] {
+				abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorOrgModelForbidden, map[string]any{"Model": modelRequest.Model}))
+				return
+			}
 
 			if shouldSelectChannel {
 				if modelRequest.Model == "" {
@@ -145,10 +165,12 @@ func Distribute() func(c *gin.Context) {
 						//	common.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
 						//	message = "数据库一致性已被破坏，请联系管理员"
 						//}
+						service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeChannelSaturation})
 						abortWithOpenAiMessage(c, http.StatusServiceUnavailable, message, types.ErrorCodeModelNotFound)
 						return
 					}
 					if channel == nil {
+						service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeChannelSaturation})
 						abortWithOpenAiMessage(c, http.StatusServiceUnavailable, i18n.T(c, i18n.MsgDistributorNoAvailableChannel, map[string]any{"Group": usingGroup, "Model": modelRequest.Model}), types.ErrorCodeModelNotFound)
 						return
 					}
@@ -293,7 +315,7 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
 		modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e")
-	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") {
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") || strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
 		//modelRequest.Model = common.GetStringIfEmpty(c.PostForm("model"), "gpt-image-1")
 		contentType := c.ContentType()
 		if slices.Contains([]string{gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm}, contentType) {
@@ -302,6 +324,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 				modelRequest.Model = req.Model
 			}
 		}
+		// 图片变体接口仅 dall-e-2 支持，未指定模型时使用 dall-e-2
+		if strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
+			modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e-2")
+		}
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/audio") {
 		relayMode := relayconstant.RelayModeAudioSpeech
@@ -342,6 +368,23 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	return &modelRequest, shouldSelectChannel, nil
 }
 
+// modelAvailableForUsingGroup 判断模型在当前令牌分组（auto 分组时为任一自动分组）中是否有可用渠道
+func modelAvailableForUsingGroup(c *gin.Context) func(string) bool {
+	usingGroup := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	groups := []string{usingGroup}
+	if usingGroup == "auto" {
+		groups = service.GetUserAutoGroup(common.GetContextKeyString(c, constant.ContextKeyUserGroup))
+	}
+	return func(modelName string) bool {
+		for _, group := range groups {
+			if channel, err := model.GetRandomSatisfiedChannel(group, modelName, 0); err == nil && channel != nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, modelName string) *types.NewAPIError {
 	c.Set("original_model", modelName) // for retry
 	if channel == nil {
@@ -358,6 +401,9 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	if mergedParam, applied := service.ApplyChannelAffinityOverrideTemplate(c, paramOverride); applied {
 		paramOverride = mergedParam
 	}
+	if mergedParam, applied := service.MergeExperimentParamOverride(c, paramOverride); applied {
+		paramOverride = mergedParam
+	}
 	common.SetContextKey(c, constant.ContextKeyChannelParamOverride, paramOverride)
 	common.SetContextKey(c, constant.ContextKeyChannelHeaderOverride, headerOverride)
 	if nil != channel.OpenAIOrganization && *channel.OpenAIOrganization != "" {
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type grantAllEntitlements struct{}
+
+func (grantAllEntitlements) Name() string         { return "test" }
+func (grantAllEntitlements) Entitled(string) bool { return true }
+
+func TestDistributeEnforcesTenantModelsByGroup(t *testing.T) {
+	entitlement.RegisterChecker(grantAllEntitlements{})
+	setting := system_setting.GetTenantSetting()
+	saved := *setting
+	t.Cleanup(func() { *setting = saved })
+	*setting = system_setting.TenantSetting{
+		Enabled: true,
+		Tenants: []system_setting.Tenant{{
+			Name:          "acme",
+			Hostnames:     []string{"ai.acme.example"},
+			Group:         "acme",
+			AllowedModels: []string{"gpt-4o-mini"},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		userGroup  string
+		usingGroup string
+	}{
+		// 租户令牌改用主站域名访问时，白名单仍然生效
+		{"tenant user on main domain", "api.main.example", "acme", "acme"},
+		{"tenant user on tenant domain", "ai.acme.example", "acme", "acme"},
+		{"main user spending tenant group", "api.main.example", "default", "acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Host = tt.host
+			common.SetContextKey(c, constant.ContextKeyUserGroup, tt.userGroup)
+			common.SetContextKey(c, constant.ContextKeyUsingGroup, tt.usingGroup)
+
+			Distribute()(c)
+
+			require.True(t, c.IsAborted())
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+			require.Contains(t, recorder.Body.String(), "gpt-4o")
+		})
+	}
+}
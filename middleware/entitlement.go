@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireEntitlement 未获得指定功能授权时返回 403，并在响应中注明缺失的功能
+func RequireEntitlement(feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if entitlement.IsEnabled(feature) {
+			c.Next()
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": common.TranslateMessage(c, i18n.MsgFeatureNotEntitled, map[string]any{"Feature": feature}),
+			"code":    "feature_not_entitled",
+			"feature": feature,
+		})
+		c.Abort()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
@@ -32,6 +33,10 @@ func DecompressRequestMiddleware() gin.HandlerFunc {
 		if maxMB <= 0 {
 			maxMB = 32
 		}
+		// multipart 文件上传使用单独的上限
+		if common.IsMultipartRequest(c.Request) && constant.MaxUploadBodyMB > maxMB {
+			maxMB = constant.MaxUploadBodyMB
+		}
 		maxBytes := int64(maxMB) << 20
 
 		origBody := c.Request.Body
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotentQuotaHeader     = "X-New-Api-Idempotent-Quota"
+	idempotentRequestIdHeader = "X-New-Api-Idempotent-Request-Id"
+	idempotencyDefaultMaxBody = 1 << 20
+)
+
+// idempotencyWriter 透传响应的同时保留一份副本，超过上限后停止保留
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	buffer   bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *idempotencyWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buffer.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.buffer.Reset()
+		return
+	}
+	w.buffer.Write(data)
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.capture(data[:n])
+	return n, err
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Idempotency 支持 Idempotency-Key 请求头：同一令牌在有效期内重试相同请求时直接返回首次响应，不再转发上游与计费。
+// 首次请求仍在处理时返回 409，同一 Key 用于不同请求时返回 422；首次请求失败（不计费）时释放 Key 允许重试。
+// 需在 TokenAuth 之后、Distribute 之前使用
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+		setting := operation_setting.GetIdempotencySetting()
+		if key == "" || !setting.Enabled || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > service.IdempotencyKeyMaxLength {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "Idempotency-Key is too long", types.ErrorCodeInvalidRequest)
+			return
+		}
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "failed to read request body: "+err.Error(), types.ErrorCodeReadRequestBodyFailed)
+			return
+		}
+		fingerprint, err := service.IdempotencyFingerprint(c.Request.Method, c.Request.URL.Path, io.NewSectionReader(storage, 0, storage.Size()))
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "failed to read request body: "+err.Error(), types.ErrorCodeReadRequestBodyFailed)
+			return
+		}
+
+		cacheKey := service.IdempotencyCacheKey(c.GetInt("token_id"), key)
+		existing, acquired, err := service.AcquireIdempotencyKey(cacheKey, fingerprint)
+		if err != nil {
+			// 存储不可用时不阻断请求，按普通请求处理
+			logger.LogWarn(c, "idempotency key acquire failed: "+err.Error())
+			c.Next()
+			return
+		}
+		if !acquired {
+			replayIdempotentResponse(c, existing, fingerprint)
+			return
+		}
+
+		limit := setting.MaxResponseBytes
+		if limit <= 0 {
+			limit = idempotencyDefaultMaxBody
+		}
+		original := c.Writer
+		writer := &idempotencyWriter{ResponseWriter: original, limit: limit}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		status := original.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			if err := service.ReleaseIdempotencyKey(cacheKey); err != nil {
+				logger.LogWarn(c, "idempotency key release failed: "+err.Error())
+			}
+			return
+		}
+		record := service.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  status,
+			ContentType: original.Header().Get("Content-Type"),
+			Quota:       common.GetContextKeyInt(c, constant.ContextKeyConsumedQuota),
+			RequestId:   c.GetString(common.RequestIdKey),
+		}
+		if writer.overflow || common.IsNoStore(c) {
+			record.BodyOmitted = true
+		} else {
+			record.Body = writer.buffer.Bytes()
+		}
+		if err := service.CompleteIdempotencyKey(cacheKey, record); err != nil {
+			logger.LogWarn(c, "idempotency key save failed: "+err.Error())
+		}
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, record *service.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		abortWithOpenAiMessage(c, http.StatusUnprocessableEntity, "Idempotency-Key has already been used with a different request", types.ErrorCodeIdempotencyKeyReused)
+		return
+	}
+	if !record.Completed {
+		abortWithOpenAiMessage(c, http.StatusConflict, "a request with this Idempotency-Key is still being processed", types.ErrorCodeIdempotencyKeyInUse)
+		return
+	}
+	if record.BodyOmitted {
+		abortWithOpenAiMessage(c, http.StatusConflict, "a request with this Idempotency-Key has already been processed and its response is not available for replay", types.ErrorCodeIdempotencyKeyInUse)
+		return
+	}
+	c.Header(idempotentReplayedHeader, "true")
+	c.Header(idempotentQuotaHeader, strconv.Itoa(record.Quota))
+	if record.RequestId != "" {
+		c.Header(idempotentRequestIdHeader, record.RequestId)
+	}
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	c.Data(record.StatusCode, contentType, record.Body)
+	c.Abort()
+}
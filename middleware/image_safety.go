@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// imageSafetyWriter 缓存图片生成响应，在响应体完整后立即扫描并写出，
+// 使扫描结果能在消费日志写入前记录到上下文。非 2xx 与流式响应直接透传。
+type imageSafetyWriter struct {
+	gin.ResponseWriter
+	c      *gin.Context
+	group  string
+	status int
+	buffer bytes.Buffer
+	done   bool
+}
+
+func (w *imageSafetyWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *imageSafetyWriter) WriteHeaderNow() {
+	if w.done {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *imageSafetyWriter) Status() int {
+	return w.status
+}
+
+func (w *imageSafetyWriter) Written() bool {
+	return w.done
+}
+
+func (w *imageSafetyWriter) Write(data []byte) (int, error) {
+	if w.done {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.status < 200 || w.status >= 300 || strings.HasPrefix(w.ResponseWriter.Header().Get("Content-Type"), "text/event-stream") {
+		w.done = true
+		w.ResponseWriter.WriteHeader(w.status)
+		return w.ResponseWriter.Write(data)
+	}
+	w.buffer.Write(data)
+	if gjson.ValidBytes(w.buffer.Bytes()) {
+		w.flush()
+	}
+	return len(data), nil
+}
+
+func (w *imageSafetyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *imageSafetyWriter) Flush() {
+	if w.done {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// flush 扫描已缓存的完整响应并写出
+func (w *imageSafetyWriter) flush() {
+	w.done = true
+	body, apiErr := service.ScanImageResponse(w.c, w.group, common.GetContextKeyString(w.c, constant.ContextKeyOriginalModel), w.buffer.Bytes())
+	w.ResponseWriter.Header().Del("Content-Length")
+	if apiErr != nil {
+		w.ResponseWriter.Header().Set("Content-Type", "application/json")
+		w.ResponseWriter.WriteHeader(apiErr.StatusCode)
+		body, _ = common.Marshal(gin.H{"error": apiErr.ToOpenAIError()})
+	} else {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// ImageSafety 扫描图片生成与编辑接口返回的图片，需在 Distribute 之后使用以获取分组信息
+func ImageSafety() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		if !strings.Contains(c.Request.URL.Path, "/images/") || !service.ShouldScanImages(group) {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		writer := &imageSafetyWriter{ResponseWriter: original, c: c, group: group, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if writer.done {
+			return
+		}
+		// 响应体不是合法 JSON（或为空），原样写出
+		original.WriteHeader(writer.status)
+		if writer.buffer.Len() > 0 {
+			_, _ = original.Write(writer.buffer.Bytes())
+		} else {
+			original.WriteHeaderNow()
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/common/limiter"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
 
 	"github.com/gin-gonic/gin"
@@ -21,44 +22,44 @@ const (
 	ModelRequestRateLimitSuccessCountMark = "MRRLS"
 )
 
-// 检查Redis中的请求限制
-func checkRedisRateLimit(ctx context.Context, rdb *redis.Client, key string, maxCount int, duration int64) (bool, error) {
+// 检查Redis中的请求限制，被拒绝时同时返回需等待的秒数
+func checkRedisRateLimit(ctx context.Context, rdb *redis.Client, key string, maxCount int, duration int64) (bool, int64, error) {
 	// 如果maxCount为0，表示不限制
 	if maxCount == 0 {
-		return true, nil
+		return true, 0, nil
 	}
 
 	// 获取当前计数
 	length, err := rdb.LLen(ctx, key).Result()
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
 	// 如果未达到限制，允许请求
 	if length < int64(maxCount) {
-		return true, nil
+		return true, 0, nil
 	}
 
 	// 检查时间窗口
 	oldTimeStr, _ := rdb.LIndex(ctx, key, -1).Result()
 	oldTime, err := time.Parse(timeFormat, oldTimeStr)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
 	nowTimeStr := time.Now().Format(timeFormat)
 	nowTime, err := time.Parse(timeFormat, nowTimeStr)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 	// 如果在时间窗口内已达到限制，拒绝请求
 	subTime := nowTime.Sub(oldTime).Seconds()
 	if int64(subTime) < duration {
 		rdb.Expire(ctx, key, time.Duration(setting.ModelRequestRateLimitDurationMinutes)*time.Minute)
-		return false, nil
+		return false, duration - int64(subTime), nil
 	}
 
-	return true, nil
+	return true, 0, nil
 }
 
 // 记录Redis请求
@@ -83,13 +84,14 @@ func redisRateLimitHandler(duration int64, totalMaxCount, successMaxCount int) g
 
 		// 1. 检查成功请求数限制
 		successKey := fmt.Sprintf("rateLimit:%s:%s", ModelRequestRateLimitSuccessCountMark, userId)
-		allowed, err := checkRedisRateLimit(ctx, rdb, successKey, successMaxCount, duration)
+		allowed, retryAfter, err := checkRedisRateLimit(ctx, rdb, successKey, successMaxCount, duration)
 		if err != nil {
 			fmt.Println("检查成功请求数限制失败:", err.Error())
 			abortWithOpenAiMessage(c, http.StatusInternalServerError, "rate_limit_check_failed")
 			return
 		}
 		if !allowed {
+			setRequestRateLimitHint(c, service.RetryScopeUserRequests, retryAfter, successMaxCount, duration)
 			abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("您已达到请求数限制：%d分钟内最多请求%d次", setting.ModelRequestRateLimitDurationMinutes, successMaxCount))
 			return
 		}
@@ -114,7 +116,10 @@ func redisRateLimitHandler(duration int64, totalMaxCount, successMaxCount int) g
 			}
 
 			if !allowed {
+				// 令牌桶每秒补充 totalMaxCount 个令牌，每次请求消耗 duration 个
+				setRequestRateLimitHint(c, service.RetryScopeUserTotalRequests, (duration+int64(totalMaxCount)-1)/int64(totalMaxCount), totalMaxCount, duration)
 				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("您已达到总请求数限制：%d分钟内最多请求%d次，包括失败次数，请检查您的请求是否正确", setting.ModelRequestRateLimitDurationMinutes, totalMaxCount))
+				return
 			}
 		}
 
@@ -139,8 +144,8 @@ func memoryRateLimitHandler(duration int64, totalMaxCount, successMaxCount int)
 
 		// 1. 检查总请求数限制（当totalMaxCount为0时跳过）
 		if totalMaxCount > 0 && !inMemoryRateLimiter.Request(totalKey, totalMaxCount, duration) {
-			c.Status(http.StatusTooManyRequests)
-			c.Abort()
+			setRequestRateLimitHint(c, service.RetryScopeUserTotalRequests, inMemoryRateLimiter.RetryAfter(totalKey, totalMaxCount, duration), totalMaxCount, duration)
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("您已达到总请求数限制：%d分钟内最多请求%d次，包括失败次数，请检查您的请求是否正确", setting.ModelRequestRateLimitDurationMinutes, totalMaxCount))
 			return
 		}
 
@@ -148,8 +153,8 @@ func memoryRateLimitHandler(duration int64, totalMaxCount, successMaxCount int)
 		// 使用一个临时key来检查限制，这样可以避免实际记录
 		checkKey := successKey + "_check"
 		if !inMemoryRateLimiter.Request(checkKey, successMaxCount, duration) {
-			c.Status(http.StatusTooManyRequests)
-			c.Abort()
+			setRequestRateLimitHint(c, service.RetryScopeUserRequests, inMemoryRateLimiter.RetryAfter(checkKey, successMaxCount, duration), successMaxCount, duration)
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("您已达到请求数限制：%d分钟内最多请求%d次", setting.ModelRequestRateLimitDurationMinutes, successMaxCount))
 			return
 		}
 
@@ -163,6 +168,19 @@ func memoryRateLimitHandler(duration int64, totalMaxCount, successMaxCount int)
 	}
 }
 
+// setRequestRateLimitHint 记录命中的请求数限制，错误响应据此返回 Retry-After 与 X-RateLimit-* 响应头
+func setRequestRateLimitHint(c *gin.Context, scope string, retryAfter int64, limit int, duration int64) {
+	if retryAfter <= 0 {
+		retryAfter = 1
+	}
+	service.SetRetryHint(c, service.RetryHint{
+		Scope:             scope,
+		RetryAfterSeconds: retryAfter,
+		Limit:             limit,
+		WindowSeconds:     duration,
+	})
+}
+
 // ModelRequestRateLimit 模型请求限流中间件
 func ModelRequestRateLimit() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// 流式响应最多保留的原始字节数，超出部分不参与输出审核
+const moderationStreamCaptureLimit = 4 << 20
+
+type moderationWriteMode int
+
+const (
+	// moderationPassthrough 不参与审核的响应（错误、二进制等）直接透传
+	moderationPassthrough moderationWriteMode = iota
+	// moderationBuffered 非流式 JSON 响应先缓存，审核通过后再写出
+	moderationBuffered
+	// moderationStreaming SSE 响应直接透传，同时保留一份副本在结束后审核
+	moderationStreaming
+)
+
+// moderationWriter 捕获响应内容用于输出审核，首次写出时根据状态码与 Content-Type 决定处理方式：
+// 仅 2xx 的 JSON 响应会被缓存，SSE 边透传边捕获，其余响应原样透传。
+// 设置 streamTransform 时，流式分片在写出前先经过改写。
+type moderationWriter struct {
+	gin.ResponseWriter
+	status          int
+	buffer          bytes.Buffer
+	mode            moderationWriteMode
+	decided         bool
+	streamTransform func([]byte) []byte
+	// bufferErrors 为 true 时非 2xx 的 JSON 响应同样缓存，供需要处理错误响应的插件使用
+	bufferErrors bool
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (w *moderationWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	contentType := w.ResponseWriter.Header().Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		w.mode = moderationStreaming
+	case (w.bufferErrors || w.status >= 200 && w.status < 300) && isJSONContentType(contentType):
+		w.mode = moderationBuffered
+	default:
+		w.mode = moderationPassthrough
+	}
+	if w.mode != moderationBuffered {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *moderationWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+	}
+}
+
+func (w *moderationWriter) WriteHeaderNow() {
+	w.decide()
+	if w.mode != moderationBuffered {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *moderationWriter) Status() int {
+	return w.status
+}
+
+func (w *moderationWriter) Written() bool {
+	return w.decided
+}
+
+func (w *moderationWriter) Write(data []byte) (int, error) {
+	w.decide()
+	switch w.mode {
+	case moderationBuffered:
+		return w.buffer.Write(data)
+	case moderationStreaming:
+		if w.buffer.Len() < moderationStreamCaptureLimit {
+			w.buffer.Write(data)
+		}
+		if w.streamTransform != nil {
+			if _, err := w.ResponseWriter.Write(w.streamTransform(data)); err != nil {
+				return 0, err
+			}
+			return len(data), nil
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *moderationWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *moderationWriter) Flush() {
+	w.decide()
+	if w.mode != moderationBuffered {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// Hijack 接管连接（如 WebSocket）后不再参与审核
+func (w *moderationWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	w.mode = moderationPassthrough
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *moderationWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.CloseNotify()
+}
+
+// finishPassthrough 处理器返回后调用：未写出任何内容时补发状态码；返回 true 表示响应已透传，无需再处理
+func (w *moderationWriter) finishPassthrough(original gin.ResponseWriter) bool {
+	if !w.decided {
+		original.WriteHeader(w.status)
+		original.WriteHeaderNow()
+		return true
+	}
+	return w.mode == moderationPassthrough
+}
+
+// OutputModeration 对模型输出执行审核，需在 Distribute 之后使用以获取分组信息
+func OutputModeration() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		if !service.ShouldModerate(group, moderation_setting.StageOutput) {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		writer := &moderationWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		var text string
+		switch writer.mode {
+		case moderationStreaming:
+			text = extractStreamOutputText(writer.buffer.Bytes())
+		case moderationBuffered:
+			text = extractOutputText(writer.buffer.Bytes())
+		}
+		if writer.finishPassthrough(original) {
+			return
+		}
+
+		verdict := service.RunModeration(c, service.ModerationInput{
+			Stage: moderation_setting.StageOutput,
+			Group: group,
+			Model: common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+			Text:  text,
+		})
+		if verdict != nil && writer.mode == moderationStreaming && verdict.Action == moderation_setting.ActionBlock {
+			// 流式内容已发送给客户端，无法拦截，降级为标记
+			verdict.Action = moderation_setting.ActionFlag
+		}
+		if verdict != nil {
+			if apiErr := service.HandleModerationVerdict(c, verdict); apiErr != nil {
+				service.WriteOpenAIError(c, apiErr.StatusCode, apiErr.ToOpenAIError())
+				return
+			}
+		}
+		if writer.mode == moderationStreaming {
+			return
+		}
+		original.WriteHeader(writer.status)
+		_, _ = original.Write(writer.buffer.Bytes())
+	}
+}
+
+// extractOutputText 从非流式响应中提取模型生成的文本，兼容 OpenAI、Claude、Gemini 与 Responses 格式
+func extractOutputText(body []byte) string {
+	if len(body) == 0 || !gjson.ValidBytes(body) {
+		return ""
+	}
+	var builder strings.Builder
+	for _, path := range []string{
+		"choices.#.message.content",
+		"choices.#.text",
+		"content.#.text",
+		"candidates.#.content.parts.#.text",
+		"output.#.content.#.text",
+	} {
+		appendGjsonText(&builder, gjson.GetBytes(body, path), "\n")
+	}
+	return builder.String()
+}
+
+// extractStreamOutputText 从 SSE 数据中拼接增量文本
+func extractStreamOutputText(data []byte) string {
+	var builder strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), moderationStreamCaptureLimit)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "" || payload == "[DONE]" || !gjson.Valid(payload) {
+			continue
+		}
+		if gjson.Get(payload, "type").String() == "response.output_text.delta" {
+			builder.WriteString(gjson.Get(payload, "delta").String())
+			continue
+		}
+		for _, path := range []string{
+			"choices.#.delta.content",
+			"choices.#.text",
+			"delta.text",
+			"candidates.#.content.parts.#.text",
+		} {
+			appendGjsonText(&builder, gjson.Get(payload, path), "")
+		}
+	}
+	return builder.String()
+}
+
+func appendGjsonText(builder *strings.Builder, result gjson.Result, separator string) {
+	if !result.Exists() {
+		return
+	}
+	if result.IsArray() {
+		for _, item := range result.Array() {
+			appendGjsonText(builder, item, separator)
+		}
+		return
+	}
+	if result.Type == gjson.String {
+		builder.WriteString(result.String())
+		builder.WriteString(separator)
+	}
+}
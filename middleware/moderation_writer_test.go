@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newModerationWriterContext() (*gin.Context, *httptest.ResponseRecorder, *moderationWriter) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := &moderationWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+	c.Writer = writer
+	return c, recorder, writer
+}
+
+func TestModerationWriterBuffersOnlySuccessfulJSON(t *testing.T) {
+	c, recorder, writer := newModerationWriterContext()
+	c.JSON(http.StatusOK, gin.H{"choices": []gin.H{{"message": gin.H{"content": "hi"}}}})
+	require.Equal(t, moderationBuffered, writer.mode)
+	require.Empty(t, recorder.Body.String())
+	require.Equal(t, "hi\n", extractOutputText(writer.buffer.Bytes()))
+}
+
+func TestModerationWriterPassesThroughOtherResponses(t *testing.T) {
+	t.Run("binary", func(t *testing.T) {
+		c, recorder, writer := newModerationWriterContext()
+		c.Data(http.StatusOK, "audio/mpeg", []byte{0xff, 0xfb, 0x90})
+		require.Equal(t, moderationPassthrough, writer.mode)
+		require.Equal(t, []byte{0xff, 0xfb, 0x90}, recorder.Body.Bytes())
+		require.Zero(t, writer.buffer.Len())
+		require.True(t, writer.finishPassthrough(c.Writer))
+	})
+
+	t.Run("error json", func(t *testing.T) {
+		c, recorder, writer := newModerationWriterContext()
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+		require.Equal(t, moderationPassthrough, writer.mode)
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		require.Contains(t, recorder.Body.String(), "rate limited")
+	})
+
+	t.Run("event stream is forwarded and captured", func(t *testing.T) {
+		c, recorder, writer := newModerationWriterContext()
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"he\"}}]}\n\n")
+		_, _ = c.Writer.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"llo\"}}]}\n\n")
+		require.Equal(t, moderationStreaming, writer.mode)
+		require.Contains(t, recorder.Body.String(), "llo")
+		require.Equal(t, "hello", extractStreamOutputText(writer.buffer.Bytes()))
+	})
+
+	t.Run("nothing written", func(t *testing.T) {
+		_, recorder, writer := newModerationWriterContext()
+		writer.WriteHeader(http.StatusNoContent)
+		require.True(t, writer.finishPassthrough(writer.ResponseWriter))
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+}
+
+func TestModerationWriterBufferErrors(t *testing.T) {
+	c, recorder, writer := newModerationWriterContext()
+	writer.bufferErrors = true
+	c.JSON(http.StatusBadRequest, gin.H{"error": "bad"})
+	require.Equal(t, moderationBuffered, writer.mode)
+	require.Empty(t, recorder.Body.String())
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func (r *hijackableRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestModerationWriterForwardsHijack(t *testing.T) {
+	recorder := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c, _ := gin.CreateTestContext(recorder)
+	writer := &moderationWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+
+	_, _, err := writer.Hijack()
+	require.NoError(t, err)
+	require.True(t, recorder.hijacked)
+	require.True(t, writer.Written())
+	require.Equal(t, moderationPassthrough, writer.mode)
+	require.NotNil(t, writer.CloseNotify())
+}
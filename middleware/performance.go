@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +19,8 @@ func SystemPerformanceCheck() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		if strings.HasPrefix(path, "/v1/messages") {
 			if err := checkSystemPerformance(); err != nil {
+				service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeSystemOverload})
+				service.WriteRetryHintHeaders(c, err.StatusCode, nil)
 				c.JSON(err.StatusCode, gin.H{
 					"error": err.ToClaudeError(),
 				})
@@ -26,9 +29,8 @@ func SystemPerformanceCheck() gin.HandlerFunc {
 			}
 		} else {
 			if err := checkSystemPerformance(); err != nil {
-				c.JSON(err.StatusCode, gin.H{
-					"error": err.ToOpenAIError(),
-				})
+				service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeSystemOverload})
+				service.WriteOpenAIError(c, err.StatusCode, err.ToOpenAIError())
 				c.Abort()
 				return
 			}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// piiRestoreWriter 将响应中的可逆脱敏占位符还原为原文。
+// 按单次写入替换，流式响应中被拆分到多个分片的占位符不会被还原。
+type piiRestoreWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	replacer *strings.Replacer
+}
+
+func (w *piiRestoreWriter) getReplacer() *strings.Replacer {
+	if w.replacer != nil {
+		return w.replacer
+	}
+	mapping := service.GetPiiMapping(w.c)
+	if len(mapping) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(mapping)*2)
+	for placeholder, original := range mapping {
+		pairs = append(pairs, placeholder, original)
+	}
+	w.replacer = strings.NewReplacer(pairs...)
+	return w.replacer
+}
+
+func (w *piiRestoreWriter) WriteHeader(code int) {
+	if w.getReplacer() != nil {
+		// 还原后长度变化，移除上游计算的 Content-Length
+		w.ResponseWriter.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *piiRestoreWriter) Write(data []byte) (int, error) {
+	replacer := w.getReplacer()
+	if replacer == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.ResponseWriter.Header().Del("Content-Length")
+	if _, err := w.ResponseWriter.Write([]byte(replacer.Replace(string(data)))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *piiRestoreWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// PiiRestore 在可逆脱敏模式下还原响应中的占位符，需在 Distribute 之后使用以获取分组信息
+func PiiRestore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		policy, ok := moderation_setting.GetPiiPolicy(group)
+		if !ok || policy.Mode != moderation_setting.PiiModeReversible {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		c.Writer = &piiRestoreWriter{ResponseWriter: original, c: c}
+		c.Next()
+		c.Writer = original
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// Plugins 执行运营方配置的脚本插件：请求阶段可拒绝、改写请求或模型（需放在 Distribute 之前，改写后的模型参与渠道选择），
+// 响应阶段可改写非流式响应。插件按分组过滤，限定渠道的请求阶段插件由 ChannelPlugins 执行
+func Plugins() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		env := newPluginEnv(c)
+		requestPlugins := system_setting.FilterPlugins(system_setting.GetActivePlugins(system_setting.PluginStageRequest), env.Group, 0, false)
+		responsePlugins := make([]system_setting.Plugin, 0)
+		for _, plugin := range system_setting.GetActivePlugins(system_setting.PluginStageResponse) {
+			if plugin.InScope(env.Group, 0) {
+				responsePlugins = append(responsePlugins, plugin)
+			}
+		}
+		if len(requestPlugins) == 0 && len(responsePlugins) == 0 {
+			c.Next()
+			return
+		}
+
+		if len(requestPlugins) > 0 && !runRequestPlugins(c, requestPlugins, env) {
+			return
+		}
+
+		if len(responsePlugins) == 0 {
+			c.Next()
+			return
+		}
+		original := c.Writer
+		writer := &moderationWriter{ResponseWriter: original, status: http.StatusOK, bufferErrors: true}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+		if writer.mode == moderationStreaming || writer.finishPassthrough(original) {
+			return
+		}
+
+		env.Stage = system_setting.PluginStageResponse
+		env.Status = writer.status
+		env.Response = writer.buffer.Bytes()
+		env.ChannelId = common.GetContextKeyInt(c, constant.ContextKeyChannelId)
+		if model := common.GetContextKeyString(c, constant.ContextKeyOriginalModel); model != "" {
+			env.Model = model
+		}
+		// 响应阶段按最终使用的渠道过滤限定渠道的插件
+		scoped := make([]system_setting.Plugin, 0, len(responsePlugins))
+		for _, plugin := range responsePlugins {
+			if plugin.ChannelScoped() && env.ChannelId == 0 {
+				continue
+			}
+			if plugin.InScope(env.Group, env.ChannelId) {
+				scoped = append(scoped, plugin)
+			}
+		}
+		result := service.RunPlugins(c, scoped, env, writer.buffer.Bytes())
+		if result.Denied {
+			original.Header().Del("Content-Length")
+			abortWithOpenAiMessage(c, result.DenyStatus, result.DenyMessage, types.ErrorCodeAccessDenied)
+			return
+		}
+		for name, value := range result.Headers {
+			original.Header().Set(name, value)
+		}
+		if result.BodyChanged {
+			original.Header().Del("Content-Length")
+		}
+		original.WriteHeader(writer.status)
+		_, _ = original.Write(result.Body)
+	}
+}
+
+// ChannelPlugins 执行限定渠道的请求阶段插件，需放在 Distribute 之后；重试切换渠道时不会再次执行
+func ChannelPlugins() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channelId := common.GetContextKeyInt(c, constant.ContextKeyChannelId)
+		if channelId == 0 {
+			c.Next()
+			return
+		}
+		env := newPluginEnv(c)
+		plugins := system_setting.FilterPlugins(system_setting.GetActivePlugins(system_setting.PluginStageRequest), env.Group, channelId, true)
+		if len(plugins) == 0 {
+			c.Next()
+			return
+		}
+		env.ChannelId = channelId
+		if !runRequestPlugins(c, plugins, env) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// runRequestPlugins 对 JSON 请求体执行请求阶段插件并写回改写后的请求体，请求被拒绝或出错时中止并返回 false
+func runRequestPlugins(c *gin.Context, plugins []system_setting.Plugin, env *service.PluginEnv) bool {
+	var body []byte
+	if strings.Contains(c.Request.Header.Get("Content-Type"), "application/json") {
+		storage, err := common.GetBodyStorage(c)
+		if err == nil {
+			body, err = storage.Bytes()
+		}
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "failed to read request body: "+err.Error(), types.ErrorCodeReadRequestBodyFailed)
+			return false
+		}
+	}
+	env.Stage = system_setting.PluginStageRequest
+	env.Body = body
+	env.Model = gjson.GetBytes(body, "model").String()
+	result := service.RunPlugins(c, plugins, env, body)
+	if result.Denied {
+		abortWithOpenAiMessage(c, result.DenyStatus, result.DenyMessage, types.ErrorCodeAccessDenied)
+		return false
+	}
+	if result.BodyChanged {
+		if err := common.ReplaceBodyStorage(c, result.Body); err != nil {
+			abortWithOpenAiMessage(c, http.StatusInternalServerError, "failed to rewrite request body: "+err.Error(), types.ErrorCodeReadRequestBodyFailed)
+			return false
+		}
+	}
+	return true
+}
+
+func newPluginEnv(c *gin.Context) *service.PluginEnv {
+	group := common.GetContextKeyString(c, constant.ContextKeyTokenGroup)
+	if group == "" {
+		group = common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+	}
+	return &service.PluginEnv{
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Group:     group,
+		UserId:    common.GetContextKeyInt(c, constant.ContextKeyUserId),
+		Username:  common.GetContextKeyString(c, constant.ContextKeyUserName),
+		TokenId:   common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		TokenName: c.GetString("token_name"),
+		Header:    c.Request.Header,
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"runtime/debug"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,11 +17,9 @@ func RelayPanicRecover() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				common.SysLog(fmt.Sprintf("panic detected: %v", err))
 				common.SysLog(fmt.Sprintf("stacktrace from panic: %s", string(debug.Stack())))
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": gin.H{
-						"message": fmt.Sprintf("Panic detected, error: %v. Please submit a issue here: https://github.com/Calcium-Ion/new-api", err),
-						"type":    "new_api_panic",
-					},
+				service.WriteOpenAIError(c, http.StatusInternalServerError, types.OpenAIError{
+					Message: fmt.Sprintf("Panic detected, error: %v. Please submit a issue here: https://github.com/Calcium-Ion/new-api", err),
+					Type:    "new_api_panic",
 				})
 				c.Abort()
 			}
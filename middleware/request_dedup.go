@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestDedupHeader = "X-New-Api-Dedup"
+
+// RequestDedup 合并并发的相同非流式请求：首个请求转发上游，其余请求等待并共享其成功响应，按配置的策略只计费一次或各自计费。
+// 首个请求失败或等待超时的请求按普通请求转发。需在 ResponseCache 与 SemanticCache 之后使用
+func RequestDedup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		if c.Request.Method != http.MethodPost || common.IsNoStore(c) || !service.ShouldDedupRequest(c.Request.URL.Path, group) {
+			c.Next()
+			return
+		}
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		body, err := storage.Bytes()
+		if err != nil {
+			c.Next()
+			return
+		}
+		setting := operation_setting.GetRequestDedupSetting()
+		userId := c.GetInt("id")
+		scope := userId
+		if setting.SharedAcrossUsers {
+			scope = 0
+		}
+		key, ok := service.RequestDedupKey(c.Request.URL.Path, group, scope, body)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		call, leader := service.JoinRequestDedup(key)
+		if !leader {
+			timeout := time.Duration(setting.WaitTimeoutSeconds) * time.Second
+			if timeout <= 0 {
+				timeout = 120 * time.Second
+			}
+			if result, ok := call.Wait(c.Request.Context(), timeout); ok && replayDedupResponse(c, result, userId, group, setting) {
+				return
+			}
+			c.Next()
+			return
+		}
+
+		var result *service.RequestDedupResult
+		defer func() {
+			if waiters := service.FinishRequestDedup(key, call, result); waiters > 0 {
+				logger.LogInfo(c, fmt.Sprintf("request dedup: %d concurrent identical requests merged, shared=%t", waiters, result != nil))
+			}
+		}()
+		limit := setting.MaxResponseBytes
+		if limit <= 0 {
+			limit = idempotencyDefaultMaxBody
+		}
+		original := c.Writer
+		writer := &idempotencyWriter{ResponseWriter: original, limit: limit}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if original.Status() != http.StatusOK || writer.overflow || writer.buffer.Len() == 0 {
+			return
+		}
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		result = service.NewRequestDedupResult(modelName, original.Header().Get("Content-Type"), writer.buffer.Bytes(),
+			common.GetContextKeyInt(c, constant.ContextKeyConsumedQuota))
+	}
+}
+
+// replayDedupResponse 向合并的请求返回共享的响应并记录消费日志；per_request 策略下余额不足时返回 false，按普通请求转发
+func replayDedupResponse(c *gin.Context, result *service.RequestDedupResult, userId int, group string, setting *operation_setting.RequestDedupSetting) bool {
+	quota := 0
+	content := "合并并发的相同请求，不计费"
+	if setting.BillPerRequest() && result.Quota > 0 {
+		userQuota, err := model.GetUserQuota(userId, false)
+		if err != nil || userQuota < result.Quota {
+			return false
+		}
+		if err := service.ChargeRequestDedupFollower(userId, c.GetInt("token_id"), c.GetString("token_key"), c.GetBool("token_unlimited_quota"), result.Quota); err != nil {
+			logger.LogError(c, "request dedup charge failed: "+err.Error())
+			return false
+		}
+		quota = result.Quota
+		content = "合并并发的相同请求，按首个请求的消耗计费"
+	}
+
+	c.Header(requestDedupHeader, "merged")
+	c.Data(http.StatusOK, result.ContentType, result.Body)
+	c.Abort()
+
+	modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+	if modelName == "" {
+		modelName = result.ModelName
+	}
+	model.RecordConsumeLog(c, userId, model.RecordConsumeLogParams{
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+		ModelName:        modelName,
+		TokenName:        c.GetString("token_name"),
+		Quota:            quota,
+		Content:          content,
+		TokenId:          c.GetInt("token_id"),
+		Group:            group,
+		Other: map[string]interface{}{
+			"request_dedup":         "merged",
+			"request_dedup_billing": setting.BillingPolicy,
+			"request_path":          c.Request.URL.Path,
+		},
+	})
+	return true
+}
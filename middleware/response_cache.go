@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseCache 确定性请求的响应缓存：命中时记录缓存结果，由 Relay 在请求侧安全检查通过后返回并记录零费用的消费日志，
+// 未命中时缓存成功的非流式响应。需在 Distribute 之后使用，令牌的模型限制与分组已生效
+func ResponseCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		if c.Request.Method != http.MethodPost || common.IsNoStore(c) || !service.ShouldCacheResponse(c.Request.URL.Path, group) {
+			c.Next()
+			return
+		}
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		body, err := storage.Bytes()
+		if err != nil {
+			c.Next()
+			return
+		}
+		setting := operation_setting.GetResponseCacheSetting()
+		userId := c.GetInt("id")
+		scope := userId
+		if setting.SharedAcrossUsers {
+			scope = 0
+		}
+		key, ok := service.ResponseCacheKey(c.Request.URL.Path, group, scope, body)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		record, found, err := service.GetCachedResponse(key)
+		if err != nil {
+			logger.LogWarn(c, "response cache lookup failed: "+err.Error())
+		}
+		if found {
+			service.DeferCachedResponse(c, record, service.ResponseCacheHit, "响应缓存命中，不计费", nil)
+			c.Next()
+			return
+		}
+
+		common.SetContextKey(c, constant.ContextKeyResponseCache, service.ResponseCacheMiss)
+		limit := setting.MaxResponseBytes
+		if limit <= 0 {
+			limit = idempotencyDefaultMaxBody
+		}
+		original := c.Writer
+		writer := &idempotencyWriter{ResponseWriter: original, limit: limit}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if original.Status() != http.StatusOK || writer.overflow || writer.buffer.Len() == 0 {
+			return
+		}
+		// 语义缓存命中的相似请求结果不作为本请求的精确缓存
+		if common.GetContextKeyString(c, constant.ContextKeyResponseCache) == service.ResponseCacheSemanticHit {
+			return
+		}
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		if err := service.SaveCachedResponse(key, modelName, original.Header().Get("Content-Type"), writer.buffer.Bytes()); err != nil {
+			logger.LogWarn(c, "response cache save failed: "+err.Error())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheReplaysOnlyAfterRequestChecks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setting := operation_setting.GetResponseCacheSetting()
+	saved := *setting
+	logConsumeEnabled := common.LogConsumeEnabled
+	redisEnabled := common.RedisEnabled
+	t.Cleanup(func() {
+		*setting = saved
+		common.LogConsumeEnabled = logConsumeEnabled
+		common.RedisEnabled = redisEnabled
+	})
+	setting.Enabled = true
+	setting.Groups = nil
+	common.LogConsumeEnabled = false
+	common.RedisEnabled = false
+
+	path := "/v1/chat/completions"
+	body := `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"response cache replay test"}]}`
+	key, ok := service.ResponseCacheKey(path, "", 0, []byte(body))
+	require.True(t, ok)
+	require.NoError(t, service.SaveCachedResponse(key, "gpt-4o", "application/json", []byte(`{"id":"cached"}`)))
+
+	engine := gin.New()
+	engine.Use(ResponseCache())
+	// 模拟 Relay：请求侧检查未通过时返回错误，通过后才返回缓存结果
+	engine.POST(path, func(c *gin.Context) {
+		if c.GetHeader("X-Test-Blocked") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "blocked by moderation"})
+			return
+		}
+		if service.ReplayPendingCachedResponse(c) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": "fresh"})
+	})
+	send := func(blocked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if blocked {
+			req.Header.Set("X-Test-Blocked", "1")
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send(true)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Empty(t, recorder.Header().Get(service.ResponseCacheHeader))
+	require.NotContains(t, recorder.Body.String(), "cached")
+
+	recorder = send(false)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, service.ResponseCacheHit, recorder.Header().Get(service.ResponseCacheHeader))
+	require.JSONEq(t, `{"id":"cached"}`, recorder.Body.String())
+}
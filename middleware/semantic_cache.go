@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SemanticCache 语义缓存：令牌配置了相似度阈值时，计算提示词向量并查找相似的历史请求，命中时由 Relay 在请求侧安全检查通过后返回历史响应且不计费，
+// 未命中时缓存成功的非流式响应。需在 ResponseCache 之后使用，精确命中的请求不再计算向量
+func SemanticCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := c.GetFloat64("token_semantic_cache_threshold")
+		if c.Request.Method != http.MethodPost || common.IsNoStore(c) || service.HasPendingCachedResponse(c) || !service.ShouldUseSemanticCache(c.Request.URL.Path, threshold) {
+			c.Next()
+			return
+		}
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		body, err := storage.Bytes()
+		if err != nil {
+			c.Next()
+			return
+		}
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		tokenId := c.GetInt("token_id")
+		request, ok := service.BuildSemanticCacheRequest(c.Request.URL.Path, group, tokenId, body)
+		if !ok {
+			c.Next()
+			return
+		}
+		vector, err := service.EmbedSemanticPrompt(c.Request.Context(), request.Prompt)
+		if err != nil {
+			logger.LogWarn(c, "semantic cache embedding failed: "+err.Error())
+			c.Next()
+			return
+		}
+
+		if record, similarity, found := service.LookupSemanticCache(request.Scope, vector, threshold); found {
+			service.DeferCachedResponse(c, record, service.ResponseCacheSemanticHit, "语义缓存命中，不计费", map[string]interface{}{
+				"semantic_similarity": similarity,
+			})
+			c.Next()
+			return
+		}
+
+		common.SetContextKey(c, constant.ContextKeyResponseCache, service.ResponseCacheSemanticMiss)
+		limit := operation_setting.GetSemanticCacheSetting().MaxResponseBytes
+		if limit <= 0 {
+			limit = idempotencyDefaultMaxBody
+		}
+		original := c.Writer
+		writer := &idempotencyWriter{ResponseWriter: original, limit: limit}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if original.Status() != http.StatusOK || writer.overflow || writer.buffer.Len() == 0 {
+			return
+		}
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		service.SaveSemanticCache(request.Scope, vector, modelName, original.Header().Get("Content-Type"), writer.buffer.Bytes())
+	}
+}
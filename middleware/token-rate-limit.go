@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenRateLimit 令牌级限流：按令牌自身配置的 RPM、TPM 与每日消费额度拦截请求，与所属用户分组的限流配置相互独立。
+// TPM 与每日额度按已完成请求的实际消耗累计，超出后拦截后续请求
+func TokenRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		tokenId := c.GetInt("token_id")
+		if tokenId <= 0 {
+			c.Next()
+			return
+		}
+		if limit := c.GetInt("token_daily_quota_limit"); limit > 0 {
+			used, err := model.GetTokenDailyQuota(tokenId)
+			if err != nil {
+				common.SysError("failed to get token daily quota: " + err.Error())
+			} else if used >= int64(limit) {
+				service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeTokenDailyQuota, RetryAfterSeconds: secondsUntilTomorrow()})
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("令牌已达到每日消费额度上限：%s", logger.LogQuota(limit)))
+				return
+			}
+		}
+		if limit := c.GetInt("token_tpm_limit"); limit > 0 {
+			used, err := model.GetTokenMinuteTokens(tokenId)
+			if err != nil {
+				common.SysError("failed to get token tpm usage: " + err.Error())
+			} else if used >= int64(limit) {
+				service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeTokenTokens, RetryAfterSeconds: secondsUntilNextMinute(), Limit: limit, WindowSeconds: 60})
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("令牌已达到每分钟 token 数限制：%d", limit))
+				return
+			}
+		}
+		if limit := c.GetInt("token_rpm_limit"); limit > 0 {
+			count, err := model.IncrTokenMinuteRequests(tokenId)
+			if err != nil {
+				common.SysError("failed to record token rpm: " + err.Error())
+			} else if count > int64(limit) {
+				service.SetRetryHint(c, service.RetryHint{Scope: service.RetryScopeTokenRequests, RetryAfterSeconds: secondsUntilNextMinute(), Limit: limit, WindowSeconds: 60})
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("令牌已达到每分钟请求数限制：%d", limit))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func secondsUntilNextMinute() int64 {
+	return 60 - time.Now().Unix()%60
+}
+
+func secondsUntilTomorrow() int64 {
+	now := time.Now()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	return int64(tomorrow.Sub(now).Seconds()) + 1
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing 为每个请求创建服务端 span，客户端携带 traceparent 时作为其子节点；
+// span 保存在 c.Request 的 context 中，后续渠道选择、上游请求与计费的 span 均挂在其下
+func Tracing() gin.HandlerFunc {
+	if !tracing.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return func(c *gin.Context) {
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, c.Request.Method, trace.SpanKindServer,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String("request.id", c.GetString(common.RequestIdKey)),
+		)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if route := c.FullPath(); route != "" {
+			span.SetName(c.Request.Method + " " + route)
+			span.SetAttributes(attribute.String("http.route", route))
+		}
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", status),
+			attribute.Int("user.id", c.GetInt("id")),
+		)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingPropagatesClientTraceparent(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4318")
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Tracing())
+	upstreamHeader := http.Header{}
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "upstream.request", trace.SpanKindClient)
+		tracing.Inject(trace.ContextWithSpan(c.Request.Context(), span), upstreamHeader)
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "upstream.request", spans[0].Name())
+	require.Equal(t, "POST /v1/chat/completions", spans[1].Name())
+	for _, span := range spans {
+		require.Equal(t, traceId, span.SpanContext().TraceID().String())
+	}
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Contains(t, upstreamHeader.Get("traceparent"), traceId+"-"+spans[0].SpanContext().SpanID().String())
+}
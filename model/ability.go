@@ -103,7 +103,7 @@ func getChannelQuery(group string, model string, retry int) (*gorm.DB, error) {
 	return channelQuery, nil
 }
 
-func GetChannel(group string, model string, retry int) (*Channel, error) {
+func GetChannel(group string, model string, retry int, excludeChannelIds ...int) (*Channel, error) {
 	var abilities []Ability
 
 	var err error = nil
@@ -120,21 +120,17 @@ func GetChannel(group string, model string, retry int) (*Channel, error) {
 		return nil, err
 	}
 	channel := Channel{}
+	abilities = excludeFailedChannels(abilities, func(ability Ability) int { return ability.ChannelId }, excludeChannelIds)
+	abilities = excludeOpenCircuits(abilities, func(ability Ability) int { return ability.ChannelId }, model)
 	if len(abilities) > 0 {
-		// Randomly choose one
-		weightSum := uint(0)
-		for _, ability_ := range abilities {
-			weightSum += ability_.Weight + 10
+		channelIds := make([]int, 0, len(abilities))
+		for _, ability := range abilities {
+			channelIds = append(channelIds, ability.ChannelId)
 		}
-		// Randomly choose one
-		weight := common.GetRandomInt(int(weightSum))
-		for _, ability_ := range abilities {
-			weight -= int(ability_.Weight) + 10
-			//log.Printf("weight: %d, ability weight: %d", weight, *ability_.Weight)
-			if weight <= 0 {
-				channel.Id = ability_.ChannelId
-				break
-			}
+		if channelId, ok := selectFastestChannelId(channelIds, model); ok && len(abilities) > 1 {
+			channel.Id = channelId
+		} else {
+			channel.Id = selectAbilityByWeight(abilities).ChannelId
 		}
 	} else {
 		return nil, nil
@@ -143,6 +139,27 @@ func GetChannel(group string, model string, retry int) (*Channel, error) {
 	return &channel, err
 }
 
+// selectAbilityByWeight 按权重随机选择渠道，流量与权重成正比（如 80/20），与内存缓存的选择逻辑一致；权重全为 0 时均匀选择
+func selectAbilityByWeight(abilities []Ability) Ability {
+	weightSum := 0
+	for _, ability := range abilities {
+		weightSum += int(ability.Weight)
+	}
+	adjustment := 0
+	if weightSum == 0 {
+		adjustment = 1
+		weightSum = len(abilities)
+	}
+	weight := common.GetRandomInt(weightSum)
+	for _, ability := range abilities {
+		weight -= int(ability.Weight) + adjustment
+		if weight < 0 {
+			return ability
+		}
+	}
+	return abilities[len(abilities)-1]
+}
+
 func (channel *Channel) AddAbilities(tx *gorm.DB) error {
 	models_ := strings.Split(channel.Models, ",")
 	groups_ := strings.Split(channel.Group, ",")
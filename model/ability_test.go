@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectAbilityByWeight(t *testing.T) {
+	abilities := []Ability{
+		{ChannelId: 1, Weight: 80},
+		{ChannelId: 2, Weight: 20},
+		{ChannelId: 3, Weight: 0},
+	}
+	counts := make(map[int]int)
+	for i := 0; i < 10000; i++ {
+		counts[selectAbilityByWeight(abilities).ChannelId]++
+	}
+	require.InDelta(t, 8000, counts[1], 400)
+	require.InDelta(t, 2000, counts[2], 400)
+	require.Zero(t, counts[3])
+
+	// 权重全为 0 时均匀选择
+	unweighted := []Ability{{ChannelId: 1}, {ChannelId: 2}}
+	counts = make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		counts[selectAbilityByWeight(unweighted).ChannelId]++
+	}
+	require.Positive(t, counts[1])
+	require.Positive(t, counts[2])
+}
+
+func TestExcludeFailedChannels(t *testing.T) {
+	abilities := []Ability{{ChannelId: 1}, {ChannelId: 2}, {ChannelId: 3}}
+	channelId := func(ability Ability) int { return ability.ChannelId }
+
+	filtered := excludeFailedChannels(abilities, channelId, []int{1, 3})
+	require.Len(t, filtered, 1)
+	require.Equal(t, 2, filtered[0].ChannelId)
+
+	// 全部失败时保留原列表
+	require.Len(t, excludeFailedChannels(abilities, channelId, []int{1, 2, 3}), 3)
+	require.Len(t, excludeFailedChannels(abilities, channelId, nil), 3)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AssistantObjectAssistant = "assistant"
+	AssistantObjectThread    = "thread"
+	AssistantObjectRun       = "thread.run"
+)
+
+// AssistantResource Assistants API 资源与上游渠道的映射。网关直接返回上游 ID，
+// 之后引用该 ID 的请求据此固定到同一渠道并校验归属
+type AssistantResource struct {
+	Id         int    `json:"id"`
+	ResourceId string `json:"resource_id" gorm:"type:varchar(128);uniqueIndex"`
+	Object     string `json:"object" gorm:"type:varchar(32);index:idx_assistant_resource_user_object,priority:2"`
+	// ParentId 运行所属的线程 ID，删除线程时一并清理
+	ParentId  string `json:"parent_id,omitempty" gorm:"type:varchar(128);index"`
+	UserId    int    `json:"user_id" gorm:"index:idx_assistant_resource_user_object,priority:1"`
+	TokenId   int    `json:"token_id"`
+	ChannelId int    `json:"channel_id"`
+	Model     string `json:"model,omitempty" gorm:"size:128"`
+	// Group 创建运行时的分组，结算时按该分组倍率计费
+	Group string `json:"group,omitempty" gorm:"type:varchar(64)"`
+	// PreConsumedQuota 创建运行时预扣的额度，结算时按实际用量多退少补
+	PreConsumedQuota int `json:"pre_consumed_quota" gorm:"default:0"`
+	// Billed 运行结束并完成结算后置为 true，保证每个运行只结算一次
+	Billed    bool  `json:"billed" gorm:"default:false;index"`
+	CreatedAt int64 `json:"created_at" gorm:"bigint"`
+}
+
+// Insert 记录映射，同一资源重复记录时保留最早的一条
+func (r *AssistantResource) Insert() error {
+	r.CreatedAt = common.GetTimestamp()
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(r).Error
+}
+
+func GetAssistantResource(resourceId string) (*AssistantResource, error) {
+	var resource AssistantResource
+	err := DB.First(&resource, "resource_id = ?", resourceId).Error
+	return &resource, err
+}
+
+// GetUserAssistantResources 按创建时间倒序列出用户某类资源
+func GetUserAssistantResources(userId int, object string, startIdx int, num int) ([]*AssistantResource, error) {
+	var resources []*AssistantResource
+	err := DB.Where("user_id = ? AND object = ?", userId, object).
+		Order("id DESC").Offset(startIdx).Limit(num).Find(&resources).Error
+	return resources, err
+}
+
+// DeleteAssistantResource 删除映射，删除线程时其下运行的映射一并删除
+func DeleteAssistantResource(resourceId string) error {
+	return DB.Where("resource_id = ? OR parent_id = ?", resourceId, resourceId).Delete(&AssistantResource{}).Error
+}
+
+// GetUnbilledAssistantRuns 按创建时间正序列出尚未结算的运行，供后台轮询结算
+func GetUnbilledAssistantRuns(limit int) ([]*AssistantResource, error) {
+	var resources []*AssistantResource
+	err := DB.Where("object = ? AND billed = ?", AssistantObjectRun, false).
+		Order("id ASC").Limit(limit).Find(&resources).Error
+	return resources, err
+}
+
+// MarkAssistantRunBilled 原子地将运行标记为已计费，返回 false 表示已被其他请求计费
+func MarkAssistantRunBilled(resourceId string) (bool, error) {
+	result := DB.Model(&AssistantResource{}).
+		Where("resource_id = ? AND object = ? AND billed = ?", resourceId, AssistantObjectRun, false).
+		Update("billed", true)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssistantResourceMapping(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM assistant_resources")
+	})
+	require.NoError(t, (&AssistantResource{ResourceId: "thread_1", Object: AssistantObjectThread, UserId: 1, ChannelId: 3}).Insert())
+	// 重复记录保留最早的渠道
+	require.NoError(t, (&AssistantResource{ResourceId: "thread_1", Object: AssistantObjectThread, UserId: 1, ChannelId: 4}).Insert())
+	require.NoError(t, (&AssistantResource{ResourceId: "run_1", Object: AssistantObjectRun, ParentId: "thread_1", UserId: 1, ChannelId: 3}).Insert())
+
+	thread, err := GetAssistantResource("thread_1")
+	require.NoError(t, err)
+	require.Equal(t, 3, thread.ChannelId)
+
+	threads, err := GetUserAssistantResources(1, AssistantObjectThread, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, threads, 1)
+
+	billed, err := MarkAssistantRunBilled("run_1")
+	require.NoError(t, err)
+	require.True(t, billed)
+	billed, err = MarkAssistantRunBilled("run_1")
+	require.NoError(t, err)
+	require.False(t, billed)
+
+	require.NoError(t, DeleteAssistantResource("thread_1"))
+	_, err = GetAssistantResource("run_1")
+	require.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCompleted  = "completed"
+	BatchStatusCancelled  = "cancelled"
+	BatchStatusExpired    = "expired"
+)
+
+const (
+	BatchItemStatusPending   = 1
+	BatchItemStatusRunning   = 2
+	BatchItemStatusSucceeded = 3
+	BatchItemStatusFailed    = 4
+	BatchItemStatusCancelled = 5
+)
+
+// Batch 离线批量推理任务，请求逐条保存在 BatchItem 中，由主节点后台调度执行
+type Batch struct {
+	Id             int    `json:"-"`
+	BatchId        string `json:"id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId         int    `json:"-" gorm:"index"`
+	TokenId        int    `json:"-"`
+	ClientIp       string `json:"-" gorm:"type:varchar(64)"`
+	InputFileId    string `json:"input_file_id,omitempty" gorm:"type:varchar(64)"`
+	Endpoint       string `json:"endpoint" gorm:"type:varchar(64)"`
+	Status         string `json:"status" gorm:"type:varchar(20);index"`
+	TotalCount     int    `json:"total_count"`
+	CompletedCount int    `json:"completed_count"`
+	FailedCount    int    `json:"failed_count"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt      int64  `json:"expires_at" gorm:"bigint;index"`
+	FinishedAt     int64  `json:"finished_at,omitempty" gorm:"bigint;index"`
+}
+
+// BatchItem 批次中的单条请求
+type BatchItem struct {
+	Id            int             `json:"-"`
+	BatchId       int             `json:"-" gorm:"index:idx_batch_item_batch_line,priority:1"`
+	LineIndex     int             `json:"line_index" gorm:"index:idx_batch_item_batch_line,priority:2"`
+	CustomId      string          `json:"custom_id" gorm:"type:varchar(128)"`
+	Body          json.RawMessage `json:"-" gorm:"type:json"`
+	Status        int             `json:"status" gorm:"index"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt int64           `json:"-" gorm:"bigint;index"`
+	StatusCode    int             `json:"status_code"`
+	Response      json.RawMessage `json:"response" gorm:"type:json"`
+	Error         string          `json:"error,omitempty" gorm:"type:text"`
+	UpdatedAt     int64           `json:"updated_at" gorm:"bigint"`
+}
+
+// CreateBatch 在同一事务中写入批次及全部请求
+func CreateBatch(batch *Batch, items []*BatchItem) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			item.BatchId = batch.Id
+		}
+		return tx.CreateInBatches(items, 200).Error
+	})
+}
+
+func GetUserBatch(userId int, batchId string) (*Batch, error) {
+	var batch Batch
+	err := DB.First(&batch, "batch_id = ? AND user_id = ?", batchId, userId).Error
+	return &batch, err
+}
+
+func GetUserBatches(userId int, limit int) ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("user_id = ?", userId).Order("id DESC").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+func GetBatchById(id int) (*Batch, error) {
+	var batch Batch
+	err := DB.First(&batch, "id = ?", id).Error
+	return &batch, err
+}
+
+// GetFinishedBatchItems 按行号分页获取已结束的请求，用于下载部分结果；未指定 statuses 时返回成功与失败的请求
+func GetFinishedBatchItems(batchId int, afterLine int, limit int, statuses ...int) ([]*BatchItem, error) {
+	if len(statuses) == 0 {
+		statuses = []int{BatchItemStatusSucceeded, BatchItemStatusFailed}
+	}
+	var items []*BatchItem
+	err := DB.Where("batch_id = ? AND line_index > ? AND status IN ?", batchId, afterLine, statuses).
+		Order("line_index ASC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// CancelBatch 取消批次，尚未执行的请求标记为已取消，执行中的请求结束后照常记录
+func CancelBatch(batch *Batch) error {
+	return finishBatch(batch.Id, BatchStatusCancelled, BatchItemStatusCancelled, "batch cancelled")
+}
+
+// ExpireBatches 将超过完成时限的批次标记为过期，返回处理的批次数
+func ExpireBatches(now int64) (int, error) {
+	var ids []int
+	if err := DB.Model(&Batch{}).Where("status = ? AND expires_at <= ?", BatchStatusInProgress, now).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := finishBatch(id, BatchStatusExpired, BatchItemStatusFailed, "batch expired"); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+func finishBatch(id int, status string, itemStatus int, reason string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		now := common.GetTimestamp()
+		result := tx.Model(&BatchItem{}).Where("batch_id = ? AND status = ?", id, BatchItemStatusPending).
+			Updates(map[string]interface{}{"status": itemStatus, "error": reason, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		updates := map[string]interface{}{"status": status, "finished_at": now}
+		if itemStatus == BatchItemStatusFailed {
+			updates["failed_count"] = gorm.Expr("failed_count + ?", result.RowsAffected)
+		}
+		return tx.Model(&Batch{}).Where("id = ? AND status = ?", id, BatchStatusInProgress).Updates(updates).Error
+	})
+}
+
+// ClaimBatchItems 领取可执行的请求并标记为执行中，仅由主节点调用
+func ClaimBatchItems(now int64, limit int) ([]*BatchItem, error) {
+	var items []*BatchItem
+	err := DB.Model(&BatchItem{}).
+		Joins("JOIN batches ON batches.id = batch_items.batch_id").
+		Where("batch_items.status = ? AND batch_items.next_attempt_at <= ? AND batches.status = ?", BatchItemStatusPending, now, BatchStatusInProgress).
+		Order("batch_items.id ASC").Limit(limit).
+		Select("batch_items.*").Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+	err = DB.Model(&BatchItem{}).Where("id IN ? AND status = ?", ids, BatchItemStatusPending).
+		Updates(map[string]interface{}{"status": BatchItemStatusRunning, "updated_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ResetRunningBatchItems 进程重启后将中断的请求重新放回队列
+func ResetRunningBatchItems() error {
+	return DB.Model(&BatchItem{}).Where("status = ?", BatchItemStatusRunning).
+		Update("status", BatchItemStatusPending).Error
+}
+
+// RetryBatchItem 请求失败但可重试时放回队列
+func RetryBatchItem(item *BatchItem, nextAttemptAt int64, reason string) error {
+	return DB.Model(&BatchItem{}).Where("id = ?", item.Id).Updates(map[string]interface{}{
+		"status":          BatchItemStatusPending,
+		"attempts":        item.Attempts,
+		"next_attempt_at": nextAttemptAt,
+		"error":           reason,
+		"updated_at":      common.GetTimestamp(),
+	}).Error
+}
+
+// CompleteBatchItem 记录请求结果并累加批次计数，全部请求结束后批次标记为完成
+func CompleteBatchItem(item *BatchItem) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		now := common.GetTimestamp()
+		err := tx.Model(&BatchItem{}).Where("id = ? AND status = ?", item.Id, BatchItemStatusRunning).Updates(map[string]interface{}{
+			"status":      item.Status,
+			"attempts":    item.Attempts,
+			"status_code": item.StatusCode,
+			"response":    item.Response,
+			"error":       item.Error,
+			"updated_at":  now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		counter := "completed_count"
+		if item.Status != BatchItemStatusSucceeded {
+			counter = "failed_count"
+		}
+		if err := tx.Model(&Batch{}).Where("id = ?", item.BatchId).
+			Update(counter, gorm.Expr(counter+" + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Batch{}).
+			Where("id = ? AND status = ? AND completed_count + failed_count >= total_count", item.BatchId, BatchStatusInProgress).
+			Updates(map[string]interface{}{"status": BatchStatusCompleted, "finished_at": now}).Error
+	})
+}
+
+// DeleteFinishedBatchesBefore 清理结束时间早于 cutoff 的批次及其请求
+func DeleteFinishedBatchesBefore(cutoff int64) error {
+	var ids []int
+	if err := DB.Model(&Batch{}).Where("status <> ? AND finished_at > 0 AND finished_at < ?", BatchStatusInProgress, cutoff).
+		Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("batch_id IN ?", ids).Delete(&BatchItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Batch{}).Error
+	})
+}
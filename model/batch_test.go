@@ -0,0 +1,120 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchItemLifecycle(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM batch_items")
+		DB.Exec("DELETE FROM batches")
+	})
+	batch := &Batch{BatchId: "batch_test", UserId: 1, Endpoint: "/v1/chat/completions", Status: BatchStatusInProgress, TotalCount: 3, ExpiresAt: 1 << 40}
+	items := make([]*BatchItem, 3)
+	for i := range items {
+		items[i] = &BatchItem{LineIndex: i, CustomId: string(rune('a' + i)), Body: json.RawMessage(`{"model":"m"}`), Status: BatchItemStatusPending}
+	}
+	require.NoError(t, CreateBatch(batch, items))
+
+	claimed, err := ClaimBatchItems(100, 2)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	// 执行中的请求不会被重复领取
+	rest, err := ClaimBatchItems(100, 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+
+	claimed[0].Status = BatchItemStatusSucceeded
+	claimed[0].StatusCode = 200
+	claimed[0].Response = json.RawMessage(`{"ok":true}`)
+	require.NoError(t, CompleteBatchItem(claimed[0]))
+
+	claimed[1].Attempts = 1
+	require.NoError(t, RetryBatchItem(claimed[1], 200, "rate limited"))
+	retry, err := ClaimBatchItems(150, 10)
+	require.NoError(t, err)
+	require.Empty(t, retry)
+	retry, err = ClaimBatchItems(200, 10)
+	require.NoError(t, err)
+	require.Len(t, retry, 1)
+	retry[0].Status = BatchItemStatusFailed
+	retry[0].StatusCode = 429
+	require.NoError(t, CompleteBatchItem(retry[0]))
+
+	loaded, err := GetUserBatch(1, "batch_test")
+	require.NoError(t, err)
+	require.Equal(t, BatchStatusInProgress, loaded.Status)
+	require.Equal(t, 1, loaded.CompletedCount)
+	require.Equal(t, 1, loaded.FailedCount)
+
+	rest[0].Status = BatchItemStatusSucceeded
+	require.NoError(t, CompleteBatchItem(rest[0]))
+	loaded, err = GetUserBatch(1, "batch_test")
+	require.NoError(t, err)
+	require.Equal(t, BatchStatusCompleted, loaded.Status)
+	require.NotZero(t, loaded.FinishedAt)
+
+	finished, err := GetFinishedBatchItems(batch.Id, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, finished, 2)
+	require.Equal(t, 1, finished[0].LineIndex)
+
+	failed, err := GetFinishedBatchItems(batch.Id, -1, 10, BatchItemStatusFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+}
+
+func TestCancelBatch(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM batch_items")
+		DB.Exec("DELETE FROM batches")
+	})
+	batch := &Batch{BatchId: "batch_cancel", UserId: 2, Status: BatchStatusInProgress, TotalCount: 2, ExpiresAt: 1 << 40}
+	items := []*BatchItem{
+		{LineIndex: 0, CustomId: "a", Body: json.RawMessage(`{}`), Status: BatchItemStatusPending},
+		{LineIndex: 1, CustomId: "b", Body: json.RawMessage(`{}`), Status: BatchItemStatusPending},
+	}
+	require.NoError(t, CreateBatch(batch, items))
+	require.NoError(t, CancelBatch(batch))
+
+	loaded, err := GetUserBatch(2, "batch_cancel")
+	require.NoError(t, err)
+	require.Equal(t, BatchStatusCancelled, loaded.Status)
+	claimed, err := ClaimBatchItems(1<<40, 10)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
+
+func TestMigrateLegacyBatchFiles(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM user_files")
+		DB.Exec("DROP TABLE IF EXISTS batch_files")
+	})
+	// 早期版本的批量输入文件表
+	require.NoError(t, DB.Exec(`CREATE TABLE batch_files (id integer PRIMARY KEY, file_id varchar(64), user_id integer,
+		filename varchar(255), purpose varchar(32), bytes integer, content blob, created_at bigint)`).Error)
+	require.NoError(t, DB.Exec(`INSERT INTO batch_files (file_id, user_id, filename, purpose, bytes, content, created_at)
+		VALUES ('file-abc', 3, 'in.jsonl', 'batch', 2, '{}', 100)`).Error)
+	require.NoError(t, (&UserFile{FileId: "file-new", UserId: 3, Purpose: "batch", Storage: "local", StorageKey: "3/file-new"}).Insert())
+
+	require.NoError(t, migrateLegacyBatchFiles())
+	require.False(t, DB.Migrator().HasTable("batch_files"))
+
+	meta, err := GetUserFile(3, "file-abc", false)
+	require.NoError(t, err)
+	require.Empty(t, meta.Content)
+	full, err := GetUserFile(3, "file-abc", true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("{}"), full.Content)
+	require.Equal(t, "database", full.Storage)
+	require.EqualValues(t, 100, full.CreatedAt)
+	_, err = GetUserFile(4, "file-abc", false)
+	require.Error(t, err)
+
+	// 再次执行不报错
+	require.NoError(t, migrateLegacyBatchFiles())
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/samber/lo"
@@ -205,6 +207,10 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	if len(enabledIdx) == 0 {
 		return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
 	}
+	// 跳过冷却中的 Key
+	if operation_setting.GetMultiKeyCooldownSetting().Enabled {
+		enabledIdx = filterCoolingDownKeys(channel.Id, keys, enabledIdx)
+	}
 
 	switch channel.ChannelInfo.MultiKeyMode {
 	case constant.MultiKeyModeRandom:
@@ -236,7 +242,7 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		}
 		for i := 0; i < len(keys); i++ {
 			idx := (start + i) % len(keys)
-			if getStatus(idx) == common.ChannelStatusEnabled {
+			if slices.Contains(enabledIdx, idx) {
 				// update polling index for next call (point to the next position)
 				channel.ChannelInfo.MultiKeyPollingIndex = (idx + 1) % len(keys)
 				return keys[idx], idx, nil
@@ -404,6 +410,13 @@ func GetChannelById(id int, selectAll bool) (*Channel, error) {
 	return channel, nil
 }
 
+// GetChannelsByName 按名称精确查询渠道（不含密钥），供幂等 upsert 使用
+func GetChannelsByName(name string) ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Omit("key").Where("name = ?", name).Order("id asc").Find(&channels).Error
+	return channels, err
+}
+
 func BatchInsertChannels(channels []Channel) error {
 	if len(channels) == 0 {
 		return nil
@@ -504,8 +517,8 @@ func (channel *Channel) Insert() error {
 	return err
 }
 
-func (channel *Channel) Update() error {
-	// If this is a multi-key channel, recalculate MultiKeySize based on the current key list to avoid inconsistency after editing keys
+// refreshMultiKeySize 多 Key 渠道根据当前密钥列表重新计算 MultiKeySize，避免编辑密钥后数量不一致
+func (channel *Channel) refreshMultiKeySize() {
 	if channel.ChannelInfo.IsMultiKey {
 		var keyStr string
 		if channel.Key != "" {
@@ -543,6 +556,11 @@ func (channel *Channel) Update() error {
 			}
 		}
 	}
+}
+
+func (channel *Channel) Update() error {
+	// If this is a multi-key channel, recalculate MultiKeySize based on the current key list to avoid inconsistency after editing keys
+	channel.refreshMultiKeySize()
 	var err error
 	err = DB.Model(channel).Updates(channel).Error
 	if err != nil {
@@ -553,6 +571,37 @@ func (channel *Channel) Update() error {
 	return err
 }
 
+// channelReplaceColumns Replace 覆盖写入的配置列，运行时统计字段（余额、用量、测速等）与创建时间不在其中
+var channelReplaceColumns = []string{
+	"type", "openai_organization", "test_model", "status", "name", "weight", "base_url", "other",
+	"models", "group", "model_mapping", "status_code_mapping", "priority", "auto_ban", "other_info",
+	"tag", "setting", "param_override", "header_override", "remark", "channel_info", "settings",
+}
+
+// Replace 以完整的期望状态覆盖渠道配置，与 Update 不同，零值字段（如 status、空 models）同样会写入；
+// key 为空时保留原密钥。
+func (channel *Channel) Replace() error {
+	if channel.Weight == nil {
+		channel.Weight = lo.ToPtr(uint(0))
+	}
+	if channel.Priority == nil {
+		channel.Priority = lo.ToPtr(int64(0))
+	}
+	if channel.AutoBan == nil {
+		channel.AutoBan = lo.ToPtr(1)
+	}
+	channel.refreshMultiKeySize()
+	columns := channelReplaceColumns
+	if channel.Key != "" {
+		columns = append(append([]string{}, channelReplaceColumns...), "key")
+	}
+	if err := DB.Model(channel).Select(columns).Updates(channel).Error; err != nil {
+		return err
+	}
+	DB.Model(channel).First(channel, "id = ?", channel.Id)
+	return channel.UpdateAbilities(nil)
+}
+
 func (channel *Channel) UpdateResponseTime(responseTime int64) {
 	err := DB.Model(channel).Select("response_time", "test_time").Updates(Channel{
 		TestTime:     common.GetTimestamp(),
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -11,6 +12,9 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	channelmetrics "github.com/QuantumNous/new-api/pkg/channel_metrics"
+	circuitbreaker "github.com/QuantumNous/new-api/pkg/circuit_breaker"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
@@ -93,10 +97,20 @@ func SyncChannelCache(frequency int) {
 	}
 }
 
-func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel, error) {
+// GetRandomSatisfiedChannel 按重试次数对应的优先级选择渠道，excludeChannelIds 为本次请求已失败的渠道，
+// 同优先级中还有其他渠道时不会再次选中；熔断中的渠道同样被跳过
+func GetRandomSatisfiedChannel(group string, model string, retry int, excludeChannelIds ...int) (*Channel, error) {
+	channel, err := getRandomSatisfiedChannel(group, model, retry, excludeChannelIds)
+	if channel != nil {
+		circuitbreaker.OnSelected(channel.Id, model)
+	}
+	return channel, err
+}
+
+func getRandomSatisfiedChannel(group string, model string, retry int, excludeChannelIds []int) (*Channel, error) {
 	// if memory cache is disabled, get channel directly from database
 	if !common.MemoryCacheEnabled {
-		return GetChannel(group, model, retry)
+		return GetChannel(group, model, retry, excludeChannelIds...)
 	}
 
 	channelSyncLock.RLock()
@@ -158,6 +172,18 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	if len(targetChannels) == 0 {
 		return nil, errors.New(fmt.Sprintf("no channel found, group: %s, model: %s, priority: %d", group, model, targetPriority))
 	}
+	targetChannels = excludeFailedChannels(targetChannels, func(channel *Channel) int { return channel.Id }, excludeChannelIds)
+	targetChannels = excludeOpenCircuits(targetChannels, func(channel *Channel) int { return channel.Id }, model)
+
+	if len(targetChannels) > 1 {
+		channelIds := make([]int, 0, len(targetChannels))
+		for _, channel := range targetChannels {
+			channelIds = append(channelIds, channel.Id)
+		}
+		if channelId, ok := selectFastestChannelId(channelIds, model); ok {
+			return channelsIDM[channelId], nil
+		}
+	}
 
 	// smoothing factor and adjustment
 	smoothingFactor := 1
@@ -190,6 +216,46 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	return nil, errors.New("channel not found")
 }
 
+// excludeFailedChannels 过滤本次请求已失败的渠道，全部失败时保留原列表，仍按原逻辑重试
+func excludeFailedChannels[T any](items []T, channelId func(T) int, excludeChannelIds []int) []T {
+	if len(excludeChannelIds) == 0 {
+		return items
+	}
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(excludeChannelIds, channelId(item)) {
+			filtered = append(filtered, item)
+		}
+	}
+	if len(filtered) == 0 {
+		return items
+	}
+	return filtered
+}
+
+// excludeOpenCircuits 过滤该模型熔断中的渠道，全部熔断时保留原列表，避免请求直接失败
+func excludeOpenCircuits[T any](items []T, channelId func(T) int, model string) []T {
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if circuitbreaker.Available(channelId(item), model) {
+			filtered = append(filtered, item)
+		}
+	}
+	if len(filtered) == 0 {
+		return items
+	}
+	return filtered
+}
+
+// selectFastestChannelId 延迟优先模式下从同优先级渠道中选择最快的健康渠道，未启用或样本不足时返回 false
+func selectFastestChannelId(channelIds []int, model string) (int, bool) {
+	routingSetting := operation_setting.GetChannelRoutingSetting()
+	if !routingSetting.IsLatencyMode() {
+		return 0, false
+	}
+	return channelmetrics.SelectFastest(channelIds, model, routingSetting.Window(), routingSetting.MinSamples, routingSetting.MaxErrorRate)
+}
+
 func CacheGetChannel(id int) (*Channel, error) {
 	if !common.MemoryCacheEnabled {
 		return GetChannelById(id, true)
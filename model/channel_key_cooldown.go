@@ -0,0 +1,108 @@
+package model
+
+import (
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// ChannelKeyCooldown 多 Key 渠道中单个 Key 的冷却状态
+type ChannelKeyCooldown struct {
+	Until      int64  `json:"until"`
+	Strikes    int    `json:"strikes"`
+	StatusCode int    `json:"status_code"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// channelKeyCooldowns channelId -> key -> 冷却状态；按 Key 内容而非索引记录，编辑密钥列表后不会错位。
+// 冷却状态仅保存在当前节点内存中
+var (
+	channelKeyCooldowns     = make(map[int]map[string]*ChannelKeyCooldown)
+	channelKeyCooldownsLock sync.RWMutex
+)
+
+// CooldownChannelKey 让多 Key 渠道中的 Key 进入冷却，距上次冷却结束不超过下一次冷却时长时视为连续冷却，时长翻倍
+func CooldownChannelKey(channelId int, key string, statusCode int, reason string) *ChannelKeyCooldown {
+	setting := operation_setting.GetMultiKeyCooldownSetting()
+	now := common.GetTimestamp()
+	channelKeyCooldownsLock.Lock()
+	defer channelKeyCooldownsLock.Unlock()
+	keys, ok := channelKeyCooldowns[channelId]
+	if !ok {
+		keys = make(map[string]*ChannelKeyCooldown)
+		channelKeyCooldowns[channelId] = keys
+	}
+	cooldown, ok := keys[key]
+	if !ok || now-cooldown.Until > setting.CooldownSecondsFor(cooldown.Strikes+1) {
+		cooldown = &ChannelKeyCooldown{}
+		keys[key] = cooldown
+	}
+	if cooldown.Until > now {
+		// 冷却期间的并发失败不重复累计
+		return cooldown
+	}
+	cooldown.Strikes++
+	cooldown.Until = now + setting.CooldownSecondsFor(cooldown.Strikes)
+	cooldown.StatusCode = statusCode
+	cooldown.Reason = reason
+	return cooldown
+}
+
+// GetChannelKeyCooldown 返回 Key 当前的冷却状态，不在冷却中时返回 nil
+func GetChannelKeyCooldown(channelId int, key string) *ChannelKeyCooldown {
+	channelKeyCooldownsLock.RLock()
+	defer channelKeyCooldownsLock.RUnlock()
+	cooldown, ok := channelKeyCooldowns[channelId][key]
+	if !ok || cooldown.Until <= common.GetTimestamp() {
+		return nil
+	}
+	copied := *cooldown
+	return &copied
+}
+
+// ClearChannelKeyCooldowns 清除渠道所有 Key 的冷却状态
+func ClearChannelKeyCooldowns(channelId int) int {
+	channelKeyCooldownsLock.Lock()
+	defer channelKeyCooldownsLock.Unlock()
+	count := 0
+	now := common.GetTimestamp()
+	for _, cooldown := range channelKeyCooldowns[channelId] {
+		if cooldown.Until > now {
+			count++
+		}
+	}
+	delete(channelKeyCooldowns, channelId)
+	return count
+}
+
+// channelKeyCoolingDownUntil 返回冷却中的 Key 的冷却结束时间，未冷却时返回 0
+func channelKeyCoolingDownUntil(channelId int, key string, now int64) int64 {
+	channelKeyCooldownsLock.RLock()
+	defer channelKeyCooldownsLock.RUnlock()
+	if cooldown, ok := channelKeyCooldowns[channelId][key]; ok && cooldown.Until > now {
+		return cooldown.Until
+	}
+	return 0
+}
+
+// filterCoolingDownKeys 过滤冷却中的 Key 索引；全部冷却时返回最早结束冷却的 Key，避免渠道直接不可用
+func filterCoolingDownKeys(channelId int, keys []string, enabledIdx []int) []int {
+	now := common.GetTimestamp()
+	available := make([]int, 0, len(enabledIdx))
+	earliestIdx, earliestUntil := -1, int64(0)
+	for _, idx := range enabledIdx {
+		until := channelKeyCoolingDownUntil(channelId, keys[idx], now)
+		if until == 0 {
+			available = append(available, idx)
+			continue
+		}
+		if earliestIdx < 0 || until < earliestUntil {
+			earliestIdx, earliestUntil = idx, until
+		}
+	}
+	if len(available) == 0 && earliestIdx >= 0 {
+		return []int{earliestIdx}
+	}
+	return available
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelKeyCooldownSkipsKey(t *testing.T) {
+	setting := operation_setting.GetMultiKeyCooldownSetting()
+	original := *setting
+	setting.Enabled = true
+	setting.CooldownSeconds = 60
+	setting.MaxCooldownSeconds = 600
+	t.Cleanup(func() {
+		*setting = original
+		ClearChannelKeyCooldowns(9001)
+	})
+
+	channel := &Channel{
+		Id:  9001,
+		Key: "key-a\nkey-b",
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:   true,
+			MultiKeySize: 2,
+			MultiKeyMode: constant.MultiKeyModeRandom,
+		},
+	}
+
+	first := CooldownChannelKey(channel.Id, "key-a", 429, "rate limited")
+	require.Equal(t, 1, first.Strikes)
+	// 冷却期间的重复失败不累计
+	again := CooldownChannelKey(channel.Id, "key-a", 429, "rate limited")
+	require.Equal(t, 1, again.Strikes)
+	require.Equal(t, first.Until, again.Until)
+
+	for i := 0; i < 20; i++ {
+		key, idx, err := channel.GetNextEnabledKey()
+		require.Nil(t, err)
+		require.Equal(t, "key-b", key)
+		require.Equal(t, 1, idx)
+	}
+	require.NotNil(t, GetChannelKeyCooldown(channel.Id, "key-a"))
+	require.Nil(t, GetChannelKeyCooldown(channel.Id, "key-b"))
+
+	// 全部冷却时仍返回最早结束冷却的 Key，渠道不会直接不可用
+	CooldownChannelKey(channel.Id, "key-b", 401, "invalid key")
+	channelKeyCooldowns[channel.Id]["key-b"].Until = first.Until + 100
+	key, _, err := channel.GetNextEnabledKey()
+	require.Nil(t, err)
+	require.Equal(t, "key-a", key)
+
+	require.Equal(t, 2, ClearChannelKeyCooldowns(channel.Id))
+	require.Nil(t, GetChannelKeyCooldown(channel.Id, "key-a"))
+}
+
+func TestChannelKeyCooldownBackoff(t *testing.T) {
+	setting := operation_setting.GetMultiKeyCooldownSetting()
+	original := *setting
+	setting.CooldownSeconds = 60
+	setting.MaxCooldownSeconds = 200
+	t.Cleanup(func() { *setting = original })
+
+	require.EqualValues(t, 60, setting.CooldownSecondsFor(1))
+	require.EqualValues(t, 120, setting.CooldownSecondsFor(2))
+	require.EqualValues(t, 200, setting.CooldownSecondsFor(3))
+	require.EqualValues(t, 200, setting.CooldownSecondsFor(10))
+}
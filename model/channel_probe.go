@@ -0,0 +1,159 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChannelProbeLog 渠道拨测记录，每次定时拨测对渠道的每个模型各写一条
+type ChannelProbeLog struct {
+	Id         int    `json:"id"`
+	ChannelId  int    `json:"channel_id" gorm:"index:idx_channel_probe_channel_created,priority:1"`
+	Model      string `json:"model" gorm:"type:varchar(255)"`
+	Success    bool   `json:"success"`
+	LatencyMs  int64  `json:"latency_ms"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error" gorm:"type:text"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index;index:idx_channel_probe_channel_created,priority:2"`
+}
+
+// ChannelProbeState 渠道拨测状态；Model 为空的行记录渠道级的降权状态，其余行记录各模型的连续失败次数
+type ChannelProbeState struct {
+	ChannelId           int    `json:"channel_id" gorm:"primaryKey;autoIncrement:false"`
+	Model               string `json:"model" gorm:"primaryKey;type:varchar(255)"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Penalized           bool   `json:"penalized"`
+	OriginalPriority    int64  `json:"original_priority"`
+	UpdatedAt           int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func RecordChannelProbe(log *ChannelProbeLog) error {
+	if log.CreatedAt == 0 {
+		log.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(log).Error
+}
+
+// UpdateChannelProbeFailures 根据本次结果更新连续失败次数并返回更新后的值
+func UpdateChannelProbeFailures(channelId int, modelName string, success bool) (int, error) {
+	var state ChannelProbeState
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("channel_id = ? AND model = ?", channelId, modelName).First(&state).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		state.ChannelId = channelId
+		state.Model = modelName
+		if success {
+			state.ConsecutiveFailures = 0
+		} else {
+			state.ConsecutiveFailures++
+		}
+		state.UpdatedAt = common.GetTimestamp()
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "channel_id"}, {Name: "model"}},
+			DoUpdates: clause.AssignmentColumns([]string{"consecutive_failures", "updated_at"}),
+		}).Create(&state).Error
+	})
+	return state.ConsecutiveFailures, err
+}
+
+func GetChannelProbeState(channelId int) (*ChannelProbeState, error) {
+	var state ChannelProbeState
+	err := DB.Where("channel_id = ? AND model = ?", channelId, "").First(&state).Error
+	if err == gorm.ErrRecordNotFound {
+		return &ChannelProbeState{ChannelId: channelId}, nil
+	}
+	return &state, err
+}
+
+// PenalizeChannelPriority 将渠道优先级降低 penalty 并记录原优先级，已降权的渠道不重复降低
+func PenalizeChannelPriority(channelId int, penalty int64) (bool, error) {
+	penalized := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var state ChannelProbeState
+		err := tx.Where("channel_id = ? AND model = ?", channelId, "").First(&state).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if state.Penalized {
+			return nil
+		}
+		var channel Channel
+		if err := tx.Select("id", "priority").First(&channel, "id = ?", channelId).Error; err != nil {
+			return err
+		}
+		original := channel.GetPriority()
+		if err := setChannelPriority(tx, channelId, original-penalty); err != nil {
+			return err
+		}
+		penalized = true
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "channel_id"}, {Name: "model"}},
+			DoUpdates: clause.AssignmentColumns([]string{"penalized", "original_priority", "updated_at"}),
+		}).Create(&ChannelProbeState{
+			ChannelId:        channelId,
+			Penalized:        true,
+			OriginalPriority: original,
+			UpdatedAt:        common.GetTimestamp(),
+		}).Error
+	})
+	return penalized, err
+}
+
+// RestoreChannelPriority 恢复拨测降权前的优先级，未降权时返回 false
+func RestoreChannelPriority(channelId int) (bool, error) {
+	restored := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var state ChannelProbeState
+		err := tx.Where("channel_id = ? AND model = ?", channelId, "").First(&state).Error
+		if err == gorm.ErrRecordNotFound || (err == nil && !state.Penalized) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := setChannelPriority(tx, channelId, state.OriginalPriority); err != nil {
+			return err
+		}
+		restored = true
+		return tx.Where("channel_id = ? AND model = ?", channelId, "").Delete(&ChannelProbeState{}).Error
+	})
+	return restored, err
+}
+
+func setChannelPriority(tx *gorm.DB, channelId int, priority int64) error {
+	if err := tx.Model(&Channel{}).Where("id = ?", channelId).Update("priority", priority).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Ability{}).Where("channel_id = ?", channelId).Update("priority", priority).Error
+}
+
+// GetChannelProbeLogs 分页查询拨测记录，channelId 为 0 时查询全部渠道
+func GetChannelProbeLogs(channelId int, startIdx int, num int) ([]*ChannelProbeLog, int64, error) {
+	var logs []*ChannelProbeLog
+	var total int64
+	query := DB.Model(&ChannelProbeLog{})
+	if channelId != 0 {
+		query = query.Where("channel_id = ?", channelId)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+func GetChannelProbeStates() ([]*ChannelProbeState, error) {
+	var states []*ChannelProbeState
+	err := DB.Order("channel_id, model").Find(&states).Error
+	return states, err
+}
+
+// DeleteChannelProbeLogsBefore 清理过期的拨测记录
+func DeleteChannelProbeLogsBefore(timestamp int64) (int64, error) {
+	result := DB.Where("created_at < ?", timestamp).Delete(&ChannelProbeLog{})
+	return result.RowsAffected, result.Error
+}
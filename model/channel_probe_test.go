@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateChannelProbeFailures(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM channel_probe_states") })
+
+	failures, err := UpdateChannelProbeFailures(1, "gpt-4o", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, failures)
+	failures, err = UpdateChannelProbeFailures(1, "gpt-4o", false)
+	require.NoError(t, err)
+	require.Equal(t, 2, failures)
+
+	// 其他模型独立计数
+	failures, err = UpdateChannelProbeFailures(1, "gpt-4o-mini", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, failures)
+
+	failures, err = UpdateChannelProbeFailures(1, "gpt-4o", true)
+	require.NoError(t, err)
+	require.Zero(t, failures)
+}
+
+func TestPenalizeAndRestoreChannelPriority(t *testing.T) {
+	truncateTables(t)
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM abilities")
+		DB.Exec("DELETE FROM channel_probe_states")
+	})
+
+	priority := int64(10)
+	channel := &Channel{Name: "probe", Key: "sk-test", Models: "gpt-4o", Group: "default", Priority: &priority}
+	require.NoError(t, DB.Create(channel).Error)
+	require.NoError(t, DB.Create(&Ability{Group: "default", Model: "gpt-4o", ChannelId: channel.Id, Enabled: true, Priority: &priority}).Error)
+
+	penalized, err := PenalizeChannelPriority(channel.Id, 100)
+	require.NoError(t, err)
+	require.True(t, penalized)
+	// 已降权的渠道不重复降低
+	penalized, err = PenalizeChannelPriority(channel.Id, 100)
+	require.NoError(t, err)
+	require.False(t, penalized)
+
+	var reloaded Channel
+	require.NoError(t, DB.First(&reloaded, channel.Id).Error)
+	require.Equal(t, int64(-90), reloaded.GetPriority())
+	var ability Ability
+	require.NoError(t, DB.Where("channel_id = ?", channel.Id).First(&ability).Error)
+	require.Equal(t, int64(-90), *ability.Priority)
+
+	restored, err := RestoreChannelPriority(channel.Id)
+	require.NoError(t, err)
+	require.True(t, restored)
+	require.NoError(t, DB.First(&reloaded, channel.Id).Error)
+	require.Equal(t, int64(10), reloaded.GetPriority())
+
+	restored, err = RestoreChannelPriority(channel.Id)
+	require.NoError(t, err)
+	require.False(t, restored)
+}
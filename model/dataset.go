@@ -0,0 +1,161 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DatasetSampleStatusPending  = 1
+	DatasetSampleStatusApproved = 2
+	DatasetSampleStatusRejected = 3
+)
+
+// DatasetSample 采集到的一次聊天交互，Messages 为 OpenAI 格式的消息数组（最后一条为模型回复）
+type DatasetSample struct {
+	Id        int       `json:"id"`
+	RequestId string    `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId    int       `json:"user_id" gorm:"index"`
+	TokenId   int       `json:"token_id"`
+	ModelName string    `json:"model_name" gorm:"size:128;index"`
+	Group     string    `json:"group" gorm:"size:64"`
+	Rating    *int      `json:"rating" gorm:"index"`
+	Messages  JSONValue `json:"messages" gorm:"type:json"`
+	// Tags 以逗号包裹存储（如 ",code,zh,"），便于在各数据库中按标签过滤
+	Tags      string   `json:"-" gorm:"type:varchar(512)"`
+	TagList   []string `json:"tags" gorm:"-"`
+	Status    int      `json:"status" gorm:"default:1;index"`
+	Note      string   `json:"note,omitempty" gorm:"type:varchar(512)"`
+	CreatedAt int64    `json:"created_at" gorm:"bigint;index"`
+	UpdatedAt int64    `json:"updated_at" gorm:"bigint"`
+}
+
+// DatasetSampleFilter 数据集筛选条件，零值字段不参与过滤
+type DatasetSampleFilter struct {
+	ModelName      string
+	UserId         int
+	Status         int
+	Tag            string
+	MinRating      *int
+	MaxRating      *int
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func (f *DatasetSampleFilter) apply(tx *gorm.DB) *gorm.DB {
+	if f.ModelName != "" {
+		tx = tx.Where("model_name = ?", f.ModelName)
+	}
+	if f.UserId != 0 {
+		tx = tx.Where("user_id = ?", f.UserId)
+	}
+	if f.Status != 0 {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if f.Tag != "" {
+		tx = tx.Where("tags LIKE ?", "%,"+f.Tag+",%")
+	}
+	if f.MinRating != nil {
+		tx = tx.Where("rating >= ?", *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		tx = tx.Where("rating <= ?", *f.MaxRating)
+	}
+	if f.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", f.StartTimestamp)
+	}
+	if f.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", f.EndTimestamp)
+	}
+	return tx
+}
+
+// NormalizeDatasetTags 去除空白与重复标签，标签中不允许出现逗号
+func NormalizeDatasetTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", ""))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+func encodeDatasetTags(tags []string) string {
+	tags = NormalizeDatasetTags(tags)
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+func (s *DatasetSample) AfterFind(tx *gorm.DB) error {
+	s.TagList = []string{}
+	for _, tag := range strings.Split(s.Tags, ",") {
+		if tag != "" {
+			s.TagList = append(s.TagList, tag)
+		}
+	}
+	return nil
+}
+
+func (s *DatasetSample) Insert() error {
+	now := common.GetTimestamp()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	s.Tags = encodeDatasetTags(s.TagList)
+	if s.Status == 0 {
+		s.Status = DatasetSampleStatusPending
+	}
+	return DB.Create(s).Error
+}
+
+// UpdateReview 更新审阅结果：标签、状态、备注与清洗后的消息
+func (s *DatasetSample) UpdateReview() error {
+	s.UpdatedAt = common.GetTimestamp()
+	s.Tags = encodeDatasetTags(s.TagList)
+	return DB.Model(s).Select("messages", "tags", "status", "note", "updated_at").Updates(s).Error
+}
+
+func GetDatasetSampleById(id int) (*DatasetSample, error) {
+	var sample DatasetSample
+	err := DB.First(&sample, "id = ?", id).Error
+	return &sample, err
+}
+
+// SearchDatasetSamples 分页查询，列表不返回消息内容
+func SearchDatasetSamples(filter *DatasetSampleFilter, startIdx int, num int) ([]*DatasetSample, int64, error) {
+	var samples []*DatasetSample
+	var total int64
+	tx := filter.apply(DB.Model(&DatasetSample{}))
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Omit("messages").Order("id DESC").Offset(startIdx).Limit(num).Find(&samples).Error
+	return samples, total, err
+}
+
+// GetDatasetSamplesAfter 按 ID 递增分批读取，用于导出
+func GetDatasetSamplesAfter(filter *DatasetSampleFilter, afterId int, limit int) ([]*DatasetSample, error) {
+	var samples []*DatasetSample
+	err := filter.apply(DB.Model(&DatasetSample{})).Where("id > ?", afterId).
+		Order("id ASC").Limit(limit).Find(&samples).Error
+	return samples, err
+}
+
+// UpdateDatasetSamplesStatus 批量设置审阅状态
+func UpdateDatasetSamplesStatus(ids []int, status int) error {
+	return DB.Model(&DatasetSample{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"status": status, "updated_at": common.GetTimestamp()}).Error
+}
+
+func DeleteDatasetSamples(ids []int) error {
+	return DB.Where("id IN ?", ids).Delete(&DatasetSample{}).Error
+}
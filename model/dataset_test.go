@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasetSampleFilter(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM dataset_samples") })
+	good, bad := 5, 1
+	samples := []*DatasetSample{
+		{UserId: 1, ModelName: "gpt-4o", Rating: &good, TagList: []string{"code", " zh ", "code"}, Messages: JSONValue(`[]`)},
+		{UserId: 2, ModelName: "gpt-4o", Rating: &bad, Messages: JSONValue(`[]`)},
+		{UserId: 1, ModelName: "claude", Messages: JSONValue(`[]`)},
+	}
+	for _, sample := range samples {
+		require.NoError(t, sample.Insert())
+	}
+
+	minRating := 4
+	found, total, err := SearchDatasetSamples(&DatasetSampleFilter{ModelName: "gpt-4o", MinRating: &minRating}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, []string{"code", "zh"}, found[0].TagList)
+
+	found, _, err = SearchDatasetSamples(&DatasetSampleFilter{Tag: "zh"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	found, _, err = SearchDatasetSamples(&DatasetSampleFilter{Tag: "z"}, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	require.NoError(t, UpdateDatasetSamplesStatus([]int{samples[0].Id, samples[2].Id}, DatasetSampleStatusApproved))
+	exported, err := GetDatasetSamplesAfter(&DatasetSampleFilter{Status: DatasetSampleStatusApproved, UserId: 1}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	exported, err = GetDatasetSamplesAfter(&DatasetSampleFilter{Status: DatasetSampleStatusApproved}, samples[0].Id, 10)
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+}
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ExperimentStatusEnabled  = 1
+	ExperimentStatusDisabled = 2
+)
+
+// ExperimentArm 实验的一个分组：按权重分流，可替换模型并覆盖请求参数（格式同渠道参数覆盖）
+type ExperimentArm struct {
+	Name          string                 `json:"name"`
+	Weight        int                    `json:"weight"`
+	Model         string                 `json:"model,omitempty"`
+	ParamOverride map[string]interface{} `json:"param_override,omitempty"`
+}
+
+type ExperimentArms []ExperimentArm
+
+func (a ExperimentArms) Value() (driver.Value, error) {
+	data, err := common.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *ExperimentArms) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return common.Unmarshal(v, a)
+	case string:
+		return common.UnmarshalJsonStr(v, a)
+	default:
+		return fmt.Errorf("unsupported experiment arms type %T", value)
+	}
+}
+
+// Experiment A/B 实验：将某个令牌（TokenId 为 0 时为所有令牌）请求指定模型的流量按权重分配到各分组，
+// 分组信息记录到使用日志并按小时汇总延迟、费用与错误率
+type Experiment struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name" gorm:"size:64;not null"`
+	Description string         `json:"description,omitempty" gorm:"type:varchar(255)"`
+	Status      int            `json:"status" gorm:"default:1;index"`
+	TokenId     int            `json:"token_id" gorm:"index"`
+	ModelName   string         `json:"model_name" gorm:"size:128;index"`
+	Arms        ExperimentArms `json:"arms" gorm:"type:text"`
+	CreatedTime int64          `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64          `json:"updated_time" gorm:"bigint"`
+}
+
+// Validate 校验实验配置
+func (e *Experiment) Validate() error {
+	e.Name = strings.TrimSpace(e.Name)
+	e.ModelName = strings.TrimSpace(e.ModelName)
+	if e.Name == "" || e.ModelName == "" {
+		return errors.New("实验名称和模型不能为空")
+	}
+	if e.Status != ExperimentStatusEnabled && e.Status != ExperimentStatusDisabled {
+		return errors.New("实验状态无效")
+	}
+	if len(e.Arms) < 2 {
+		return errors.New("实验至少需要两个分组")
+	}
+	seen := make(map[string]bool, len(e.Arms))
+	for _, arm := range e.Arms {
+		name := strings.TrimSpace(arm.Name)
+		if name == "" || len(name) > 64 {
+			return errors.New("分组名称不能为空且不超过 64 个字符")
+		}
+		if seen[name] {
+			return fmt.Errorf("分组名称 %s 重复", name)
+		}
+		seen[name] = true
+		if arm.Weight <= 0 {
+			return fmt.Errorf("分组 %s 的权重必须大于 0", name)
+		}
+	}
+	return nil
+}
+
+// HasEnabledExperimentConflict 同一令牌与模型只允许一个启用中的实验（排除自身 ID）
+func HasEnabledExperimentConflict(id int, tokenId int, modelName string) (bool, error) {
+	var cnt int64
+	err := DB.Model(&Experiment{}).
+		Where("id <> ? AND token_id = ? AND model_name = ? AND status = ?", id, tokenId, modelName, ExperimentStatusEnabled).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func (e *Experiment) Insert() error {
+	now := common.GetTimestamp()
+	e.CreatedTime = now
+	e.UpdatedTime = now
+	return DB.Create(e).Error
+}
+
+func (e *Experiment) Update() error {
+	e.UpdatedTime = common.GetTimestamp()
+	return DB.Select("name", "description", "status", "token_id", "model_name", "arms", "updated_time").Updates(e).Error
+}
+
+func GetExperimentById(id int) (*Experiment, error) {
+	var experiment Experiment
+	err := DB.First(&experiment, "id = ?", id).Error
+	return &experiment, err
+}
+
+func GetAllExperiments() ([]*Experiment, error) {
+	var experiments []*Experiment
+	err := DB.Order("id DESC").Find(&experiments).Error
+	return experiments, err
+}
+
+func GetEnabledExperiments() ([]*Experiment, error) {
+	var experiments []*Experiment
+	err := DB.Where("status = ?", ExperimentStatusEnabled).Find(&experiments).Error
+	return experiments, err
+}
+
+func DeleteExperimentById(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("experiment_id = ?", id).Delete(&ExperimentMetric{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Experiment{}, id).Error
+	})
+}
+
+// ExperimentMetric 实验分组的小时级汇总指标
+type ExperimentMetric struct {
+	Id               int    `json:"id" gorm:"primaryKey"`
+	ExperimentId     int    `json:"experiment_id" gorm:"uniqueIndex:idx_experiment_arm_bucket,priority:1"`
+	Arm              string `json:"arm" gorm:"size:64;uniqueIndex:idx_experiment_arm_bucket,priority:2"`
+	BucketTs         int64  `json:"bucket_ts" gorm:"uniqueIndex:idx_experiment_arm_bucket,priority:3"`
+	RequestCount     int64  `json:"request_count" gorm:"default:0"`
+	ErrorCount       int64  `json:"error_count" gorm:"default:0"`
+	TotalLatencyMs   int64  `json:"total_latency_ms" gorm:"default:0"`
+	Quota            int64  `json:"quota" gorm:"default:0"`
+	PromptTokens     int64  `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int64  `json:"completion_tokens" gorm:"default:0"`
+}
+
+func upsertExperimentMetric(metric *ExperimentMetric) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "experiment_id"},
+			{Name: "arm"},
+			{Name: "bucket_ts"},
+		},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"request_count":     gorm.Expr("request_count + ?", metric.RequestCount),
+			"error_count":       gorm.Expr("error_count + ?", metric.ErrorCount),
+			"total_latency_ms":  gorm.Expr("total_latency_ms + ?", metric.TotalLatencyMs),
+			"quota":             gorm.Expr("quota + ?", metric.Quota),
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", metric.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", metric.CompletionTokens),
+		}),
+	}).Create(metric).Error
+}
+
+// ExperimentArmSummary 实验分组在时间范围内的汇总
+type ExperimentArmSummary struct {
+	Arm              string `json:"arm"`
+	RequestCount     int64  `json:"request_count"`
+	ErrorCount       int64  `json:"error_count"`
+	TotalLatencyMs   int64  `json:"total_latency_ms"`
+	Quota            int64  `json:"quota"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+}
+
+func GetExperimentArmSummaries(experimentId int, startTs int64, endTs int64) ([]ExperimentArmSummary, error) {
+	var summaries []ExperimentArmSummary
+	query := DB.Model(&ExperimentMetric{}).
+		Select("arm, SUM(request_count) as request_count, SUM(error_count) as error_count, SUM(total_latency_ms) as total_latency_ms, SUM(quota) as quota, SUM(prompt_tokens) as prompt_tokens, SUM(completion_tokens) as completion_tokens").
+		Where("experiment_id = ?", experimentId)
+	if startTs > 0 {
+		query = query.Where("bucket_ts >= ?", startTs)
+	}
+	if endTs > 0 {
+		query = query.Where("bucket_ts <= ?", endTs)
+	}
+	err := query.Group("arm").Find(&summaries).Error
+	return summaries, err
+}
+
+// appendExperimentTags 将实验分组写入使用日志的 other 字段
+func appendExperimentTags(c *gin.Context, other map[string]interface{}) map[string]interface{} {
+	experimentId := common.GetContextKeyInt(c, constant.ContextKeyExperimentId)
+	if experimentId == 0 {
+		return other
+	}
+	if other == nil {
+		other = make(map[string]interface{})
+	}
+	other["experiment_id"] = experimentId
+	other["experiment_arm"] = common.GetContextKeyString(c, constant.ContextKeyExperimentArm)
+	return other
+}
+
+// RecordExperimentError 记录实验请求的最终失败（重试后仍失败时计一次）
+func RecordExperimentError(c *gin.Context) {
+	recordExperimentMetric(c, true, 0, 0, 0)
+}
+
+// recordExperimentMetric 异步累加实验分组指标，请求未参与实验时忽略
+func recordExperimentMetric(c *gin.Context, isError bool, quota int, promptTokens int, completionTokens int) {
+	experimentId := common.GetContextKeyInt(c, constant.ContextKeyExperimentId)
+	if experimentId == 0 {
+		return
+	}
+	metric := &ExperimentMetric{
+		ExperimentId:     experimentId,
+		Arm:              common.GetContextKeyString(c, constant.ContextKeyExperimentArm),
+		BucketTs:         time.Now().Truncate(time.Hour).Unix(),
+		RequestCount:     1,
+		Quota:            int64(quota),
+		PromptTokens:     int64(promptTokens),
+		CompletionTokens: int64(completionTokens),
+	}
+	if isError {
+		metric.ErrorCount = 1
+	}
+	if startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime); !startTime.IsZero() {
+		metric.TotalLatencyMs = time.Since(startTime).Milliseconds()
+	}
+	gopool.Go(func() {
+		if err := upsertExperimentMetric(metric); err != nil {
+			common.SysError(fmt.Sprintf("failed to record experiment metric: experiment=%d arm=%s: %s", metric.ExperimentId, metric.Arm, err.Error()))
+		}
+	})
+}
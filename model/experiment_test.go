@@ -0,0 +1,72 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperimentArmsRoundTrip(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM experiments") })
+	experiment := &Experiment{
+		Name:      "temperature",
+		Status:    ExperimentStatusEnabled,
+		TokenId:   1,
+		ModelName: "gpt-4o",
+		Arms: ExperimentArms{
+			{Name: "control", Weight: 50},
+			{Name: "cold", Weight: 50, Model: "gpt-4o-mini", ParamOverride: map[string]interface{}{"temperature": 0.2}},
+		},
+	}
+	require.NoError(t, experiment.Validate())
+	require.NoError(t, experiment.Insert())
+
+	loaded, err := GetExperimentById(experiment.Id)
+	require.NoError(t, err)
+	require.Len(t, loaded.Arms, 2)
+	require.Equal(t, "gpt-4o-mini", loaded.Arms[1].Model)
+	require.Equal(t, 0.2, loaded.Arms[1].ParamOverride["temperature"])
+
+	conflict, err := HasEnabledExperimentConflict(0, 1, "gpt-4o")
+	require.NoError(t, err)
+	require.True(t, conflict)
+	conflict, err = HasEnabledExperimentConflict(experiment.Id, 1, "gpt-4o")
+	require.NoError(t, err)
+	require.False(t, conflict)
+}
+
+func TestExperimentValidate(t *testing.T) {
+	arms := ExperimentArms{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}}
+	require.Error(t, (&Experiment{Name: "x", ModelName: "m", Status: ExperimentStatusEnabled, Arms: arms}).Validate())
+	arms = ExperimentArms{{Name: "a", Weight: 1}}
+	require.Error(t, (&Experiment{Name: "x", ModelName: "m", Status: ExperimentStatusEnabled, Arms: arms}).Validate())
+	arms = ExperimentArms{{Name: "a", Weight: 1}, {Name: "b", Weight: 0}}
+	require.Error(t, (&Experiment{Name: "x", ModelName: "m", Status: ExperimentStatusEnabled, Arms: arms}).Validate())
+}
+
+func TestExperimentMetricAggregation(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM experiment_metrics") })
+	for i := 0; i < 3; i++ {
+		require.NoError(t, upsertExperimentMetric(&ExperimentMetric{
+			ExperimentId: 7, Arm: "control", BucketTs: 3600, RequestCount: 1, TotalLatencyMs: 100, Quota: 10,
+		}))
+	}
+	require.NoError(t, upsertExperimentMetric(&ExperimentMetric{
+		ExperimentId: 7, Arm: "cold", BucketTs: 7200, RequestCount: 1, ErrorCount: 1, TotalLatencyMs: 40,
+	}))
+
+	summaries, err := GetExperimentArmSummaries(7, 0, 0)
+	require.NoError(t, err)
+	byArm := make(map[string]ExperimentArmSummary)
+	for _, summary := range summaries {
+		byArm[summary.Arm] = summary
+	}
+	require.Equal(t, int64(3), byArm["control"].RequestCount)
+	require.Equal(t, int64(300), byArm["control"].TotalLatencyMs)
+	require.Equal(t, int64(30), byArm["control"].Quota)
+	require.Equal(t, int64(1), byArm["cold"].ErrorCount)
+
+	summaries, err = GetExperimentArmSummaries(7, 7200, 0)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+}
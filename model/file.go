@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// UserFile 用户通过 /v1/files 上传的文件。内容按上传时的存储方式保存：
+// database 时存于 Content，local 与 s3 时通过 StorageKey 定位
+type UserFile struct {
+	Id         int    `json:"-"`
+	FileId     string `json:"id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId     int    `json:"-" gorm:"index"`
+	Filename   string `json:"filename" gorm:"type:varchar(255)"`
+	Purpose    string `json:"purpose" gorm:"type:varchar(32);index"`
+	Bytes      int64  `json:"bytes" gorm:"bigint"`
+	Storage    string `json:"-" gorm:"type:varchar(16)"`
+	StorageKey string `json:"-" gorm:"type:varchar(255)"`
+	Content    []byte `json:"-"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (f *UserFile) Insert() error {
+	f.CreatedAt = common.GetTimestamp()
+	return DB.Create(f).Error
+}
+
+// GetUserFile 获取文件信息，withContent 为 false 时不读取数据库中的文件内容
+func GetUserFile(userId int, fileId string, withContent bool) (*UserFile, error) {
+	var file UserFile
+	tx := DB.Where("file_id = ? AND user_id = ?", fileId, userId)
+	if !withContent {
+		tx = tx.Omit("content")
+	}
+	err := tx.First(&file).Error
+	return &file, err
+}
+
+// GetUserFiles 按上传时间倒序列出文件，purpose 为空时不过滤
+func GetUserFiles(userId int, purpose string, limit int) ([]*UserFile, error) {
+	var files []*UserFile
+	tx := DB.Omit("content").Where("user_id = ?", userId)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+	err := tx.Order("id DESC").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// GetUserFileUsage 用户已占用的文件存储字节数
+func GetUserFileUsage(userId int) (int64, error) {
+	var total int64
+	err := DB.Model(&UserFile{}).Where("user_id = ?", userId).
+		Select("COALESCE(SUM(bytes), 0)").Scan(&total).Error
+	return total, err
+}
+
+// GetFilesBefore 获取某种用途下上传时间早于 cutoff 的文件，用于过期清理
+func GetFilesBefore(purpose string, cutoff int64, limit int) ([]*UserFile, error) {
+	var files []*UserFile
+	err := DB.Omit("content").Where("purpose = ? AND created_at < ?", purpose, cutoff).
+		Order("id ASC").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// DeleteUserFile 删除文件记录及其在各渠道的上游副本映射
+func DeleteUserFile(file *UserFile) error {
+	if err := DB.Where("file_id = ?", file.FileId).Delete(&UserFileUpstream{}).Error; err != nil {
+		return err
+	}
+	return DB.Delete(file).Error
+}
+
+// UserFileUpstream 文件转发到渠道后上游返回的文件 ID，同一文件在同一渠道只上传一次
+type UserFileUpstream struct {
+	Id             int    `json:"id"`
+	FileId         string `json:"file_id" gorm:"type:varchar(64);uniqueIndex:uk_user_file_upstream,priority:1"`
+	ChannelId      int    `json:"channel_id" gorm:"uniqueIndex:uk_user_file_upstream,priority:2;index:idx_user_file_upstream_id,priority:1"`
+	UpstreamFileId string `json:"upstream_file_id" gorm:"type:varchar(128);index:idx_user_file_upstream_id,priority:2"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint"`
+}
+
+func (u *UserFileUpstream) Insert() error {
+	u.CreatedAt = common.GetTimestamp()
+	return DB.Create(u).Error
+}
+
+func GetUserFileUpstream(fileId string, channelId int) (*UserFileUpstream, error) {
+	var upstream UserFileUpstream
+	err := DB.First(&upstream, "file_id = ? AND channel_id = ?", fileId, channelId).Error
+	return &upstream, err
+}
+
+// GetUserFileUpstreamById 通过上游文件 ID 反查网关文件 ID
+func GetUserFileUpstreamById(channelId int, upstreamFileId string) (*UserFileUpstream, error) {
+	var upstream UserFileUpstream
+	err := DB.First(&upstream, "channel_id = ? AND upstream_file_id = ?", channelId, upstreamFileId).Error
+	return &upstream, err
+}
+
+func GetUserFileUpstreams(fileId string) ([]*UserFileUpstream, error) {
+	var upstreams []*UserFileUpstream
+	err := DB.Where("file_id = ?", fileId).Find(&upstreams).Error
+	return upstreams, err
+}
+
+// migrateLegacyBatchFiles 将早期仅支持批量输入时保存在 batch_files 表中的文件迁移到 user_files，
+// 迁移完成后删除旧表；可重复执行
+func migrateLegacyBatchFiles() error {
+	const legacyTable = "batch_files"
+	if !DB.Migrator().HasTable(legacyTable) {
+		return nil
+	}
+	err := DB.Exec(`INSERT INTO user_files (file_id, user_id, filename, purpose, bytes, storage, content, created_at)
+		SELECT file_id, user_id, filename, purpose, bytes, ?, content, created_at FROM batch_files
+		WHERE file_id NOT IN (SELECT file_id FROM user_files)`, operation_setting.FileStorageDatabase).Error
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", legacyTable, err)
+	}
+	if err := DB.Migrator().DropTable(legacyTable); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", legacyTable, err)
+	}
+	common.SysLog("migrated legacy batch_files to user_files")
+	return nil
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFileContentOmitted(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM user_files") })
+	file := &UserFile{FileId: "file-abc", UserId: 3, Filename: "in.jsonl", Purpose: "batch", Bytes: 2, Storage: "database", Content: []byte("{}")}
+	require.NoError(t, file.Insert())
+
+	meta, err := GetUserFile(3, "file-abc", false)
+	require.NoError(t, err)
+	require.Empty(t, meta.Content)
+	full, err := GetUserFile(3, "file-abc", true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("{}"), full.Content)
+
+	_, err = GetUserFile(4, "file-abc", false)
+	require.Error(t, err)
+	require.NoError(t, DeleteUserFile(meta))
+	_, err = GetUserFile(3, "file-abc", false)
+	require.Error(t, err)
+}
+
+func TestUserFileUsageAndUpstream(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM user_files")
+		DB.Exec("DELETE FROM user_file_upstreams")
+	})
+	usage, err := GetUserFileUsage(5)
+	require.NoError(t, err)
+	require.Zero(t, usage)
+
+	first := &UserFile{FileId: "file-a", UserId: 5, Purpose: "assistants", Bytes: 100}
+	require.NoError(t, first.Insert())
+	require.NoError(t, (&UserFile{FileId: "file-b", UserId: 5, Purpose: "batch", Bytes: 50}).Insert())
+	require.NoError(t, (&UserFile{FileId: "file-c", UserId: 6, Purpose: "batch", Bytes: 70}).Insert())
+	usage, err = GetUserFileUsage(5)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, usage)
+
+	files, err := GetUserFiles(5, "assistants", 10)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	require.NoError(t, (&UserFileUpstream{FileId: "file-a", ChannelId: 2, UpstreamFileId: "file-up"}).Insert())
+	require.Error(t, (&UserFileUpstream{FileId: "file-a", ChannelId: 2, UpstreamFileId: "file-up2"}).Insert())
+	upstream, err := GetUserFileUpstreamById(2, "file-up")
+	require.NoError(t, err)
+	require.Equal(t, "file-a", upstream.FileId)
+
+	require.NoError(t, DeleteUserFile(first))
+	upstreams, err := GetUserFileUpstreams("file-a")
+	require.NoError(t, err)
+	require.Empty(t, upstreams)
+}
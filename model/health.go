@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+var errHealthProbeRollback = errors.New("health probe rollback")
+
+// CheckDatabase 检查主库连接，不复用 PingDB 的 10 秒缓存，保证探针反映实时状态
+func CheckDatabase(ctx context.Context) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckLogWritable 在日志库中写入一条探测日志并回滚，用于确认日志库可写（例如只读副本或磁盘已满时会失败）
+func CheckLogWritable(ctx context.Context) error {
+	err := LOG_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		probe := &Log{
+			CreatedAt: common.GetTimestamp(),
+			Type:      LogTypeSystem,
+			Content:   "health probe",
+		}
+		if err := tx.Create(probe).Error; err != nil {
+			return err
+		}
+		return errHealthProbeRollback
+	})
+	if errors.Is(err, errHealthProbeRollback) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecks(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, CheckDatabase(ctx))
+
+	var before int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&before).Error)
+	require.NoError(t, CheckLogWritable(ctx))
+
+	// 探测日志会被回滚，不留下记录
+	var after int64
+	require.NoError(t, LOG_DB.Model(&Log{}).Count(&after).Error)
+	require.Equal(t, before, after)
+}
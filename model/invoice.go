@@ -0,0 +1,22 @@
+package model
+
+// InvoiceUsage 账单周期内单个模型的消费汇总
+type InvoiceUsage struct {
+	ModelName        string `json:"model_name"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	Quota            int64  `json:"quota"`
+}
+
+// GetUserInvoiceUsages 按模型汇总用户在 [startTime, endTime) 内的消费日志，用于生成账单
+func GetUserInvoiceUsages(userId int, startTime int64, endTime int64) ([]*InvoiceUsage, error) {
+	var usages []*InvoiceUsage
+	err := LOG_DB.Table("logs").
+		Select("model_name, count(*) as requests, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeConsume, startTime, endTime).
+		Group("model_name").
+		Order("model_name ASC").
+		Scan(&usages).Error
+	return usages, err
+}
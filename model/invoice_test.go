@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserInvoiceUsages(t *testing.T) {
+	const userId = 9201
+	logs := []*Log{
+		{UserId: userId, Type: LogTypeConsume, CreatedAt: 1000, ModelName: "gpt-4o", PromptTokens: 100, CompletionTokens: 50, Quota: 300},
+		{UserId: userId, Type: LogTypeConsume, CreatedAt: 1500, ModelName: "gpt-4o", PromptTokens: 200, CompletionTokens: 20, Quota: 400},
+		{UserId: userId, Type: LogTypeConsume, CreatedAt: 1800, ModelName: "claude-sonnet-4", PromptTokens: 10, CompletionTokens: 5, Quota: 30},
+		// 周期外、非消费日志与其他用户的日志不计入
+		{UserId: userId, Type: LogTypeConsume, CreatedAt: 2000, ModelName: "gpt-4o", Quota: 999},
+		{UserId: userId, Type: LogTypeTopup, CreatedAt: 1200, Quota: 5000},
+		{UserId: userId + 1, Type: LogTypeConsume, CreatedAt: 1200, ModelName: "gpt-4o", Quota: 999},
+	}
+	require.NoError(t, LOG_DB.Create(logs).Error)
+	t.Cleanup(func() { LOG_DB.Where("user_id IN ?", []int{userId, userId + 1}).Delete(&Log{}) })
+
+	usages, err := GetUserInvoiceUsages(userId, 1000, 2000)
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+	require.Equal(t, InvoiceUsage{ModelName: "claude-sonnet-4", Requests: 1, PromptTokens: 10, CompletionTokens: 5, Quota: 30}, *usages[0])
+	require.Equal(t, InvoiceUsage{ModelName: "gpt-4o", Requests: 2, PromptTokens: 300, CompletionTokens: 70, Quota: 700}, *usages[1])
+}
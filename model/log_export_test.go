@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAllLogsPagesByIdCursor(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM logs")
+	})
+	for i := 0; i < 7; i++ {
+		logType := LogTypeConsume
+		if i == 3 {
+			logType = LogTypeSystem
+		}
+		require.NoError(t, LOG_DB.Create(&Log{UserId: 1, Username: "alice", Type: logType, CreatedAt: int64(100 + i)}).Error)
+	}
+
+	var batches [][]int
+	err := ExportAllLogs(LogTypeConsume, 0, 0, "", "alice", "", 0, "", "", "", 100, 2, func(logs []*Log) error {
+		ids := make([]int, 0, len(logs))
+		for _, log := range logs {
+			require.Equal(t, LogTypeConsume, log.Type)
+			ids = append(ids, log.Id)
+		}
+		batches = append(batches, ids)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+	require.Len(t, batches[0], 2)
+	require.Len(t, batches[2], 2)
+	var all []int
+	for _, batch := range batches {
+		all = append(all, batch...)
+	}
+	require.Len(t, all, 6)
+	for i := 1; i < len(all); i++ {
+		require.Greater(t, all[i-1], all[i])
+	}
+
+	// 达到导出上限后停止读取
+	exported := 0
+	err = ExportAllLogs(LogTypeUnknown, 0, 0, "", "", "", 0, "", "", "", 5, 2, func(logs []*Log) error {
+		exported += len(logs)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, exported)
+}
@@ -281,10 +281,28 @@ func migrateDB() error {
 		&CustomOAuthProvider{},
 		&UserOAuthBinding{},
 		&PerfMetric{},
+		&Experiment{},
+		&ExperimentMetric{},
+		&Batch{},
+		&BatchItem{},
+		&UserFile{},
+		&UserFileUpstream{},
+		&PlaygroundSession{},
+		&PlaygroundPrompt{},
+		&DatasetSample{},
+		&AssistantResource{},
+		&Organization{},
+		&OrganizationMember{},
+		&ChannelProbeLog{},
+		&ChannelProbeState{},
+		&MonthlyUsage{},
 	)
 	if err != nil {
 		return err
 	}
+	if err := migrateLegacyBatchFiles(); err != nil {
+		return err
+	}
 	if common.UsingSQLite {
 		if err := ensureSubscriptionPlanTableSQLite(); err != nil {
 			return err
@@ -330,6 +348,21 @@ func migrateDBFast() error {
 		{&CustomOAuthProvider{}, "CustomOAuthProvider"},
 		{&UserOAuthBinding{}, "UserOAuthBinding"},
 		{&PerfMetric{}, "PerfMetric"},
+		{&Experiment{}, "Experiment"},
+		{&ExperimentMetric{}, "ExperimentMetric"},
+		{&Batch{}, "Batch"},
+		{&BatchItem{}, "BatchItem"},
+		{&UserFile{}, "UserFile"},
+		{&UserFileUpstream{}, "UserFileUpstream"},
+		{&PlaygroundSession{}, "PlaygroundSession"},
+		{&PlaygroundPrompt{}, "PlaygroundPrompt"},
+		{&DatasetSample{}, "DatasetSample"},
+		{&AssistantResource{}, "AssistantResource"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&ChannelProbeLog{}, "ChannelProbeLog"},
+		{&ChannelProbeState{}, "ChannelProbeState"},
+		{&MonthlyUsage{}, "MonthlyUsage"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -354,6 +387,9 @@ func migrateDBFast() error {
 			return err
 		}
 	}
+	if err := migrateLegacyBatchFiles(); err != nil {
+		return err
+	}
 	if common.UsingSQLite {
 		if err := ensureSubscriptionPlanTableSQLite(); err != nil {
 			return err
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MonthlyUsageKindQuota  = "quota"
+	MonthlyUsageKindVolume = "volume"
+)
+
+// MonthlyUsage 按自然月累计的用量，持久化在数据库中，重启后不丢失且多节点共享同一份计数。
+// Kind 区分用量类型，ModelName 为空表示不区分模型
+type MonthlyUsage struct {
+	Kind      string `json:"kind" gorm:"primaryKey;type:varchar(32)"`
+	Scope     string `json:"scope" gorm:"primaryKey;type:varchar(32)"`
+	Subject   string `json:"subject" gorm:"primaryKey;type:varchar(128)"`
+	ModelName string `json:"model_name" gorm:"primaryKey;type:varchar(191)"`
+	Month     string `json:"month" gorm:"primaryKey;type:varchar(6)"`
+	Amount    int64  `json:"amount" gorm:"bigint;not null;default:0"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// IncrMonthlyQuota 累计用户或令牌本月的消费额度，返回累计后的值，供预算提醒使用
+func IncrMonthlyQuota(scope string, id int, quota int) (int64, error) {
+	return incrMonthlyUsage(MonthlyUsageKindQuota, scope, strconv.Itoa(id), "", int64(quota))
+}
+
+// IncrMonthlyModelVolume 累计本月某模型在用户或分组维度的 token 用量，返回累计后的值，供按量阶梯价格使用
+func IncrMonthlyModelVolume(scope string, subject string, modelName string, tokens int64) (int64, error) {
+	return incrMonthlyUsage(MonthlyUsageKindVolume, scope, subject, modelName, tokens)
+}
+
+// incrMonthlyUsage 原子地累加本月用量并返回累加后的值
+func incrMonthlyUsage(kind string, scope string, subject string, modelName string, delta int64) (int64, error) {
+	usage := MonthlyUsage{
+		Kind:      kind,
+		Scope:     scope,
+		Subject:   subject,
+		ModelName: modelName,
+		Month:     time.Now().Format("200601"),
+		Amount:    delta,
+		UpdatedAt: common.GetTimestamp(),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "kind"}, {Name: "scope"}, {Name: "subject"}, {Name: "model_name"}, {Name: "month"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				// 带表名引用已有行的值，PostgreSQL 中不带表名会与 excluded 产生歧义
+				"amount":     gorm.Expr("monthly_usages.amount + ?", delta),
+				"updated_at": usage.UpdatedAt,
+			}),
+		}).Create(&usage).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&MonthlyUsage{}).
+			Where("kind = ? AND scope = ? AND subject = ? AND model_name = ? AND month = ?", usage.Kind, usage.Scope, usage.Subject, usage.ModelName, usage.Month).
+			Select("amount").
+			Scan(&usage.Amount).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return usage.Amount, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrMonthlyModelVolume(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM monthly_usages") })
+
+	used, err := IncrMonthlyModelVolume("user", "7", "gpt-4o", 1000)
+	require.NoError(t, err)
+	require.EqualValues(t, 1000, used)
+	used, err = IncrMonthlyModelVolume("user", "7", "gpt-4o", 500)
+	require.NoError(t, err)
+	require.EqualValues(t, 1500, used)
+
+	// 不同模型与维度分别累计
+	used, err = IncrMonthlyModelVolume("user", "7", "gpt-4o-mini", 200)
+	require.NoError(t, err)
+	require.EqualValues(t, 200, used)
+	used, err = IncrMonthlyModelVolume("group", "7", "gpt-4o", 300)
+	require.NoError(t, err)
+	require.EqualValues(t, 300, used)
+
+	var count int64
+	require.NoError(t, DB.Model(&MonthlyUsage{}).Count(&count).Error)
+	require.EqualValues(t, 3, count)
+}
+
+func TestIncrMonthlyQuota(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM monthly_usages") })
+
+	spent, err := IncrMonthlyQuota("user", 7, 100)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, spent)
+	spent, err = IncrMonthlyQuota("user", 7, 50)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, spent)
+
+	// 额度与用量、用户与令牌分别累计
+	spent, err = IncrMonthlyQuota("token", 7, 30)
+	require.NoError(t, err)
+	require.EqualValues(t, 30, spent)
+	used, err := IncrMonthlyModelVolume("user", "7", "", 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, used)
+}
@@ -63,6 +63,14 @@ func InitOptionMap() {
 	common.OptionMap["SMTPToken"] = ""
 	common.OptionMap["SMTPSSLEnabled"] = strconv.FormatBool(common.SMTPSSLEnabled)
 	common.OptionMap["SMTPForceAuthLogin"] = strconv.FormatBool(common.SMTPForceAuthLogin)
+	common.OptionMap["EmailProvider"] = common.EmailProvider
+	common.OptionMap["SESRegion"] = ""
+	common.OptionMap["SESAccessKeyId"] = ""
+	common.OptionMap["SESSecretAccessKey"] = ""
+	common.OptionMap["SendGridApiKey"] = ""
+	common.OptionMap["MailgunDomain"] = ""
+	common.OptionMap["MailgunApiKey"] = ""
+	common.OptionMap["MailgunApiBase"] = ""
 	common.OptionMap["Notice"] = ""
 	common.OptionMap["About"] = ""
 	common.OptionMap["HomePageContent"] = ""
@@ -345,6 +353,22 @@ func updateOptionMap(key string, value string) (err error) {
 		common.SMTPFrom = value
 	case "SMTPToken":
 		common.SMTPToken = value
+	case "EmailProvider":
+		common.EmailProvider = value
+	case "SESRegion":
+		common.SESRegion = value
+	case "SESAccessKeyId":
+		common.SESAccessKeyId = value
+	case "SESSecretAccessKey":
+		common.SESSecretAccessKey = value
+	case "SendGridApiKey":
+		common.SendGridApiKey = value
+	case "MailgunDomain":
+		common.MailgunDomain = value
+	case "MailgunApiKey":
+		common.MailgunApiKey = value
+	case "MailgunApiBase":
+		common.MailgunApiBase = value
 	case "ServerAddress":
 		system_setting.ServerAddress = value
 	case "WorkerUrl":
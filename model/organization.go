@@ -0,0 +1,469 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+
+	OrganizationRoleMember = "member"
+	OrganizationRoleAdmin  = "admin"
+
+	OrganizationMemberStatusInvited = 1
+	OrganizationMemberStatusActive  = 2
+)
+
+var (
+	ErrOrganizationNotFound       = errors.New("组织不存在")
+	ErrOrganizationQuotaNotEnough = errors.New("组织额度不足")
+	ErrOrganizationMemberNotFound = errors.New("组织成员不存在")
+	ErrOrganizationMemberQuota    = errors.New("成员可回收额度不足")
+	ErrOrganizationAlreadyJoined  = errors.New("用户已加入其他组织")
+)
+
+// Organization 组织，管理员向组织额度池充值，组织管理员再把额度分配给成员
+type Organization struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name" gorm:"size:128"`
+	Status int    `json:"status" gorm:"default:1"`
+	// Quota 组织额度池中尚未分配的额度
+	Quota int `json:"quota" gorm:"default:0"`
+	// AllocatedQuota 累计分配给成员的额度（扣除已回收部分）
+	AllocatedQuota int   `json:"allocated_quota" gorm:"default:0"`
+	CreatedTime    int64 `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationMember 组织成员，每个用户同一时间只能是一个组织的正式成员
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"uniqueIndex:idx_org_member_user,priority:1"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_org_member_user,priority:2;index"`
+	Username       string `json:"username" gorm:"-"`
+	Role           string `json:"role" gorm:"size:16;default:'member'"`
+	Status         int    `json:"status" gorm:"default:1;index"`
+	// AllocatedQuota 组织分配给该成员的额度（扣除已回收部分）
+	AllocatedQuota int `json:"allocated_quota" gorm:"default:0"`
+	// ModelLimits 成员可用模型，逗号分隔，为空表示不限制
+	ModelLimits string `json:"model_limits" gorm:"type:text"`
+	InvitedBy   int    `json:"invited_by"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationMemberUsage 成员用量汇总
+type OrganizationMemberUsage struct {
+	UserId    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Quota     int64  `json:"quota"`
+	TokenUsed int64  `json:"token_used"`
+	Count     int64  `json:"count"`
+}
+
+func (member *OrganizationMember) IsAdmin() bool {
+	return member.Role == OrganizationRoleAdmin
+}
+
+// GetModelLimitList 返回成员可用模型列表，为空表示不限制
+func (member *OrganizationMember) GetModelLimitList() []string {
+	models := make([]string, 0)
+	for _, name := range strings.Split(member.ModelLimits, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			models = append(models, name)
+		}
+	}
+	return models
+}
+
+func (org *Organization) Update() error {
+	return DB.Model(org).Select("name", "status").Updates(org).Error
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	var org Organization
+	if err := DB.First(&org, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func GetAllOrganizations(startIdx int, num int) ([]*Organization, int64, error) {
+	var orgs []*Organization
+	var total int64
+	tx := DB.Model(&Organization{})
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id DESC").Offset(startIdx).Limit(num).Find(&orgs).Error
+	return orgs, total, err
+}
+
+// AdjustOrganizationQuota 管理员调整组织额度池，delta 为负时不能扣成负数
+func AdjustOrganizationQuota(orgId int, delta int) error {
+	tx := DB.Model(&Organization{}).Where("id = ?", orgId)
+	if delta < 0 {
+		tx = tx.Where("quota >= ?", -delta)
+	}
+	result := tx.Update("quota", gorm.Expr("quota + ?", delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := GetOrganizationById(orgId); err != nil {
+			return err
+		}
+		return ErrOrganizationQuotaNotEnough
+	}
+	return nil
+}
+
+// CreateOrganizationWithOwner 创建组织并把 owner 设为正式的组织管理员
+func CreateOrganizationWithOwner(org *Organization, ownerId int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureNotActiveMember(tx, ownerId, 0); err != nil {
+			return err
+		}
+		org.CreatedTime = common.GetTimestamp()
+		if org.Status == 0 {
+			org.Status = OrganizationStatusEnabled
+		}
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		member := &OrganizationMember{
+			OrganizationId: org.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleAdmin,
+			Status:         OrganizationMemberStatusActive,
+			CreatedTime:    org.CreatedTime,
+		}
+		return tx.Create(member).Error
+	})
+}
+
+func ensureNotActiveMember(tx *gorm.DB, userId int, exceptOrgId int) error {
+	var count int64
+	err := tx.Model(&OrganizationMember{}).
+		Where("user_id = ? AND status = ? AND organization_id <> ?", userId, OrganizationMemberStatusActive, exceptOrgId).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrOrganizationAlreadyJoined
+	}
+	return nil
+}
+
+// GetActiveOrganizationMember 返回用户当前所在组织的成员记录，未加入组织时返回 nil
+func GetActiveOrganizationMember(userId int) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := DB.Where("user_id = ? AND status = ?", userId, OrganizationMemberStatusActive).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func GetOrganizationMember(orgId int, userId int) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := DB.Where("organization_id = ? AND user_id = ?", orgId, userId).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetOrganizationMembers 列出组织成员（含未接受的邀请），附带用户名
+func GetOrganizationMembers(orgId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("organization_id = ?", orgId).Order("id ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	fillOrganizationMemberNames(members)
+	return members, nil
+}
+
+func fillOrganizationMemberNames(members []*OrganizationMember) {
+	if len(members) == 0 {
+		return
+	}
+	userIds := make([]int, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+	var users []User
+	if err := DB.Select("id", "username").Where("id IN ?", userIds).Find(&users).Error; err != nil {
+		return
+	}
+	names := make(map[int]string, len(users))
+	for _, user := range users {
+		names[user.Id] = user.Username
+	}
+	for _, member := range members {
+		member.Username = names[member.UserId]
+	}
+}
+
+// InviteOrganizationMember 邀请用户加入组织，被邀请人接受后才成为正式成员
+func InviteOrganizationMember(orgId int, username string, role string, inviterId int) (*OrganizationMember, error) {
+	var user User
+	if err := DB.Select("id", "username").Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	if err := ensureNotActiveMember(DB, user.Id, orgId); err != nil {
+		return nil, err
+	}
+	if _, err := GetOrganizationMember(orgId, user.Id); err == nil {
+		return nil, errors.New("该用户已是成员或已被邀请")
+	} else if !errors.Is(err, ErrOrganizationMemberNotFound) {
+		return nil, err
+	}
+	member := &OrganizationMember{
+		OrganizationId: orgId,
+		UserId:         user.Id,
+		Username:       user.Username,
+		Role:           role,
+		Status:         OrganizationMemberStatusInvited,
+		InvitedBy:      inviterId,
+		CreatedTime:    common.GetTimestamp(),
+	}
+	if err := DB.Create(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// GetOrganizationInvitations 列出用户收到的待处理邀请
+func GetOrganizationInvitations(userId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	err := DB.Where("user_id = ? AND status = ?", userId, OrganizationMemberStatusInvited).Order("id DESC").Find(&members).Error
+	return members, err
+}
+
+// AcceptOrganizationInvitation 接受邀请，已加入其他组织时拒绝
+func AcceptOrganizationInvitation(orgId int, userId int) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureNotActiveMember(tx, userId, orgId); err != nil {
+			return err
+		}
+		result := tx.Model(&OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ? AND status = ?", orgId, userId, OrganizationMemberStatusInvited).
+			Update("status", OrganizationMemberStatusActive)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationMemberNotFound
+		}
+		return nil
+	})
+	if err == nil {
+		invalidateOrganizationMemberCache(userId)
+	}
+	return err
+}
+
+// RemoveOrganizationMember 移除成员或拒绝邀请，已分配给成员的额度不自动回收
+func RemoveOrganizationMember(orgId int, userId int) error {
+	result := DB.Where("organization_id = ? AND user_id = ?", orgId, userId).Delete(&OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrganizationMemberNotFound
+	}
+	invalidateOrganizationMemberCache(userId)
+	return nil
+}
+
+// UpdateOrganizationMember 更新成员角色与可用模型
+func UpdateOrganizationMember(member *OrganizationMember) error {
+	err := DB.Model(member).Select("role", "model_limits").Updates(member).Error
+	if err == nil {
+		invalidateOrganizationMemberCache(member.UserId)
+	}
+	return err
+}
+
+// AllocateOrganizationQuota 在组织额度池与成员钱包之间划转额度，delta 为正时分配给成员，为负时回收。
+// 回收不超过该成员累计分配的额度与其当前余额
+func AllocateOrganizationQuota(orgId int, userId int, delta int, operatorId int) error {
+	if delta == 0 {
+		return nil
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		memberTx := tx.Model(&OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ? AND status = ?", orgId, userId, OrganizationMemberStatusActive)
+		orgTx := tx.Model(&Organization{}).Where("id = ?", orgId)
+		userTx := tx.Model(&User{}).Where("id = ?", userId)
+		if delta > 0 {
+			orgTx = orgTx.Where("quota >= ?", delta)
+		} else {
+			memberTx = memberTx.Where("allocated_quota >= ?", -delta)
+			userTx = userTx.Where("quota >= ?", -delta)
+		}
+		result := memberTx.Update("allocated_quota", gorm.Expr("allocated_quota + ?", delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if delta < 0 {
+				return ErrOrganizationMemberQuota
+			}
+			return ErrOrganizationMemberNotFound
+		}
+		result = orgTx.Updates(map[string]interface{}{
+			"quota":           gorm.Expr("quota - ?", delta),
+			"allocated_quota": gorm.Expr("allocated_quota + ?", delta),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationQuotaNotEnough
+		}
+		result = userTx.Update("quota", gorm.Expr("quota + ?", delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationMemberQuota
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	gopool.Go(func() {
+		var cacheErr error
+		if delta > 0 {
+			cacheErr = cacheIncrUserQuota(userId, int64(delta))
+		} else {
+			cacheErr = cacheDecrUserQuota(userId, int64(-delta))
+		}
+		if cacheErr != nil {
+			common.SysLog("failed to update user quota cache: " + cacheErr.Error())
+		}
+	})
+	if delta > 0 {
+		RecordLog(userId, LogTypeTopup, fmt.Sprintf("组织 #%d 分配额度 %s，操作人 %d", orgId, logger.LogQuota(delta), operatorId))
+	} else {
+		RecordLog(userId, LogTypeManage, fmt.Sprintf("组织 #%d 回收额度 %s，操作人 %d", orgId, logger.LogQuota(-delta), operatorId))
+	}
+	return nil
+}
+
+// GetOrganizationUsage 按成员汇总数据看板中的用量
+func GetOrganizationUsage(orgId int, startTime int64, endTime int64) ([]*OrganizationMemberUsage, error) {
+	members, err := GetOrganizationMembers(orgId)
+	if err != nil {
+		return nil, err
+	}
+	usages := make([]*OrganizationMemberUsage, 0, len(members))
+	userIds := make([]int, 0, len(members))
+	index := make(map[int]*OrganizationMemberUsage, len(members))
+	for _, member := range members {
+		if member.Status != OrganizationMemberStatusActive {
+			continue
+		}
+		usage := &OrganizationMemberUsage{UserId: member.UserId, Username: member.Username}
+		usages = append(usages, usage)
+		userIds = append(userIds, member.UserId)
+		index[member.UserId] = usage
+	}
+	if len(userIds) == 0 {
+		return usages, nil
+	}
+	var rows []OrganizationMemberUsage
+	tx := DB.Model(&QuotaData{}).
+		Select("user_id, sum(quota) as quota, sum(token_used) as token_used, sum(count) as count").
+		Where("user_id IN ?", userIds)
+	if startTime > 0 {
+		tx = tx.Where("created_at >= ?", startTime)
+	}
+	if endTime > 0 {
+		tx = tx.Where("created_at <= ?", endTime)
+	}
+	if err := tx.Group("user_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if usage, ok := index[row.UserId]; ok {
+			usage.Quota = row.Quota
+			usage.TokenUsed = row.TokenUsed
+			usage.Count = row.Count
+		}
+	}
+	return usages, nil
+}
+
+// 成员可用模型的进程内缓存，中继请求每次都要检查，避免频繁查库
+const organizationMemberCacheTTL = time.Minute
+
+type organizationModelLimitEntry struct {
+	models   map[string]bool
+	expireAt time.Time
+}
+
+var (
+	organizationModelLimitLock  sync.RWMutex
+	organizationModelLimitCache = make(map[int]organizationModelLimitEntry)
+)
+
+// GetOrganizationModelLimits 返回用户在所在组织中的可用模型，未加入组织或不限制时返回 nil
+func GetOrganizationModelLimits(userId int) (map[string]bool, error) {
+	now := time.Now()
+	organizationModelLimitLock.RLock()
+	entry, ok := organizationModelLimitCache[userId]
+	organizationModelLimitLock.RUnlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.models, nil
+	}
+	member, err := GetActiveOrganizationMember(userId)
+	if err != nil {
+		return nil, err
+	}
+	var models map[string]bool
+	if member != nil {
+		if list := member.GetModelLimitList(); len(list) > 0 {
+			models = make(map[string]bool, len(list))
+			for _, name := range list {
+				models[name] = true
+			}
+		}
+	}
+	organizationModelLimitLock.Lock()
+	organizationModelLimitCache[userId] = organizationModelLimitEntry{models: models, expireAt: now.Add(organizationMemberCacheTTL)}
+	organizationModelLimitLock.Unlock()
+	return models, nil
+}
+
+func invalidateOrganizationMemberCache(userId int) {
+	organizationModelLimitLock.Lock()
+	delete(organizationModelLimitCache, userId)
+	organizationModelLimitLock.Unlock()
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func insertUserForOrganizationTest(t *testing.T, id int, username string, quota int) {
+	t.Helper()
+	user := &User{Id: id, Username: username, Status: common.UserStatusEnabled, Quota: quota}
+	require.NoError(t, DB.Create(user).Error)
+	t.Cleanup(func() { DB.Delete(&User{}, id) })
+}
+
+func TestOrganizationMembershipAndQuota(t *testing.T) {
+	insertUserForOrganizationTest(t, 9101, "org_owner", 0)
+	insertUserForOrganizationTest(t, 9102, "org_member", 100)
+	t.Cleanup(func() {
+		DB.Where("1 = 1").Delete(&OrganizationMember{})
+		DB.Where("1 = 1").Delete(&Organization{})
+	})
+
+	org := &Organization{Name: "Acme"}
+	require.NoError(t, CreateOrganizationWithOwner(org, 9101))
+	require.NoError(t, AdjustOrganizationQuota(org.Id, 1000))
+
+	// 创建者已是其他组织的正式成员时不能再创建
+	require.ErrorIs(t, CreateOrganizationWithOwner(&Organization{Name: "Other"}, 9101), ErrOrganizationAlreadyJoined)
+
+	_, err := InviteOrganizationMember(org.Id, "org_member", OrganizationRoleMember, 9101)
+	require.NoError(t, err)
+	// 未接受邀请前不能分配额度
+	require.ErrorIs(t, AllocateOrganizationQuota(org.Id, 9102, 100, 9101), ErrOrganizationMemberNotFound)
+	require.NoError(t, AcceptOrganizationInvitation(org.Id, 9102))
+
+	require.NoError(t, AllocateOrganizationQuota(org.Id, 9102, 400, 9101))
+	require.ErrorIs(t, AllocateOrganizationQuota(org.Id, 9102, 700, 9101), ErrOrganizationQuotaNotEnough)
+	require.NoError(t, AllocateOrganizationQuota(org.Id, 9102, -150, 9101))
+	// 回收不超过累计分配的额度
+	require.ErrorIs(t, AllocateOrganizationQuota(org.Id, 9102, -300, 9101), ErrOrganizationMemberQuota)
+
+	reloaded, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 750, reloaded.Quota)
+	require.Equal(t, 250, reloaded.AllocatedQuota)
+	var user User
+	require.NoError(t, DB.First(&user, 9102).Error)
+	require.Equal(t, 350, user.Quota)
+
+	limits, err := GetOrganizationModelLimits(9102)
+	require.NoError(t, err)
+	require.Nil(t, limits)
+	member, err := GetOrganizationMember(org.Id, 9102)
+	require.NoError(t, err)
+	member.ModelLimits = "gpt-4o, claude-sonnet-4"
+	require.NoError(t, UpdateOrganizationMember(member))
+	limits, err = GetOrganizationModelLimits(9102)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"gpt-4o": true, "claude-sonnet-4": true}, limits)
+}
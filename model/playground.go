@@ -0,0 +1,117 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// PlaygroundSession 用户保存的操练场对话，可生成带有效期的分享链接供他人只读回放
+type PlaygroundSession struct {
+	Id             int       `json:"id"`
+	UserId         int       `json:"-" gorm:"index"`
+	Title          string    `json:"title" gorm:"size:128"`
+	Model          string    `json:"model" gorm:"size:128"`
+	Group          string    `json:"group" gorm:"size:64"`
+	Messages       JSONValue `json:"messages" gorm:"type:json"`
+	Params         JSONValue `json:"params,omitempty" gorm:"type:json"`
+	ShareCode      string    `json:"share_code,omitempty" gorm:"type:varchar(32);index"`
+	ShareExpiresAt int64     `json:"share_expires_at,omitempty" gorm:"bigint"`
+	CreatedTime    int64     `json:"created_time" gorm:"bigint"`
+	UpdatedTime    int64     `json:"updated_time" gorm:"bigint"`
+}
+
+func (s *PlaygroundSession) Insert() error {
+	now := common.GetTimestamp()
+	s.CreatedTime = now
+	s.UpdatedTime = now
+	return DB.Create(s).Error
+}
+
+// Update 更新对话内容，分享状态通过 SetPlaygroundSessionShare 单独维护
+func (s *PlaygroundSession) Update() error {
+	s.UpdatedTime = common.GetTimestamp()
+	return DB.Model(s).Where("user_id = ?", s.UserId).
+		Select("title", "model", "group", "messages", "params", "updated_time").Updates(s).Error
+}
+
+// GetUserPlaygroundSessions 列表不返回消息内容
+func GetUserPlaygroundSessions(userId int, startIdx int, num int) ([]*PlaygroundSession, int64, error) {
+	var sessions []*PlaygroundSession
+	var total int64
+	query := DB.Model(&PlaygroundSession{}).Where("user_id = ?", userId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("messages", "params").Order("updated_time DESC").Offset(startIdx).Limit(num).Find(&sessions).Error
+	return sessions, total, err
+}
+
+func GetUserPlaygroundSession(userId int, id int) (*PlaygroundSession, error) {
+	var session PlaygroundSession
+	err := DB.First(&session, "id = ? AND user_id = ?", id, userId).Error
+	return &session, err
+}
+
+func DeleteUserPlaygroundSession(userId int, id int) error {
+	return DB.Where("id = ? AND user_id = ?", id, userId).Delete(&PlaygroundSession{}).Error
+}
+
+// SetPlaygroundSessionShare 设置或撤销（shareCode 为空）分享链接
+func SetPlaygroundSessionShare(userId int, id int, shareCode string, expiresAt int64) error {
+	return DB.Model(&PlaygroundSession{}).Where("id = ? AND user_id = ?", id, userId).
+		Updates(map[string]interface{}{"share_code": shareCode, "share_expires_at": expiresAt}).Error
+}
+
+// GetSharedPlaygroundSession 通过分享码获取未过期的对话
+func GetSharedPlaygroundSession(shareCode string, now int64) (*PlaygroundSession, error) {
+	var session PlaygroundSession
+	err := DB.First(&session, "share_code = ? AND share_expires_at > ?", shareCode, now).Error
+	return &session, err
+}
+
+// PlaygroundPrompt 用户命名的提示词预设，同一用户下名称唯一
+type PlaygroundPrompt struct {
+	Id          int       `json:"id"`
+	UserId      int       `json:"-" gorm:"uniqueIndex:uk_playground_prompt_user_name,priority:1"`
+	Name        string    `json:"name" gorm:"size:64;not null;uniqueIndex:uk_playground_prompt_user_name,priority:2"`
+	Content     string    `json:"content" gorm:"type:text"`
+	Model       string    `json:"model,omitempty" gorm:"size:128"`
+	Params      JSONValue `json:"params,omitempty" gorm:"type:json"`
+	CreatedTime int64     `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64     `json:"updated_time" gorm:"bigint"`
+}
+
+func (p *PlaygroundPrompt) Insert() error {
+	now := common.GetTimestamp()
+	p.CreatedTime = now
+	p.UpdatedTime = now
+	return DB.Create(p).Error
+}
+
+func (p *PlaygroundPrompt) Update() error {
+	p.UpdatedTime = common.GetTimestamp()
+	return DB.Model(p).Where("user_id = ?", p.UserId).
+		Select("name", "content", "model", "params", "updated_time").Updates(p).Error
+}
+
+// IsPlaygroundPromptNameDuplicated 检查用户的预设名称是否重复（排除自身 ID）
+func IsPlaygroundPromptNameDuplicated(userId int, id int, name string) (bool, error) {
+	var cnt int64
+	err := DB.Model(&PlaygroundPrompt{}).Where("user_id = ? AND name = ? AND id <> ?", userId, name, id).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func GetUserPlaygroundPrompts(userId int) ([]*PlaygroundPrompt, error) {
+	var prompts []*PlaygroundPrompt
+	err := DB.Where("user_id = ?", userId).Order("updated_time DESC").Find(&prompts).Error
+	return prompts, err
+}
+
+func GetUserPlaygroundPrompt(userId int, id int) (*PlaygroundPrompt, error) {
+	var prompt PlaygroundPrompt
+	err := DB.First(&prompt, "id = ? AND user_id = ?", id, userId).Error
+	return &prompt, err
+}
+
+func DeleteUserPlaygroundPrompt(userId int, id int) error {
+	return DB.Where("id = ? AND user_id = ?", id, userId).Delete(&PlaygroundPrompt{}).Error
+}
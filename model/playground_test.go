@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaygroundSessionShare(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM playground_sessions") })
+	session := &PlaygroundSession{UserId: 1, Title: "demo", Model: "gpt-4o", Messages: JSONValue(`[{"role":"user","content":"hi"}]`)}
+	require.NoError(t, session.Insert())
+
+	// 其他用户无法读取或分享
+	_, err := GetUserPlaygroundSession(2, session.Id)
+	require.Error(t, err)
+
+	require.NoError(t, SetPlaygroundSessionShare(1, session.Id, "code123", 1000))
+	shared, err := GetSharedPlaygroundSession("code123", 999)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"role":"user","content":"hi"}]`, string(shared.Messages))
+
+	_, err = GetSharedPlaygroundSession("code123", 1000)
+	require.Error(t, err)
+
+	require.NoError(t, SetPlaygroundSessionShare(1, session.Id, "", 0))
+	_, err = GetSharedPlaygroundSession("", 0)
+	require.Error(t, err)
+
+	sessions, total, err := GetUserPlaygroundSessions(1, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Empty(t, sessions[0].Messages)
+}
+
+func TestPlaygroundPromptNameDuplicated(t *testing.T) {
+	t.Cleanup(func() { DB.Exec("DELETE FROM playground_prompts") })
+	prompt := &PlaygroundPrompt{UserId: 1, Name: "translator", Content: "Translate to English"}
+	require.NoError(t, prompt.Insert())
+
+	dup, err := IsPlaygroundPromptNameDuplicated(1, 0, "translator")
+	require.NoError(t, err)
+	require.True(t, dup)
+	dup, err = IsPlaygroundPromptNameDuplicated(1, prompt.Id, "translator")
+	require.NoError(t, err)
+	require.False(t, dup)
+	dup, err = IsPlaygroundPromptNameDuplicated(2, 0, "translator")
+	require.NoError(t, err)
+	require.False(t, dup)
+}
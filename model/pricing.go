@@ -35,7 +35,9 @@ type Pricing struct {
 	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
 	BillingMode            string                  `json:"billing_mode,omitempty"`
 	BillingExpr            string                  `json:"billing_expr,omitempty"`
-	PricingVersion         string                  `json:"pricing_version,omitempty"`
+	// VolumePricing 按量阶梯价格，各档倍数作用于上述基础价格，按自然月累计用量
+	VolumePricing  *billing_setting.VolumePricingRule `json:"volume_pricing,omitempty"`
+	PricingVersion string                             `json:"pricing_version,omitempty"`
 }
 
 type PricingVendor struct {
@@ -337,6 +339,9 @@ func updatePricing() {
 				pricing.BillingExpr = expr
 			}
 		}
+		if rule, ok := billing_setting.GetVolumePricing(model); ok {
+			pricing.VolumePricing = rule
+		}
 		pricingMap = append(pricingMap, pricing)
 	}
 
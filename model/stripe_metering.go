@@ -0,0 +1,45 @@
+package model
+
+// GetMaxLogId 返回当前最大的日志 ID
+func GetMaxLogId() (int, error) {
+	var maxId int
+	err := LOG_DB.Model(&Log{}).Select("COALESCE(MAX(id), 0)").Scan(&maxId).Error
+	return maxId, err
+}
+
+// GetNthConsumeLogId 返回 afterId 之后第 n 条消费日志的 ID，不足 n 条时返回最后一条，没有时返回 0
+func GetNthConsumeLogId(afterId int, n int) (int, error) {
+	var ids []int
+	err := LOG_DB.Model(&Log{}).Where("id > ? AND type = ?", afterId, LogTypeConsume).
+		Order("id ASC").Limit(n).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[len(ids)-1], nil
+}
+
+// GetConsumeLogsInRange 读取 (afterId, endId] 范围内的消费日志，只包含计量所需的字段
+func GetConsumeLogsInRange(afterId int, endId int) ([]*Log, error) {
+	var logs []*Log
+	err := LOG_DB.Select("id", "user_id", "prompt_tokens", "completion_tokens", "quota").
+		Where("id > ? AND id <= ? AND type = ?", afterId, endId, LogTypeConsume).
+		Order("id ASC").Find(&logs).Error
+	return logs, err
+}
+
+// GetUserStripeCustomers 返回已绑定 Stripe 客户的用户与客户 ID
+func GetUserStripeCustomers(userIds []int) (map[int]string, error) {
+	customers := make(map[int]string)
+	if len(userIds) == 0 {
+		return customers, nil
+	}
+	var users []User
+	err := DB.Select("id", "stripe_customer").Where("id IN ? AND stripe_customer <> ''", userIds).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		customers[user.Id] = user.StripeCustomer
+	}
+	return customers, nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripeMeteringQueries(t *testing.T) {
+	require.NoError(t, DB.Create(&User{Id: 9301, Username: "metered_user", Status: common.UserStatusEnabled, StripeCustomer: "cus_123"}).Error)
+	require.NoError(t, DB.Create(&User{Id: 9302, Username: "prepaid_user", Status: common.UserStatusEnabled}).Error)
+	t.Cleanup(func() { DB.Delete(&User{}, []int{9301, 9302}) })
+
+	baseId, err := GetMaxLogId()
+	require.NoError(t, err)
+	logs := []*Log{
+		{Id: baseId + 1, UserId: 9301, Type: LogTypeConsume, PromptTokens: 10, CompletionTokens: 5, Quota: 20},
+		{Id: baseId + 2, UserId: 9301, Type: LogTypeTopup, Quota: 1000},
+		{Id: baseId + 3, UserId: 9302, Type: LogTypeConsume, PromptTokens: 7, Quota: 8},
+		{Id: baseId + 4, UserId: 9301, Type: LogTypeConsume, PromptTokens: 1, Quota: 2},
+	}
+	require.NoError(t, LOG_DB.Create(logs).Error)
+	t.Cleanup(func() { LOG_DB.Where("id > ?", baseId).Delete(&Log{}) })
+
+	endId, err := GetNthConsumeLogId(baseId, 2)
+	require.NoError(t, err)
+	require.Equal(t, baseId+3, endId)
+	endId, err = GetNthConsumeLogId(baseId, 100)
+	require.NoError(t, err)
+	require.Equal(t, baseId+4, endId)
+	endId, err = GetNthConsumeLogId(baseId+4, 100)
+	require.NoError(t, err)
+	require.Zero(t, endId)
+
+	ranged, err := GetConsumeLogsInRange(baseId, baseId+3)
+	require.NoError(t, err)
+	require.Len(t, ranged, 2)
+	require.Equal(t, 15, ranged[0].PromptTokens+ranged[0].CompletionTokens)
+
+	customers, err := GetUserStripeCustomers([]int{9301, 9302})
+	require.NoError(t, err)
+	require.Equal(t, map[int]string{9301: "cus_123"}, customers)
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreConsumeUserSubscriptionOrdersByPriorityThenExpiry(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM subscription_plans")
+		DB.Exec("DELETE FROM user_subscriptions")
+		DB.Exec("DELETE FROM subscription_pre_consume_records")
+	})
+	plan := &SubscriptionPlan{Title: "10M tokens / 30 days", DurationUnit: SubscriptionDurationDay, DurationValue: 30, TotalAmount: 1000}
+	require.NoError(t, DB.Create(plan).Error)
+
+	now := common.GetTimestamp()
+	newSub := func(endTime int64, priority int) *UserSubscription {
+		sub := &UserSubscription{UserId: 77, PlanId: plan.Id, AmountTotal: 1000, StartTime: now, EndTime: endTime, Status: "active", Priority: priority}
+		require.NoError(t, DB.Create(sub).Error)
+		return sub
+	}
+	later := newSub(now+30*86400, 0)
+	sooner := newSub(now+86400, 0)
+
+	// 同优先级先消耗最早到期的套餐
+	result, err := PreConsumeUserSubscription("req-expiry", 77, "gpt-4o", 0, 100)
+	require.NoError(t, err)
+	require.Equal(t, sooner.Id, result.UserSubscriptionId)
+
+	// 优先级更高的套餐优先消耗
+	preferred := newSub(now+60*86400, 10)
+	result, err = PreConsumeUserSubscription("req-priority", 77, "gpt-4o", 0, 100)
+	require.NoError(t, err)
+	require.Equal(t, preferred.Id, result.UserSubscriptionId)
+
+	// 余额不足的套餐被跳过
+	result, err = PreConsumeUserSubscription("req-skip", 77, "gpt-4o", 0, 950)
+	require.NoError(t, err)
+	require.Equal(t, later.Id, result.UserSubscriptionId)
+}
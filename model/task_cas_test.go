@@ -43,6 +43,24 @@ func TestMain(m *testing.M) {
 		&SubscriptionPlan{},
 		&SubscriptionOrder{},
 		&UserSubscription{},
+		&Experiment{},
+		&ExperimentMetric{},
+		&Batch{},
+		&BatchItem{},
+		&UserFile{},
+		&UserFileUpstream{},
+		&PlaygroundSession{},
+		&PlaygroundPrompt{},
+		&DatasetSample{},
+		&AssistantResource{},
+		&QuotaData{},
+		&SubscriptionPreConsumeRecord{},
+		&Organization{},
+		&OrganizationMember{},
+		&ChannelProbeLog{},
+		&ChannelProbeState{},
+		&MonthlyUsage{},
+		&Ability{},
 	); err != nil {
 		panic("failed to migrate: " + err.Error())
 	}
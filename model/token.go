@@ -27,8 +27,16 @@ type Token struct {
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
+	CrossGroupRetry    bool           `json:"cross_group_retry"`                     // 跨分组重试，仅auto分组有效
+	NoStore            bool           `json:"no_store"`                              // 合规模式，不落盘、不记录请求与响应内容
+	RpmLimit           int            `json:"rpm_limit" gorm:"default:0"`            // 每分钟请求数上限，0 表示不限制
+	TpmLimit           int            `json:"tpm_limit" gorm:"default:0"`            // 每分钟 token 数上限，0 表示不限制
+	DailyQuotaLimit    int            `json:"daily_quota_limit" gorm:"default:0"`    // 每日消费额度上限，0 表示不限制
+	MonthlyBudgetQuota int            `json:"monthly_budget_quota" gorm:"default:0"` // 每月预算额度，达到阈值时提醒，0 表示不提醒
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
+
+	// 语义缓存相似度阈值（0~1），语义缓存全局启用时生效，0 表示不启用
+	SemanticCacheThreshold float64 `json:"semantic_cache_threshold" gorm:"default:0"`
 }
 
 func (token *Token) Clean() {
@@ -235,6 +243,13 @@ func GetTokenByIds(id int, userId int) (*Token, error) {
 	return &token, err
 }
 
+// GetUserTokensByName 按名称精确查询用户的令牌，供幂等 upsert 使用
+func GetUserTokensByName(userId int, name string) ([]*Token, error) {
+	var tokens []*Token
+	err := DB.Where("user_id = ? AND name = ?", userId, name).Order("id asc").Find(&tokens).Error
+	return tokens, err
+}
+
 func GetTokenById(id int) (*Token, error) {
 	if id == 0 {
 		return nil, errors.New("id 为空！")
@@ -295,7 +310,8 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "no_store",
+		"rpm_limit", "tpm_limit", "daily_quota_limit", "monthly_budget_quota", "semantic_cache_threshold").Updates(token).Error
 	return err
 }
 
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 令牌级 RPM / TPM / 每日消费额度计数，按固定窗口统计。启用 Redis 时在多节点间共享计数
+
+const tokenUsageKeyPrefix = "new-api:token_usage:v1:"
+
+type tokenUsageCounter struct {
+	value    int64
+	expireAt time.Time
+}
+
+var (
+	tokenUsageLock     sync.Mutex
+	tokenUsageCounters = make(map[string]*tokenUsageCounter)
+	tokenUsageSweepAt  time.Time
+)
+
+func tokenRequestMinuteKey(tokenId int, now time.Time) string {
+	return fmt.Sprintf("rpm:%d:%d", tokenId, now.Unix()/60)
+}
+
+func tokenTokensMinuteKey(tokenId int, now time.Time) string {
+	return fmt.Sprintf("tpm:%d:%d", tokenId, now.Unix()/60)
+}
+
+func tokenDailyQuotaKey(tokenId int, now time.Time) string {
+	return fmt.Sprintf("daily_quota:%d:%s", tokenId, now.Format("20060102"))
+}
+
+// IncrTokenMinuteRequests 记录令牌本分钟的一次请求，返回记录后的请求数
+func IncrTokenMinuteRequests(tokenId int) (int64, error) {
+	return incrTokenUsage(tokenRequestMinuteKey(tokenId, time.Now()), 1, 2*time.Minute)
+}
+
+// GetTokenMinuteTokens 返回令牌本分钟已消耗的 token 数
+func GetTokenMinuteTokens(tokenId int) (int64, error) {
+	return getTokenUsage(tokenTokensMinuteKey(tokenId, time.Now()))
+}
+
+// GetTokenDailyQuota 返回令牌当天已消费的额度
+func GetTokenDailyQuota(tokenId int) (int64, error) {
+	return getTokenUsage(tokenDailyQuotaKey(tokenId, time.Now()))
+}
+
+// RecordTokenUsage 累计令牌本分钟的 token 数与当天的消费额度，供令牌级 TPM 与每日额度限制使用
+func RecordTokenUsage(tokenId int, tokens int, quota int) {
+	if tokenId <= 0 {
+		return
+	}
+	now := time.Now()
+	if tokens > 0 {
+		if _, err := incrTokenUsage(tokenTokensMinuteKey(tokenId, now), int64(tokens), 2*time.Minute); err != nil {
+			common.SysError("failed to record token tpm usage: " + err.Error())
+		}
+	}
+	if quota > 0 {
+		if _, err := incrTokenUsage(tokenDailyQuotaKey(tokenId, now), int64(quota), 48*time.Hour); err != nil {
+			common.SysError("failed to record token daily quota: " + err.Error())
+		}
+	}
+}
+
+func incrTokenUsage(key string, delta int64, ttl time.Duration) (int64, error) {
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		// 计数键名已包含统计窗口，每次累加都设置过期时间，避免首次设置失败后计数永不过期
+		pipe := common.RDB.TxPipeline()
+		incr := pipe.IncrBy(ctx, tokenUsageKeyPrefix+key, delta)
+		pipe.Expire(ctx, tokenUsageKeyPrefix+key, ttl)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+		return incr.Val(), nil
+	}
+	now := time.Now()
+	tokenUsageLock.Lock()
+	defer tokenUsageLock.Unlock()
+	sweepTokenUsageLocked(now)
+	counter, ok := tokenUsageCounters[key]
+	if !ok || now.After(counter.expireAt) {
+		counter = &tokenUsageCounter{expireAt: now.Add(ttl)}
+		tokenUsageCounters[key] = counter
+	}
+	counter.value += delta
+	return counter.value, nil
+}
+
+func getTokenUsage(key string) (int64, error) {
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		value, err := common.RDB.Get(ctx, tokenUsageKeyPrefix+key).Int64()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return 0, nil
+			}
+			return 0, err
+		}
+		return value, nil
+	}
+	tokenUsageLock.Lock()
+	defer tokenUsageLock.Unlock()
+	counter, ok := tokenUsageCounters[key]
+	if !ok || time.Now().After(counter.expireAt) {
+		return 0, nil
+	}
+	return counter.value, nil
+}
+
+// sweepTokenUsageLocked 每分钟清理一次过期的内存计数
+func sweepTokenUsageLocked(now time.Time) {
+	if now.Before(tokenUsageSweepAt) {
+		return
+	}
+	tokenUsageSweepAt = now.Add(time.Minute)
+	for key, counter := range tokenUsageCounters {
+		if now.After(counter.expireAt) {
+			delete(tokenUsageCounters, key)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenUsageCounters(t *testing.T) {
+	const tokenId = 4242
+
+	count, err := IncrTokenMinuteRequests(tokenId)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+	count, err = IncrTokenMinuteRequests(tokenId)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+
+	RecordTokenUsage(tokenId, 300, 50)
+	RecordTokenUsage(tokenId, 200, 25)
+	// 未使用令牌的请求不计数
+	RecordTokenUsage(0, 1000, 1000)
+
+	tokens, err := GetTokenMinuteTokens(tokenId)
+	require.NoError(t, err)
+	require.EqualValues(t, 500, tokens)
+	quota, err := GetTokenDailyQuota(tokenId)
+	require.NoError(t, err)
+	require.EqualValues(t, 75, quota)
+
+	quota, err = GetTokenDailyQuota(tokenId + 1)
+	require.NoError(t, err)
+	require.Zero(t, quota)
+}
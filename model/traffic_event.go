@@ -0,0 +1,36 @@
+package model
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	trafficstream "github.com/QuantumNous/new-api/pkg/traffic_stream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publishTrafficEvent 向管理员实时流量推送一次成功请求
+func publishTrafficEvent(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	latencyMs := int64(params.UseTimeSeconds) * 1000
+	if startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime); !startTime.IsZero() {
+		latencyMs = time.Since(startTime).Milliseconds()
+	}
+	trafficstream.Publish(trafficstream.Event{
+		RequestId:        c.GetString(common.RequestIdKey),
+		UserId:           userId,
+		Username:         c.GetString("username"),
+		TokenName:        params.TokenName,
+		Model:            params.ModelName,
+		Group:            params.Group,
+		ChannelId:        params.ChannelId,
+		StatusCode:       http.StatusOK,
+		Success:          true,
+		LatencyMs:        latencyMs,
+		IsStream:         params.IsStream,
+		PromptTokens:     params.PromptTokens,
+		CompletionTokens: params.CompletionTokens,
+		Quota:            params.Quota,
+	})
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaDataAggregatesCacheTokens(t *testing.T) {
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM quota_data")
+		CacheQuotaData = make(map[string]*QuotaData)
+	})
+	createdAt := int64(7200)
+	LogQuotaData(1, "alice", "claude-sonnet", createdAt, QuotaDataUsage{Quota: 10, TokenUsed: 100, CacheTokens: 60, CacheCreationTokens: 20})
+	LogQuotaData(1, "alice", "claude-sonnet", createdAt+10, QuotaDataUsage{Quota: 5, TokenUsed: 50, CacheTokens: 30})
+	SaveQuotaDataCache()
+
+	// 已存在的记录按增量累加
+	LogQuotaData(1, "alice", "claude-sonnet", createdAt, QuotaDataUsage{Quota: 1, TokenUsed: 10, CacheCreationTokens: 5})
+	SaveQuotaDataCache()
+
+	quotaDatas, err := GetAllQuotaDates(0, createdAt+3600, "")
+	require.NoError(t, err)
+	require.Len(t, quotaDatas, 1)
+	require.Equal(t, 3, quotaDatas[0].Count)
+	require.Equal(t, 16, quotaDatas[0].Quota)
+	require.Equal(t, 90, quotaDatas[0].CacheTokens)
+	require.Equal(t, 25, quotaDatas[0].CacheCreationTokens)
+}
+
+func TestOtherInfoInt(t *testing.T) {
+	other := map[string]interface{}{"cache_tokens": 12, "cache_creation_tokens": float64(3), "cache_ratio": "x"}
+	require.Equal(t, 12, otherInfoInt(other, "cache_tokens"))
+	require.Equal(t, 3, otherInfoInt(other, "cache_creation_tokens"))
+	require.Equal(t, 0, otherInfoInt(other, "cache_ratio"))
+	require.Equal(t, 0, otherInfoInt(nil, "cache_tokens"))
+}
@@ -0,0 +1,158 @@
+package channelmetrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxSamplesPerSeries 每个渠道+模型保留的最近样本数，超出后覆盖最旧的样本
+const maxSamplesPerSeries = 512
+
+type sample struct {
+	at        int64 // unix 毫秒
+	latencyMs int64
+	success   bool
+}
+
+type seriesKey struct {
+	channelId int
+	model     string
+}
+
+type series struct {
+	mu      sync.Mutex
+	samples []sample
+	next    int
+}
+
+var collector sync.Map
+
+// Stats 渠道+模型在窗口内的延迟与错误率，延迟分位数只统计成功的请求
+type Stats struct {
+	ChannelId int     `json:"channel_id"`
+	Model     string  `json:"model"`
+	Samples   int     `json:"samples"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+}
+
+// Record 记录一次渠道请求的延迟与结果，由中继与渠道自动测试共同写入
+func Record(channelId int, model string, latency time.Duration, success bool) {
+	if channelId <= 0 || model == "" {
+		return
+	}
+	value, _ := collector.LoadOrStore(seriesKey{channelId: channelId, model: model}, &series{})
+	value.(*series).add(sample{
+		at:        time.Now().UnixMilli(),
+		latencyMs: latency.Milliseconds(),
+		success:   success,
+	})
+}
+
+func (s *series) add(item sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < maxSamplesPerSeries {
+		s.samples = append(s.samples, item)
+		return
+	}
+	s.samples[s.next] = item
+	s.next = (s.next + 1) % maxSamplesPerSeries
+}
+
+func (s *series) stats(key seriesKey, since int64) Stats {
+	s.mu.Lock()
+	latencies := make([]int64, 0, len(s.samples))
+	stats := Stats{ChannelId: key.channelId, Model: key.model}
+	for _, item := range s.samples {
+		if item.at < since {
+			continue
+		}
+		stats.Samples++
+		if !item.success {
+			stats.Errors++
+			continue
+		}
+		latencies = append(latencies, item.latencyMs)
+	}
+	s.mu.Unlock()
+
+	if stats.Samples > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Samples)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.P50Ms = percentile(latencies, 0.5)
+	stats.P95Ms = percentile(latencies, 0.95)
+	return stats
+}
+
+// percentile 按最近秩法计算已排序延迟的分位数
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+// GetStats 返回渠道+模型在窗口内的统计
+func GetStats(channelId int, model string, window time.Duration) Stats {
+	key := seriesKey{channelId: channelId, model: model}
+	value, ok := collector.Load(key)
+	if !ok {
+		return Stats{ChannelId: channelId, Model: model}
+	}
+	return value.(*series).stats(key, time.Now().Add(-window).UnixMilli())
+}
+
+// Snapshot 返回窗口内有样本的全部渠道+模型统计，按渠道与模型排序
+func Snapshot(window time.Duration) []Stats {
+	since := time.Now().Add(-window).UnixMilli()
+	result := make([]Stats, 0)
+	collector.Range(func(key, value any) bool {
+		stats := value.(*series).stats(key.(seriesKey), since)
+		if stats.Samples > 0 {
+			result = append(result, stats)
+		}
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChannelId != result[j].ChannelId {
+			return result[i].ChannelId < result[j].ChannelId
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
+
+// SelectFastest 选择健康（错误率不超过 maxErrorRate）且 p50 延迟最低的渠道，p50 相同时比较 p95。
+// 任一渠道样本不足 minSamples 时返回 false，由调用方按权重随机选择，使新渠道和恢复中的渠道持续获得采样
+func SelectFastest(channelIds []int, model string, window time.Duration, minSamples int, maxErrorRate float64) (int, bool) {
+	if minSamples < 1 {
+		minSamples = 1
+	}
+	var best *Stats
+	for _, channelId := range channelIds {
+		stats := GetStats(channelId, model, window)
+		if stats.Samples < minSamples {
+			return 0, false
+		}
+		if stats.ErrorRate > maxErrorRate || stats.Samples == stats.Errors {
+			continue
+		}
+		if best == nil || stats.P50Ms < best.P50Ms || (stats.P50Ms == best.P50Ms && stats.P95Ms < best.P95Ms) {
+			best = &stats
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.ChannelId, true
+}
@@ -0,0 +1,58 @@
+package channelmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func recordN(channelId int, model string, n int, latency time.Duration, success bool) {
+	for i := 0; i < n; i++ {
+		Record(channelId, model, latency, success)
+	}
+}
+
+func TestStatsPercentiles(t *testing.T) {
+	for i := 1; i <= 100; i++ {
+		Record(1001, "gpt-4o", time.Duration(i)*time.Millisecond, true)
+	}
+	recordN(1001, "gpt-4o", 25, time.Millisecond, false)
+
+	stats := GetStats(1001, "gpt-4o", time.Minute)
+	require.Equal(t, 125, stats.Samples)
+	require.Equal(t, 25, stats.Errors)
+	require.InDelta(t, 0.2, stats.ErrorRate, 1e-9)
+	require.Equal(t, int64(50), stats.P50Ms)
+	require.Equal(t, int64(95), stats.P95Ms)
+
+	// 超出容量后只保留最近的样本
+	recordN(1001, "gpt-4o", maxSamplesPerSeries, 7*time.Millisecond, true)
+	stats = GetStats(1001, "gpt-4o", time.Minute)
+	require.Equal(t, maxSamplesPerSeries, stats.Samples)
+	require.Equal(t, int64(7), stats.P95Ms)
+}
+
+func TestSelectFastest(t *testing.T) {
+	recordN(2001, "claude", 10, 300*time.Millisecond, true)
+	recordN(2002, "claude", 10, 100*time.Millisecond, true)
+	recordN(2003, "claude", 5, 50*time.Millisecond, true)
+
+	// 2003 样本不足，按权重随机选择
+	_, ok := SelectFastest([]int{2001, 2002, 2003}, "claude", time.Minute, 10, 0.2)
+	require.False(t, ok)
+
+	channelId, ok := SelectFastest([]int{2001, 2002}, "claude", time.Minute, 10, 0.2)
+	require.True(t, ok)
+	require.Equal(t, 2002, channelId)
+
+	// 错误率过高的渠道不被选择
+	recordN(2002, "claude", 10, 0, false)
+	channelId, ok = SelectFastest([]int{2001, 2002}, "claude", time.Minute, 10, 0.2)
+	require.True(t, ok)
+	require.Equal(t, 2001, channelId)
+
+	recordN(2001, "claude", 10, 0, false)
+	_, ok = SelectFastest([]int{2001, 2002}, "claude", time.Minute, 10, 0.2)
+	require.False(t, ok)
+}
@@ -0,0 +1,190 @@
+package circuitbreaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+type breakerKey struct {
+	channelId int
+	model     string
+}
+
+type breaker struct {
+	state               State
+	consecutiveFailures int
+	lastFailureAt       time.Time
+	openedAt            time.Time
+	probes              int
+	probeStartedAt      time.Time
+}
+
+var (
+	mu       sync.Mutex
+	breakers = make(map[breakerKey]*breaker)
+	now      = time.Now
+)
+
+// Status 熔断器状态，供管理接口展示
+type Status struct {
+	ChannelId           int    `json:"channel_id"`
+	Model               string `json:"model"`
+	State               State  `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	OpenedAt            int64  `json:"opened_at,omitempty"`
+	RetryAt             int64  `json:"retry_at,omitempty"`
+}
+
+// Available 判断渠道是否可以接收该模型的请求：熔断中且未到冷却时间，或半开状态探测名额已满时不可用
+func Available(channelId int, model string) bool {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return true
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	b, ok := breakers[breakerKey{channelId: channelId, model: model}]
+	if !ok {
+		return true
+	}
+	switch b.state {
+	case StateOpen:
+		return now().Sub(b.openedAt) >= setting.OpenDuration()
+	case StateHalfOpen:
+		return b.probes < setting.GetHalfOpenProbes() || probeExpired(b, setting)
+	}
+	return true
+}
+
+// probeExpired 探测请求超过冷却时间仍未返回结果时允许新的探测，避免熔断器停在半开状态
+func probeExpired(b *breaker, setting *operation_setting.CircuitBreakerSetting) bool {
+	return now().Sub(b.probeStartedAt) >= setting.OpenDuration()
+}
+
+// OnSelected 渠道被选中时调用，冷却结束的熔断器转为半开并占用一个探测名额
+func OnSelected(channelId int, model string) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	b, ok := breakers[breakerKey{channelId: channelId, model: model}]
+	if !ok {
+		return
+	}
+	switch b.state {
+	case StateOpen:
+		if now().Sub(b.openedAt) < setting.OpenDuration() {
+			return
+		}
+		b.state = StateHalfOpen
+		b.probes = 1
+		b.probeStartedAt = now()
+	case StateHalfOpen:
+		if probeExpired(b, setting) {
+			b.probes = 0
+		}
+		b.probes++
+		b.probeStartedAt = now()
+	}
+}
+
+// Record 记录请求结果：关闭状态下窗口内连续失败达到阈值时熔断；半开状态下探测成功则恢复，失败则重新熔断
+func Record(channelId int, model string, success bool) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	if !setting.Enabled || channelId <= 0 || model == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	key := breakerKey{channelId: channelId, model: model}
+	b, ok := breakers[key]
+	if !ok {
+		if success {
+			return
+		}
+		b = &breaker{state: StateClosed}
+		breakers[key] = b
+	}
+
+	current := now()
+	switch b.state {
+	case StateClosed:
+		if success {
+			delete(breakers, key)
+			return
+		}
+		if current.Sub(b.lastFailureAt) > setting.Window() {
+			b.consecutiveFailures = 0
+		}
+		b.consecutiveFailures++
+		b.lastFailureAt = current
+		if b.consecutiveFailures >= setting.GetFailureThreshold() {
+			b.state = StateOpen
+			b.openedAt = current
+		}
+	case StateHalfOpen:
+		if success {
+			delete(breakers, key)
+			return
+		}
+		b.consecutiveFailures++
+		b.lastFailureAt = current
+		b.state = StateOpen
+		b.openedAt = current
+		b.probes = 0
+	case StateOpen:
+		// 熔断前已发出的请求的结果不改变状态
+	}
+}
+
+// Reset 手动关闭熔断器，model 为空时关闭该渠道所有模型的熔断器
+func Reset(channelId int, model string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for key := range breakers {
+		if key.channelId == channelId && (model == "" || key.model == model) {
+			delete(breakers, key)
+		}
+	}
+}
+
+// Snapshot 返回熔断中、半开以及已有连续失败的熔断器，按渠道与模型排序
+func Snapshot() []Status {
+	openDuration := operation_setting.GetCircuitBreakerSetting().OpenDuration()
+	mu.Lock()
+	result := make([]Status, 0, len(breakers))
+	for key, b := range breakers {
+		status := Status{
+			ChannelId:           key.channelId,
+			Model:               key.model,
+			State:               b.state,
+			ConsecutiveFailures: b.consecutiveFailures,
+		}
+		if b.state != StateClosed {
+			status.OpenedAt = b.openedAt.Unix()
+			status.RetryAt = b.openedAt.Add(openDuration).Unix()
+		}
+		result = append(result, status)
+	}
+	mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChannelId != result[j].ChannelId {
+			return result[i].ChannelId < result[j].ChannelId
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
@@ -0,0 +1,81 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBreaker(t *testing.T) *time.Time {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	original := *setting
+	setting.Enabled = true
+	setting.FailureThreshold = 3
+	setting.WindowSeconds = 60
+	setting.OpenSeconds = 30
+	setting.HalfOpenProbes = 1
+
+	current := time.Unix(1700000000, 0)
+	now = func() time.Time { return current }
+	t.Cleanup(func() {
+		*setting = original
+		now = time.Now
+		mu.Lock()
+		breakers = make(map[breakerKey]*breaker)
+		mu.Unlock()
+	})
+	return &current
+}
+
+func TestBreakerTripsAndRecovers(t *testing.T) {
+	current := setupBreaker(t)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, Available(1, "gpt-4o"))
+		Record(1, "gpt-4o", false)
+	}
+	require.False(t, Available(1, "gpt-4o"))
+	require.True(t, Available(1, "gpt-4o-mini"))
+	require.Equal(t, StateOpen, Snapshot()[0].State)
+
+	// 冷却结束后只放行一个探测请求
+	*current = current.Add(30 * time.Second)
+	require.True(t, Available(1, "gpt-4o"))
+	OnSelected(1, "gpt-4o")
+	require.False(t, Available(1, "gpt-4o"))
+	require.Equal(t, StateHalfOpen, Snapshot()[0].State)
+
+	// 探测失败重新熔断
+	Record(1, "gpt-4o", false)
+	require.False(t, Available(1, "gpt-4o"))
+
+	*current = current.Add(30 * time.Second)
+	OnSelected(1, "gpt-4o")
+	Record(1, "gpt-4o", true)
+	require.True(t, Available(1, "gpt-4o"))
+	require.Empty(t, Snapshot())
+}
+
+func TestBreakerCountsFailuresWithinWindow(t *testing.T) {
+	current := setupBreaker(t)
+
+	Record(2, "claude", false)
+	Record(2, "claude", false)
+	// 超过窗口后重新计数
+	*current = current.Add(2 * time.Minute)
+	Record(2, "claude", false)
+	require.True(t, Available(2, "claude"))
+
+	// 成功请求清零连续失败次数
+	Record(2, "claude", true)
+	Record(2, "claude", false)
+	Record(2, "claude", false)
+	require.True(t, Available(2, "claude"))
+	Record(2, "claude", false)
+	require.False(t, Available(2, "claude"))
+
+	Reset(2, "")
+	require.True(t, Available(2, "claude"))
+}
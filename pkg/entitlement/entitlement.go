@@ -0,0 +1,96 @@
+// Package entitlement gates optional enterprise modules behind a signed
+// license file or an environment grant.
+package entitlement
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// 可被授权的可选模块
+const (
+	FeatureSSO          = "sso"
+	FeatureAuditExport  = "audit_export"
+	FeatureMultiTenancy = "multi_tenancy"
+)
+
+var AllFeatures = []string{
+	FeatureSSO,
+	FeatureAuditExport,
+	FeatureMultiTenancy,
+}
+
+var ErrNotEntitled = errors.New("feature not entitled")
+
+// Checker 授权来源，例如签名许可证文件或环境变量授权
+type Checker interface {
+	Name() string
+	// Entitled 返回该来源是否授予指定功能
+	Entitled(feature string) bool
+}
+
+// FeatureStatus 单个功能的授权状态
+type FeatureStatus struct {
+	Feature string `json:"feature"`
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source,omitempty"`
+}
+
+var (
+	checkersMu sync.RWMutex
+	checkers   []Checker
+)
+
+// RegisterChecker 注册授权来源，任一来源授予即视为已授权
+func RegisterChecker(checker Checker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers = append(checkers, checker)
+}
+
+func resetCheckers() {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers = nil
+}
+
+// grantedBy 返回授予该功能的来源名称，未授权时返回空字符串
+func grantedBy(feature string) string {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+	for _, checker := range checkers {
+		if checker.Entitled(feature) {
+			return checker.Name()
+		}
+	}
+	return ""
+}
+
+func IsEnabled(feature string) bool {
+	return grantedBy(feature) != ""
+}
+
+// Require 未授权时返回 ErrNotEntitled
+func Require(feature string) error {
+	if !IsEnabled(feature) {
+		return ErrNotEntitled
+	}
+	return nil
+}
+
+// Status 返回所有已知功能的授权状态
+func Status() []FeatureStatus {
+	features := append([]string(nil), AllFeatures...)
+	sort.Strings(features)
+	statuses := make([]FeatureStatus, 0, len(features))
+	for _, feature := range features {
+		source := grantedBy(feature)
+		statuses = append(statuses, FeatureStatus{
+			Feature: feature,
+			Enabled: source != "",
+			Source:  source,
+		})
+	}
+	return statuses
+}
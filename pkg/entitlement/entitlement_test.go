@@ -0,0 +1,58 @@
+package entitlement
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func signLicense(t *testing.T, priv ed25519.PrivateKey, license License) []byte {
+	t.Helper()
+	payload, err := common.Marshal(license)
+	require.NoError(t, err)
+	data, err := common.Marshal(licenseFile{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseLicense(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	data := signLicense(t, priv, License{Licensee: "acme", Features: []string{FeatureSSO}})
+	license, err := ParseLicense(data, pub)
+	require.NoError(t, err)
+	require.Equal(t, "acme", license.Licensee)
+
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = ParseLicense(data, otherPub)
+	require.Error(t, err)
+}
+
+func TestCheckers(t *testing.T) {
+	t.Cleanup(resetCheckers)
+	resetCheckers()
+
+	require.False(t, IsEnabled(FeatureSSO))
+	require.ErrorIs(t, Require(FeatureSSO), ErrNotEntitled)
+
+	RegisterChecker(newEnvChecker("audit_export, SSO"))
+	require.True(t, IsEnabled(FeatureSSO))
+	require.True(t, IsEnabled(FeatureAuditExport))
+	require.False(t, IsEnabled(FeatureMultiTenancy))
+
+	RegisterChecker(&licenseChecker{license: License{Licensee: "acme", Features: []string{"*"}, ExpiresAt: time.Now().Add(-time.Hour).Unix()}})
+	require.False(t, IsEnabled(FeatureMultiTenancy), "expired license must not grant features")
+
+	RegisterChecker(newEnvChecker("*"))
+	require.True(t, IsEnabled(FeatureMultiTenancy))
+}
@@ -0,0 +1,29 @@
+package entitlement
+
+import (
+	"strings"
+)
+
+// envChecker 通过环境变量 ENTITLEMENTS 授权，逗号分隔，"*" 表示授予全部功能
+type envChecker struct {
+	features map[string]bool
+}
+
+func newEnvChecker(grant string) *envChecker {
+	features := make(map[string]bool)
+	for _, feature := range strings.Split(grant, ",") {
+		feature = strings.ToLower(strings.TrimSpace(feature))
+		if feature != "" {
+			features[feature] = true
+		}
+	}
+	return &envChecker{features: features}
+}
+
+func (c *envChecker) Name() string {
+	return "env"
+}
+
+func (c *envChecker) Entitled(feature string) bool {
+	return c.features["*"] || c.features[feature]
+}
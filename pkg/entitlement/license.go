@@ -0,0 +1,117 @@
+package entitlement
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// License 许可证内容
+type License struct {
+	Licensee  string   `json:"licensee"`
+	Features  []string `json:"features"`
+	ExpiresAt int64    `json:"expires_at"` // unix 秒，0 表示永不过期
+}
+
+// licenseFile 许可证文件格式：payload 为 License 的 JSON 经 base64 编码，
+// signature 为使用 Ed25519 私钥对 payload 原始字节的签名（base64）
+type licenseFile struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+type licenseChecker struct {
+	license License
+}
+
+// ParseLicense 校验签名并解析许可证
+func ParseLicense(data []byte, publicKey ed25519.PublicKey) (*License, error) {
+	var file licenseFile
+	if err := common.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid license file: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(file.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid license payload: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(file.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid license signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, fmt.Errorf("license signature verification failed")
+	}
+	var license License
+	if err := common.Unmarshal(payload, &license); err != nil {
+		return nil, fmt.Errorf("invalid license payload: %w", err)
+	}
+	return &license, nil
+}
+
+func parsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid license public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid license public key size: %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func (c *licenseChecker) Name() string {
+	return "license:" + c.license.Licensee
+}
+
+func (c *licenseChecker) Entitled(feature string) bool {
+	if c.license.ExpiresAt != 0 && time.Now().Unix() > c.license.ExpiresAt {
+		return false
+	}
+	for _, f := range c.license.Features {
+		if f == feature || f == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func loadLicenseChecker(path string, publicKey string) (*licenseChecker, error) {
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read license file: %w", err)
+	}
+	license, err := ParseLicense(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return &licenseChecker{license: *license}, nil
+}
+
+// Init 从环境变量加载授权来源：
+// ENTITLEMENTS 直接授予功能；LICENSE_FILE 与 LICENSE_PUBLIC_KEY 加载签名许可证
+func Init() {
+	resetCheckers()
+	if grant := os.Getenv("ENTITLEMENTS"); grant != "" {
+		RegisterChecker(newEnvChecker(grant))
+	}
+	path := os.Getenv("LICENSE_FILE")
+	if path == "" {
+		return
+	}
+	checker, err := loadLicenseChecker(path, os.Getenv("LICENSE_PUBLIC_KEY"))
+	if err != nil {
+		common.SysError("failed to load license: " + err.Error())
+		return
+	}
+	RegisterChecker(checker)
+	common.SysLog(fmt.Sprintf("license loaded for %s, features: %s", checker.license.Licensee, strings.Join(checker.license.Features, ",")))
+}
@@ -0,0 +1,190 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Error 执行错误，Path 为出错字段在响应中的路径
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Context 单次查询的执行上下文
+type Context struct {
+	// Role 请求者角色，用于字段级授权
+	Role int
+	// Value 调用方透传的数据，例如 gin.Context
+	Value any
+
+	variables map[string]any
+	fragments map[string]*Fragment
+	errors    []*Error
+}
+
+// Request 标准 GraphQL HTTP 请求体
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Execute 解析并执行查询，返回 data 与执行过程中的字段错误；
+// 语法错误或操作不可执行时 data 为 nil
+func (s *Schema) Execute(req Request, role int, value any) (map[string]any, []*Error) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, []*Error{{Message: err.Error()}}
+	}
+	op, err := doc.Operation(req.OperationName)
+	if err != nil {
+		return nil, []*Error{{Message: err.Error()}}
+	}
+	if op.Type != "query" {
+		return nil, []*Error{{Message: fmt.Sprintf("%s operations are not supported", op.Type)}}
+	}
+	variables := make(map[string]any, len(op.Variables))
+	for _, def := range op.Variables {
+		if v, ok := req.Variables[def.Name]; ok {
+			variables[def.Name] = v
+		} else if def.DefaultValue != nil {
+			if variables[def.Name], err = def.DefaultValue.resolve(nil); err != nil {
+				return nil, []*Error{{Message: err.Error()}}
+			}
+		}
+	}
+	ctx := &Context{Role: role, Value: value, variables: variables, fragments: doc.Fragments}
+	data := ctx.executeSelections(s.Query, nil, op.SelectionSet, nil)
+	return data, ctx.errors
+}
+
+func (ctx *Context) addError(path []any, format string, args ...any) {
+	ctx.errors = append(ctx.errors, &Error{Message: fmt.Sprintf(format, args...), Path: append([]any(nil), path...)})
+}
+
+// collectFields 展开片段并按响应键合并同名字段，同时处理 @include / @skip
+func (ctx *Context) collectFields(selections []*Selection, visited map[string]bool, keys *[]string, fields map[string][]*Selection) {
+	for _, selection := range selections {
+		if !ctx.shouldInclude(selection.Directives) {
+			continue
+		}
+		switch selection.Kind {
+		case selectionField:
+			key := selection.ResponseKey()
+			if _, ok := fields[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			fields[key] = append(fields[key], selection)
+		case selectionInlineFragment:
+			ctx.collectFields(selection.SelectionSet, visited, keys, fields)
+		case selectionFragmentSpread:
+			fragment, ok := ctx.fragments[selection.Name]
+			if !ok {
+				ctx.addError(nil, "unknown fragment %q", selection.Name)
+				continue
+			}
+			if visited[selection.Name] {
+				continue
+			}
+			visited[selection.Name] = true
+			ctx.collectFields(fragment.SelectionSet, visited, keys, fields)
+		}
+	}
+}
+
+func (ctx *Context) shouldInclude(directives []*Directive) bool {
+	for _, directive := range directives {
+		if directive.Name != "include" && directive.Name != "skip" {
+			continue
+		}
+		args, err := resolveArguments(directive.Arguments, ctx.variables)
+		if err != nil {
+			continue
+		}
+		condition, _ := args["if"].(bool)
+		if directive.Name == "include" && !condition || directive.Name == "skip" && condition {
+			return false
+		}
+	}
+	return true
+}
+
+func (ctx *Context) executeSelections(object *Object, source any, selections []*Selection, path []any) map[string]any {
+	var keys []string
+	fields := make(map[string][]*Selection)
+	ctx.collectFields(selections, make(map[string]bool), &keys, fields)
+
+	result := make(map[string]any, len(keys))
+	for _, key := range keys {
+		merged := fields[key]
+		selection := merged[0]
+		fieldPath := append(append([]any(nil), path...), key)
+		if selection.Name == "__typename" {
+			result[key] = object.Name
+			continue
+		}
+		field, ok := object.Fields[selection.Name]
+		if !ok {
+			ctx.addError(fieldPath, "cannot query field %q on type %q", selection.Name, object.Name)
+			result[key] = nil
+			continue
+		}
+		if ctx.Role < field.MinRole {
+			ctx.addError(fieldPath, "permission denied for field %q on type %q", selection.Name, object.Name)
+			result[key] = nil
+			continue
+		}
+		args, err := resolveArguments(selection.Arguments, ctx.variables)
+		if err != nil {
+			ctx.addError(fieldPath, "%v", err)
+			result[key] = nil
+			continue
+		}
+		value, err := field.Resolve(ctx, source, args)
+		if err != nil {
+			ctx.addError(fieldPath, "%v", err)
+			result[key] = nil
+			continue
+		}
+		var subSelections []*Selection
+		for _, s := range merged {
+			subSelections = append(subSelections, s.SelectionSet...)
+		}
+		result[key] = ctx.completeValue(field, value, subSelections, fieldPath)
+	}
+	return result
+}
+
+func (ctx *Context) completeValue(field *Field, value any, selections []*Selection, path []any) any {
+	if field.Type == nil {
+		if len(selections) > 0 {
+			ctx.addError(path, "field of scalar type must not have a selection set")
+			return nil
+		}
+		return value
+	}
+	if len(selections) == 0 {
+		ctx.addError(path, "field of type %q must have a selection set", field.Type.Name)
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	if v.Kind() == reflect.Slice {
+		list := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i).Interface()
+			if reflect.ValueOf(item).Kind() == reflect.Ptr && reflect.ValueOf(item).IsNil() {
+				continue
+			}
+			list[i] = ctx.executeSelections(field.Type, item, selections, append(path, i))
+		}
+		return list
+	}
+	return ctx.executeSelections(field.Type, value, selections, path)
+}
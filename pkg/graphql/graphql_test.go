@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	Id       int     `json:"id"`
+	Username string  `json:"username"`
+	Email    string  `json:"email"`
+	Remark   *string `json:"remark"`
+	Password string  `json:"-"`
+}
+
+type testToken struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+const (
+	roleAdmin = 10
+	roleRoot  = 100
+)
+
+func newTestSchema() *Schema {
+	token := NewObject("Token").AddStructFields(testToken{}, roleAdmin, nil)
+	user := NewObject("User").AddStructFields(testUser{}, roleAdmin, map[string]int{"email": roleRoot})
+	user.AddField("tokens", &Field{Type: token, MinRole: roleAdmin, Resolve: func(ctx *Context, source any, args map[string]any) (any, error) {
+		u := source.(*testUser)
+		limit := ArgInt(args, "limit", 10)
+		tokens := []*testToken{{Id: u.Id * 10, Name: "a"}, {Id: u.Id*10 + 1, Name: "b"}}
+		if limit < len(tokens) {
+			tokens = tokens[:limit]
+		}
+		return tokens, nil
+	}})
+	remark := "vip"
+	users := []*testUser{{Id: 1, Username: "alice", Email: "a@example.com", Remark: &remark}, {Id: 2, Username: "bob"}}
+	query := NewObject("Query").
+		AddField("users", &Field{Type: user, MinRole: roleAdmin, Resolve: func(ctx *Context, source any, args map[string]any) (any, error) {
+			return users, nil
+		}}).
+		AddField("user", &Field{Type: user, MinRole: roleAdmin, Resolve: func(ctx *Context, source any, args map[string]any) (any, error) {
+			id, err := RequireArgInt(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			for _, u := range users {
+				if u.Id == id {
+					return u, nil
+				}
+			}
+			return nil, errors.New("user not found")
+		}})
+	return &Schema{Query: query}
+}
+
+func TestExecuteSelectsFieldsWithAliasesAndArguments(t *testing.T) {
+	schema := newTestSchema()
+	data, errs := schema.Execute(Request{Query: `
+		query ($id: Int!) {
+			first: user(id: $id) { id username remark tokens(limit: 1) { id } }
+			users { __typename username }
+		}`, Variables: map[string]any{"id": float64(1)}}, roleAdmin, nil)
+	require.Empty(t, errs)
+	first := data["first"].(map[string]any)
+	require.Equal(t, 1, first["id"])
+	require.Equal(t, "alice", first["username"])
+	require.Equal(t, "vip", first["remark"])
+	require.Equal(t, []any{map[string]any{"id": 10}}, first["tokens"])
+	require.Equal(t, []any{
+		map[string]any{"__typename": "User", "username": "alice"},
+		map[string]any{"__typename": "User", "username": "bob"},
+	}, data["users"])
+}
+
+func TestExecuteEnforcesFieldRoles(t *testing.T) {
+	schema := newTestSchema()
+	query := `{ user(id: 1) { username email } }`
+
+	data, errs := schema.Execute(Request{Query: query}, roleAdmin, nil)
+	require.Len(t, errs, 1)
+	require.Equal(t, []any{"user", "email"}, errs[0].Path)
+	user := data["user"].(map[string]any)
+	require.Equal(t, "alice", user["username"])
+	require.Nil(t, user["email"])
+
+	data, errs = schema.Execute(Request{Query: query}, roleRoot, nil)
+	require.Empty(t, errs)
+	require.Equal(t, "a@example.com", data["user"].(map[string]any)["email"])
+
+	data, errs = schema.Execute(Request{Query: query}, 1, nil)
+	require.Len(t, errs, 1)
+	require.Nil(t, data["user"])
+}
+
+func TestExecuteFragmentsAndDirectives(t *testing.T) {
+	schema := newTestSchema()
+	data, errs := schema.Execute(Request{Query: `
+		query Q($withTokens: Boolean = false) {
+			user(id: 2) { ...Basic ... on User { tokens @include(if: $withTokens) { id } } remark @skip(if: true) }
+		}
+		fragment Basic on User { id username }`, OperationName: "Q"}, roleAdmin, nil)
+	require.Empty(t, errs)
+	require.Equal(t, map[string]any{"id": 2, "username": "bob"}, data["user"])
+}
+
+func TestExecuteReportsErrors(t *testing.T) {
+	schema := newTestSchema()
+
+	data, errs := schema.Execute(Request{Query: `{ user(id: 3) { id } missing }`}, roleAdmin, nil)
+	require.Len(t, errs, 2)
+	require.Nil(t, data["user"])
+	require.Contains(t, errs[1].Message, `cannot query field "missing"`)
+
+	_, errs = schema.Execute(Request{Query: `{ users }`}, roleAdmin, nil)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Message, "must have a selection set")
+
+	data, errs = schema.Execute(Request{Query: `mutation { users { id } }`}, roleAdmin, nil)
+	require.Nil(t, data)
+	require.Len(t, errs, 1)
+
+	data, errs = schema.Execute(Request{Query: `{ users { id `}, roleAdmin, nil)
+	require.Nil(t, data)
+	require.Contains(t, errs[0].Message, "syntax error")
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := Parse(`{ f(a: -1, b: 2.5e1, c: "x\né", d: [1 2], e: {k: ENUM}, g: null, h: """ block """) }`)
+	require.NoError(t, err)
+	args, err := resolveArguments(doc.Operations[0].SelectionSet[0].Arguments, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), args["a"])
+	require.Equal(t, 25.0, args["b"])
+	require.Equal(t, "x\né", args["c"])
+	require.Equal(t, []any{int64(1), int64(2)}, args["d"])
+	require.Equal(t, map[string]any{"k": "ENUM"}, args["e"])
+	require.Nil(t, args["g"])
+	require.Equal(t, "block", args["h"])
+}
@@ -0,0 +1,206 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) error {
+	line, col := 1, 1
+	for _, r := range l.src[:pos] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// skipIgnored 跳过空白、逗号、BOM 与 # 注释
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch ch := l.src[l.pos]; ch {
+		case ' ', '\t', '\n', '\r', ',':
+			l.pos++
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\uFEFF") {
+				l.pos += len("\uFEFF")
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	ch := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$()[]{}:=@|&", ch) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(ch), pos: start}, nil
+	case ch == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{kind: tokenPunct, value: "...", pos: start}, nil
+		}
+		return token{}, l.errorf(start, "unexpected character %q", ch)
+	case ch == '_' || isLetter(ch):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	case ch == '-' || isDigit(ch):
+		return l.readNumber()
+	case ch == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.readBlockString()
+		}
+		return l.readString()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *lexer) readNumber() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.readDigits() {
+		return token{}, l.errorf(start, "invalid number")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if !l.readDigits() {
+			return token{}, l.errorf(start, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.readDigits() {
+			return token{}, l.errorf(start, "invalid number")
+		}
+	}
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) readDigits() bool {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+func (l *lexer) readString() (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '"':
+			l.pos++
+			return token{kind: tokenString, value: b.String(), pos: start}, nil
+		case ch == '\n' || ch == '\r':
+			return token{}, l.errorf(start, "unterminated string")
+		case ch == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(start, "unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, l.errorf(start, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(start, "invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, l.errorf(l.pos-2, "invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(ch)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) readBlockString() (token, error) {
+	start := l.pos
+	l.pos += 3
+	end := strings.Index(l.src[l.pos:], `"""`)
+	if end < 0 {
+		return token{}, l.errorf(start, "unterminated block string")
+	}
+	raw := l.src[l.pos : l.pos+end]
+	l.pos += end + 3
+	return token{kind: tokenString, value: strings.TrimSpace(strings.ReplaceAll(raw, `\"""`, `"""`)), pos: start}, nil
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,541 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// Value 查询文本中的字面量或变量引用
+type Value struct {
+	Kind   valueKind
+	Raw    string
+	List   []*Value
+	Fields []*Argument
+}
+
+type Argument struct {
+	Name  string
+	Value *Value
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+type selectionKind int
+
+const (
+	selectionField selectionKind = iota
+	selectionFragmentSpread
+	selectionInlineFragment
+)
+
+// Selection 字段、片段展开或内联片段
+type Selection struct {
+	Kind         selectionKind
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []*Selection
+}
+
+// ResponseKey 返回字段在响应中的键名（别名优先）
+func (s *Selection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+type VariableDefinition struct {
+	Name         string
+	DefaultValue *Value
+}
+
+type Operation struct {
+	Type         string
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []*Selection
+}
+
+type Fragment struct {
+	Name         string
+	SelectionSet []*Selection
+}
+
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+// Parse 解析 GraphQL 查询文档
+func Parse(query string) (*Document, error) {
+	p := &parser{lexer: &lexer{src: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokenEOF {
+		if p.tok.kind == tokenName && p.tok.value == "fragment" {
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.Fragments[fragment.Name]; exists {
+				return nil, fmt.Errorf("duplicate fragment %q", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+			continue
+		}
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.Operations = append(doc.Operations, op)
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document contains no operation")
+	}
+	return doc, nil
+}
+
+// Operation 按名称选择要执行的操作，文档只有一个操作时名称可省略
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains multiple operations")
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.lexer.errorf(p.tok.pos, "unexpected end of document")
+	}
+	return p.lexer.errorf(p.tok.pos, "unexpected %q", p.tok.value)
+}
+
+func (p *parser) peekPunct(value string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == value
+}
+
+func (p *parser) expectPunct(value string) error {
+	if !p.peekPunct(value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if p.tok.kind != tokenName || p.tok.value != keyword {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: "query"}
+	if p.peekPunct("{") {
+		selections, err := p.parseSelectionSet()
+		if err != nil {
+			return nil, err
+		}
+		op.SelectionSet = selections
+		return op, nil
+	}
+	opType, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	switch opType {
+	case "query", "mutation", "subscription":
+		op.Type = opType
+	default:
+		return nil, p.lexer.errorf(p.tok.pos, "unknown operation type %q", opType)
+	}
+	if p.tok.kind == tokenName {
+		op.Name = p.tok.value
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peekPunct("(") {
+		if op.Variables, err = p.parseVariableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if op.SelectionSet, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) parseFragment() (*Fragment, error) {
+	if err := p.expectKeyword("fragment"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+	if _, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if _, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: name, SelectionSet: selections}, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*VariableDefinition, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var defs []*VariableDefinition
+	for !p.peekPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if err = p.parseType(); err != nil {
+			return nil, err
+		}
+		def := &VariableDefinition{Name: name}
+		if p.peekPunct("=") {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if def.DefaultValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+// parseType 解析变量类型，类型仅用于语法校验，参数在解析时按实际值转换
+func (p *parser) parseType() error {
+	if p.peekPunct("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.peekPunct("!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Selection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var selections []*Selection
+	for !p.peekPunct("}") {
+		selection, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) parseSelection() (*Selection, error) {
+	if p.peekPunct("...") {
+		return p.parseFragmentSelection()
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	selection := &Selection{Kind: selectionField, Name: name}
+	if p.peekPunct(":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		selection.Alias = name
+		if selection.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peekPunct("(") {
+		if selection.Arguments, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+	if selection.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		if selection.SelectionSet, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return selection, nil
+}
+
+func (p *parser) parseFragmentSelection() (*Selection, error) {
+	if err := p.expectPunct("..."); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		selection := &Selection{Kind: selectionFragmentSpread, Name: p.tok.value}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if selection.Directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		return selection, nil
+	}
+	selection := &Selection{Kind: selectionInlineFragment}
+	if p.tok.kind == tokenName {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if selection.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if selection.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if selection.SelectionSet, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return selection, nil
+}
+
+func (p *parser) parseArguments() ([]*Argument, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var args []*Argument
+	for !p.peekPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: name, Value: value})
+	}
+	return args, p.advance()
+}
+
+func (p *parser) parseDirectives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peekPunct("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		directive := &Directive{Name: name}
+		if p.peekPunct("(") {
+			if directive.Arguments, err = p.parseArguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+func (p *parser) parseValue(constant bool) (*Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt:
+		return &Value{Kind: valueInt, Raw: tok.value}, p.advance()
+	case tokenFloat:
+		return &Value{Kind: valueFloat, Raw: tok.value}, p.advance()
+	case tokenString:
+		return &Value{Kind: valueString, Raw: tok.value}, p.advance()
+	case tokenName:
+		switch tok.value {
+		case "true", "false":
+			return &Value{Kind: valueBoolean, Raw: tok.value}, p.advance()
+		case "null":
+			return &Value{Kind: valueNull}, p.advance()
+		}
+		return &Value{Kind: valueEnum, Raw: tok.value}, p.advance()
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, p.lexer.errorf(tok.pos, "variables are not allowed here")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return &Value{Kind: valueVariable, Raw: name}, nil
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			value := &Value{Kind: valueList}
+			for !p.peekPunct("]") {
+				item, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				value.List = append(value.List, item)
+			}
+			return value, p.advance()
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			value := &Value{Kind: valueObject}
+			for !p.peekPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err = p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				field, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				value.Fields = append(value.Fields, &Argument{Name: name, Value: field})
+			}
+			return value, p.advance()
+		}
+	}
+	return nil, p.unexpected()
+}
+
+// resolve 将字面量转换为 Go 值，变量从 variables 中读取
+func (v *Value) resolve(variables map[string]any) (any, error) {
+	switch v.Kind {
+	case valueVariable:
+		return variables[v.Raw], nil
+	case valueInt:
+		return strconv.ParseInt(v.Raw, 10, 64)
+	case valueFloat:
+		return strconv.ParseFloat(v.Raw, 64)
+	case valueString, valueEnum:
+		return v.Raw, nil
+	case valueBoolean:
+		return v.Raw == "true", nil
+	case valueNull:
+		return nil, nil
+	case valueList:
+		list := make([]any, 0, len(v.List))
+		for _, item := range v.List {
+			resolved, err := item.resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, resolved)
+		}
+		return list, nil
+	case valueObject:
+		return resolveArguments(v.Fields, variables)
+	}
+	return nil, fmt.Errorf("unknown value kind %d", v.Kind)
+}
+
+func resolveArguments(args []*Argument, variables map[string]any) (map[string]any, error) {
+	resolved := make(map[string]any, len(args))
+	for _, arg := range args {
+		value, err := arg.Value.resolve(variables)
+		if err != nil {
+			return nil, err
+		}
+		resolved[arg.Name] = value
+	}
+	return resolved, nil
+}
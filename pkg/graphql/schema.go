@@ -0,0 +1,114 @@
+// Package graphql 实现只读查询所需的 GraphQL 子集：查询解析（别名、参数、变量、片段、@include/@skip）、
+// 基于角色的字段级授权与按选择集执行。不支持 mutation、subscription 与内省查询（__typename 除外）。
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResolveFunc 解析字段值，source 为父对象，args 为已替换变量的参数
+type ResolveFunc func(ctx *Context, source any, args map[string]any) (any, error)
+
+// Field 对象类型上的一个字段。Type 为空表示标量，否则返回值（单个或切片）按 Type 继续执行子选择集
+type Field struct {
+	Type *Object
+	// MinRole 访问该字段所需的最低角色
+	MinRole int
+	Resolve ResolveFunc
+}
+
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+func NewObject(name string) *Object {
+	return &Object{Name: name, Fields: make(map[string]*Field)}
+}
+
+// AddField 添加字段并返回对象本身，便于链式定义
+func (o *Object) AddField(name string, field *Field) *Object {
+	o.Fields[name] = field
+	return o
+}
+
+// AddStructFields 按 json 标签将结构体的导出字段注册为标量字段，
+// roles 用于提升个别字段的访问角色，未列出的字段使用 minRole
+func (o *Object) AddStructFields(sample any, minRole int, roles map[string]int, omit ...string) *Object {
+	t := reflect.TypeOf(sample)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	omitted := make(map[string]bool, len(omit))
+	for _, name := range omit {
+		omitted[name] = true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || omitted[name] {
+			continue
+		}
+		role := minRole
+		if r, ok := roles[name]; ok {
+			role = r
+		}
+		index := sf.Index
+		o.Fields[name] = &Field{MinRole: role, Resolve: func(ctx *Context, source any, args map[string]any) (any, error) {
+			v := reflect.ValueOf(source)
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return nil, nil
+				}
+				v = v.Elem()
+			}
+			f := v.FieldByIndex(index)
+			for f.Kind() == reflect.Ptr {
+				if f.IsNil() {
+					return nil, nil
+				}
+				f = f.Elem()
+			}
+			return f.Interface(), nil
+		}}
+	}
+	return o
+}
+
+type Schema struct {
+	Query *Object
+}
+
+// ArgInt 读取整数参数，兼容 JSON 变量中的浮点数
+func ArgInt(args map[string]any, name string, defaultValue int) int {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return defaultValue
+}
+
+func ArgString(args map[string]any, name string) string {
+	if v, ok := args[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// RequireArgInt 读取必填的整数参数
+func RequireArgInt(args map[string]any, name string) (int, error) {
+	switch args[name].(type) {
+	case int64, float64, int:
+		return ArgInt(args, name, 0), nil
+	}
+	return 0, fmt.Errorf("argument %q of type Int is required", name)
+}
@@ -0,0 +1,159 @@
+// Package grpcrelay 实现 relay.proto 所需的最小 gRPC 线协议：长度前缀帧、Payload 消息编解码与状态码映射。
+// 消息只有一个 bytes 字段，直接手写 protobuf 编码，无需引入 grpc 运行时。
+package grpcrelay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	ContentType = "application/grpc"
+	ServiceName = "newapi.relay.v1.Relay"
+
+	payloadJsonField = 1
+	frameHeaderSize  = 5
+)
+
+// gRPC 状态码，见 https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	CodeOK                 = 0
+	CodeInvalidArgument    = 3
+	CodeDeadlineExceeded   = 4
+	CodeNotFound           = 5
+	CodePermissionDenied   = 7
+	CodeResourceExhausted  = 8
+	CodeFailedPrecondition = 9
+	CodeUnimplemented      = 12
+	CodeInternal           = 13
+	CodeUnavailable        = 14
+	CodeUnauthenticated    = 16
+)
+
+var ErrCompressedMessage = errors.New("compressed grpc messages are not supported")
+
+// EncodePayload 将 JSON 编码为 Payload 消息
+func EncodePayload(json []byte) []byte {
+	msg := make([]byte, 0, len(json)+binary.MaxVarintLen64+1)
+	msg = binary.AppendUvarint(msg, payloadJsonField<<3|2)
+	msg = binary.AppendUvarint(msg, uint64(len(json)))
+	return append(msg, json...)
+}
+
+// DecodePayload 从 Payload 消息中取出 JSON，忽略未知字段
+func DecodePayload(msg []byte) ([]byte, error) {
+	var json []byte
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf field key")
+		}
+		msg = msg[n:]
+		field, wireType := key>>3, key&7
+		switch wireType {
+		case 0:
+			_, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, errors.New("invalid protobuf varint")
+			}
+			msg = msg[n:]
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(msg) < size {
+				return nil, errors.New("truncated protobuf message")
+			}
+			msg = msg[size:]
+		case 2:
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return nil, errors.New("truncated protobuf message")
+			}
+			value := msg[n : n+int(length)]
+			if field == payloadJsonField {
+				json = value
+			}
+			msg = msg[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+	}
+	return json, nil
+}
+
+// ReadFrame 读取一个长度前缀帧，消息超过 maxBytes 时返回错误
+func ReadFrame(r io.Reader, maxBytes int64) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, ErrCompressedMessage
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if int64(length) > maxBytes {
+		return nil, fmt.Errorf("grpc message exceeds %d bytes", maxBytes)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// WriteFrame 写入一个未压缩的长度前缀帧
+func WriteFrame(w io.Writer, msg []byte) error {
+	var header [frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// CodeFromHTTPStatus 将 HTTP 接口的状态码映射为 gRPC 状态码
+func CodeFromHTTPStatus(status int) int {
+	switch {
+	case status >= 200 && status < 300:
+		return CodeOK
+	case status == http.StatusBadRequest:
+		return CodeInvalidArgument
+	case status == http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case status == http.StatusForbidden:
+		return CodePermissionDenied
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusPaymentRequired:
+		return CodeFailedPrecondition
+	case status == http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		return CodeDeadlineExceeded
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// EncodeMessage 按 gRPC 规范对 grpc-message 进行百分号编码
+func EncodeMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		ch := message[i]
+		if ch >= 0x20 && ch <= 0x7e && ch != '%' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
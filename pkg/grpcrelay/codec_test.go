@@ -0,0 +1,58 @@
+package grpcrelay
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadRoundTrip(t *testing.T) {
+	json := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	decoded, err := DecodePayload(EncodePayload(json))
+	require.NoError(t, err)
+	require.Equal(t, json, decoded)
+}
+
+func TestDecodePayloadSkipsUnknownFields(t *testing.T) {
+	// field 2 varint 150, field 3 fixed32, then field 1 bytes "{}"
+	msg := []byte{0x10, 0x96, 0x01, 0x1d, 1, 2, 3, 4}
+	msg = append(msg, EncodePayload([]byte(`{}`))...)
+	decoded, err := DecodePayload(msg)
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{}`), decoded)
+
+	_, err = DecodePayload([]byte{0x0a, 0x05, '{'})
+	require.Error(t, err)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, []byte("hello")))
+	require.Equal(t, []byte{0, 0, 0, 0, 5}, buf.Bytes()[:5])
+
+	msg, err := ReadFrame(bytes.NewReader(buf.Bytes()), 1024)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), msg)
+
+	_, err = ReadFrame(bytes.NewReader(buf.Bytes()), 4)
+	require.Error(t, err)
+
+	compressed := append([]byte{1}, buf.Bytes()[1:]...)
+	_, err = ReadFrame(bytes.NewReader(compressed), 1024)
+	require.ErrorIs(t, err, ErrCompressedMessage)
+}
+
+func TestCodeFromHTTPStatus(t *testing.T) {
+	require.Equal(t, CodeOK, CodeFromHTTPStatus(http.StatusOK))
+	require.Equal(t, CodeUnauthenticated, CodeFromHTTPStatus(http.StatusUnauthorized))
+	require.Equal(t, CodeResourceExhausted, CodeFromHTTPStatus(http.StatusTooManyRequests))
+	require.Equal(t, CodeUnavailable, CodeFromHTTPStatus(http.StatusServiceUnavailable))
+	require.Equal(t, CodeInternal, CodeFromHTTPStatus(http.StatusInternalServerError))
+}
+
+func TestEncodeMessage(t *testing.T) {
+	require.Equal(t, "rate limited", EncodeMessage("rate limited"))
+	require.Equal(t, "100%25 %E9%A2%9D%E5%BA%A6", EncodeMessage("100% 额度"))
+}
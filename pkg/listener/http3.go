@@ -0,0 +1,38 @@
+package listener
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// ListenPacket 为 TCP 监听地址创建同地址同端口的 UDP 监听，用于 HTTP/3（QUIC）
+func ListenPacket(spec Spec) (net.PacketConn, error) {
+	if spec.IsUnix() {
+		return nil, net.UnknownNetworkError(spec.Network)
+	}
+	return net.ListenPacket(strings.Replace(spec.Network, NetworkTCP, "udp", 1), spec.Address)
+}
+
+// NewHTTP3Server 创建 HTTP/3 服务，与 HTTPS 共用证书配置；同一个服务可通过 Serve 挂在多个 UDP 监听上。
+// ACME TLS-ALPN-01 验证仍走 TCP，QUIC 握手只复用已签发的证书
+func NewHTTP3Server(handler http.Handler, tlsConfig *tls.Config) *http3.Server {
+	return &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsConfig),
+	}
+}
+
+// AltSvcHandler 在 HTTPS 响应中添加 Alt-Svc 头，告知客户端可在相同端口升级到 HTTP/3
+func AltSvcHandler(handler http.Handler, server *http3.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && r.ProtoMajor < 3 {
+			// UDP 监听尚未就绪时没有可公布的端口，此时不添加
+			_ = server.SetQUICHeaders(w.Header())
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
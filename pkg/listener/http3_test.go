@@ -0,0 +1,59 @@
+package listener
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP3ServerWithAltSvc(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	tlsConfig, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+	server := NewHTTP3Server(handler, tlsConfig)
+	t.Cleanup(func() { _ = server.Close() })
+
+	spec, err := Parse("tcp4://127.0.0.1:0")
+	require.NoError(t, err)
+	conn, err := ListenPacket(spec)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() { _ = server.Serve(conn) }()
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	t.Cleanup(func() { _ = transport.Close() })
+	resp, err := (&http.Client{Transport: transport}).Get("https://" + conn.LocalAddr().String() + "/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, 3, resp.ProtoMajor)
+	require.Equal(t, "HTTP/3.0", string(body))
+
+	// HTTPS 响应通告 HTTP/3 端口，明文与 HTTP/3 自身的响应不通告
+	altSvc := AltSvcHandler(handler, server)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "https://localhost/", nil)
+	altSvc.ServeHTTP(recorder, request)
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	require.Equal(t, `h3=":`+port+`"; ma=2592000`, recorder.Header().Get("Alt-Svc"))
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	altSvc.ServeHTTP(recorder, request)
+	require.Empty(t, recorder.Header().Get("Alt-Svc"))
+}
+
+func TestListenPacketRejectsUnix(t *testing.T) {
+	_, err := ListenPacket(Spec{Network: NetworkUnix, Address: "/tmp/x.sock"})
+	require.Error(t, err)
+}
@@ -0,0 +1,117 @@
+// Package listener 解析并创建 HTTP 服务的监听地址，支持 TCP（IPv4 / IPv6 / 双栈）与 Unix domain socket，
+// 便于网关同时监听多个地址，或通过本地 socket 挂在反向代理之后。
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// 支持的网络类型：tcp 在通配地址上同时接受 IPv4 与 IPv6；tcp4 / tcp6 仅监听对应协议栈
+const (
+	NetworkTCP  = "tcp"
+	NetworkTCP4 = "tcp4"
+	NetworkTCP6 = "tcp6"
+	NetworkUnix = "unix"
+)
+
+// Spec 单个监听地址
+type Spec struct {
+	Network string
+	Address string
+}
+
+func (s Spec) String() string {
+	return s.Network + "://" + s.Address
+}
+
+// IsUnix 是否为 Unix domain socket
+func (s Spec) IsUnix() bool {
+	return s.Network == NetworkUnix
+}
+
+// Parse 解析监听地址，格式为 network://address，例如 tcp://:3000、tcp4://0.0.0.0:3000、tcp6://[::]:3000、
+// unix:///run/new-api.sock；省略 network 时按 tcp 处理，仅有端口号时监听所有地址
+func Parse(raw string) (Spec, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Spec{}, errors.New("empty listen address")
+	}
+	network, address, found := strings.Cut(raw, "://")
+	if !found {
+		network, address = NetworkTCP, raw
+	}
+	network = strings.ToLower(network)
+	switch network {
+	case NetworkUnix:
+		if address == "" {
+			return Spec{}, fmt.Errorf("invalid listen address %q: missing socket path", raw)
+		}
+		return Spec{Network: network, Address: address}, nil
+	case NetworkTCP, NetworkTCP4, NetworkTCP6:
+		if !strings.Contains(address, ":") {
+			address = ":" + address
+		}
+		if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+			return Spec{}, fmt.Errorf("invalid listen address %q: expect host:port", raw)
+		}
+		return Spec{Network: network, Address: address}, nil
+	default:
+		return Spec{}, fmt.Errorf("invalid listen address %q: unsupported network %q", raw, network)
+	}
+}
+
+// ParseList 解析逗号分隔的多个监听地址，忽略空项，重复地址只保留一个
+func ParseList(raw string) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[Spec]bool)
+	for _, item := range strings.Split(raw, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		spec, err := Parse(item)
+		if err != nil {
+			return nil, err
+		}
+		if seen[spec] {
+			continue
+		}
+		seen[spec] = true
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no listen address configured")
+	}
+	return specs, nil
+}
+
+// Listen 创建监听器。Unix socket 会先清理上次残留的 socket 文件（仅限 socket 类型，避免误删普通文件），
+// 并将权限设置为 socketMode
+func Listen(spec Spec, socketMode os.FileMode) (net.Listener, error) {
+	if !spec.IsUnix() {
+		return net.Listen(spec.Network, spec.Address)
+	}
+	if info, err := os.Lstat(spec.Address); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("listen %s: path exists and is not a socket", spec)
+		}
+		if err := os.Remove(spec.Address); err != nil {
+			return nil, fmt.Errorf("listen %s: remove stale socket: %w", spec, err)
+		}
+	}
+	ln, err := net.Listen(NetworkUnix, spec.Address)
+	if err != nil {
+		return nil, err
+	}
+	if socketMode != 0 {
+		if err := os.Chmod(spec.Address, socketMode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("listen %s: chmod socket: %w", spec, err)
+		}
+	}
+	return ln, nil
+}
@@ -0,0 +1,83 @@
+package listener
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Spec
+	}{
+		{raw: "3000", want: Spec{Network: NetworkTCP, Address: ":3000"}},
+		{raw: ":3000", want: Spec{Network: NetworkTCP, Address: ":3000"}},
+		{raw: "tcp4://0.0.0.0:3000", want: Spec{Network: NetworkTCP4, Address: "0.0.0.0:3000"}},
+		{raw: "TCP6://[::]:3000", want: Spec{Network: NetworkTCP6, Address: "[::]:3000"}},
+		{raw: " unix:///run/new-api.sock ", want: Spec{Network: NetworkUnix, Address: "/run/new-api.sock"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		require.NoError(t, err, tt.raw)
+		require.Equal(t, tt.want, got, tt.raw)
+	}
+
+	for _, raw := range []string{"", "udp://:3000", "unix://", "tcp://"} {
+		_, err := Parse(raw)
+		require.Error(t, err, raw)
+	}
+}
+
+func TestParseListDeduplicates(t *testing.T) {
+	specs, err := ParseList("tcp4://0.0.0.0:3000, tcp6://[::]:3000,,tcp4://0.0.0.0:3000")
+	require.NoError(t, err)
+	require.Equal(t, []Spec{
+		{Network: NetworkTCP4, Address: "0.0.0.0:3000"},
+		{Network: NetworkTCP6, Address: "[::]:3000"},
+	}, specs)
+
+	_, err = ParseList(" , ")
+	require.Error(t, err)
+}
+
+func TestListenUnixReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gw.sock")
+	spec := Spec{Network: NetworkUnix, Address: path}
+
+	first, err := Listen(spec, 0o660)
+	require.NoError(t, err)
+	// 模拟进程异常退出后残留的 socket 文件
+	first.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, first.Close())
+
+	ln, err := Listen(spec, 0o660)
+	require.NoError(t, err)
+	defer ln.Close()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	conn, err := net.Dial(NetworkUnix, path)
+	require.NoError(t, err)
+	_ = conn.Close()
+}
+
+func TestListenUnixRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := Listen(Spec{Network: NetworkUnix, Address: path}, 0)
+	require.Error(t, err)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
@@ -0,0 +1,60 @@
+package listener
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSOptions 下游 TLS 配置：使用证书文件，或通过 ACME（Let's Encrypt）自动签发证书，二者择一
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+	// AutocertDomains 自动签发证书的域名白名单，非空时启用 ACME
+	AutocertDomains []string
+	// AutocertCacheDir 证书缓存目录，重启后复用已签发的证书，避免触发 ACME 频率限制
+	AutocertCacheDir string
+	AutocertEmail    string
+}
+
+// Enabled 是否配置了 TLS
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || len(o.AutocertDomains) > 0
+}
+
+// NewTLSConfig 根据配置创建 tls.Config，未配置 TLS 时返回 nil。
+// 自动签发使用 TLS-ALPN-01 验证，无需额外开放 80 端口，但监听地址需能通过 443 端口访问
+func NewTLSConfig(o TLSOptions) (*tls.Config, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+	if len(o.AutocertDomains) > 0 {
+		if o.CertFile != "" || o.KeyFile != "" {
+			return nil, errors.New("tls: certificate files and autocert domains are mutually exclusive")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(o.AutocertDomains...),
+			Email:      o.AutocertEmail,
+		}
+		if o.AutocertCacheDir != "" {
+			manager.Cache = autocert.DirCache(o.AutocertCacheDir)
+		}
+		config := manager.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config, nil
+	}
+	if o.CertFile == "" || o.KeyFile == "" {
+		return nil, errors.New("tls: both certificate file and key file are required")
+	}
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
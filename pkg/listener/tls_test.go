@@ -0,0 +1,65 @@
+package listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	config, err := NewTLSConfig(TLSOptions{})
+	require.NoError(t, err)
+	require.Nil(t, config)
+
+	certFile, keyFile := writeSelfSignedCert(t)
+	config, err = NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	require.Len(t, config.Certificates, 1)
+
+	config, err = NewTLSConfig(TLSOptions{AutocertDomains: []string{"api.example.com"}, AutocertCacheDir: t.TempDir()})
+	require.NoError(t, err)
+	require.NotNil(t, config.GetCertificate)
+	require.Contains(t, config.NextProtos, "acme-tls/1")
+}
+
+func TestNewTLSConfigRejectsInvalidOptions(t *testing.T) {
+	_, err := NewTLSConfig(TLSOptions{CertFile: "cert.pem"})
+	require.Error(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{CertFile: "cert.pem", KeyFile: "key.pem", AutocertDomains: []string{"api.example.com"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package listener
+
+import (
+	"context"
+	"net/http"
+)
+
+type unixSocketContextKey struct{}
+
+// UnixSocketHandler Unix socket 连接没有对端 IP，补一个回环地址，使 ClientIP 能继续按可信代理解析 X-Forwarded-For；
+// 同时标记请求来自 Unix socket，管理端 IP 白名单等安全检查不能把这个补上的回环地址当作真实来源
+func UnixSocketHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RemoteAddr == "" || r.RemoteAddr == "@" {
+			r.RemoteAddr = "127.0.0.1:0"
+		}
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), unixSocketContextKey{}, true)))
+	})
+}
+
+// IsUnixSocketRequest 请求是否经 Unix socket 到达
+func IsUnixSocketRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	fromUnix, _ := r.Context().Value(unixSocketContextKey{}).(bool)
+	return fromUnix
+}
@@ -0,0 +1,247 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const clientMaxResponseBytes = 16 << 20
+
+// ErrSessionExpired 上游返回 404，会话已失效，需要重新初始化
+var ErrSessionExpired = errors.New("mcp session expired")
+
+// Client 通过 Streamable HTTP 调用上游 MCP 服务器，首次调用时自动完成初始化握手，可并发使用
+type Client struct {
+	URL        string
+	Headers    map[string]string
+	HTTPClient *http.Client
+	Info       Implementation
+
+	// mu 串行化初始化握手，stateMu 保护会话状态
+	mu              sync.Mutex
+	initialized     bool
+	stateMu         sync.RWMutex
+	sessionId       string
+	protocolVersion string
+	nextId          atomic.Int64
+}
+
+func NewClient(url string, headers map[string]string, httpClient *http.Client, info Implementation) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{URL: url, Headers: headers, HTTPClient: httpClient, Info: info}
+}
+
+// ListTools 获取上游的全部工具，自动处理分页
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for page := 0; page < 100; page++ {
+		var result ListToolsResult
+		if err := c.Call(ctx, "tools/list", ListToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+	return nil, errors.New("too many tools/list pages")
+}
+
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.Call(ctx, "tools/call", CallToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Call 发送请求并解码结果，会话失效时重新初始化并重试一次
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	err := c.ensureInitialized(ctx)
+	if err == nil {
+		err = c.roundTrip(ctx, method, params, result)
+	}
+	if !errors.Is(err, ErrSessionExpired) {
+		return err
+	}
+	c.mu.Lock()
+	c.initialized = false
+	c.mu.Unlock()
+	c.setSession("", "")
+	if err = c.ensureInitialized(ctx); err != nil {
+		return err
+	}
+	return c.roundTrip(ctx, method, params, result)
+}
+
+func (c *Client) ensureInitialized(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.initialized {
+		return nil
+	}
+	var result InitializeResult
+	params := InitializeParams{
+		ProtocolVersion: LatestProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      c.Info,
+	}
+	resp, err := c.post(ctx, "initialize", params, false)
+	if err != nil {
+		return err
+	}
+	if err = decodeResult(resp, &result); err != nil {
+		return err
+	}
+	c.setSession(c.session(), result.ProtocolVersion)
+	if _, err = c.post(ctx, "notifications/initialized", nil, true); err != nil {
+		return err
+	}
+	c.initialized = true
+	return nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, method string, params any, result any) error {
+	resp, err := c.post(ctx, method, params, false)
+	if err != nil {
+		return err
+	}
+	return decodeResult(resp, result)
+}
+
+func (c *Client) session() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.sessionId
+}
+
+func (c *Client) setSession(sessionId string, protocolVersion string) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.sessionId = sessionId
+	c.protocolVersion = protocolVersion
+}
+
+// post 发送一条消息，notification 为 true 时不等待响应
+func (c *Client) post(ctx context.Context, method string, params any, notification bool) (*Response, error) {
+	req := Request{JSONRPC: "2.0", Method: method}
+	if !notification {
+		req.ID = json.RawMessage(strconv.FormatInt(c.nextId.Add(1), 10))
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		req.Params = data
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	c.stateMu.RLock()
+	sessionId, protocolVersion := c.sessionId, c.protocolVersion
+	c.stateMu.RUnlock()
+	if sessionId != "" {
+		httpReq.Header.Set(SessionHeader, sessionId)
+	}
+	if protocolVersion != "" {
+		httpReq.Header.Set(ProtocolVersionHeader, protocolVersion)
+	}
+	httpResp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if newSessionId := httpResp.Header.Get(SessionHeader); newSessionId != "" && newSessionId != sessionId {
+		c.stateMu.Lock()
+		c.sessionId = newSessionId
+		c.stateMu.Unlock()
+	}
+	if httpResp.StatusCode == http.StatusNotFound && sessionId != "" {
+		return nil, ErrSessionExpired
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, fmt.Errorf("mcp server returned status code %d: %s", httpResp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if notification {
+		return nil, nil
+	}
+	reader := io.LimitReader(httpResp.Body, clientMaxResponseBytes)
+	if strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/event-stream") {
+		return readEventStreamResponse(reader, req.ID)
+	}
+	var resp Response
+	if err = json.NewDecoder(reader).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid mcp response: %w", err)
+	}
+	return &resp, nil
+}
+
+// readEventStreamResponse 从 SSE 流中找到与请求 ID 对应的响应，忽略服务端推送的通知与请求
+func readEventStreamResponse(reader io.Reader, id json.RawMessage) (*Response, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), clientMaxResponseBytes)
+	var data bytes.Buffer
+	for {
+		more := scanner.Scan()
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !more || line == "" {
+			if data.Len() > 0 {
+				var resp Response
+				if err := json.Unmarshal(data.Bytes(), &resp); err == nil && bytes.Equal(resp.ID, id) {
+					return &resp, nil
+				}
+				data.Reset()
+			}
+			if !more {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("mcp event stream ended without a response")
+}
+
+func decodeResult(resp *Response, result any) error {
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func newEchoServer() *Server {
+	server := NewServer(Implementation{Name: "test", Version: "1.0"})
+	server.AddTool(Tool{Name: "echo", InputSchema: ObjectSchema(map[string]any{"text": map[string]any{"type": "string"}}, "text")},
+		func(ctx context.Context, args json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			if params.Text == "" {
+				return nil, errors.New("text is required")
+			}
+			return TextResult(params.Text), nil
+		})
+	return server
+}
+
+func handle(t *testing.T, server *Server, message string) *Response {
+	t.Helper()
+	var req Request
+	if err := json.Unmarshal([]byte(message), &req); err != nil {
+		t.Fatal(err)
+	}
+	return server.Handle(context.Background(), &req)
+}
+
+func TestServerHandle(t *testing.T) {
+	server := newEchoServer()
+
+	resp := handle(t, server, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`)
+	var initResult InitializeResult
+	if err := decodeResult(resp, &initResult); err != nil {
+		t.Fatal(err)
+	}
+	if initResult.ProtocolVersion != "2025-03-26" || initResult.ServerInfo.Name != "test" {
+		t.Fatalf("unexpected initialize result: %+v", initResult)
+	}
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if err := decodeResult(resp, &initResult); err != nil || initResult.ProtocolVersion != LatestProtocolVersion {
+		t.Fatalf("unsupported version should fall back to latest, got %+v, %v", initResult, err)
+	}
+
+	if resp = handle(t, server, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp != nil {
+		t.Fatalf("notification should not have a response: %+v", resp)
+	}
+
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`)
+	var listResult ListToolsResult
+	if err := decodeResult(resp, &listResult); err != nil || len(listResult.Tools) != 1 || listResult.Tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools/list result: %+v, %v", listResult, err)
+	}
+	if string(resp.ID) != `"a"` {
+		t.Fatalf("response id = %s", resp.ID)
+	}
+
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+	var callResult CallToolResult
+	if err := decodeResult(resp, &callResult); err != nil || callResult.IsError || callResult.Text() != "hi" {
+		t.Fatalf("unexpected tools/call result: %+v, %v", callResult, err)
+	}
+
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo"}}`)
+	callResult = CallToolResult{}
+	if err := decodeResult(resp, &callResult); err != nil || !callResult.IsError {
+		t.Fatalf("tool failure should be reported as isError: %+v, %v", callResult, err)
+	}
+
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`)
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("unknown tool should be invalid params: %+v", resp)
+	}
+	resp = handle(t, server, `{"jsonrpc":"2.0","id":6,"method":"resources/list"}`)
+	if resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Fatalf("unknown method should be method not found: %+v", resp)
+	}
+}
+
+// newHTTPServer 使用 Server 处理请求，sse 为 true 时以事件流返回响应，并在响应前推送一条通知
+func newHTTPServer(t *testing.T, server *Server, sse bool, sessions *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		if req.Method == "initialize" {
+			w.Header().Set(SessionHeader, fmt.Sprintf("session-%d", sessions.Add(1)))
+		} else if r.Header.Get(SessionHeader) != fmt.Sprintf("session-%d", sessions.Load()) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := server.Handle(r.Context(), &req)
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(resp)
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+}
+
+func TestClient(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		var sessions atomic.Int32
+		httpServer := newHTTPServer(t, newEchoServer(), sse, &sessions)
+		client := NewClient(httpServer.URL, nil, httpServer.Client(), Implementation{Name: "client", Version: "1"})
+
+		tools, err := client.ListTools(context.Background())
+		if err != nil || len(tools) != 1 {
+			t.Fatalf("sse=%v: ListTools = %+v, %v", sse, tools, err)
+		}
+		result, err := client.CallTool(context.Background(), "echo", json.RawMessage(`{"text":"hello"}`))
+		if err != nil || result.Text() != "hello" {
+			t.Fatalf("sse=%v: CallTool = %+v, %v", sse, result, err)
+		}
+
+		// 模拟服务端重启导致会话失效，客户端应重新初始化后重试
+		sessions.Add(1)
+		result, err = client.CallTool(context.Background(), "echo", json.RawMessage(`{"text":"again"}`))
+		if err != nil || result.Text() != "again" {
+			t.Fatalf("sse=%v: CallTool after session reset = %+v, %v", sse, result, err)
+		}
+		if sessions.Load() != 3 {
+			t.Fatalf("sse=%v: expected re-initialization, sessions = %d", sse, sessions.Load())
+		}
+
+		_, err = client.CallTool(context.Background(), "missing", nil)
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+			t.Fatalf("sse=%v: expected JSON-RPC error, got %v", sse, err)
+		}
+		httpServer.Close()
+	}
+}
@@ -0,0 +1,156 @@
+// Package mcp 实现 Model Context Protocol 的 Streamable HTTP 传输所需的最小子集：
+// JSON-RPC 2.0 消息、工具列表与工具调用，既可作为服务端处理请求，也可作为客户端调用上游 MCP 服务器。
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LatestProtocolVersion 服务端优先使用的协议版本，客户端请求的版本受支持时原样返回
+const LatestProtocolVersion = "2025-06-18"
+
+var SupportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+const (
+	SessionHeader         = "Mcp-Session-Id"
+	ProtocolVersionHeader = "Mcp-Protocol-Version"
+)
+
+// JSON-RPC 错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request JSON-RPC 请求，ID 为空表示通知
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type InitializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+type InitializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      Implementation `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// Tool 工具定义，InputSchema 为 JSON Schema
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type ListToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type ListToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Content 工具结果中的内容块，网关只产生 text 类型，其余类型原样透传
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+type CallToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+func TextResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// ErrorResult 工具执行失败的结果，按协议约定以 isError 返回而不是 JSON-RPC 错误，便于模型读取错误原因
+func ErrorResult(message string) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: message}}, IsError: true}
+}
+
+// JSONResult 将结构化结果同时以文本与 structuredContent 返回
+func JSONResult(v any) (*CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	result := TextResult(string(data))
+	result.StructuredContent = v
+	return result, nil
+}
+
+// Text 拼接结果中的全部文本内容
+func (r *CallToolResult) Text() string {
+	text := ""
+	for _, content := range r.Content {
+		if content.Type != "text" {
+			continue
+		}
+		if text != "" {
+			text += "\n"
+		}
+		text += content.Text
+	}
+	return text
+}
+
+// ObjectSchema 构造 type 为 object 的 JSON Schema
+func ObjectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+)
+
+// ToolHandler 执行工具调用，返回的 error 会转换为 isError 结果
+type ToolHandler func(ctx context.Context, args json.RawMessage) (*CallToolResult, error)
+
+// Server 无状态的 MCP 服务端，只提供 tools 能力
+type Server struct {
+	Info         Implementation
+	Instructions string
+
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+func NewServer(info Implementation) *Server {
+	return &Server{Info: info, handlers: make(map[string]ToolHandler)}
+}
+
+// AddTool 注册工具，同名工具以后注册的为准
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = ObjectSchema(map[string]any{})
+	}
+	if _, ok := s.handlers[tool.Name]; ok {
+		s.tools = slices.DeleteFunc(s.tools, func(t Tool) bool { return t.Name == tool.Name })
+	}
+	s.tools = append(s.tools, tool)
+	s.handlers[tool.Name] = handler
+}
+
+func (s *Server) Tools() []Tool {
+	return s.tools
+}
+
+// Handle 处理一条 JSON-RPC 消息，通知没有响应，返回 nil
+func (s *Server) Handle(ctx context.Context, req *Request) *Response {
+	if req.IsNotification() {
+		return nil
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, CodeInvalidRequest, "invalid JSON-RPC request")
+	}
+	var result any
+	var rpcErr *Error
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = ListToolsResult{Tools: s.tools}
+	case "tools/call":
+		result, rpcErr = s.callTool(ctx, req.Params)
+	default:
+		rpcErr = &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	if rpcErr != nil {
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, CodeInternalError, err.Error())
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: data}
+}
+
+func (s *Server) initialize(raw json.RawMessage) (any, *Error) {
+	var params InitializeParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+	version := LatestProtocolVersion
+	if slices.Contains(SupportedProtocolVersions, params.ProtocolVersion) {
+		version = params.ProtocolVersion
+	}
+	return InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    map[string]any{"tools": map[string]any{"listChanged": false}},
+		ServerInfo:      s.Info,
+		Instructions:    s.Instructions,
+	}, nil
+}
+
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (any, *Error) {
+	var params CallToolParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	handler, ok := s.handlers[params.Name]
+	if !ok {
+		return nil, &Error{Code: CodeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	args := params.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	result, err := handler(ctx, args)
+	if err != nil {
+		return ErrorResult(err.Error()), nil
+	}
+	if result.Content == nil {
+		result.Content = []Content{}
+	}
+	return result, nil
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: message}}
+}
+
+// ErrorResponse 构造无法解析请求时返回的错误响应
+func ErrorResponse(code int, message string) *Response {
+	return errorResponse(nil, code, message)
+}
@@ -0,0 +1,130 @@
+package prommetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	circuitbreaker "github.com/QuantumNous/new-api/pkg/circuit_breaker"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "new_api"
+
+// latencyBuckets 覆盖 50ms 到 5 分钟，长时间的流式请求落入最后几档
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Relay attempts by channel, model and upstream status code.",
+	}, []string{"channel", "model", "status"})
+
+	upstreamLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_upstream_latency_seconds",
+		Help:      "Total duration of a relay attempt against the upstream channel.",
+		Buckets:   latencyBuckets,
+	}, []string{"channel", "model"})
+
+	streamTTFB = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_stream_ttfb_seconds",
+		Help:      "Time to first byte of streaming relay responses.",
+		Buckets:   latencyBuckets,
+	}, []string{"channel", "model"})
+
+	tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Billed tokens by model and direction (in = prompt, out = completion).",
+	}, []string{"model", "direction"})
+
+	quotaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Quota consumed by model and group.",
+	}, []string{"model", "group"})
+
+	logWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_write_errors_total",
+		Help:      "Failed request log writes by log type.",
+	}, []string{"type"})
+
+	breakerState = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "channel", "breaker_state"),
+		"Circuit breaker state per channel and model, 1 for the current state.",
+		[]string{"channel", "model", "state"}, nil,
+	)
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		relayRequests,
+		upstreamLatency,
+		streamTTFB,
+		tokens,
+		quotaConsumed,
+		logWriteErrors,
+		breakerCollector{},
+	)
+}
+
+// breakerCollector 在抓取时读取熔断器快照，避免在状态变化处埋点
+type breakerCollector struct{}
+
+func (breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerState
+}
+
+func (breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range circuitbreaker.Snapshot() {
+		ch <- prometheus.MustNewConstMetric(breakerState, prometheus.GaugeValue, 1,
+			strconv.Itoa(status.ChannelId), status.Model, string(status.State))
+	}
+}
+
+// Handler 返回 Prometheus 文本格式的指标输出
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RecordRelayAttempt 记录一次渠道尝试，statusCode 为 0 时按 200 计；ttfb 为 0 表示非流式或未返回数据
+func RecordRelayAttempt(channelId int, model string, statusCode int, latency time.Duration, ttfb time.Duration) {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	channel := strconv.Itoa(channelId)
+	relayRequests.WithLabelValues(channel, model, strconv.Itoa(statusCode)).Inc()
+	upstreamLatency.WithLabelValues(channel, model).Observe(latency.Seconds())
+	if ttfb > 0 {
+		streamTTFB.WithLabelValues(channel, model).Observe(ttfb.Seconds())
+	}
+}
+
+// RecordConsumption 记录计费后的 token 与额度消耗
+func RecordConsumption(model string, group string, promptTokens int, completionTokens int, quota int) {
+	if promptTokens > 0 {
+		tokens.WithLabelValues(model, "in").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		tokens.WithLabelValues(model, "out").Add(float64(completionTokens))
+	}
+	if quota > 0 {
+		quotaConsumed.WithLabelValues(model, group).Add(float64(quota))
+	}
+}
+
+// IncLogWriteError 记录一次日志写库失败
+func IncLogWriteError(logType string) {
+	logWriteErrors.WithLabelValues(logType).Inc()
+}
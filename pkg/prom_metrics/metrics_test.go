@@ -0,0 +1,36 @@
+package prommetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerExposesRecordedMetrics(t *testing.T) {
+	RecordRelayAttempt(3, "gpt-4o", 0, 2*time.Second, 300*time.Millisecond)
+	RecordRelayAttempt(3, "gpt-4o", http.StatusTooManyRequests, time.Second, 0)
+	RecordConsumption("gpt-4o", "default", 100, 20, 600)
+	IncLogWriteError("consume")
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`new_api_relay_requests_total{channel="3",model="gpt-4o",status="200"} 1`,
+		`new_api_relay_requests_total{channel="3",model="gpt-4o",status="429"} 1`,
+		`new_api_relay_upstream_latency_seconds_count{channel="3",model="gpt-4o"} 2`,
+		`new_api_relay_stream_ttfb_seconds_count{channel="3",model="gpt-4o"} 1`,
+		`new_api_tokens_total{direction="in",model="gpt-4o"} 100`,
+		`new_api_tokens_total{direction="out",model="gpt-4o"} 20`,
+		`new_api_quota_consumed_total{group="default",model="gpt-4o"} 600`,
+		`new_api_log_write_errors_total{type="consume"} 1`,
+	} {
+		require.True(t, strings.Contains(body, want), "missing %s", want)
+	}
+}
@@ -0,0 +1,180 @@
+package trafficstream
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/google/uuid"
+)
+
+const (
+	// subscriberBuffer 单个订阅者的缓冲事件数，消费过慢时丢弃新事件而不阻塞请求
+	subscriberBuffer = 256
+
+	redisChannel     = "new-api:traffic_stream:v1:events"
+	redisWatcherKey  = "new-api:traffic_stream:v1:watchers"
+	watcherTTL       = 15 * time.Second
+	watcherRefresh   = 5 * time.Second
+	watcherCacheTime = 5 * time.Second
+)
+
+// Event 一次中继请求的实时事件，成功事件在记账后发布，失败事件在每次渠道尝试失败后发布
+type Event struct {
+	Time             int64  `json:"time"` // unix 毫秒
+	Node             string `json:"node,omitempty"`
+	RequestId        string `json:"request_id,omitempty"`
+	UserId           int    `json:"user_id"`
+	Username         string `json:"username,omitempty"`
+	TokenName        string `json:"token_name,omitempty"`
+	Model            string `json:"model"`
+	Group            string `json:"group,omitempty"`
+	ChannelId        int    `json:"channel_id"`
+	StatusCode       int    `json:"status_code"`
+	Success          bool   `json:"success"`
+	LatencyMs        int64  `json:"latency_ms"`
+	IsStream         bool   `json:"is_stream"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+	Error            string `json:"error,omitempty"`
+}
+
+type redisEnvelope struct {
+	Origin string `json:"origin"`
+	Event  Event  `json:"event"`
+}
+
+// Subscription 订阅者通过 Events 读取事件，结束时必须调用 Close
+type Subscription struct {
+	Events <-chan Event
+	ch     chan Event
+	closed atomic.Bool
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = make(map[*Subscription]struct{})
+
+	nodeId = uuid.NewString()
+
+	// relayCancel 非空表示本节点正在监听 Redis 并维持观察者标记
+	relayCancel context.CancelFunc
+
+	remoteWatchersAt    atomic.Int64
+	remoteWatchersValue atomic.Bool
+)
+
+// Subscribe 注册订阅者，启用 Redis 时同时接收其他节点的事件
+func Subscribe() *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: ch, ch: ch}
+	mu.Lock()
+	subscribers[sub] = struct{}{}
+	if common.RedisEnabled && relayCancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		relayCancel = cancel
+		gopool.Go(func() { runRedisRelay(ctx) })
+	}
+	mu.Unlock()
+	return sub
+}
+
+// Close 取消订阅，最后一个订阅者离开时停止监听 Redis
+func (sub *Subscription) Close() {
+	if !sub.closed.CompareAndSwap(false, true) {
+		return
+	}
+	mu.Lock()
+	delete(subscribers, sub)
+	if len(subscribers) == 0 && relayCancel != nil {
+		relayCancel()
+		relayCancel = nil
+	}
+	mu.Unlock()
+	close(sub.ch)
+}
+
+// Publish 发布事件；本节点与其他节点都没有订阅者时直接返回，不产生额外开销
+func Publish(event Event) {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+	if event.Node == "" {
+		event.Node = common.NodeName
+	}
+	deliverLocal(event)
+	if common.RedisEnabled && hasRemoteWatchers() {
+		payload, err := common.Marshal(redisEnvelope{Origin: nodeId, Event: event})
+		if err != nil {
+			return
+		}
+		gopool.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = common.RDB.Publish(ctx, redisChannel, payload).Err()
+		})
+	}
+}
+
+func deliverLocal(event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for sub := range subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// hasRemoteWatchers 查询是否有节点存在订阅者，结果缓存数秒以避免每个请求都访问 Redis
+func hasRemoteWatchers() bool {
+	now := time.Now().UnixNano()
+	if now-remoteWatchersAt.Load() < int64(watcherCacheTime) {
+		return remoteWatchersValue.Load()
+	}
+	remoteWatchersAt.Store(now)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	count, err := common.RDB.Exists(ctx, redisWatcherKey).Result()
+	remoteWatchersValue.Store(err == nil && count > 0)
+	return remoteWatchersValue.Load()
+}
+
+// runRedisRelay 在本节点有订阅者期间维持观察者标记，并把其他节点发布的事件转发给本地订阅者
+func runRedisRelay(ctx context.Context) {
+	refresh := func() {
+		_ = common.RDB.Set(ctx, redisWatcherKey, nodeId, watcherTTL).Err()
+	}
+	refresh()
+	remoteWatchersValue.Store(true)
+	remoteWatchersAt.Store(time.Now().UnixNano())
+
+	pubsub := common.RDB.Subscribe(ctx, redisChannel)
+	defer pubsub.Close()
+	messages := pubsub.Channel()
+	ticker := time.NewTicker(watcherRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var envelope redisEnvelope
+			if err := common.UnmarshalJsonStr(msg.Payload, &envelope); err != nil || envelope.Origin == nodeId {
+				continue
+			}
+			deliverLocal(envelope.Event)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package trafficstream
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishDeliversToSubscribers(t *testing.T) {
+	common.RedisEnabled = false
+
+	first := Subscribe()
+	second := Subscribe()
+	Publish(Event{Model: "gpt-4o", ChannelId: 3, Success: true})
+
+	for _, sub := range []*Subscription{first, second} {
+		event := <-sub.Events
+		require.Equal(t, "gpt-4o", event.Model)
+		require.NotZero(t, event.Time)
+	}
+
+	first.Close()
+	first.Close()
+	_, ok := <-first.Events
+	require.False(t, ok)
+
+	// 订阅者消费过慢时丢弃新事件，不阻塞发布方
+	for i := 0; i < subscriberBuffer+10; i++ {
+		Publish(Event{Model: "gpt-4o"})
+	}
+	require.Len(t, second.Events, subscriberBuffer)
+	second.Close()
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"unicode/utf8"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +26,12 @@ func AudioHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 		return types.NewError(errors.New("invalid request type"), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	if info.RelayMode == relayconstant.RelayModeAudioTranscription || info.RelayMode == relayconstant.RelayModeAudioTranslation {
+		if newAPIError := checkAudioFileSize(c, info); newAPIError != nil {
+			return newAPIError
+		}
+	}
+
 	request, err := common.DeepCopy(audioReq)
 	if err != nil {
 		return types.NewError(fmt.Errorf("failed to copy request to AudioRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
@@ -67,6 +76,10 @@ func AudioHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
+	if info.RelayMode == relayconstant.RelayModeAudioSpeech && applySpeechCharacterPrice(info, audioReq.Input) {
+		service.PostTextConsumeQuota(c, info, usage.(*dto.Usage), nil)
+		return nil
+	}
 	if usage.(*dto.Usage).CompletionTokenDetails.AudioTokens > 0 || usage.(*dto.Usage).PromptTokensDetails.AudioTokens > 0 {
 		service.PostAudioConsumeQuota(c, info, usage.(*dto.Usage), "")
 	} else {
@@ -75,3 +88,39 @@ func AudioHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 
 	return nil
 }
+
+// applySpeechCharacterPrice 语音合成模型配置了字符价格时改为按输入字符数计费：额度 = 字符数 × 单字符价格 × 分组倍率
+func applySpeechCharacterPrice(info *relaycommon.RelayInfo, input string) bool {
+	price, ok := operation_setting.GetAudioSetting().SpeechPricePerCharacter(info.OriginModelName)
+	if !ok {
+		return false
+	}
+	info.PriceData.UsePrice = true
+	info.PriceData.ModelPrice = price
+	info.PriceData.AddOtherRatio("characters", float64(utf8.RuneCountInString(input)))
+	return true
+}
+
+// checkAudioFileSize 按分组限制上传音频文件的大小
+func checkAudioFileSize(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
+	maxBytes := operation_setting.GetAudioSetting().MaxFileBytes(info.UsingGroup)
+	if maxBytes <= 0 {
+		return nil
+	}
+	form, err := common.ParseMultipartFormReusable(c)
+	if err != nil {
+		return types.NewErrorWithStatusCode(fmt.Errorf("error parsing multipart form: %w", err), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	defer form.RemoveAll()
+	for _, fileHeader := range form.File["file"] {
+		if fileHeader.Size > maxBytes {
+			return types.NewErrorWithStatusCode(
+				fmt.Errorf("audio file is too large: %d bytes, the limit for group %s is %d MB", fileHeader.Size, info.UsingGroup, maxBytes>>20),
+				types.ErrorCodeInvalidRequest,
+				http.StatusRequestEntityTooLarge,
+				types.ErrOptionWithSkipRetry(),
+			)
+		}
+	}
+	return nil
+}
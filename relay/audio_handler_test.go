@@ -0,0 +1,27 @@
+package relay
+
+import (
+	"testing"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySpeechCharacterPrice(t *testing.T) {
+	setting := operation_setting.GetAudioSetting()
+	original := setting.SpeechCharacterPrice
+	setting.SpeechCharacterPrice = map[string]float64{"tts-1": 15}
+	t.Cleanup(func() { setting.SpeechCharacterPrice = original })
+
+	info := &relaycommon.RelayInfo{OriginModelName: "tts-1"}
+	require.True(t, applySpeechCharacterPrice(info, "你好, world"))
+	require.True(t, info.PriceData.UsePrice)
+	require.InDelta(t, 0.000015, info.PriceData.ModelPrice, 1e-12)
+	// 按字符而非字节计数
+	require.Equal(t, float64(9), info.PriceData.OtherRatios["characters"])
+
+	info = &relaycommon.RelayInfo{OriginModelName: "gpt-4o-mini-tts"}
+	require.False(t, applySpeechCharacterPrice(info, "hello"))
+	require.False(t, info.PriceData.UsePrice)
+}
@@ -78,6 +78,11 @@ type TaskAdaptor interface {
 	ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error)
 }
 
+// ResponsesNativeAdaptor 原生支持 /v1/responses 的适配器，未实现该接口的适配器经 Chat Completions 转换
+type ResponsesNativeAdaptor interface {
+	SupportsNativeResponses() bool
+}
+
 type OpenAIVideoConverter interface {
 	ConvertToOpenAIVideo(originTask *model.Task) ([]byte, error)
 }
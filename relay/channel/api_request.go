@@ -13,6 +13,7 @@ import (
 
 	common2 "github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
@@ -23,6 +24,9 @@ import (
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func SetupApiRequestHeader(info *common.RelayInfo, c *gin.Context, req *http.Header) {
@@ -371,7 +375,11 @@ func DoWssRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBody
 		targetHeader.Set(key, value)
 	}
 	targetHeader.Set("Content-Type", c.Request.Header.Get("Content-Type"))
-	targetConn, _, err := websocket.DefaultDialer.Dial(fullRequestURL, targetHeader)
+	dialer, err := service.NewChannelWebsocketDialer(info.ChannelSetting, info.GetUpstreamTimeouts())
+	if err != nil {
+		return nil, fmt.Errorf("new channel websocket dialer failed: %w", err)
+	}
+	targetConn, _, err := dialer.Dial(fullRequestURL, targetHeader)
 	if err != nil {
 		return nil, fmt.Errorf("dial failed to %s: %w", fullRequestURL, err)
 	}
@@ -381,7 +389,8 @@ func DoWssRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBody
 	return targetConn, nil
 }
 
-func startPingKeepAlive(c *gin.Context, pingInterval time.Duration) context.CancelFunc {
+// startPingKeepAlive 等待上游响应期间周期发送 SSE 心跳；maxSilent 大于 0 时，等待超过该时长后停止发送
+func startPingKeepAlive(c *gin.Context, pingInterval time.Duration, maxSilent time.Duration) context.CancelFunc {
 	pingerCtx, stopPinger := context.WithCancel(context.Background())
 
 	gopool.Go(func() {
@@ -417,6 +426,9 @@ func startPingKeepAlive(c *gin.Context, pingInterval time.Duration) context.Canc
 
 		// 增加超时控制，防止goroutine长时间运行
 		maxPingDuration := 120 * time.Minute // 最大ping持续时间
+		if maxSilent > 0 && maxSilent < maxPingDuration {
+			maxPingDuration = maxSilent
+		}
 		pingTimeout := time.NewTimer(maxPingDuration)
 		defer pingTimeout.Stop()
 
@@ -480,29 +492,83 @@ func sendPingData(c *gin.Context, mutex *sync.Mutex) error {
 	}
 }
 
+// nonStreamKeepAliveSupported 仅对响应体为 JSON 的文本生成与任务提交请求启用保活，音频、图片等二进制响应不能写入前导空白
+func nonStreamKeepAliveSupported(info *common.RelayInfo) bool {
+	switch info.RelayFormat {
+	case types.RelayFormatOpenAI, types.RelayFormatClaude, types.RelayFormatGemini,
+		types.RelayFormatOpenAIResponses, types.RelayFormatTask:
+		return true
+	}
+	return false
+}
+
+// startNonStreamKeepAlive 等待上游非流式响应期间，每隔 pingInterval 向客户端写入一个换行，防止中间代理因空闲超时断开连接；
+// 首次写入前才提交 200 响应头，上游在一个间隔内返回时不影响原有状态码。返回的函数会等待保活协程退出后再返回
+func startNonStreamKeepAlive(c *gin.Context, pingInterval time.Duration) func() {
+	if pingInterval <= 0 {
+		pingInterval = helper.DefaultPingInterval
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	gopool.Go(func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				if common2.DebugEnabled {
+					println("non-stream keep-alive goroutine panic recovered:", fmt.Sprintf("%v", r))
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !c.Writer.Written() {
+					c.Writer.Header().Set("Content-Type", "application/json")
+					c.Writer.Header().Set("X-Accel-Buffering", "no")
+					c.Writer.WriteHeader(http.StatusOK)
+				}
+				if _, err := c.Writer.Write([]byte("\n")); err != nil {
+					return
+				}
+				if err := helper.FlushWriter(c); err != nil {
+					return
+				}
+			case <-stop:
+				return
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+	})
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
 func DoRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http.Response, error) {
 	return doRequest(c, req, info)
 }
 func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http.Response, error) {
-	var client *http.Client
-	var err error
-	if info.ChannelSetting.Proxy != "" {
-		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
-		if err != nil {
-			return nil, fmt.Errorf("new proxy http client failed: %w", err)
-		}
-	} else {
-		client = service.GetHttpClient()
+	client, err := service.NewChannelHttpClient(info.ChannelSetting, info.GetUpstreamTimeouts())
+	if err != nil {
+		return nil, fmt.Errorf("new channel http client failed: %w", err)
 	}
 
 	var stopPinger context.CancelFunc
+	generalSettings := operation_setting.GetGeneralSetting()
 	if info.IsStream {
 		helper.SetEventStreamHeaders(c)
 		// 处理流式请求的 ping 保活
-		generalSettings := operation_setting.GetGeneralSetting()
 		if generalSettings.PingIntervalEnabled && !info.DisablePing {
 			pingInterval := time.Duration(generalSettings.PingIntervalSeconds) * time.Second
-			stopPinger = startPingKeepAlive(c, pingInterval)
+			stopPinger = startPingKeepAlive(c, pingInterval, generalSettings.PingMaxSilentDuration())
 			// 使用defer确保在任何情况下都能停止ping goroutine
 			defer func() {
 				if stopPinger != nil {
@@ -513,16 +579,37 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 				}
 			}()
 		}
-	}
-
+	} else if generalSettings.NonStreamKeepAliveEnabled && !info.DisablePing && nonStreamKeepAliveSupported(info) {
+		// 处理非流式请求的空白字符保活，须在返回前同步停止，避免与响应体写入交错
+		pingInterval := time.Duration(generalSettings.PingIntervalSeconds) * time.Second
+		stopKeepAlive := startNonStreamKeepAlive(c, pingInterval)
+		defer stopKeepAlive()
+	}
+
+	// 上游请求的 span 只用于传递 traceparent，不改变请求本身的 context，避免客户端断开时提前取消
+	_, span := tracing.Start(c.Request.Context(), "upstream.request", trace.SpanKindClient,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.Int("channel.id", info.ChannelId),
+		attribute.String("upstream.model", info.UpstreamModelName),
+	)
+	spanCtx := trace.ContextWithSpan(req.Context(), span)
+	tracing.Inject(spanCtx, req.Header)
 	resp, err := client.Do(req)
 	if err != nil {
+		tracing.End(span, err)
 		logger.LogError(c, "do request failed: "+err.Error())
 		return nil, types.NewError(err, types.ErrorCodeDoRequestFailed, types.ErrOptionWithHideErrMsg("upstream error: do request failed"))
 	}
 	if resp == nil {
+		tracing.End(span, errors.New("resp is nil"))
 		return nil, errors.New("resp is nil")
 	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
 
 	if upID := resp.Header.Get(common2.RequestIdKey); upID != "" {
 		c.Set(common2.UpstreamRequestIdKey, upID)
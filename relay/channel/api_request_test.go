@@ -3,9 +3,12 @@ package channel
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
@@ -191,3 +194,44 @@ func TestProcessHeaderOverride_PassHeadersTemplateSetsRuntimeHeaders(t *testing.
 	require.Equal(t, "sess-123", upstreamReq.Header.Get("Session_id"))
 	require.Empty(t, upstreamReq.Header.Get("X-Codex-Beta-Features"))
 }
+
+func TestStartNonStreamKeepAlive_WritesWhitespaceUntilStopped(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	stop := startNonStreamKeepAlive(ctx, 10*time.Millisecond)
+	time.Sleep(35 * time.Millisecond)
+	stop()
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.NotEmpty(t, recorder.Body.String())
+	require.Empty(t, strings.TrimSpace(recorder.Body.String()))
+
+	// 停止后不再写入，后续响应体紧跟在空白之后仍是合法 JSON
+	written := recorder.Body.Len()
+	time.Sleep(25 * time.Millisecond)
+	require.Equal(t, written, recorder.Body.Len())
+}
+
+func TestStartNonStreamKeepAlive_FastUpstreamKeepsStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	stop := startNonStreamKeepAlive(ctx, time.Hour)
+	stop()
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "bad"})
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestNonStreamKeepAliveSupported(t *testing.T) {
+	require.True(t, nonStreamKeepAliveSupported(&relaycommon.RelayInfo{RelayFormat: types.RelayFormatOpenAI}))
+	require.True(t, nonStreamKeepAliveSupported(&relaycommon.RelayInfo{RelayFormat: types.RelayFormatTask}))
+	require.False(t, nonStreamKeepAliveSupported(&relaycommon.RelayInfo{RelayFormat: types.RelayFormatOpenAIAudio}))
+	require.False(t, nonStreamKeepAliveSupported(&relaycommon.RelayInfo{RelayFormat: types.RelayFormatOpenAIImage}))
+}
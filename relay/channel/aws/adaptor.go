@@ -31,6 +31,7 @@ type Adaptor struct {
 	AwsModelId string
 	AwsReq     any
 	IsNova     bool
+	IsConverse bool
 }
 
 func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
@@ -122,6 +123,12 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 		return novaReq, nil
 	}
 
+	// Llama、Titan 等非 Claude 模型使用 Converse API
+	if isConverseModel(getAwsModelID(request.Model)) {
+		a.IsConverse = true
+		return convertToConverseRequest(request), nil
+	}
+
 	// 原有的Claude模型处理逻辑
 	claudeReq, err := claude.RequestOpenAI2ClaudeMessage(c, *request)
 	if err != nil {
@@ -160,6 +167,12 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	} else {
 		if a.IsNova {
 			err, usage = handleNovaRequest(c, info, a)
+		} else if a.IsConverse {
+			if info.IsStream {
+				err, usage = handleConverseStreamRequest(c, info, a)
+			} else {
+				err, usage = handleConverseRequest(c, info, a)
+			}
 		} else {
 			if info.IsStream {
 				err, usage = awsStreamHandler(c, info, a)
@@ -28,6 +28,14 @@ var awsModelIDMap = map[string]string{
 	"nova-reel-v1:0":    "amazon.nova-reel-v1:0",
 	"nova-reel-v1:1":    "amazon.nova-reel-v1:1",
 	"nova-sonic-v1:0":   "amazon.nova-sonic-v1:0",
+	// Converse API models
+	"llama3-3-70b-instruct-v1:0": "meta.llama3-3-70b-instruct-v1:0",
+	"llama3-1-70b-instruct-v1:0": "meta.llama3-1-70b-instruct-v1:0",
+	"llama3-1-8b-instruct-v1:0":  "meta.llama3-1-8b-instruct-v1:0",
+	"titan-text-premier-v1:0":    "amazon.titan-text-premier-v1:0",
+	"titan-text-express-v1":      "amazon.titan-text-express-v1",
+	"titan-text-lite-v1":         "amazon.titan-text-lite-v1",
+	"mistral-large-2402-v1:0":    "mistral.mistral-large-2402-v1:0",
 }
 
 var awsModelCanCrossRegionMap = map[string]map[string]bool{
@@ -139,6 +147,15 @@ var awsModelCanCrossRegionMap = map[string]map[string]bool{
 		"eu":   true,
 		"apac": true,
 	},
+	"meta.llama3-3-70b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama3-1-70b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama3-1-8b-instruct-v1:0": {
+		"us": true,
+	},
 }
 
 var awsRegionCrossModelPrefixMap = map[string]string{
@@ -0,0 +1,288 @@
+package aws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// converseModelPrefixes 使用 Converse API 调用的模型（Llama、Titan、Mistral 等），请求体格式与 Claude 不同
+var converseModelPrefixes = []string{
+	"meta.",
+	"amazon.titan-text",
+	"mistral.",
+	"cohere.command",
+	"ai21.",
+	"deepseek.",
+}
+
+// isConverseModel 判断模型是否通过 Converse API 调用，模型 ID 可带跨区域前缀（如 us.meta.llama3-3-70b-instruct-v1:0）
+func isConverseModel(modelId string) bool {
+	for _, prefix := range converseModelPrefixes {
+		if strings.Contains(modelId, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConverseRequest Converse API 请求体，字段与 Bedrock Converse JSON 格式一致
+type ConverseRequest struct {
+	Messages        []ConverseMessage        `json:"messages"`
+	System          []ConverseContent        `json:"system,omitempty"`
+	InferenceConfig *ConverseInferenceConfig `json:"inferenceConfig,omitempty"`
+}
+
+type ConverseMessage struct {
+	Role    string            `json:"role"`
+	Content []ConverseContent `json:"content"`
+}
+
+type ConverseContent struct {
+	Text string `json:"text"`
+}
+
+type ConverseInferenceConfig struct {
+	MaxTokens     *int32   `json:"maxTokens,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+// convertToConverseRequest 转换 OpenAI 请求为 Converse 格式：system/developer 消息放入 system，
+// 其余消息按 user/assistant 交替合并，Converse 要求相邻消息角色不同
+func convertToConverseRequest(req *dto.GeneralOpenAIRequest) *ConverseRequest {
+	converseReq := &ConverseRequest{}
+	for _, msg := range req.Messages {
+		text := msg.StringContent()
+		switch msg.Role {
+		case "system", "developer":
+			if text != "" {
+				converseReq.System = append(converseReq.System, ConverseContent{Text: text})
+			}
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "assistant"
+		}
+		if last := len(converseReq.Messages) - 1; last >= 0 && converseReq.Messages[last].Role == role {
+			converseReq.Messages[last].Content = append(converseReq.Messages[last].Content, ConverseContent{Text: text})
+			continue
+		}
+		converseReq.Messages = append(converseReq.Messages, ConverseMessage{
+			Role:    role,
+			Content: []ConverseContent{{Text: text}},
+		})
+	}
+
+	config := &ConverseInferenceConfig{}
+	hasConfig := false
+	if maxTokens := req.GetMaxTokens(); maxTokens > 0 {
+		config.MaxTokens = aws.Int32(int32(maxTokens))
+		hasConfig = true
+	}
+	if req.Temperature != nil {
+		config.Temperature = aws.Float32(float32(*req.Temperature))
+		hasConfig = true
+	}
+	if req.TopP != nil {
+		config.TopP = aws.Float32(float32(*req.TopP))
+		hasConfig = true
+	}
+	if stopSequences := parseStopSequences(req.Stop); len(stopSequences) > 0 {
+		config.StopSequences = stopSequences
+		hasConfig = true
+	}
+	if hasConfig {
+		converseReq.InferenceConfig = config
+	}
+	return converseReq
+}
+
+func (r *ConverseRequest) toSDKInput() ([]bedrockruntimeTypes.Message, []bedrockruntimeTypes.SystemContentBlock, *bedrockruntimeTypes.InferenceConfiguration) {
+	messages := make([]bedrockruntimeTypes.Message, 0, len(r.Messages))
+	for _, msg := range r.Messages {
+		content := make([]bedrockruntimeTypes.ContentBlock, 0, len(msg.Content))
+		for _, block := range msg.Content {
+			content = append(content, &bedrockruntimeTypes.ContentBlockMemberText{Value: block.Text})
+		}
+		messages = append(messages, bedrockruntimeTypes.Message{
+			Role:    bedrockruntimeTypes.ConversationRole(msg.Role),
+			Content: content,
+		})
+	}
+	system := make([]bedrockruntimeTypes.SystemContentBlock, 0, len(r.System))
+	for _, block := range r.System {
+		system = append(system, &bedrockruntimeTypes.SystemContentBlockMemberText{Value: block.Text})
+	}
+	var config *bedrockruntimeTypes.InferenceConfiguration
+	if r.InferenceConfig != nil {
+		config = &bedrockruntimeTypes.InferenceConfiguration{
+			MaxTokens:     r.InferenceConfig.MaxTokens,
+			Temperature:   r.InferenceConfig.Temperature,
+			TopP:          r.InferenceConfig.TopP,
+			StopSequences: r.InferenceConfig.StopSequences,
+		}
+	}
+	return messages, system, config
+}
+
+// stopReasonConverse2OpenAI 转换 Converse 的 stopReason 为 OpenAI 的 finish_reason
+func stopReasonConverse2OpenAI(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "content_filtered", "guardrail_intervened":
+		return "content_filter"
+	default:
+		return reason
+	}
+}
+
+// converseUsage 将 Bedrock 用量折算为计费用量：inputTokens 不含缓存读写部分，prompt_tokens 需要加回
+func converseUsage(usage *bedrockruntimeTypes.TokenUsage) *dto.Usage {
+	result := &dto.Usage{}
+	if usage == nil {
+		return result
+	}
+	cacheRead := int(aws.ToInt32(usage.CacheReadInputTokens))
+	cacheWrite := int(aws.ToInt32(usage.CacheWriteInputTokens))
+	result.PromptTokens = int(aws.ToInt32(usage.InputTokens)) + cacheRead + cacheWrite
+	result.CompletionTokens = int(aws.ToInt32(usage.OutputTokens))
+	result.TotalTokens = result.PromptTokens + result.CompletionTokens
+	result.PromptTokensDetails.CachedTokens = cacheRead
+	result.PromptTokensDetails.CachedCreationTokens = cacheWrite
+	return result
+}
+
+func handleConverseRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
+	ctx, cancel := newAwsInvokeContext(info)
+	defer cancel()
+
+	awsResp, err := a.AwsClient.Converse(ctx, a.AwsReq.(*bedrockruntime.ConverseInput))
+	if err != nil {
+		statusCode := getAwsErrorStatusCode(err)
+		return types.NewOpenAIError(errors.Wrap(err, "Converse"), types.ErrorCodeAwsInvokeError, statusCode), nil
+	}
+
+	var text strings.Builder
+	if output, ok := awsResp.Output.(*bedrockruntimeTypes.ConverseOutputMemberMessage); ok {
+		for _, block := range output.Value.Content {
+			if textBlock, ok := block.(*bedrockruntimeTypes.ContentBlockMemberText); ok {
+				text.WriteString(textBlock.Value)
+			}
+		}
+	}
+	usage := converseUsage(awsResp.Usage)
+	if usage.TotalTokens == 0 {
+		usage = service.ResponseText2Usage(c, text.String(), info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+
+	response := dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Model:   info.UpstreamModelName,
+		Choices: []dto.OpenAITextResponseChoice{{
+			Index: 0,
+			Message: dto.Message{
+				Role:    "assistant",
+				Content: text.String(),
+			},
+			FinishReason: stopReasonConverse2OpenAI(string(awsResp.StopReason)),
+		}},
+		Usage: *usage,
+	}
+	c.JSON(http.StatusOK, response)
+	return nil, usage
+}
+
+func handleConverseStreamRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
+	ctx, cancel := newAwsInvokeContext(info)
+	defer cancel()
+
+	awsResp, err := a.AwsClient.ConverseStream(ctx, a.AwsReq.(*bedrockruntime.ConverseStreamInput))
+	if err != nil {
+		statusCode := getAwsErrorStatusCode(err)
+		return types.NewOpenAIError(errors.Wrap(err, "ConverseStream"), types.ErrorCodeAwsInvokeError, statusCode), nil
+	}
+	stream := awsResp.GetStream()
+	defer stream.Close()
+
+	id := helper.GetResponseID(c)
+	created := common.GetTimestamp()
+	usage := &dto.Usage{}
+	var responseText strings.Builder
+	helper.SetEventStreamHeaders(c)
+
+	for event := range stream.Events() {
+		switch v := event.(type) {
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStart:
+			info.SetFirstResponseTime()
+			if err := helper.ObjectData(c, helper.GenerateStartEmptyResponse(id, created, info.UpstreamModelName, nil)); err != nil {
+				logger.LogError(c, "error_rendering_stream_response: "+err.Error())
+			}
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockDelta:
+			info.SetFirstResponseTime()
+			delta, ok := v.Value.Delta.(*bedrockruntimeTypes.ContentBlockDeltaMemberText)
+			if !ok {
+				continue
+			}
+			chunk := dto.ChatCompletionsStreamResponse{
+				Id:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   info.UpstreamModelName,
+				Choices: []dto.ChatCompletionsStreamResponseChoice{{}},
+			}
+			chunk.Choices[0].Delta.SetContentString(delta.Value)
+			responseText.WriteString(delta.Value)
+			if err := helper.ObjectData(c, chunk); err != nil {
+				logger.LogError(c, "error_rendering_stream_response: "+err.Error())
+			}
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStop:
+			stop := helper.GenerateStopResponse(id, created, info.UpstreamModelName, stopReasonConverse2OpenAI(string(v.Value.StopReason)))
+			if err := helper.ObjectData(c, stop); err != nil {
+				logger.LogError(c, "error_rendering_stream_response: "+err.Error())
+			}
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMetadata:
+			usage = converseUsage(v.Value.Usage)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return types.NewOpenAIError(errors.Wrap(err, "ConverseStream"), types.ErrorCodeAwsInvokeError, getAwsErrorStatusCode(err)), nil
+	}
+	// 上游未返回 metadata 事件时按输出文本估算用量
+	if usage.TotalTokens == 0 {
+		usage = service.ResponseText2Usage(c, responseText.String(), info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+
+	if info.ShouldIncludeUsage {
+		if err := helper.ObjectData(c, helper.GenerateFinalUsageResponse(id, created, info.UpstreamModelName, *usage)); err != nil {
+			logger.LogError(c, "error_rendering_final_usage_response: "+err.Error())
+		}
+	}
+	helper.Done(c)
+	return nil, usage
+}
@@ -40,11 +40,16 @@ func getAwsErrorStatusCode(err error) int {
 	return http.StatusInternalServerError
 }
 
-func newAwsInvokeContext() (context.Context, context.CancelFunc) {
-	if common.RelayTimeout <= 0 {
+// newAwsInvokeContext 创建 Bedrock 调用的 context，渠道或模型配置了总超时时优先使用，否则沿用 RELAY_TIMEOUT
+func newAwsInvokeContext(info *relaycommon.RelayInfo) (context.Context, context.CancelFunc) {
+	timeout := common.RelayTimeout
+	if totalTimeout := info.GetUpstreamTimeouts().TotalTimeout; totalTimeout > 0 {
+		timeout = totalTimeout
+	}
+	if timeout <= 0 {
 		return context.Background(), func() {}
 	}
-	return context.WithTimeout(context.Background(), time.Duration(common.RelayTimeout)*time.Second)
+	return context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 }
 
 func newAwsClient(c *gin.Context, info *relaycommon.RelayInfo) (*bedrockruntime.Client, error) {
@@ -52,13 +57,9 @@ func newAwsClient(c *gin.Context, info *relaycommon.RelayInfo) (*bedrockruntime.
 		httpClient *http.Client
 		err        error
 	)
-	if info.ChannelSetting.Proxy != "" {
-		httpClient, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
-		if err != nil {
-			return nil, fmt.Errorf("new proxy http client failed: %w", err)
-		}
-	} else {
-		httpClient = service.GetHttpClient()
+	httpClient, err = service.NewChannelHttpClient(info.ChannelSetting, info.GetUpstreamTimeouts())
+	if err != nil {
+		return nil, fmt.Errorf("new channel http client failed: %w", err)
 	}
 
 	awsSecret := strings.Split(info.ApiKey, "|")
@@ -136,6 +137,29 @@ func doAwsClientRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor,
 		awsReq.Body = reqBody
 		a.AwsReq = awsReq
 		return nil, nil
+	} else if isConverseModel(awsModelId) {
+		var converseReq ConverseRequest
+		if err := common.DecodeJson(requestBody, &converseReq); err != nil {
+			return nil, types.NewError(errors.Wrap(err, "decode converse request fail"), types.ErrorCodeBadRequestBody)
+		}
+		// Converse API 由 SDK 按 SigV4（或 API Key）签名，请求体按 SDK 结构传入
+		messages, system, inferenceConfig := converseReq.toSDKInput()
+		if info.IsStream {
+			a.AwsReq = &bedrockruntime.ConverseStreamInput{
+				ModelId:         aws.String(awsModelId),
+				Messages:        messages,
+				System:          system,
+				InferenceConfig: inferenceConfig,
+			}
+		} else {
+			a.AwsReq = &bedrockruntime.ConverseInput{
+				ModelId:         aws.String(awsModelId),
+				Messages:        messages,
+				System:          system,
+				InferenceConfig: inferenceConfig,
+			}
+		}
+		return nil, nil
 	} else {
 		awsClaudeReq, err := formatRequest(requestBody, requestHeader)
 		if err != nil {
@@ -223,7 +247,7 @@ func getAwsModelID(requestModel string) string {
 
 func awsHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
 
-	ctx, cancel := newAwsInvokeContext()
+	ctx, cancel := newAwsInvokeContext(info)
 	defer cancel()
 
 	awsResp, err := a.AwsClient.InvokeModel(ctx, a.AwsReq.(*bedrockruntime.InvokeModelInput))
@@ -253,7 +277,7 @@ func awsHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types
 }
 
 func awsStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
-	ctx, cancel := newAwsInvokeContext()
+	ctx, cancel := newAwsInvokeContext(info)
 	defer cancel()
 
 	awsResp, err := a.AwsClient.InvokeModelWithResponseStream(ctx, a.AwsReq.(*bedrockruntime.InvokeModelWithResponseStreamInput))
@@ -296,7 +320,7 @@ func awsStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (
 // Nova模型处理函数
 func handleNovaRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
 
-	ctx, cancel := newAwsInvokeContext()
+	ctx, cancel := newAwsInvokeContext(info)
 	defer cancel()
 
 	awsResp, err := a.AwsClient.InvokeModel(ctx, a.AwsReq.(*bedrockruntime.InvokeModelInput))
@@ -7,8 +7,11 @@ import (
 	"testing"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
@@ -53,3 +56,77 @@ func TestDoAwsClientRequest_AppliesRuntimeHeaderOverrideToAnthropicBeta(t *testi
 	require.True(t, ok)
 	require.Equal(t, []any{"computer-use-2025-01-24"}, values)
 }
+
+func TestConvertToConverseRequest(t *testing.T) {
+	t.Parallel()
+
+	var request dto.GeneralOpenAIRequest
+	require.NoError(t, common.UnmarshalJsonStr(`{
+		"model": "llama3-3-70b-instruct-v1:0",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "hi"},
+			{"role": "user", "content": "there"},
+			{"role": "assistant", "content": "hello"},
+			{"role": "user", "content": "bye"}
+		],
+		"max_tokens": 64,
+		"temperature": 0.5,
+		"stop": "END"
+	}`, &request))
+
+	converseReq := convertToConverseRequest(&request)
+	require.Equal(t, []ConverseContent{{Text: "be brief"}}, converseReq.System)
+	require.Len(t, converseReq.Messages, 3)
+	require.Equal(t, "user", converseReq.Messages[0].Role)
+	// 相邻的同角色消息合并为一条
+	require.Equal(t, []ConverseContent{{Text: "hi"}, {Text: "there"}}, converseReq.Messages[0].Content)
+	require.Equal(t, "assistant", converseReq.Messages[1].Role)
+	require.EqualValues(t, 64, *converseReq.InferenceConfig.MaxTokens)
+	require.EqualValues(t, 0.5, *converseReq.InferenceConfig.Temperature)
+	require.Equal(t, []string{"END"}, converseReq.InferenceConfig.StopSequences)
+}
+
+func TestDoAwsClientRequest_BuildsConverseInput(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	info := &relaycommon.RelayInfo{
+		OriginModelName: "llama3-3-70b-instruct-v1:0",
+		IsStream:        true,
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ApiKey:            "access-key|secret-key|us-west-2",
+			UpstreamModelName: "llama3-3-70b-instruct-v1:0",
+		},
+	}
+	body, err := common.Marshal(&ConverseRequest{Messages: []ConverseMessage{{Role: "user", Content: []ConverseContent{{Text: "hello"}}}}})
+	require.NoError(t, err)
+
+	adaptor := &Adaptor{}
+	_, err = doAwsClientRequest(ctx, info, adaptor, bytes.NewReader(body))
+	require.NoError(t, err)
+
+	awsReq, ok := adaptor.AwsReq.(*bedrockruntime.ConverseStreamInput)
+	require.True(t, ok)
+	require.Equal(t, "us.meta.llama3-3-70b-instruct-v1:0", *awsReq.ModelId)
+	require.Len(t, awsReq.Messages, 1)
+}
+
+func TestConverseUsageIncludesCache(t *testing.T) {
+	t.Parallel()
+
+	usage := converseUsage(&bedrockruntimeTypes.TokenUsage{
+		InputTokens:          aws.Int32(10),
+		OutputTokens:         aws.Int32(5),
+		TotalTokens:          aws.Int32(45),
+		CacheReadInputTokens: aws.Int32(30),
+	})
+	require.Equal(t, 40, usage.PromptTokens)
+	require.Equal(t, 5, usage.CompletionTokens)
+	require.Equal(t, 45, usage.TotalTokens)
+	require.Equal(t, 30, usage.PromptTokensDetails.CachedTokens)
+	require.Equal(t, "length", stopReasonConverse2OpenAI("max_tokens"))
+}
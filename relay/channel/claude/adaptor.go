@@ -6,11 +6,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +27,7 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	applySafetySystemPrompt(request, info)
 	return request, nil
 }
 
@@ -95,7 +98,12 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if request == nil {
 		return nil, errors.New("request is nil")
 	}
-	return RequestOpenAI2ClaudeMessage(c, *request)
+	claudeRequest, err := RequestOpenAI2ClaudeMessage(c, *request)
+	if err != nil {
+		return nil, err
+	}
+	applySafetySystemPrompt(claudeRequest, info)
+	return claudeRequest, nil
 }
 
 func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
@@ -132,3 +140,27 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+// applySafetySystemPrompt 将安全等级对应的约束提示词放在 system 最前面
+func applySafetySystemPrompt(request *dto.ClaudeRequest, info *relaycommon.RelayInfo) {
+	prompt := moderation_setting.GetClaudeSafetyPrompt(info.SafetyLevel)
+	if prompt == "" {
+		return
+	}
+	if request.System == nil {
+		request.SetStringSystem(prompt)
+		return
+	}
+	if request.IsStringSystem() {
+		existing := strings.TrimSpace(request.GetStringSystem())
+		if existing == "" {
+			request.SetStringSystem(prompt)
+		} else {
+			request.SetStringSystem(prompt + "\n" + existing)
+		}
+		return
+	}
+	safetySystem := dto.ClaudeMediaMessage{Type: dto.ContentTypeText}
+	safetySystem.SetText(prompt)
+	request.System = append([]dto.ClaudeMediaMessage{safetySystem}, request.ParseSystem()...)
+}
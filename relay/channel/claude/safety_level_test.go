@@ -0,0 +1,39 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySafetySystemPrompt(t *testing.T) {
+	setting := moderation_setting.GetSafetyLevelSetting()
+	original := setting.ClaudeSystemPrompts
+	setting.ClaudeSystemPrompts = map[string]string{moderation_setting.SafetyLevelStrict: "be safe"}
+	t.Cleanup(func() { setting.ClaudeSystemPrompts = original })
+
+	info := &relaycommon.RelayInfo{SafetyLevel: moderation_setting.SafetyLevelStrict}
+
+	request := &dto.ClaudeRequest{}
+	applySafetySystemPrompt(request, info)
+	require.Equal(t, "be safe", request.GetStringSystem())
+
+	request = &dto.ClaudeRequest{System: "you are helpful"}
+	applySafetySystemPrompt(request, info)
+	require.Equal(t, "be safe\nyou are helpful", request.GetStringSystem())
+
+	existing := dto.ClaudeMediaMessage{Type: dto.ContentTypeText}
+	existing.SetText("you are helpful")
+	request = &dto.ClaudeRequest{System: []dto.ClaudeMediaMessage{existing}}
+	applySafetySystemPrompt(request, info)
+	system := request.ParseSystem()
+	require.Len(t, system, 2)
+	require.Equal(t, "be safe", system[0].GetText())
+
+	request = &dto.ClaudeRequest{System: "you are helpful"}
+	applySafetySystemPrompt(request, &relaycommon.RelayInfo{SafetyLevel: moderation_setting.SafetyLevelRelaxed})
+	require.Equal(t, "you are helpful", request.GetStringSystem())
+}
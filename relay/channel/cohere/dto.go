@@ -32,11 +32,13 @@ type CohereResponseResult struct {
 }
 
 type CohereRerankRequest struct {
-	Documents       []any  `json:"documents"`
-	Query           string `json:"query"`
-	Model           string `json:"model"`
-	TopN            int    `json:"top_n"`
-	ReturnDocuments bool   `json:"return_documents"`
+	Documents       []any    `json:"documents"`
+	Query           string   `json:"query"`
+	Model           string   `json:"model"`
+	TopN            *int     `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+	RankFields      []string `json:"rank_fields,omitempty"`
+	MaxChunksPerDoc *int     `json:"max_chunks_per_doc,omitempty"`
 }
 
 type CohereRerankResponseResult struct {
@@ -52,6 +54,7 @@ type CohereMeta struct {
 type CohereBilledUnits struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
+	SearchUnits  int `json:"search_units"`
 }
 
 type CohereTokens struct {
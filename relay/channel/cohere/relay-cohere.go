@@ -55,17 +55,24 @@ func requestOpenAI2Cohere(textRequest dto.GeneralOpenAIRequest) *CohereRequest {
 	return &cohereReq
 }
 
+// requestConvertRerank2Cohere 转换为 Cohere 格式：未指定 top_n 时返回全部文档，Jina 的 max_chunk_per_doc 对应 max_chunks_per_doc
 func requestConvertRerank2Cohere(rerankRequest dto.RerankRequest) *CohereRerankRequest {
-	topN := lo.FromPtrOr(rerankRequest.TopN, 1)
-	if topN <= 0 {
-		topN = 1
+	var topN *int
+	if rerankRequest.TopN != nil && *rerankRequest.TopN > 0 {
+		topN = rerankRequest.TopN
+	}
+	maxChunksPerDoc := rerankRequest.MaxChunksPerDoc
+	if maxChunksPerDoc == nil {
+		maxChunksPerDoc = rerankRequest.MaxChunkPerDoc
 	}
 	cohereReq := CohereRerankRequest{
 		Query:           rerankRequest.Query,
 		Documents:       rerankRequest.Documents,
 		Model:           rerankRequest.Model,
 		TopN:            topN,
-		ReturnDocuments: true,
+		ReturnDocuments: lo.FromPtrOr(rerankRequest.ReturnDocuments, true),
+		RankFields:      rerankRequest.RankFields,
+		MaxChunksPerDoc: maxChunksPerDoc,
 	}
 	return &cohereReq
 }
@@ -235,6 +242,10 @@ func cohereRerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 		usage.CompletionTokens = cohereResp.Meta.BilledUnits.OutputTokens
 		usage.TotalTokens = cohereResp.Meta.BilledUnits.InputTokens + cohereResp.Meta.BilledUnits.OutputTokens
 	}
+	// 按次计费的模型以 Cohere 返回的搜索单位数计费
+	if info.PriceData.UsePrice && cohereResp.Meta.BilledUnits.SearchUnits > 0 {
+		info.PriceData.AddOtherRatio(relaycommon.RerankSearchUnitsRatio, float64(cohereResp.Meta.BilledUnits.SearchUnits))
+	}
 
 	var rerankResp dto.RerankResponse
 	rerankResp.Results = cohereResp.Results
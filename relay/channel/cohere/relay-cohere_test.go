@@ -0,0 +1,57 @@
+package cohere
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestConvertRerank2Cohere(t *testing.T) {
+	request := dto.RerankRequest{
+		Model:          "rerank-english-v3.0",
+		Query:          "capital",
+		Documents:      []any{"Paris", map[string]any{"title": "Berlin", "text": "Germany"}},
+		MaxChunkPerDoc: lo.ToPtr(5),
+		RankFields:     []string{"title", "text"},
+	}
+	converted := requestConvertRerank2Cohere(request)
+	// 未指定 top_n 时返回全部结果
+	require.Nil(t, converted.TopN)
+	require.True(t, converted.ReturnDocuments)
+	require.Equal(t, 5, *converted.MaxChunksPerDoc)
+	require.Equal(t, []string{"title", "text"}, converted.RankFields)
+
+	request.TopN = lo.ToPtr(1)
+	request.ReturnDocuments = lo.ToPtr(false)
+	converted = requestConvertRerank2Cohere(request)
+	require.Equal(t, 1, *converted.TopN)
+	require.False(t, converted.ReturnDocuments)
+}
+
+func TestCohereRerankHandlerBillsSearchUnits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rerank", nil)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"results":[{"index":1,"relevance_score":0.9}],"meta":{"billed_units":{"search_units":2}}}`)),
+	}
+	info := &relaycommon.RelayInfo{}
+	info.PriceData.UsePrice = true
+	info.SetEstimatePromptTokens(12)
+
+	usage, err := cohereRerankHandler(c, resp, info)
+	require.Nil(t, err)
+	require.Equal(t, 12, usage.TotalTokens)
+	require.Equal(t, float64(2), info.PriceData.OtherRatios[relaycommon.RerankSearchUnitsRatio])
+	require.Contains(t, recorder.Body.String(), `"relevance_score":0.9`)
+}
@@ -13,6 +13,7 @@ import (
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/setting/reasoning"
 	"github.com/QuantumNous/new-api/types"
 
@@ -40,6 +41,17 @@ func (a *Adaptor) ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayIn
 			}
 		}
 	}
+	// 安全等级由管理员设定，覆盖客户端自带的 safetySettings
+	if threshold := moderation_setting.GetGeminiSafetyThreshold(info.SafetyLevel); threshold != "" {
+		safetySettings := make([]dto.GeminiChatSafetySettings, 0, len(SafetySettingList))
+		for _, category := range SafetySettingList {
+			safetySettings = append(safetySettings, dto.GeminiChatSafetySettings{
+				Category:  category,
+				Threshold: threshold,
+			})
+		}
+		request.SafetySettings = safetySettings
+	}
 	return request, nil
 }
 
@@ -21,6 +21,7 @@ import (
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/setting/reasoning"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
@@ -356,11 +357,16 @@ func CovertOpenAI2Gemini(c *gin.Context, textRequest dto.GeneralOpenAIRequest, i
 		ThinkingAdaptor(&geminiRequest, info, textRequest)
 	}
 
+	safetyThreshold := moderation_setting.GetGeminiSafetyThreshold(info.SafetyLevel)
 	safetySettings := make([]dto.GeminiChatSafetySettings, 0, len(SafetySettingList))
 	for _, category := range SafetySettingList {
+		threshold := model_setting.GetGeminiSafetySetting(category)
+		if safetyThreshold != "" {
+			threshold = safetyThreshold
+		}
 		safetySettings = append(safetySettings, dto.GeminiChatSafetySettings{
 			Category:  category,
-			Threshold: model_setting.GetGeminiSafetySetting(category),
+			Threshold: threshold,
 		})
 	}
 	geminiRequest.SafetySettings = safetySettings
@@ -1422,7 +1428,7 @@ func GeminiChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.R
 		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
 	}
 	service.CloseResponseBodyGracefully(resp)
-	if common.DebugEnabled {
+	if common.DebugEnabled && !common.IsNoStore(c) {
 		println(string(responseBody))
 	}
 	var geminiResponse dto.GeminiChatResponse
@@ -0,0 +1,148 @@
+package huggingface
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	"github.com/QuantumNous/new-api/relay/channel/openai"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// warmupMaxWait 等待 Inference Endpoint 从缩容状态启动的最长时间，超过后把 503 交给上层重试其他渠道
+const warmupMaxWait = 30 * time.Second
+
+type Adaptor struct {
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+// useGenerate 对话请求是否改走 TGI 原生 /generate 接口
+func useGenerate(info *relaycommon.RelayInfo) bool {
+	return info.RelayMode == relayconstant.RelayModeChatCompletions && info.ChannelOtherSettings.HuggingFaceUseGenerate
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	if useGenerate(info) {
+		if info.IsStream {
+			return fmt.Sprintf("%s/generate_stream", info.ChannelBaseUrl), nil
+		}
+		return fmt.Sprintf("%s/generate", info.ChannelBaseUrl), nil
+	}
+	switch info.RelayMode {
+	case relayconstant.RelayModeChatCompletions:
+		return fmt.Sprintf("%s/v1/chat/completions", info.ChannelBaseUrl), nil
+	case relayconstant.RelayModeCompletions:
+		return fmt.Sprintf("%s/v1/completions", info.ChannelBaseUrl), nil
+	}
+	return "", fmt.Errorf("unsupported relay mode: %d", info.RelayMode)
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
+	channel.SetupApiRequestHeader(info, c, req)
+	req.Set("Authorization", "Bearer "+info.ApiKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	if useGenerate(info) {
+		return requestOpenAI2TGI(request, info.IsStream), nil
+	}
+	return request, nil
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+// DoRequest Inference Endpoint 缩容到零后首次请求会返回 503，按 Retry-After 或 estimated_time 等待后重发，直到超过 warmupMaxWait
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	body, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("read request body failed: %w", err)
+	}
+	deadline := time.Now().Add(warmupMaxWait)
+	for {
+		resp, err := channel.DoApiRequest(a, c, info, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		service.CloseResponseBodyGracefully(resp)
+		if err != nil {
+			return nil, fmt.Errorf("read response body failed: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		delay, warmingUp := warmupDelay(resp.Header, respBody)
+		if !warmingUp || time.Now().Add(delay).After(deadline) {
+			return resp, nil
+		}
+		logger.LogInfo(c, fmt.Sprintf("huggingface endpoint is warming up, retrying in %s", delay))
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	if useGenerate(info) {
+		if info.IsStream {
+			return tgiGenerateStreamHandler(c, info, resp)
+		}
+		return tgiGenerateHandler(c, info, resp)
+	}
+	adaptor := openai.Adaptor{}
+	return adaptor.DoResponse(c, resp, info)
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
@@ -0,0 +1,8 @@
+package huggingface
+
+// ModelList Inference Endpoints 部署的模型由渠道自行配置，TGI 的 Messages API 接受任意模型名（通常为 tgi）
+var ModelList = []string{
+	"tgi",
+}
+
+var ChannelName = "huggingface"
@@ -0,0 +1,51 @@
+package huggingface
+
+// TGIGenerateRequest TGI /generate 与 /generate_stream 接口的请求体
+type TGIGenerateRequest struct {
+	Inputs     string         `json:"inputs"`
+	Parameters *TGIParameters `json:"parameters,omitempty"`
+	Stream     bool           `json:"stream,omitempty"`
+}
+
+type TGIParameters struct {
+	MaxNewTokens      *int     `json:"max_new_tokens,omitempty"`
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	TopK              *int     `json:"top_k,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	Seed              *int64   `json:"seed,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
+	DoSample          *bool    `json:"do_sample,omitempty"`
+	Details           bool     `json:"details"`
+}
+
+type TGIDetails struct {
+	FinishReason    string `json:"finish_reason"`
+	GeneratedTokens int    `json:"generated_tokens"`
+}
+
+type TGIGenerateResponse struct {
+	GeneratedText string      `json:"generated_text"`
+	Details       *TGIDetails `json:"details,omitempty"`
+}
+
+type TGIToken struct {
+	Id      int     `json:"id"`
+	Text    string  `json:"text"`
+	Logprob float64 `json:"logprob"`
+	Special bool    `json:"special"`
+}
+
+// TGIStreamResponse /generate_stream 的 SSE 事件，最后一个事件带有 generated_text 与 details
+type TGIStreamResponse struct {
+	Token         TGIToken    `json:"token"`
+	GeneratedText *string     `json:"generated_text,omitempty"`
+	Details       *TGIDetails `json:"details,omitempty"`
+}
+
+// TGIErrorResponse 模型加载中时 Inference Endpoints 返回 503 与预计加载时间
+type TGIErrorResponse struct {
+	Error         string  `json:"error"`
+	ErrorType     string  `json:"error_type,omitempty"`
+	EstimatedTime float64 `json:"estimated_time,omitempty"`
+}
@@ -0,0 +1,243 @@
+package huggingface
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	warmupDefaultDelay = 5 * time.Second
+	warmupMaxDelay     = 10 * time.Second
+)
+
+// requestOpenAI2TGI 转换 OpenAI 对话请求为 TGI generate 请求，消息按角色拼接为纯文本提示词
+func requestOpenAI2TGI(request *dto.GeneralOpenAIRequest, stream bool) *TGIGenerateRequest {
+	params := &TGIParameters{
+		TopK:    request.TopK,
+		Stop:    parseStop(request.Stop),
+		Details: true,
+	}
+	if maxTokens := request.GetMaxTokens(); maxTokens > 0 {
+		maxNewTokens := int(maxTokens)
+		params.MaxNewTokens = &maxNewTokens
+	}
+	// TGI 要求 temperature 大于 0、top_p 在 (0, 1) 之间，超出范围的值不透传
+	if request.Temperature != nil && *request.Temperature > 0 {
+		params.Temperature = request.Temperature
+		doSample := true
+		params.DoSample = &doSample
+	}
+	if request.TopP != nil && *request.TopP > 0 && *request.TopP < 1 {
+		params.TopP = request.TopP
+	}
+	if request.Seed != nil {
+		seed := int64(*request.Seed)
+		params.Seed = &seed
+	}
+	return &TGIGenerateRequest{
+		Inputs:     messagesToPrompt(request.Messages),
+		Parameters: params,
+		Stream:     stream,
+	}
+}
+
+func messagesToPrompt(messages []dto.Message) string {
+	var prompt strings.Builder
+	for _, msg := range messages {
+		text := msg.StringContent()
+		if text == "" {
+			continue
+		}
+		switch msg.Role {
+		case "system", "developer":
+			prompt.WriteString("System: ")
+		case "assistant":
+			prompt.WriteString("Assistant: ")
+		default:
+			prompt.WriteString("User: ")
+		}
+		prompt.WriteString(text)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString("Assistant:")
+	return prompt.String()
+}
+
+func parseStop(stop any) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []string:
+		return v
+	case []any:
+		var sequences []string
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				sequences = append(sequences, str)
+			}
+		}
+		return sequences
+	}
+	return nil
+}
+
+// warmupDelay 判断 503 响应是否表示模型正在加载，返回重试前的等待时间：
+// 优先使用 Retry-After，其次使用响应体中的 estimated_time，单次等待不超过 warmupMaxDelay
+func warmupDelay(header http.Header, body []byte) (time.Duration, bool) {
+	var errResp TGIErrorResponse
+	_ = common.Unmarshal(body, &errResp)
+	retryAfter := header.Get("Retry-After")
+	loading := strings.Contains(strings.ToLower(errResp.Error), "loading") || errResp.EstimatedTime > 0
+	if retryAfter == "" && !loading {
+		return 0, false
+	}
+
+	delay := warmupDefaultDelay
+	if seconds, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && seconds > 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if errResp.EstimatedTime > 0 {
+		delay = time.Duration(errResp.EstimatedTime * float64(time.Second))
+	}
+	if delay < time.Second {
+		delay = time.Second
+	}
+	if delay > warmupMaxDelay {
+		delay = warmupMaxDelay
+	}
+	return delay, true
+}
+
+func finishReasonTGI2OpenAI(details *TGIDetails) string {
+	if details == nil {
+		return "stop"
+	}
+	switch details.FinishReason {
+	case "length":
+		return "length"
+	default:
+		// eos_token、stop_sequence
+		return "stop"
+	}
+}
+
+// tgiUsage 优先使用 TGI 返回的 x-prompt-tokens 头与 details.generated_tokens，缺失时按文本估算
+func tgiUsage(c *gin.Context, info *relaycommon.RelayInfo, header http.Header, text string, details *TGIDetails) *dto.Usage {
+	usage := service.ResponseText2Usage(c, text, info.UpstreamModelName, info.GetEstimatePromptTokens())
+	if promptTokens, err := strconv.Atoi(header.Get("x-prompt-tokens")); err == nil && promptTokens > 0 {
+		usage.PromptTokens = promptTokens
+	}
+	if details != nil && details.GeneratedTokens > 0 {
+		usage.CompletionTokens = details.GeneratedTokens
+	} else if generatedTokens, err := strconv.Atoi(header.Get("x-generated-tokens")); err == nil && generatedTokens > 0 {
+		usage.CompletionTokens = generatedTokens
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
+func tgiGenerateHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var generateResp TGIGenerateResponse
+	// Serverless Inference API 以数组形式返回生成结果
+	if trimmed := bytes.TrimSpace(responseBody); len(trimmed) > 0 && trimmed[0] == '[' {
+		var results []TGIGenerateResponse
+		if err := common.Unmarshal(trimmed, &results); err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		if len(results) > 0 {
+			generateResp = results[0]
+		}
+	} else if err := common.Unmarshal(trimmed, &generateResp); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	usage := tgiUsage(c, info, resp.Header, generateResp.GeneratedText, generateResp.Details)
+	response := dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Model:   info.UpstreamModelName,
+		Choices: []dto.OpenAITextResponseChoice{{
+			Index: 0,
+			Message: dto.Message{
+				Role:    "assistant",
+				Content: generateResp.GeneratedText,
+			},
+			FinishReason: finishReasonTGI2OpenAI(generateResp.Details),
+		}},
+		Usage: *usage,
+	}
+	c.JSON(http.StatusOK, response)
+	return usage, nil
+}
+
+func tgiGenerateStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	id := helper.GetResponseID(c)
+	created := common.GetTimestamp()
+	var responseText strings.Builder
+	var details *TGIDetails
+
+	helper.StreamScannerHandler(c, resp, info, func(data string, sr *helper.StreamResult) {
+		var streamResp TGIStreamResponse
+		if err := common.UnmarshalJsonStr(data, &streamResp); err != nil {
+			logger.LogError(c, "error_unmarshalling_stream_response: "+err.Error())
+			sr.Error(err)
+			return
+		}
+		if !streamResp.Token.Special && streamResp.Token.Text != "" {
+			chunk := dto.ChatCompletionsStreamResponse{
+				Id:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   info.UpstreamModelName,
+				Choices: []dto.ChatCompletionsStreamResponseChoice{{}},
+			}
+			chunk.Choices[0].Delta.SetContentString(streamResp.Token.Text)
+			responseText.WriteString(streamResp.Token.Text)
+			if err := helper.ObjectData(c, chunk); err != nil {
+				sr.Error(err)
+			}
+		}
+		// 最后一个事件携带 details，生成结束
+		if streamResp.Details != nil {
+			details = streamResp.Details
+			stop := helper.GenerateStopResponse(id, created, info.UpstreamModelName, finishReasonTGI2OpenAI(details))
+			if err := helper.ObjectData(c, stop); err != nil {
+				sr.Error(err)
+			}
+			sr.Done()
+		}
+	})
+	service.CloseResponseBodyGracefully(resp)
+
+	usage := tgiUsage(c, info, resp.Header, responseText.String(), details)
+	if info.ShouldIncludeUsage {
+		if err := helper.ObjectData(c, helper.GenerateFinalUsageResponse(id, created, info.UpstreamModelName, *usage)); err != nil {
+			logger.LogError(c, fmt.Sprintf("error_rendering_final_usage_response: %s", err.Error()))
+		}
+	}
+	helper.Done(c)
+	return usage, nil
+}
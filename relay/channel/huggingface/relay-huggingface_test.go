@@ -0,0 +1,60 @@
+package huggingface
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupDelay(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	delay, ok := warmupDelay(header, []byte(`{"error":"Service Unavailable"}`))
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, delay)
+
+	delay, ok = warmupDelay(http.Header{}, []byte(`{"error":"Model is currently loading","estimated_time":2.5}`))
+	require.True(t, ok)
+	require.Equal(t, 2500*time.Millisecond, delay)
+
+	// 单次等待不超过上限
+	delay, ok = warmupDelay(http.Header{}, []byte(`{"error":"Model is currently loading","estimated_time":120}`))
+	require.True(t, ok)
+	require.Equal(t, warmupMaxDelay, delay)
+
+	_, ok = warmupDelay(http.Header{}, []byte(`{"error":"overloaded"}`))
+	require.False(t, ok)
+}
+
+func TestRequestOpenAI2TGI(t *testing.T) {
+	maxTokens := uint(64)
+	temperature := 0.0
+	topP := 1.0
+	request := &dto.GeneralOpenAIRequest{
+		Messages: []dto.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "hi"},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+		TopP:        &topP,
+		Stop:        []any{"\n\nUser:"},
+	}
+	tgiRequest := requestOpenAI2TGI(request, true)
+	require.Equal(t, "System: be brief\n\nUser: hi\n\nAssistant:", tgiRequest.Inputs)
+	require.True(t, tgiRequest.Stream)
+	require.Equal(t, 64, *tgiRequest.Parameters.MaxNewTokens)
+	require.Nil(t, tgiRequest.Parameters.Temperature)
+	require.Nil(t, tgiRequest.Parameters.TopP)
+	require.Equal(t, []string{"\n\nUser:"}, tgiRequest.Parameters.Stop)
+	require.True(t, tgiRequest.Parameters.Details)
+}
+
+func TestFinishReasonTGI2OpenAI(t *testing.T) {
+	require.Equal(t, "length", finishReasonTGI2OpenAI(&TGIDetails{FinishReason: "length"}))
+	require.Equal(t, "stop", finishReasonTGI2OpenAI(&TGIDetails{FinishReason: "eos_token"}))
+	require.Equal(t, "stop", finishReasonTGI2OpenAI(nil))
+}
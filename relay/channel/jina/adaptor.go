@@ -73,6 +73,13 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 }
 
 func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	// Jina 不支持 Cohere 格式的字段，max_chunks_per_doc 对应 max_chunk_per_doc
+	if request.MaxChunkPerDoc == nil {
+		request.MaxChunkPerDoc = request.MaxChunksPerDoc
+	}
+	request.MaxChunksPerDoc = nil
+	request.MaxTokensPerDoc = nil
+	request.RankFields = nil
 	return request, nil
 }
 
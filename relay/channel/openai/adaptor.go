@@ -28,6 +28,7 @@ import (
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/setting/reasoning"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/samber/lo"
@@ -108,7 +109,11 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	}
 	switch info.ChannelType {
 	case constant.ChannelTypeAzure:
+		deployment, hasDeployment := info.ChannelOtherSettings.GetAzureDeployment(info.UpstreamModelName, info.OriginModelName)
 		apiVersion := info.ApiVersion
+		if hasDeployment && deployment.ApiVersion != "" {
+			apiVersion = deployment.ApiVersion
+		}
 		if apiVersion == "" {
 			apiVersion = constant.AzureDefaultAPIVersion
 		}
@@ -146,8 +151,11 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 		}
 
 		model_ := info.UpstreamModelName
-		// 2025年5月10日后创建的渠道不移除.
-		if info.ChannelCreateTime < constant.AzureNoRemoveDotTime {
+		if hasDeployment {
+			// 显式配置的部署名原样使用
+			model_ = deployment.Name
+		} else if info.ChannelCreateTime < constant.AzureNoRemoveDotTime {
+			// 2025年5月10日后创建的渠道不移除.
 			model_ = strings.Replace(model_, ".", "", -1)
 		}
 		// https://github.com/songquanpeng/one-api/issues/67
@@ -176,6 +184,10 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *
 	channel.SetupApiRequestHeader(info, c, header)
 	if info.ChannelType == constant.ChannelTypeAzure {
 		header.Set("api-key", info.ApiKey)
+		// Azure 支持按请求指定内容筛选配置
+		if policyId := moderation_setting.GetAzurePolicyId(info.SafetyLevel); policyId != "" {
+			header.Set("x-policy-id", policyId)
+		}
 		return nil
 	}
 	if info.ChannelType == constant.ChannelTypeOpenAI && "" != info.Organization {
@@ -226,6 +238,14 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *
 	return nil
 }
 
+// shouldDisableUpstreamStore 合规模式（no-store）下要求 OpenAI / Azure 不保存请求与响应
+func shouldDisableUpstreamStore(c *gin.Context, info *relaycommon.RelayInfo) bool {
+	if !common.IsNoStore(c) {
+		return false
+	}
+	return info.ChannelType == constant.ChannelTypeOpenAI || info.ChannelType == constant.ChannelTypeAzure
+}
+
 func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
 	if request == nil {
 		return nil, errors.New("request is nil")
@@ -233,6 +253,9 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if info.ChannelType != constant.ChannelTypeOpenAI && info.ChannelType != constant.ChannelTypeAzure {
 		request.StreamOptions = nil
 	}
+	if shouldDisableUpstreamStore(c, info) {
+		request.Store = json.RawMessage("false")
+	}
 	if info.ChannelType == constant.ChannelTypeOpenRouter {
 		if len(request.Usage) == 0 {
 			request.Usage = json.RawMessage(`{"include":true}`)
@@ -366,11 +389,6 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		}
 		return bytes.NewReader(jsonData), nil
 	} else {
-		var requestBody bytes.Buffer
-		writer := multipart.NewWriter(&requestBody)
-
-		writer.WriteField("model", request.Model)
-
 		formData, err2 := common.ParseMultipartFormReusable(c)
 		if err2 != nil {
 			return nil, fmt.Errorf("error parsing multipart form: %w", err2)
@@ -379,20 +397,10 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		// 打印类似 curl 命令格式的信息
 		logger.LogDebug(c.Request.Context(), fmt.Sprintf("--form 'model=\"%s\"'", request.Model))
 
-		// 遍历表单字段并打印输出
-		for key, values := range formData.Value {
-			if key == "model" {
-				continue
-			}
-			for _, value := range values {
-				writer.WriteField(key, value)
-				logger.LogDebug(c.Request.Context(), fmt.Sprintf("--form '%s=\"%s\"'", key, value))
-			}
-		}
-
 		// 从 formData 中获取文件
 		fileHeaders := formData.File["file"]
 		if len(fileHeaders) == 0 {
+			_ = formData.RemoveAll()
 			return nil, errors.New("file is required")
 		}
 
@@ -401,31 +409,52 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		logger.LogDebug(c.Request.Context(), fmt.Sprintf("--form 'file=@\"%s\"' (size: %d bytes, content-type: %s)",
 			fileHeader.Filename, fileHeader.Size, fileHeader.Header.Get("Content-Type")))
 
-		file, err := fileHeader.Open()
-		if err != nil {
-			return nil, fmt.Errorf("error opening audio file: %v", err)
-		}
-		defer file.Close()
+		// 文件内容在上游读取请求体时流式写入，不在内存中拼接完整的请求体
+		ctx := c.Request.Context()
+		requestBody, contentType := common.NewMultipartStream(ctx, func(writer *multipart.Writer) error {
+			defer formData.RemoveAll()
 
-		part, err := writer.CreateFormFile("file", fileHeader.Filename)
-		if err != nil {
-			return nil, errors.New("create form file failed")
-		}
-		if _, err := io.Copy(part, file); err != nil {
-			return nil, errors.New("copy file failed")
-		}
+			if err := writer.WriteField("model", request.Model); err != nil {
+				return err
+			}
+			// 遍历表单字段并打印输出
+			for key, values := range formData.Value {
+				if key == "model" {
+					continue
+				}
+				for _, value := range values {
+					if err := writer.WriteField(key, value); err != nil {
+						return err
+					}
+					logger.LogDebug(ctx, fmt.Sprintf("--form '%s=\"%s\"'", key, value))
+				}
+			}
 
-		// 关闭 multipart 编写器以设置分界线
-		writer.Close()
-		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
-		logger.LogDebug(c.Request.Context(), fmt.Sprintf("--header 'Content-Type: %s'", writer.FormDataContentType()))
-		return &requestBody, nil
+			file, err := fileHeader.Open()
+			if err != nil {
+				return fmt.Errorf("error opening audio file: %v", err)
+			}
+			defer file.Close()
+
+			part, err := writer.CreateFormFile("file", fileHeader.Filename)
+			if err != nil {
+				return errors.New("create form file failed")
+			}
+			if _, err := io.Copy(part, file); err != nil {
+				return errors.New("copy file failed")
+			}
+			return nil
+		})
+
+		c.Request.Header.Set("Content-Type", contentType)
+		logger.LogDebug(c.Request.Context(), fmt.Sprintf("--header 'Content-Type: %s'", contentType))
+		return requestBody, nil
 	}
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		if isJSONRequest(c) {
 			return request, nil
 		}
@@ -581,7 +610,15 @@ func detectImageMimeType(filename string) string {
 	}
 }
 
+// SupportsNativeResponses 上游原生支持 /v1/responses
+func (a *Adaptor) SupportsNativeResponses() bool {
+	return true
+}
+
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	if shouldDisableUpstreamStore(c, info) {
+		request.Store = json.RawMessage("false")
+	}
 	//  转换模型推理力度后缀
 	effort, originModel := reasoning.ParseOpenAIReasoningEffortFromModelSuffix(request.Model)
 	if effort != "" {
@@ -597,13 +634,19 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 	if info != nil && request.Reasoning != nil && request.Reasoning.Effort != "" {
 		info.ReasoningEffort = request.Reasoning.Effort
 	}
+	// Azure responses API 通过请求体中的 model 指定部署
+	if info != nil && info.ChannelMeta != nil && info.ChannelType == constant.ChannelTypeAzure {
+		if deployment, ok := info.ChannelOtherSettings.GetAzureDeployment(info.UpstreamModelName, info.OriginModelName); ok {
+			request.Model = deployment.Name
+		}
+	}
 	return request, nil
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
 	if info.RelayMode == relayconstant.RelayModeAudioTranscription ||
 		info.RelayMode == relayconstant.RelayModeAudioTranslation ||
-		(info.RelayMode == relayconstant.RelayModeImagesEdits && !isJSONRequest(c)) {
+		((info.RelayMode == relayconstant.RelayModeImagesEdits || info.RelayMode == relayconstant.RelayModeImagesVariations) && !isJSONRequest(c)) {
 		return channel.DoFormRequest(a, c, info, requestBody)
 	} else if info.RelayMode == relayconstant.RelayModeRealtime {
 		return channel.DoWssRequest(a, c, info, requestBody)
@@ -622,7 +665,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		fallthrough
 	case relayconstant.RelayModeAudioTranscription:
 		err, usage = OpenaiSTTHandler(c, resp, info, a.ResponseFormat)
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		usage, err = OpenaiHandlerWithUsage(c, info, resp)
 	case relayconstant.RelayModeRerank:
 		usage, err = common_handler.RerankHandler(c, info, resp)
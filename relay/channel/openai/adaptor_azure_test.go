@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newAzureRelayInfo(model string, deployments map[string]dto.AzureDeployment) *relaycommon.RelayInfo {
+	return &relaycommon.RelayInfo{
+		OriginModelName: model,
+		RelayMode:       relayconstant.RelayModeChatCompletions,
+		RequestURLPath:  "/v1/chat/completions",
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelType:          constant.ChannelTypeAzure,
+			ChannelBaseUrl:       "https://example.openai.azure.com",
+			ApiVersion:           "2024-10-21",
+			UpstreamModelName:    model,
+			ChannelCreateTime:    constant.AzureNoRemoveDotTime - 1,
+			ChannelOtherSettings: dto.ChannelOtherSettings{AzureDeployments: deployments},
+		},
+	}
+}
+
+func TestAzureRequestURLUsesDeploymentMapping(t *testing.T) {
+	adaptor := &Adaptor{}
+	deployments := map[string]dto.AzureDeployment{
+		"gpt-4.1":      {Name: "prod-gpt41", ApiVersion: "2025-04-01-preview"},
+		"gpt-4o-mini":  {Name: "mini.v2"},
+		"gpt-disabled": {},
+	}
+
+	url, err := adaptor.GetRequestURL(newAzureRelayInfo("gpt-4.1", deployments))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.openai.azure.com/openai/deployments/prod-gpt41/chat/completions?api-version=2025-04-01-preview", url)
+
+	// 显式配置的部署名不移除 .，未配置 api_version 时沿用渠道版本
+	url, err = adaptor.GetRequestURL(newAzureRelayInfo("gpt-4o-mini", deployments))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.openai.azure.com/openai/deployments/mini.v2/chat/completions?api-version=2024-10-21", url)
+
+	// 未配置或部署名为空时按模型名生成部署名
+	url, err = adaptor.GetRequestURL(newAzureRelayInfo("gpt-3.5-turbo", deployments))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.openai.azure.com/openai/deployments/gpt-35-turbo/chat/completions?api-version=2024-10-21", url)
+	url, err = adaptor.GetRequestURL(newAzureRelayInfo("gpt-disabled", deployments))
+	require.NoError(t, err)
+	require.Contains(t, url, "/openai/deployments/gpt-disabled/")
+}
+
+func TestAzureResponsesRequestUsesDeploymentName(t *testing.T) {
+	info := newAzureRelayInfo("gpt-4.1", map[string]dto.AzureDeployment{"gpt-4.1": {Name: "prod-gpt41"}})
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	converted, err := (&Adaptor{}).ConvertOpenAIResponsesRequest(c, info, dto.OpenAIResponsesRequest{Model: "gpt-4.1"})
+	require.NoError(t, err)
+	require.Equal(t, "prod-gpt41", converted.(dto.OpenAIResponsesRequest).Model)
+}
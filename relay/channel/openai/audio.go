@@ -6,6 +6,7 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"strings"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -14,6 +15,7 @@ import (
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/gin-gonic/gin"
 )
@@ -56,20 +58,19 @@ func OpenaiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 		})
 	} else {
 		common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
-		// 读取响应体到缓冲区
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			logger.LogError(c, fmt.Sprintf("failed to read TTS response body: %v", err))
-			c.Writer.WriteHeaderNow()
-			return usage
+		// 按字符计费的模型无需计算音频时长，不再缓存音频内容
+		_, byCharacter := operation_setting.GetAudioSetting().SpeechPricePerCharacter(info.OriginModelName)
+		var audioBuffer *bytes.Buffer
+		if !byCharacter {
+			audioBuffer = &bytes.Buffer{}
 		}
-
-		// 写入响应到客户端
-		c.Writer.WriteHeaderNow()
-		_, err = c.Writer.Write(bodyBytes)
-		if err != nil {
-			logger.LogError(c, fmt.Sprintf("failed to write TTS response: %v", err))
+		if err := streamTTSAudio(c, resp.Body, audioBuffer); err != nil {
+			logger.LogError(c, fmt.Sprintf("failed to stream TTS response: %v", err))
+		}
+		if byCharacter {
+			return usage
 		}
+		bodyBytes := audioBuffer.Bytes()
 
 		// 计算音频时长并更新 usage
 		audioFormat := "mp3" // 默认格式
@@ -114,7 +115,85 @@ func OpenaiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	return usage
 }
 
+// streamTTSAudio 将上游返回的音频边读边写给客户端（分块传输），audio 不为空时同时保留一份用于计算时长；
+// 客户端断开后停止转发
+func streamTTSAudio(c *gin.Context, body io.Reader, audio *bytes.Buffer) error {
+	c.Writer.WriteHeaderNow()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if audio != nil {
+				audio.Write(buf[:n])
+			}
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			c.Writer.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sttUsage 转写接口返回的用量：whisper 类模型按时长（type=duration），gpt-4o-transcribe 类模型按 token（type=tokens）
+type sttUsage struct {
+	Type              string  `json:"type"`
+	Seconds           float64 `json:"seconds"`
+	InputTokens       int     `json:"input_tokens"`
+	OutputTokens      int     `json:"output_tokens"`
+	TotalTokens       int     `json:"total_tokens"`
+	InputTokenDetails struct {
+		TextTokens  int `json:"text_tokens"`
+		AudioTokens int `json:"audio_tokens"`
+	} `json:"input_token_details"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// toUsage 转换为计费用量，按时长计费时每分钟折合 1000 token，与本地预估保持一致
+func (u *sttUsage) toUsage() *dto.Usage {
+	if u.Type == "duration" {
+		if u.Seconds <= 0 {
+			return nil
+		}
+		tokens := int(math.Round(math.Ceil(u.Seconds) / 60.0 * 1000))
+		return &dto.Usage{PromptTokens: tokens, TotalTokens: tokens}
+	}
+	if u.TotalTokens <= 0 {
+		return nil
+	}
+	usage := &dto.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = u.InputTokens
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = u.OutputTokens
+	}
+	usage.PromptTokensDetails.TextTokens = u.InputTokenDetails.TextTokens
+	usage.PromptTokensDetails.AudioTokens = u.InputTokenDetails.AudioTokens
+	return usage
+}
+
+func estimatedSTTUsage(info *relaycommon.RelayInfo) *dto.Usage {
+	usage := &dto.Usage{}
+	usage.PromptTokens = info.GetEstimatePromptTokens()
+	usage.TotalTokens = usage.PromptTokens
+	return usage
+}
+
 func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, responseFormat string) (*types.NewAPIError, *dto.Usage) {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return openaiSTTStreamHandler(c, resp, info)
+	}
 	defer service.CloseResponseBodyGracefully(resp)
 
 	responseBody, err := io.ReadAll(resp.Body)
@@ -125,24 +204,38 @@ func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	service.IOCopyBytesGracefully(c, resp, responseBody)
 
 	var responseData struct {
-		Usage *dto.Usage `json:"usage"`
+		Usage *sttUsage `json:"usage"`
 	}
 	if err := common.Unmarshal(responseBody, &responseData); err == nil && responseData.Usage != nil {
-		if responseData.Usage.TotalTokens > 0 {
-			usage := responseData.Usage
-			if usage.PromptTokens == 0 {
-				usage.PromptTokens = usage.InputTokens
-			}
-			if usage.CompletionTokens == 0 {
-				usage.CompletionTokens = usage.OutputTokens
-			}
+		if usage := responseData.Usage.toUsage(); usage != nil {
 			return nil, usage
 		}
 	}
+	return nil, estimatedSTTUsage(info)
+}
 
-	usage := &dto.Usage{}
-	usage.PromptTokens = info.GetEstimatePromptTokens()
-	usage.CompletionTokens = 0
-	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+// openaiSTTStreamHandler 转发 stream=true 时上游返回的 transcript.text.delta 事件，
+// 用量取自 transcript.text.done 事件
+func openaiSTTStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.Usage) {
+	info.IsStream = true
+	var usage *dto.Usage
+	helper.StreamScannerHandler(c, resp, info, func(data string, sr *helper.StreamResult) {
+		if service.SundaySearch(data, "usage") {
+			var event struct {
+				Usage *sttUsage `json:"usage"`
+			}
+			if err := common.UnmarshalJsonStr(data, &event); err == nil && event.Usage != nil {
+				if eventUsage := event.Usage.toUsage(); eventUsage != nil {
+					usage = eventUsage
+				}
+			}
+		}
+		if err := helper.StringData(c, data); err != nil {
+			sr.Error(err)
+		}
+	})
+	if usage == nil {
+		usage = estimatedSTTUsage(info)
+	}
 	return nil, usage
 }
@@ -2,22 +2,22 @@ package vertex
 
 import (
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/pem"
-	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/QuantumNous/new-api/common"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/service"
 
-	"github.com/bytedance/gopkg/cache/asynccache"
 	"github.com/golang-jwt/jwt/v5"
-
-	"fmt"
-	"time"
+	"golang.org/x/sync/singleflight"
 )
 
 type Credentials struct {
@@ -28,39 +28,62 @@ type Credentials struct {
 	ClientID     string `json:"client_id"`
 }
 
-var Cache = asynccache.NewAsyncCache(asynccache.Options{
-	RefreshDuration: time.Minute * 35,
-	EnableExpire:    true,
-	ExpireDuration:  time.Minute * 30,
-	Fetcher: func(key string) (interface{}, error) {
-		return nil, errors.New("not found")
-	},
-})
+// accessTokenRefreshMargin 令牌过期前提前刷新的时间，避免请求途中令牌失效
+const accessTokenRefreshMargin = 5 * time.Minute
+
+type cachedAccessToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	accessTokenMu    sync.RWMutex
+	accessTokenCache = make(map[string]cachedAccessToken)
+	// accessTokenGroup 合并同一服务账号的并发刷新
+	accessTokenGroup singleflight.Group
+)
+
+// accessTokenCacheKey 按服务账号与私钥生成缓存键，渠道更换密钥后不会复用旧令牌
+func accessTokenCacheKey(creds Credentials) string {
+	sum := sha256.Sum256([]byte(creds.PrivateKey))
+	return fmt.Sprintf("%s|%s|%x", creds.ClientEmail, creds.PrivateKeyID, sum[:8])
+}
 
 func getAccessToken(a *Adaptor, info *relaycommon.RelayInfo) (string, error) {
-	var cacheKey string
-	if info.ChannelIsMultiKey {
-		cacheKey = fmt.Sprintf("access-token-%d-%d", info.ChannelId, info.ChannelMultiKeyIndex)
-	} else {
-		cacheKey = fmt.Sprintf("access-token-%d", info.ChannelId)
-	}
-	val, err := Cache.Get(cacheKey)
-	if err == nil {
-		return val.(string), nil
-	}
+	return getCachedAccessToken(a.AccountCredentials, info.ChannelSetting.Proxy)
+}
 
-	signedJWT, err := createSignedJWT(a.AccountCredentials.ClientEmail, a.AccountCredentials.PrivateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to create signed JWT: %w", err)
+// getCachedAccessToken 返回缓存的访问令牌，临近过期时重新签发；刷新失败但旧令牌未过期时继续使用旧令牌
+func getCachedAccessToken(creds Credentials, proxy string) (string, error) {
+	key := accessTokenCacheKey(creds)
+	accessTokenMu.RLock()
+	cached, found := accessTokenCache[key]
+	accessTokenMu.RUnlock()
+	if found && time.Until(cached.expiresAt) > accessTokenRefreshMargin {
+		return cached.token, nil
 	}
-	newToken, err := exchangeJwtForAccessToken(signedJWT, info)
+
+	value, err, _ := accessTokenGroup.Do(key, func() (any, error) {
+		signedJWT, err := createSignedJWT(creds.ClientEmail, creds.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signed JWT: %w", err)
+		}
+		token, expiresIn, err := exchangeJwtForAccessToken(signedJWT, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exchange JWT for access token: %w", err)
+		}
+		accessTokenMu.Lock()
+		accessTokenCache[key] = cachedAccessToken{token: token, expiresAt: time.Now().Add(expiresIn)}
+		accessTokenMu.Unlock()
+		return token, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to exchange JWT for access token: %w", err)
-	}
-	if err := Cache.SetDefault(cacheKey, newToken); err {
-		return newToken, nil
+		if found && time.Now().Before(cached.expiresAt) {
+			return cached.token, nil
+		}
+		return "", err
 	}
-	return newToken, nil
+	return value.(string), nil
 }
 
 func createSignedJWT(email, privateKeyPEM string) (string, error) {
@@ -104,8 +127,8 @@ func createSignedJWT(email, privateKeyPEM string) (string, error) {
 	return signedToken, nil
 }
 
-func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (string, error) {
-
+// exchangeJwtForAccessToken 用签名的 JWT 换取访问令牌，返回令牌及其有效期
+func exchangeJwtForAccessToken(signedJWT string, proxy string) (string, time.Duration, error) {
 	authURL := "https://www.googleapis.com/oauth2/v4/token"
 	data := url.Values{}
 	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
@@ -113,10 +136,10 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 
 	var client *http.Client
 	var err error
-	if info.ChannelSetting.Proxy != "" {
-		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
+	if proxy != "" {
+		client, err = service.NewProxyHttpClient(proxy)
 		if err != nil {
-			return "", fmt.Errorf("new proxy http client failed: %w", err)
+			return "", 0, fmt.Errorf("new proxy http client failed: %w", err)
 		}
 	} else {
 		client = service.GetHttpClient()
@@ -124,60 +147,28 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 
 	resp, err := client.PostForm(authURL, data)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	defer resp.Body.Close()
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if err := common.DecodeJson(resp.Body, &result); err != nil {
+		return "", 0, err
 	}
 
-	if accessToken, ok := result["access_token"].(string); ok {
-		return accessToken, nil
+	accessToken, ok := result["access_token"].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("failed to get access token: %v", result)
 	}
-
-	return "", fmt.Errorf("failed to get access token: %v", result)
-}
-
-func AcquireAccessToken(creds Credentials, proxy string) (string, error) {
-	signedJWT, err := createSignedJWT(creds.ClientEmail, creds.PrivateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to create signed JWT: %w", err)
+	// Google 签发的令牌有效期为 1 小时，响应缺少 expires_in 时按此处理
+	expiresIn := time.Hour
+	if seconds, ok := result["expires_in"].(float64); ok && seconds > 0 {
+		expiresIn = time.Duration(seconds) * time.Second
 	}
-	return exchangeJwtForAccessTokenWithProxy(signedJWT, proxy)
+	return accessToken, expiresIn, nil
 }
 
-func exchangeJwtForAccessTokenWithProxy(signedJWT string, proxy string) (string, error) {
-	authURL := "https://www.googleapis.com/oauth2/v4/token"
-	data := url.Values{}
-	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
-	data.Set("assertion", signedJWT)
-
-	var client *http.Client
-	var err error
-	if proxy != "" {
-		client, err = service.NewProxyHttpClient(proxy)
-		if err != nil {
-			return "", fmt.Errorf("new proxy http client failed: %w", err)
-		}
-	} else {
-		client = service.GetHttpClient()
-	}
-
-	resp, err := client.PostForm(authURL, data)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-
-	if accessToken, ok := result["access_token"].(string); ok {
-		return accessToken, nil
-	}
-	return "", fmt.Errorf("failed to get access token: %v", result)
+// AcquireAccessToken 获取服务账号的访问令牌，令牌在有效期内复用
+func AcquireAccessToken(creds Credentials, proxy string) (string, error) {
+	return getCachedAccessToken(creds, proxy)
 }
@@ -0,0 +1,48 @@
+package vertex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCachedAccessTokenReusesValidToken(t *testing.T) {
+	creds := Credentials{ClientEmail: "svc@example.iam.gserviceaccount.com", PrivateKeyID: "kid", PrivateKey: "not-a-key"}
+	key := accessTokenCacheKey(creds)
+	accessTokenMu.Lock()
+	accessTokenCache[key] = cachedAccessToken{token: "cached-token", expiresAt: time.Now().Add(30 * time.Minute)}
+	accessTokenMu.Unlock()
+	t.Cleanup(func() {
+		accessTokenMu.Lock()
+		delete(accessTokenCache, key)
+		accessTokenMu.Unlock()
+	})
+
+	token, err := getCachedAccessToken(creds, "")
+	require.NoError(t, err)
+	require.Equal(t, "cached-token", token)
+
+	// 临近过期时重新签发，私钥无效导致刷新失败，仍使用未过期的旧令牌
+	accessTokenMu.Lock()
+	accessTokenCache[key] = cachedAccessToken{token: "expiring-token", expiresAt: time.Now().Add(time.Minute)}
+	accessTokenMu.Unlock()
+	token, err = getCachedAccessToken(creds, "")
+	require.NoError(t, err)
+	require.Equal(t, "expiring-token", token)
+
+	// 旧令牌已过期时返回刷新错误
+	accessTokenMu.Lock()
+	accessTokenCache[key] = cachedAccessToken{token: "expired-token", expiresAt: time.Now().Add(-time.Minute)}
+	accessTokenMu.Unlock()
+	_, err = getCachedAccessToken(creds, "")
+	require.Error(t, err)
+}
+
+func TestAccessTokenCacheKeyChangesWithPrivateKey(t *testing.T) {
+	creds := Credentials{ClientEmail: "svc@example.iam.gserviceaccount.com", PrivateKeyID: "kid", PrivateKey: "key-a"}
+	rotated := creds
+	rotated.PrivateKey = "key-b"
+	require.NotEqual(t, accessTokenCacheKey(creds), accessTokenCacheKey(rotated))
+	require.Equal(t, accessTokenCacheKey(creds), accessTokenCacheKey(creds))
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchRequestInfo 离线批量推理在进程内发起的中继请求标记，计费时按折扣计算
+type BatchRequestInfo struct {
+	BatchId       string
+	DiscountRatio float64
+}
+
+type batchRequestKey struct{}
+
+// WithBatchRequest 标记请求来自离线批量推理。标记只能通过请求的 context 传递，外部请求无法伪造
+func WithBatchRequest(ctx context.Context, info BatchRequestInfo) context.Context {
+	return context.WithValue(ctx, batchRequestKey{}, info)
+}
+
+// GetBatchRequest 获取请求的离线批量推理标记
+func GetBatchRequest(c *gin.Context) (BatchRequestInfo, bool) {
+	if c == nil || c.Request == nil {
+		return BatchRequestInfo{}, false
+	}
+	info, ok := c.Request.Context().Value(batchRequestKey{}).(BatchRequestInfo)
+	return info, ok
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/QuantumNous/new-api/pkg/billingexpr"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -49,6 +50,21 @@ type RerankerInfo struct {
 	ReturnDocuments bool
 }
 
+const (
+	// RerankSearchUnitsRatio 重排序按次计费时的搜索单位倍率名称
+	RerankSearchUnitsRatio = "search_units"
+	// rerankDocumentsPerSearchUnit 每个搜索单位包含的文档数，与 Cohere 计费规则一致
+	rerankDocumentsPerSearchUnit = 100
+)
+
+// EstimateRerankSearchUnits 上游未返回搜索单位时按文档数估算：每 100 个文档计 1 个搜索单位
+func EstimateRerankSearchUnits(documents int) int {
+	if documents <= rerankDocumentsPerSearchUnit {
+		return 1
+	}
+	return (documents + rerankDocumentsPerSearchUnit - 1) / rerankDocumentsPerSearchUnit
+}
+
 type BuildInToolInfo struct {
 	ToolName          string
 	CallCount         int
@@ -115,12 +131,16 @@ type RelayInfo struct {
 	AudioUsage             bool
 	ReasoningEffort        string
 	UserSetting            dto.UserSetting
-	UserEmail              string
-	UserQuota              int
-	RelayFormat            types.RelayFormat
-	SendResponseCount      int
-	ReceivedResponseCount  int
-	FinalPreConsumedQuota  int // 最终预消耗的配额
+	// SafetyLevel 生效的安全等级（用户 > 分组 > 默认），由各渠道映射为上游的安全参数
+	SafetyLevel string
+	// InjectedSystemPromptTokens 管理员注入的系统提示词 token 数，结算时不计入用户输入
+	InjectedSystemPromptTokens int
+	UserEmail                  string
+	UserQuota                  int
+	RelayFormat                types.RelayFormat
+	SendResponseCount          int
+	ReceivedResponseCount      int
+	FinalPreConsumedQuota      int // 最终预消耗的配额
 	// ForcePreConsume 为 true 时禁用 BillingSession 的信任额度旁路，
 	// 强制预扣全额。用于异步任务（视频/音乐生成等），因为请求返回后任务仍在运行，
 	// 必须在提交前锁定全额。
@@ -501,6 +521,7 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 	if ok {
 		info.UserSetting = userSetting
 	}
+	info.SafetyLevel = moderation_setting.ResolveSafetyLevel(info.UserSetting.SafetyLevel, info.UsingGroup)
 
 	return info
 }
@@ -658,6 +679,14 @@ func (info *RelayInfo) HasSendResponse() bool {
 	return info.FirstResponseTime.After(info.StartTime)
 }
 
+// GetUpstreamTimeouts 返回当前请求的上游超时配置，模型级配置优先匹配上游模型名，其次是请求的原始模型名
+func (info *RelayInfo) GetUpstreamTimeouts() dto.UpstreamTimeouts {
+	if info.ChannelMeta == nil {
+		return dto.UpstreamTimeouts{}
+	}
+	return info.ChannelOtherSettings.GetUpstreamTimeouts(info.UpstreamModelName, info.OriginModelName)
+}
+
 type TaskRelayInfo struct {
 	Action       string
 	OriginTaskID string
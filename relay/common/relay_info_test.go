@@ -38,3 +38,10 @@ func TestRelayInfoGetFinalRequestRelayFormatNilReceiver(t *testing.T) {
 	var info *RelayInfo
 	require.Equal(t, types.RelayFormat(""), info.GetFinalRequestRelayFormat())
 }
+
+func TestEstimateRerankSearchUnits(t *testing.T) {
+	require.Equal(t, 1, EstimateRerankSearchUnits(0))
+	require.Equal(t, 1, EstimateRerankSearchUnits(100))
+	require.Equal(t, 2, EstimateRerankSearchUnits(101))
+	require.Equal(t, 3, EstimateRerankSearchUnits(250))
+}
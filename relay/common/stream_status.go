@@ -36,6 +36,8 @@ type StreamStatus struct {
 	mu         sync.Mutex
 	Errors     []StreamErrorEntry
 	ErrorCount int
+	// responseText 流中已收到的输出文本，上游未返回 usage 时用于估算输出 token
+	responseText strings.Builder
 }
 
 func NewStreamStatus() *StreamStatus {
@@ -67,6 +69,26 @@ func (s *StreamStatus) RecordError(msg string) {
 	}
 }
 
+// AppendResponseText 累积流中收到的输出文本
+func (s *StreamStatus) AppendResponseText(text string) {
+	if s == nil || text == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responseText.WriteString(text)
+}
+
+// ResponseText 返回流中已收到的输出文本
+func (s *StreamStatus) ResponseText() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responseText.String()
+}
+
 func (s *StreamStatus) HasErrors() bool {
 	if s == nil {
 		return false
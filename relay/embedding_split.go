@@ -0,0 +1,180 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultEmbeddingMaxBatchSize 上游已知的单次 Embeddings 输入条数上限，渠道未配置时使用
+var defaultEmbeddingMaxBatchSize = map[int]int{
+	constant.APITypeGemini: 100,
+	constant.APITypeCohere: 96,
+}
+
+func embeddingMaxBatchSize(info *relaycommon.RelayInfo) int {
+	if size := info.ChannelOtherSettings.EmbeddingMaxBatchSize; size > 0 {
+		return size
+	}
+	return defaultEmbeddingMaxBatchSize[info.ApiType]
+}
+
+// embeddingBufferWriter 缓存适配器写出的单批响应，由调用方合并后统一返回
+type embeddingBufferWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *embeddingBufferWriter) WriteHeader(int) {}
+
+func (w *embeddingBufferWriter) WriteHeaderNow() {}
+
+func (w *embeddingBufferWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *embeddingBufferWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// splittableEmbeddingInputs 返回可按条拆分的输入列表：字符串数组或 token 数组的数组。
+// 单个 token 数组（如 [1,2,3]）是一条输入，不能拆分
+func splittableEmbeddingInputs(input any) ([]any, bool) {
+	inputs, ok := input.([]any)
+	if !ok || len(inputs) == 0 {
+		return nil, false
+	}
+	for _, item := range inputs {
+		switch item.(type) {
+		case string, []any:
+		default:
+			return nil, false
+		}
+	}
+	return inputs, true
+}
+
+// splitEmbeddingInputs 按批大小切分输入，保持原有顺序
+func splitEmbeddingInputs(inputs []any, batchSize int) [][]any {
+	batches := make([][]any, 0, (len(inputs)+batchSize-1)/batchSize)
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+		batches = append(batches, inputs[start:end])
+	}
+	return batches
+}
+
+// mergeEmbeddingResponse 将一批响应追加到合并结果中，index 按该批在原始输入中的偏移量修正；
+// 批内 index 必须覆盖该批的每条输入
+func mergeEmbeddingResponse(merged *dto.FlexibleEmbeddingResponse, batch *dto.FlexibleEmbeddingResponse, offset int, size int) error {
+	if len(batch.Data) != size {
+		return fmt.Errorf("embedding batch returned %d items for %d inputs", len(batch.Data), size)
+	}
+	seen := make([]bool, size)
+	for _, item := range batch.Data {
+		if item.Index < 0 || item.Index >= size || seen[item.Index] {
+			return fmt.Errorf("embedding batch returned invalid index %d", item.Index)
+		}
+		seen[item.Index] = true
+	}
+	if merged.Model == "" {
+		merged.Model = batch.Model
+	}
+	for _, item := range batch.Data {
+		item.Index += offset
+		merged.Data = append(merged.Data, item)
+	}
+	return nil
+}
+
+// sortEmbeddingData 按输入位置排序，上游不保证批内结果按 index 顺序返回
+func sortEmbeddingData(merged *dto.FlexibleEmbeddingResponse) {
+	sort.SliceStable(merged.Data, func(i, j int) bool {
+		return merged.Data[i].Index < merged.Data[j].Index
+	})
+}
+
+func addEmbeddingUsage(total *dto.Usage, usage *dto.Usage) {
+	if usage == nil {
+		return
+	}
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
+
+// embeddingBatchFunc 发送一批输入，返回该批响应与上游用量
+type embeddingBatchFunc func(batch []any) (*dto.FlexibleEmbeddingResponse, *dto.Usage, *types.NewAPIError)
+
+// runEmbeddingBatches 依次发送各批请求并合并结果。某一批失败时立即停止，不再发送后续批次；
+// 返回的用量只包含上游已处理的批次，completed 为成功返回结果的批次数
+func runEmbeddingBatches(batches [][]any, do embeddingBatchFunc) (merged *dto.FlexibleEmbeddingResponse, totalUsage *dto.Usage, completed int, newAPIError *types.NewAPIError) {
+	merged = &dto.FlexibleEmbeddingResponse{Object: "list"}
+	totalUsage = &dto.Usage{}
+	offset := 0
+	for _, batch := range batches {
+		batchResponse, usage, newAPIError := do(batch)
+		// 上游已处理的批次即使结果异常也计入用量
+		addEmbeddingUsage(totalUsage, usage)
+		if newAPIError != nil {
+			return merged, totalUsage, completed, newAPIError
+		}
+		completed++
+		if err := mergeEmbeddingResponse(merged, batchResponse, offset, len(batch)); err != nil {
+			return merged, totalUsage, completed, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		offset += len(batch)
+	}
+	sortEmbeddingData(merged)
+	merged.Usage = *totalUsage
+	return merged, totalUsage, completed, nil
+}
+
+// relayEmbeddingInBatches 输入条数超过上游单次上限时拆分为多次请求，按原顺序合并结果并累加用量计费。
+// 中途失败时已成功批次的用量照常计费，且不再重试其他渠道，避免重复发送
+func relayEmbeddingInBatches(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.EmbeddingRequest, inputs []any, batchSize int) *types.NewAPIError {
+	original := c.Writer
+	defer func() {
+		c.Writer = original
+	}()
+
+	merged, totalUsage, completed, newAPIError := runEmbeddingBatches(splitEmbeddingInputs(inputs, batchSize), func(batch []any) (*dto.FlexibleEmbeddingResponse, *dto.Usage, *types.NewAPIError) {
+		batchRequest := *request
+		batchRequest.Input = batch
+		writer := &embeddingBufferWriter{ResponseWriter: original}
+		c.Writer = writer
+		usage, newAPIError := doEmbeddingRequest(c, info, adaptor, &batchRequest)
+		if newAPIError != nil {
+			return nil, nil, newAPIError
+		}
+		var batchResponse dto.FlexibleEmbeddingResponse
+		if err := common.Unmarshal(writer.body.Bytes(), &batchResponse); err != nil {
+			return nil, usage, types.NewOpenAIError(fmt.Errorf("failed to parse embedding batch response: %w", err), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		return &batchResponse, usage, nil
+	})
+	c.Writer = original
+	if newAPIError != nil {
+		if completed > 0 || totalUsage.TotalTokens > 0 {
+			service.PostTextConsumeQuota(c, info, totalUsage, []string{fmt.Sprintf("分批请求失败，已完成 %d 批", completed)})
+			types.ErrOptionWithSkipRetry()(newAPIError)
+		}
+		return newAPIError
+	}
+
+	c.Writer.Header().Del("Content-Length")
+	c.JSON(http.StatusOK, merged)
+	service.PostTextConsumeQuota(c, info, totalUsage, nil)
+	return nil
+}
@@ -0,0 +1,133 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeEmbeddingInput(t *testing.T, raw string) any {
+	var input any
+	require.NoError(t, common.UnmarshalJsonStr(raw, &input))
+	return input
+}
+
+func TestSplittableEmbeddingInputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		count int
+		ok    bool
+	}{
+		{"single string", `"hello"`, 0, false},
+		{"string list", `["a","b","c"]`, 3, true},
+		{"single token array", `[1,2,3,4]`, 0, false},
+		{"token array list", `[[1,2],[3],[4,5,6]]`, 3, true},
+		{"mixed numbers and strings", `["a",1]`, 0, false},
+		{"empty list", `[]`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, ok := splittableEmbeddingInputs(decodeEmbeddingInput(t, tt.input))
+			require.Equal(t, tt.ok, ok)
+			require.Len(t, inputs, tt.count)
+		})
+	}
+}
+
+func TestSplitEmbeddingInputs(t *testing.T) {
+	tests := []struct {
+		count     int
+		batchSize int
+		sizes     []int
+	}{
+		{5, 2, []int{2, 2, 1}},
+		{4, 2, []int{2, 2}},
+		{3, 5, []int{3}},
+	}
+	for _, tt := range tests {
+		inputs := make([]any, tt.count)
+		for i := range inputs {
+			inputs[i] = i
+		}
+		batches := splitEmbeddingInputs(inputs, tt.batchSize)
+		var sizes []int
+		next := 0
+		for _, batch := range batches {
+			sizes = append(sizes, len(batch))
+			for _, item := range batch {
+				require.Equal(t, next, item)
+				next++
+			}
+		}
+		require.Equal(t, tt.sizes, sizes)
+	}
+}
+
+// embeddingBatchStub 按批返回倒序的结果，模拟上游不按 index 顺序返回
+func embeddingBatchStub(failAt int) (embeddingBatchFunc, *int) {
+	calls := 0
+	return func(batch []any) (*dto.FlexibleEmbeddingResponse, *dto.Usage, *types.NewAPIError) {
+		calls++
+		if calls == failAt {
+			return nil, nil, types.NewOpenAIError(errors.New("upstream failed"), types.ErrorCodeBadResponseStatusCode, http.StatusBadGateway)
+		}
+		response := &dto.FlexibleEmbeddingResponse{Model: "text-embedding-3-small"}
+		for i := len(batch) - 1; i >= 0; i-- {
+			response.Data = append(response.Data, dto.FlexibleEmbeddingResponseItem{Object: "embedding", Index: i, Embedding: batch[i]})
+		}
+		tokens := len(batch) * 10
+		return response, &dto.Usage{PromptTokens: tokens, TotalTokens: tokens}, nil
+	}, &calls
+}
+
+func TestRunEmbeddingBatches(t *testing.T) {
+	inputs := []any{"a", "b", "c", "d", "e"}
+
+	t.Run("merged in input order with summed usage", func(t *testing.T) {
+		do, calls := embeddingBatchStub(0)
+		merged, usage, completed, newAPIError := runEmbeddingBatches(splitEmbeddingInputs(inputs, 2), do)
+		require.Nil(t, newAPIError)
+		require.Equal(t, 3, *calls)
+		require.Equal(t, 3, completed)
+		require.Equal(t, "text-embedding-3-small", merged.Model)
+		require.Len(t, merged.Data, len(inputs))
+		for i, item := range merged.Data {
+			require.Equal(t, i, item.Index)
+			require.Equal(t, inputs[i], item.Embedding)
+		}
+		require.Equal(t, 50, usage.PromptTokens)
+		require.Equal(t, 50, usage.TotalTokens)
+		require.Equal(t, 50, merged.Usage.TotalTokens)
+	})
+
+	t.Run("failure stops and keeps usage of finished batches", func(t *testing.T) {
+		do, calls := embeddingBatchStub(2)
+		_, usage, completed, newAPIError := runEmbeddingBatches(splitEmbeddingInputs(inputs, 2), do)
+		require.NotNil(t, newAPIError)
+		require.Equal(t, 2, *calls)
+		require.Equal(t, 1, completed)
+		require.Equal(t, 20, usage.TotalTokens)
+	})
+
+	t.Run("mismatched item count", func(t *testing.T) {
+		_, usage, completed, newAPIError := runEmbeddingBatches([][]any{{"a", "b"}}, func(batch []any) (*dto.FlexibleEmbeddingResponse, *dto.Usage, *types.NewAPIError) {
+			return &dto.FlexibleEmbeddingResponse{Data: []dto.FlexibleEmbeddingResponseItem{{Index: 0}}}, &dto.Usage{TotalTokens: 7}, nil
+		})
+		require.NotNil(t, newAPIError)
+		require.Equal(t, 1, completed)
+		require.Equal(t, 7, usage.TotalTokens)
+	})
+
+	t.Run("duplicate index", func(t *testing.T) {
+		_, _, _, newAPIError := runEmbeddingBatches([][]any{{"a", "b"}}, func(batch []any) (*dto.FlexibleEmbeddingResponse, *dto.Usage, *types.NewAPIError) {
+			return &dto.FlexibleEmbeddingResponse{Data: []dto.FlexibleEmbeddingResponseItem{{Index: 1}, {Index: 1}}}, nil, nil
+		})
+		require.NotNil(t, newAPIError)
+	})
+}
@@ -3,6 +3,7 @@ package helper
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/logger"
@@ -61,6 +62,21 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 		groupRatioInfo.GroupRatio = ratio_setting.GetGroupRatio(relayInfo.UsingGroup)
 	}
 
+	// 离线批量推理按折扣计费
+	if batch, ok := relaycommon.GetBatchRequest(ctx); ok && batch.DiscountRatio > 0 {
+		groupRatioInfo.GroupRatio *= batch.DiscountRatio
+	}
+
+	// 分时价格，例如闲时折扣
+	now := relayInfo.StartTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if multiplier, ok := billing_setting.GetTimePricingMultiplier(relayInfo.UsingGroup, relayInfo.OriginModelName, now); ok {
+		groupRatioInfo.GroupRatio *= multiplier
+		groupRatioInfo.TimeMultiplier = multiplier
+	}
+
 	return groupRatioInfo
 }
 
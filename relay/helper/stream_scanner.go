@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
@@ -19,6 +20,7 @@ import (
 	"github.com/bytedance/gopkg/util/gopool"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 const (
@@ -44,13 +46,20 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	info.StreamStatus = relaycommon.NewStreamStatus()
 
 	// 确保响应体总是被关闭
-	defer func() {
-		if resp.Body != nil {
-			resp.Body.Close()
-		}
-	}()
+	var closeBodyOnce sync.Once
+	closeBody := func() {
+		closeBodyOnce.Do(func() {
+			if resp.Body != nil {
+				resp.Body.Close()
+			}
+		})
+	}
+	defer closeBody()
 
 	streamingTimeout := time.Duration(constant.StreamingTimeout) * time.Second
+	if idleTimeout := info.GetUpstreamTimeouts().StreamIdleTimeout; idleTimeout > 0 {
+		streamingTimeout = time.Duration(idleTimeout) * time.Second
+	}
 
 	var (
 		stopChan   = make(chan bool, 3) // 增加缓冲区避免阻塞
@@ -71,6 +80,10 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	if pingEnabled {
 		pingTicker = time.NewTicker(pingInterval)
 	}
+	// 上游最近一次返回数据的时间，用于判断心跳是否超过最长静默时长
+	maxSilent := generalSettings.PingMaxSilentDuration()
+	var lastUpstreamData atomic.Int64
+	lastUpstreamData.Store(time.Now().UnixNano())
 
 	if common.DebugEnabled {
 		// print timeout and ping interval for debugging
@@ -140,6 +153,10 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 			for {
 				select {
 				case <-pingTicker.C:
+					if maxSilent > 0 && time.Since(time.Unix(0, lastUpstreamData.Load())) >= maxSilent {
+						logger.LogWarn(c, fmt.Sprintf("upstream silent for more than %v, stop sending ping", maxSilent))
+						return
+					}
 					// 使用超时机制防止写操作阻塞
 					done := make(chan error, 1)
 					gopool.Go(func() {
@@ -196,6 +213,7 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		}()
 		sr := newStreamResult(info.StreamStatus)
 		for data := range dataChan {
+			info.StreamStatus.AppendResponseText(streamDeltaText(data))
 			sr.reset()
 			writeMutex.Lock()
 			dataHandler(data, sr)
@@ -236,6 +254,7 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 			}
 
 			ticker.Reset(streamingTimeout)
+			lastUpstreamData.Store(time.Now().UnixNano())
 			data := scanner.Text()
 			if common.DebugEnabled {
 				println(data)
@@ -290,6 +309,10 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	case <-c.Request.Context().Done():
 		info.StreamStatus.SetEndReason(relaycommon.StreamEndReasonClientGone, c.Request.Context().Err())
 	}
+	if info.StreamStatus.EndReason == relaycommon.StreamEndReasonClientGone {
+		// 客户端断开后立即关闭上游响应体以中断上游生成，不等待阻塞在读取上的扫描协程；计费按已收到的内容计算
+		closeBody()
+	}
 
 	if info.StreamStatus.IsNormalEnd() && !info.StreamStatus.HasErrors() {
 		logger.LogInfo(c, fmt.Sprintf("stream ended: %s", info.StreamStatus.Summary()))
@@ -297,3 +320,45 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		logger.LogError(c, fmt.Sprintf("stream ended: %s, received=%d", info.StreamStatus.Summary(), info.ReceivedResponseCount))
 	}
 }
+
+// streamDeltaPaths 各格式流式分片中输出文本所在的字段：OpenAI Chat/Completions、Claude、Gemini
+var streamDeltaPaths = []string{
+	"choices.#.delta.content",
+	"choices.#.delta.reasoning_content",
+	"choices.#.delta.reasoning",
+	"choices.#.delta.tool_calls.#.function.name",
+	"choices.#.delta.tool_calls.#.function.arguments",
+	"choices.#.text",
+	"delta.text",
+	"delta.thinking",
+	"delta.partial_json",
+	"candidates.#.content.parts.#.text",
+}
+
+// streamDeltaText 提取单个流式分片中的输出文本，上游未返回 usage 时据此估算输出 token
+func streamDeltaText(data string) string {
+	if !gjson.Valid(data) {
+		return ""
+	}
+	// Responses API 的增量事件，如 response.output_text.delta、response.function_call_arguments.delta
+	if eventType := gjson.Get(data, "type").String(); strings.HasPrefix(eventType, "response.") && strings.HasSuffix(eventType, ".delta") {
+		return gjson.Get(data, "delta").String()
+	}
+	var builder strings.Builder
+	for _, result := range gjson.GetMany(data, streamDeltaPaths...) {
+		writeStreamDeltaResult(&builder, result)
+	}
+	return builder.String()
+}
+
+func writeStreamDeltaResult(builder *strings.Builder, result gjson.Result) {
+	if result.IsArray() {
+		for _, item := range result.Array() {
+			writeStreamDeltaResult(builder, item)
+		}
+		return
+	}
+	if result.Type == gjson.String {
+		builder.WriteString(result.Str)
+	}
+}
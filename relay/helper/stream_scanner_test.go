@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -375,6 +376,67 @@ func TestStreamScannerHandler_PingSentDuringSlowUpstream(t *testing.T) {
 		"expected at least 2 pings during 3.5s stream with 1s interval; got %d", pingCount)
 }
 
+func TestStreamScannerHandler_PingStopsAfterMaxSilent(t *testing.T) {
+	t.Parallel()
+
+	setting := operation_setting.GetGeneralSetting()
+	oldEnabled := setting.PingIntervalEnabled
+	oldSeconds := setting.PingIntervalSeconds
+	oldMaxSilent := setting.PingMaxSilentSeconds
+	setting.PingIntervalEnabled = true
+	setting.PingIntervalSeconds = 1
+	setting.PingMaxSilentSeconds = 2
+	t.Cleanup(func() {
+		setting.PingIntervalEnabled = oldEnabled
+		setting.PingIntervalSeconds = oldSeconds
+		setting.PingMaxSilentSeconds = oldMaxSilent
+	})
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		fmt.Fprint(pw, "data: chunk_0\n")
+		// 上游长时间无数据
+		time.Sleep(4500 * time.Millisecond)
+		fmt.Fprint(pw, "data: chunk_1\n")
+		fmt.Fprint(pw, "data: [DONE]\n")
+	}()
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	oldTimeout := constant.StreamingTimeout
+	constant.StreamingTimeout = 30
+	t.Cleanup(func() {
+		constant.StreamingTimeout = oldTimeout
+	})
+
+	resp := &http.Response{Body: pr}
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}
+
+	var count atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		StreamScannerHandler(c, resp, info, func(data string, sr *StreamResult) {
+			count.Add(1)
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatal("timed out waiting for stream to finish")
+	}
+
+	assert.Equal(t, int64(2), count.Load())
+
+	pingCount := strings.Count(recorder.Body.String(), ": PING")
+	assert.GreaterOrEqual(t, pingCount, 1)
+	assert.LessOrEqual(t, pingCount, 2, "pings should stop after max silent duration; got %d", pingCount)
+}
+
 func TestStreamScannerHandler_PingDisabledByRelayInfo(t *testing.T) {
 	t.Parallel()
 
@@ -688,3 +750,61 @@ func TestStreamScannerHandler_PingInterleavesWithSlowUpstream(t *testing.T) {
 	assert.GreaterOrEqual(t, pingCount, 3,
 		"expected at least 3 pings during 5s stream with 1s ping interval; got %d", pingCount)
 }
+
+func TestStreamScannerHandler_ClientGoneClosesUpstream(t *testing.T) {
+	t.Parallel()
+
+	pr, pw := io.Pipe()
+	c, _, info := setupStreamTest(t, nil)
+	resp := &http.Response{Body: pr}
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+
+	go func() {
+		fmt.Fprint(pw, "data: chunk_0\n")
+	}()
+
+	var count atomic.Int64
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		StreamScannerHandler(c, resp, info, func(data string, sr *StreamResult) {
+			count.Add(1)
+			// 收到第一块数据后客户端断开，上游此后不再返回数据
+			cancel()
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatal("timed out waiting for stream to finish")
+	}
+
+	// 不等待阻塞在读取上的扫描协程，立即关闭上游
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.Equal(t, int64(1), count.Load())
+	assert.Equal(t, relaycommon.StreamEndReasonClientGone, info.StreamStatus.EndReason)
+	_, err := fmt.Fprint(pw, "data: chunk_1\n")
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestStreamDeltaText(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		`{"choices":[{"delta":{"content":"Hel","reasoning_content":"think"}}]}`:                                "Helthink",
+		`{"choices":[{"delta":{"tool_calls":[{"function":{"name":"get_weather","arguments":"{\"city\""}}]}}]}`: `get_weather{"city"`,
+		`{"choices":[{"text":"legacy"}]}`:                                                           "legacy",
+		`{"type":"content_block_delta","delta":{"type":"text_delta","text":"claude"}}`:              "claude",
+		`{"candidates":[{"content":{"parts":[{"text":"gem"},{"text":"ini"}]}}]}`:                    "gemini",
+		`{"type":"response.output_text.delta","delta":"responses"}`:                                 "responses",
+		`{"type":"response.completed","response":{"output":[{"content":[{"text":"duplicated"}]}]}}`: "",
+		`{"choices":[{"delta":{}}],"usage":{"prompt_tokens":1}}`:                                    "",
+		`not json`: "",
+	}
+	for data, expected := range testCases {
+		assert.Equal(t, expected, streamDeltaText(data), data)
+	}
+}
@@ -142,11 +142,11 @@ func GetAndValidOpenAIImageRequest(c *gin.Context, relayMode int) (*dto.ImageReq
 	imageRequest := &dto.ImageRequest{}
 
 	switch relayMode {
-	case relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		if strings.Contains(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
-			_, err := c.MultipartForm()
+			form, err := c.MultipartForm()
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse image edit form request: %w", err)
+				return nil, fmt.Errorf("failed to parse image %s form request: %w", imageFormName(relayMode), err)
 			}
 			formData := c.Request.PostForm
 			imageRequest.Prompt = formData.Get("prompt")
@@ -154,15 +154,36 @@ func GetAndValidOpenAIImageRequest(c *gin.Context, relayMode int) (*dto.ImageReq
 			imageRequest.N = common.GetPointer(uint(common.String2Int(formData.Get("n"))))
 			imageRequest.Quality = formData.Get("quality")
 			imageRequest.Size = formData.Get("size")
+			imageRequest.ResponseFormat = formData.Get("response_format")
 			if imageValue := formData.Get("image"); imageValue != "" {
 				imageRequest.Image, _ = common.Marshal(imageValue)
 			}
 
+			if relayMode == relayconstant.RelayModeImagesVariations {
+				// 变体接口没有 prompt，只接受单张图片文件
+				if imageRequest.Model == "" {
+					imageRequest.Model = "dall-e-2"
+				}
+				if len(form.File["image"]) != 1 {
+					return nil, errors.New("exactly one image file is required")
+				}
+			} else if len(form.File["mask"]) > 0 && len(form.File["image"]) == 0 && len(form.File["image[]"]) == 0 {
+				return nil, errors.New("mask requires an image file")
+			}
+
 			if imageRequest.Model == "gpt-image-1" {
 				if imageRequest.Quality == "" {
 					imageRequest.Quality = "standard"
 				}
 			}
+			if imageRequest.Model == "dall-e-2" || imageRequest.Model == "dall-e" {
+				if imageRequest.Size != "" && imageRequest.Size != "256x256" && imageRequest.Size != "512x512" && imageRequest.Size != "1024x1024" {
+					return nil, errors.New("size must be one of 256x256, 512x512, or 1024x1024 for dall-e-2 or dall-e")
+				}
+				if imageRequest.Size == "" {
+					imageRequest.Size = "1024x1024"
+				}
+			}
 			if imageRequest.N == nil || *imageRequest.N == 0 {
 				imageRequest.N = common.GetPointer(uint(1))
 			}
@@ -226,6 +247,13 @@ func GetAndValidOpenAIImageRequest(c *gin.Context, relayMode int) (*dto.ImageReq
 	return imageRequest, nil
 }
 
+func imageFormName(relayMode int) string {
+	if relayMode == relayconstant.RelayModeImagesVariations {
+		return "variation"
+	}
+	return "edit"
+}
+
 func GetAndValidateClaudeRequest(c *gin.Context) (textRequest *dto.ClaudeRequest, err error) {
 	textRequest = &dto.ClaudeRequest{}
 	err = common.UnmarshalBodyReusable(c, textRequest)
@@ -0,0 +1,65 @@
+package helper
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newImageFormContext(t *testing.T, path string, fields map[string]string, files ...string) *gin.Context {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		require.NoError(t, writer.WriteField(key, value))
+	}
+	for _, name := range files {
+		part, err := writer.CreateFormFile(name, name+".png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("\x89PNG"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, path, &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+func TestGetAndValidOpenAIImageRequestVariations(t *testing.T) {
+	c := newImageFormContext(t, "/v1/images/variations", map[string]string{"n": "2", "size": "512x512"}, "image")
+	request, err := GetAndValidOpenAIImageRequest(c, relayconstant.RelayModeImagesVariations)
+	require.NoError(t, err)
+	require.Equal(t, "dall-e-2", request.Model)
+	require.Equal(t, uint(2), *request.N)
+	require.Equal(t, "512x512", request.Size)
+	require.InDelta(t, 0.45, request.GetTokenCountMeta().ImagePriceRatio, 1e-9)
+
+	c = newImageFormContext(t, "/v1/images/variations", map[string]string{"model": "dall-e-2"})
+	_, err = GetAndValidOpenAIImageRequest(c, relayconstant.RelayModeImagesVariations)
+	require.Error(t, err)
+
+	c = newImageFormContext(t, "/v1/images/variations", map[string]string{"size": "1792x1024"}, "image")
+	_, err = GetAndValidOpenAIImageRequest(c, relayconstant.RelayModeImagesVariations)
+	require.Error(t, err)
+}
+
+func TestGetAndValidOpenAIImageRequestEdits(t *testing.T) {
+	c := newImageFormContext(t, "/v1/images/edits", map[string]string{"model": "dall-e-2", "prompt": "add a hat"}, "image", "mask")
+	request, err := GetAndValidOpenAIImageRequest(c, relayconstant.RelayModeImagesEdits)
+	require.NoError(t, err)
+	require.Equal(t, "add a hat", request.Prompt)
+	require.Equal(t, "1024x1024", request.Size)
+	require.Equal(t, uint(1), *request.N)
+
+	c = newImageFormContext(t, "/v1/images/edits", map[string]string{"model": "dall-e-2", "prompt": "add a hat"}, "mask")
+	_, err = GetAndValidOpenAIImageRequest(c, relayconstant.RelayModeImagesEdits)
+	require.Error(t, err)
+}
@@ -96,6 +96,16 @@ func RerankHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
+	// 按次计费的模型按搜索单位计费，渠道已从响应中取得实际单位数时不再估算
+	if info.PriceData.UsePrice {
+		if _, ok := info.PriceData.OtherRatios[relaycommon.RerankSearchUnitsRatio]; !ok {
+			info.PriceData.AddOtherRatio(relaycommon.RerankSearchUnitsRatio, float64(relaycommon.EstimateRerankSearchUnits(len(request.Documents))))
+		}
+	}
+	if usage.(*dto.Usage).TotalTokens == 0 {
+		usage.(*dto.Usage).PromptTokens = info.GetEstimatePromptTokens()
+		usage.(*dto.Usage).TotalTokens = usage.(*dto.Usage).PromptTokens
+	}
 	service.PostTextConsumeQuota(c, info, usage.(*dto.Usage), nil)
 	return nil
 }
@@ -0,0 +1,234 @@
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shouldResponsesUseChatCompletions 适配器不支持原生 /v1/responses 时经 Chat Completions 转换
+func shouldResponsesUseChatCompletions(info *relaycommon.RelayInfo, adaptor channel.Adaptor) bool {
+	if info.RelayMode != relayconstant.RelayModeResponses {
+		return false
+	}
+	if native, ok := adaptor.(channel.ResponsesNativeAdaptor); !ok || !native.SupportsNativeResponses() {
+		return true
+	}
+	return service.ShouldResponsesUseChatCompletionsGlobal(info.ChannelId, info.ChannelType, info.OriginModelName)
+}
+
+// chatToResponsesWriter 将适配器输出的 Chat Completions 响应改写为 /v1/responses 格式：
+// 流式响应逐行转换为语义事件，非流式响应缓存到结束后整体转换
+type chatToResponsesWriter struct {
+	gin.ResponseWriter
+	isStream bool
+	stream   *service.ChatToResponsesStream
+	pending  []byte
+	status   int
+	body     bytes.Buffer
+}
+
+func (w *chatToResponsesWriter) WriteHeader(code int) {
+	if w.isStream {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *chatToResponsesWriter) WriteHeaderNow() {
+	if w.isStream {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *chatToResponsesWriter) Flush() {
+	if w.isStream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *chatToResponsesWriter) Write(data []byte) (int, error) {
+	if !w.isStream {
+		return w.body.Write(data)
+	}
+	w.pending = append(w.pending, data...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(w.pending[:idx]))
+		w.pending = w.pending[idx+1:]
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *chatToResponsesWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *chatToResponsesWriter) handleLine(line string) error {
+	if strings.HasPrefix(line, ":") {
+		// 保活注释原样转发
+		_, err := io.WriteString(w.ResponseWriter, line+"\n\n")
+		return err
+	}
+	payload, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return nil
+	}
+	payload = strings.TrimSpace(payload)
+	if payload == "" || payload == "[DONE]" {
+		return nil
+	}
+	var chunk dto.ChatCompletionsStreamResponse
+	if err := common.UnmarshalJsonStr(payload, &chunk); err != nil {
+		return nil
+	}
+	return w.writeEvents(w.stream.Feed(&chunk))
+}
+
+func (w *chatToResponsesWriter) writeEvents(events []map[string]any) error {
+	for _, event := range events {
+		data, err := common.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", event["type"], data); err != nil {
+			return err
+		}
+	}
+	if len(events) > 0 {
+		w.ResponseWriter.Flush()
+	}
+	return nil
+}
+
+// finish 在适配器处理完响应后写出结束事件或完整的响应对象
+func (w *chatToResponsesWriter) finish(usage *dto.Usage) error {
+	if w.isStream {
+		if line := strings.TrimSpace(string(w.pending)); line != "" {
+			if err := w.handleLine(line); err != nil {
+				return err
+			}
+		}
+		return w.writeEvents(w.stream.Finish(usage))
+	}
+	var chat dto.OpenAITextResponse
+	if err := common.Unmarshal(w.body.Bytes(), &chat); err != nil {
+		return err
+	}
+	data, err := common.Marshal(service.ChatCompletionsResponseToResponsesResponse(&chat, usage))
+	if err != nil {
+		return err
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(status)
+	_, err = w.ResponseWriter.Write(data)
+	return err
+}
+
+// responsesViaChatCompletions 将 /v1/responses 请求转换为 Chat Completions 发往上游，再把响应转换回 Responses 格式
+func responsesViaChatCompletions(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, responsesReq *dto.OpenAIResponsesRequest) (*dto.Usage, *types.NewAPIError) {
+	chatReq, err := service.ResponsesRequestToChatCompletionsRequest(responsesReq)
+	if err != nil {
+		return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	if !info.SupportStreamOptions {
+		chatReq.StreamOptions = nil
+	}
+	info.AppendRequestConversion(types.RelayFormatOpenAI)
+
+	savedRelayMode := info.RelayMode
+	savedRequestURLPath := info.RequestURLPath
+	defer func() {
+		info.RelayMode = savedRelayMode
+		info.RequestURLPath = savedRequestURLPath
+	}()
+	info.RelayMode = relayconstant.RelayModeChatCompletions
+	info.RequestURLPath = "/v1/chat/completions"
+	info.ShouldIncludeUsage = true
+
+	applySystemPromptIfNeeded(c, info, chatReq)
+	convertedRequest, err := adaptor.ConvertOpenAIRequest(c, info, chatReq)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
+
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeJsonMarshalFailed, types.ErrOptionWithSkipRetry())
+	}
+	jsonData, err = relaycommon.RemoveDisabledFields(jsonData, info.ChannelOtherSettings, info.ChannelSetting.PassThroughBodyEnabled)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverrideWithRelayInfo(jsonData, info)
+		if err != nil {
+			return nil, newAPIErrorFromParamOverride(err)
+		}
+	}
+
+	resp, err := adaptor.DoRequest(c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
+	}
+	if resp == nil {
+		return nil, types.NewOpenAIError(nil, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+
+	statusCodeMappingStr := c.GetString("status_code_mapping")
+	httpResp := resp.(*http.Response)
+	info.IsStream = info.IsStream || strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/event-stream")
+	if httpResp.StatusCode != http.StatusOK {
+		newApiErr := service.RelayErrorHandler(c.Request.Context(), httpResp, false)
+		service.ResetStatusCode(newApiErr, statusCodeMappingStr)
+		return nil, newApiErr
+	}
+
+	writer := &chatToResponsesWriter{
+		ResponseWriter: c.Writer,
+		isStream:       info.IsStream,
+		stream:         service.NewChatToResponsesStream(info.UpstreamModelName),
+	}
+	original := c.Writer
+	c.Writer = writer
+	usage, newApiErr := adaptor.DoResponse(c, httpResp, info)
+	c.Writer = original
+	if newApiErr != nil {
+		service.ResetStatusCode(newApiErr, statusCodeMappingStr)
+		return nil, newApiErr
+	}
+	usageDto, _ := usage.(*dto.Usage)
+	if usageDto == nil {
+		usageDto = &dto.Usage{}
+	}
+	if err := writer.finish(usageDto); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+	return usageDto, nil
+}
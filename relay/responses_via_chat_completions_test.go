@@ -0,0 +1,34 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesNativeAdaptors(t *testing.T) {
+	native := []int{
+		constant.APITypeOpenAI,
+		constant.APITypeOpenRouter,
+		constant.APITypeXinference,
+		constant.APITypeCodex,
+		constant.APITypeCloudflare,
+		constant.APITypeAli,
+		constant.APITypeXai,
+		constant.APITypeVolcEngine,
+		constant.APITypePerplexity,
+	}
+	for _, apiType := range native {
+		info := &relaycommon.RelayInfo{RelayMode: relayconstant.RelayModeResponses}
+		info.ApiType = apiType
+		require.False(t, shouldResponsesUseChatCompletions(info, GetAdaptor(apiType)), apiType)
+	}
+	for _, apiType := range []int{constant.APITypeAnthropic, constant.APITypeGemini, constant.APITypeDeepSeek} {
+		info := &relaycommon.RelayInfo{RelayMode: relayconstant.RelayModeResponses}
+		info.ApiType = apiType
+		require.True(t, shouldResponsesUseChatCompletions(info, GetAdaptor(apiType)), apiType)
+	}
+}
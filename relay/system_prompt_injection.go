@@ -0,0 +1,90 @@
+package relay
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// joinSystemPrompt 将注入内容拼接在已有系统提示词前后
+func joinSystemPrompt(prepend string, existing string, appendPrompt string) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{prepend, existing, appendPrompt} {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// recordInjectedSystemPrompt 记录注入的 token 数，结算时从输入 token 中扣除
+func recordInjectedSystemPrompt(info *relaycommon.RelayInfo, prepend string, appendPrompt string) {
+	if !operation_setting.GetSystemPromptSetting().ExcludeFromBilling {
+		return
+	}
+	info.InjectedSystemPromptTokens = service.CountTextToken(joinSystemPrompt(prepend, "", appendPrompt), info.OriginModelName)
+}
+
+// applyGlobalSystemPromptOpenAI 向 OpenAI 格式的对话请求注入管理员配置的系统提示词
+func applyGlobalSystemPromptOpenAI(info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) {
+	prepend, appendPrompt := operation_setting.GetSystemPromptInjection(info.UsingGroup)
+	if (prepend == "" && appendPrompt == "") || len(request.Messages) == 0 {
+		return
+	}
+	recordInjectedSystemPrompt(info, prepend, appendPrompt)
+	for i, message := range request.Messages {
+		if message.Role != "system" && message.Role != "developer" {
+			continue
+		}
+		if message.IsStringContent() {
+			request.Messages[i].SetStringContent(joinSystemPrompt(prepend, message.StringContent(), appendPrompt))
+			return
+		}
+		contents := message.ParseContent()
+		if prepend != "" {
+			contents = append([]dto.MediaContent{{Type: dto.ContentTypeText, Text: prepend}}, contents...)
+		}
+		if appendPrompt != "" {
+			contents = append(contents, dto.MediaContent{Type: dto.ContentTypeText, Text: appendPrompt})
+		}
+		request.Messages[i].Content = contents
+		return
+	}
+	systemMessage := dto.Message{
+		Role:    request.GetSystemRoleName(),
+		Content: joinSystemPrompt(prepend, "", appendPrompt),
+	}
+	request.Messages = append([]dto.Message{systemMessage}, request.Messages...)
+}
+
+// applyGlobalSystemPromptClaude 向 Claude 格式的请求注入管理员配置的系统提示词
+func applyGlobalSystemPromptClaude(info *relaycommon.RelayInfo, request *dto.ClaudeRequest) {
+	prepend, appendPrompt := operation_setting.GetSystemPromptInjection(info.UsingGroup)
+	if prepend == "" && appendPrompt == "" {
+		return
+	}
+	recordInjectedSystemPrompt(info, prepend, appendPrompt)
+	if request.System == nil || request.IsStringSystem() {
+		existing := ""
+		if request.System != nil {
+			existing = request.GetStringSystem()
+		}
+		request.SetStringSystem(joinSystemPrompt(prepend, existing, appendPrompt))
+		return
+	}
+	systemContents := request.ParseSystem()
+	if prepend != "" {
+		prependSystem := dto.ClaudeMediaMessage{Type: dto.ContentTypeText}
+		prependSystem.SetText(prepend)
+		systemContents = append([]dto.ClaudeMediaMessage{prependSystem}, systemContents...)
+	}
+	if appendPrompt != "" {
+		appendSystem := dto.ClaudeMediaMessage{Type: dto.ContentTypeText}
+		appendSystem.SetText(appendPrompt)
+		systemContents = append(systemContents, appendSystem)
+	}
+	request.System = systemContents
+}
@@ -0,0 +1,66 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func withSystemPromptRules(t *testing.T, rules []operation_setting.SystemPromptRule) {
+	t.Helper()
+	setting := operation_setting.GetSystemPromptSetting()
+	original := *setting
+	t.Cleanup(func() { *setting = original })
+	setting.Enabled = true
+	setting.ExcludeFromBilling = true
+	setting.Rules = rules
+}
+
+func TestApplyGlobalSystemPromptOpenAI(t *testing.T) {
+	withSystemPromptRules(t, []operation_setting.SystemPromptRule{
+		{Prompt: "Disclaimer."},
+		{Prompt: "Footer.", Position: operation_setting.SystemPromptPositionAppend},
+	})
+
+	info := &relaycommon.RelayInfo{UsingGroup: "default", OriginModelName: "gpt-4o"}
+	request := &dto.GeneralOpenAIRequest{Model: "gpt-4o", Messages: []dto.Message{{Role: "user", Content: "hello"}}}
+	applyGlobalSystemPromptOpenAI(info, request)
+	require.Len(t, request.Messages, 2)
+	require.Equal(t, "system", request.Messages[0].Role)
+	require.Equal(t, "Disclaimer.\nFooter.", request.Messages[0].StringContent())
+	require.Positive(t, info.InjectedSystemPromptTokens)
+
+	request = &dto.GeneralOpenAIRequest{Model: "gpt-4o", Messages: []dto.Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "hello"},
+	}}
+	applyGlobalSystemPromptOpenAI(info, request)
+	require.Len(t, request.Messages, 2)
+	require.Equal(t, "Disclaimer.\nYou are helpful.\nFooter.", request.Messages[0].StringContent())
+}
+
+func TestApplyGlobalSystemPromptClaude(t *testing.T) {
+	withSystemPromptRules(t, []operation_setting.SystemPromptRule{
+		{Prompt: "Disclaimer.", Groups: []string{"enterprise"}},
+	})
+
+	info := &relaycommon.RelayInfo{UsingGroup: "default", OriginModelName: "claude-sonnet-4"}
+	request := &dto.ClaudeRequest{Model: "claude-sonnet-4"}
+	applyGlobalSystemPromptClaude(info, request)
+	require.Nil(t, request.System)
+
+	info.UsingGroup = "enterprise"
+	request.SetStringSystem("You are helpful.")
+	applyGlobalSystemPromptClaude(info, request)
+	require.Equal(t, "Disclaimer.\nYou are helpful.", request.GetStringSystem())
+
+	request = &dto.ClaudeRequest{Model: "claude-sonnet-4", System: []dto.ClaudeMediaMessage{{Type: dto.ContentTypeText}}}
+	request.System.([]dto.ClaudeMediaMessage)[0].SetText("You are helpful.")
+	applyGlobalSystemPromptClaude(info, request)
+	system := request.ParseSystem()
+	require.Len(t, system, 2)
+	require.Equal(t, "Disclaimer.", system[0].GetText())
+}
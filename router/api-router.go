@@ -6,6 +6,7 @@ import (
 
 	// Import oauth package to register providers via init()
 	_ "github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,7 @@ import (
 func SetApiRouter(router *gin.Engine) {
 	apiRouter := router.Group("/api")
 	apiRouter.Use(middleware.RouteTag("api"))
-	apiRouter.Use(gzip.Gzip(gzip.DefaultCompression))
+	apiRouter.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/traffic/stream"})))
 	apiRouter.Use(middleware.BodyStorageCleanup()) // 清理请求体存储
 	apiRouter.Use(middleware.GlobalAPIRateLimit())
 	{
@@ -24,6 +25,8 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
 		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.POST("/graphql", middleware.AdminAuth(), controller.AdminGraphQL)
+		apiRouter.POST("/mcp", middleware.AdminAuth(), controller.AdminMCP())
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/user-agreement", controller.GetUserAgreement)
 		apiRouter.GET("/privacy-policy", controller.GetPrivacyPolicy)
@@ -31,6 +34,7 @@ func SetApiRouter(router *gin.Engine) {
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
 		apiRouter.GET("/home_page_content", controller.GetHomePageContent)
 		apiRouter.GET("/pricing", middleware.TryUserAuth(), controller.GetPricing)
+		apiRouter.GET("/models/catalog", middleware.TryUserAuth(), controller.GetModelCatalog)
 		perfMetricsRoute := apiRouter.Group("/perf-metrics")
 		perfMetricsRoute.Use(middleware.TryUserAuth())
 		{
@@ -104,6 +108,7 @@ func SetApiRouter(router *gin.Engine) {
 				//selfRoute.POST("/waffo-pancake/pay", middleware.CriticalRateLimit(), controller.RequestWaffoPancakePay)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
+				selfRoute.GET("/invoices", controller.GetUserInvoice)
 
 				// 2FA routes
 				selfRoute.GET("/2fa/status", controller.Get2FAStatus)
@@ -131,6 +136,7 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.GET("/:id/oauth/bindings", controller.GetUserOAuthBindingsByAdmin)
 				adminRoute.DELETE("/:id/oauth/bindings/:provider_id", controller.UnbindCustomOAuthByAdmin)
 				adminRoute.DELETE("/:id/bindings/:binding_type", controller.AdminClearUserBinding)
+				adminRoute.PUT("/:id/safety_level", controller.UpdateUserSafetyLevel)
 				adminRoute.GET("/:id", controller.GetUser)
 				adminRoute.POST("/", controller.CreateUser)
 				adminRoute.POST("/manage", controller.ManageUser)
@@ -176,17 +182,38 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/subscription/epay/notify", controller.SubscriptionEpayNotify)
 		apiRouter.GET("/subscription/epay/return", controller.SubscriptionEpayReturn)
 		apiRouter.POST("/subscription/epay/return", controller.SubscriptionEpayReturn)
+		apiRouter.GET("/entitlement", middleware.RootAuth(), controller.GetEntitlements)
+		abusePatternRoute := apiRouter.Group("/abuse_pattern")
+		abusePatternRoute.Use(middleware.RootAuth())
+		{
+			abusePatternRoute.GET("/", controller.GetAbusePatterns)
+			abusePatternRoute.PUT("/", controller.UploadAbusePatterns)
+			abusePatternRoute.POST("/sync", middleware.CriticalRateLimit(), controller.SyncAbusePatternFeed)
+		}
 		optionRoute := apiRouter.Group("/option")
 		optionRoute.Use(middleware.RootAuth())
 		{
 			optionRoute.GET("/", controller.GetOptions)
 			optionRoute.PUT("/", controller.UpdateOption)
+			optionRoute.POST("/test_email", middleware.CriticalRateLimit(), controller.SendTestEmail)
+			optionRoute.POST("/test_event_webhook", middleware.CriticalRateLimit(), controller.SendTestEventWebhook)
 			optionRoute.GET("/channel_affinity_cache", controller.GetChannelAffinityCacheStats)
 			optionRoute.DELETE("/channel_affinity_cache", controller.ClearChannelAffinityCache)
+			optionRoute.GET("/blocklist_stats", controller.GetBlocklistStats)
+			optionRoute.DELETE("/blocklist_stats", controller.ResetBlocklistStats)
 			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
 			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
 
+		notifyTemplateRoute := apiRouter.Group("/notify_template")
+		notifyTemplateRoute.Use(middleware.RootAuth())
+		{
+			notifyTemplateRoute.GET("/", controller.GetNotifyTemplates)
+			notifyTemplateRoute.PUT("/", controller.UpdateNotifyTemplate)
+			notifyTemplateRoute.DELETE("/", controller.ResetNotifyTemplate)
+			notifyTemplateRoute.POST("/preview", controller.PreviewNotifyTemplate)
+		}
+
 		// Custom OAuth provider management (root only)
 		customOAuthRoute := apiRouter.Group("/custom-oauth-provider")
 		customOAuthRoute.Use(middleware.RootAuth())
@@ -221,6 +248,11 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/search", controller.SearchChannels)
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
+			channelRoute.GET("/metrics", controller.GetChannelMetrics)
+			channelRoute.GET("/circuit_breakers", controller.GetCircuitBreakers)
+			channelRoute.GET("/probes", controller.GetChannelProbeLogs)
+			channelRoute.GET("/probes/states", controller.GetChannelProbeStates)
+			channelRoute.POST("/circuit_breakers/reset", controller.ResetCircuitBreaker)
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.POST("/:id/key", middleware.RootAuth(), middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.GetChannelKey)
 			channelRoute.GET("/test", controller.TestAllChannels)
@@ -229,6 +261,7 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
 			channelRoute.POST("/", controller.AddChannel)
 			channelRoute.PUT("/", controller.UpdateChannel)
+			channelRoute.PUT("/upsert", controller.UpsertChannel)
 			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
 			channelRoute.POST("/tag/disabled", controller.DisableTagChannels)
 			channelRoute.POST("/tag/enabled", controller.EnableTagChannels)
@@ -266,6 +299,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/:id/key", middleware.CriticalRateLimit(), middleware.DisableCache(), controller.GetTokenKey)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
+			tokenRoute.PUT("/upsert", controller.UpsertToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
 			tokenRoute.POST("/batch", controller.DeleteTokenBatch)
 			tokenRoute.POST("/batch/keys", middleware.CriticalRateLimit(), middleware.DisableCache(), controller.GetTokenKeysBatch)
@@ -299,9 +333,12 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
 		logRoute.GET("/channel_affinity_usage_cache", middleware.AdminAuth(), controller.GetChannelAffinityUsageCacheStats)
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/export", middleware.AdminAuth(), middleware.RequireEntitlement(entitlement.FeatureAuditExport), controller.ExportLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), middleware.SearchRateLimit(), controller.SearchUserLogs)
 
+		apiRouter.GET("/traffic/stream", middleware.AdminAuth(), controller.StreamTraffic)
+
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
 		dataRoute.GET("/users", middleware.AdminAuth(), controller.GetQuotaDatesByUser)
@@ -326,6 +363,69 @@ func SetApiRouter(router *gin.Engine) {
 			prefillGroupRoute.DELETE("/:id", controller.DeletePrefillGroup)
 		}
 
+		experimentRoute := apiRouter.Group("/experiment")
+		experimentRoute.Use(middleware.AdminAuth())
+		{
+			experimentRoute.GET("/", controller.GetExperiments)
+			experimentRoute.POST("/", controller.CreateExperiment)
+			experimentRoute.PUT("/", controller.UpdateExperiment)
+			experimentRoute.DELETE("/:id", controller.DeleteExperiment)
+			experimentRoute.GET("/:id/report", controller.GetExperimentReport)
+		}
+
+		datasetRoute := apiRouter.Group("/dataset")
+		datasetRoute.Use(middleware.AdminAuth())
+		{
+			datasetRoute.GET("/samples", controller.GetDatasetSamples)
+			datasetRoute.GET("/samples/:id", controller.GetDatasetSample)
+			datasetRoute.PUT("/samples", controller.UpdateDatasetSample)
+			datasetRoute.POST("/samples/status", controller.BatchUpdateDatasetSamples)
+			datasetRoute.POST("/samples/delete", controller.DeleteDatasetSamples)
+			datasetRoute.GET("/export", controller.ExportDatasetSamples)
+		}
+
+		organizationRoute := apiRouter.Group("/organization")
+		organizationRoute.Use(middleware.UserAuth())
+		{
+			organizationRoute.GET("/self", controller.GetSelfOrganization)
+			organizationRoute.POST("/leave", controller.LeaveOrganization)
+			organizationRoute.GET("/invitations", controller.GetOrganizationInvitations)
+			organizationRoute.POST("/invitations/:id/accept", controller.AcceptOrganizationInvitation)
+			organizationRoute.POST("/invitations/:id/reject", controller.RejectOrganizationInvitation)
+			organizationRoute.GET("/members", controller.GetOrganizationMembers)
+			organizationRoute.POST("/members", controller.InviteOrganizationMember)
+			organizationRoute.PUT("/members/:user_id", controller.UpdateOrganizationMember)
+			organizationRoute.DELETE("/members/:user_id", controller.RemoveOrganizationMember)
+			organizationRoute.POST("/members/:user_id/quota", controller.AllocateOrganizationMemberQuota)
+			organizationRoute.GET("/usage", controller.GetOrganizationUsage)
+		}
+		organizationAdminRoute := apiRouter.Group("/organization/admin")
+		organizationAdminRoute.Use(middleware.AdminAuth())
+		{
+			organizationAdminRoute.GET("/", controller.GetOrganizations)
+			organizationAdminRoute.POST("/", controller.CreateOrganization)
+			organizationAdminRoute.PUT("/", controller.UpdateOrganization)
+			organizationAdminRoute.POST("/:id/quota", controller.AdjustOrganizationQuota)
+		}
+
+		// 分享链接无需登录，只读回放
+		apiRouter.GET("/playground/share/:code", controller.GetSharedPlaygroundSession)
+		playgroundRoute := apiRouter.Group("/playground")
+		playgroundRoute.Use(middleware.UserAuth())
+		{
+			playgroundRoute.GET("/sessions", controller.GetPlaygroundSessions)
+			playgroundRoute.GET("/sessions/:id", controller.GetPlaygroundSession)
+			playgroundRoute.POST("/sessions", controller.CreatePlaygroundSession)
+			playgroundRoute.PUT("/sessions", controller.UpdatePlaygroundSession)
+			playgroundRoute.DELETE("/sessions/:id", controller.DeletePlaygroundSession)
+			playgroundRoute.POST("/sessions/:id/share", controller.SharePlaygroundSession)
+			playgroundRoute.DELETE("/sessions/:id/share", controller.UnsharePlaygroundSession)
+			playgroundRoute.GET("/prompts", controller.GetPlaygroundPrompts)
+			playgroundRoute.POST("/prompts", controller.CreatePlaygroundPrompt)
+			playgroundRoute.PUT("/prompts", controller.UpdatePlaygroundPrompt)
+			playgroundRoute.DELETE("/prompts/:id", controller.DeletePlaygroundPrompt)
+		}
+
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/pkg/grpcrelay"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetGrpcRouter 注册 relay.proto 中定义的 gRPC 方法，需配合 h2c 或 TLS 使用 HTTP/2
+func SetGrpcRouter(router *gin.Engine) {
+	if !constant.GrpcRelayEnabled {
+		return
+	}
+	prefix := "/" + grpcrelay.ServiceName + "/"
+	router.POST(prefix+"ChatCompletions", controller.RelayGrpc(router, "/v1/chat/completions", false))
+	router.POST(prefix+"StreamChatCompletions", controller.RelayGrpc(router, "/v1/chat/completions", true))
+	router.POST(prefix+"Embeddings", controller.RelayGrpc(router, "/v1/embeddings", false))
+}
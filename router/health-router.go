@@ -0,0 +1,13 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetHealthRouter 注册供 Kubernetes 探针与负载均衡器使用的健康检查接口，无需鉴权
+func SetHealthRouter(router *gin.Engine) {
+	router.GET("/healthz", controller.Healthz)
+	router.GET("/readyz", controller.Readyz)
+}
@@ -16,7 +16,12 @@ func SetRouter(router *gin.Engine, assets ThemeAssets) {
 	SetApiRouter(router)
 	SetDashboardRouter(router)
 	SetRelayRouter(router)
+	SetGrpcRouter(router)
+	SetMcpRouter(router)
+	SetChatWebSocketRouter(router)
 	SetVideoRouter(router)
+	SetMetricsRouter(router)
+	SetHealthRouter(router)
 	frontendBaseUrl := os.Getenv("FRONTEND_BASE_URL")
 	if common.IsMasterNode && frontendBaseUrl != "" {
 		frontendBaseUrl = ""
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetMcpRouter 注册 MCP Streamable HTTP 端点，客户端使用 API 令牌鉴权
+func SetMcpRouter(router *gin.Engine) {
+	mcpRouter := router.Group("/mcp")
+	mcpRouter.Use(middleware.RouteTag("relay"))
+	mcpRouter.Use(middleware.TokenAuth())
+	{
+		handler := controller.MCP(router)
+		mcpRouter.POST("", handler)
+		mcpRouter.GET("", handler)
+		mcpRouter.DELETE("", handler)
+	}
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/QuantumNous/new-api/common"
+	prommetrics "github.com/QuantumNous/new-api/pkg/prom_metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetMetricsRouter 暴露 Prometheus 指标，需设置 METRICS_ENABLED=true 开启；
+// 设置 METRICS_TOKEN 后抓取方需携带 Authorization: Bearer <token>
+func SetMetricsRouter(router *gin.Engine) {
+	if !common.GetEnvOrDefaultBool("METRICS_ENABLED", false) {
+		return
+	}
+	token := os.Getenv("METRICS_TOKEN")
+	handler := prommetrics.Handler()
+	router.GET("/metrics", func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
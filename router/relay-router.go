@@ -1,6 +1,8 @@
 package router
 
 import (
+	"strings"
+
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/middleware"
@@ -15,6 +17,7 @@ func SetRelayRouter(router *gin.Engine) {
 	router.Use(middleware.DecompressRequestMiddleware())
 	router.Use(middleware.BodyStorageCleanup()) // 清理请求体存储
 	router.Use(middleware.StatsMiddleware())
+	router.Use(middleware.ResponseCompression())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
 	modelsRouter.Use(middleware.RouteTag("relay"))
@@ -31,6 +34,8 @@ func SetRelayRouter(router *gin.Engine) {
 			}
 		})
 
+		modelsRouter.GET("/export", controller.ExportRouterConfig)
+
 		modelsRouter.GET("/:model", func(c *gin.Context) {
 			switch {
 			case c.GetHeader("x-api-key") != "" && c.GetHeader("anthropic-version") != "":
@@ -59,6 +64,47 @@ func SetRelayRouter(router *gin.Engine) {
 		})
 	}
 
+	batchRouter := router.Group("/v1/batches")
+	batchRouter.Use(middleware.RouteTag("relay"))
+	batchRouter.Use(middleware.TokenAuth())
+	{
+		batchRouter.POST("", controller.CreateBatch)
+		batchRouter.GET("", controller.ListBatches)
+		batchRouter.GET("/:id", controller.GetBatch)
+		batchRouter.POST("/:id/cancel", controller.CancelBatch)
+		batchRouter.GET("/:id/output", controller.GetBatchOutput)
+	}
+
+	fileRouter := router.Group("/v1/files")
+	fileRouter.Use(middleware.RouteTag("relay"))
+	fileRouter.Use(middleware.TokenAuth())
+	{
+		fileRouter.POST("", controller.UploadFile)
+		fileRouter.GET("", controller.ListFiles)
+		fileRouter.GET("/:id", controller.GetFile)
+		fileRouter.GET("/:id/content", controller.GetFileContent)
+		fileRouter.DELETE("/:id", controller.DeleteFile)
+	}
+
+	assistantRouter := router.Group("/v1")
+	assistantRouter.Use(middleware.RouteTag("relay"))
+	assistantRouter.Use(middleware.TokenAuth())
+	{
+		assistantRouter.POST("/assistants", controller.RelayAssistants)
+		assistantRouter.GET("/assistants", controller.RelayAssistants)
+		assistantRouter.GET("/assistants/:id", controller.RelayAssistants)
+		assistantRouter.POST("/assistants/:id", controller.RelayAssistants)
+		assistantRouter.DELETE("/assistants/:id", controller.RelayAssistants)
+		assistantRouter.POST("/threads", controller.RelayAssistants)
+		assistantRouter.POST("/threads/runs", controller.RelayAssistants)
+		assistantRouter.GET("/threads/:id", controller.RelayAssistants)
+		assistantRouter.POST("/threads/:id", controller.RelayAssistants)
+		assistantRouter.DELETE("/threads/:id", controller.RelayAssistants)
+		assistantRouter.GET("/threads/:id/*path", controller.RelayAssistants)
+		assistantRouter.POST("/threads/:id/*path", controller.RelayAssistants)
+		assistantRouter.DELETE("/threads/:id/*path", controller.RelayAssistants)
+	}
+
 	playgroundRouter := router.Group("/pg")
 	playgroundRouter.Use(middleware.RouteTag("relay"))
 	playgroundRouter.Use(middleware.SystemPerformanceCheck())
@@ -71,6 +117,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relayV1Router.Use(middleware.SystemPerformanceCheck())
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
+	relayV1Router.Use(middleware.TokenRateLimit())
 	{
 		// WebSocket 路由（统一到 Relay）
 		wsRouter := relayV1Router.Group("")
@@ -79,10 +126,25 @@ func SetRelayRouter(router *gin.Engine) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
 	}
+	{
+		// 本地估算 token，不经过渠道分发
+		relayV1Router.POST("/messages/count_tokens", controller.CountClaudeTokens)
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
+		httpRouter.Use(middleware.Idempotency())
+		httpRouter.Use(middleware.Plugins())
 		httpRouter.Use(middleware.Distribute())
+		httpRouter.Use(middleware.ChannelPlugins())
+		httpRouter.Use(middleware.ResponseCache())
+		httpRouter.Use(middleware.SemanticCache())
+		httpRouter.Use(middleware.RequestDedup())
+		httpRouter.Use(middleware.DatasetCapture())
+		httpRouter.Use(middleware.OutputModeration())
+		httpRouter.Use(middleware.BlocklistOutput())
+		httpRouter.Use(middleware.ImageSafety())
+		httpRouter.Use(middleware.PiiRestore())
 
 		// claude related routes
 		httpRouter.POST("/messages", func(c *gin.Context) {
@@ -115,6 +177,9 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/images/edits", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIImage)
 		})
+		httpRouter.POST("/images/variations", func(c *gin.Context) {
+			controller.Relay(c, types.RelayFormatOpenAIImage)
+		})
 
 		// embedding related routes
 		httpRouter.POST("/embeddings", func(c *gin.Context) {
@@ -142,6 +207,10 @@ func SetRelayRouter(router *gin.Engine) {
 			controller.Relay(c, types.RelayFormatGemini)
 		})
 		httpRouter.POST("/models/*path", func(c *gin.Context) {
+			if strings.HasSuffix(c.Param("path"), ":countTokens") {
+				controller.CountGeminiTokens(c)
+				return
+			}
 			controller.Relay(c, types.RelayFormatGemini)
 		})
 
@@ -151,12 +220,6 @@ func SetRelayRouter(router *gin.Engine) {
 		})
 
 		// not implemented
-		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
 		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
@@ -179,7 +242,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relaySunoRouter := router.Group("/suno")
 	relaySunoRouter.Use(middleware.RouteTag("relay"))
 	relaySunoRouter.Use(middleware.SystemPerformanceCheck())
-	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.TokenAuth(), middleware.TokenRateLimit(), middleware.Distribute())
 	{
 		relaySunoRouter.POST("/submit/:action", controller.RelayTask)
 		relaySunoRouter.POST("/fetch", controller.RelayTaskFetch)
@@ -191,10 +254,15 @@ func SetRelayRouter(router *gin.Engine) {
 	relayGeminiRouter.Use(middleware.SystemPerformanceCheck())
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
+	relayGeminiRouter.Use(middleware.TokenRateLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
 		relayGeminiRouter.POST("/models/*path", func(c *gin.Context) {
+			if strings.HasSuffix(c.Param("path"), ":countTokens") {
+				controller.CountGeminiTokens(c)
+				return
+			}
 			controller.Relay(c, types.RelayFormatGemini)
 		})
 	}
@@ -202,7 +270,7 @@ func SetRelayRouter(router *gin.Engine) {
 
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", relay.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.TokenAuth(), middleware.TokenRateLimit(), middleware.Distribute())
 	{
 		relayMjRouter.POST("/submit/action", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", controller.RelayMidjourney)
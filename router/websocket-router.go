@@ -0,0 +1,20 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetChatWebSocketRouter 注册 WebSocket 流式 chat completions 接口，供无法正常使用 SSE 的客户端使用
+func SetChatWebSocketRouter(router *gin.Engine) {
+	if !constant.ChatWebSocketEnabled {
+		return
+	}
+	wsRouter := router.Group("/v1/chat/completions/ws")
+	wsRouter.Use(middleware.RouteTag("relay"))
+	wsRouter.Use(middleware.TokenAuth())
+	wsRouter.GET("", controller.RelayChatWebSocket(router))
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	abusePatternFeedMaxBytes   = 10 << 20
+	abusePatternFeedTimeout    = 30 * time.Second
+	abusePatternFeedCheckEvery = time.Minute
+)
+
+var (
+	abusePatternFeedOnce    sync.Once
+	abusePatternFeedRunning atomic.Bool
+)
+
+// MatchAbusePatterns 使用 Aho-Corasick 自动机在文本中查找已知的越狱/滥用特征，
+// 自动机按特征集合缓存，特征库更新后自动重建
+func MatchAbusePatterns(text string) []moderation_setting.AbusePattern {
+	patterns := moderation_setting.GetActiveAbusePatterns()
+	if len(patterns) == 0 || text == "" {
+		return nil
+	}
+	dict := make([]string, len(patterns))
+	for i, p := range patterns {
+		dict[i] = p.Pattern
+	}
+	hit, words := AcSearch(strings.ToLower(text), dict, false)
+	if !hit {
+		return nil
+	}
+	categories := make(map[string]string, len(patterns))
+	for _, p := range patterns {
+		categories[strings.ToLower(strings.TrimSpace(p.Pattern))] = p.Category
+	}
+	matched := make([]moderation_setting.AbusePattern, 0, len(words))
+	for _, word := range RemoveDuplicate(words) {
+		matched = append(matched, moderation_setting.AbusePattern{Pattern: word, Category: categories[word]})
+	}
+	return matched
+}
+
+// CheckAbusePatterns 对输入文本执行特征库匹配，动作为 block 时返回错误
+func CheckAbusePatterns(c *gin.Context, text string) *types.NewAPIError {
+	setting := moderation_setting.GetAbusePatternSetting()
+	if !setting.Enabled {
+		return nil
+	}
+	matched := MatchAbusePatterns(text)
+	if len(matched) == 0 {
+		return nil
+	}
+	names := make([]string, len(matched))
+	for i, m := range matched {
+		names[i] = fmt.Sprintf("%s:%s", m.Category, m.Pattern)
+	}
+	message := fmt.Sprintf("abuse patterns matched: %s", strings.Join(names, ", "))
+	switch setting.Action {
+	case moderation_setting.ActionBlock:
+		logger.LogWarn(c, message)
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("request rejected: known abuse pattern detected (%s)", matched[0].Category),
+			types.ErrorCodeModerationBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	case moderation_setting.ActionFlag:
+		logger.LogWarn(c, message)
+		common.SetContextKey(c, constant.ContextKeyAbusePatternHits, names)
+	default:
+		logger.LogInfo(c, message)
+	}
+	return nil
+}
+
+// GetAbusePatternHits 返回本次请求被标记的特征，用于写入消费日志
+func GetAbusePatternHits(c *gin.Context) []string {
+	hits, _ := common.GetContextKeyType[[]string](c, constant.ContextKeyAbusePatternHits)
+	return hits
+}
+
+// SaveAbusePatterns 保存管理员上传的特征库
+func SaveAbusePatterns(patterns []moderation_setting.AbusePattern) error {
+	data, err := common.Marshal(patterns)
+	if err != nil {
+		return err
+	}
+	return model.UpdateOption("abuse_pattern_setting.patterns", string(data))
+}
+
+// SyncAbusePatternFeed 从远程特征源拉取特征库并保存，返回同步到的特征数量
+func SyncAbusePatternFeed(ctx context.Context) (int, error) {
+	feedUrl := moderation_setting.GetAbusePatternSetting().FeedUrl
+	if feedUrl == "" {
+		return 0, fmt.Errorf("abuse pattern feed url is not configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, abusePatternFeedTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("abuse pattern feed returned status code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, abusePatternFeedMaxBytes))
+	if err != nil {
+		return 0, err
+	}
+	patterns, err := moderation_setting.ParseAbusePatterns(data)
+	if err != nil {
+		return 0, err
+	}
+	encoded, err := common.Marshal(patterns)
+	if err != nil {
+		return 0, err
+	}
+	if err = model.UpdateOption("abuse_pattern_setting.feed_patterns", string(encoded)); err != nil {
+		return 0, err
+	}
+	if err = model.UpdateOption("abuse_pattern_setting.feed_last_sync_time", strconv.FormatInt(common.GetTimestamp(), 10)); err != nil {
+		return 0, err
+	}
+	return len(patterns), nil
+}
+
+// StartAbusePatternFeedSyncTask 在主节点上按配置的间隔同步远程特征源
+func StartAbusePatternFeedSyncTask() {
+	abusePatternFeedOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			ticker := time.NewTicker(abusePatternFeedCheckEvery)
+			defer ticker.Stop()
+			for range ticker.C {
+				runAbusePatternFeedSyncOnce()
+			}
+		})
+	})
+}
+
+func runAbusePatternFeedSyncOnce() {
+	setting := moderation_setting.GetAbusePatternSetting()
+	if !setting.Enabled || setting.FeedUrl == "" || setting.FeedSyncIntervalMinutes <= 0 {
+		return
+	}
+	if common.GetTimestamp()-setting.FeedLastSyncTime < int64(setting.FeedSyncIntervalMinutes)*60 {
+		return
+	}
+	if !abusePatternFeedRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer abusePatternFeedRunning.Store(false)
+
+	ctx := context.Background()
+	count, err := SyncAbusePatternFeed(ctx)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("abuse pattern feed sync failed: %v", err))
+		return
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("abuse pattern feed synced: %d patterns", count))
+}
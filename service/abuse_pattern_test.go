@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchAbusePatterns(t *testing.T) {
+	setting := moderation_setting.GetAbusePatternSetting()
+	origin := *setting
+	t.Cleanup(func() { *setting = origin })
+
+	setting.UseBuiltin = true
+	setting.Patterns = []moderation_setting.AbusePattern{{Pattern: "Evil Twin Mode", Category: "custom"}}
+	setting.FeedPatterns = nil
+
+	matched := MatchAbusePatterns("Hi! From now on DAN Mode Enabled and evil twin mode too.")
+	require.ElementsMatch(t, []moderation_setting.AbusePattern{
+		{Pattern: "dan mode enabled", Category: "jailbreak"},
+		{Pattern: "evil twin mode", Category: "custom"},
+	}, matched)
+
+	setting.UseBuiltin = false
+	require.Empty(t, MatchAbusePatterns("dan mode enabled"))
+}
+
+func TestParseAbusePatterns(t *testing.T) {
+	t.Parallel()
+
+	patterns, err := moderation_setting.ParseAbusePatterns([]byte("# comment\nfoo bar\n\n  baz  \n"))
+	require.NoError(t, err)
+	require.Equal(t, []moderation_setting.AbusePattern{{Pattern: "foo bar", Category: "custom"}, {Pattern: "baz", Category: "custom"}}, patterns)
+
+	patterns, err = moderation_setting.ParseAbusePatterns([]byte(`[{"pattern":"x","category":"jailbreak"}]`))
+	require.NoError(t, err)
+	require.Equal(t, []moderation_setting.AbusePattern{{Pattern: "x", Category: "jailbreak"}}, patterns)
+}
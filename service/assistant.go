@@ -0,0 +1,272 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// AssistantBinding 一次 Assistants API 请求所固定的渠道与调用方
+type AssistantBinding struct {
+	ChannelId int
+	UserId    int
+	TokenId   int
+	Group     string
+	// PreConsumedQuota 创建运行的请求预扣的额度，记录到本次创建的运行上，运行结束后结算
+	PreConsumedQuota int
+	// RunId 承接预扣额度的运行，为空表示上游未返回运行，预扣额度需要退还
+	RunId string
+}
+
+// assistantRunTerminalStatuses 运行的终止状态，进入这些状态后用量不再变化
+var assistantRunTerminalStatuses = []string{"completed", "failed", "cancelled", "expired", "incomplete"}
+
+// CalculateAssistantRunQuota 按运行用量计费：配置了按次价格时按次计费，否则按 token 倍率计费
+func CalculateAssistantRunQuota(modelName string, group string, promptTokens int, completionTokens int) int {
+	groupRatio := ratio_setting.GetGroupRatio(group)
+	if price, ok := ratio_setting.GetModelPrice(modelName, false); ok {
+		return int(math.Round(price * common.QuotaPerUnit * groupRatio))
+	}
+	modelRatio, _, _ := ratio_setting.GetModelRatio(modelName)
+	completionRatio := ratio_setting.GetCompletionRatio(modelName)
+	quota := int(math.Round((float64(promptTokens) + float64(completionTokens)*completionRatio) * modelRatio * groupRatio))
+	if quota <= 0 && modelRatio > 0 && promptTokens+completionTokens > 0 {
+		quota = 1
+	}
+	return quota
+}
+
+// ObserveAssistantResponse 处理上游返回的对象（或对象列表）：
+// 创建请求返回的助手、线程与运行记录到当前渠道，删除成功时移除映射，已结束的运行结算一次
+func ObserveAssistantResponse(c *gin.Context, binding *AssistantBinding, body []byte) {
+	result := gjson.ParseBytes(body)
+	if result.Get("object").String() == "list" {
+		result.Get("data").ForEach(func(_, item gjson.Result) bool {
+			observeAssistantObject(c, binding, item)
+			return true
+		})
+		return
+	}
+	observeAssistantObject(c, binding, result)
+}
+
+func observeAssistantObject(c *gin.Context, binding *AssistantBinding, obj gjson.Result) {
+	id := obj.Get("id").String()
+	if id == "" {
+		return
+	}
+	switch obj.Get("object").String() {
+	case model.AssistantObjectAssistant, model.AssistantObjectThread:
+		if c.Request.Method == http.MethodPost {
+			recordAssistantResource(binding, id, obj.Get("object").String(), "", obj.Get("model").String())
+		}
+	case model.AssistantObjectRun:
+		threadId := obj.Get("thread_id").String()
+		if c.Request.Method == http.MethodPost {
+			// 通过 /threads/runs 一并创建的线程也需要记录
+			recordAssistantResource(binding, threadId, model.AssistantObjectThread, "", "")
+			recordAssistantResource(binding, id, model.AssistantObjectRun, threadId, obj.Get("model").String())
+		}
+		if slices.Contains(assistantRunTerminalStatuses, obj.Get("status").String()) {
+			settleObservedAssistantRun(c, id, obj)
+		}
+	case "assistant.deleted", "thread.deleted":
+		if obj.Get("deleted").Bool() {
+			if err := model.DeleteAssistantResource(id); err != nil {
+				common.SysError("failed to delete assistant resource: " + err.Error())
+			}
+		}
+	}
+}
+
+func recordAssistantResource(binding *AssistantBinding, id string, object string, parentId string, modelName string) {
+	if id == "" {
+		return
+	}
+	resource := &model.AssistantResource{
+		ResourceId: id,
+		Object:     object,
+		ParentId:   parentId,
+		UserId:     binding.UserId,
+		TokenId:    binding.TokenId,
+		ChannelId:  binding.ChannelId,
+		Model:      modelName,
+		Group:      binding.Group,
+	}
+	// 预扣额度只记在本次请求创建的第一个运行上
+	claimed := object == model.AssistantObjectRun && binding.PreConsumedQuota > 0 && binding.RunId == ""
+	if claimed {
+		resource.PreConsumedQuota = binding.PreConsumedQuota
+	}
+	if err := resource.Insert(); err != nil {
+		common.SysError("failed to record assistant resource: " + err.Error())
+		return
+	}
+	if claimed {
+		binding.RunId = id
+	}
+}
+
+// PreConsumeAssistantRun 创建运行前预扣额度。运行在上游异步执行，结束时未必经过网关，
+// 因此先预扣，再由网关观察到的结果或后台轮询按实际用量结算
+func PreConsumeAssistantRun(c *gin.Context) (int, error) {
+	userId := c.GetInt("id")
+	userQuota, err := model.GetUserQuota(userId, false)
+	if err != nil {
+		return 0, err
+	}
+	quota := operation_setting.GetAssistantSetting().RunPreConsumeQuota
+	if userQuota <= 0 || userQuota < quota {
+		return 0, errors.New("user quota is not enough")
+	}
+	if quota <= 0 {
+		return 0, nil
+	}
+	if tokenId := c.GetInt("token_id"); tokenId > 0 {
+		token, err := model.GetTokenById(tokenId)
+		if err != nil {
+			return 0, err
+		}
+		if !token.UnlimitedQuota && token.RemainQuota < quota {
+			return 0, errors.New("token quota is not enough")
+		}
+		if err := model.DecreaseTokenQuota(token.Id, token.Key, quota); err != nil {
+			return 0, err
+		}
+	}
+	if err := model.DecreaseUserQuota(userId, quota, false); err != nil {
+		adjustAssistantTokenQuota(c.GetInt("token_id"), -quota)
+		return 0, err
+	}
+	return quota, nil
+}
+
+// RefundUnclaimedAssistantPreConsume 上游未返回运行（请求失败）时退还预扣的额度
+func RefundUnclaimedAssistantPreConsume(binding *AssistantBinding) {
+	if binding.PreConsumedQuota <= 0 || binding.RunId != "" {
+		return
+	}
+	adjustAssistantQuota(binding.UserId, binding.TokenId, -binding.PreConsumedQuota)
+	binding.PreConsumedQuota = 0
+}
+
+// adjustAssistantQuota 按差额调整用户与令牌额度，delta 为正时补扣，为负时退还
+func adjustAssistantQuota(userId int, tokenId int, delta int) {
+	if delta == 0 {
+		return
+	}
+	var err error
+	if delta > 0 {
+		err = model.DecreaseUserQuota(userId, delta, false)
+	} else {
+		err = model.IncreaseUserQuota(userId, -delta, false)
+	}
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to adjust user quota for assistant run: user_id=%d, error=%v", userId, err))
+	}
+	adjustAssistantTokenQuota(tokenId, delta)
+}
+
+func adjustAssistantTokenQuota(tokenId int, delta int) {
+	if tokenId <= 0 || delta == 0 {
+		return
+	}
+	token, err := model.GetTokenById(tokenId)
+	if err != nil {
+		return
+	}
+	if delta > 0 {
+		err = model.DecreaseTokenQuota(token.Id, token.Key, delta)
+	} else {
+		err = model.IncreaseTokenQuota(token.Id, token.Key, -delta)
+	}
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to adjust token quota for assistant run: token_id=%d, error=%v", tokenId, err))
+		return
+	}
+	if delta > 0 {
+		CheckTokenExhausted(token.Key)
+	}
+}
+
+// settleObservedAssistantRun 网关转发的响应中出现已结束的运行时立即结算
+func settleObservedAssistantRun(c *gin.Context, runId string, run gjson.Result) {
+	resource, err := model.GetAssistantResource(runId)
+	if err != nil || resource.Billed {
+		// 未经网关创建的运行不计费
+		return
+	}
+	if err := settleAssistantRun(resource, run); err != nil {
+		logger.LogError(c, "failed to settle assistant run: "+err.Error())
+	}
+}
+
+// settleAssistantRun 按上游报告的用量结算运行，与预扣额度多退少补，额度记在创建该运行的令牌上。
+// run 不存在表示上游已无法查询该运行，按预扣额度结算
+func settleAssistantRun(resource *model.AssistantResource, run gjson.Result) error {
+	billed, err := model.MarkAssistantRunBilled(resource.ResourceId)
+	if err != nil || !billed {
+		return err
+	}
+	modelName := run.Get("model").String()
+	if modelName == "" {
+		modelName = resource.Model
+	}
+	group := resource.Group
+	if group == "" {
+		group, _ = model.GetUserGroup(resource.UserId, false)
+	}
+	usage := run.Get("usage")
+	promptTokens := int(usage.Get("prompt_tokens").Int())
+	completionTokens := int(usage.Get("completion_tokens").Int())
+	status := run.Get("status").String()
+	quota := 0
+	switch {
+	case !run.Exists():
+		quota = resource.PreConsumedQuota
+		status = "unknown"
+	case usage.IsObject():
+		quota = CalculateAssistantRunQuota(modelName, group, promptTokens, completionTokens)
+	}
+	adjustAssistantQuota(resource.UserId, resource.TokenId, quota-resource.PreConsumedQuota)
+	if quota <= 0 {
+		return nil
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(resource.UserId, quota)
+	model.UpdateChannelUsedQuota(resource.ChannelId, quota)
+	threadId := run.Get("thread_id").String()
+	if threadId == "" {
+		threadId = resource.ParentId
+	}
+	model.RecordTaskBillingLog(model.RecordTaskBillingLogParams{
+		UserId:           resource.UserId,
+		LogType:          model.LogTypeConsume,
+		Content:          fmt.Sprintf("Assistants 运行 %s（线程 %s）", resource.ResourceId, threadId),
+		ChannelId:        resource.ChannelId,
+		ModelName:        modelName,
+		Quota:            quota,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TokenId:          resource.TokenId,
+		Group:            group,
+		Other: map[string]interface{}{
+			"assistant_run_id":   resource.ResourceId,
+			"assistant_id":       run.Get("assistant_id").String(),
+			"thread_id":          threadId,
+			"status":             status,
+			"pre_consumed_quota": resource.PreConsumedQuota,
+		},
+	})
+	return nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	assistantRunSettleBatchSize = 100
+	// assistantRunSettleMaxAge 超过该时长仍查询不到结果的运行按预扣额度结算，不再轮询
+	assistantRunSettleMaxAge = 24 * time.Hour
+)
+
+var (
+	assistantRunSettleOnce    sync.Once
+	assistantRunSettleRunning atomic.Bool
+)
+
+// StartAssistantRunSettlementTask 后台轮询未结算运行的状态，运行结束后按实际用量结算预扣额度。
+// 客户端创建运行后可能不再经过网关查询结果，仅靠网关观察响应会漏计费
+func StartAssistantRunSettlementTask() {
+	assistantRunSettleOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			for {
+				interval := operation_setting.GetAssistantSetting().RunPollIntervalSeconds
+				if interval <= 0 {
+					interval = 30
+				}
+				time.Sleep(time.Duration(interval) * time.Second)
+				runAssistantRunSettlementOnce()
+			}
+		})
+	})
+}
+
+func runAssistantRunSettlementOnce() {
+	if !operation_setting.GetAssistantSetting().Enabled {
+		return
+	}
+	if !assistantRunSettleRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer assistantRunSettleRunning.Store(false)
+	runs, err := model.GetUnbilledAssistantRuns(assistantRunSettleBatchSize)
+	if err != nil {
+		common.SysError("failed to list unsettled assistant runs: " + err.Error())
+		return
+	}
+	for _, resource := range runs {
+		pollAssistantRun(resource)
+	}
+}
+
+// pollAssistantRun 查询运行状态，已结束时结算；渠道不可用或运行已不存在且超过最长等待时间时按预扣额度结算
+func pollAssistantRun(resource *model.AssistantResource) {
+	expired := time.Since(time.Unix(resource.CreatedAt, 0)) > assistantRunSettleMaxAge
+	run, found, err := fetchAssistantRun(resource)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to poll assistant run %s: %s", resource.ResourceId, err.Error()))
+	}
+	switch {
+	case found && slices.Contains(assistantRunTerminalStatuses, run.Get("status").String()):
+	case expired && (!found || err != nil):
+		run = gjson.Result{}
+	default:
+		return
+	}
+	if err := settleAssistantRun(resource, run); err != nil {
+		common.SysError(fmt.Sprintf("failed to settle assistant run %s: %s", resource.ResourceId, err.Error()))
+	}
+}
+
+// fetchAssistantRun 从运行所在渠道查询运行对象，found 为 false 表示渠道或运行已不存在
+func fetchAssistantRun(resource *model.AssistantResource) (gjson.Result, bool, error) {
+	channel, err := model.GetChannelById(resource.ChannelId, true)
+	if err != nil {
+		return gjson.Result{}, false, nil
+	}
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return gjson.Result{}, false, apiErr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	url := fmt.Sprintf("%s/v1/threads/%s/runs/%s", strings.TrimSuffix(channel.GetBaseURL(), "/"), resource.ParentId, resource.ResourceId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return gjson.Result{}, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	client, err := NewProxyHttpClient(channel.GetSetting().Proxy)
+	if err != nil {
+		return gjson.Result{}, false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return gjson.Result{}, false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return gjson.Result{}, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return gjson.Result{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return gjson.Result{}, false, fmt.Errorf("upstream returned status code %d: %s", resp.StatusCode, string(body))
+	}
+	return gjson.ParseBytes(body), true, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newAssistantTestContext(userId int, tokenId int) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/threads/thread_1/runs", nil)
+	c.Set("id", userId)
+	c.Set("token_id", tokenId)
+	return c
+}
+
+func setAssistantPreConsumeQuota(t *testing.T, quota int) {
+	t.Helper()
+	setting := operation_setting.GetAssistantSetting()
+	original := *setting
+	t.Cleanup(func() { *setting = original })
+	setting.Enabled = true
+	setting.RunPreConsumeQuota = quota
+}
+
+func TestPreConsumeAssistantRunRefundsUnclaimed(t *testing.T) {
+	truncate(t)
+	setAssistantPreConsumeQuota(t, 3000)
+	seedUser(t, 1, 10000)
+	seedToken(t, 1, 1, "sk-assistant", 5000)
+
+	c := newAssistantTestContext(1, 1)
+	preConsumed, err := PreConsumeAssistantRun(c)
+	require.NoError(t, err)
+	require.Equal(t, 3000, preConsumed)
+	require.Equal(t, 7000, getUserQuota(t, 1))
+	require.Equal(t, 2000, getTokenRemainQuota(t, 1))
+
+	// 上游未返回运行时全额退还
+	binding := &AssistantBinding{UserId: 1, TokenId: 1, PreConsumedQuota: preConsumed}
+	RefundUnclaimedAssistantPreConsume(binding)
+	require.Equal(t, 10000, getUserQuota(t, 1))
+	require.Equal(t, 5000, getTokenRemainQuota(t, 1))
+
+	// 额度不足预扣金额时拒绝创建运行
+	_, err = PreConsumeAssistantRun(newAssistantTestContext(1, 1))
+	require.NoError(t, err)
+	_, err = PreConsumeAssistantRun(newAssistantTestContext(1, 1))
+	require.Error(t, err)
+}
+
+func TestAssistantRunSettlementPoll(t *testing.T) {
+	truncate(t)
+	setAssistantPreConsumeQuota(t, 3000)
+	seedUser(t, 1, 7000)
+	seedToken(t, 1, 1, "sk-assistant", 2000)
+
+	status := "in_progress"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/threads/thread_1/runs/run_1", r.URL.Path)
+		require.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"run_1","object":"thread.run","thread_id":"thread_1","model":"gpt-4o-mini","status":"` + status + `","usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`))
+	}))
+	defer server.Close()
+	baseURL := server.URL
+	require.NoError(t, model.DB.Create(&model.Channel{Id: 1, Name: "test_channel", Key: "sk-test", BaseURL: &baseURL, Status: common.ChannelStatusEnabled}).Error)
+	require.NoError(t, (&model.AssistantResource{
+		ResourceId:       "run_1",
+		Object:           model.AssistantObjectRun,
+		ParentId:         "thread_1",
+		UserId:           1,
+		TokenId:          1,
+		ChannelId:        1,
+		Model:            "gpt-4o-mini",
+		Group:            "default",
+		PreConsumedQuota: 3000,
+	}).Insert())
+
+	// 运行未结束时不结算
+	runAssistantRunSettlementOnce()
+	runs, err := model.GetUnbilledAssistantRuns(10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, 7000, getUserQuota(t, 1))
+
+	status = "completed"
+	runAssistantRunSettlementOnce()
+	runs, err = model.GetUnbilledAssistantRuns(10)
+	require.NoError(t, err)
+	require.Empty(t, runs)
+
+	// 按实际用量与预扣额度多退少补
+	quota := CalculateAssistantRunQuota("gpt-4o-mini", "default", 1000, 500)
+	require.Equal(t, 10000-quota, getUserQuota(t, 1))
+	require.Equal(t, 5000-quota, getTokenRemainQuota(t, 1))
+	log := getLastLog(t)
+	require.NotNil(t, log)
+	require.Equal(t, model.LogTypeConsume, log.Type)
+	require.Equal(t, quota, log.Quota)
+	require.Equal(t, 1000, log.PromptTokens)
+
+	// 已结算的运行不会重复计费
+	runAssistantRunSettlementOnce()
+	require.Equal(t, int64(1), countLogs(t))
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	batchTickInterval        = 2 * time.Second
+	batchMaintenanceInterval = time.Minute
+	batchRetryBaseDelay      = 15 * time.Second
+	batchMaxLineBytes        = 8 << 20
+)
+
+// BatchEndpoints 支持离线批量推理的接口
+var BatchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+	"/v1/moderations":      true,
+}
+
+var (
+	batchTaskOnce        sync.Once
+	batchRelayHandler    atomic.Value
+	batchInflight        atomic.Int64
+	batchMaintenanceLast atomic.Int64
+)
+
+// batchInputLine JSONL 中的一行，格式与 OpenAI Batch API 相同
+type batchInputLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// ParseBatchInput 解析并校验 JSONL 输入，每行需包含唯一的 custom_id，url 与批次接口一致且不能为流式请求
+func ParseBatchInput(reader io.Reader, endpoint string, maxLines int) ([]*model.BatchItem, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64<<10), batchMaxLineBytes)
+	seen := make(map[string]bool)
+	var items []*model.BatchItem
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if maxLines > 0 && len(items) >= maxLines {
+			return nil, fmt.Errorf("batch exceeds the limit of %d requests", maxLines)
+		}
+		var line batchInputLine
+		if err := common.Unmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("line %d: invalid json: %v", lineNo, err)
+		}
+		if line.CustomId == "" || len(line.CustomId) > 128 {
+			return nil, fmt.Errorf("line %d: custom_id is required and must be at most 128 characters", lineNo)
+		}
+		if seen[line.CustomId] {
+			return nil, fmt.Errorf("line %d: duplicate custom_id %q", lineNo, line.CustomId)
+		}
+		seen[line.CustomId] = true
+		if line.Method != "" && line.Method != http.MethodPost {
+			return nil, fmt.Errorf("line %d: only POST is supported", lineNo)
+		}
+		if line.Url != "" && line.Url != endpoint {
+			return nil, fmt.Errorf("line %d: url %q does not match batch endpoint %q", lineNo, line.Url, endpoint)
+		}
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if len(line.Body) == 0 || line.Body[0] != '{' || common.Unmarshal(line.Body, &body) != nil {
+			return nil, fmt.Errorf("line %d: body must be a json object", lineNo)
+		}
+		if body.Model == "" {
+			return nil, fmt.Errorf("line %d: body.model is required", lineNo)
+		}
+		if body.Stream {
+			return nil, fmt.Errorf("line %d: streaming is not supported in batches", lineNo)
+		}
+		items = append(items, &model.BatchItem{
+			LineIndex: len(items),
+			CustomId:  line.CustomId,
+			Body:      append(json.RawMessage(nil), line.Body...),
+			Status:    model.BatchItemStatusPending,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch input: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("batch input is empty")
+	}
+	return items, nil
+}
+
+// BatchOutputLine 结果文件中的一行，格式与 OpenAI Batch API 相同
+type BatchOutputLine struct {
+	Id       string               `json:"id"`
+	CustomId string               `json:"custom_id"`
+	Response *BatchOutputResponse `json:"response"`
+	Error    *BatchOutputError    `json:"error"`
+}
+
+type BatchOutputResponse struct {
+	StatusCode int             `json:"status_code"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type BatchOutputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewBatchOutputLine(batch *model.Batch, item *model.BatchItem) BatchOutputLine {
+	line := BatchOutputLine{
+		Id:       fmt.Sprintf("%s_req_%d", batch.BatchId, item.LineIndex),
+		CustomId: item.CustomId,
+	}
+	if item.StatusCode > 0 {
+		line.Response = &BatchOutputResponse{StatusCode: item.StatusCode, Body: item.Response}
+	}
+	if item.Status != model.BatchItemStatusSucceeded {
+		line.Error = &BatchOutputError{Code: "request_failed", Message: item.Error}
+	}
+	return line
+}
+
+// SetBatchRelayHandler 设置执行批量请求的 HTTP handler（即网关自身），请求在进程内完整经过鉴权、限流、分发与计费
+func SetBatchRelayHandler(handler http.Handler) {
+	batchRelayHandler.Store(handler)
+}
+
+// StartBatchInferenceTask 启动离线批量推理调度，仅在主节点运行
+func StartBatchInferenceTask() {
+	batchTaskOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			if err := model.ResetRunningBatchItems(); err != nil {
+				common.SysError("failed to reset running batch items: " + err.Error())
+			}
+			logger.LogInfo(context.Background(), fmt.Sprintf("batch inference task started: tick=%s", batchTickInterval))
+			ticker := time.NewTicker(batchTickInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				runBatchSchedulerOnce()
+			}
+		})
+	})
+}
+
+func runBatchSchedulerOnce() {
+	setting := operation_setting.GetBatchSetting()
+	handler, _ := batchRelayHandler.Load().(http.Handler)
+	if !setting.Enabled || handler == nil {
+		return
+	}
+	now := time.Now()
+	if now.Unix()-batchMaintenanceLast.Load() >= int64(batchMaintenanceInterval.Seconds()) {
+		batchMaintenanceLast.Store(now.Unix())
+		runBatchMaintenance(setting, now)
+	}
+
+	free := int64(setting.MaxConcurrency) - batchInflight.Load()
+	if free <= 0 {
+		return
+	}
+	items, err := model.ClaimBatchItems(now.Unix(), int(free))
+	if err != nil {
+		common.SysError("failed to claim batch items: " + err.Error())
+		return
+	}
+	for _, item := range items {
+		item := item
+		batchInflight.Add(1)
+		gopool.Go(func() {
+			defer batchInflight.Add(-1)
+			executeBatchItem(handler, setting, item)
+		})
+	}
+}
+
+func runBatchMaintenance(setting *operation_setting.BatchSetting, now time.Time) {
+	if n, err := model.ExpireBatches(now.Unix()); err != nil {
+		common.SysError("failed to expire batches: " + err.Error())
+	} else if n > 0 {
+		common.SysLog(fmt.Sprintf("expired %d batches", n))
+	}
+	if setting.RetentionDays > 0 {
+		cutoff := now.Add(-time.Duration(setting.RetentionDays) * 24 * time.Hour).Unix()
+		if err := model.DeleteFinishedBatchesBefore(cutoff); err != nil {
+			common.SysError("failed to cleanup batches: " + err.Error())
+		}
+		if err := CleanupUserFilesBefore("batch", cutoff); err != nil {
+			common.SysError("failed to cleanup batch files: " + err.Error())
+		}
+	}
+}
+
+// batchResponseWriter 收集进程内请求的响应
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *batchResponseWriter) Flush() {}
+
+func executeBatchItem(handler http.Handler, setting *operation_setting.BatchSetting, item *model.BatchItem) {
+	batch, err := model.GetBatchById(item.BatchId)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to load batch #%d: %s", item.BatchId, err.Error()))
+		return
+	}
+	item.Attempts++
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil || token.Status != common.TokenStatusEnabled {
+		item.Status = model.BatchItemStatusFailed
+		item.Error = "the token used to create this batch is no longer available"
+		saveBatchItemResult(item)
+		return
+	}
+
+	ctx := relaycommon.WithBatchRequest(context.Background(), relaycommon.BatchRequestInfo{
+		BatchId:       batch.BatchId,
+		DiscountRatio: setting.DiscountRatio,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, batch.Endpoint, bytes.NewReader(item.Body))
+	if err != nil {
+		item.Status = model.BatchItemStatusFailed
+		item.Error = err.Error()
+		saveBatchItemResult(item)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-"+token.Key)
+	req.RemoteAddr = net.JoinHostPort(common.GetStringIfEmpty(batch.ClientIp, "127.0.0.1"), "0")
+
+	writer := &batchResponseWriter{header: make(http.Header)}
+	handler.ServeHTTP(writer, req)
+
+	item.StatusCode = writer.status
+	item.Response = batchResponseBody(writer.body.Bytes())
+	if writer.status >= http.StatusOK && writer.status < http.StatusMultipleChoices {
+		item.Status = model.BatchItemStatusSucceeded
+		item.Error = ""
+		saveBatchItemResult(item)
+		return
+	}
+	item.Error = batchErrorMessage(writer)
+	if isBatchRetryableStatus(writer.status) && item.Attempts < setting.MaxAttempts {
+		next := time.Now().Add(batchRetryDelay(writer.header, item.Attempts)).Unix()
+		if err := model.RetryBatchItem(item, next, item.Error); err != nil {
+			common.SysError(fmt.Sprintf("failed to requeue batch item #%d: %s", item.Id, err.Error()))
+		}
+		return
+	}
+	item.Status = model.BatchItemStatusFailed
+	saveBatchItemResult(item)
+}
+
+func saveBatchItemResult(item *model.BatchItem) {
+	if err := model.CompleteBatchItem(item); err != nil {
+		common.SysError(fmt.Sprintf("failed to save batch item #%d: %s", item.Id, err.Error()))
+	}
+}
+
+// isBatchRetryableStatus 限流、渠道饱和与上游错误可稍后重试，请求本身的错误直接失败
+func isBatchRetryableStatus(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= http.StatusInternalServerError
+}
+
+// batchRetryDelay 优先使用 Retry-After，否则按尝试次数指数退避
+func batchRetryDelay(header http.Header, attempts int) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	return batchRetryBaseDelay << (attempts - 1)
+}
+
+func batchResponseBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return append(json.RawMessage(nil), body...)
+	}
+	encoded, _ := common.Marshal(string(body))
+	return encoded
+}
+
+func batchErrorMessage(writer *batchResponseWriter) string {
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if common.Unmarshal(writer.body.Bytes(), &payload) == nil && payload.Error.Message != "" {
+		return payload.Error.Message
+	}
+	return fmt.Sprintf("request failed with status code %d", writer.status)
+}
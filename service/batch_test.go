@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchInput(t *testing.T) {
+	input := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[]}}
+
+{"custom_id":"b","body":{"model":"gpt-4o","messages":[]}}
+`
+	items, err := ParseBatchInput(strings.NewReader(input), "/v1/chat/completions", 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "b", items[1].CustomId)
+	require.Equal(t, 1, items[1].LineIndex)
+
+	cases := map[string]string{
+		"duplicate":     `{"custom_id":"a","body":{"model":"m"}}` + "\n" + `{"custom_id":"a","body":{"model":"m"}}`,
+		"missing id":    `{"body":{"model":"m"}}`,
+		"url mismatch":  `{"custom_id":"a","url":"/v1/embeddings","body":{"model":"m"}}`,
+		"stream":        `{"custom_id":"a","body":{"model":"m","stream":true}}`,
+		"non-object":    `{"custom_id":"a","body":[1]}`,
+		"missing model": `{"custom_id":"a","body":{}}`,
+		"empty":         "\n\n",
+	}
+	for name, input := range cases {
+		_, err := ParseBatchInput(strings.NewReader(input), "/v1/chat/completions", 10)
+		require.Error(t, err, name)
+	}
+
+	_, err = ParseBatchInput(strings.NewReader(`{"custom_id":"a","body":{"model":"m"}}`+"\n"+`{"custom_id":"b","body":{"model":"m"}}`), "/v1/chat/completions", 1)
+	require.Error(t, err)
+}
+
+func TestBatchRetryDelay(t *testing.T) {
+	header := http.Header{}
+	require.Equal(t, 15*time.Second, batchRetryDelay(header, 1))
+	require.Equal(t, 60*time.Second, batchRetryDelay(header, 3))
+	header.Set("Retry-After", "7")
+	require.Equal(t, 7*time.Second, batchRetryDelay(header, 3))
+	require.True(t, isBatchRetryableStatus(http.StatusTooManyRequests))
+	require.False(t, isBatchRetryableStatus(http.StatusBadRequest))
+}
@@ -47,6 +47,7 @@ func (s *BillingSession) Settle(actualQuota int) error {
 	delta := actualQuota - s.preConsumedQuota
 	if delta == 0 {
 		s.settled = true
+		s.checkTokenExhausted()
 		return nil
 	}
 	// 1) 调整资金来源（仅在尚未提交时执行，防止重复调用）
@@ -75,9 +76,16 @@ func (s *BillingSession) Settle(actualQuota int) error {
 		s.relayInfo.SubscriptionPostDelta += int64(delta)
 	}
 	s.settled = true
+	s.checkTokenExhausted()
 	return tokenErr
 }
 
+func (s *BillingSession) checkTokenExhausted() {
+	if !s.relayInfo.IsPlayground && !s.relayInfo.TokenUnlimited {
+		CheckTokenExhausted(s.relayInfo.TokenKey)
+	}
+}
+
 // Refund 退还所有预扣费，幂等安全，异步执行。
 func (s *BillingSession) Refund(c *gin.Context) {
 	s.mu.Lock()
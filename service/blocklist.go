@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	blocklistRegexCache sync.Map // map[string]*regexp.Regexp
+	blocklistHitCounter sync.Map // map[string]*atomic.Int64，按规则名称计数
+)
+
+// BlocklistOutcome 一次请求（或一次响应）中黑名单规则的命中情况
+type BlocklistOutcome struct {
+	Rejected string   // 命中的 reject 规则名称
+	Hits     []string // 命中的规则名称，去重
+}
+
+func (o *BlocklistOutcome) addHit(rule moderation_setting.BlocklistRule) {
+	if rule.Action == moderation_setting.BlocklistActionReject && o.Rejected == "" {
+		o.Rejected = rule.Name
+	}
+	if !common.StringsContains(o.Hits, rule.Name) {
+		o.Hits = append(o.Hits, rule.Name)
+	}
+}
+
+// BlocklistRuleStat 规则命中计数（进程内统计，重启后清零）
+type BlocklistRuleStat struct {
+	Name string `json:"name"`
+	Hits int64  `json:"hits"`
+}
+
+func compileBlocklistRule(rule moderation_setting.BlocklistRule) (*regexp.Regexp, error) {
+	patterns := make([]string, 0, len(rule.Patterns))
+	for _, pattern := range rule.Patterns {
+		if pattern == "" {
+			continue
+		}
+		if rule.Type == moderation_setting.BlocklistTypeTerm {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		patterns = append(patterns, "(?:"+pattern+")")
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	expr := strings.Join(patterns, "|")
+	if !rule.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	if re, ok := blocklistRegexCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	blocklistRegexCache.Store(expr, re)
+	return re, nil
+}
+
+// ApplyBlocklistText 对文本执行分组在指定阶段的黑名单规则，返回替换后的文本，命中情况写入 outcome
+func ApplyBlocklistText(group string, stage string, text string, outcome *BlocklistOutcome) string {
+	if text == "" {
+		return text
+	}
+	for _, rule := range moderation_setting.BlocklistRulesFor(group, stage) {
+		re, err := compileBlocklistRule(rule)
+		if err != nil || re == nil {
+			continue
+		}
+		if !re.MatchString(text) {
+			continue
+		}
+		outcome.addHit(rule)
+		if rule.Action == moderation_setting.BlocklistActionReplace {
+			replacement := rule.GetReplacement()
+			if rule.Type == moderation_setting.BlocklistTypeTerm {
+				text = re.ReplaceAllLiteralString(text, replacement)
+			} else {
+				text = re.ReplaceAllString(text, replacement)
+			}
+		}
+	}
+	return text
+}
+
+// ApplyBlocklistJSON 对 JSON 中承载文本的字段执行黑名单规则
+func ApplyBlocklistJSON(group string, stage string, body []byte, outcome *BlocklistOutcome) ([]byte, bool, error) {
+	keys := promptTextKeys
+	if stage == moderation_setting.StageOutput {
+		keys = completionTextKeys
+	}
+	return rewriteJSONStrings(body, keys, func(text string) string {
+		return ApplyBlocklistText(group, stage, text, outcome)
+	})
+}
+
+// ApplyBlocklistSSE 对一段 SSE 数据中的每个 data 事件执行输出阶段的黑名单规则。
+// 按分片处理，跨分片的命中内容无法识别。
+func ApplyBlocklistSSE(group string, chunk []byte, outcome *BlocklistOutcome) []byte {
+	lines := bytes.Split(chunk, []byte("\n"))
+	changed := false
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if !bytes.HasPrefix(trimmed, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("data:")))
+		rewritten, ok, err := ApplyBlocklistJSON(group, moderation_setting.StageOutput, payload, outcome)
+		if err != nil || !ok {
+			continue
+		}
+		lines[i] = append([]byte("data: "), rewritten...)
+		changed = true
+	}
+	if !changed {
+		return chunk
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// HandleBlocklistOutcome 记录命中计数与日志，allowReject 为 true 且命中 reject 规则时返回错误
+func HandleBlocklistOutcome(c *gin.Context, stage string, outcome *BlocklistOutcome, allowReject bool) *types.NewAPIError {
+	if outcome == nil || len(outcome.Hits) == 0 {
+		return nil
+	}
+	for _, name := range outcome.Hits {
+		counter, _ := blocklistHitCounter.LoadOrStore(name, &atomic.Int64{})
+		counter.(*atomic.Int64).Add(1)
+	}
+	var hits []string
+	if v, ok := common.GetContextKey(c, constant.ContextKeyBlocklistHits); ok {
+		hits, _ = v.([]string)
+	}
+	common.SetContextKey(c, constant.ContextKeyBlocklistHits, append(hits, outcome.Hits...))
+
+	logger.LogWarn(c, fmt.Sprintf("blocklist %s hit by rules: %s", stage, strings.Join(outcome.Hits, ",")))
+	if outcome.Rejected != "" && allowReject {
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("content rejected by blocklist rule (%s)", outcome.Rejected),
+			types.ErrorCodeBlocklistRejected, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	return nil
+}
+
+// ApplyRequestBlocklist 对请求体执行输入阶段的黑名单规则，需在解析请求之前调用
+func ApplyRequestBlocklist(c *gin.Context) *types.NewAPIError {
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	if len(moderation_setting.BlocklistRulesFor(group, moderation_setting.StageInput)) == 0 ||
+		!strings.Contains(c.Request.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	outcome := &BlocklistOutcome{}
+	rewritten, changed, err := ApplyBlocklistJSON(group, moderation_setting.StageInput, body, outcome)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	if apiErr := HandleBlocklistOutcome(c, moderation_setting.StageInput, outcome, true); apiErr != nil {
+		return apiErr
+	}
+	if changed {
+		if err = common.ReplaceBodyStorage(c, rewritten); err != nil {
+			return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+		}
+	}
+	return nil
+}
+
+// GetBlocklistHits 返回本次请求命中的黑名单规则，用于写入消费日志
+func GetBlocklistHits(c *gin.Context) []string {
+	hits, _ := common.GetContextKeyType[[]string](c, constant.ContextKeyBlocklistHits)
+	return hits
+}
+
+// GetBlocklistStats 返回当前配置中各规则的命中次数
+func GetBlocklistStats() []BlocklistRuleStat {
+	rules := moderation_setting.GetBlocklistSetting().Rules
+	stats := make([]BlocklistRuleStat, 0, len(rules))
+	for _, rule := range rules {
+		stat := BlocklistRuleStat{Name: rule.Name}
+		if counter, ok := blocklistHitCounter.Load(rule.Name); ok {
+			stat.Hits = counter.(*atomic.Int64).Load()
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// ResetBlocklistStats 清空命中计数，ruleName 为空时清空全部
+func ResetBlocklistStats(ruleName string) {
+	if ruleName != "" {
+		blocklistHitCounter.Delete(ruleName)
+		return
+	}
+	blocklistHitCounter.Range(func(key, _ any) bool {
+		blocklistHitCounter.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyBlocklistPerGroup(t *testing.T) {
+	setting := moderation_setting.GetBlocklistSetting()
+	origin := *setting
+	t.Cleanup(func() { *setting = origin })
+
+	setting.Enabled = true
+	setting.Rules = []moderation_setting.BlocklistRule{
+		{Name: "secret-term", Enabled: true, Type: moderation_setting.BlocklistTypeTerm, Patterns: []string{"project x"}, Stage: moderation_setting.StageBoth, Action: moderation_setting.BlocklistActionReplace, Replacement: "[redacted]"},
+		{Name: "api-key", Enabled: true, Type: moderation_setting.BlocklistTypeRegex, Patterns: []string{`sk-[a-z0-9]{8,}`}, Stage: moderation_setting.StageInput, Action: moderation_setting.BlocklistActionReject, Groups: []string{"default"}},
+	}
+
+	outcome := &BlocklistOutcome{}
+	text := ApplyBlocklistText("vip", moderation_setting.StageInput, "about Project X and sk-abcdefgh123", outcome)
+	require.Equal(t, "about [redacted] and sk-abcdefgh123", text)
+	require.Empty(t, outcome.Rejected)
+	require.Equal(t, []string{"secret-term"}, outcome.Hits)
+
+	outcome = &BlocklistOutcome{}
+	body := []byte(`{"model":"project x","messages":[{"role":"user","content":"use sk-abcdefgh123 for project x"}]}`)
+	rewritten, changed, err := ApplyBlocklistJSON("default", moderation_setting.StageInput, body, outcome)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "project x", gjson.GetBytes(rewritten, "model").String())
+	require.Equal(t, "use sk-abcdefgh123 for [redacted]", gjson.GetBytes(rewritten, "messages.0.content").String())
+	require.Equal(t, "api-key", outcome.Rejected)
+
+	outcome = &BlocklistOutcome{}
+	chunk := []byte("data: {\"choices\":[{\"delta\":{\"content\":\"PROJECT X ready\"}}]}\n\ndata: [DONE]\n\n")
+	require.Equal(t, "data: {\"choices\":[{\"delta\":{\"content\":\"[redacted] ready\"}}]}\n\ndata: [DONE]\n\n", string(ApplyBlocklistSSE("vip", chunk, outcome)))
+}
+
+func TestValidateBlocklistRules(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, moderation_setting.ValidateBlocklistRules(`[{"name":"a","type":"regex","patterns":["^foo"],"stage":"input","action":"log"}]`))
+	require.Error(t, moderation_setting.ValidateBlocklistRules(`[{"name":"a","type":"regex","patterns":["("],"stage":"input","action":"log"}]`))
+	require.Error(t, moderation_setting.ValidateBlocklistRules(`[{"name":"a","type":"term","patterns":["x"],"stage":"input","action":"drop"}]`))
+}
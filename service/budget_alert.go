@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	BudgetScopeUser  = "user"
+	BudgetScopeToken = "token"
+
+	budgetAlertKeyPrefix = "new-api:budget_alert:v1:"
+)
+
+var (
+	budgetAlertLock   sync.Mutex
+	budgetAlertSentAt = make(map[string]time.Time)
+)
+
+// checkBudgetAlerts 累计用户与令牌本月消费，越过预算阈值时通知用户
+func checkBudgetAlerts(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, quota int) {
+	setting := operation_setting.GetBudgetAlertSetting()
+	if !setting.Enabled || quota <= 0 || relayInfo == nil {
+		return
+	}
+	tokenBudget := ctx.GetInt("token_monthly_budget_quota")
+	tokenName := ctx.GetString("token_name")
+	userName := common.GetContextKeyString(ctx, constant.ContextKeyUserName)
+	gopool.Go(func() {
+		userSetting := relayInfo.UserSetting
+		thresholds := setting.Thresholds
+		if len(userSetting.BudgetAlertThresholds) > 0 {
+			thresholds = userSetting.BudgetAlertThresholds
+		}
+		userBudget := setting.DefaultMonthlyBudgetQuota
+		if userSetting.MonthlyBudgetQuota > 0 {
+			userBudget = userSetting.MonthlyBudgetQuota
+		}
+		checkBudgetScope(relayInfo, BudgetScopeUser, relayInfo.UserId, userName, userBudget, thresholds, quota, setting.CooldownMinutes)
+		if relayInfo.TokenId > 0 {
+			checkBudgetScope(relayInfo, BudgetScopeToken, relayInfo.TokenId, tokenName, tokenBudget, thresholds, quota, setting.CooldownMinutes)
+		}
+	})
+}
+
+func checkBudgetScope(relayInfo *relaycommon.RelayInfo, scope string, id int, name string, budget int, thresholds []int, quota int, cooldownMinutes int) {
+	if budget <= 0 || id <= 0 {
+		return
+	}
+	spent, err := model.IncrMonthlyQuota(scope, id, quota)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to record monthly quota for %s %d: %s", scope, id, err.Error()))
+		return
+	}
+	threshold, ok := crossedBudgetThreshold(spent-int64(quota), spent, int64(budget), thresholds)
+	if !ok {
+		return
+	}
+	alertKey := fmt.Sprintf("%s:%d:%s:%d", scope, id, time.Now().Format("200601"), threshold)
+	if !acquireBudgetAlert(alertKey, time.Duration(cooldownMinutes)*time.Minute) {
+		return
+	}
+	err = NotifyUserWithTemplate(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NotifyTypeBudgetAlert, NotifyTemplateBudgetAlert, map[string]any{
+		"Scope":   scope,
+		"Name":    name,
+		"Percent": threshold,
+		"Spent":   logger.FormatQuota(int(spent)),
+		"Budget":  logger.FormatQuota(budget),
+	})
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to send budget alert to user %d: %s", relayInfo.UserId, err.Error()))
+	}
+}
+
+// crossedBudgetThreshold 返回本次消费越过的最高阈值，before 与 after 为消费前后的本月累计额度
+func crossedBudgetThreshold(before int64, after int64, budget int64, thresholds []int) (int, bool) {
+	if budget <= 0 {
+		return 0, false
+	}
+	sorted := append([]int(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	for _, threshold := range sorted {
+		if threshold <= 0 {
+			continue
+		}
+		line := int64(threshold) * budget
+		if before*100 < line && after*100 >= line {
+			return threshold, true
+		}
+	}
+	return 0, false
+}
+
+// acquireBudgetAlert 冷却期内同一提醒只发送一次，启用 Redis 时在多节点间共享
+func acquireBudgetAlert(key string, cooldown time.Duration) bool {
+	if cooldown <= 0 {
+		return true
+	}
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		ok, err := common.RDB.SetNX(ctx, budgetAlertKeyPrefix+key, 1, cooldown).Result()
+		if err != nil {
+			common.SysError("failed to check budget alert cooldown: " + err.Error())
+			return false
+		}
+		return ok
+	}
+	now := time.Now()
+	budgetAlertLock.Lock()
+	defer budgetAlertLock.Unlock()
+	for k, sentAt := range budgetAlertSentAt {
+		if now.Sub(sentAt) >= cooldown {
+			delete(budgetAlertSentAt, k)
+		}
+	}
+	if sentAt, ok := budgetAlertSentAt[key]; ok && now.Sub(sentAt) < cooldown {
+		return false
+	}
+	budgetAlertSentAt[key] = now
+	return true
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossedBudgetThreshold(t *testing.T) {
+	thresholds := []int{50, 80, 100}
+
+	threshold, ok := crossedBudgetThreshold(400, 500, 1000, thresholds)
+	require.True(t, ok)
+	require.Equal(t, 50, threshold)
+
+	_, ok = crossedBudgetThreshold(500, 600, 1000, thresholds)
+	require.False(t, ok)
+
+	// 一次消费越过多个阈值时只提醒最高的一个
+	threshold, ok = crossedBudgetThreshold(100, 1200, 1000, thresholds)
+	require.True(t, ok)
+	require.Equal(t, 100, threshold)
+
+	_, ok = crossedBudgetThreshold(0, 100, 0, thresholds)
+	require.False(t, ok)
+}
+
+func TestAcquireBudgetAlertCooldown(t *testing.T) {
+	key := "user:1:202601:80"
+	require.True(t, acquireBudgetAlert(key, time.Hour))
+	require.False(t, acquireBudgetAlert(key, time.Hour))
+	require.True(t, acquireBudgetAlert("user:1:202601:100", time.Hour))
+	require.True(t, acquireBudgetAlert(key, 0))
+}
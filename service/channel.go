@@ -27,18 +27,44 @@ func DisableChannel(channelError types.ChannelError, reason string) {
 
 	success := model.UpdateChannelStatus(channelError.ChannelId, channelError.UsingKey, common.ChannelStatusAutoDisabled, reason)
 	if success {
-		subject := fmt.Sprintf("通道「%s」（#%d）已被禁用", channelError.ChannelName, channelError.ChannelId)
-		content := fmt.Sprintf("通道「%s」（#%d）已被禁用，原因：%s", channelError.ChannelName, channelError.ChannelId, reason)
-		NotifyRootUser(formatNotifyType(channelError.ChannelId, common.ChannelStatusAutoDisabled), subject, content)
+		NotifyRootUser(formatNotifyType(channelError.ChannelId, common.ChannelStatusAutoDisabled), NotifyTemplateChannelDisabled, map[string]any{
+			"ChannelId":   channelError.ChannelId,
+			"ChannelName": channelError.ChannelName,
+			"Reason":      reason,
+		})
+		EmitEvent(EventChannelAutoDisabled, map[string]any{
+			"channel_id":   channelError.ChannelId,
+			"channel_name": channelError.ChannelName,
+			"channel_type": channelError.ChannelType,
+			"reason":       reason,
+		})
 	}
 }
 
+// CooldownChannelKey 多 Key 渠道命中冷却状态码时让当前 Key 进入冷却而不是禁用，返回是否已冷却
+func CooldownChannelKey(channelError types.ChannelError, err *types.NewAPIError) bool {
+	if !channelError.IsMultiKey || channelError.UsingKey == "" || err == nil {
+		return false
+	}
+	if !operation_setting.GetMultiKeyCooldownSetting().ShouldCooldown(err.StatusCode) {
+		return false
+	}
+	cooldown := model.CooldownChannelKey(channelError.ChannelId, channelError.UsingKey, err.StatusCode, err.MaskSensitiveErrorWithStatusCode())
+	common.SysLog(fmt.Sprintf("通道「%s」（#%d）的密钥因状态码 %d 进入冷却，连续第 %d 次，冷却至 %d", channelError.ChannelName, channelError.ChannelId, err.StatusCode, cooldown.Strikes, cooldown.Until))
+	return true
+}
+
 func EnableChannel(channelId int, usingKey string, channelName string) {
 	success := model.UpdateChannelStatus(channelId, usingKey, common.ChannelStatusEnabled, "")
 	if success {
-		subject := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
-		content := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
-		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusEnabled), subject, content)
+		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusEnabled), NotifyTemplateChannelEnabled, map[string]any{
+			"ChannelId":   channelId,
+			"ChannelName": channelName,
+		})
+		EmitEvent(EventChannelRecovered, map[string]any{
+			"channel_id":   channelId,
+			"channel_name": channelName,
+		})
 	}
 }
 
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"hash/fnv"
 	"regexp"
@@ -302,16 +304,23 @@ func extractChannelAffinityValue(c *gin.Context, src operation_setting.ChannelAf
 			return ""
 		}
 		return strings.TrimSpace(c.GetString(src.Key))
-	case "gjson":
-		if src.Path == "" {
+	case "header":
+		if src.Key == "" || c.Request == nil {
 			return ""
 		}
-		storage, err := common.GetBodyStorage(c)
-		if err != nil {
+		return strings.TrimSpace(c.Request.Header.Get(src.Key))
+	case "first_message":
+		body := getChannelAffinityRequestBody(c)
+		if len(body) == 0 {
+			return ""
+		}
+		return firstMessageFingerprint(body)
+	case "gjson":
+		if src.Path == "" {
 			return ""
 		}
-		body, err := storage.Bytes()
-		if err != nil || len(body) == 0 {
+		body := getChannelAffinityRequestBody(c)
+		if len(body) == 0 {
 			return ""
 		}
 		res := gjson.GetBytes(body, src.Path)
@@ -329,6 +338,40 @@ func extractChannelAffinityValue(c *gin.Context, src operation_setting.ChannelAf
 	}
 }
 
+func getChannelAffinityRequestBody(c *gin.Context) []byte {
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return nil
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// firstMessageAffinityPaths 依次尝试的首条消息位置：OpenAI/Claude 的首条用户消息、Gemini contents、Responses input
+var firstMessageAffinityPaths = []string{
+	`messages.#(role=="user").content`,
+	"contents.0",
+	"input.0",
+	"input",
+	"prompt",
+}
+
+// firstMessageFingerprint 客户端未提供会话标识时，以首条用户消息的哈希作为会话标识，同一对话的后续轮次首条消息不变
+func firstMessageFingerprint(body []byte) string {
+	for _, path := range firstMessageAffinityPaths {
+		res := gjson.GetBytes(body, path)
+		if !res.Exists() || strings.TrimSpace(res.Raw) == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(res.Raw))
+		return hex.EncodeToString(sum[:16])
+	}
+	return ""
+}
+
 func buildChannelAffinityCacheKeySuffix(rule operation_setting.ChannelAffinityRule, modelName string, usingGroup string, affinityValue string) string {
 	parts := make([]string, 0, 4)
 	if rule.IncludeRuleName && rule.Name != "" {
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newChannelAffinityKeySourceContext(body string) *gin.Context {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	ctx.Request.Header.Set("X-Session-Id", " sess-1 ")
+	return ctx
+}
+
+func TestExtractChannelAffinityValueHeader(t *testing.T) {
+	ctx := newChannelAffinityKeySourceContext(`{}`)
+	value := extractChannelAffinityValue(ctx, operation_setting.ChannelAffinityKeySource{Type: "header", Key: "X-Session-Id"})
+	require.Equal(t, "sess-1", value)
+}
+
+func TestExtractChannelAffinityValueFirstMessage(t *testing.T) {
+	source := operation_setting.ChannelAffinityKeySource{Type: "first_message"}
+	firstTurn := newChannelAffinityKeySourceContext(`{"model":"gpt-4o","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"}]}`)
+	secondTurn := newChannelAffinityKeySourceContext(`{"model":"gpt-4o","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"},{"role":"assistant","content":"hi"},{"role":"user","content":"how are you"}]}`)
+	other := newChannelAffinityKeySourceContext(`{"model":"gpt-4o","messages":[{"role":"user","content":"another topic"}]}`)
+
+	value := extractChannelAffinityValue(firstTurn, source)
+	require.NotEmpty(t, value)
+	require.Equal(t, value, extractChannelAffinityValue(secondTurn, source))
+	require.NotEqual(t, value, extractChannelAffinityValue(other, source))
+
+	gemini := newChannelAffinityKeySourceContext(`{"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`)
+	require.NotEmpty(t, extractChannelAffinityValue(gemini, source))
+	require.Empty(t, extractChannelAffinityValue(newChannelAffinityKeySourceContext(`{}`), source))
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	channelConcurrencyKeyPrefix = "new-api:channel_concurrency:v2:"
+	// 单个名额的兜底过期时间：每个请求独立占用一个名额，进程异常退出或漏释放时该名额到期后自动失效，
+	// 不会因为其他请求持续占用而一直续期
+	channelConcurrencySlotTTL = time.Hour
+	// channelConcurrencyPollInterval 排队等待时检查名额的间隔
+	channelConcurrencyPollInterval = 100 * time.Millisecond
+)
+
+var (
+	channelConcurrencyLock  sync.Mutex
+	channelConcurrencySlots = make(map[int]map[string]time.Time)
+)
+
+// AcquireChannelConcurrency 占用渠道的并发名额，渠道未配置 max_concurrency 时不限制。
+// 名额已满时按 concurrency_queue_seconds 排队等待，超时后返回渠道错误，由上层重试其他渠道；
+// 成功时返回的 release 需在请求结束后调用（建议 defer）。启用 Redis 时在多节点间共享名额
+func AcquireChannelConcurrency(c *gin.Context, channel *model.Channel) (release func(), newAPIError *types.NewAPIError) {
+	settings := channel.GetOtherSettings()
+	if settings.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+	deadline := time.Now().Add(time.Duration(settings.ConcurrencyQueueSeconds) * time.Second)
+	for {
+		slot := common.GetUUID()
+		count, err := acquireChannelConcurrencySlot(channel.Id, slot)
+		if err != nil {
+			// 计数失败时不阻塞请求
+			common.SysError(fmt.Sprintf("failed to acquire channel concurrency: channel_id=%d, error=%v", channel.Id, err))
+			return func() {}, nil
+		}
+		if count <= settings.MaxConcurrency {
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					releaseChannelConcurrencySlot(channel.Id, slot)
+					notifyChannelSlotReleased()
+				})
+			}, nil
+		}
+		releaseChannelConcurrencySlot(channel.Id, slot)
+		if !time.Now().Before(deadline) {
+			return nil, types.NewErrorWithStatusCode(
+				fmt.Errorf("channel #%d reached its concurrency limit (%d)", channel.Id, settings.MaxConcurrency),
+				types.ErrorCodeChannelConcurrencyLimited,
+				http.StatusTooManyRequests,
+			)
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, types.NewErrorWithStatusCode(c.Request.Context().Err(), types.ErrorCodeChannelConcurrencyLimited, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+		case <-time.After(channelConcurrencyPollInterval):
+		}
+	}
+}
+
+// acquireChannelConcurrencySlot 登记一个名额并返回当前占用数（含本次），登记前先清除已过期的名额。
+// Redis 中以有序集合保存名额，score 为名额的过期时间
+func acquireChannelConcurrencySlot(channelId int, slot string) (int, error) {
+	now := time.Now()
+	expireAt := now.Add(channelConcurrencySlotTTL)
+	if common.RedisEnabled && common.RDB != nil {
+		key := fmt.Sprintf("%s%d", channelConcurrencyKeyPrefix, channelId)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var countCmd *redis.IntCmd
+		_, err := common.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+			pipe.ZAdd(ctx, key, &redis.Z{Score: float64(expireAt.UnixMilli()), Member: slot})
+			countCmd = pipe.ZCard(ctx, key)
+			// 整个集合在最后一个名额过期后自动删除
+			pipe.Expire(ctx, key, channelConcurrencySlotTTL)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return int(countCmd.Val()), nil
+	}
+	channelConcurrencyLock.Lock()
+	defer channelConcurrencyLock.Unlock()
+	slots, ok := channelConcurrencySlots[channelId]
+	if !ok {
+		slots = make(map[string]time.Time)
+		channelConcurrencySlots[channelId] = slots
+	}
+	for id, slotExpireAt := range slots {
+		if !slotExpireAt.After(now) {
+			delete(slots, id)
+		}
+	}
+	slots[slot] = expireAt
+	return len(slots), nil
+}
+
+func releaseChannelConcurrencySlot(channelId int, slot string) {
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := common.RDB.ZRem(ctx, fmt.Sprintf("%s%d", channelConcurrencyKeyPrefix, channelId), slot).Err(); err != nil {
+			common.SysError("failed to release channel concurrency: " + err.Error())
+		}
+		return
+	}
+	channelConcurrencyLock.Lock()
+	defer channelConcurrencyLock.Unlock()
+	slots := channelConcurrencySlots[channelId]
+	delete(slots, slot)
+	if len(slots) == 0 {
+		delete(channelConcurrencySlots, channelId)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newChannelConcurrencyContext() *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	return ctx
+}
+
+func TestAcquireChannelConcurrency(t *testing.T) {
+	channel := &model.Channel{Id: 9101, OtherSettings: `{"max_concurrency":1}`}
+	ctx := newChannelConcurrencyContext()
+
+	release, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+
+	// 名额已满且未配置排队时直接返回渠道错误
+	_, newAPIError = AcquireChannelConcurrency(ctx, channel)
+	require.NotNil(t, newAPIError)
+	require.Equal(t, types.ErrorCodeChannelConcurrencyLimited, newAPIError.GetErrorCode())
+	require.Equal(t, http.StatusTooManyRequests, newAPIError.StatusCode)
+	require.True(t, types.IsChannelError(newAPIError))
+
+	release()
+	release()
+	release, newAPIError = AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+	release()
+
+	unlimited := &model.Channel{Id: 9102}
+	for i := 0; i < 3; i++ {
+		_, newAPIError = AcquireChannelConcurrency(ctx, unlimited)
+		require.Nil(t, newAPIError)
+	}
+}
+
+func TestAcquireChannelConcurrencyQueues(t *testing.T) {
+	channel := &model.Channel{Id: 9103, OtherSettings: `{"max_concurrency":1,"concurrency_queue_seconds":2}`}
+	ctx := newChannelConcurrencyContext()
+
+	release, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		release()
+	}()
+
+	queuedRelease, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+	queuedRelease()
+}
+
+func TestChannelConcurrencyLeakedSlotExpires(t *testing.T) {
+	channel := &model.Channel{Id: 9104, OtherSettings: `{"max_concurrency":1}`}
+	ctx := newChannelConcurrencyContext()
+
+	// 模拟未释放的名额：其他请求持续占用不会延长它的过期时间
+	_, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+	_, newAPIError = AcquireChannelConcurrency(ctx, channel)
+	require.NotNil(t, newAPIError)
+
+	channelConcurrencyLock.Lock()
+	for slot := range channelConcurrencySlots[channel.Id] {
+		channelConcurrencySlots[channel.Id][slot] = time.Now().Add(-time.Second)
+	}
+	channelConcurrencyLock.Unlock()
+
+	release, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+	release()
+	channelConcurrencyLock.Lock()
+	defer channelConcurrencyLock.Unlock()
+	require.NotContains(t, channelConcurrencySlots, channel.Id)
+}
@@ -7,8 +7,11 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RetryParam struct {
@@ -17,6 +20,29 @@ type RetryParam struct {
 	ModelName    string
 	Retry        *int
 	resetNextTry bool
+
+	// FailedChannelIds 本次请求已失败的渠道，重试时优先选择其他渠道
+	FailedChannelIds []int
+	// BusyChannelIds 本轮选择中并发已满的渠道，排队等到名额释放后清空
+	BusyChannelIds []int
+}
+
+func (p *RetryParam) AddFailedChannel(channelId int) {
+	p.FailedChannelIds = append(p.FailedChannelIds, channelId)
+}
+
+func (p *RetryParam) AddBusyChannel(channelId int) {
+	p.BusyChannelIds = append(p.BusyChannelIds, channelId)
+}
+
+// ExcludedChannelIds 选择渠道时需要跳过的渠道
+func (p *RetryParam) ExcludedChannelIds() []int {
+	if len(p.BusyChannelIds) == 0 {
+		return p.FailedChannelIds
+	}
+	excluded := make([]int, 0, len(p.FailedChannelIds)+len(p.BusyChannelIds))
+	excluded = append(excluded, p.FailedChannelIds...)
+	return append(excluded, p.BusyChannelIds...)
 }
 
 func (p *RetryParam) GetRetry() int {
@@ -81,6 +107,20 @@ func (p *RetryParam) ResetRetryNextTry() {
 //	Retry=3: GroupB, priority1 (startRetryIndex=2, priorityRetry=1)
 //	         分组B, 优先级1
 func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string, error) {
+	_, span := tracing.Start(param.Ctx.Request.Context(), "channel.select", trace.SpanKindInternal,
+		attribute.String("group", param.TokenGroup),
+		attribute.String("model", param.ModelName),
+		attribute.Int("retry", param.GetRetry()),
+	)
+	channel, selectGroup, err := cacheGetRandomSatisfiedChannel(param)
+	if channel != nil {
+		span.SetAttributes(attribute.Int("channel.id", channel.Id), attribute.String("selected_group", selectGroup))
+	}
+	tracing.End(span, err)
+	return channel, selectGroup, err
+}
+
+func cacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string, error) {
 	var channel *model.Channel
 	var err error
 	selectGroup := param.TokenGroup
@@ -115,7 +155,7 @@ func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string,
 			}
 			logger.LogDebug(param.Ctx, "Auto selecting group: %s, priorityRetry: %d", autoGroup, priorityRetry)
 
-			channel, _ = model.GetRandomSatisfiedChannel(autoGroup, param.ModelName, priorityRetry)
+			channel, _ = model.GetRandomSatisfiedChannel(autoGroup, param.ModelName, priorityRetry, param.ExcludedChannelIds()...)
 			if channel == nil {
 				// Current group has no available channel for this model, try next group
 				// 当前分组没有该模型的可用渠道，尝试下一个分组
@@ -153,7 +193,7 @@ func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string,
 			break
 		}
 	} else {
-		channel, err = model.GetRandomSatisfiedChannel(param.TokenGroup, param.ModelName, param.GetRetry())
+		channel, err = model.GetRandomSatisfiedChannel(param.TokenGroup, param.ModelName, param.GetRetry(), param.ExcludedChannelIds()...)
 		if err != nil {
 			return nil, param.TokenGroup, err
 		}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+
+	"github.com/gorilla/websocket"
+)
+
+// ChannelTLSConfig 根据渠道设置构建上游 TLS 配置：客户端证书与私钥用于双向 TLS 认证，自定义 CA 追加到系统根证书用于校验上游证书。
+// 未配置任何证书时返回 nil，沿用默认配置
+func ChannelTLSConfig(setting dto.ChannelSettings) (*tls.Config, error) {
+	clientCert := strings.TrimSpace(setting.TLSClientCert)
+	clientKey := strings.TrimSpace(setting.TLSClientKey)
+	caCert := strings.TrimSpace(setting.TLSCACert)
+	if clientCert == "" && clientKey == "" && caCert == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if common.TLSInsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, errors.New("tls client certificate and key must be provided together")
+		}
+		certificate, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, errors.New("invalid tls client certificate or key: " + err.Error())
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if caCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, errors.New("invalid tls ca certificate: no PEM certificate found")
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// channelTLSFingerprint 证书内容的摘要，用于区分不同 TLS 配置的客户端缓存，避免以证书原文作为缓存键
+func channelTLSFingerprint(setting dto.ChannelSettings) string {
+	h := sha256.New()
+	h.Write([]byte(setting.TLSClientCert))
+	h.Write([]byte{0})
+	h.Write([]byte(setting.TLSClientKey))
+	h.Write([]byte{0})
+	h.Write([]byte(setting.TLSCACert))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewChannelWebsocketDialer 按渠道设置创建上游 WebSocket 拨号器，同时应用渠道代理、TLS 配置与连接超时
+func NewChannelWebsocketDialer(setting dto.ChannelSettings, timeouts dto.UpstreamTimeouts) (*websocket.Dialer, error) {
+	dialer, err := NewProxyWebsocketDialer(setting.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := ChannelTLSConfig(setting)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil && timeouts.ConnectTimeout <= 0 {
+		return dialer, nil
+	}
+	customized := *dialer
+	if tlsConfig != nil {
+		customized.TLSClientConfig = tlsConfig
+	}
+	if timeouts.ConnectTimeout > 0 {
+		customized.HandshakeTimeout = time.Duration(timeouts.ConnectTimeout) * time.Second
+	}
+	return &customized, nil
+}
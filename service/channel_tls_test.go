@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClientCertificate 生成自签名的客户端证书，返回 PEM 格式的证书与私钥
+func newTestClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "new-api-test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(privateKey)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return string(certPEM), string(keyPEM), certificate
+}
+
+func TestChannelTLSConfigValidation(t *testing.T) {
+	config, err := ChannelTLSConfig(dto.ChannelSettings{})
+	require.NoError(t, err)
+	require.Nil(t, config)
+
+	certPEM, keyPEM, _ := newTestClientCertificate(t)
+	_, err = ChannelTLSConfig(dto.ChannelSettings{TLSClientCert: certPEM})
+	require.Error(t, err)
+	_, err = ChannelTLSConfig(dto.ChannelSettings{TLSClientCert: certPEM, TLSClientKey: "invalid"})
+	require.Error(t, err)
+	_, err = ChannelTLSConfig(dto.ChannelSettings{TLSCACert: "invalid"})
+	require.Error(t, err)
+
+	config, err = ChannelTLSConfig(dto.ChannelSettings{TLSClientCert: certPEM, TLSClientKey: keyPEM, TLSCACert: certPEM})
+	require.NoError(t, err)
+	require.Len(t, config.Certificates, 1)
+	require.NotNil(t, config.RootCAs)
+}
+
+func TestNewChannelHttpClientMutualTLS(t *testing.T) {
+	certPEM, keyPEM, clientCertificate := newTestClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCertificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	// 仅信任自定义 CA、未提供客户端证书时握手失败
+	client, err := NewChannelHttpClient(dto.ChannelSettings{TLSCACert: serverCA}, dto.UpstreamTimeouts{})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	setting := dto.ChannelSettings{TLSClientCert: certPEM, TLSClientKey: keyPEM, TLSCACert: serverCA}
+	client, err = NewChannelHttpClient(setting, dto.UpstreamTimeouts{})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 相同配置复用同一个客户端
+	cached, err := NewChannelHttpClient(setting, dto.UpstreamTimeouts{})
+	require.NoError(t, err)
+	require.Same(t, client, cached)
+}
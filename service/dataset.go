@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	DatasetFormatOpenAI   = "openai"
+	DatasetFormatShareGPT = "sharegpt"
+)
+
+// datasetMessage 数据集中的一条消息，content 统一转为纯文本
+type datasetMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// BuildDatasetMessages 从聊天请求中取出消息并追加模型回复，多模态内容只保留文本部分
+func BuildDatasetMessages(requestBody []byte, reply string) ([]byte, error) {
+	messages := gjson.GetBytes(requestBody, "messages")
+	if !messages.IsArray() {
+		return nil, errors.New("request has no messages")
+	}
+	result := make([]datasetMessage, 0, len(messages.Array())+1)
+	for _, message := range messages.Array() {
+		role := message.Get("role").String()
+		if role == "developer" {
+			role = "system"
+		}
+		content := datasetContentText(message.Get("content"))
+		if role == "" || content == "" {
+			continue
+		}
+		result = append(result, datasetMessage{Role: role, Content: content})
+	}
+	if len(result) == 0 || reply == "" {
+		return nil, errors.New("conversation is empty")
+	}
+	result = append(result, datasetMessage{Role: "assistant", Content: reply})
+	return common.Marshal(result)
+}
+
+func datasetContentText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return content.String()
+	}
+	if !content.IsArray() {
+		return ""
+	}
+	var parts []string
+	for _, part := range content.Array() {
+		if part.Get("type").String() == "text" {
+			parts = append(parts, part.Get("text").String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ShouldCaptureDataset 判断当前请求是否需要采集，合规模式（no-store）的请求不采集
+func ShouldCaptureDataset(c *gin.Context) bool {
+	setting := operation_setting.GetDatasetSetting()
+	if !setting.CaptureEnabled || setting.SampleRate <= 0 || common.IsNoStore(c) {
+		return false
+	}
+	if !setting.ShouldCaptureModel(common.GetContextKeyString(c, constant.ContextKeyOriginalModel)) {
+		return false
+	}
+	return setting.SampleRate >= 1 || rand.Float64() < setting.SampleRate
+}
+
+// CaptureDatasetSample 异步保存一次聊天交互
+func CaptureDatasetSample(c *gin.Context, requestBody []byte, reply string) {
+	setting := operation_setting.GetDatasetSetting()
+	if setting.MaxSampleBytes > 0 && len(requestBody)+len(reply) > setting.MaxSampleBytes {
+		return
+	}
+	messages, err := BuildDatasetMessages(requestBody, reply)
+	if err != nil {
+		return
+	}
+	sample := &model.DatasetSample{
+		RequestId: c.GetString(common.RequestIdKey),
+		UserId:    c.GetInt("id"),
+		TokenId:   c.GetInt("token_id"),
+		ModelName: common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+		Group:     common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Messages:  messages,
+	}
+	if header := setting.RatingHeader; header != "" {
+		if rating, err := strconv.Atoi(strings.TrimSpace(c.GetHeader(header))); err == nil {
+			sample.Rating = &rating
+		}
+	}
+	gopool.Go(func() {
+		if err := sample.Insert(); err != nil {
+			common.SysError("failed to save dataset sample: " + err.Error())
+		}
+	})
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+var shareGPTRoles = map[string]string{
+	"system":    "system",
+	"user":      "human",
+	"assistant": "gpt",
+	"tool":      "tool",
+}
+
+// FormatDatasetSample 将样本转换为导出格式的一行：openai 为 {"messages": [...]}，sharegpt 为 {"conversations": [...]}
+func FormatDatasetSample(sample *model.DatasetSample, format string) ([]byte, error) {
+	var messages []datasetMessage
+	if err := common.Unmarshal(sample.Messages, &messages); err != nil {
+		return nil, err
+	}
+	switch format {
+	case DatasetFormatShareGPT:
+		turns := make([]shareGPTTurn, 0, len(messages))
+		for _, message := range messages {
+			from, ok := shareGPTRoles[message.Role]
+			if !ok {
+				from = message.Role
+			}
+			turns = append(turns, shareGPTTurn{From: from, Value: message.Content})
+		}
+		return common.Marshal(map[string]interface{}{"conversations": turns})
+	default:
+		return common.Marshal(map[string]interface{}{"messages": messages})
+	}
+}
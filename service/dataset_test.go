@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDatasetMessages(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","messages":[
+		{"role":"developer","content":"be brief"},
+		{"role":"user","content":[{"type":"text","text":"what is this"},{"type":"image_url","image_url":{"url":"data:"}}]}
+	]}`)
+	messages, err := BuildDatasetMessages(body, "a cat")
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":"what is this"},
+		{"role":"assistant","content":"a cat"}
+	]`, string(messages))
+
+	_, err = BuildDatasetMessages(body, "")
+	require.Error(t, err)
+	_, err = BuildDatasetMessages([]byte(`{"prompt":"hi"}`), "hello")
+	require.Error(t, err)
+}
+
+func TestFormatDatasetSample(t *testing.T) {
+	sample := &model.DatasetSample{Messages: model.JSONValue(`[{"role":"system","content":"s"},{"role":"user","content":"q"},{"role":"assistant","content":"a"}]`)}
+
+	line, err := FormatDatasetSample(sample, DatasetFormatOpenAI)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"messages":[{"role":"system","content":"s"},{"role":"user","content":"q"},{"role":"assistant","content":"a"}]}`, string(line))
+
+	line, err = FormatDatasetSample(sample, DatasetFormatShareGPT)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"conversations":[{"from":"system","value":"s"},{"from":"human","value":"q"},{"from":"gpt","value":"a"}]}`, string(line))
+}
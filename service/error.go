@@ -85,6 +85,10 @@ func ClaudeErrorWrapperLocal(err error, code string, statusCode int) *dto.Claude
 
 func RelayErrorHandler(ctx context.Context, resp *http.Response, showBodyWhenFail bool) (newApiErr *types.NewAPIError) {
 	newApiErr = types.InitOpenAIError(types.ErrorCodeBadResponseStatusCode, resp.StatusCode)
+	defer func() {
+		// 记录上游原始错误，供写出响应前映射为一致的错误码
+		types.ErrOptionWithProviderError(resp.StatusCode)(newApiErr)
+	}()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// 网关生命周期事件
+const (
+	EventUserRegistered       = "user.registered"
+	EventChannelAutoDisabled  = "channel.auto_disabled"
+	EventChannelRecovered     = "channel.recovered"
+	EventTokenExhausted       = "token.exhausted"
+	EventLargeRequestFailed   = "request.large_failed"
+	EventSecretLeakRepeated   = "security.secret_leak_repeated"
+	EventWebhookTestEventName = "webhook.test"
+)
+
+var AllWebhookEvents = []string{
+	EventUserRegistered,
+	EventChannelAutoDisabled,
+	EventChannelRecovered,
+	EventTokenExhausted,
+	EventLargeRequestFailed,
+	EventSecretLeakRepeated,
+}
+
+const (
+	eventWebhookMaxBackoff = 30 * time.Second
+	// 同一令牌耗尽事件的最小触发间隔，避免每次请求都触发
+	tokenExhaustedEventInterval = time.Hour
+)
+
+// EventWebhookPayload 事件 webhook 的负载数据
+type EventWebhookPayload struct {
+	Id        string         `json:"id"`
+	Event     string         `json:"event"`
+	Timestamp int64          `json:"timestamp"`
+	Data      map[string]any `json:"data"`
+}
+
+var tokenExhaustedEventFired sync.Map // tokenId -> time.Time
+
+// EmitEvent 异步将事件投递到所有订阅了该事件的 webhook 端点
+func EmitEvent(event string, data map[string]any) {
+	setting := system_setting.GetEventWebhookSetting()
+	if !setting.Enabled {
+		return
+	}
+	payload := EventWebhookPayload{
+		Id:        common.GetUUID(),
+		Event:     event,
+		Timestamp: common.GetTimestamp(),
+		Data:      data,
+	}
+	payloadBytes, err := common.Marshal(payload)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to marshal event webhook payload: %s", err.Error()))
+		return
+	}
+	maxRetries := setting.MaxRetries
+	for _, endpoint := range setting.Endpoints {
+		if !endpoint.Enabled || !isEventSubscribed(endpoint.Events, event) {
+			continue
+		}
+		endpoint := endpoint
+		gopool.Go(func() {
+			if err := deliverEventWebhook(endpoint, payload, payloadBytes, maxRetries); err != nil {
+				common.SysError(fmt.Sprintf("failed to deliver event %s to webhook %s: %s", event, endpoint.Url, err.Error()))
+			}
+		})
+	}
+}
+
+// EmitTokenExhaustedEvent 令牌额度耗尽时触发，同一令牌在间隔内只触发一次
+func EmitTokenExhaustedEvent(tokenId int, userId int, tokenName string) {
+	if !system_setting.GetEventWebhookSetting().Enabled {
+		return
+	}
+	now := time.Now()
+	if last, ok := tokenExhaustedEventFired.Load(tokenId); ok && now.Sub(last.(time.Time)) < tokenExhaustedEventInterval {
+		return
+	}
+	tokenExhaustedEventFired.Store(tokenId, now)
+	EmitEvent(EventTokenExhausted, map[string]any{
+		"token_id":   tokenId,
+		"token_name": tokenName,
+		"user_id":    userId,
+	})
+}
+
+// CheckTokenExhausted 令牌结算扣费后检查剩余额度，降到 0 及以下时触发 token.exhausted 事件。
+// 预扣阶段不检查，避免预扣后又退还的请求误报
+func CheckTokenExhausted(tokenKey string) {
+	if tokenKey == "" || !system_setting.GetEventWebhookSetting().Enabled {
+		return
+	}
+	gopool.Go(func() {
+		// 批量更新模式下数据库额度延迟落盘，只能读取缓存
+		token, err := model.GetTokenByKey(tokenKey, !common.BatchUpdateEnabled)
+		if err != nil || token == nil || token.UnlimitedQuota || token.RemainQuota > 0 {
+			return
+		}
+		EmitTokenExhaustedEvent(token.Id, token.UserId, token.Name)
+	})
+}
+
+// SendTestEventWebhook 同步向指定端点发送一条测试事件，不进行重试
+func SendTestEventWebhook(endpoint system_setting.EventWebhookEndpoint) error {
+	payload := EventWebhookPayload{
+		Id:        common.GetUUID(),
+		Event:     EventWebhookTestEventName,
+		Timestamp: common.GetTimestamp(),
+		Data:      map[string]any{"message": "this is a test event"},
+	}
+	payloadBytes, err := common.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = postEventWebhook(endpoint, payload, payloadBytes)
+	return err
+}
+
+func isEventSubscribed(events []string, event string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// eventWebhookSleep 重试前的等待，测试时可替换
+var eventWebhookSleep = time.Sleep
+
+// eventWebhookBackoff 第 attempt 次重试前的等待时间：1s、2s、4s…，最长 eventWebhookMaxBackoff
+func eventWebhookBackoff(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	if attempt > 16 {
+		return eventWebhookMaxBackoff
+	}
+	return min(time.Second<<(attempt-1), eventWebhookMaxBackoff)
+}
+
+func deliverEventWebhook(endpoint system_setting.EventWebhookEndpoint, payload EventWebhookPayload, payloadBytes []byte, maxRetries int) error {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			eventWebhookSleep(eventWebhookBackoff(attempt))
+		}
+		retryable, err := postEventWebhook(endpoint, payload, payloadBytes)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	return lastErr
+}
+
+// signEventWebhook 对 "时间戳.请求体" 计算 HMAC-SHA256，接收方校验签名与时间戳以防止重放
+func signEventWebhook(secret string, timestamp string, payloadBytes []byte) string {
+	signed := make([]byte, 0, len(timestamp)+1+len(payloadBytes))
+	signed = append(signed, timestamp...)
+	signed = append(signed, '.')
+	signed = append(signed, payloadBytes...)
+	return generateSignature(secret, signed)
+}
+
+// postEventWebhook 发送一次投递，返回失败是否可以重试
+func postEventWebhook(endpoint system_setting.EventWebhookEndpoint, payload EventWebhookPayload, payloadBytes []byte) (bool, error) {
+	timestamp := fmt.Sprintf("%d", payload.Timestamp)
+	headers := map[string]string{
+		"Content-Type":        "application/json",
+		"X-Webhook-Event":     payload.Event,
+		"X-Webhook-Id":        payload.Id,
+		"X-Webhook-Timestamp": timestamp,
+	}
+	if endpoint.Secret != "" {
+		headers["X-Webhook-Signature"] = signEventWebhook(endpoint.Secret, timestamp, payloadBytes)
+	}
+
+	var resp *http.Response
+	var err error
+	if system_setting.EnableWorker() {
+		resp, err = DoWorkerRequest(&WorkerRequest{
+			URL:     endpoint.Url,
+			Key:     system_setting.WorkerValidKey,
+			Method:  http.MethodPost,
+			Headers: headers,
+			Body:    payloadBytes,
+		})
+	} else {
+		fetchSetting := system_setting.GetFetchSetting()
+		if err := common.ValidateURLWithFetchSetting(endpoint.Url, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+			return false, fmt.Errorf("request reject: %v", err)
+		}
+		req, reqErr := http.NewRequest(http.MethodPost, endpoint.Url, bytes.NewBuffer(payloadBytes))
+		if reqErr != nil {
+			return false, fmt.Errorf("failed to create webhook request: %v", reqErr)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err = GetHttpClient().Do(req)
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
+	}
+	return false, nil
+}
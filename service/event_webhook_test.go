@@ -0,0 +1,172 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsEventSubscribed(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		events   []string
+		event    string
+		expected bool
+	}{
+		{
+			name:     "empty list subscribes all",
+			events:   nil,
+			event:    EventUserRegistered,
+			expected: true,
+		},
+		{
+			name:     "wildcard",
+			events:   []string{"*"},
+			event:    EventTokenExhausted,
+			expected: true,
+		},
+		{
+			name:     "explicit match",
+			events:   []string{EventChannelAutoDisabled, EventLargeRequestFailed},
+			event:    EventLargeRequestFailed,
+			expected: true,
+		},
+		{
+			name:     "not subscribed",
+			events:   []string{EventChannelAutoDisabled},
+			event:    EventUserRegistered,
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, isEventSubscribed(tc.events, tc.event))
+		})
+	}
+}
+
+// newEventWebhookTestServer 按 statuses 顺序依次返回状态码，并记录每次投递的请求头与请求体
+func newEventWebhookTestServer(t *testing.T, statuses ...int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		status := http.StatusOK
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	fetchSetting := system_setting.GetFetchSetting()
+	saved := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = saved })
+	if GetHttpClient() == nil {
+		InitHttpClient()
+	}
+	return server, &requests, &bodies
+}
+
+func stubEventWebhookSleep(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	saved := eventWebhookSleep
+	eventWebhookSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	t.Cleanup(func() { eventWebhookSleep = saved })
+	return &sleeps
+}
+
+func TestEventWebhookSignatureCoversTimestamp(t *testing.T) {
+	server, requests, bodies := newEventWebhookTestServer(t)
+	endpoint := system_setting.EventWebhookEndpoint{Url: server.URL, Secret: "s3cret", Enabled: true}
+	payload := EventWebhookPayload{Id: "evt_1", Event: EventUserRegistered, Timestamp: 1700000000, Data: map[string]any{"user_id": 1}}
+	payloadBytes, err := common.Marshal(payload)
+	require.NoError(t, err)
+
+	retryable, err := postEventWebhook(endpoint, payload, payloadBytes)
+	require.NoError(t, err)
+	require.False(t, retryable)
+	require.Len(t, *requests, 1)
+
+	req := (*requests)[0]
+	require.Equal(t, "1700000000", req.Header.Get("X-Webhook-Timestamp"))
+	require.Equal(t, EventUserRegistered, req.Header.Get("X-Webhook-Event"))
+	require.Equal(t, "evt_1", req.Header.Get("X-Webhook-Id"))
+
+	// 接收方按文档校验：HMAC-SHA256(secret, timestamp + "." + body)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("1700000000." + string((*bodies)[0])))
+	require.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.Header.Get("X-Webhook-Signature"))
+
+	// 替换时间戳重放同一请求体时签名不再匹配
+	require.NotEqual(t, signEventWebhook("s3cret", "1700000999", (*bodies)[0]), req.Header.Get("X-Webhook-Signature"))
+	require.NotEqual(t, generateSignature("s3cret", (*bodies)[0]), req.Header.Get("X-Webhook-Signature"))
+}
+
+func TestEventWebhookWithoutSecretIsUnsigned(t *testing.T) {
+	server, requests, _ := newEventWebhookTestServer(t)
+	payload := EventWebhookPayload{Id: "evt_2", Event: EventTokenExhausted, Timestamp: 1}
+	payloadBytes, err := common.Marshal(payload)
+	require.NoError(t, err)
+	_, err = postEventWebhook(system_setting.EventWebhookEndpoint{Url: server.URL}, payload, payloadBytes)
+	require.NoError(t, err)
+	require.Empty(t, (*requests)[0].Header.Get("X-Webhook-Signature"))
+}
+
+func TestDeliverEventWebhookRetries(t *testing.T) {
+	payload := EventWebhookPayload{Id: "evt_3", Event: EventChannelAutoDisabled, Timestamp: 1}
+	payloadBytes, err := common.Marshal(payload)
+	require.NoError(t, err)
+
+	t.Run("retries server errors until success", func(t *testing.T) {
+		sleeps := stubEventWebhookSleep(t)
+		server, requests, _ := newEventWebhookTestServer(t, http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK)
+		err := deliverEventWebhook(system_setting.EventWebhookEndpoint{Url: server.URL}, payload, payloadBytes, 3)
+		require.NoError(t, err)
+		require.Len(t, *requests, 3)
+		require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		sleeps := stubEventWebhookSleep(t)
+		server, requests, _ := newEventWebhookTestServer(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		err := deliverEventWebhook(system_setting.EventWebhookEndpoint{Url: server.URL}, payload, payloadBytes, 2)
+		require.ErrorContains(t, err, "502")
+		require.Len(t, *requests, 3)
+		require.Len(t, *sleeps, 2)
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		sleeps := stubEventWebhookSleep(t)
+		server, requests, _ := newEventWebhookTestServer(t, http.StatusBadRequest)
+		err := deliverEventWebhook(system_setting.EventWebhookEndpoint{Url: server.URL}, payload, payloadBytes, 3)
+		require.ErrorContains(t, err, "400")
+		require.Len(t, *requests, 1)
+		require.Empty(t, *sleeps)
+	})
+}
+
+func TestEventWebhookBackoff(t *testing.T) {
+	require.Equal(t, time.Duration(0), eventWebhookBackoff(0))
+	require.Equal(t, time.Second, eventWebhookBackoff(1))
+	require.Equal(t, 4*time.Second, eventWebhookBackoff(3))
+	require.Equal(t, eventWebhookMaxBackoff, eventWebhookBackoff(6))
+	require.Equal(t, eventWebhookMaxBackoff, eventWebhookBackoff(100))
+}
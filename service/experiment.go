@@ -0,0 +1,177 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 启用中的实验缓存，管理端修改后立即刷新，其他节点最迟一个周期后生效
+const experimentCacheTTL = time.Minute
+
+type experimentKey struct {
+	tokenId   int
+	modelName string
+}
+
+type experimentSnapshot struct {
+	loadedAt    time.Time
+	experiments map[experimentKey]*model.Experiment
+}
+
+var (
+	experimentCache     atomic.Pointer[experimentSnapshot]
+	experimentReloading atomic.Bool
+)
+
+// ReloadExperiments 从数据库重新加载启用中的实验
+func ReloadExperiments() error {
+	experiments, err := model.GetEnabledExperiments()
+	if err != nil {
+		return err
+	}
+	snapshot := &experimentSnapshot{
+		loadedAt:    time.Now(),
+		experiments: make(map[experimentKey]*model.Experiment, len(experiments)),
+	}
+	for _, experiment := range experiments {
+		snapshot.experiments[experimentKey{tokenId: experiment.TokenId, modelName: experiment.ModelName}] = experiment
+	}
+	experimentCache.Store(snapshot)
+	return nil
+}
+
+func getExperimentSnapshot() *experimentSnapshot {
+	snapshot := experimentCache.Load()
+	if snapshot == nil {
+		if err := ReloadExperiments(); err != nil {
+			common.SysError("failed to load experiments: " + err.Error())
+			return nil
+		}
+		return experimentCache.Load()
+	}
+	if time.Since(snapshot.loadedAt) > experimentCacheTTL && experimentReloading.CompareAndSwap(false, true) {
+		gopool.Go(func() {
+			defer experimentReloading.Store(false)
+			if err := ReloadExperiments(); err != nil {
+				common.SysError("failed to reload experiments: " + err.Error())
+			}
+		})
+	}
+	return snapshot
+}
+
+// findExperiment 优先匹配令牌专属实验，其次匹配所有令牌（TokenId 为 0）的实验
+func findExperiment(tokenId int, modelName string) *model.Experiment {
+	snapshot := getExperimentSnapshot()
+	if snapshot == nil || len(snapshot.experiments) == 0 {
+		return nil
+	}
+	if experiment, ok := snapshot.experiments[experimentKey{tokenId: tokenId, modelName: modelName}]; ok {
+		return experiment
+	}
+	return snapshot.experiments[experimentKey{modelName: modelName}]
+}
+
+// pickExperimentArm 按权重选择分组，roll 取值范围为 [0, 总权重)
+func pickExperimentArm(arms model.ExperimentArms, roll int) model.ExperimentArm {
+	for _, arm := range arms {
+		if roll < arm.Weight {
+			return arm
+		}
+		roll -= arm.Weight
+	}
+	return arms[len(arms)-1]
+}
+
+// AssignExperiment 为命中实验的请求分配分组，返回分组指定的模型（未指定时沿用原模型）
+func AssignExperiment(c *gin.Context, modelName string) string {
+	tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+	if modelName == "" || tokenId == 0 {
+		return modelName
+	}
+	experiment := findExperiment(tokenId, modelName)
+	if experiment == nil || len(experiment.Arms) == 0 {
+		return modelName
+	}
+	total := 0
+	for _, arm := range experiment.Arms {
+		total += arm.Weight
+	}
+	if total <= 0 {
+		return modelName
+	}
+	arm := pickExperimentArm(experiment.Arms, rand.Intn(total))
+	common.SetContextKey(c, constant.ContextKeyExperimentId, experiment.Id)
+	common.SetContextKey(c, constant.ContextKeyExperimentArm, arm.Name)
+	if len(arm.ParamOverride) > 0 {
+		common.SetContextKey(c, constant.ContextKeyExperimentParamOverride, arm.ParamOverride)
+	}
+	if arm.Model != "" {
+		modelName = arm.Model
+	}
+	return modelName
+}
+
+// MergeExperimentParamOverride 合并实验分组与渠道的参数覆盖，同名字段以实验分组为准
+func MergeExperimentParamOverride(c *gin.Context, paramOverride map[string]interface{}) (map[string]interface{}, bool) {
+	experimentParams := common.GetContextKeyStringMap(c, constant.ContextKeyExperimentParamOverride)
+	if len(experimentParams) == 0 {
+		return paramOverride, false
+	}
+	return mergeChannelOverride(experimentParams, paramOverride), true
+}
+
+// ExperimentArmReport 实验分组的对比指标
+type ExperimentArmReport struct {
+	model.ExperimentArmSummary
+	ErrorRate          float64 `json:"error_rate"`
+	AvgLatencyMs       float64 `json:"avg_latency_ms"`
+	AvgQuotaPerRequest float64 `json:"avg_quota_per_request"`
+}
+
+// GetExperimentReport 汇总实验各分组在时间范围内的延迟、费用与错误率，未产生流量的分组同样列出
+func GetExperimentReport(experiment *model.Experiment, startTs int64, endTs int64) ([]ExperimentArmReport, error) {
+	summaries, err := model.GetExperimentArmSummaries(experiment.Id, startTs, endTs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment metrics: %w", err)
+	}
+	byArm := make(map[string]model.ExperimentArmSummary, len(summaries))
+	for _, summary := range summaries {
+		byArm[summary.Arm] = summary
+	}
+	reports := make([]ExperimentArmReport, 0, len(experiment.Arms))
+	appendReport := func(summary model.ExperimentArmSummary) {
+		report := ExperimentArmReport{ExperimentArmSummary: summary}
+		if summary.RequestCount > 0 {
+			count := float64(summary.RequestCount)
+			report.ErrorRate = float64(summary.ErrorCount) / count
+			report.AvgLatencyMs = float64(summary.TotalLatencyMs) / count
+			report.AvgQuotaPerRequest = float64(summary.Quota) / count
+		}
+		reports = append(reports, report)
+	}
+	for _, arm := range experiment.Arms {
+		summary, ok := byArm[arm.Name]
+		if !ok {
+			summary = model.ExperimentArmSummary{Arm: arm.Name}
+		}
+		delete(byArm, arm.Name)
+		appendReport(summary)
+	}
+	// 已从配置中移除的分组仍保留历史数据
+	for _, summary := range summaries {
+		if _, ok := byArm[summary.Arm]; ok {
+			appendReport(summary)
+		}
+	}
+	return reports, nil
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickExperimentArm(t *testing.T) {
+	arms := model.ExperimentArms{
+		{Name: "control", Weight: 90},
+		{Name: "treatment", Weight: 10},
+	}
+	require.Equal(t, "control", pickExperimentArm(arms, 0).Name)
+	require.Equal(t, "control", pickExperimentArm(arms, 89).Name)
+	require.Equal(t, "treatment", pickExperimentArm(arms, 90).Name)
+	require.Equal(t, "treatment", pickExperimentArm(arms, 99).Name)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/samber/hot"
+)
+
+const (
+	idempotencyCacheNamespace = "new-api:idempotency:v1"
+	// 首次请求处理期间占位记录的有效期，进程异常退出时占位会自然过期
+	idempotencyPendingTTL = 30 * time.Minute
+	// Idempotency-Key 的最大长度
+	IdempotencyKeyMaxLength = 255
+)
+
+// IdempotencyRecord 记录一次携带 Idempotency-Key 的请求结果
+type IdempotencyRecord struct {
+	// 请求指纹，同一 Key 用于不同请求时拒绝
+	Fingerprint string `json:"fingerprint"`
+	// 为 false 表示首次请求仍在处理中
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+	// 响应体过大或处于合规模式时不保存响应内容，仅记录请求已处理
+	BodyOmitted bool `json:"body_omitted,omitempty"`
+	// 首次请求的计费结果，重放时原样告知客户端，重放本身不计费
+	Quota     int    `json:"quota"`
+	RequestId string `json:"request_id,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+var (
+	idempotencyCacheOnce sync.Once
+	idempotencyCache     *cachex.HybridCache[IdempotencyRecord]
+	// 内存模式下保证占位操作的原子性
+	idempotencyMemoryLock sync.Mutex
+)
+
+func getIdempotencyCache() *cachex.HybridCache[IdempotencyRecord] {
+	idempotencyCacheOnce.Do(func() {
+		capacity := operation_setting.GetIdempotencySetting().MaxEntries
+		if capacity <= 0 {
+			capacity = 10000
+		}
+		idempotencyCache = cachex.NewHybridCache[IdempotencyRecord](cachex.HybridCacheConfig[IdempotencyRecord]{
+			Namespace: cachex.Namespace(idempotencyCacheNamespace),
+			Redis:     common.RDB,
+			RedisEnabled: func() bool {
+				return common.RedisEnabled && common.RDB != nil
+			},
+			RedisCodec: cachex.JSONCodec[IdempotencyRecord]{},
+			Memory: func() *hot.HotCache[string, IdempotencyRecord] {
+				return hot.NewHotCache[string, IdempotencyRecord](hot.LRU, capacity).
+					WithTTL(idempotencyPendingTTL).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return idempotencyCache
+}
+
+func idempotencyTTL() time.Duration {
+	ttl := operation_setting.GetIdempotencySetting().TTLSeconds
+	if ttl <= 0 {
+		ttl = 86400
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// IdempotencyCacheKey 按令牌隔离 Idempotency-Key，不同令牌使用相同 Key 互不影响
+func IdempotencyCacheKey(tokenId int, key string) string {
+	return fmt.Sprintf("%d:%s", tokenId, common.Sha1([]byte(key)))
+}
+
+// IdempotencyFingerprint 由请求方法、路径与请求体计算指纹，请求体以流式读取，大文件上传不会整体载入内存
+func IdempotencyFingerprint(method string, path string, body io.Reader) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	if body != nil {
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AcquireIdempotencyKey 尝试为请求占用 Key：acquired 为 true 表示当前请求应正常处理，
+// 否则返回已存在的记录（处理中或已完成）
+func AcquireIdempotencyKey(cacheKey string, fingerprint string) (existing *IdempotencyRecord, acquired bool, err error) {
+	cache := getIdempotencyCache()
+	pending := IdempotencyRecord{Fingerprint: fingerprint, CreatedAt: common.GetTimestamp()}
+
+	if common.RedisEnabled && common.RDB != nil {
+		raw, err := cachex.JSONCodec[IdempotencyRecord]{}.Encode(pending)
+		if err != nil {
+			return nil, false, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		ok, err := common.RDB.SetNX(ctx, cache.FullKey(cacheKey), raw, idempotencyPendingTTL).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return nil, true, nil
+		}
+		record, found, err := cache.Get(cacheKey)
+		if err != nil {
+			return nil, false, err
+		}
+		if !found {
+			// 占位恰好过期，本次按未命中处理
+			return nil, true, nil
+		}
+		return &record, false, nil
+	}
+
+	idempotencyMemoryLock.Lock()
+	defer idempotencyMemoryLock.Unlock()
+	record, found, err := cache.Get(cacheKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if found {
+		return &record, false, nil
+	}
+	return nil, true, cache.SetWithTTL(cacheKey, pending, idempotencyPendingTTL)
+}
+
+// CompleteIdempotencyKey 保存首次请求的结果，在有效期内供重试请求直接返回
+func CompleteIdempotencyKey(cacheKey string, record IdempotencyRecord) error {
+	record.Completed = true
+	if record.CreatedAt == 0 {
+		record.CreatedAt = common.GetTimestamp()
+	}
+	return getIdempotencyCache().SetWithTTL(cacheKey, record, idempotencyTTL())
+}
+
+// ReleaseIdempotencyKey 首次请求失败（未产生计费）时释放 Key，允许客户端使用同一 Key 重试
+func ReleaseIdempotencyKey(cacheKey string) error {
+	_, err := getIdempotencyCache().DeleteMany([]string{cacheKey})
+	return err
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	cacheKey := IdempotencyCacheKey(1, "retry-key")
+	fingerprint, err := IdempotencyFingerprint(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ReleaseIdempotencyKey(cacheKey) })
+
+	existing, acquired, err := AcquireIdempotencyKey(cacheKey, fingerprint)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.Nil(t, existing)
+
+	// 首次请求处理中，重试拿到未完成的占位
+	existing, acquired, err = AcquireIdempotencyKey(cacheKey, fingerprint)
+	require.NoError(t, err)
+	require.False(t, acquired)
+	require.False(t, existing.Completed)
+
+	require.NoError(t, CompleteIdempotencyKey(cacheKey, IdempotencyRecord{
+		Fingerprint: fingerprint,
+		StatusCode:  http.StatusOK,
+		ContentType: "application/json",
+		Body:        []byte(`{"id":"chatcmpl-1"}`),
+		Quota:       1500,
+		RequestId:   "req-1",
+	}))
+	existing, acquired, err = AcquireIdempotencyKey(cacheKey, fingerprint)
+	require.NoError(t, err)
+	require.False(t, acquired)
+	require.True(t, existing.Completed)
+	require.Equal(t, `{"id":"chatcmpl-1"}`, string(existing.Body))
+	require.Equal(t, 1500, existing.Quota)
+	require.Equal(t, "req-1", existing.RequestId)
+
+	// 不同令牌使用同一 Key 互不影响
+	_, acquired, err = AcquireIdempotencyKey(IdempotencyCacheKey(2, "retry-key"), fingerprint)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	_ = ReleaseIdempotencyKey(IdempotencyCacheKey(2, "retry-key"))
+}
+
+func TestIdempotencyKeyReleasedAfterFailure(t *testing.T) {
+	cacheKey := IdempotencyCacheKey(1, "failed-key")
+	fingerprint, err := IdempotencyFingerprint(http.MethodPost, "/v1/chat/completions", nil)
+	require.NoError(t, err)
+
+	_, acquired, err := AcquireIdempotencyKey(cacheKey, fingerprint)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, ReleaseIdempotencyKey(cacheKey))
+
+	_, acquired, err = AcquireIdempotencyKey(cacheKey, fingerprint)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, ReleaseIdempotencyKey(cacheKey))
+}
+
+func TestIdempotencyFingerprintDiffersByBody(t *testing.T) {
+	a, err := IdempotencyFingerprint(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"a":1}`))
+	require.NoError(t, err)
+	b, err := IdempotencyFingerprint(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"a":2}`))
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// 马赛克的色块数量（按长边计算）
+const imageBlurBlocks = 24
+
+// ImageSafetyVerdict 单张生成图片的扫描结果
+type ImageSafetyVerdict struct {
+	Index      int      `json:"index"`
+	Flagged    bool     `json:"flagged"`
+	Source     string   `json:"source"` // provider 或 classifier
+	Score      float64  `json:"score,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Action     string   `json:"action"`
+}
+
+// ShouldScanImages 判断分组的图片生成响应是否需要扫描
+func ShouldScanImages(group string) bool {
+	action := moderation_setting.GetImageSafetyAction(group)
+	if action == moderation_setting.ImageActionOff {
+		return false
+	}
+	setting := moderation_setting.GetImageSafetySetting()
+	return setting.UseProviderScores || setting.ClassifierUrl != ""
+}
+
+// ScanImageResponse 扫描 OpenAI 格式的图片生成响应，按分组动作处理命中的图片。
+// 返回处理后的响应体；动作为 block 且有图片命中时返回错误。
+func ScanImageResponse(c *gin.Context, group string, modelName string, body []byte) ([]byte, *types.NewAPIError) {
+	action := moderation_setting.GetImageSafetyAction(group)
+	items := gjson.GetBytes(body, "data")
+	if !items.IsArray() {
+		return body, nil
+	}
+	var verdicts []*ImageSafetyVerdict
+	for index, item := range items.Array() {
+		verdict := scanImageItem(c, group, modelName, body, index, item)
+		if verdict == nil {
+			continue
+		}
+		verdict.Action = action
+		verdicts = append(verdicts, verdict)
+		logger.LogWarn(c, fmt.Sprintf("generated image %d flagged by %s (score %.2f, categories: %s), action: %s",
+			index, verdict.Source, verdict.Score, strings.Join(verdict.Categories, ","), action))
+	}
+	if len(verdicts) == 0 {
+		return body, nil
+	}
+	common.SetContextKey(c, constant.ContextKeyImageSafetyVerdicts, verdicts)
+
+	switch action {
+	case moderation_setting.ImageActionBlock:
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("generated image rejected by safety policy"),
+			types.ErrorCodeModerationBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	case moderation_setting.ImageActionBlur:
+		for _, verdict := range verdicts {
+			blurred, err := blurImageItem(body, verdict.Index)
+			if err != nil {
+				// 无法处理的图片不能原样返回，降级为拒绝
+				logger.LogError(c, fmt.Sprintf("failed to blur generated image %d: %s", verdict.Index, err.Error()))
+				return nil, types.NewErrorWithStatusCode(
+					fmt.Errorf("generated image rejected by safety policy"),
+					types.ErrorCodeModerationBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+			}
+			body = blurred
+		}
+	}
+	return body, nil
+}
+
+// GetImageSafetyVerdicts 返回本次请求命中的图片扫描结果，用于写入消费日志
+func GetImageSafetyVerdicts(c *gin.Context) []*ImageSafetyVerdict {
+	verdicts, _ := common.GetContextKeyType[[]*ImageSafetyVerdict](c, constant.ContextKeyImageSafetyVerdicts)
+	return verdicts
+}
+
+func scanImageItem(c *gin.Context, group string, modelName string, body []byte, index int, item gjson.Result) *ImageSafetyVerdict {
+	setting := moderation_setting.GetImageSafetySetting()
+	if setting.UseProviderScores {
+		if verdict := providerImageVerdict(body, index, item); verdict != nil {
+			return verdict
+		}
+	}
+	if setting.ClassifierUrl == "" {
+		return nil
+	}
+	verdict, err := classifyImage(c, setting, group, modelName, item)
+	if err != nil {
+		// 与文本审核一致，分类服务故障时放行
+		logger.LogError(c, fmt.Sprintf("image classifier failed for image %d: %s", index, err.Error()))
+		return nil
+	}
+	if verdict != nil {
+		verdict.Index = index
+	}
+	return verdict
+}
+
+// providerImageVerdict 读取上游自带的安全评分：
+// Azure 的 data[i].content_filter_results，以及 Stable Diffusion 类接口的 nsfw_content_detected / has_nsfw_concepts
+func providerImageVerdict(body []byte, index int, item gjson.Result) *ImageSafetyVerdict {
+	var categories []string
+	item.Get("content_filter_results").ForEach(func(category, result gjson.Result) bool {
+		severity := result.Get("severity").String()
+		if result.Get("filtered").Bool() || severity == "medium" || severity == "high" {
+			categories = append(categories, category.String())
+		}
+		return true
+	})
+	for _, key := range []string{"nsfw_content_detected", "has_nsfw_concepts"} {
+		if gjson.GetBytes(body, fmt.Sprintf("%s.%d", key, index)).Bool() {
+			categories = append(categories, "nsfw")
+		}
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	return &ImageSafetyVerdict{Index: index, Flagged: true, Source: "provider", Categories: categories}
+}
+
+func classifyImage(ctx context.Context, setting *moderation_setting.ImageSafetySetting, group string, modelName string, item gjson.Result) (*ImageSafetyVerdict, error) {
+	timeout := time.Duration(setting.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultModerationTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request := map[string]any{"group": group, "model": modelName}
+	if b64 := item.Get("b64_json").String(); b64 != "" {
+		request["b64_json"] = b64
+	} else if url := item.Get("url").String(); url != "" {
+		request["url"] = url
+	} else {
+		return nil, nil
+	}
+	payload, err := common.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.ClassifierUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range setting.ClassifierHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("image classifier returned status code %d", resp.StatusCode)
+	}
+	var result struct {
+		Flagged    bool     `json:"flagged"`
+		Score      float64  `json:"score"`
+		Categories []string `json:"categories"`
+	}
+	if err := common.DecodeJson(resp.Body, &result); err != nil {
+		return nil, err
+	}
+	if !result.Flagged && (setting.ClassifierThreshold <= 0 || result.Score < setting.ClassifierThreshold) {
+		return nil, nil
+	}
+	return &ImageSafetyVerdict{Flagged: true, Source: "classifier", Score: result.Score, Categories: result.Categories}, nil
+}
+
+// blurImageItem 将第 index 张图片替换为马赛克后的 PNG（b64_json），URL 形式的图片会先下载
+func blurImageItem(body []byte, index int) ([]byte, error) {
+	prefix := fmt.Sprintf("data.%d.", index)
+	data := gjson.GetBytes(body, prefix+"b64_json").String()
+	if data == "" {
+		url := gjson.GetBytes(body, prefix+"url").String()
+		if url == "" {
+			return nil, fmt.Errorf("image has neither b64_json nor url")
+		}
+		var err error
+		if _, data, err = GetImageFromUrl(url); err != nil {
+			return nil, err
+		}
+	}
+	blurred, err := pixelateBase64Image(data)
+	if err != nil {
+		return nil, err
+	}
+	if body, err = sjson.SetBytes(body, prefix+"b64_json", blurred); err != nil {
+		return nil, err
+	}
+	return sjson.DeleteBytes(body, prefix+"url")
+}
+
+func pixelateBase64Image(data string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	blockSize := max(bounds.Dx(), bounds.Dy()) / imageBlurBlocks
+	if blockSize < 1 {
+		blockSize = 1
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += blockSize {
+		for x := bounds.Min.X; x < bounds.Max.X; x += blockSize {
+			block := image.Rect(x, y, min(x+blockSize, bounds.Max.X), min(y+blockSize, bounds.Max.Y))
+			draw.Draw(dst, block, &image.Uniform{C: averageColor(src, block)}, image.Point{}, draw.Src)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func averageColor(img image.Image, rect image.Rectangle) color.RGBA {
+	var r, g, b, a, n uint64
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			r += uint64(cr)
+			g += uint64(cg)
+			b += uint64(cb)
+			a += uint64(ca)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8)}
+}
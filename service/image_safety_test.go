@@ -0,0 +1,55 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestProviderImageVerdict(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"data":[{"url":"a","content_filter_results":{"sexual":{"filtered":false,"severity":"safe"}}},{"url":"b","content_filter_results":{"sexual":{"filtered":true,"severity":"high"}}},{"url":"c"}],"nsfw_content_detected":[false,false,true]}`)
+	items := gjson.GetBytes(body, "data").Array()
+
+	require.Nil(t, providerImageVerdict(body, 0, items[0]))
+	verdict := providerImageVerdict(body, 1, items[1])
+	require.NotNil(t, verdict)
+	require.Equal(t, []string{"sexual"}, verdict.Categories)
+	verdict = providerImageVerdict(body, 2, items[2])
+	require.NotNil(t, verdict)
+	require.Equal(t, []string{"nsfw"}, verdict.Categories)
+}
+
+func TestBlurImageItem(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewRGBA(image.Rect(0, 0, 48, 48))
+	for x := 0; x < 48; x++ {
+		for y := 0; y < 48; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 5), G: uint8(y * 5), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	body, err := blurImageItem([]byte(`{"data":[{"b64_json":"`+encoded+`"}]}`), 0)
+	require.NoError(t, err)
+	blurred := gjson.GetBytes(body, "data.0.b64_json").String()
+	require.NotEqual(t, encoded, blurred)
+
+	raw, err := base64.StdEncoding.DecodeString(blurred)
+	require.NoError(t, err)
+	out, err := png.Decode(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, img.Bounds(), out.Bounds())
+	// 同一色块内的像素颜色一致
+	require.Equal(t, out.At(0, 0), out.At(1, 1))
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const InvoicePeriodLayout = "2006-01"
+
+// InvoiceItem 账单明细，每个模型一行；UnitPrice 为按实际消费折算的每百万 token 单价
+type InvoiceItem struct {
+	ModelName        string  `json:"model_name"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Quota            int64   `json:"quota"`
+	UnitPrice        float64 `json:"unit_price"`
+	Amount           float64 `json:"amount"`
+}
+
+// Invoice 用户月度账单，金额按站点的额度展示货币计算
+type Invoice struct {
+	Number        string        `json:"number"`
+	UserId        int           `json:"user_id"`
+	Username      string        `json:"username"`
+	Period        string        `json:"period"`
+	PeriodStart   int64         `json:"period_start"`
+	PeriodEnd     int64         `json:"period_end"`
+	IssuedAt      int64         `json:"issued_at"`
+	Currency      string        `json:"currency"`
+	Items         []InvoiceItem `json:"items"`
+	TotalRequests int64         `json:"total_requests"`
+	TotalTokens   int64         `json:"total_tokens"`
+	TotalQuota    int64         `json:"total_quota"`
+	TotalAmount   float64       `json:"total_amount"`
+}
+
+// ParseInvoicePeriod 解析 YYYY-MM 格式的账单月份，为空时使用当月
+func ParseInvoicePeriod(period string) (time.Time, error) {
+	if period == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
+	}
+	return time.ParseInLocation(InvoicePeriodLayout, period, time.Local)
+}
+
+// invoiceCurrency 返回账单货币与相对美元的汇率，按额度点数展示的站点按美元出账
+func invoiceCurrency() (string, float64) {
+	switch operation_setting.GetQuotaDisplayType() {
+	case operation_setting.QuotaDisplayTypeCNY:
+		return "CNY", operation_setting.USDExchangeRate
+	case operation_setting.QuotaDisplayTypeCustom:
+		general := operation_setting.GetGeneralSetting()
+		rate := general.CustomCurrencyExchangeRate
+		if rate <= 0 {
+			rate = 1
+		}
+		symbol := general.CustomCurrencySymbol
+		if symbol == "" {
+			symbol = "¤"
+		}
+		return symbol, rate
+	default:
+		return "USD", 1
+	}
+}
+
+func roundInvoiceAmount(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// BuildMonthlyInvoice 汇总用户在指定月份的消费日志生成账单
+func BuildMonthlyInvoice(userId int, username string, periodStart time.Time) (*Invoice, error) {
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	usages, err := model.GetUserInvoiceUsages(userId, periodStart.Unix(), periodEnd.Unix())
+	if err != nil {
+		return nil, err
+	}
+	currency, rate := invoiceCurrency()
+	invoice := &Invoice{
+		Number:      fmt.Sprintf("INV-%s-%d", periodStart.Format("200601"), userId),
+		UserId:      userId,
+		Username:    username,
+		Period:      periodStart.Format(InvoicePeriodLayout),
+		PeriodStart: periodStart.Unix(),
+		PeriodEnd:   periodEnd.Unix(),
+		IssuedAt:    common.GetTimestamp(),
+		Currency:    currency,
+		Items:       make([]InvoiceItem, 0, len(usages)),
+	}
+	for _, usage := range usages {
+		item := InvoiceItem{
+			ModelName:        usage.ModelName,
+			Requests:         usage.Requests,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.PromptTokens + usage.CompletionTokens,
+			Quota:            usage.Quota,
+			Amount:           roundInvoiceAmount(float64(usage.Quota) / common.QuotaPerUnit * rate),
+		}
+		if item.TotalTokens > 0 {
+			item.UnitPrice = roundInvoiceAmount(float64(usage.Quota) / common.QuotaPerUnit * rate * 1e6 / float64(item.TotalTokens))
+		}
+		invoice.Items = append(invoice.Items, item)
+		invoice.TotalRequests += item.Requests
+		invoice.TotalTokens += item.TotalTokens
+		invoice.TotalQuota += item.Quota
+	}
+	invoice.TotalAmount = roundInvoiceAmount(float64(invoice.TotalQuota) / common.QuotaPerUnit * rate)
+	return invoice, nil
+}
+
+var invoiceCSVColumns = []string{"model_name", "requests", "prompt_tokens", "completion_tokens", "total_tokens", "unit_price_per_1m_tokens", "amount", "currency"}
+
+func formatInvoiceAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// WriteInvoiceCSV 以 CSV 输出账单明细，最后一行为合计
+func WriteInvoiceCSV(w io.Writer, invoice *Invoice) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write(invoiceCSVColumns)
+	for _, item := range invoice.Items {
+		_ = writer.Write([]string{
+			item.ModelName,
+			strconv.FormatInt(item.Requests, 10),
+			strconv.FormatInt(item.PromptTokens, 10),
+			strconv.FormatInt(item.CompletionTokens, 10),
+			strconv.FormatInt(item.TotalTokens, 10),
+			formatInvoiceAmount(item.UnitPrice),
+			formatInvoiceAmount(item.Amount),
+			invoice.Currency,
+		})
+	}
+	_ = writer.Write([]string{
+		"TOTAL",
+		strconv.FormatInt(invoice.TotalRequests, 10),
+		"",
+		"",
+		strconv.FormatInt(invoice.TotalTokens, 10),
+		"",
+		formatInvoiceAmount(invoice.TotalAmount),
+		invoice.Currency,
+	})
+	writer.Flush()
+	return writer.Error()
+}
+
+// RenderInvoicePDF 以纯文本排版输出账单 PDF
+func RenderInvoicePDF(invoice *Invoice) []byte {
+	lines := []string{
+		"INVOICE " + invoice.Number,
+		"",
+		fmt.Sprintf("Customer: %s (ID %d)", invoice.Username, invoice.UserId),
+		fmt.Sprintf("Period:   %s to %s", time.Unix(invoice.PeriodStart, 0).Format("2006-01-02"), time.Unix(invoice.PeriodEnd, 0).AddDate(0, 0, -1).Format("2006-01-02")),
+		fmt.Sprintf("Issued:   %s", time.Unix(invoice.IssuedAt, 0).Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("Currency: %s", invoice.Currency),
+		"",
+		fmt.Sprintf("%-32s %8s %12s %12s %14s %14s", "Model", "Requests", "Prompt", "Completion", "Price/1M", "Amount"),
+	}
+	for _, item := range invoice.Items {
+		lines = append(lines, fmt.Sprintf("%-32s %8d %12d %12d %14s %14s",
+			truncateInvoiceText(item.ModelName, 32), item.Requests, item.PromptTokens, item.CompletionTokens,
+			formatInvoiceAmount(item.UnitPrice), formatInvoiceAmount(item.Amount)))
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Total requests: %d", invoice.TotalRequests),
+		fmt.Sprintf("Total tokens:   %d", invoice.TotalTokens),
+		fmt.Sprintf("Total amount:   %s %s", formatInvoiceAmount(invoice.TotalAmount), invoice.Currency),
+	)
+	return renderTextPDF(lines)
+}
+
+func truncateInvoiceText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
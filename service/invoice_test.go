@@ -0,0 +1,49 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testInvoice() *Invoice {
+	return &Invoice{
+		Number:      "INV-202609-7",
+		UserId:      7,
+		Username:    "reseller",
+		Period:      "2026-09",
+		PeriodStart: 1788192000,
+		PeriodEnd:   1790784000,
+		IssuedAt:    1790784000,
+		Currency:    "USD",
+		Items: []InvoiceItem{
+			{ModelName: "gpt-4o", Requests: 2, PromptTokens: 300, CompletionTokens: 70, TotalTokens: 370, Quota: 700, UnitPrice: 3.783784, Amount: 0.0014},
+		},
+		TotalRequests: 2,
+		TotalTokens:   370,
+		TotalQuota:    700,
+		TotalAmount:   0.0014,
+	}
+}
+
+func TestWriteInvoiceCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInvoiceCSV(&buf, testInvoice()))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, "gpt-4o,2,300,70,370,3.783784,0.001400,USD", lines[1])
+	require.Equal(t, "TOTAL,2,,,370,,0.001400,USD", lines[2])
+}
+
+func TestRenderInvoicePDF(t *testing.T) {
+	invoice := testInvoice()
+	invoice.Username = "用户(a)"
+	pdf := string(RenderInvoicePDF(invoice))
+	require.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	require.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	require.Contains(t, pdf, "INVOICE INV-202609-7")
+	// 非 ASCII 字符替换为 ?，括号需要转义
+	require.Contains(t, pdf, `Customer: ??\(a\) \(ID 7\)`)
+}
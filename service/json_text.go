@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// promptTextKeys 请求体中承载用户文本的字段，仅改写这些字段下的字符串，
+// 避免误改模型名、工具参数 schema 等结构化字段
+var promptTextKeys = map[string]bool{
+	"content":      true,
+	"text":         true,
+	"input":        true,
+	"prompt":       true,
+	"system":       true,
+	"instructions": true,
+	"query":        true,
+	"documents":    true,
+}
+
+// completionTextKeys 响应体（含流式分片）中承载模型输出文本的字段
+var completionTextKeys = map[string]bool{
+	"content": true,
+	"text":    true,
+	"delta":   true,
+}
+
+// rewriteJSONStrings 遍历 JSON，对 keys 指定字段下的字符串值调用 fn 改写，
+// 仅修改发生变化的字段，其余内容（包括数字格式）保持原样。未发生改写时返回原始数据。
+func rewriteJSONStrings(body []byte, keys map[string]bool, fn func(string) string) ([]byte, bool, error) {
+	if !gjson.ValidBytes(body) {
+		return body, false, nil
+	}
+	type edit struct {
+		path  string
+		value string
+	}
+	var edits []edit
+	var walk func(result gjson.Result, path string, key string)
+	walk = func(result gjson.Result, path string, key string) {
+		switch {
+		case result.IsObject():
+			result.ForEach(func(k, v gjson.Result) bool {
+				walk(v, joinJSONPath(path, escapeJSONPathKey(k.String())), k.String())
+				return true
+			})
+		case result.IsArray():
+			index := 0
+			result.ForEach(func(_, v gjson.Result) bool {
+				// 数组元素继承所在字段名，兼容 "input": ["..."] 形式
+				walk(v, joinJSONPath(path, fmt.Sprintf("%d", index)), key)
+				index++
+				return true
+			})
+		case result.Type == gjson.String && keys[key]:
+			original := result.String()
+			if rewritten := fn(original); rewritten != original {
+				edits = append(edits, edit{path: path, value: rewritten})
+			}
+		}
+	}
+	walk(gjson.ParseBytes(body), "", "")
+	if len(edits) == 0 {
+		return body, false, nil
+	}
+	var err error
+	for _, e := range edits {
+		body, err = sjson.SetBytes(body, e.path, e.value)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return body, true, nil
+}
+
+func joinJSONPath(parent string, part string) string {
+	if parent == "" {
+		return part
+	}
+	return parent + "." + part
+}
+
+func escapeJSONPathKey(key string) string {
+	var builder strings.Builder
+	for _, ch := range key {
+		switch ch {
+		case '.', '*', '?', '|', '#', '@', '\\', ':', '!', '=', '<', '>', '%':
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(ch)
+	}
+	return builder.String()
+}
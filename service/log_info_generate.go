@@ -51,11 +51,49 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
+	if alias := common.GetContextKeyString(ctx, constant.ContextKeyRequestedModelAlias); alias != "" {
+		other["model_alias"] = alias
+	}
+	if cacheStatus := common.GetContextKeyString(ctx, constant.ContextKeyResponseCache); cacheStatus != "" {
+		other["response_cache"] = cacheStatus
+	}
+	if relayInfo.InjectedSystemPromptTokens > 0 {
+		other["injected_system_prompt_tokens"] = relayInfo.InjectedSystemPromptTokens
+	}
+	if batch, ok := relaycommon.GetBatchRequest(ctx); ok {
+		other["batch_id"] = batch.BatchId
+		other["batch_discount"] = batch.DiscountRatio
+	}
+	if multiplier := relayInfo.PriceData.GroupRatioInfo.TimeMultiplier; multiplier > 0 {
+		other["time_multiplier"] = multiplier
+	}
 
 	isSystemPromptOverwritten := common.GetContextKeyBool(ctx, constant.ContextKeySystemPromptOverride)
 	if isSystemPromptOverwritten {
 		other["is_system_prompt_overwritten"] = true
 	}
+	if flags := GetModerationFlags(ctx); len(flags) > 0 {
+		other["moderation_flags"] = flags
+	}
+	if hits := GetBlocklistHits(ctx); len(hits) > 0 {
+		other["blocklist_hits"] = hits
+	}
+	if injection, ok := GetPromptInjectionResult(ctx); ok {
+		other["prompt_injection_score"] = injection.Score
+		other["prompt_injection_rules"] = injection.Rules
+	}
+	if verdicts := GetImageSafetyVerdicts(ctx); len(verdicts) > 0 {
+		other["image_safety"] = verdicts
+	}
+	if hits := GetAbusePatternHits(ctx); len(hits) > 0 {
+		other["abuse_pattern_hits"] = hits
+	}
+	if secretTypes := GetSecretLeakTypes(ctx); len(secretTypes) > 0 {
+		other["secret_leak_types"] = secretTypes
+	}
+	if common.IsNoStore(ctx) {
+		other["no_store"] = true
+	}
 
 	adminInfo := make(map[string]interface{})
 	adminInfo["use_channel"] = ctx.GetStringSlice("use_channel")
@@ -65,9 +103,10 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		adminInfo["multi_key_index"] = common.GetContextKeyInt(ctx, constant.ContextKeyChannelMultiKeyIndex)
 	}
 
-	isLocalCountTokens := common.GetContextKeyBool(ctx, constant.ContextKeyLocalCountTokens)
+	isLocalCountTokens := IsUsageEstimated(ctx)
 	if isLocalCountTokens {
 		adminInfo["local_count_tokens"] = isLocalCountTokens
+		other["usage_estimated"] = true
 	}
 
 	AppendChannelAffinityAdminInfo(ctx, adminInfo)
@@ -95,7 +134,10 @@ func appendStreamStatus(relayInfo *relaycommon.RelayInfo, other map[string]inter
 	}
 	ss := relayInfo.StreamStatus
 	status := "ok"
-	if !ss.IsNormalEnd() || ss.HasErrors() {
+	if ss.EndReason == relaycommon.StreamEndReasonClientGone {
+		// 客户端中途断开，仅按断开前已生成的内容计费
+		status = "client_cancelled"
+	} else if !ss.IsNormalEnd() || ss.HasErrors() {
 		status = "error"
 	}
 	streamInfo := map[string]interface{}{
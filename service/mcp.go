@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/mcp"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+const (
+	// MCPToolNameSeparator 上游工具名称前缀分隔符，工具对外名称为 "服务器名__工具名"
+	MCPToolNameSeparator = "__"
+	mcpUpstreamTimeout   = 60 * time.Second
+)
+
+// MCPUpstreamTool 上游 MCP 服务器提供的工具，Tool.Name 为带服务器前缀的名称
+type MCPUpstreamTool struct {
+	Server   string
+	Original string
+	Tool     mcp.Tool
+}
+
+type mcpToolCacheEntry struct {
+	tools     []mcp.Tool
+	expiresAt time.Time
+}
+
+var (
+	mcpClientsMu   sync.Mutex
+	mcpClients     = make(map[string]*mcp.Client) // 配置指纹 -> 客户端，复用上游会话
+	mcpToolCacheMu sync.Mutex
+	mcpToolCache   = make(map[string]mcpToolCacheEntry)
+)
+
+func mcpServerKey(server system_setting.MCPUpstreamServer) string {
+	headers, _ := common.Marshal(server.Headers)
+	return server.Name + "\n" + server.Url + "\n" + string(headers)
+}
+
+func getMCPClient(server system_setting.MCPUpstreamServer) (*mcp.Client, error) {
+	fetchSetting := system_setting.GetFetchSetting()
+	if err := common.ValidateURLWithFetchSetting(server.Url, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", server.Name, err)
+	}
+	key := mcpServerKey(server)
+	mcpClientsMu.Lock()
+	defer mcpClientsMu.Unlock()
+	client, ok := mcpClients[key]
+	if !ok {
+		client = mcp.NewClient(server.Url, server.Headers, GetHttpClient(), mcp.Implementation{Name: "new-api", Version: common.Version})
+		mcpClients[key] = client
+	}
+	return client, nil
+}
+
+// GetMCPServerTools 获取上游服务器的工具列表，按配置的时间缓存
+func GetMCPServerTools(ctx context.Context, server system_setting.MCPUpstreamServer) ([]mcp.Tool, error) {
+	key := mcpServerKey(server)
+	mcpToolCacheMu.Lock()
+	entry, ok := mcpToolCache[key]
+	mcpToolCacheMu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.tools, nil
+	}
+
+	client, err := getMCPClient(server)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpUpstreamTimeout)
+	defer cancel()
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", server.Name, err)
+	}
+	ttl := time.Duration(system_setting.GetMCPSetting().ToolCacheSeconds) * time.Second
+	if ttl > 0 {
+		mcpToolCacheMu.Lock()
+		mcpToolCache[key] = mcpToolCacheEntry{tools: tools, expiresAt: time.Now().Add(ttl)}
+		mcpToolCacheMu.Unlock()
+	}
+	return tools, nil
+}
+
+// ListMCPUpstreamTools 汇总指定上游服务器（names 为空表示全部已启用服务器）的工具，单个服务器失败时跳过并记录日志
+func ListMCPUpstreamTools(ctx context.Context, names []string) []MCPUpstreamTool {
+	var tools []MCPUpstreamTool
+	for _, server := range system_setting.GetMCPSetting().UpstreamServers {
+		if !server.Enabled || (len(names) > 0 && !common.StringsContains(names, server.Name)) {
+			continue
+		}
+		serverTools, err := GetMCPServerTools(ctx, server)
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("failed to list mcp tools: %v", err))
+			continue
+		}
+		for _, tool := range serverTools {
+			prefixed := tool
+			prefixed.Name = server.Name + MCPToolNameSeparator + tool.Name
+			tools = append(tools, MCPUpstreamTool{Server: server.Name, Original: tool.Name, Tool: prefixed})
+		}
+	}
+	return tools
+}
+
+// SplitMCPToolName 将带前缀的工具名称拆分为服务器名与上游工具名
+func SplitMCPToolName(name string) (server string, tool string, ok bool) {
+	server, tool, ok = strings.Cut(name, MCPToolNameSeparator)
+	if !ok || server == "" || tool == "" {
+		return "", "", false
+	}
+	return server, tool, true
+}
+
+// CallMCPUpstreamTool 调用带前缀名称的上游工具
+func CallMCPUpstreamTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	serverName, toolName, ok := SplitMCPToolName(name)
+	if !ok {
+		return nil, fmt.Errorf("invalid mcp tool name: %s", name)
+	}
+	server, ok := system_setting.GetMCPUpstreamServer(serverName)
+	if !ok {
+		return nil, fmt.Errorf("mcp server %s is not registered", serverName)
+	}
+	client, err := getMCPClient(server)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpUpstreamTimeout)
+	defer cancel()
+	result, err := client.CallTool(ctx, toolName, args)
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", serverName, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+// ResolveModelAlias 解析全局模型别名，available 判断候选模型当前是否有可用渠道。
+// 非别名时原样返回；候选均不可用时返回第一个候选，由后续的渠道选择给出无可用渠道的错误
+func ResolveModelAlias(modelName string, available func(string) bool) (string, bool) {
+	alias, ok := model_setting.GetModelAlias(modelName)
+	if !ok {
+		return modelName, false
+	}
+	resolved := ""
+	bestCost, bestPriced := 0.0, false
+	for _, candidate := range alias.Models {
+		if !available(candidate) {
+			continue
+		}
+		if alias.Strategy != model_setting.ModelAliasStrategyCheapest {
+			resolved = candidate
+			break
+		}
+		cost, priced := approxModelCost(candidate)
+		if resolved == "" || cheaperCandidate(candidate, cost, priced, resolved, bestCost, bestPriced) {
+			resolved, bestCost, bestPriced = candidate, cost, priced
+		}
+	}
+	if resolved == "" {
+		resolved = alias.Models[0]
+	}
+	return resolved, resolved != modelName
+}
+
+// AvailableModelAliases 返回至少有一个候选模型在 models 中的别名，用于模型列表展示
+func AvailableModelAliases(models []string) []string {
+	if !model_setting.GetModelAliasSettings().Enabled {
+		return nil
+	}
+	enabled := make(map[string]bool, len(models))
+	for _, m := range models {
+		enabled[m] = true
+	}
+	var aliases []string
+	for name, alias := range model_setting.GetModelAliasSettings().Aliases {
+		for _, candidate := range alias.Models {
+			if enabled[candidate] {
+				aliases = append(aliases, name)
+				break
+			}
+		}
+	}
+	return aliases
+}
+
+// cheaperCandidate 比较两个候选模型：未配置价格的排在最后，成本相同时按模型名排序，保证结果稳定
+func cheaperCandidate(name string, cost float64, priced bool, bestName string, bestCost float64, bestPriced bool) bool {
+	if priced != bestPriced {
+		return priced
+	}
+	if priced && cost != bestCost {
+		return cost < bestCost
+	}
+	return name < bestName
+}
+
+// approxModelCost 估算单次请求的美元成本用于比较：按次计费取价格，按倍率计费按 1K 输入 + 1K 输出 token 估算
+// （倍率 1 = $0.002 / 1K tokens，输出按补全倍率折算），未配置价格时 priced 为 false
+func approxModelCost(modelName string) (float64, bool) {
+	value, usePrice, exist := ratio_setting.GetModelRatioOrPrice(modelName)
+	if !exist {
+		return 0, false
+	}
+	if usePrice {
+		return value, true
+	}
+	completionRatio := ratio_setting.GetCompletionRatio(modelName)
+	return (value + value*completionRatio) * 0.002, true
+}
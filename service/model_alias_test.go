@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveModelAlias(t *testing.T) {
+	settings := model_setting.GetModelAliasSettings()
+	original := *settings
+	t.Cleanup(func() { *settings = original })
+	seedModelAliasRatios(t)
+	settings.Enabled = true
+	settings.Aliases = map[string]model_setting.ModelAlias{
+		"gpt-4": {Models: []string{"gpt-4o-2024-11-20", "gpt-4o"}},
+		"fast":  {Models: []string{"gpt-4o", "gpt-4o-mini"}, Strategy: model_setting.ModelAliasStrategyCheapest},
+		// 输入便宜但输出昂贵的模型不应胜出；未配置价格的模型排在最后
+		"balanced": {Models: []string{"unpriced-model", "cheap-input", "balanced-model"}, Strategy: model_setting.ModelAliasStrategyCheapest},
+		// 成本相同时按模型名排序
+		"tie": {Models: []string{"tie-b", "tie-a"}, Strategy: model_setting.ModelAliasStrategyCheapest},
+	}
+	all := func(string) bool { return true }
+
+	resolved, aliased := ResolveModelAlias("gpt-4", func(m string) bool { return m == "gpt-4o" })
+	require.True(t, aliased)
+	require.Equal(t, "gpt-4o", resolved)
+
+	resolved, aliased = ResolveModelAlias("fast", all)
+	require.True(t, aliased)
+	require.Equal(t, "gpt-4o-mini", resolved)
+
+	resolved, _ = ResolveModelAlias("balanced", all)
+	require.Equal(t, "balanced-model", resolved)
+	resolved, _ = ResolveModelAlias("balanced", func(m string) bool { return m == "unpriced-model" || m == "cheap-input" })
+	require.Equal(t, "cheap-input", resolved)
+	resolved, _ = ResolveModelAlias("tie", all)
+	require.Equal(t, "tie-a", resolved)
+
+	// 候选均不可用时返回第一个候选，由渠道选择报错
+	resolved, _ = ResolveModelAlias("gpt-4", func(string) bool { return false })
+	require.Equal(t, "gpt-4o-2024-11-20", resolved)
+
+	resolved, aliased = ResolveModelAlias("claude-sonnet-4", all)
+	require.False(t, aliased)
+	require.Equal(t, "claude-sonnet-4", resolved)
+
+	settings.Enabled = false
+	_, aliased = ResolveModelAlias("gpt-4", all)
+	require.False(t, aliased)
+}
+
+// seedModelAliasRatios 写入测试用的模型倍率与补全倍率，结束后恢复原配置
+func seedModelAliasRatios(t *testing.T) {
+	t.Helper()
+	originalModelRatio := ratio_setting.ModelRatio2JSONString()
+	originalCompletionRatio := ratio_setting.CompletionRatio2JSONString()
+	t.Cleanup(func() {
+		_ = ratio_setting.UpdateModelRatioByJSONString(originalModelRatio)
+		_ = ratio_setting.UpdateCompletionRatioByJSONString(originalCompletionRatio)
+	})
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"gpt-4o":1.25,"gpt-4o-mini":0.075,"cheap-input":1,"balanced-model":2,"tie-a":1,"tie-b":1}`))
+	require.NoError(t, ratio_setting.UpdateCompletionRatioByJSONString(`{"gpt-4o":4,"gpt-4o-mini":4,"cheap-input":10,"balanced-model":2,"tie-a":1,"tie-b":1}`))
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/billing_setting"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+)
+
+// ModelCatalogItem 模型目录中的单个模型，合并已配置渠道的模型、能力元数据与当前价格
+type ModelCatalogItem struct {
+	ModelName              string                  `json:"model_name"`
+	Description            string                  `json:"description,omitempty"`
+	Icon                   string                  `json:"icon,omitempty"`
+	Tags                   string                  `json:"tags,omitempty"`
+	VendorID               int                     `json:"vendor_id,omitempty"`
+	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
+	// 能力元数据，未知时为零值
+	model_setting.ModelCapability
+	BillingMode string `json:"billing_mode,omitempty"`
+	// Pricing 为各可用分组的当前价格，已计入分组倍率与当前时段的分时倍率
+	Pricing []ModelGroupPricing `json:"pricing"`
+}
+
+// BuildModelCatalog 生成用户可用分组下的模型目录，按模型名排序
+func BuildModelCatalog(userGroup string, usableGroups map[string]string, now time.Time) []ModelCatalogItem {
+	return buildModelCatalog(model.GetPricing(), userGroup, usableGroups, now)
+}
+
+func buildModelCatalog(pricing []model.Pricing, userGroup string, usableGroups map[string]string, now time.Time) []ModelCatalogItem {
+	groups := make([]string, 0, len(usableGroups))
+	for group := range usableGroups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	catalog := make([]ModelCatalogItem, 0, len(pricing))
+	for _, p := range pricing {
+		item := ModelCatalogItem{
+			ModelName:              p.ModelName,
+			Description:            p.Description,
+			Icon:                   p.Icon,
+			Tags:                   p.Tags,
+			VendorID:               p.VendorID,
+			SupportedEndpointTypes: p.SupportedEndpointTypes,
+			BillingMode:            p.BillingMode,
+			Pricing:                make([]ModelGroupPricing, 0),
+		}
+		for _, group := range groups {
+			if !common.StringsContains(p.EnableGroup, group) && !common.StringsContains(p.EnableGroup, "all") {
+				continue
+			}
+			groupRatio := resolveGroupRatio(userGroup, group)
+			if multiplier, ok := billing_setting.GetTimePricingMultiplier(group, p.ModelName, now); ok {
+				groupRatio *= multiplier
+			}
+			item.Pricing = append(item.Pricing, buildModelGroupPricing(p, group, groupRatio))
+		}
+		// 用户没有任何可用分组的模型不出现在目录中
+		if len(item.Pricing) == 0 {
+			continue
+		}
+		item.ModelCapability, _ = model_setting.GetModelCapability(p.ModelName)
+		catalog = append(catalog, item)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].ModelName < catalog[j].ModelName
+	})
+	return catalog
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildModelCatalog(t *testing.T) {
+	pricing := []model.Pricing{
+		{ModelName: "gpt-4o", ModelRatio: 1.25, CompletionRatio: 4, EnableGroup: []string{"default"}},
+		{ModelName: "claude-opus-4", ModelRatio: 7.5, CompletionRatio: 5, EnableGroup: []string{"vip"}},
+		{ModelName: "dall-e-3", QuotaType: 1, ModelPrice: 0.04, EnableGroup: []string{"all"}},
+	}
+	catalog := buildModelCatalog(pricing, "default", map[string]string{"default": ""}, time.Now())
+
+	// 没有可用分组的模型不出现，结果按模型名排序
+	require.Len(t, catalog, 2)
+	require.Equal(t, "dall-e-3", catalog[0].ModelName)
+	require.Equal(t, "gpt-4o", catalog[1].ModelName)
+
+	gpt := catalog[1]
+	require.Equal(t, 128000, gpt.ContextWindow)
+	require.True(t, gpt.SupportsTools)
+	require.True(t, gpt.SupportsVision)
+	require.Len(t, gpt.Pricing, 1)
+	require.InDelta(t, 2.5, *gpt.Pricing[0].InputPerMillion, 1e-9)
+	require.InDelta(t, 10, *gpt.Pricing[0].OutputPerMillion, 1e-9)
+
+	dalle := catalog[0]
+	require.Equal(t, []string{"image"}, dalle.OutputModalities)
+	require.InDelta(t, 0.04, *dalle.Pricing[0].PerRequest, 1e-9)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+// ModelGroupPricing 模型在某个分组下的价格（美元，已乘分组倍率）；按量计费给出每百万 token 价格，按次计费给出每次价格
+type ModelGroupPricing struct {
+	Group                string   `json:"group"`
+	GroupRatio           float64  `json:"group_ratio"`
+	QuotaType            int      `json:"quota_type"`
+	InputPerMillion      *float64 `json:"input_per_million,omitempty"`
+	OutputPerMillion     *float64 `json:"output_per_million,omitempty"`
+	CacheReadPerMillion  *float64 `json:"cache_read_per_million,omitempty"`
+	CacheWritePerMillion *float64 `json:"cache_write_per_million,omitempty"`
+	PerRequest           *float64 `json:"per_request,omitempty"`
+}
+
+// ModelWithMetadata /v1/models?include_metadata=true 的单个模型
+type ModelWithMetadata struct {
+	dto.OpenAIModels
+	Capabilities *model_setting.ModelCapability `json:"capabilities,omitempty"`
+	BillingMode  string                         `json:"billing_mode,omitempty"`
+	Pricing      []ModelGroupPricing            `json:"pricing"`
+}
+
+// BuildModelsWithMetadata 为模型列表附加能力元数据与各分组价格，groups 为令牌可使用的分组
+func BuildModelsWithMetadata(models []dto.OpenAIModels, userGroup string, groups []string) []ModelWithMetadata {
+	pricingMap := make(map[string]model.Pricing)
+	for _, pricing := range model.GetPricing() {
+		pricingMap[pricing.ModelName] = pricing
+	}
+	result := make([]ModelWithMetadata, 0, len(models))
+	for _, m := range models {
+		item := ModelWithMetadata{OpenAIModels: m, Pricing: make([]ModelGroupPricing, 0)}
+		if capability, ok := model_setting.GetModelCapability(m.Id); ok {
+			item.Capabilities = &capability
+		}
+		if pricing, ok := pricingMap[m.Id]; ok {
+			item.BillingMode = pricing.BillingMode
+			for _, group := range groups {
+				if !common.StringsContains(pricing.EnableGroup, group) && !common.StringsContains(pricing.EnableGroup, "all") {
+					continue
+				}
+				item.Pricing = append(item.Pricing, buildModelGroupPricing(pricing, group, resolveGroupRatio(userGroup, group)))
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func resolveGroupRatio(userGroup string, group string) float64 {
+	if ratio, ok := ratio_setting.GetGroupGroupRatio(userGroup, group); ok {
+		return ratio
+	}
+	return ratio_setting.GetGroupRatio(group)
+}
+
+func buildModelGroupPricing(pricing model.Pricing, group string, groupRatio float64) ModelGroupPricing {
+	item := ModelGroupPricing{Group: group, GroupRatio: groupRatio, QuotaType: pricing.QuotaType}
+	if pricing.QuotaType == 1 {
+		item.PerRequest = common.GetPointer(pricing.ModelPrice * groupRatio)
+		return item
+	}
+	// 倍率 1 对应每 token 消耗 1 额度
+	input := pricing.ModelRatio * groupRatio * 1000000 / common.QuotaPerUnit
+	item.InputPerMillion = common.GetPointer(input)
+	item.OutputPerMillion = common.GetPointer(input * pricing.CompletionRatio)
+	if pricing.CacheRatio != nil {
+		item.CacheReadPerMillion = common.GetPointer(input * *pricing.CacheRatio)
+	}
+	if pricing.CreateCacheRatio != nil {
+		item.CacheWritePerMillion = common.GetPointer(input * *pricing.CreateCacheRatio)
+	}
+	return item
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	modelQueueLock    sync.Mutex
+	modelQueueWaiting = make(map[string]int)
+
+	// channelSlotReleased 本节点有渠道释放并发名额时关闭并替换，用于唤醒排队中的请求
+	channelSlotReleasedLock sync.Mutex
+	channelSlotReleased     = make(chan struct{})
+)
+
+// ModelQueueTicket 单个请求在模型队列中的排队状态，同一请求多次等待共享一个截止时间和队列名额
+type ModelQueueTicket struct {
+	modelName string
+	rule      operation_setting.ModelQueueRule
+	enabled   bool
+	joined    bool
+	deadline  time.Time
+}
+
+// NewModelQueueTicket 创建请求的排队凭证，请求结束时需调用 Leave
+func NewModelQueueTicket(modelName string) *ModelQueueTicket {
+	rule, ok := operation_setting.GetModelQueueSetting().GetRule(modelName)
+	return &ModelQueueTicket{modelName: modelName, rule: rule, enabled: ok}
+}
+
+// Wait 在所有渠道并发已满时排队等待名额释放。
+// 返回 true 表示有名额释放，应重新选择渠道；返回 false 且无错误表示该模型未启用排队，沿用原错误；
+// 队列已满或等待超时时返回 429
+func (t *ModelQueueTicket) Wait(c *gin.Context) (bool, *types.NewAPIError) {
+	if !t.enabled {
+		return false, nil
+	}
+	if !t.joined {
+		if !joinModelQueue(t.modelName, t.rule.MaxWaiting) {
+			return false, types.NewErrorWithStatusCode(
+				fmt.Errorf("model %s is overloaded, request queue is full (%d)", t.modelName, t.rule.MaxWaiting),
+				types.ErrorCodeModelQueueFull,
+				http.StatusTooManyRequests,
+				types.ErrOptionWithSkipRetry(),
+			)
+		}
+		t.joined = true
+		t.deadline = time.Now().Add(t.rule.WaitTimeout())
+	}
+	remaining := time.Until(t.deadline)
+	if remaining <= 0 {
+		return false, modelQueueTimeoutError(t.modelName, t.rule.WaitSeconds)
+	}
+	// 多节点部署时其他节点释放名额不会通知本节点，按轮询间隔兜底重试
+	timer := time.NewTimer(min(remaining, channelConcurrencyPollInterval*5))
+	defer timer.Stop()
+	select {
+	case <-c.Request.Context().Done():
+		return false, types.NewErrorWithStatusCode(c.Request.Context().Err(), types.ErrorCodeModelQueueTimeout, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	case <-channelSlotReleasedSignal():
+	case <-timer.C:
+	}
+	return true, nil
+}
+
+// Leave 离开队列，释放排队名额
+func (t *ModelQueueTicket) Leave() {
+	if !t.joined {
+		return
+	}
+	t.joined = false
+	leaveModelQueue(t.modelName)
+}
+
+func modelQueueTimeoutError(modelName string, waitSeconds int) *types.NewAPIError {
+	return types.NewErrorWithStatusCode(
+		fmt.Errorf("model %s is overloaded, no channel became available within %ds", modelName, waitSeconds),
+		types.ErrorCodeModelQueueTimeout,
+		http.StatusTooManyRequests,
+		types.ErrOptionWithSkipRetry(),
+	)
+}
+
+func joinModelQueue(modelName string, maxWaiting int) bool {
+	modelQueueLock.Lock()
+	defer modelQueueLock.Unlock()
+	if modelQueueWaiting[modelName] >= maxWaiting {
+		return false
+	}
+	modelQueueWaiting[modelName]++
+	return true
+}
+
+func leaveModelQueue(modelName string) {
+	modelQueueLock.Lock()
+	defer modelQueueLock.Unlock()
+	if modelQueueWaiting[modelName] <= 1 {
+		delete(modelQueueWaiting, modelName)
+		return
+	}
+	modelQueueWaiting[modelName]--
+}
+
+// GetModelQueueWaiting 返回本节点各模型当前排队的请求数
+func GetModelQueueWaiting() map[string]int {
+	modelQueueLock.Lock()
+	defer modelQueueLock.Unlock()
+	waiting := make(map[string]int, len(modelQueueWaiting))
+	for modelName, count := range modelQueueWaiting {
+		waiting[modelName] = count
+	}
+	return waiting
+}
+
+func channelSlotReleasedSignal() <-chan struct{} {
+	channelSlotReleasedLock.Lock()
+	defer channelSlotReleasedLock.Unlock()
+	return channelSlotReleased
+}
+
+func notifyChannelSlotReleased() {
+	channelSlotReleasedLock.Lock()
+	defer channelSlotReleasedLock.Unlock()
+	close(channelSlotReleased)
+	channelSlotReleased = make(chan struct{})
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func withModelQueueSetting(t *testing.T, models map[string]operation_setting.ModelQueueRule) {
+	setting := operation_setting.GetModelQueueSetting()
+	original := *setting
+	setting.Enabled = true
+	setting.Models = models
+	t.Cleanup(func() { *setting = original })
+}
+
+func TestModelQueueTicketDisabled(t *testing.T) {
+	withModelQueueSetting(t, map[string]operation_setting.ModelQueueRule{
+		"gpt-4o": {MaxWaiting: 1, WaitSeconds: 1},
+	})
+	ticket := NewModelQueueTicket("other-model")
+	defer ticket.Leave()
+
+	wakeUp, newAPIError := ticket.Wait(newChannelConcurrencyContext())
+	require.False(t, wakeUp)
+	require.Nil(t, newAPIError)
+}
+
+func TestModelQueueTicketOverflow(t *testing.T) {
+	withModelQueueSetting(t, map[string]operation_setting.ModelQueueRule{
+		"*": {MaxWaiting: 1, WaitSeconds: 1},
+	})
+	ctx := newChannelConcurrencyContext()
+	first := NewModelQueueTicket("queue-overflow-model")
+	defer first.Leave()
+	_, newAPIError := first.Wait(ctx)
+	require.Nil(t, newAPIError)
+
+	second := NewModelQueueTicket("queue-overflow-model")
+	defer second.Leave()
+	wakeUp, newAPIError := second.Wait(ctx)
+	require.False(t, wakeUp)
+	require.NotNil(t, newAPIError)
+	require.Equal(t, types.ErrorCodeModelQueueFull, newAPIError.GetErrorCode())
+	require.Equal(t, http.StatusTooManyRequests, newAPIError.StatusCode)
+
+	first.Leave()
+	require.Zero(t, GetModelQueueWaiting()["queue-overflow-model"])
+}
+
+func TestModelQueueTicketWakesOnRelease(t *testing.T) {
+	withModelQueueSetting(t, map[string]operation_setting.ModelQueueRule{
+		"*": {MaxWaiting: 1, WaitSeconds: 5},
+	})
+	ctx := newChannelConcurrencyContext()
+	channel := &model.Channel{Id: 9201, OtherSettings: `{"max_concurrency":1}`}
+	release, newAPIError := AcquireChannelConcurrency(ctx, channel)
+	require.Nil(t, newAPIError)
+
+	ticket := NewModelQueueTicket("queue-wake-model")
+	defer ticket.Leave()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+	}()
+
+	// 名额释放时立即唤醒，不必等到轮询间隔
+	start := time.Now()
+	wakeUp, newAPIError := ticket.Wait(ctx)
+	require.True(t, wakeUp)
+	require.Nil(t, newAPIError)
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestModelQueueTicketTimeout(t *testing.T) {
+	withModelQueueSetting(t, map[string]operation_setting.ModelQueueRule{
+		"*": {MaxWaiting: 1, WaitSeconds: 1},
+	})
+	ctx := newChannelConcurrencyContext()
+	ticket := NewModelQueueTicket("queue-timeout-model")
+	defer ticket.Leave()
+
+	var newAPIError *types.NewAPIError
+	for wakeUp := true; wakeUp; {
+		wakeUp, newAPIError = ticket.Wait(ctx)
+	}
+	require.NotNil(t, newAPIError)
+	require.Equal(t, types.ErrorCodeModelQueueTimeout, newAPIError.GetErrorCode())
+}
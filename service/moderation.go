@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultModerationTimeout = 10 * time.Second
+
+// ModerationVerdict 审核结果
+type ModerationVerdict struct {
+	Flagged    bool     `json:"flagged"`
+	Step       string   `json:"step"`
+	Stage      string   `json:"stage"`
+	Action     string   `json:"action"`
+	Categories []string `json:"categories,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+}
+
+// ModerationInput 审核的上下文信息
+type ModerationInput struct {
+	Stage string
+	Group string
+	Model string
+	Text  string
+}
+
+// ModerationChecker 审核步骤的实现，按步骤类型注册
+type ModerationChecker interface {
+	Check(ctx context.Context, step moderation_setting.Step, input ModerationInput) (*ModerationVerdict, error)
+}
+
+var (
+	moderationCheckersMu sync.RWMutex
+	moderationCheckers   = map[string]ModerationChecker{
+		moderation_setting.StepTypeKeyword: keywordModerationChecker{},
+		moderation_setting.StepTypeOpenAI:  openAIModerationChecker{},
+		moderation_setting.StepTypeHttp:    httpModerationChecker{},
+	}
+)
+
+// RegisterModerationChecker 注册或替换某一类型的审核实现
+func RegisterModerationChecker(stepType string, checker ModerationChecker) {
+	moderationCheckersMu.Lock()
+	defer moderationCheckersMu.Unlock()
+	moderationCheckers[stepType] = checker
+}
+
+func getModerationChecker(stepType string) (ModerationChecker, bool) {
+	moderationCheckersMu.RLock()
+	defer moderationCheckersMu.RUnlock()
+	checker, ok := moderationCheckers[stepType]
+	return checker, ok
+}
+
+// ShouldModerate 判断分组在指定阶段是否需要审核
+func ShouldModerate(group string, stage string) bool {
+	setting := moderation_setting.GetModerationSetting()
+	if !setting.Enabled {
+		return false
+	}
+	policy := moderation_setting.GetPolicy(group)
+	switch stage {
+	case moderation_setting.StageInput:
+		return policy.InputEnabled
+	case moderation_setting.StageOutput:
+		return policy.OutputEnabled
+	}
+	return false
+}
+
+// RunModeration 依次执行分组策略中的审核步骤，返回第一个命中的结果。
+// 单个步骤执行失败时记录日志并跳过（fail-open），避免审核服务故障影响主链路。
+func RunModeration(ctx context.Context, input ModerationInput) *ModerationVerdict {
+	if strings.TrimSpace(input.Text) == "" {
+		return nil
+	}
+	policy := moderation_setting.GetPolicy(input.Group)
+	for _, step := range moderation_setting.StepsFor(policy, input.Stage) {
+		checker, ok := getModerationChecker(step.Type)
+		if !ok {
+			logger.LogWarn(ctx, fmt.Sprintf("unknown moderation step type %s (step %s)", step.Type, step.Name))
+			continue
+		}
+		verdict, err := checker.Check(ctx, step, input)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("moderation step %s failed: %s", step.Name, err.Error()))
+			continue
+		}
+		if verdict != nil && verdict.Flagged {
+			verdict.Step = step.Name
+			verdict.Stage = input.Stage
+			verdict.Action = policy.Action
+			return verdict
+		}
+	}
+	return nil
+}
+
+// ModerateInput 对请求输入执行审核，策略为 block 时返回错误，flag/log 时记录后放行
+func ModerateInput(c *gin.Context, group string, modelName string, text string) *types.NewAPIError {
+	if !ShouldModerate(group, moderation_setting.StageInput) {
+		return nil
+	}
+	verdict := RunModeration(c, ModerationInput{
+		Stage: moderation_setting.StageInput,
+		Group: group,
+		Model: modelName,
+		Text:  text,
+	})
+	if verdict == nil {
+		return nil
+	}
+	return HandleModerationVerdict(c, verdict)
+}
+
+// HandleModerationVerdict 按动作处理命中的审核结果，需要拦截时返回错误
+func HandleModerationVerdict(c *gin.Context, verdict *ModerationVerdict) *types.NewAPIError {
+	message := fmt.Sprintf("moderation %s hit by step %s, categories: %s, reason: %s",
+		verdict.Stage, verdict.Step, strings.Join(verdict.Categories, ","), verdict.Reason)
+	switch verdict.Action {
+	case moderation_setting.ActionBlock:
+		logger.LogWarn(c, message)
+		recordModerationFlag(c, verdict)
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("content rejected by moderation policy (%s)", verdict.Step),
+			types.ErrorCodeModerationBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	case moderation_setting.ActionFlag:
+		logger.LogWarn(c, message)
+		recordModerationFlag(c, verdict)
+	default:
+		logger.LogInfo(c, message)
+	}
+	return nil
+}
+
+func recordModerationFlag(c *gin.Context, verdict *ModerationVerdict) {
+	var flags []*ModerationVerdict
+	if v, ok := common.GetContextKey(c, constant.ContextKeyModerationFlags); ok {
+		flags, _ = v.([]*ModerationVerdict)
+	}
+	common.SetContextKey(c, constant.ContextKeyModerationFlags, append(flags, verdict))
+}
+
+// GetModerationFlags 返回本次请求中被标记或拦截的审核结果，用于写入消费日志与错误日志
+func GetModerationFlags(c *gin.Context) []*ModerationVerdict {
+	if v, ok := common.GetContextKey(c, constant.ContextKeyModerationFlags); ok {
+		flags, _ := v.([]*ModerationVerdict)
+		return flags
+	}
+	return nil
+}
+
+type keywordModerationChecker struct{}
+
+func (keywordModerationChecker) Check(ctx context.Context, step moderation_setting.Step, input ModerationInput) (*ModerationVerdict, error) {
+	if len(step.Keywords) > 0 {
+		if hit, words := AcSearch(strings.ToLower(input.Text), step.Keywords, true); hit {
+			return &ModerationVerdict{
+				Flagged:    true,
+				Categories: []string{"keyword"},
+				Reason:     strings.Join(words, ","),
+			}, nil
+		}
+	}
+	for _, pattern := range step.Patterns {
+		re, err := compileModerationPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if re.MatchString(input.Text) {
+			return &ModerationVerdict{
+				Flagged:    true,
+				Categories: []string{"regex"},
+				Reason:     pattern,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+var moderationPatternCache sync.Map // pattern -> *regexp.Regexp
+
+func compileModerationPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := moderationPatternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	moderationPatternCache.Store(pattern, re)
+	return re, nil
+}
+
+type openAIModerationChecker struct{}
+
+func (openAIModerationChecker) Check(ctx context.Context, step moderation_setting.Step, input ModerationInput) (*ModerationVerdict, error) {
+	baseUrl := strings.TrimSuffix(step.BaseUrl, "/")
+	if baseUrl == "" {
+		baseUrl = "https://api.openai.com"
+	}
+	body := map[string]any{"input": input.Text}
+	if step.Model != "" {
+		body["model"] = step.Model
+	}
+	headers := map[string]string{"Authorization": "Bearer " + step.ApiKey}
+	var resp struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := postModerationRequest(ctx, step, baseUrl+"/v1/moderations", headers, body, &resp); err != nil {
+		return nil, err
+	}
+	for _, result := range resp.Results {
+		if !result.Flagged {
+			continue
+		}
+		categories := make([]string, 0, len(result.Categories))
+		for category, hit := range result.Categories {
+			if hit {
+				categories = append(categories, category)
+			}
+		}
+		return &ModerationVerdict{Flagged: true, Categories: categories}, nil
+	}
+	return nil, nil
+}
+
+type httpModerationChecker struct{}
+
+func (httpModerationChecker) Check(ctx context.Context, step moderation_setting.Step, input ModerationInput) (*ModerationVerdict, error) {
+	body := map[string]any{
+		"stage": input.Stage,
+		"group": input.Group,
+		"model": input.Model,
+		"text":  input.Text,
+	}
+	var verdict ModerationVerdict
+	if err := postModerationRequest(ctx, step, step.Url, step.Headers, body, &verdict); err != nil {
+		return nil, err
+	}
+	if !verdict.Flagged {
+		return nil, nil
+	}
+	return &verdict, nil
+}
+
+func postModerationRequest(ctx context.Context, step moderation_setting.Step, url string, headers map[string]string, body any, out any) error {
+	timeout := defaultModerationTimeout
+	if step.TimeoutSeconds > 0 {
+		timeout = time.Duration(step.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := common.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("moderation request failed with status code %d: %s", resp.StatusCode, string(respBody))
+	}
+	return common.Unmarshal(respBody, out)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunModerationKeywordPolicy(t *testing.T) {
+	setting := moderation_setting.GetModerationSetting()
+	origin := *setting
+	t.Cleanup(func() { *setting = origin })
+
+	setting.Enabled = true
+	setting.Steps = []moderation_setting.Step{
+		{Name: "input-words", Type: moderation_setting.StepTypeKeyword, Stage: moderation_setting.StageInput, Enabled: true, Keywords: []string{"forbidden"}},
+		{Name: "output-words", Type: moderation_setting.StepTypeKeyword, Stage: moderation_setting.StageOutput, Enabled: true, Keywords: []string{"leak"}},
+	}
+	setting.DefaultPolicy = moderation_setting.Policy{InputEnabled: true, Action: moderation_setting.ActionBlock}
+	setting.GroupPolicies = map[string]moderation_setting.Policy{
+		"vip": {InputEnabled: true, Action: moderation_setting.ActionFlag, Steps: []string{"output-words"}},
+	}
+
+	verdict := RunModeration(context.Background(), ModerationInput{Stage: moderation_setting.StageInput, Group: "default", Text: "this is FORBIDDEN text"})
+	require.NotNil(t, verdict)
+	require.Equal(t, "input-words", verdict.Step)
+	require.Equal(t, moderation_setting.ActionBlock, verdict.Action)
+
+	// stage mismatch: output step must not run on input
+	require.Nil(t, RunModeration(context.Background(), ModerationInput{Stage: moderation_setting.StageInput, Group: "default", Text: "leak"}))
+
+	// group policy restricts the steps
+	require.Nil(t, RunModeration(context.Background(), ModerationInput{Stage: moderation_setting.StageInput, Group: "vip", Text: "forbidden"}))
+	verdict = RunModeration(context.Background(), ModerationInput{Stage: moderation_setting.StageOutput, Group: "vip", Text: "a leak"})
+	require.NotNil(t, verdict)
+	require.Equal(t, moderation_setting.ActionFlag, verdict.Action)
+
+	require.True(t, ShouldModerate("default", moderation_setting.StageInput))
+	require.False(t, ShouldModerate("default", moderation_setting.StageOutput))
+}
+
+func TestKeywordModerationPatterns(t *testing.T) {
+	step := moderation_setting.Step{Name: "ids", Type: moderation_setting.StepTypeKeyword, Patterns: []string{`\bssn:\s*\d{3}-\d{2}-\d{4}\b`}}
+	verdict, err := keywordModerationChecker{}.Check(context.Background(), step, ModerationInput{Text: "my SSN: 123-45-6789"})
+	require.NoError(t, err)
+	require.NotNil(t, verdict)
+	require.Equal(t, []string{"regex"}, verdict.Categories)
+
+	verdict, err = keywordModerationChecker{}.Check(context.Background(), step, ModerationInput{Text: "nothing to see"})
+	require.NoError(t, err)
+	require.Nil(t, verdict)
+
+	require.Error(t, moderation_setting.ValidateSteps(`[{"name":"bad","type":"keyword","stage":"input","patterns":["("]}]`))
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	NotifyTemplateEmailVerification        = "email_verification"
+	NotifyTemplatePasswordReset            = "password_reset"
+	NotifyTemplateChannelDisabled          = "channel_disabled"
+	NotifyTemplateChannelEnabled           = "channel_enabled"
+	NotifyTemplateChannelTestFinished      = "channel_test_finished"
+	NotifyTemplateQuotaWarning             = "quota_warning"
+	NotifyTemplateSubscriptionQuotaWarning = "subscription_quota_warning"
+	NotifyTemplateUpstreamModelUpdate      = "upstream_model_update"
+	NotifyTemplateBudgetAlert              = "budget_alert"
+	NotifyTemplateTestEmail                = "test_email"
+	// NotifyTemplateWebhookPayload webhook 通知的请求体，只使用 Content，渲染结果必须是合法 JSON
+	NotifyTemplateWebhookPayload = "webhook_payload"
+)
+
+// 未设置语言偏好的用户沿用历史行为，使用简体中文模板
+const defaultNotifyLocale = i18n.LangZhCN
+
+// NotifyTemplateInfo 模板列表项，供管理接口展示
+type NotifyTemplateInfo struct {
+	Name      string                         `json:"name"`
+	Locale    string                         `json:"locale"`
+	Variables []string                       `json:"variables"`
+	Default   system_setting.NotifyTemplate  `json:"default"`
+	Custom    *system_setting.NotifyTemplate `json:"custom,omitempty"`
+	Effective system_setting.NotifyTemplate  `json:"effective"`
+}
+
+// 各模板可用的变量，NotifyType 在面向用户的通知中始终可用
+var notifyTemplateVariables = map[string][]string{
+	NotifyTemplateEmailVerification:        {"SystemName", "Code", "ValidMinutes"},
+	NotifyTemplatePasswordReset:            {"SystemName", "Link", "ValidMinutes"},
+	NotifyTemplateChannelDisabled:          {"NotifyType", "ChannelId", "ChannelName", "Reason"},
+	NotifyTemplateChannelEnabled:           {"NotifyType", "ChannelId", "ChannelName"},
+	NotifyTemplateChannelTestFinished:      {"NotifyType"},
+	NotifyTemplateQuotaWarning:             {"NotifyType", "RemainQuota", "TopUpLink"},
+	NotifyTemplateSubscriptionQuotaWarning: {"NotifyType", "RemainQuota", "TopUpLink"},
+	NotifyTemplateUpstreamModelUpdate:      {"NotifyType", "Summary"},
+	NotifyTemplateBudgetAlert:              {"NotifyType", "Scope", "Name", "Percent", "Spent", "Budget"},
+	NotifyTemplateTestEmail:                {"SystemName", "Provider"},
+	NotifyTemplateWebhookPayload:           {"Type", "Title", "Content", "Values", "Timestamp"},
+}
+
+// defaultWebhookPayloadTemplate 与历史 webhook 负载结构保持一致
+const defaultWebhookPayloadTemplate = `{"type":{{json .Type}},"title":{{json .Title}},"content":{{json .Content}}` +
+	`{{if .Values}},"values":{{json .Values}}{{end}},"timestamp":{{.Timestamp}}}`
+
+var defaultNotifyTemplates = map[string]map[string]system_setting.NotifyTemplate{
+	NotifyTemplateEmailVerification: {
+		i18n.LangZhCN: {
+			Subject: "{{.SystemName}}邮箱验证邮件",
+			Content: "<p>您好，你正在进行{{.SystemName}}邮箱验证。</p>" +
+				"<p>您的验证码为: <strong>{{.Code}}</strong></p>" +
+				"<p>验证码 {{.ValidMinutes}} 分钟内有效，如果不是本人操作，请忽略。</p>",
+		},
+		i18n.LangEn: {
+			Subject: "{{.SystemName}} email verification",
+			Content: "<p>Hello, you are verifying your email for {{.SystemName}}.</p>" +
+				"<p>Your verification code is: <strong>{{.Code}}</strong></p>" +
+				"<p>The code is valid for {{.ValidMinutes}} minutes. If this was not you, please ignore this email.</p>",
+		},
+	},
+	NotifyTemplatePasswordReset: {
+		i18n.LangZhCN: {
+			Subject: "{{.SystemName}}密码重置",
+			Content: "<p>您好，你正在进行{{.SystemName}}密码重置。</p>" +
+				"<p>点击 <a href='{{.Link}}'>此处</a> 进行密码重置。</p>" +
+				"<p>如果链接无法点击，请尝试点击下面的链接或将其复制到浏览器中打开：<br> {{.Link}} </p>" +
+				"<p>重置链接 {{.ValidMinutes}} 分钟内有效，如果不是本人操作，请忽略。</p>",
+		},
+		i18n.LangEn: {
+			Subject: "{{.SystemName}} password reset",
+			Content: "<p>Hello, you are resetting your password for {{.SystemName}}.</p>" +
+				"<p>Click <a href='{{.Link}}'>here</a> to reset your password.</p>" +
+				"<p>If the link does not work, copy it into your browser:<br> {{.Link}} </p>" +
+				"<p>The link is valid for {{.ValidMinutes}} minutes. If this was not you, please ignore this email.</p>",
+		},
+	},
+	NotifyTemplateChannelDisabled: {
+		i18n.LangZhCN: {
+			Subject: "通道「{{.ChannelName}}」（#{{.ChannelId}}）已被禁用",
+			Content: "通道「{{.ChannelName}}」（#{{.ChannelId}}）已被禁用，原因：{{.Reason}}",
+		},
+		i18n.LangEn: {
+			Subject: "Channel \"{{.ChannelName}}\" (#{{.ChannelId}}) has been disabled",
+			Content: "Channel \"{{.ChannelName}}\" (#{{.ChannelId}}) has been disabled, reason: {{.Reason}}",
+		},
+	},
+	NotifyTemplateChannelEnabled: {
+		i18n.LangZhCN: {
+			Subject: "通道「{{.ChannelName}}」（#{{.ChannelId}}）已被启用",
+			Content: "通道「{{.ChannelName}}」（#{{.ChannelId}}）已被启用",
+		},
+		i18n.LangEn: {
+			Subject: "Channel \"{{.ChannelName}}\" (#{{.ChannelId}}) has been enabled",
+			Content: "Channel \"{{.ChannelName}}\" (#{{.ChannelId}}) has been enabled",
+		},
+	},
+	NotifyTemplateChannelTestFinished: {
+		i18n.LangZhCN: {
+			Subject: "通道测试完成",
+			Content: "所有通道测试已完成",
+		},
+		i18n.LangEn: {
+			Subject: "Channel test finished",
+			Content: "All channel tests have finished",
+		},
+	},
+	NotifyTemplateQuotaWarning: {
+		i18n.LangZhCN: {
+			Subject: "您的额度即将用尽",
+			Content: "{{if eq .NotifyType \"bark\"}}您的额度即将用尽，剩余额度：{{.RemainQuota}}，请及时充值" +
+				"{{else if eq .NotifyType \"gotify\"}}您的额度即将用尽，当前剩余额度为 {{.RemainQuota}}，请及时充值。" +
+				"{{else}}您的额度即将用尽，当前剩余额度为 {{.RemainQuota}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{.TopUpLink}}'>{{.TopUpLink}}</a>{{end}}",
+		},
+		i18n.LangEn: {
+			Subject: "Your quota is running low",
+			Content: "{{if eq .NotifyType \"bark\"}}Your quota is running low, remaining: {{.RemainQuota}}, please top up" +
+				"{{else if eq .NotifyType \"gotify\"}}Your quota is running low, remaining quota is {{.RemainQuota}}, please top up." +
+				"{{else}}Your quota is running low, remaining quota is {{.RemainQuota}}. Please top up to avoid interruption.<br/>Top up: <a href='{{.TopUpLink}}'>{{.TopUpLink}}</a>{{end}}",
+		},
+	},
+	NotifyTemplateSubscriptionQuotaWarning: {
+		i18n.LangZhCN: {
+			Subject: "您的订阅额度即将用尽",
+			Content: "{{if eq .NotifyType \"bark\"}}您的订阅额度即将用尽，剩余额度：{{.RemainQuota}}，请及时充值" +
+				"{{else if eq .NotifyType \"gotify\"}}您的订阅额度即将用尽，当前剩余额度为 {{.RemainQuota}}，请及时充值。" +
+				"{{else}}您的订阅额度即将用尽，当前剩余额度为 {{.RemainQuota}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{.TopUpLink}}'>{{.TopUpLink}}</a>{{end}}",
+		},
+		i18n.LangEn: {
+			Subject: "Your subscription quota is running low",
+			Content: "{{if eq .NotifyType \"bark\"}}Your subscription quota is running low, remaining: {{.RemainQuota}}, please top up" +
+				"{{else if eq .NotifyType \"gotify\"}}Your subscription quota is running low, remaining quota is {{.RemainQuota}}, please top up." +
+				"{{else}}Your subscription quota is running low, remaining quota is {{.RemainQuota}}. Please top up to avoid interruption.<br/>Top up: <a href='{{.TopUpLink}}'>{{.TopUpLink}}</a>{{end}}",
+		},
+	},
+	NotifyTemplateBudgetAlert: {
+		i18n.LangZhCN: {
+			Subject: "{{if eq .Scope \"token\"}}令牌「{{.Name}}」{{else}}您{{end}}本月消费已达预算的 {{.Percent}}%",
+			Content: "{{if eq .NotifyType \"email\"}}{{if eq .Scope \"token\"}}令牌「{{.Name}}」{{else}}您{{end}}本月已消费 {{.Spent}}，已达到月度预算 {{.Budget}} 的 {{.Percent}}%。<br/>如需继续使用，请关注后续消费。" +
+				"{{else}}{{if eq .Scope \"token\"}}令牌「{{.Name}}」{{else}}您{{end}}本月已消费 {{.Spent}}，已达到月度预算 {{.Budget}} 的 {{.Percent}}%{{end}}",
+		},
+		i18n.LangEn: {
+			Subject: "{{if eq .Scope \"token\"}}Token \"{{.Name}}\" has{{else}}You have{{end}} reached {{.Percent}}% of the monthly budget",
+			Content: "{{if eq .NotifyType \"email\"}}{{if eq .Scope \"token\"}}Token \"{{.Name}}\" has{{else}}You have{{end}} spent {{.Spent}} this month, reaching {{.Percent}}% of the monthly budget {{.Budget}}.<br/>Please keep an eye on further usage." +
+				"{{else}}{{if eq .Scope \"token\"}}Token \"{{.Name}}\" has{{else}}You have{{end}} spent {{.Spent}} this month, reaching {{.Percent}}% of the monthly budget {{.Budget}}{{end}}",
+		},
+	},
+	NotifyTemplateUpstreamModelUpdate: {
+		i18n.LangZhCN: {
+			Subject: "上游模型巡检通知",
+			Content: "{{.Summary}}",
+		},
+		i18n.LangEn: {
+			Subject: "Upstream model check report",
+			Content: "{{.Summary}}",
+		},
+	},
+	NotifyTemplateTestEmail: {
+		i18n.LangZhCN: {
+			Subject: "{{.SystemName}} 测试邮件",
+			Content: "<p>这是一封来自 {{.SystemName}} 的测试邮件，当前邮件服务商：{{.Provider}}。</p>",
+		},
+		i18n.LangEn: {
+			Subject: "{{.SystemName}} test email",
+			Content: "<p>This is a test email from {{.SystemName}}, sent with provider: {{.Provider}}.</p>",
+		},
+	},
+	NotifyTemplateWebhookPayload: {
+		i18n.LangZhCN: {Content: defaultWebhookPayloadTemplate},
+		i18n.LangEn:   {Content: defaultWebhookPayloadTemplate},
+	},
+}
+
+// notifyTemplateFuncs 模板可用的函数，json 用于在 webhook 负载中安全地输出字符串
+var notifyTemplateFuncs = map[string]any{
+	"json": func(v any) (string, error) {
+		data, err := common.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NormalizeNotifyLocale 将用户语言偏好转换为模板语言，空值沿用默认语言
+func NormalizeNotifyLocale(lang string) string {
+	if strings.TrimSpace(lang) == "" {
+		return defaultNotifyLocale
+	}
+	return i18n.NormalizeLang(lang)
+}
+
+func IsValidNotifyTemplateName(name string) bool {
+	_, ok := defaultNotifyTemplates[name]
+	return ok
+}
+
+// resolveNotifyTemplate 按 自定义(语言) -> 默认(语言) -> 默认(默认语言) 的顺序查找模板
+func resolveNotifyTemplate(name string, locale string) (system_setting.NotifyTemplate, error) {
+	if tpl, ok := system_setting.GetCustomNotifyTemplate(name, locale); ok {
+		return tpl, nil
+	}
+	defaults, ok := defaultNotifyTemplates[name]
+	if !ok {
+		return system_setting.NotifyTemplate{}, fmt.Errorf("unknown notify template: %s", name)
+	}
+	if tpl, ok := defaults[locale]; ok {
+		return tpl, nil
+	}
+	if tpl, ok := system_setting.GetCustomNotifyTemplate(name, defaultNotifyLocale); ok {
+		return tpl, nil
+	}
+	return defaults[defaultNotifyLocale], nil
+}
+
+// executeNotifyTemplate 渲染模板，html 为 true 时使用 html/template 对变量做 HTML 转义（用于邮件正文）
+func executeNotifyTemplate(name string, text string, data map[string]any, html bool) (string, error) {
+	var buf bytes.Buffer
+	if html {
+		tpl, err := htmltemplate.New(name).Funcs(notifyTemplateFuncs).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		if err := tpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	tpl, err := template.New(name).Funcs(notifyTemplateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderNotifyTemplateText 渲染任意模板文本，用于保存前校验与预览；html 控制正文是否按 HTML 转义，标题始终为纯文本
+func RenderNotifyTemplateText(tpl system_setting.NotifyTemplate, data map[string]any, html bool) (string, string, error) {
+	subject, err := executeNotifyTemplate("subject", tpl.Subject, data, false)
+	if err != nil {
+		return "", "", fmt.Errorf("subject: %w", err)
+	}
+	content, err := executeNotifyTemplate("content", tpl.Content, data, html)
+	if err != nil {
+		return "", "", fmt.Errorf("content: %w", err)
+	}
+	return subject, content, nil
+}
+
+// RenderNotifyTemplate 渲染指定模板，自定义模板渲染失败时回退到内置模板
+func RenderNotifyTemplate(name string, lang string, data map[string]any, html bool) (string, string, error) {
+	locale := NormalizeNotifyLocale(lang)
+	tpl, err := resolveNotifyTemplate(name, locale)
+	if err != nil {
+		return "", "", err
+	}
+	subject, content, err := RenderNotifyTemplateText(tpl, data, html)
+	if err == nil {
+		return subject, content, nil
+	}
+	defaults := defaultNotifyTemplates[name]
+	fallback, ok := defaults[locale]
+	if !ok {
+		fallback = defaults[defaultNotifyLocale]
+	}
+	return RenderNotifyTemplateText(fallback, data, html)
+}
+
+// IsHTMLNotifyTemplate 判断模板正文按 HTML 渲染：邮件类模板，或按邮件方式发送的用户通知
+func IsHTMLNotifyTemplate(name string, notifyType string) bool {
+	switch name {
+	case NotifyTemplateEmailVerification, NotifyTemplatePasswordReset, NotifyTemplateTestEmail:
+		return true
+	case NotifyTemplateWebhookPayload:
+		return false
+	}
+	return notifyType == "" || notifyType == dto.NotifyTypeEmail
+}
+
+// RenderWebhookPayload 按用户语言渲染 webhook 负载，自定义模板渲染失败或结果不是合法 JSON 时回退到内置模板
+func RenderWebhookPayload(lang string, data map[string]any) ([]byte, error) {
+	_, content, err := RenderNotifyTemplate(NotifyTemplateWebhookPayload, lang, data, false)
+	if err == nil && gjson.Valid(content) {
+		return []byte(content), nil
+	}
+	_, content, err = RenderNotifyTemplateText(system_setting.NotifyTemplate{Content: defaultWebhookPayloadTemplate}, data, false)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// ListNotifyTemplates 列出所有模板在各语言下的默认值与自定义值
+func ListNotifyTemplates() []NotifyTemplateInfo {
+	names := make([]string, 0, len(defaultNotifyTemplates))
+	for name := range defaultNotifyTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]NotifyTemplateInfo, 0, len(names)*len(i18n.SupportedLanguages()))
+	for _, name := range names {
+		for _, locale := range i18n.SupportedLanguages() {
+			defaultTpl, ok := defaultNotifyTemplates[name][locale]
+			if !ok {
+				defaultTpl = defaultNotifyTemplates[name][defaultNotifyLocale]
+			}
+			info := NotifyTemplateInfo{
+				Name:      name,
+				Locale:    locale,
+				Variables: notifyTemplateVariables[name],
+				Default:   defaultTpl,
+				Effective: defaultTpl,
+			}
+			if custom, ok := system_setting.GetCustomNotifyTemplate(name, locale); ok {
+				customCopy := custom
+				info.Custom = &customCopy
+				info.Effective = custom
+			}
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// SampleNotifyTemplateData 生成模板预览与保存校验使用的示例数据
+func SampleNotifyTemplateData(name string) map[string]any {
+	data := make(map[string]any)
+	for _, variable := range notifyTemplateVariables[name] {
+		switch variable {
+		case "NotifyType":
+			data[variable] = "email"
+		case "ChannelId", "ValidMinutes", "Timestamp":
+			data[variable] = 10
+		case "Values":
+			data[variable] = []any{}
+		default:
+			data[variable] = "{{" + variable + "}}"
+		}
+	}
+	return data
+}
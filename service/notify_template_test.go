@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func withCustomNotifyTemplates(t *testing.T, templates map[string]map[string]system_setting.NotifyTemplate) {
+	setting := system_setting.GetNotifyTemplateSetting()
+	saved := setting.Templates
+	setting.Templates = templates
+	t.Cleanup(func() { setting.Templates = saved })
+}
+
+func TestRenderNotifyTemplateLocaleFallback(t *testing.T) {
+	withCustomNotifyTemplates(t, map[string]map[string]system_setting.NotifyTemplate{})
+	data := map[string]any{"NotifyType": dto.NotifyTypeBark, "ChannelId": 3, "ChannelName": "main"}
+
+	subject, content, err := RenderNotifyTemplate(NotifyTemplateChannelEnabled, i18n.LangEn, data, false)
+	require.NoError(t, err)
+	require.Equal(t, `Channel "main" (#3) has been enabled`, subject)
+	require.Equal(t, subject, content)
+
+	subject, _, err = RenderNotifyTemplate(NotifyTemplateChannelEnabled, "", data, false)
+	require.NoError(t, err)
+	require.Equal(t, "通道「main」（#3）已被启用", subject)
+}
+
+func TestRenderNotifyTemplateCustomOverride(t *testing.T) {
+	withCustomNotifyTemplates(t, map[string]map[string]system_setting.NotifyTemplate{
+		NotifyTemplateChannelEnabled: {
+			i18n.LangEn: {Subject: "up: {{.ChannelName}}", Content: "{{.ChannelId}}"},
+		},
+		NotifyTemplateChannelDisabled: {
+			i18n.LangEn: {Subject: "{{.Broken", Content: "x"},
+		},
+	})
+	data := map[string]any{"ChannelId": 3, "ChannelName": "main", "Reason": "timeout"}
+
+	subject, content, err := RenderNotifyTemplate(NotifyTemplateChannelEnabled, i18n.LangEn, data, false)
+	require.NoError(t, err)
+	require.Equal(t, "up: main", subject)
+	require.Equal(t, "3", content)
+
+	// 自定义模板语法错误时回退到内置模板
+	subject, _, err = RenderNotifyTemplate(NotifyTemplateChannelDisabled, i18n.LangEn, data, false)
+	require.NoError(t, err)
+	require.Equal(t, `Channel "main" (#3) has been disabled`, subject)
+}
+
+func TestRenderNotifyTemplateEscapesHTMLEmail(t *testing.T) {
+	withCustomNotifyTemplates(t, map[string]map[string]system_setting.NotifyTemplate{})
+	data := map[string]any{
+		"NotifyType":  dto.NotifyTypeEmail,
+		"ChannelId":   1,
+		"ChannelName": "<script>alert(1)</script>",
+		"Reason":      "a & b",
+	}
+
+	subject, content, err := RenderNotifyTemplate(NotifyTemplateChannelDisabled, i18n.LangEn, data, true)
+	require.NoError(t, err)
+	require.Contains(t, subject, "<script>")
+	require.NotContains(t, content, "<script>")
+	require.Contains(t, content, "&lt;script&gt;")
+	require.Contains(t, content, "a &amp; b")
+
+	_, content, err = RenderNotifyTemplate(NotifyTemplateChannelDisabled, i18n.LangEn, data, false)
+	require.NoError(t, err)
+	require.Contains(t, content, "<script>")
+}
+
+func TestIsHTMLNotifyTemplate(t *testing.T) {
+	require.True(t, IsHTMLNotifyTemplate(NotifyTemplatePasswordReset, dto.NotifyTypeWebhook))
+	require.True(t, IsHTMLNotifyTemplate(NotifyTemplateQuotaWarning, ""))
+	require.True(t, IsHTMLNotifyTemplate(NotifyTemplateQuotaWarning, dto.NotifyTypeEmail))
+	require.False(t, IsHTMLNotifyTemplate(NotifyTemplateQuotaWarning, dto.NotifyTypeBark))
+	require.False(t, IsHTMLNotifyTemplate(NotifyTemplateWebhookPayload, ""))
+}
+
+func TestRenderWebhookPayload(t *testing.T) {
+	withCustomNotifyTemplates(t, map[string]map[string]system_setting.NotifyTemplate{})
+	data := map[string]any{
+		"Type":      "quota_exceed",
+		"Title":     `say "hi"`,
+		"Content":   "line1\nline2",
+		"Values":    []any(nil),
+		"Timestamp": int64(1700000000),
+	}
+
+	payload, err := RenderWebhookPayload(i18n.LangEn, data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"quota_exceed","title":"say \"hi\"","content":"line1\nline2","timestamp":1700000000}`, string(payload))
+
+	withCustomNotifyTemplates(t, map[string]map[string]system_setting.NotifyTemplate{
+		NotifyTemplateWebhookPayload: {
+			i18n.LangEn:   {Content: `{"text":{{json .Title}}}`},
+			i18n.LangZhCN: {Content: `not json {{.Title}}`},
+		},
+	})
+	payload, err = RenderWebhookPayload(i18n.LangEn, data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"text":"say \"hi\""}`, string(payload))
+
+	// 渲染结果不是合法 JSON 时回退到内置负载
+	payload, err = RenderWebhookPayload(i18n.LangZhCN, data)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, common.Unmarshal(payload, &decoded))
+	require.Equal(t, "quota_exceed", decoded["type"])
+}
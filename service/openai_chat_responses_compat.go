@@ -16,3 +16,17 @@ func ResponsesResponseToChatCompletionsResponse(resp *dto.OpenAIResponsesRespons
 func ExtractOutputTextFromResponses(resp *dto.OpenAIResponsesResponse) string {
 	return openaicompat.ExtractOutputTextFromResponses(resp)
 }
+
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	return openaicompat.ResponsesRequestToChatCompletionsRequest(req)
+}
+
+func ChatCompletionsResponseToResponsesResponse(chat *dto.OpenAITextResponse, usage *dto.Usage) map[string]any {
+	return openaicompat.ChatCompletionsResponseToResponsesResponse(chat, usage)
+}
+
+type ChatToResponsesStream = openaicompat.ChatToResponsesStream
+
+func NewChatToResponsesStream(model string) *ChatToResponsesStream {
+	return openaicompat.NewChatToResponsesStream(model)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAIErrorWithRetryHint 在错误对象中附带限制维度与重试等待时间
+type openAIErrorWithRetryHint struct {
+	types.OpenAIError
+	*RetryHint
+}
+
+// WriteOpenAIError 以 OpenAI 格式写出错误响应，限流与过载错误附带 Retry-After 等重试提示；
+// 开启严格兼容模式时规范化状态码与错误对象（提示仅保留在响应头中），并设置官方 SDK 识别的 x-should-retry 响应头
+func WriteOpenAIError(c *gin.Context, statusCode int, openAIError types.OpenAIError) {
+	hint := WriteRetryHintHeaders(c, statusCode, openAIError.Code)
+	if !operation_setting.GetGeneralSetting().StrictOpenAIErrors {
+		if hint != nil {
+			c.JSON(statusCode, gin.H{
+				"error": openAIErrorWithRetryHint{OpenAIError: openAIError, RetryHint: hint},
+			})
+			return
+		}
+		c.JSON(statusCode, gin.H{
+			"error": openAIError,
+		})
+		return
+	}
+	statusCode, strictError := types.NormalizeOpenAIError(statusCode, openAIError)
+	c.Header("x-should-retry", strconv.FormatBool(types.OpenAIShouldRetry(statusCode, strictError)))
+	if statusCode == http.StatusTooManyRequests && strictError.Type != types.OpenAIErrorTypeInsufficientQuota && c.Writer.Header().Get("Retry-After") == "" {
+		c.Header("Retry-After", "1")
+	}
+	c.JSON(statusCode, gin.H{
+		"error": strictError,
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func writeTestOpenAIError(t *testing.T, statusCode int, openAIError types.OpenAIError) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	WriteOpenAIError(c, statusCode, openAIError)
+	return recorder
+}
+
+func TestWriteOpenAIErrorStrictMode(t *testing.T) {
+	setting := operation_setting.GetGeneralSetting()
+	original := setting.StrictOpenAIErrors
+	setting.StrictOpenAIErrors = true
+	defer func() { setting.StrictOpenAIErrors = original }()
+
+	tests := []struct {
+		name        string
+		status      int
+		err         types.OpenAIError
+		wantStatus  int
+		wantType    string
+		wantCode    any
+		shouldRetry string
+	}{
+		{
+			name:        "quota exhausted",
+			status:      http.StatusForbidden,
+			err:         types.OpenAIError{Message: "quota", Type: "new_api_error", Code: types.ErrorCodeInsufficientUserQuota},
+			wantStatus:  http.StatusTooManyRequests,
+			wantType:    "insufficient_quota",
+			wantCode:    "insufficient_quota",
+			shouldRetry: "false",
+		},
+		{
+			name:        "invalid token",
+			status:      http.StatusUnauthorized,
+			err:         types.OpenAIError{Message: "invalid token", Type: "new_api_error", Code: ""},
+			wantStatus:  http.StatusUnauthorized,
+			wantType:    "invalid_request_error",
+			wantCode:    "invalid_api_key",
+			shouldRetry: "false",
+		},
+		{
+			name:        "rate limited",
+			status:      http.StatusTooManyRequests,
+			err:         types.OpenAIError{Message: "slow down", Type: "new_api_error"},
+			wantStatus:  http.StatusTooManyRequests,
+			wantType:    "requests",
+			wantCode:    "rate_limit_exceeded",
+			shouldRetry: "true",
+		},
+		{
+			name:        "upstream failure",
+			status:      http.StatusBadGateway,
+			err:         types.OpenAIError{Message: "bad gateway", Type: "upstream_error", Code: "bad_response_status_code"},
+			wantStatus:  http.StatusBadGateway,
+			wantType:    "server_error",
+			wantCode:    nil,
+			shouldRetry: "true",
+		},
+		{
+			name:        "validation",
+			status:      http.StatusBadRequest,
+			err:         types.OpenAIError{Message: "bad", Type: "invalid_request_error", Param: "messages", Code: "invalid_value"},
+			wantStatus:  http.StatusBadRequest,
+			wantType:    "invalid_request_error",
+			wantCode:    "invalid_value",
+			shouldRetry: "false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := writeTestOpenAIError(t, tt.status, tt.err)
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			require.Equal(t, tt.shouldRetry, recorder.Header().Get("x-should-retry"))
+			body := recorder.Body.Bytes()
+			require.Equal(t, tt.wantType, gjson.GetBytes(body, "error.type").String())
+			require.Equal(t, tt.wantCode, gjson.GetBytes(body, "error.code").Value())
+			require.True(t, gjson.GetBytes(body, "error.param").Exists())
+		})
+	}
+}
+
+func TestWriteOpenAIErrorDefaultMode(t *testing.T) {
+	recorder := writeTestOpenAIError(t, http.StatusForbidden, types.OpenAIError{Message: "quota", Type: "new_api_error", Code: "insufficient_user_quota"})
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Empty(t, recorder.Header().Get("x-should-retry"))
+	require.Equal(t, "new_api_error", gjson.GetBytes(recorder.Body.Bytes(), "error.type").String())
+}
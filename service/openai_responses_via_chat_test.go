@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesRequestToChatCompletionsRequest(t *testing.T) {
+	var req dto.OpenAIResponsesRequest
+	require.NoError(t, common.UnmarshalJsonStr(`{
+		"model":"claude-sonnet",
+		"instructions":"be brief",
+		"max_output_tokens":128,
+		"stream":true,
+		"input":[
+			{"role":"user","content":[{"type":"input_text","text":"weather?"}]},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"}
+		],
+		"tools":[{"type":"function","name":"get_weather","parameters":{"type":"object"}}]
+	}`, &req))
+
+	chatReq, err := ResponsesRequestToChatCompletionsRequest(&req)
+	require.NoError(t, err)
+	require.Len(t, chatReq.Messages, 4)
+	require.Equal(t, "system", chatReq.Messages[0].Role)
+	require.Equal(t, "be brief", chatReq.Messages[0].StringContent())
+	require.Equal(t, "user", chatReq.Messages[1].Role)
+	require.Equal(t, "weather?", chatReq.Messages[1].StringContent())
+	require.Equal(t, "assistant", chatReq.Messages[2].Role)
+	require.Contains(t, string(chatReq.Messages[2].ToolCalls), "get_weather")
+	require.Equal(t, "tool", chatReq.Messages[3].Role)
+	require.Equal(t, "call_1", chatReq.Messages[3].ToolCallId)
+	require.Len(t, chatReq.Tools, 1)
+	require.Equal(t, "get_weather", chatReq.Tools[0].Function.Name)
+	require.NotNil(t, chatReq.StreamOptions)
+	require.True(t, chatReq.StreamOptions.IncludeUsage)
+
+	req.PreviousResponseID = "resp_1"
+	_, err = ResponsesRequestToChatCompletionsRequest(&req)
+	require.Error(t, err)
+}
+
+func TestChatToResponsesStream(t *testing.T) {
+	stream := NewChatToResponsesStream("claude-sonnet")
+	var eventTypes []string
+	feed := func(raw string) {
+		var chunk dto.ChatCompletionsStreamResponse
+		require.NoError(t, common.UnmarshalJsonStr(raw, &chunk))
+		for _, event := range stream.Feed(&chunk) {
+			eventTypes = append(eventTypes, event["type"].(string))
+		}
+	}
+	feed(`{"choices":[{"index":0,"delta":{"content":"Hel"}}]}`)
+	feed(`{"choices":[{"index":0,"delta":{"content":"lo"}}]}`)
+	feed(`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)
+	events := stream.Finish(&dto.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	for _, event := range events {
+		eventTypes = append(eventTypes, event["type"].(string))
+	}
+
+	require.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.completed",
+	}, eventTypes)
+
+	completed := events[len(events)-1]["response"].(map[string]any)
+	require.Equal(t, "completed", completed["status"])
+	data, err := common.Marshal(completed["output"])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"text":"Hello"`)
+}
@@ -0,0 +1,562 @@
+package openaicompat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// responsesInputItem /v1/responses 请求 input 数组中的一项
+type responsesInputItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	CallId    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Output    json.RawMessage `json:"output"`
+}
+
+type responsesContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageUrl string `json:"image_url"`
+	Detail   string `json:"detail"`
+	FileId   string `json:"file_id"`
+	FileData string `json:"file_data"`
+	Filename string `json:"filename"`
+}
+
+type responsesFunctionTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"`
+	Strict      *bool  `json:"strict"`
+}
+
+// ResponsesRequestToChatCompletionsRequest 将 /v1/responses 请求转换为 Chat Completions 请求，
+// 用于只支持 Chat Completions 的渠道。依赖服务端状态的 previous_response_id 与 conversation 无法转换
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if req.PreviousResponseID != "" || len(req.Conversation) > 0 {
+		return nil, errors.New("previous_response_id and conversation are not supported for this model")
+	}
+
+	chatReq := &dto.GeneralOpenAIRequest{
+		Model:       req.Model,
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		TopLogProbs: req.TopLogProbs,
+		User:        req.User,
+		Metadata:    req.Metadata,
+		MaxTokens:   req.MaxOutputTokens,
+	}
+	if req.Stream != nil && *req.Stream {
+		chatReq.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	if req.Reasoning != nil && req.Reasoning.Effort != "" {
+		chatReq.ReasoningEffort = req.Reasoning.Effort
+	}
+	if len(req.ParallelToolCalls) > 0 {
+		var parallel bool
+		if err := common.Unmarshal(req.ParallelToolCalls, &parallel); err == nil {
+			chatReq.ParallelTooCalls = &parallel
+		}
+	}
+
+	if len(req.Instructions) > 0 {
+		var instructions string
+		if err := common.Unmarshal(req.Instructions, &instructions); err == nil && strings.TrimSpace(instructions) != "" {
+			chatReq.Messages = append(chatReq.Messages, dto.Message{Role: "system", Content: instructions})
+		}
+	}
+	messages, err := responsesInputToChatMessages(req.Input)
+	if err != nil {
+		return nil, err
+	}
+	chatReq.Messages = append(chatReq.Messages, messages...)
+	if len(chatReq.Messages) == 0 {
+		return nil, errors.New("input is required")
+	}
+
+	if len(req.Tools) > 0 {
+		var tools []responsesFunctionTool
+		if err := common.Unmarshal(req.Tools, &tools); err != nil {
+			return nil, fmt.Errorf("invalid tools: %w", err)
+		}
+		for _, tool := range tools {
+			if tool.Type != "function" {
+				return nil, fmt.Errorf("tool type %q is not supported for this model", tool.Type)
+			}
+			chatReq.Tools = append(chatReq.Tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			})
+		}
+	}
+	if len(req.ToolChoice) > 0 {
+		chatReq.ToolChoice = convertResponsesToolChoiceToChat(req.ToolChoice)
+	}
+	if len(req.Text) > 0 {
+		chatReq.ResponseFormat = convertResponsesTextToChatResponseFormat(req.Text)
+	}
+	return chatReq, nil
+}
+
+func responsesInputToChatMessages(input json.RawMessage) ([]dto.Message, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	var text string
+	if err := common.Unmarshal(input, &text); err == nil {
+		return []dto.Message{{Role: "user", Content: text}}, nil
+	}
+	var items []responsesInputItem
+	if err := common.Unmarshal(input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	messages := make([]dto.Message, 0, len(items))
+	var pendingCalls []dto.ToolCallRequest
+	// 连续的 function_call 合并为一条 assistant 消息，紧跟在 assistant 文本之后时挂到该消息上
+	flushCalls := func() {
+		if len(pendingCalls) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == "assistant" && len(messages[n-1].ToolCalls) == 0 {
+			messages[n-1].SetToolCalls(pendingCalls)
+		} else {
+			message := dto.Message{Role: "assistant"}
+			message.SetToolCalls(pendingCalls)
+			messages = append(messages, message)
+		}
+		pendingCalls = nil
+	}
+
+	for _, item := range items {
+		switch item.Type {
+		case "", "message":
+			flushCalls()
+			role := item.Role
+			if role == "developer" {
+				role = "system"
+			}
+			content, err := responsesContentToChat(item.Content)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, dto.Message{Role: role, Content: content})
+		case "function_call":
+			pendingCalls = append(pendingCalls, dto.ToolCallRequest{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			})
+		case "function_call_output":
+			flushCalls()
+			output, err := responsesContentToChat(item.Output)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, dto.Message{Role: "tool", ToolCallId: item.CallId, Content: output})
+		case "reasoning":
+			// 推理内容只对原模型有意义，不回传给上游
+			continue
+		default:
+			return nil, fmt.Errorf("input item type %q is not supported for this model", item.Type)
+		}
+	}
+	flushCalls()
+	return messages, nil
+}
+
+// responsesContentToChat 字符串原样返回，内容数组转换为 Chat Completions 的多模态内容
+func responsesContentToChat(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := common.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []responsesContentPart
+	if err := common.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("invalid content: %w", err)
+	}
+	contents := make([]dto.MediaContent, 0, len(parts))
+	textOnly := true
+	for _, part := range parts {
+		if part.Type != "input_text" && part.Type != "output_text" && part.Type != "text" {
+			textOnly = false
+		}
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			contents = append(contents, dto.MediaContent{Type: dto.ContentTypeText, Text: part.Text})
+		case "input_image":
+			if part.ImageUrl == "" {
+				return nil, errors.New("input_image without image_url is not supported for this model")
+			}
+			contents = append(contents, dto.MediaContent{
+				Type:     dto.ContentTypeImageURL,
+				ImageUrl: &dto.MessageImageUrl{Url: part.ImageUrl, Detail: part.Detail},
+			})
+		case "input_file":
+			if part.FileData == "" && part.FileId == "" {
+				return nil, errors.New("input_file without file_data or file_id is not supported for this model")
+			}
+			file := map[string]any{}
+			if part.FileData != "" {
+				file["file_data"] = part.FileData
+			}
+			if part.FileId != "" {
+				file["file_id"] = part.FileId
+			}
+			if part.Filename != "" {
+				file["filename"] = part.Filename
+			}
+			contents = append(contents, dto.MediaContent{Type: dto.ContentTypeFile, File: file})
+		default:
+			return nil, fmt.Errorf("content type %q is not supported for this model", part.Type)
+		}
+	}
+	// 纯文本内容合并为字符串，兼容不接受内容数组的上游
+	if textOnly {
+		texts := make([]string, 0, len(contents))
+		for _, content := range contents {
+			texts = append(texts, content.Text)
+		}
+		return strings.Join(texts, "\n"), nil
+	}
+	return contents, nil
+}
+
+func convertResponsesToolChoiceToChat(raw json.RawMessage) any {
+	var choice string
+	if err := common.Unmarshal(raw, &choice); err == nil {
+		return choice
+	}
+	var named struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if err := common.Unmarshal(raw, &named); err == nil && named.Type == "function" && named.Name != "" {
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": named.Name},
+		}
+	}
+	return nil
+}
+
+func convertResponsesTextToChatResponseFormat(raw json.RawMessage) *dto.ResponseFormat {
+	var text struct {
+		Format *struct {
+			Type        string          `json:"type"`
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			Schema      json.RawMessage `json:"schema"`
+			Strict      json.RawMessage `json:"strict"`
+		} `json:"format"`
+	}
+	if err := common.Unmarshal(raw, &text); err != nil || text.Format == nil {
+		return nil
+	}
+	switch text.Format.Type {
+	case "json_object":
+		return &dto.ResponseFormat{Type: "json_object"}
+	case "json_schema":
+		schema, err := common.Marshal(dto.FormatJsonSchema{
+			Name:        text.Format.Name,
+			Description: text.Format.Description,
+			Schema:      text.Format.Schema,
+			Strict:      text.Format.Strict,
+		})
+		if err != nil {
+			return nil
+		}
+		return &dto.ResponseFormat{Type: "json_schema", JsonSchema: schema}
+	}
+	return nil
+}
+
+// ResponsesUsageFromChat 将 Chat Completions 用量转换为 /v1/responses 的 usage 对象
+func ResponsesUsageFromChat(usage *dto.Usage) map[string]any {
+	if usage == nil {
+		usage = &dto.Usage{}
+	}
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	return map[string]any{
+		"input_tokens":          usage.PromptTokens,
+		"output_tokens":         usage.CompletionTokens,
+		"total_tokens":          total,
+		"input_tokens_details":  map[string]any{"cached_tokens": usage.PromptTokensDetails.CachedTokens},
+		"output_tokens_details": map[string]any{"reasoning_tokens": usage.CompletionTokenDetails.ReasoningTokens},
+	}
+}
+
+func newResponsesObject(id string, model string, createdAt int64, status string) map[string]any {
+	return map[string]any{
+		"id":         id,
+		"object":     "response",
+		"created_at": createdAt,
+		"status":     status,
+		"model":      model,
+		"output":     []any{},
+		"error":      nil,
+	}
+}
+
+func responsesMessageItem(id string, status string, text string) map[string]any {
+	return map[string]any{
+		"id":     id,
+		"type":   "message",
+		"status": status,
+		"role":   "assistant",
+		"content": []any{
+			map[string]any{"type": "output_text", "text": text, "annotations": []any{}},
+		},
+	}
+}
+
+func responsesFunctionCallItem(id string, status string, callId string, name string, arguments string) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"type":      "function_call",
+		"status":    status,
+		"call_id":   callId,
+		"name":      name,
+		"arguments": arguments,
+	}
+}
+
+// applyResponsesFinish 根据 finish_reason 设置响应状态，因长度截断时标记为 incomplete
+func applyResponsesFinish(response map[string]any, finishReason string) {
+	if finishReason == "length" {
+		response["status"] = "incomplete"
+		response["incomplete_details"] = map[string]any{"reason": "max_output_tokens"}
+		return
+	}
+	response["status"] = "completed"
+}
+
+// ChatCompletionsResponseToResponsesResponse 将非流式 Chat Completions 响应转换为 /v1/responses 响应对象
+func ChatCompletionsResponseToResponsesResponse(chat *dto.OpenAITextResponse, usage *dto.Usage) map[string]any {
+	id := "resp_" + strings.TrimPrefix(chat.Id, "chatcmpl-")
+	response := newResponsesObject(id, chat.Model, time.Now().Unix(), "completed")
+	output := make([]any, 0)
+	finishReason := ""
+	if len(chat.Choices) > 0 {
+		choice := chat.Choices[0]
+		finishReason = choice.FinishReason
+		if text := choice.Message.StringContent(); text != "" {
+			output = append(output, responsesMessageItem("msg_"+common.GetUUID(), "completed", text))
+		}
+		for _, call := range choice.Message.ParseToolCalls() {
+			output = append(output, responsesFunctionCallItem("fc_"+call.ID, "completed", call.ID, call.Function.Name, call.Function.Arguments))
+		}
+	}
+	response["output"] = output
+	response["usage"] = ResponsesUsageFromChat(usage)
+	applyResponsesFinish(response, finishReason)
+	return response
+}
+
+type responsesStreamToolCall struct {
+	itemId      string
+	callId      string
+	name        string
+	arguments   strings.Builder
+	outputIndex int
+}
+
+// ChatToResponsesStream 将 Chat Completions 流式分片转换为 /v1/responses 语义事件
+type ChatToResponsesStream struct {
+	responseId  string
+	model       string
+	createdAt   int64
+	sequence    int
+	started     bool
+	nextOutput  int
+	textItemId  string
+	textIndex   int
+	text        strings.Builder
+	toolCalls   map[int]*responsesStreamToolCall
+	toolOrder   []int
+	output      map[int]any
+	finish      string
+	textOpened  bool
+	textEnded   bool
+	toolsClosed bool
+}
+
+func NewChatToResponsesStream(model string) *ChatToResponsesStream {
+	return &ChatToResponsesStream{
+		responseId: "resp_" + common.GetUUID(),
+		model:      model,
+		createdAt:  time.Now().Unix(),
+		toolCalls:  make(map[int]*responsesStreamToolCall),
+		output:     make(map[int]any),
+	}
+}
+
+func (s *ChatToResponsesStream) event(eventType string, fields map[string]any) map[string]any {
+	fields["type"] = eventType
+	fields["sequence_number"] = s.sequence
+	s.sequence++
+	return fields
+}
+
+func (s *ChatToResponsesStream) start() []map[string]any {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return []map[string]any{
+		s.event("response.created", map[string]any{"response": newResponsesObject(s.responseId, s.model, s.createdAt, "in_progress")}),
+		s.event("response.in_progress", map[string]any{"response": newResponsesObject(s.responseId, s.model, s.createdAt, "in_progress")}),
+	}
+}
+
+func (s *ChatToResponsesStream) closeText() []map[string]any {
+	if !s.textOpened || s.textEnded {
+		return nil
+	}
+	s.textEnded = true
+	text := s.text.String()
+	part := map[string]any{"type": "output_text", "text": text, "annotations": []any{}}
+	item := responsesMessageItem(s.textItemId, "completed", text)
+	s.output[s.textIndex] = item
+	return []map[string]any{
+		s.event("response.output_text.done", map[string]any{"item_id": s.textItemId, "output_index": s.textIndex, "content_index": 0, "text": text}),
+		s.event("response.content_part.done", map[string]any{"item_id": s.textItemId, "output_index": s.textIndex, "content_index": 0, "part": part}),
+		s.event("response.output_item.done", map[string]any{"output_index": s.textIndex, "item": item}),
+	}
+}
+
+func (s *ChatToResponsesStream) closeToolCalls() []map[string]any {
+	if s.toolsClosed {
+		return nil
+	}
+	s.toolsClosed = true
+	var events []map[string]any
+	for _, index := range s.toolOrder {
+		call := s.toolCalls[index]
+		arguments := call.arguments.String()
+		item := responsesFunctionCallItem(call.itemId, "completed", call.callId, call.name, arguments)
+		s.output[call.outputIndex] = item
+		events = append(events,
+			s.event("response.function_call_arguments.done", map[string]any{"item_id": call.itemId, "output_index": call.outputIndex, "arguments": arguments}),
+			s.event("response.output_item.done", map[string]any{"output_index": call.outputIndex, "item": item}),
+		)
+	}
+	return events
+}
+
+// Feed 处理一个 Chat Completions 流式分片，返回需要下发的事件
+func (s *ChatToResponsesStream) Feed(chunk *dto.ChatCompletionsStreamResponse) []map[string]any {
+	events := s.start()
+	if chunk.Model != "" {
+		s.model = chunk.Model
+	}
+	if len(chunk.Choices) == 0 {
+		return events
+	}
+	choice := chunk.Choices[0]
+	if content := choice.Delta.GetContentString(); content != "" && !s.textEnded {
+		if !s.textOpened {
+			s.textOpened = true
+			s.textItemId = "msg_" + common.GetUUID()
+			s.textIndex = s.nextOutput
+			s.nextOutput++
+			events = append(events,
+				s.event("response.output_item.added", map[string]any{"output_index": s.textIndex, "item": map[string]any{
+					"id": s.textItemId, "type": "message", "status": "in_progress", "role": "assistant", "content": []any{},
+				}}),
+				s.event("response.content_part.added", map[string]any{"item_id": s.textItemId, "output_index": s.textIndex, "content_index": 0,
+					"part": map[string]any{"type": "output_text", "text": "", "annotations": []any{}}}),
+			)
+		}
+		s.text.WriteString(content)
+		events = append(events, s.event("response.output_text.delta", map[string]any{
+			"item_id": s.textItemId, "output_index": s.textIndex, "content_index": 0, "delta": content,
+		}))
+	}
+	for i, toolCall := range choice.Delta.ToolCalls {
+		index := i
+		if toolCall.Index != nil {
+			index = *toolCall.Index
+		}
+		call, ok := s.toolCalls[index]
+		if !ok {
+			// 文本结束后才开始工具调用，先关闭文本输出项
+			events = append(events, s.closeText()...)
+			call = &responsesStreamToolCall{
+				itemId:      "fc_" + toolCall.ID,
+				callId:      toolCall.ID,
+				name:        toolCall.Function.Name,
+				outputIndex: s.nextOutput,
+			}
+			s.nextOutput++
+			s.toolCalls[index] = call
+			s.toolOrder = append(s.toolOrder, index)
+			events = append(events, s.event("response.output_item.added", map[string]any{
+				"output_index": call.outputIndex,
+				"item":         responsesFunctionCallItem(call.itemId, "in_progress", call.callId, call.name, ""),
+			}))
+		}
+		if args := toolCall.Function.Arguments; args != "" {
+			call.arguments.WriteString(args)
+			events = append(events, s.event("response.function_call_arguments.delta", map[string]any{
+				"item_id": call.itemId, "output_index": call.outputIndex, "delta": args,
+			}))
+		}
+	}
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		s.finish = *choice.FinishReason
+	}
+	return events
+}
+
+// Finish 关闭所有输出项并返回 response.completed（或 incomplete）事件
+func (s *ChatToResponsesStream) Finish(usage *dto.Usage) []map[string]any {
+	events := s.start()
+	events = append(events, s.closeText()...)
+	events = append(events, s.closeToolCalls()...)
+	response := newResponsesObject(s.responseId, s.model, s.createdAt, "completed")
+	output := make([]any, 0, len(s.output))
+	for i := 0; i < s.nextOutput; i++ {
+		if item, ok := s.output[i]; ok {
+			output = append(output, item)
+		}
+	}
+	response["output"] = output
+	response["usage"] = ResponsesUsageFromChat(usage)
+	applyResponsesFinish(response, s.finish)
+	eventType := "response.completed"
+	if response["status"] == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	return append(events, s.event(eventType, map[string]any{"response": response}))
+}
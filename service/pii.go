@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type piiDetector struct {
+	piiType  string
+	label    string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// 检测顺序有意义：证件号最先匹配，电话号码先于卡号，避免带国际区号的号码被 Luhn 误判为卡号
+var piiDetectors = []piiDetector{
+	{
+		piiType:  moderation_setting.PiiTypeIdNumber,
+		label:    "ID_NUMBER",
+		pattern:  regexp.MustCompile(`\b\d{17}[\dXx]\b|\b\d{3}-\d{2}-\d{4}\b`),
+		validate: isValidIdNumber,
+	},
+	{
+		piiType: moderation_setting.PiiTypeEmail,
+		label:   "EMAIL",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		piiType: moderation_setting.PiiTypePhone,
+		label:   "PHONE",
+		pattern: regexp.MustCompile(`\+\d{1,3}[ -]?\(?\d{1,4}\)?(?:[ -]?\d{2,4}){2,3}\b|\b1[3-9]\d{9}\b|\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`),
+	},
+	{
+		piiType:  moderation_setting.PiiTypeCreditCard,
+		label:    "CREDIT_CARD",
+		pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		validate: isValidCreditCard,
+	},
+}
+
+// PiiRedactor 按策略替换文本中的敏感信息，可逆模式下保留占位符与原文的映射
+type PiiRedactor struct {
+	policy   moderation_setting.PiiPolicy
+	mapping  map[string]string // 占位符 -> 原文
+	reverse  map[string]string // 原文 -> 占位符，同一原文复用同一占位符
+	counters map[string]int
+	Counts   map[string]int // 各类型命中次数
+}
+
+func NewPiiRedactor(policy moderation_setting.PiiPolicy) *PiiRedactor {
+	return &PiiRedactor{
+		policy:   policy,
+		mapping:  make(map[string]string),
+		reverse:  make(map[string]string),
+		counters: make(map[string]int),
+		Counts:   make(map[string]int),
+	}
+}
+
+// RedactText 替换文本中命中的敏感信息
+func (r *PiiRedactor) RedactText(text string) string {
+	for _, detector := range piiDetectors {
+		if !r.policy.PiiTypeEnabled(detector.piiType) {
+			continue
+		}
+		text = detector.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if detector.validate != nil && !detector.validate(match) {
+				return match
+			}
+			r.Counts[detector.piiType]++
+			return r.placeholder(detector.label, match)
+		})
+	}
+	return text
+}
+
+func (r *PiiRedactor) placeholder(label string, original string) string {
+	if r.policy.Mode != moderation_setting.PiiModeReversible {
+		return "[" + label + "]"
+	}
+	if p, ok := r.reverse[original]; ok {
+		return p
+	}
+	r.counters[label]++
+	p := fmt.Sprintf("[%s_%d]", label, r.counters[label])
+	r.reverse[original] = p
+	r.mapping[p] = original
+	return p
+}
+
+// Mapping 返回可逆模式下的占位符映射
+func (r *PiiRedactor) Mapping() map[string]string {
+	return r.mapping
+}
+
+// RedactJSON 遍历 JSON 请求体，对文本字段中的敏感信息做替换，未命中时返回原始数据
+func (r *PiiRedactor) RedactJSON(body []byte) ([]byte, bool, error) {
+	return rewriteJSONStrings(body, promptTextKeys, r.RedactText)
+}
+
+// RedactRequestPii 按分组策略对请求体中的敏感信息脱敏，并替换缓存的请求体，需在解析请求之前调用
+func RedactRequestPii(c *gin.Context) error {
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	policy, ok := moderation_setting.GetPiiPolicy(group)
+	if !ok || !strings.Contains(c.Request.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return err
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return err
+	}
+	redactor := NewPiiRedactor(policy)
+	redacted, changed, err := redactor.RedactJSON(body)
+	if err != nil || !changed {
+		return err
+	}
+	if err = common.ReplaceBodyStorage(c, redacted); err != nil {
+		return err
+	}
+	if policy.Mode == moderation_setting.PiiModeReversible {
+		common.SetContextKey(c, constant.ContextKeyPiiMapping, redactor.Mapping())
+	}
+	logger.LogInfo(c, fmt.Sprintf("pii redacted before forwarding upstream: %s", formatPiiCounts(redactor.Counts)))
+	return nil
+}
+
+// GetPiiMapping 返回本次请求可逆脱敏的占位符映射
+func GetPiiMapping(c *gin.Context) map[string]string {
+	mapping, _ := common.GetContextKeyType[map[string]string](c, constant.ContextKeyPiiMapping)
+	return mapping
+}
+
+func formatPiiCounts(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for piiType, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s=%d", piiType, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// isValidIdNumber 校验中国居民身份证号的校验位，或美国 SSN 的号段
+func isValidIdNumber(s string) bool {
+	if strings.Contains(s, "-") {
+		parts := strings.Split(s, "-")
+		return parts[0] != "000" && parts[0] != "666" && parts[0][0] != '9' && parts[1] != "00" && parts[2] != "0000"
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	checks := "10X98765432"
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(s[i]-'0') * weights[i]
+	}
+	return strings.ToUpper(s[17:]) == string(checks[sum%11])
+}
+
+// isValidCreditCard 使用 Luhn 算法校验卡号
+func isValidCreditCard(s string) bool {
+	digits := make([]int, 0, len(s))
+	for _, ch := range s {
+		if ch >= '0' && ch <= '9' {
+			digits = append(digits, int(ch-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
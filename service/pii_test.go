@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestPiiRedactorRedactText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   moderation_setting.PiiPolicy
+		input    string
+		expected string
+	}{
+		{
+			name:     "email and phone masked",
+			policy:   moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeMask},
+			input:    "mail alice@example.com or call 13812345678",
+			expected: "mail [EMAIL] or call [PHONE]",
+		},
+		{
+			name:     "valid card is redacted",
+			policy:   moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeMask},
+			input:    "card 4111 1111 1111 1111 ok",
+			expected: "card [CREDIT_CARD] ok",
+		},
+		{
+			name:     "luhn failure is kept",
+			policy:   moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeMask, Types: []string{moderation_setting.PiiTypeCreditCard}},
+			input:    "order 4111111111111112",
+			expected: "order 4111111111111112",
+		},
+		{
+			name:     "chinese id with checksum",
+			policy:   moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeMask},
+			input:    "id 11010519491231002X",
+			expected: "id [ID_NUMBER]",
+		},
+		{
+			name:     "disabled type is kept",
+			policy:   moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeMask, Types: []string{moderation_setting.PiiTypePhone}},
+			input:    "alice@example.com",
+			expected: "alice@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, NewPiiRedactor(tt.policy).RedactText(tt.input))
+		})
+	}
+}
+
+func TestPiiRedactorRedactJSONReversible(t *testing.T) {
+	t.Parallel()
+
+	redactor := NewPiiRedactor(moderation_setting.PiiPolicy{Enabled: true, Mode: moderation_setting.PiiModeReversible})
+	body := []byte(`{"model":"bob@example.com","max_tokens":1024,"messages":[{"role":"user","content":"I am bob@example.com, cc bob@example.com and amy@example.org"},{"role":"user","content":[{"type":"text","text":"amy@example.org"}]}]}`)
+
+	redacted, changed, err := redactor.RedactJSON(body)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "bob@example.com", gjson.GetBytes(redacted, "model").String())
+	require.Equal(t, "1024", gjson.GetBytes(redacted, "max_tokens").Raw)
+	require.Equal(t, "I am [EMAIL_1], cc [EMAIL_1] and [EMAIL_2]", gjson.GetBytes(redacted, "messages.0.content").String())
+	require.Equal(t, "[EMAIL_2]", gjson.GetBytes(redacted, "messages.1.content.0.text").String())
+	require.Equal(t, map[string]string{"[EMAIL_1]": "bob@example.com", "[EMAIL_2]": "amy@example.org"}, redactor.Mapping())
+}
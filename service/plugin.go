@@ -0,0 +1,328 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// 插件脚本示例：
+//
+//	def handle(ctx):
+//	    if ctx.group == "free" and ctx.model.startswith("gpt-4o"):
+//	        body = json.decode(ctx.body)
+//	        body["max_tokens"] = min(body.get("max_tokens", 4096), 4096)
+//	        return {"model": "gpt-4o-mini", "body": body, "headers": {"X-Plugin": "downgraded"}}
+//	    if not ctx.header("X-Team-Key"):
+//	        return {"deny": "missing team key", "status": 401}
+//
+// ctx 为只读对象，包含 stage、method、path、model、group、channel_id（限定渠道的插件及响应阶段）、user_id、username、token_id、token_name、
+// status（仅响应阶段）、body（请求体原文）、response（响应体原文，仅响应阶段）以及 header(name) 函数。
+// handle 返回 None 表示不做处理，或返回包含以下键的 dict：
+//   - deny: 拒绝原因（字符串或 True），status 可指定 400-599 的状态码，默认 403
+//   - body: 新的请求体（请求阶段）或响应体（响应阶段），可以是 JSON 字符串或 dict/list
+//   - model: 改写模型名称，渠道按新模型选择（仅未限定渠道的请求阶段插件）
+//   - headers: 请求阶段设置请求头，响应阶段设置响应头
+//
+// 脚本只能使用 Starlark 内置函数与 json 模块，不支持 load，执行步数与时长受 plugin_setting 限制。
+
+const (
+	pluginEntryFunction  = "handle"
+	pluginScriptCacheMax = 256
+)
+
+var (
+	pluginScriptMu    sync.RWMutex
+	pluginScriptCache = make(map[string]*starlark.Function, 64)
+
+	pluginPredeclared = starlark.StringDict{
+		"json": starlarkjson.Module,
+	}
+)
+
+// PluginEnv 插件脚本可访问的请求信息
+type PluginEnv struct {
+	Stage  string
+	Method string
+	Path   string
+	Model  string
+	Group  string
+	// ChannelId 已选择的渠道，渠道选择之前为 0
+	ChannelId int
+	UserId    int
+	Username  string
+	TokenId   int
+	TokenName string
+	// Status 响应状态码，仅响应阶段有效
+	Status   int
+	Header   http.Header
+	Body     []byte
+	Response []byte
+}
+
+func (e *PluginEnv) toStarlark() starlark.Value {
+	header := starlark.NewBuiltin("header", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &name); err != nil {
+			return nil, err
+		}
+		return starlark.String(e.Header.Get(name)), nil
+	})
+	return starlarkstruct.FromStringDict(starlark.String("ctx"), starlark.StringDict{
+		"stage":      starlark.String(e.Stage),
+		"method":     starlark.String(e.Method),
+		"path":       starlark.String(e.Path),
+		"model":      starlark.String(e.Model),
+		"group":      starlark.String(e.Group),
+		"channel_id": starlark.MakeInt(e.ChannelId),
+		"user_id":    starlark.MakeInt(e.UserId),
+		"username":   starlark.String(e.Username),
+		"token_id":   starlark.MakeInt(e.TokenId),
+		"token_name": starlark.String(e.TokenName),
+		"status":     starlark.MakeInt(e.Status),
+		"body":       starlark.String(e.Body),
+		"response":   starlark.String(e.Response),
+		"header":     header,
+	})
+}
+
+// PluginResult 插件执行结果
+type PluginResult struct {
+	// Body 改写后的请求体（请求阶段）或响应体（响应阶段），BodyChanged 为 false 时与输入相同
+	Body        []byte
+	BodyChanged bool
+	Headers     map[string]string
+	Denied      bool
+	DenyStatus  int
+	DenyMessage string
+}
+
+// newPluginThread 创建受限的执行线程：禁止 load，限制计算步数，print 输出写入调试日志
+func newPluginThread(c *gin.Context, name string) *starlark.Thread {
+	setting := system_setting.GetPluginSetting()
+	thread := &starlark.Thread{
+		Name: name,
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not allowed in plugins")
+		},
+		Print: func(_ *starlark.Thread, msg string) {
+			if c != nil {
+				logger.LogDebug(c, fmt.Sprintf("plugin %s: %s", name, msg))
+			}
+		},
+	}
+	if setting.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(setting.MaxSteps)
+	}
+	return thread
+}
+
+// compilePluginScript 编译脚本并执行顶层代码，返回冻结后的 handle 函数；冻结后的值可被多个请求并发调用
+func compilePluginScript(script string) (*starlark.Function, error) {
+	pluginScriptMu.RLock()
+	fn, ok := pluginScriptCache[script]
+	pluginScriptMu.RUnlock()
+	if ok {
+		return fn, nil
+	}
+	_, program, err := starlark.SourceProgramOptions(&syntax.FileOptions{}, "plugin.star", script, pluginPredeclared.Has)
+	if err != nil {
+		return nil, err
+	}
+	globals, err := program.Init(newPluginThread(nil, "init"), pluginPredeclared)
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+	fn, ok = globals[pluginEntryFunction].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("script must define function %s(ctx)", pluginEntryFunction)
+	}
+	if fn.NumParams() != 1 {
+		return nil, fmt.Errorf("%s must take exactly one parameter", pluginEntryFunction)
+	}
+	pluginScriptMu.Lock()
+	if len(pluginScriptCache) >= pluginScriptCacheMax {
+		pluginScriptCache = make(map[string]*starlark.Function, 64)
+	}
+	pluginScriptCache[script] = fn
+	pluginScriptMu.Unlock()
+	return fn, nil
+}
+
+// RunPlugins 按顺序执行插件，遇到拒绝动作时立即停止；body 为当前阶段的请求体或响应体
+func RunPlugins(c *gin.Context, plugins []system_setting.Plugin, env *PluginEnv, body []byte) *PluginResult {
+	result := &PluginResult{Body: body, Headers: make(map[string]string)}
+	for _, plugin := range plugins {
+		if err := runPlugin(c, plugin, env, result); err != nil {
+			logger.LogWarn(c, fmt.Sprintf("plugin %s failed: %v", plugin.Name, err))
+			if plugin.FailClosed {
+				result.Denied = true
+				result.DenyStatus = http.StatusForbidden
+				result.DenyMessage = fmt.Sprintf("request rejected: plugin %s failed", plugin.Name)
+			}
+		}
+		if result.Denied {
+			logger.LogInfo(c, fmt.Sprintf("request denied by plugin %s", plugin.Name))
+			break
+		}
+	}
+	return result
+}
+
+func runPlugin(c *gin.Context, plugin system_setting.Plugin, env *PluginEnv, result *PluginResult) error {
+	fn, err := compilePluginScript(plugin.Script)
+	if err != nil {
+		return err
+	}
+	thread := newPluginThread(c, plugin.Name)
+	if timeoutMs := system_setting.GetPluginSetting().TimeoutMs; timeoutMs > 0 {
+		timer := time.AfterFunc(time.Duration(timeoutMs)*time.Millisecond, func() {
+			thread.Cancel("timeout")
+		})
+		defer timer.Stop()
+	}
+	ret, err := starlark.Call(thread, fn, starlark.Tuple{env.toStarlark()}, nil)
+	if err != nil {
+		return err
+	}
+	if ret == starlark.None {
+		return nil
+	}
+	dict, ok := ret.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("%s must return None or dict, got %s", pluginEntryFunction, ret.Type())
+	}
+	return applyPluginResult(thread, plugin, dict, env, result)
+}
+
+func applyPluginResult(thread *starlark.Thread, plugin system_setting.Plugin, dict *starlark.Dict, env *PluginEnv, result *PluginResult) error {
+	if deny, found, _ := dict.Get(starlark.String("deny")); found && bool(deny.Truth()) {
+		result.Denied = true
+		result.DenyStatus = http.StatusForbidden
+		result.DenyMessage = fmt.Sprintf("request rejected by plugin %s", plugin.Name)
+		if message, ok := starlark.AsString(deny); ok {
+			result.DenyMessage = message
+		}
+		if status, found, _ := dict.Get(starlark.String("status")); found {
+			code, err := starlark.AsInt32(status)
+			if err != nil || code < 400 || code > 599 {
+				return fmt.Errorf("deny status must be an integer between 400 and 599")
+			}
+			result.DenyStatus = code
+		}
+		return nil
+	}
+	if body, found, _ := dict.Get(starlark.String("body")); found && body != starlark.None {
+		data, err := pluginBodyBytes(thread, body)
+		if err != nil {
+			return err
+		}
+		setPluginBody(env, result, data)
+	}
+	if model, found, _ := dict.Get(starlark.String("model")); found && model != starlark.None {
+		if env.Stage != system_setting.PluginStageRequest || env.ChannelId != 0 {
+			return fmt.Errorf("model can only be changed in the request stage before channel selection")
+		}
+		name, ok := starlark.AsString(model)
+		if !ok || name == "" {
+			return fmt.Errorf("model must be a non-empty string")
+		}
+		if len(result.Body) == 0 || !gjson.ValidBytes(result.Body) {
+			return fmt.Errorf("body is not JSON, cannot change model")
+		}
+		data, err := sjson.SetBytes(result.Body, "model", name)
+		if err != nil {
+			return err
+		}
+		setPluginBody(env, result, data)
+		env.Model = name
+	}
+	if headers, found, _ := dict.Get(starlark.String("headers")); found && headers != starlark.None {
+		headerDict, ok := headers.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("headers must be a dict")
+		}
+		for _, item := range headerDict.Items() {
+			name, ok1 := starlark.AsString(item[0])
+			value, ok2 := starlark.AsString(item[1])
+			if !ok1 || !ok2 || name == "" || strings.ContainsAny(name+value, "\r\n") {
+				return fmt.Errorf("invalid header %s", item[0])
+			}
+			result.Headers[name] = value
+			if env.Stage == system_setting.PluginStageRequest {
+				env.Header.Set(name, value)
+			}
+		}
+	}
+	return nil
+}
+
+// pluginBodyBytes 将脚本返回的 body 转换为 JSON：字符串需为合法 JSON，dict/list 使用 json.encode 编码
+func pluginBodyBytes(thread *starlark.Thread, body starlark.Value) ([]byte, error) {
+	if text, ok := starlark.AsString(body); ok {
+		if !gjson.Valid(text) {
+			return nil, fmt.Errorf("body is not valid JSON")
+		}
+		return []byte(text), nil
+	}
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{body}, nil)
+	if err != nil {
+		return nil, err
+	}
+	text, _ := starlark.AsString(encoded)
+	return []byte(text), nil
+}
+
+func setPluginBody(env *PluginEnv, result *PluginResult, body []byte) {
+	result.Body = body
+	result.BodyChanged = true
+	if env.Stage == system_setting.PluginStageRequest {
+		env.Body = body
+	} else {
+		env.Response = body
+	}
+}
+
+// ValidatePlugins 校验插件配置，并预编译所有脚本
+func ValidatePlugins(jsonStr string) error {
+	var plugins []system_setting.Plugin
+	if err := common.UnmarshalJsonStr(jsonStr, &plugins); err != nil {
+		return fmt.Errorf("插件配置格式错误: %v", err)
+	}
+	for _, plugin := range plugins {
+		if plugin.Name == "" {
+			return fmt.Errorf("插件名称不能为空")
+		}
+		if plugin.Stage != system_setting.PluginStageRequest && plugin.Stage != system_setting.PluginStageResponse {
+			return fmt.Errorf("插件 %s: 无效的执行阶段 %s", plugin.Name, plugin.Stage)
+		}
+		if strings.TrimSpace(plugin.Script) == "" {
+			return fmt.Errorf("插件 %s: 脚本不能为空", plugin.Name)
+		}
+		for _, channelId := range plugin.ChannelIds {
+			if channelId <= 0 {
+				return fmt.Errorf("插件 %s: 无效的渠道 ID %d", plugin.Name, channelId)
+			}
+		}
+		if _, err := compilePluginScript(plugin.Script); err != nil {
+			return fmt.Errorf("插件 %s: 脚本错误: %v", plugin.Name, err)
+		}
+	}
+	return nil
+}
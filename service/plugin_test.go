@@ -0,0 +1,207 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func newPluginTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	return c
+}
+
+func TestRunPluginsRewritesRequest(t *testing.T) {
+	c := newPluginTestContext()
+	env := &PluginEnv{Stage: system_setting.PluginStageRequest, Model: "gpt-4o", Group: "free", Header: http.Header{}}
+	body := []byte(`{"model":"gpt-4o","max_tokens":8000,"user":"u1"}`)
+	env.Body = body
+
+	plugins := []system_setting.Plugin{
+		{
+			Name:  "downgrade-free",
+			Stage: system_setting.PluginStageRequest,
+			Script: `
+def handle(ctx):
+    if ctx.group != "free" or not ctx.model.startswith("gpt-4o"):
+        return None
+    body = json.decode(ctx.body)
+    body["max_tokens"] = min(body.get("max_tokens", 4096), 4096)
+    body.pop("user")
+    return {"body": body, "model": "gpt-4o-mini", "headers": {"X-Plugin": "downgraded"}}
+`,
+		},
+		{
+			Name:  "sees-previous-rewrite",
+			Stage: system_setting.PluginStageRequest,
+			Script: `
+def handle(ctx):
+    if ctx.model == "gpt-4o":
+        return {"deny": True}
+    return {"headers": {"X-Seen-Model": ctx.model}}
+`,
+		},
+	}
+	result := RunPlugins(c, plugins, env, body)
+	require.False(t, result.Denied)
+	require.True(t, result.BodyChanged)
+	require.Equal(t, "gpt-4o-mini", gjson.GetBytes(result.Body, "model").String())
+	require.EqualValues(t, 4096, gjson.GetBytes(result.Body, "max_tokens").Int())
+	require.False(t, gjson.GetBytes(result.Body, "user").Exists())
+	require.Equal(t, "gpt-4o-mini", env.Model)
+	require.Equal(t, "downgraded", env.Header.Get("X-Plugin"))
+	require.Equal(t, "gpt-4o-mini", result.Headers["X-Seen-Model"])
+}
+
+func TestRunPluginsRewritesResponse(t *testing.T) {
+	c := newPluginTestContext()
+	env := &PluginEnv{Stage: system_setting.PluginStageResponse, Status: http.StatusOK, Header: http.Header{}}
+	response := []byte(`{"id":"1","system_fingerprint":"fp"}`)
+	env.Response = response
+
+	plugins := []system_setting.Plugin{{
+		Name:  "strip",
+		Stage: system_setting.PluginStageResponse,
+		Script: `
+def handle(ctx):
+    if ctx.status != 200:
+        return None
+    data = json.decode(ctx.response)
+    data.pop("system_fingerprint")
+    return {"body": json.encode(data), "headers": {"X-Filtered": "1"}}
+`,
+	}}
+	result := RunPlugins(c, plugins, env, response)
+	require.True(t, result.BodyChanged)
+	require.JSONEq(t, `{"id":"1"}`, string(result.Body))
+	require.Equal(t, "1", result.Headers["X-Filtered"])
+	require.Empty(t, env.Header.Get("X-Filtered"))
+}
+
+func TestRunPluginsDeny(t *testing.T) {
+	c := newPluginTestContext()
+	env := &PluginEnv{Stage: system_setting.PluginStageRequest, Header: http.Header{}}
+
+	auth := []system_setting.Plugin{{
+		Name:  "team-auth",
+		Stage: system_setting.PluginStageRequest,
+		Script: `
+def handle(ctx):
+    if ctx.header("X-Team-Key") != "secret":
+        return {"deny": "missing team key", "status": 401}
+`,
+	}}
+	result := RunPlugins(c, auth, env, nil)
+	require.True(t, result.Denied)
+	require.Equal(t, http.StatusUnauthorized, result.DenyStatus)
+	require.Equal(t, "missing team key", result.DenyMessage)
+
+	env.Header.Set("X-Team-Key", "secret")
+	require.False(t, RunPlugins(c, auth, env, nil).Denied)
+
+	// 非 JSON 请求体无法改写模型：默认跳过，fail_closed 时拒绝
+	rewrite := []system_setting.Plugin{{
+		Name:   "rewrite",
+		Stage:  system_setting.PluginStageRequest,
+		Script: "def handle(ctx):\n    return {\"model\": \"x\"}\n",
+	}}
+	result = RunPlugins(c, rewrite, env, []byte("plain"))
+	require.False(t, result.Denied)
+	require.False(t, result.BodyChanged)
+	rewrite[0].FailClosed = true
+	result = RunPlugins(c, rewrite, env, []byte("plain"))
+	require.True(t, result.Denied)
+	require.Equal(t, http.StatusForbidden, result.DenyStatus)
+}
+
+func TestRunPluginsSandboxLimits(t *testing.T) {
+	c := newPluginTestContext()
+	env := &PluginEnv{Stage: system_setting.PluginStageRequest, Header: http.Header{}}
+	setting := system_setting.GetPluginSetting()
+	original := *setting
+	t.Cleanup(func() { *setting = original })
+
+	busy := []system_setting.Plugin{{
+		Name:       "busy",
+		Stage:      system_setting.PluginStageRequest,
+		FailClosed: true,
+		Script: `
+def handle(ctx):
+    n = 0
+    for i in range(100000000):
+        n += i
+    return None
+`,
+	}}
+
+	// 超出步数限制
+	setting.MaxSteps = 10000
+	setting.TimeoutMs = 0
+	require.True(t, RunPlugins(c, busy, env, nil).Denied)
+
+	// 超出执行时长
+	setting.MaxSteps = 0
+	setting.TimeoutMs = 10
+	require.True(t, RunPlugins(c, busy, env, nil).Denied)
+
+	// 不允许加载外部模块
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"load(\"x.star\", \"y\")\ndef handle(ctx):\n    return None\n"}]`))
+}
+
+func TestValidatePlugins(t *testing.T) {
+	require.NoError(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"def handle(ctx):\n    return None\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"","stage":"request","script":"def handle(ctx):\n    return None\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"later","script":"def handle(ctx):\n    return None\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":""}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"def handle(ctx):\n    return (\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"def other(ctx):\n    return None\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"def handle():\n    return None\n"}]`))
+	require.Error(t, ValidatePlugins(`[{"name":"a","stage":"request","script":"x = open(\"/etc/passwd\")\ndef handle(ctx):\n    return None\n"}]`))
+}
+
+func TestPluginScope(t *testing.T) {
+	plugins := []system_setting.Plugin{
+		{Name: "all"},
+		{Name: "vip", Groups: []string{"vip"}},
+		{Name: "channel", ChannelIds: []int{3}},
+		{Name: "vip-channel", Groups: []string{"vip"}, ChannelIds: []int{3, 4}},
+	}
+	names := func(filtered []system_setting.Plugin) []string {
+		result := make([]string, 0, len(filtered))
+		for _, plugin := range filtered {
+			result = append(result, plugin.Name)
+		}
+		return result
+	}
+
+	require.Equal(t, []string{"all"}, names(system_setting.FilterPlugins(plugins, "default", 0, false)))
+	require.Equal(t, []string{"all", "vip"}, names(system_setting.FilterPlugins(plugins, "vip", 0, false)))
+	require.Equal(t, []string{"channel"}, names(system_setting.FilterPlugins(plugins, "default", 3, true)))
+	require.Equal(t, []string{"channel", "vip-channel"}, names(system_setting.FilterPlugins(plugins, "vip", 3, true)))
+	require.Equal(t, []string{"vip-channel"}, names(system_setting.FilterPlugins(plugins, "vip", 4, true)))
+}
+
+func TestRunPluginsCannotChangeModelAfterChannelSelection(t *testing.T) {
+	c := newPluginTestContext()
+	env := &PluginEnv{Stage: system_setting.PluginStageRequest, Model: "gpt-4o", ChannelId: 3, Header: http.Header{}}
+	plugins := []system_setting.Plugin{{
+		Name:       "downgrade",
+		Stage:      system_setting.PluginStageRequest,
+		ChannelIds: []int{3},
+		Script: `
+def handle(ctx):
+    return {"model": "gpt-4o-mini"}
+`,
+		FailClosed: true,
+	}}
+	result := RunPlugins(c, plugins, env, []byte(`{"model":"gpt-4o"}`))
+	require.True(t, result.Denied)
+	require.Equal(t, "gpt-4o", env.Model)
+}
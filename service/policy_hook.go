@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+// 外部策略决策
+const (
+	PolicyDecisionAllow  = "allow"
+	PolicyDecisionDeny   = "deny"
+	PolicyDecisionModify = "modify"
+)
+
+// PolicyHookRequest 发送给策略服务的请求元数据，不包含请求正文
+type PolicyHookRequest struct {
+	RequestId             string  `json:"request_id"`
+	UserId                int     `json:"user_id"`
+	Username              string  `json:"username"`
+	Group                 string  `json:"group"`
+	TokenId               int     `json:"token_id"`
+	TokenName             string  `json:"token_name"`
+	Model                 string  `json:"model"`
+	RelayFormat           string  `json:"relay_format"`
+	Path                  string  `json:"path"`
+	IsStream              bool    `json:"is_stream"`
+	ClientIp              string  `json:"client_ip"`
+	EstimatedPromptTokens int     `json:"estimated_prompt_tokens"`
+	EstimatedQuota        int     `json:"estimated_quota"`
+	EstimatedCostUSD      float64 `json:"estimated_cost_usd"`
+}
+
+// PolicyDecision 策略服务返回的决策，modify 时可通过 Model 改写请求使用的模型
+type PolicyDecision struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// EvaluatePolicyHook 调用外部策略服务获取决策，调用失败时按 FailOpen 配置决定放行或拒绝
+func EvaluatePolicyHook(ctx context.Context, req PolicyHookRequest) (*PolicyDecision, error) {
+	setting := system_setting.GetPolicyHookSetting()
+	decision, err := callPolicyHook(ctx, setting, req)
+	if err == nil {
+		return decision, nil
+	}
+	if setting.FailOpen {
+		return &PolicyDecision{Decision: PolicyDecisionAllow, Reason: "policy hook unavailable, fail open"}, err
+	}
+	return &PolicyDecision{Decision: PolicyDecisionDeny, Reason: "policy service unavailable"}, err
+}
+
+func callPolicyHook(ctx context.Context, setting *system_setting.PolicyHookSetting, req PolicyHookRequest) (*PolicyDecision, error) {
+	timeout := time.Duration(setting.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var body any = req
+	if setting.OpaFormat {
+		body = map[string]any{"input": req}
+	}
+	payload, err := common.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.Url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if setting.AuthHeader != "" {
+		httpReq.Header.Set("Authorization", setting.AuthHeader)
+	}
+	resp, err := GetHttpClient().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("policy hook returned status code %d: %s", resp.StatusCode, string(respBody))
+	}
+	return parsePolicyDecision(respBody, setting.OpaFormat)
+}
+
+func parsePolicyDecision(body []byte, opaFormat bool) (*PolicyDecision, error) {
+	var decision PolicyDecision
+	if opaFormat {
+		var wrapper struct {
+			Result *PolicyDecision `json:"result"`
+		}
+		if err := common.Unmarshal(body, &wrapper); err != nil {
+			return nil, err
+		}
+		if wrapper.Result == nil {
+			// OPA 在规则未定义时不返回 result，视为未授权
+			return nil, fmt.Errorf("policy hook returned no result")
+		}
+		decision = *wrapper.Result
+	} else if err := common.Unmarshal(body, &decision); err != nil {
+		return nil, err
+	}
+	switch decision.Decision {
+	case PolicyDecisionAllow, PolicyDecisionDeny:
+	case PolicyDecisionModify:
+		if decision.Model == "" {
+			return nil, fmt.Errorf("policy hook returned modify decision without model")
+		}
+	default:
+		return nil, fmt.Errorf("policy hook returned unknown decision: %s", decision.Decision)
+	}
+	return &decision, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicyDecision(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		body      string
+		opa       bool
+		expected  *PolicyDecision
+		expectErr bool
+	}{
+		{name: "allow", body: `{"decision":"allow"}`, expected: &PolicyDecision{Decision: PolicyDecisionAllow}},
+		{name: "deny with reason", body: `{"decision":"deny","reason":"budget exceeded"}`, expected: &PolicyDecision{Decision: PolicyDecisionDeny, Reason: "budget exceeded"}},
+		{name: "opa modify", body: `{"result":{"decision":"modify","model":"gpt-4o-mini"}}`, opa: true, expected: &PolicyDecision{Decision: PolicyDecisionModify, Model: "gpt-4o-mini"}},
+		{name: "opa undefined", body: `{}`, opa: true, expectErr: true},
+		{name: "modify without model", body: `{"decision":"modify"}`, expectErr: true},
+		{name: "unknown decision", body: `{"decision":"maybe"}`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			decision, err := parsePolicyDecision([]byte(tt.body), tt.opa)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, decision)
+		})
+	}
+}
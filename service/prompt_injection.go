@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxPromptInjectionScore = 100
+
+type promptInjectionHeuristic struct {
+	name    string
+	pattern *regexp.Regexp
+	weight  int
+}
+
+// 内置启发式规则，覆盖指令覆盖、系统提示词套取、角色扮演越狱与对话分隔符伪造
+var promptInjectionHeuristics = []promptInjectionHeuristic{
+	{
+		name:    "instruction_override",
+		pattern: regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(previous|prior|above|earlier|all|any|your)\b.{0,20}\b(instructions?|prompts?|rules|directives|guidelines)\b`),
+		weight:  45,
+	},
+	{
+		name:    "instruction_override_zh",
+		pattern: regexp.MustCompile(`(忽略|无视|忘记|忘掉)(掉)?(你)?(之前|以上|上面|前面|先前|所有)(的)?(所有)?(指令|指示|提示|规则|设定|要求)`),
+		weight:  45,
+	},
+	{
+		name:    "system_prompt_extraction",
+		pattern: regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output|leak)\b.{0,20}\b(system prompt|initial prompt|hidden (instructions|prompt)|your instructions)\b`),
+		weight:  35,
+	},
+	{
+		name:    "system_prompt_extraction_zh",
+		pattern: regexp.MustCompile(`(输出|显示|重复|告诉我|泄露).{0,10}(系统提示词|系统提示|初始指令|system prompt)`),
+		weight:  35,
+	},
+	{
+		name:    "roleplay_jailbreak",
+		pattern: regexp.MustCompile(`(?i)\b(you are now|act as|pretend (to be|you are)|roleplay as)\b.{0,60}\b(DAN|unrestricted|unfiltered|uncensored|no (rules|restrictions|limits|filters)|without (any )?(rules|restrictions|limits|filters))\b`),
+		weight:  40,
+	},
+	{
+		name:    "roleplay_jailbreak_zh",
+		pattern: regexp.MustCompile(`(你现在是|扮演|假装).{0,30}(没有|不受|无视|不需要遵守).{0,10}(限制|约束|规则|审查)`),
+		weight:  40,
+	},
+	{
+		name:    "jailbreak_keywords",
+		pattern: regexp.MustCompile(`(?i)\b(jailbreak|developer mode|do anything now|DAN mode)\b|越狱|开发者模式`),
+		weight:  25,
+	},
+	{
+		name:    "delimiter_injection",
+		pattern: regexp.MustCompile(`(?i)<\|im_start\|>\s*system|<\|system\|>|\[/?INST\]|<</?SYS>>|^\s*#{2,}\s*(system|new instructions?)\b`),
+		weight:  20,
+	},
+}
+
+var promptInjectionRegexCache sync.Map // map[string]*regexp.Regexp
+
+// PromptInjectionResult 提示词注入风险评分
+type PromptInjectionResult struct {
+	Score int      `json:"score"`
+	Rules []string `json:"rules,omitempty"`
+}
+
+// ScorePromptInjection 按启发式规则为文本计算注入风险分（0-100），各规则命中分值累加
+func ScorePromptInjection(text string) PromptInjectionResult {
+	result := PromptInjectionResult{}
+	if strings.TrimSpace(text) == "" {
+		return result
+	}
+	for _, heuristic := range promptInjectionHeuristics {
+		if heuristic.pattern.MatchString(text) {
+			result.Score += heuristic.weight
+			result.Rules = append(result.Rules, heuristic.name)
+		}
+	}
+	for _, rule := range moderation_setting.GetPromptInjectionSetting().ExtraRules {
+		re, ok := promptInjectionRegexCache.Load(rule.Pattern)
+		if !ok {
+			compiled, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			re = compiled
+			promptInjectionRegexCache.Store(rule.Pattern, re)
+		}
+		if re.(*regexp.Regexp).MatchString(text) {
+			result.Score += rule.Weight
+			result.Rules = append(result.Rules, rule.Name)
+		}
+	}
+	if result.Score > maxPromptInjectionScore {
+		result.Score = maxPromptInjectionScore
+	}
+	return result
+}
+
+// ShouldCheckPromptInjection 判断是否需要进行提示词注入检测
+func ShouldCheckPromptInjection() bool {
+	return moderation_setting.GetPromptInjectionSetting().Enabled
+}
+
+// CheckPromptInjection 对输入文本评分并记录到上下文，分数达到分组阈值时返回错误
+func CheckPromptInjection(c *gin.Context, group string, text string) *types.NewAPIError {
+	result := ScorePromptInjection(text)
+	if result.Score == 0 {
+		return nil
+	}
+	common.SetContextKey(c, constant.ContextKeyPromptInjection, result)
+	threshold := moderation_setting.GetPromptInjectionThreshold(group)
+	message := fmt.Sprintf("prompt injection score %d (threshold %d), rules: %s", result.Score, threshold, strings.Join(result.Rules, ","))
+	if threshold > 0 && result.Score >= threshold {
+		logger.LogWarn(c, message)
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("request rejected: prompt injection risk score %d exceeds threshold", result.Score),
+			types.ErrorCodePromptInjectionDetected, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	logger.LogInfo(c, message)
+	return nil
+}
+
+// GetPromptInjectionResult 返回本次请求的注入风险评分，用于写入消费日志
+func GetPromptInjectionResult(c *gin.Context) (PromptInjectionResult, bool) {
+	return common.GetContextKeyType[PromptInjectionResult](c, constant.ContextKeyPromptInjection)
+}
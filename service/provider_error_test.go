@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapProviderError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		statusCode     int
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedType   string
+	}{
+		{
+			name:           "claude rate limit",
+			statusCode:     429,
+			body:           `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCode:   "rate_limit_exceeded",
+			expectedType:   types.OpenAIErrorTypeRequests,
+		},
+		{
+			name:           "gemini resource exhausted",
+			statusCode:     429,
+			body:           `{"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCode:   "rate_limit_exceeded",
+			expectedType:   types.OpenAIErrorTypeRequests,
+		},
+		{
+			name:           "claude context overflow",
+			statusCode:     400,
+			body:           `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 215000 tokens > 200000 maximum"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "context_length_exceeded",
+			expectedType:   types.OpenAIErrorTypeInvalidRequest,
+		},
+		{
+			name:           "azure content filter",
+			statusCode:     400,
+			body:           `{"error":{"message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy.","type":null,"param":"prompt","code":"content_filter"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "content_policy_violation",
+			expectedType:   types.OpenAIErrorTypeInvalidRequest,
+		},
+		{
+			name:           "upstream invalid key",
+			statusCode:     401,
+			body:           `{"error":{"message":"Incorrect API key provided: sk-abc***","type":"invalid_request_error","param":null,"code":"invalid_api_key"}}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "upstream_authentication_failed",
+			expectedType:   types.OpenAIErrorTypeServer,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := &http.Response{StatusCode: tc.statusCode, Body: io.NopCloser(strings.NewReader(tc.body))}
+			newAPIError := RelayErrorHandler(context.Background(), resp, false)
+			require.NotNil(t, newAPIError.GetProviderError())
+			// 映射前保留原始状态码，渠道重试与自动禁用按原始状态码判断
+			require.Equal(t, tc.statusCode, newAPIError.StatusCode)
+
+			require.True(t, newAPIError.MapProviderError())
+			require.Equal(t, tc.expectedStatus, newAPIError.StatusCode)
+			openAIError := newAPIError.ToOpenAIError()
+			require.Equal(t, tc.expectedCode, openAIError.Code)
+			require.Equal(t, tc.expectedType, openAIError.Type)
+
+			var metadata struct {
+				Category      string                    `json:"category"`
+				ProviderError types.ProviderErrorDetail `json:"provider_error"`
+			}
+			require.NoError(t, common.Unmarshal(openAIError.Metadata, &metadata))
+			require.NotEmpty(t, metadata.Category)
+			require.Equal(t, tc.statusCode, metadata.ProviderError.StatusCode)
+			require.NotEmpty(t, metadata.ProviderError.Message)
+		})
+	}
+}
+
+func TestMapProviderErrorSkipsUnclassified(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{StatusCode: 500, Body: io.NopCloser(strings.NewReader(`{"error":{"message":"internal error","type":"server_error"}}`))}
+	newAPIError := RelayErrorHandler(context.Background(), resp, false)
+	require.False(t, newAPIError.MapProviderError())
+	require.Equal(t, 500, newAPIError.StatusCode)
+
+	// 网关自身产生的错误不做映射
+	localError := types.NewErrorWithStatusCode(io.EOF, types.ErrorCodeInsufficientUserQuota, http.StatusTooManyRequests)
+	require.Nil(t, localError.GetProviderError())
+	require.False(t, localError.MapProviderError())
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const (
+	realtimeSessionKeyPrefix = "new-api:realtime_sessions:v1:"
+	// Redis 计数的兜底过期时间，进程异常退出未释放时计数最终会被清除
+	realtimeSessionCounterTTL = 6 * time.Hour
+)
+
+var (
+	realtimeSessionLock   sync.Mutex
+	realtimeSessionCounts = make(map[string]int)
+)
+
+// ErrRealtimeSessionLimit 超过 Realtime 并发会话上限
+type ErrRealtimeSessionLimit struct {
+	Scope string
+	Limit int
+}
+
+func (e *ErrRealtimeSessionLimit) Error() string {
+	return fmt.Sprintf("too many concurrent realtime sessions for this %s (limit %d)", e.Scope, e.Limit)
+}
+
+type realtimeSessionSlot struct {
+	scope string
+	key   string
+	limit int
+}
+
+// AcquireRealtimeSession 占用令牌与用户的 Realtime 会话名额，成功时返回的 release 需在会话结束后调用。
+// 启用 Redis 时在多节点间共享计数
+func AcquireRealtimeSession(tokenId int, userId int) (release func(), err error) {
+	setting := operation_setting.GetRealtimeSetting()
+	var slots []realtimeSessionSlot
+	if setting.MaxSessionsPerToken > 0 && tokenId > 0 {
+		slots = append(slots, realtimeSessionSlot{scope: "token", key: fmt.Sprintf("token:%d", tokenId), limit: setting.MaxSessionsPerToken})
+	}
+	if setting.MaxSessionsPerUser > 0 && userId > 0 {
+		slots = append(slots, realtimeSessionSlot{scope: "user", key: fmt.Sprintf("user:%d", userId), limit: setting.MaxSessionsPerUser})
+	}
+	acquired := make([]string, 0, len(slots))
+	releaseAll := func() {
+		for _, key := range acquired {
+			decrRealtimeSession(key)
+		}
+	}
+	for _, slot := range slots {
+		count, err := incrRealtimeSession(slot.key)
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		acquired = append(acquired, slot.key)
+		if count > slot.limit {
+			releaseAll()
+			return nil, &ErrRealtimeSessionLimit{Scope: slot.scope, Limit: slot.limit}
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(releaseAll) }, nil
+}
+
+func incrRealtimeSession(key string) (int, error) {
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		count, err := common.RDB.Incr(ctx, realtimeSessionKeyPrefix+key).Result()
+		if err != nil {
+			return 0, err
+		}
+		common.RDB.Expire(ctx, realtimeSessionKeyPrefix+key, realtimeSessionCounterTTL)
+		return int(count), nil
+	}
+	realtimeSessionLock.Lock()
+	defer realtimeSessionLock.Unlock()
+	realtimeSessionCounts[key]++
+	return realtimeSessionCounts[key], nil
+}
+
+func decrRealtimeSession(key string) {
+	if common.RedisEnabled && common.RDB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := common.RDB.Decr(ctx, realtimeSessionKeyPrefix+key).Err(); err != nil {
+			common.SysError("failed to release realtime session: " + err.Error())
+		}
+		return
+	}
+	realtimeSessionLock.Lock()
+	defer realtimeSessionLock.Unlock()
+	if realtimeSessionCounts[key] <= 1 {
+		delete(realtimeSessionCounts, key)
+		return
+	}
+	realtimeSessionCounts[key]--
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireRealtimeSession(t *testing.T) {
+	setting := operation_setting.GetRealtimeSetting()
+	original := *setting
+	t.Cleanup(func() { *setting = original })
+	setting.MaxSessionsPerToken = 1
+	setting.MaxSessionsPerUser = 2
+
+	release1, err := AcquireRealtimeSession(1, 10)
+	require.NoError(t, err)
+	_, err = AcquireRealtimeSession(1, 10)
+	require.ErrorAs(t, err, new(*ErrRealtimeSessionLimit))
+
+	release2, err := AcquireRealtimeSession(2, 10)
+	require.NoError(t, err)
+	// 用户名额已满，且失败时不占用令牌名额
+	_, err = AcquireRealtimeSession(3, 10)
+	require.Error(t, err)
+	release3, err := AcquireRealtimeSession(3, 11)
+	require.NoError(t, err)
+
+	release1()
+	release1()
+	release4, err := AcquireRealtimeSession(1, 10)
+	require.NoError(t, err)
+	for _, release := range []func(){release2, release3, release4} {
+		release()
+	}
+	require.Empty(t, realtimeSessionCounts)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// RequestDedupResult 首个请求的上游响应及其计费结果，供合并的请求共享
+type RequestDedupResult struct {
+	ResponseCacheRecord
+	Quota int
+}
+
+// RequestDedupCall 一组正在处理的相同请求
+type RequestDedupCall struct {
+	done    chan struct{}
+	result  *RequestDedupResult
+	waiters int
+}
+
+// Wait 等待首个请求完成，首个请求失败、响应不可共享或等待超时时返回 false
+func (call *RequestDedupCall) Wait(ctx context.Context, timeout time.Duration) (*RequestDedupResult, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-call.done:
+		return call.result, call.result != nil
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+type requestDedupGroup struct {
+	mu    sync.Mutex
+	calls map[string]*RequestDedupCall
+}
+
+// 合并仅在当前节点内生效，多节点部署时各节点分别转发一次
+var requestDedup = &requestDedupGroup{calls: make(map[string]*RequestDedupCall)}
+
+// ShouldDedupRequest 判断请求路径与分组是否启用了并发请求合并
+func ShouldDedupRequest(path string, group string) bool {
+	setting := operation_setting.GetRequestDedupSetting()
+	if !setting.Enabled || !slices.Contains(responseCacheablePaths, path) {
+		return false
+	}
+	return len(setting.Groups) == 0 || slices.Contains(setting.Groups, group)
+}
+
+// RequestDedupKey 计算请求合并键，流式请求不合并；userId 为 0 表示跨用户合并
+func RequestDedupKey(path string, group string, userId int, body []byte) (string, bool) {
+	var request map[string]any
+	if err := common.Unmarshal(body, &request); err != nil {
+		return "", false
+	}
+	if stream, _ := request["stream"].(bool); stream {
+		return "", false
+	}
+	for _, field := range responseCacheIgnoredFields {
+		delete(request, field)
+	}
+	normalized, err := common.Marshal(request)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	h.Write([]byte(fmt.Sprintf("%s\n%s\n%d\n", path, group, userId)))
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// JoinRequestDedup 加入相同请求组，没有进行中的相同请求时当前请求成为首个请求（leader 为 true），负责转发上游
+func JoinRequestDedup(key string) (call *RequestDedupCall, leader bool) {
+	return requestDedup.join(key)
+}
+
+// FinishRequestDedup 首个请求完成后唤醒等待的请求，result 为 nil 表示响应不可共享，等待的请求各自转发上游。返回等待的请求数
+func FinishRequestDedup(key string, call *RequestDedupCall, result *RequestDedupResult) int {
+	return requestDedup.finish(key, call, result)
+}
+
+// NewRequestDedupResult 从首个请求的成功响应构建共享结果，非 JSON 响应不共享
+func NewRequestDedupResult(modelName string, contentType string, body []byte, quota int) *RequestDedupResult {
+	record, ok := newResponseCacheRecord(modelName, contentType, body)
+	if !ok {
+		return nil
+	}
+	return &RequestDedupResult{ResponseCacheRecord: *record, Quota: quota}
+}
+
+func (g *requestDedupGroup) join(key string) (*RequestDedupCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		return call, false
+	}
+	call := &RequestDedupCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+func (g *requestDedupGroup) finish(key string, call *RequestDedupCall, result *RequestDedupResult) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.result = result
+	close(call.done)
+	return call.waiters
+}
+
+// ChargeRequestDedupFollower 按 per_request 策略向合并的请求收取与首个请求相同的额度，从钱包与令牌中扣除
+func ChargeRequestDedupFollower(userId int, tokenId int, tokenKey string, tokenUnlimited bool, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	if err := model.DecreaseUserQuota(userId, quota, false); err != nil {
+		return err
+	}
+	if err := model.DecreaseTokenQuota(tokenId, tokenKey, quota); err != nil {
+		return err
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(userId, quota)
+	if !tokenUnlimited {
+		CheckTokenExhausted(tokenKey)
+	}
+	return nil
+}
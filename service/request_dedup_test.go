@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestDedupKey(t *testing.T) {
+	path := "/v1/chat/completions"
+	key, ok := RequestDedupKey(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"hi"}],"user":"a"}`))
+	require.True(t, ok)
+
+	// 与响应缓存不同，非确定性请求同样可以合并
+	same, ok := RequestDedupKey(path, "default", 1, []byte(`{"messages":[{"role":"user","content":"hi"}],"temperature":0.7,"model":"gpt-4o","user":"b"}`))
+	require.True(t, ok)
+	require.Equal(t, key, same)
+
+	other, _ := RequestDedupKey(path, "default", 2, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, key, other)
+
+	_, ok = RequestDedupKey(path, "default", 1, []byte(`{"model":"gpt-4o","stream":true,"messages":[]}`))
+	require.False(t, ok)
+}
+
+func TestRequestDedupFanOut(t *testing.T) {
+	group := &requestDedupGroup{calls: make(map[string]*RequestDedupCall)}
+	call, leader := group.join("k")
+	require.True(t, leader)
+
+	followers := make([]*RequestDedupCall, 3)
+	for i := range followers {
+		var isLeader bool
+		followers[i], isLeader = group.join("k")
+		require.False(t, isLeader)
+		require.Same(t, call, followers[i])
+	}
+
+	results := make(chan *RequestDedupResult, len(followers))
+	for _, follower := range followers {
+		go func(follower *RequestDedupCall) {
+			result, _ := follower.Wait(context.Background(), time.Second)
+			results <- result
+		}(follower)
+	}
+	shared := &RequestDedupResult{ResponseCacheRecord: ResponseCacheRecord{Body: []byte(`{"id":"1"}`)}, Quota: 100}
+	require.Equal(t, 3, group.finish("k", call, shared))
+	for range followers {
+		require.Same(t, shared, <-results)
+	}
+
+	// 首个请求完成后，新的相同请求重新成为首个请求
+	next, leader := group.join("k")
+	require.True(t, leader)
+	require.NotSame(t, call, next)
+
+	// 首个请求失败时等待的请求各自转发
+	follower, _ := group.join("k")
+	group.finish("k", next, nil)
+	_, ok := follower.Wait(context.Background(), time.Second)
+	require.False(t, ok)
+}
+
+func TestRequestDedupWaitTimeout(t *testing.T) {
+	group := &requestDedupGroup{calls: make(map[string]*RequestDedupCall)}
+	call, _ := group.join("k")
+	_, ok := call.Wait(context.Background(), 10*time.Millisecond)
+	require.False(t, ok)
+	group.finish("k", call, nil)
+}
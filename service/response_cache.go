@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/samber/hot"
+)
+
+const (
+	responseCacheNamespace = "new-api:response_cache:v1"
+
+	ResponseCacheHit  = "hit"
+	ResponseCacheMiss = "miss"
+)
+
+// responseCacheablePaths 支持响应缓存的中继接口
+var responseCacheablePaths = []string{
+	"/v1/chat/completions",
+	"/v1/completions",
+	"/v1/messages",
+}
+
+// responseCacheIgnoredFields 不影响生成结果的字段，计算缓存键时忽略
+var responseCacheIgnoredFields = []string{"user", "metadata", "stream", "stream_options"}
+
+// ResponseCacheRecord 缓存的一次确定性请求的响应
+type ResponseCacheRecord struct {
+	ContentType      string `json:"content_type"`
+	Body             []byte `json:"body"`
+	ModelName        string `json:"model_name"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+var (
+	responseCacheOnce sync.Once
+	responseCache     *cachex.HybridCache[ResponseCacheRecord]
+)
+
+func getResponseCache() *cachex.HybridCache[ResponseCacheRecord] {
+	responseCacheOnce.Do(func() {
+		setting := operation_setting.GetResponseCacheSetting()
+		capacity := setting.MaxEntries
+		if capacity <= 0 {
+			capacity = 10000
+		}
+		responseCache = cachex.NewHybridCache[ResponseCacheRecord](cachex.HybridCacheConfig[ResponseCacheRecord]{
+			Namespace: cachex.Namespace(responseCacheNamespace),
+			Redis:     common.RDB,
+			RedisEnabled: func() bool {
+				return common.RedisEnabled && common.RDB != nil
+			},
+			RedisCodec: cachex.JSONCodec[ResponseCacheRecord]{},
+			Memory: func() *hot.HotCache[string, ResponseCacheRecord] {
+				return hot.NewHotCache[string, ResponseCacheRecord](hot.LRU, capacity).
+					WithTTL(responseCacheTTL()).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return responseCache
+}
+
+func responseCacheTTL() time.Duration {
+	ttl := operation_setting.GetResponseCacheSetting().TTLSeconds
+	if ttl <= 0 {
+		ttl = 3600
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// ShouldCacheResponse 判断请求路径与分组是否启用了响应缓存
+func ShouldCacheResponse(path string, group string) bool {
+	setting := operation_setting.GetResponseCacheSetting()
+	if !setting.Enabled || !slices.Contains(responseCacheablePaths, path) {
+		return false
+	}
+	return len(setting.Groups) == 0 || slices.Contains(setting.Groups, group)
+}
+
+// ResponseCacheKey 为确定性请求计算缓存键：仅 temperature 显式为 0 的非流式请求可缓存，
+// 请求体按字段排序规范化并忽略 user 等不影响结果的字段；userId 为 0 表示跨用户共享
+func ResponseCacheKey(path string, group string, userId int, body []byte) (string, bool) {
+	var request map[string]any
+	if err := common.Unmarshal(body, &request); err != nil {
+		return "", false
+	}
+	if stream, _ := request["stream"].(bool); stream {
+		return "", false
+	}
+	if temperature, ok := request["temperature"].(float64); !ok || temperature != 0 {
+		return "", false
+	}
+	for _, field := range responseCacheIgnoredFields {
+		delete(request, field)
+	}
+	// encoding/json 按键名排序输出 map，字段顺序不同的相同请求得到相同的键
+	normalized, err := common.Marshal(request)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	h.Write([]byte(fmt.Sprintf("%s\n%s\n%d\n", path, group, userId)))
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func GetCachedResponse(key string) (*ResponseCacheRecord, bool, error) {
+	record, found, err := getResponseCache().Get(key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return &record, true, nil
+}
+
+// SaveCachedResponse 缓存上游的成功响应，响应体中的 usage 用于命中时记录日志
+func SaveCachedResponse(key string, modelName string, contentType string, body []byte) error {
+	record, ok := newResponseCacheRecord(modelName, contentType, body)
+	if !ok {
+		return nil
+	}
+	return getResponseCache().SetWithTTL(key, *record, responseCacheTTL())
+}
+
+// newResponseCacheRecord 从 JSON 响应构建缓存记录并解析用量，非 JSON 响应不缓存
+func newResponseCacheRecord(modelName string, contentType string, body []byte) (*ResponseCacheRecord, bool) {
+	if !strings.Contains(contentType, "application/json") {
+		return nil, false
+	}
+	record := &ResponseCacheRecord{
+		ContentType: contentType,
+		Body:        body,
+		ModelName:   modelName,
+		CreatedAt:   common.GetTimestamp(),
+	}
+	var response struct {
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			InputTokens      int `json:"input_tokens"`
+			OutputTokens     int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := common.Unmarshal(body, &response); err == nil && response.Usage != nil {
+		record.PromptTokens = response.Usage.PromptTokens + response.Usage.InputTokens
+		record.CompletionTokens = response.Usage.CompletionTokens + response.Usage.OutputTokens
+	}
+	return record, true
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseCacheHeader 命中缓存时返回的响应头，值为命中类型
+const ResponseCacheHeader = "X-New-Api-Cache"
+
+// pendingCachedResponse 已命中但尚未返回的缓存结果
+type pendingCachedResponse struct {
+	record  *ResponseCacheRecord
+	status  string
+	content string
+	extra   map[string]interface{}
+}
+
+// DeferCachedResponse 记录命中的缓存结果。缓存中间件在请求侧的敏感词、审核、提示词注入、滥用模式与策略钩子检查之前运行，
+// 命中后不能直接返回，由 Relay 在这些检查通过后调用 ReplayPendingCachedResponse 返回；extra 为追加到日志 other 中的字段
+func DeferCachedResponse(c *gin.Context, record *ResponseCacheRecord, status string, content string, extra map[string]interface{}) {
+	common.SetContextKey(c, constant.ContextKeyPendingCachedResponse, &pendingCachedResponse{
+		record:  record,
+		status:  status,
+		content: content,
+		extra:   extra,
+	})
+}
+
+// HasPendingCachedResponse 本次请求是否已命中缓存、等待检查通过后返回
+func HasPendingCachedResponse(c *gin.Context) bool {
+	_, ok := common.GetContextKeyType[*pendingCachedResponse](c, constant.ContextKeyPendingCachedResponse)
+	return ok
+}
+
+// ReplayPendingCachedResponse 返回已命中的缓存结果，命中不计费，仅记录用量日志；没有命中的缓存时返回 false
+func ReplayPendingCachedResponse(c *gin.Context) bool {
+	pending, ok := common.GetContextKeyType[*pendingCachedResponse](c, constant.ContextKeyPendingCachedResponse)
+	if !ok || pending == nil {
+		return false
+	}
+	common.SetContextKey(c, constant.ContextKeyResponseCache, pending.status)
+	c.Header(ResponseCacheHeader, pending.status)
+	c.Data(http.StatusOK, pending.record.ContentType, pending.record.Body)
+	c.Abort()
+
+	modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+	if modelName == "" {
+		modelName = pending.record.ModelName
+	}
+	other := map[string]interface{}{
+		"response_cache": pending.status,
+		"request_path":   c.Request.URL.Path,
+	}
+	for k, v := range pending.extra {
+		other[k] = v
+	}
+	model.RecordConsumeLog(c, c.GetInt("id"), model.RecordConsumeLogParams{
+		PromptTokens:     pending.record.PromptTokens,
+		CompletionTokens: pending.record.CompletionTokens,
+		ModelName:        modelName,
+		TokenName:        c.GetString("token_name"),
+		Quota:            0,
+		Content:          pending.content,
+		TokenId:          c.GetInt("token_id"),
+		Group:            common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Other:            other,
+	})
+	return true
+}
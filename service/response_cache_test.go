@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	path := "/v1/chat/completions"
+	key, ok := ResponseCacheKey(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}],"user":"a"}`))
+	require.True(t, ok)
+
+	// 字段顺序与 user 等无关字段不影响缓存键
+	same, ok := ResponseCacheKey(path, "default", 1, []byte(`{"messages":[{"role":"user","content":"hi"}],"user":"b","temperature":0.0,"model":"gpt-4o","stream":false}`))
+	require.True(t, ok)
+	require.Equal(t, key, same)
+
+	// 不同用户、分组或请求内容使用不同的键
+	other, _ := ResponseCacheKey(path, "default", 2, []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, key, other)
+	other, _ = ResponseCacheKey(path, "vip", 1, []byte(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, key, other)
+
+	// 非确定性请求或流式请求不缓存
+	_, ok = ResponseCacheKey(path, "default", 1, []byte(`{"model":"gpt-4o","messages":[]}`))
+	require.False(t, ok)
+	_, ok = ResponseCacheKey(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[]}`))
+	require.False(t, ok)
+	_, ok = ResponseCacheKey(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0,"stream":true,"messages":[]}`))
+	require.False(t, ok)
+}
+
+func TestSaveAndGetCachedResponse(t *testing.T) {
+	body := []byte(`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+	require.NoError(t, SaveCachedResponse("test-key", "gpt-4o", "application/json", body))
+
+	record, found, err := GetCachedResponse("test-key")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, body, record.Body)
+	require.Equal(t, 12, record.PromptTokens)
+	require.Equal(t, 3, record.CompletionTokens)
+
+	_, found, err = GetCachedResponse("missing-key")
+	require.NoError(t, err)
+	require.False(t, found)
+}
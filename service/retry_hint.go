@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求被拒绝时命中的限制维度，通过 X-RateLimit-Scope 响应头与错误体的 limit_scope 字段返回，供客户端按维度退避
+const (
+	RetryScopeUserRequests      = "user_requests"       // 用户成功请求数限制
+	RetryScopeUserTotalRequests = "user_total_requests" // 用户请求总数限制（含失败请求）
+	RetryScopeChannelSaturation = "channel_saturation"  // 分组内暂无可用渠道
+	RetryScopeQuota             = "quota"               // 额度不足，重试无法恢复
+	RetryScopeUpstream          = "upstream"            // 上游限流或不可用
+	RetryScopeSystemOverload    = "system_overload"     // 网关自身负载过高
+	RetryScopeTokenRequests     = "token_requests"      // 令牌每分钟请求数限制
+	RetryScopeTokenTokens       = "token_tokens"        // 令牌每分钟 token 数限制
+	RetryScopeTokenDailyQuota   = "token_daily_quota"   // 令牌每日消费额度限制
+)
+
+// 未显式指定等待时间时各维度的默认 Retry-After（秒）
+const (
+	defaultUpstreamRetryAfterSeconds          = 1
+	defaultChannelSaturationRetryAfterSeconds = 5
+	defaultSystemOverloadRetryAfterSeconds    = 10
+)
+
+// RetryHint 描述拒绝请求的限制维度与建议的重试等待时间
+type RetryHint struct {
+	Scope             string `json:"limit_scope"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+	// 触发的限制值及其时间窗口（秒），仅请求数限制时存在
+	Limit         int   `json:"limit,omitempty"`
+	WindowSeconds int64 `json:"window_seconds,omitempty"`
+}
+
+// SetRetryHint 记录当前请求被拒绝的原因，在写出错误响应时转为响应头与错误字段
+func SetRetryHint(c *gin.Context, hint RetryHint) {
+	if hint.RetryAfterSeconds <= 0 {
+		switch hint.Scope {
+		case RetryScopeUpstream:
+			hint.RetryAfterSeconds = defaultUpstreamRetryAfterSeconds
+		case RetryScopeChannelSaturation:
+			hint.RetryAfterSeconds = defaultChannelSaturationRetryAfterSeconds
+		case RetryScopeSystemOverload:
+			hint.RetryAfterSeconds = defaultSystemOverloadRetryAfterSeconds
+		}
+	}
+	common.SetContextKey(c, constant.ContextKeyRetryHint, hint)
+}
+
+// resolveRetryHint 优先使用显式记录的提示；未记录时，额度错误归为 quota，其余 429/503 视为上游限流
+func resolveRetryHint(c *gin.Context, statusCode int, code any) *RetryHint {
+	if v, ok := common.GetContextKey(c, constant.ContextKeyRetryHint); ok {
+		if hint, ok := v.(RetryHint); ok {
+			return &hint
+		}
+	}
+	if code != nil {
+		switch types.ErrorCode(fmt.Sprintf("%v", code)) {
+		case types.ErrorCodeInsufficientUserQuota, types.ErrorCodePreConsumeTokenQuotaFailed:
+			return &RetryHint{Scope: RetryScopeQuota}
+		}
+	}
+	if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+		return &RetryHint{Scope: RetryScopeUpstream, RetryAfterSeconds: defaultUpstreamRetryAfterSeconds}
+	}
+	return nil
+}
+
+// WriteRetryHintHeaders 为错误响应设置 Retry-After 与 X-RateLimit-* 响应头，返回生效的提示；无需提示时返回 nil
+func WriteRetryHintHeaders(c *gin.Context, statusCode int, code any) *RetryHint {
+	hint := resolveRetryHint(c, statusCode, code)
+	if hint == nil {
+		return nil
+	}
+	c.Header("X-RateLimit-Scope", hint.Scope)
+	if hint.RetryAfterSeconds > 0 {
+		c.Header("Retry-After", strconv.FormatInt(hint.RetryAfterSeconds, 10))
+	}
+	if hint.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(hint.Limit))
+	}
+	if hint.WindowSeconds > 0 {
+		c.Header("X-RateLimit-Window", strconv.FormatInt(hint.WindowSeconds, 10))
+	}
+	return hint
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestWriteOpenAIErrorIncludesExplicitRetryHint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	SetRetryHint(c, RetryHint{Scope: RetryScopeUserRequests, RetryAfterSeconds: 42, Limit: 60, WindowSeconds: 60})
+	WriteOpenAIError(c, http.StatusTooManyRequests, types.OpenAIError{Message: "slow down", Type: "new_api_error"})
+
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "42", recorder.Header().Get("Retry-After"))
+	require.Equal(t, RetryScopeUserRequests, recorder.Header().Get("X-RateLimit-Scope"))
+	require.Equal(t, "60", recorder.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "60", recorder.Header().Get("X-RateLimit-Window"))
+	body := recorder.Body.Bytes()
+	require.Equal(t, "slow down", gjson.GetBytes(body, "error.message").String())
+	require.Equal(t, RetryScopeUserRequests, gjson.GetBytes(body, "error.limit_scope").String())
+	require.Equal(t, int64(42), gjson.GetBytes(body, "error.retry_after_seconds").Int())
+}
+
+func TestWriteOpenAIErrorInfersRetryHint(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		code           any
+		wantScope      string
+		wantRetryAfter string
+	}{
+		{name: "quota", status: http.StatusForbidden, code: types.ErrorCodeInsufficientUserQuota, wantScope: RetryScopeQuota},
+		{name: "upstream rate limit", status: http.StatusTooManyRequests, code: "rate_limit", wantScope: RetryScopeUpstream, wantRetryAfter: "1"},
+		{name: "bad request", status: http.StatusBadRequest, code: "invalid_value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := writeTestOpenAIError(t, tt.status, types.OpenAIError{Message: "err", Type: "new_api_error", Code: tt.code})
+			require.Equal(t, tt.wantScope, recorder.Header().Get("X-RateLimit-Scope"))
+			require.Equal(t, tt.wantRetryAfter, recorder.Header().Get("Retry-After"))
+			require.Equal(t, tt.wantScope != "", gjson.GetBytes(recorder.Body.Bytes(), "error.limit_scope").Exists())
+		})
+	}
+}
+
+func TestSetRetryHintDefaultsRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	SetRetryHint(c, RetryHint{Scope: RetryScopeChannelSaturation})
+	hint := resolveRetryHint(c, http.StatusServiceUnavailable, nil)
+	require.NotNil(t, hint)
+	require.Equal(t, int64(defaultChannelSaturationRetryAfterSeconds), hint.RetryAfterSeconds)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 路由配置导出格式
+const (
+	RouterConfigFormatLiteLLM = "litellm" // LiteLLM router 的 model_list
+	RouterConfigFormatGeneric = "generic" // 模型 -> 端点 -> 密钥的通用描述
+)
+
+type liteLLMParams struct {
+	Model   string `yaml:"model"`
+	ApiBase string `yaml:"api_base"`
+	ApiKey  string `yaml:"api_key"`
+}
+
+type liteLLMModel struct {
+	ModelName     string            `yaml:"model_name"`
+	LiteLLMParams liteLLMParams     `yaml:"litellm_params"`
+	ModelInfo     map[string]string `yaml:"model_info,omitempty"`
+}
+
+type liteLLMConfig struct {
+	ModelList []liteLLMModel `yaml:"model_list"`
+}
+
+type genericEndpoint struct {
+	Type   string `yaml:"type"`
+	Method string `yaml:"method"`
+	Url    string `yaml:"url"`
+}
+
+type genericModel struct {
+	Name      string            `yaml:"name"`
+	OwnedBy   string            `yaml:"owned_by,omitempty"`
+	Endpoints []genericEndpoint `yaml:"endpoints"`
+}
+
+type genericConfig struct {
+	BaseUrl string         `yaml:"base_url"`
+	ApiKey  string         `yaml:"api_key"`
+	Models  []genericModel `yaml:"models"`
+}
+
+// BuildRouterConfig 将令牌可用的模型导出为客户端路由配置（YAML），所有模型都指向网关地址并使用同一个令牌
+func BuildRouterConfig(format string, baseUrl string, apiKey string, models []dto.OpenAIModels, endpointMap map[string]common.EndpointInfo) ([]byte, error) {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	if format == RouterConfigFormatGeneric {
+		config := genericConfig{BaseUrl: baseUrl, ApiKey: apiKey, Models: make([]genericModel, 0, len(models))}
+		for _, m := range models {
+			config.Models = append(config.Models, genericModel{
+				Name:      m.Id,
+				OwnedBy:   m.OwnedBy,
+				Endpoints: buildGenericEndpoints(baseUrl, m, endpointMap),
+			})
+		}
+		return yaml.Marshal(config)
+	}
+
+	config := liteLLMConfig{ModelList: make([]liteLLMModel, 0, len(models))}
+	for _, m := range models {
+		config.ModelList = append(config.ModelList, buildLiteLLMModel(baseUrl, apiKey, m))
+	}
+	return yaml.Marshal(config)
+}
+
+// buildLiteLLMModel 优先使用 OpenAI 兼容接口，仅支持 Claude 原生接口的模型使用 anthropic provider
+func buildLiteLLMModel(baseUrl string, apiKey string, m dto.OpenAIModels) liteLLMModel {
+	endpoints := m.SupportedEndpointTypes
+	entry := liteLLMModel{
+		ModelName: m.Id,
+		LiteLLMParams: liteLLMParams{
+			Model:   "openai/" + m.Id,
+			ApiBase: baseUrl + "/v1",
+			ApiKey:  apiKey,
+		},
+	}
+	hasOpenAI := len(endpoints) == 0 || slices.Contains(endpoints, constant.EndpointTypeOpenAI)
+	switch {
+	case !hasOpenAI && slices.Contains(endpoints, constant.EndpointTypeAnthropic):
+		entry.LiteLLMParams.Model = "anthropic/" + m.Id
+		entry.LiteLLMParams.ApiBase = baseUrl
+	case !hasOpenAI && slices.Contains(endpoints, constant.EndpointTypeOpenAIResponse):
+		entry.ModelInfo = map[string]string{"mode": "responses"}
+	case !hasOpenAI && slices.Contains(endpoints, constant.EndpointTypeEmbeddings):
+		entry.ModelInfo = map[string]string{"mode": "embedding"}
+	case !hasOpenAI && slices.Contains(endpoints, constant.EndpointTypeImageGeneration):
+		entry.ModelInfo = map[string]string{"mode": "image_generation"}
+	case !hasOpenAI && slices.Contains(endpoints, constant.EndpointTypeJinaRerank):
+		entry.ModelInfo = map[string]string{"mode": "rerank"}
+	}
+	return entry
+}
+
+func buildGenericEndpoints(baseUrl string, m dto.OpenAIModels, endpointMap map[string]common.EndpointInfo) []genericEndpoint {
+	types := m.SupportedEndpointTypes
+	if len(types) == 0 {
+		types = []constant.EndpointType{constant.EndpointTypeOpenAI}
+	}
+	endpoints := make([]genericEndpoint, 0, len(types))
+	for _, et := range types {
+		info, ok := endpointMap[string(et)]
+		if !ok {
+			if info, ok = common.GetDefaultEndpointInfo(et); !ok {
+				continue
+			}
+		}
+		method := info.Method
+		if method == "" {
+			method = "POST"
+		}
+		endpoints = append(endpoints, genericEndpoint{
+			Type:   string(et),
+			Method: method,
+			Url:    baseUrl + strings.ReplaceAll(info.Path, "{model}", m.Id),
+		})
+	}
+	return endpoints
+}
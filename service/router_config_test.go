@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var routerConfigTestModels = []dto.OpenAIModels{
+	{Id: "gpt-4o", OwnedBy: "openai", SupportedEndpointTypes: []constant.EndpointType{constant.EndpointTypeOpenAI}},
+	{Id: "claude-sonnet-4", SupportedEndpointTypes: []constant.EndpointType{constant.EndpointTypeAnthropic}},
+	{Id: "text-embedding-3-small", SupportedEndpointTypes: []constant.EndpointType{constant.EndpointTypeEmbeddings}},
+	{Id: "gemini-2.5-pro", SupportedEndpointTypes: []constant.EndpointType{constant.EndpointTypeGemini, constant.EndpointTypeOpenAI}},
+}
+
+func TestBuildRouterConfigLiteLLM(t *testing.T) {
+	data, err := BuildRouterConfig(RouterConfigFormatLiteLLM, "https://gw.example.com/", "sk-test", routerConfigTestModels, nil)
+	require.NoError(t, err)
+
+	var config liteLLMConfig
+	require.NoError(t, yaml.Unmarshal(data, &config))
+	require.Len(t, config.ModelList, 4)
+
+	require.Equal(t, "gpt-4o", config.ModelList[0].ModelName)
+	require.Equal(t, liteLLMParams{Model: "openai/gpt-4o", ApiBase: "https://gw.example.com/v1", ApiKey: "sk-test"}, config.ModelList[0].LiteLLMParams)
+	require.Equal(t, liteLLMParams{Model: "anthropic/claude-sonnet-4", ApiBase: "https://gw.example.com", ApiKey: "sk-test"}, config.ModelList[1].LiteLLMParams)
+	require.Equal(t, "embedding", config.ModelList[2].ModelInfo["mode"])
+	require.Equal(t, "openai/gemini-2.5-pro", config.ModelList[3].LiteLLMParams.Model)
+}
+
+func TestBuildRouterConfigGeneric(t *testing.T) {
+	endpointMap := map[string]common.EndpointInfo{
+		string(constant.EndpointTypeOpenAI): {Path: "/v1/chat/completions", Method: "POST"},
+	}
+	data, err := BuildRouterConfig(RouterConfigFormatGeneric, "https://gw.example.com", "sk-test", routerConfigTestModels, endpointMap)
+	require.NoError(t, err)
+
+	var config genericConfig
+	require.NoError(t, yaml.Unmarshal(data, &config))
+	require.Equal(t, "https://gw.example.com", config.BaseUrl)
+	require.Equal(t, "sk-test", config.ApiKey)
+	require.Len(t, config.Models, 4)
+	require.Equal(t, []genericEndpoint{{Type: "openai", Method: "POST", Url: "https://gw.example.com/v1/chat/completions"}}, config.Models[0].Endpoints)
+	require.Equal(t, "https://gw.example.com/v1beta/models/gemini-2.5-pro:generateContent", config.Models[3].Endpoints[0].Url)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/moderation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内联文件超过该大小时不再解码扫描
+const secretScanMaxFileBytes = 10 << 20
+
+type secretDetector struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretDetectors = []secretDetector{
+	{name: "private_key", pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----(?:[\s\S]*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY-----)?`)},
+	{name: "aws_access_key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)},
+	{name: "aws_secret_key", pattern: regexp.MustCompile(`(?i)aws.{0,20}(?:secret|sk).{0,20}[=:]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{name: "github_token", pattern: regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,255}\b|\bgithub_pat_[A-Za-z0-9_]{22,255}\b`)},
+	{name: "gitlab_token", pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{name: "slack_token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "google_api_key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{name: "stripe_key", pattern: regexp.MustCompile(`\b(?:sk|rk)_(?:live|test)_[0-9A-Za-z]{24,}\b`)},
+	{name: "llm_api_key", pattern: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+var highEntropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_\-]{20,}={0,2}`)
+
+var (
+	secretOffensesMu sync.Mutex
+	secretOffenses   = make(map[int][]int64) // userId -> 命中时间戳
+)
+
+// secretScanResult 扫描过程中累计的命中类型
+type secretScanResult struct {
+	types map[string]bool
+}
+
+func (r *secretScanResult) add(name string) {
+	if r.types == nil {
+		r.types = make(map[string]bool)
+	}
+	r.types[name] = true
+}
+
+func (r *secretScanResult) names() []string {
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RedactSecrets 将文本中的密钥替换为 [REDACTED_SECRET:类型]，返回替换后的文本与命中类型
+func RedactSecrets(text string) (string, []string) {
+	result := &secretScanResult{}
+	text = redactSecrets(text, result)
+	return text, result.names()
+}
+
+func redactSecrets(text string, result *secretScanResult) string {
+	for _, detector := range secretDetectors {
+		text = detector.pattern.ReplaceAllStringFunc(text, func(string) string {
+			result.add(detector.name)
+			return "[REDACTED_SECRET:" + detector.name + "]"
+		})
+	}
+	setting := moderation_setting.GetSecretScanSetting()
+	if setting.DetectHighEntropy {
+		text = highEntropyCandidate.ReplaceAllStringFunc(text, func(match string) string {
+			if len(match) < setting.EntropyMinLength || !isLikelySecret(match, setting.EntropyThreshold) {
+				return match
+			}
+			result.add("high_entropy")
+			return "[REDACTED_SECRET:high_entropy]"
+		})
+	}
+	return text
+}
+
+// isLikelySecret 同时包含字母与数字且香农熵达到阈值的字符串视为疑似密钥
+func isLikelySecret(s string, threshold float64) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.IndexFunc(s, func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}) < 0 {
+		return false
+	}
+	return shannonEntropy(s) >= threshold
+}
+
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	length := float64(utf8.RuneCountInString(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redactInlineFile 解码 base64 内联的文本类文件（data URL 或纯 base64），扫描后重新编码；
+// 非文本内容原样返回
+func redactInlineFile(value string, result *secretScanResult) string {
+	prefix, data := "", value
+	if strings.HasPrefix(value, "data:") {
+		idx := strings.Index(value, ";base64,")
+		if idx < 0 {
+			return value
+		}
+		prefix, data = value[:idx+len(";base64,")], value[idx+len(";base64,"):]
+	}
+	if len(data) == 0 || len(data) > secretScanMaxFileBytes*4/3 {
+		return value
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || !utf8.Valid(raw) || strings.ContainsRune(string(raw), 0) {
+		return value
+	}
+	redacted := redactSecrets(string(raw), result)
+	if redacted == string(raw) {
+		return value
+	}
+	return prefix + base64.StdEncoding.EncodeToString([]byte(redacted))
+}
+
+// ScanRequestSecrets 扫描请求中的提示词与内联文件，按分组动作拒绝或脱敏，需在解析请求之前调用
+func ScanRequestSecrets(c *gin.Context) *types.NewAPIError {
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	action := moderation_setting.GetSecretScanAction(group)
+	if action == "" || !strings.Contains(c.Request.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+
+	result := &secretScanResult{}
+	redacted, _, err := rewriteJSONStrings(body, promptTextKeys, func(text string) string {
+		return redactSecrets(text, result)
+	})
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+	}
+	if moderation_setting.GetSecretScanSetting().ScanFiles {
+		redacted, _, err = rewriteJSONStrings(redacted, inlineFileKeys, func(value string) string {
+			return redactInlineFile(value, result)
+		})
+		if err != nil {
+			return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+		}
+	}
+	if len(result.types) == 0 {
+		return nil
+	}
+
+	names := result.names()
+	common.SetContextKey(c, constant.ContextKeySecretLeakTypes, names)
+	logger.LogWarn(c, fmt.Sprintf("secrets detected in request (%s), action: %s", strings.Join(names, ","), action))
+	recordSecretOffense(c, names)
+
+	switch action {
+	case moderation_setting.SecretActionBlock:
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("request rejected: credentials detected in prompt (%s)", strings.Join(names, ",")),
+			types.ErrorCodeSecretLeakDetected, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	case moderation_setting.SecretActionRedact:
+		if err = common.ReplaceBodyStorage(c, redacted); err != nil {
+			return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
+		}
+	}
+	return nil
+}
+
+// inlineFileKeys 以 base64 内联文件内容的字段：OpenAI file_data、Claude/Gemini 的 source.data / inline_data.data
+var inlineFileKeys = map[string]bool{
+	"file_data": true,
+	"data":      true,
+}
+
+// GetSecretLeakTypes 返回本次请求检测到的密钥类型，用于写入消费日志
+func GetSecretLeakTypes(c *gin.Context) []string {
+	names, _ := common.GetContextKeyType[[]string](c, constant.ContextKeySecretLeakTypes)
+	return names
+}
+
+// recordSecretOffense 记录用户的命中次数，窗口内达到阈值时发送告警事件（进程内统计）
+func recordSecretOffense(c *gin.Context, names []string) {
+	setting := moderation_setting.GetSecretScanSetting()
+	userId := common.GetContextKeyInt(c, constant.ContextKeyUserId)
+	if setting.AlertThreshold <= 0 || userId == 0 {
+		return
+	}
+	now := time.Now().Unix()
+	windowStart := now - int64(setting.AlertWindowMinutes)*60
+
+	secretOffensesMu.Lock()
+	offenses := secretOffenses[userId][:0]
+	for _, ts := range secretOffenses[userId] {
+		if ts >= windowStart {
+			offenses = append(offenses, ts)
+		}
+	}
+	offenses = append(offenses, now)
+	triggered := len(offenses) >= setting.AlertThreshold
+	if triggered {
+		// 告警后重新计数，避免每次命中都重复告警
+		delete(secretOffenses, userId)
+	} else {
+		secretOffenses[userId] = offenses
+	}
+	secretOffensesMu.Unlock()
+
+	if !triggered {
+		return
+	}
+	logger.LogWarn(c, fmt.Sprintf("user %d repeatedly sent credentials to upstream providers (%d times in %d minutes)", userId, len(offenses), setting.AlertWindowMinutes))
+	EmitEvent(EventSecretLeakRepeated, map[string]any{
+		"user_id":        userId,
+		"username":       common.GetContextKeyString(c, constant.ContextKeyUserName),
+		"token_id":       common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		"count":          len(offenses),
+		"window_minutes": setting.AlertWindowMinutes,
+		"secret_types":   names,
+	})
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const (
+	ResponseCacheSemanticHit  = "semantic_hit"
+	ResponseCacheSemanticMiss = "semantic_miss"
+
+	defaultSemanticCacheTimeout = 10 * time.Second
+)
+
+// semanticCachePromptFields 承载提示词的字段，参与向量计算，其余字段需完全一致才视为同类请求
+var semanticCachePromptFields = []string{"messages", "prompt", "system"}
+
+type semanticCacheEntry struct {
+	vector    []float32
+	norm      float64
+	record    ResponseCacheRecord
+	expiresAt int64
+}
+
+// semanticCacheBackend 语义缓存的向量存储，按作用域（令牌、分组与除提示词外的请求参数）隔离
+type semanticCacheBackend interface {
+	lookup(scope string, vector []float32, threshold float64, now int64) (*ResponseCacheRecord, float64, bool)
+	save(scope string, entry semanticCacheEntry, maxEntries int, now int64)
+}
+
+// semanticCacheStore 进程内的向量存储，查找时在作用域内暴力计算余弦相似度。
+// 缓存仅在当前进程内有效，只作为开发与单节点测试使用的后备实现，生产环境使用 Redis 向量检索
+type semanticCacheStore struct {
+	mu        sync.Mutex
+	buckets   map[string][]semanticCacheEntry
+	lastSweep int64
+}
+
+// semanticCacheSweepInterval 清理所有作用域中过期条目的间隔（秒），避免不再访问的作用域长期占用内存
+const semanticCacheSweepInterval = 600
+
+var (
+	memorySemanticCache = &semanticCacheStore{buckets: make(map[string][]semanticCacheEntry)}
+	redisSemanticCache  = &redisSemanticCacheStore{}
+)
+
+// getSemanticCacheBackend 返回配置的向量存储，选择 Redis 但未启用 Redis 时返回 nil
+func getSemanticCacheBackend() semanticCacheBackend {
+	switch operation_setting.GetSemanticCacheSetting().Backend {
+	case operation_setting.SemanticCacheBackendMemory:
+		return memorySemanticCache
+	case "", operation_setting.SemanticCacheBackendRedis:
+		if common.RedisEnabled && common.RDB != nil {
+			return redisSemanticCache
+		}
+	}
+	return nil
+}
+
+// SemanticCacheRequest 一次可走语义缓存的请求
+type SemanticCacheRequest struct {
+	Scope  string
+	Prompt string
+}
+
+// ShouldUseSemanticCache 判断语义缓存是否启用且请求路径支持，threshold 为令牌配置的相似度阈值
+func ShouldUseSemanticCache(path string, threshold float64) bool {
+	setting := operation_setting.GetSemanticCacheSetting()
+	if !setting.Enabled || setting.EmbeddingUrl == "" || threshold <= 0 || getSemanticCacheBackend() == nil {
+		return false
+	}
+	return slices.Contains(responseCacheablePaths, path)
+}
+
+// BuildSemanticCacheRequest 提取请求中的提示词文本并计算作用域；流式请求、含图片等非文本内容的请求以及提示词过长的请求不走语义缓存
+func BuildSemanticCacheRequest(path string, group string, tokenId int, body []byte) (*SemanticCacheRequest, bool) {
+	var request map[string]any
+	if err := common.Unmarshal(body, &request); err != nil {
+		return nil, false
+	}
+	if stream, _ := request["stream"].(bool); stream {
+		return nil, false
+	}
+	prompt, ok := extractSemanticPrompt(request)
+	if !ok || prompt == "" {
+		return nil, false
+	}
+	if maxChars := operation_setting.GetSemanticCacheSetting().MaxPromptChars; maxChars > 0 && len([]rune(prompt)) > maxChars {
+		return nil, false
+	}
+	for _, field := range semanticCachePromptFields {
+		delete(request, field)
+	}
+	for _, field := range responseCacheIgnoredFields {
+		delete(request, field)
+	}
+	params, err := common.Marshal(request)
+	if err != nil {
+		return nil, false
+	}
+	h := sha256.New()
+	h.Write([]byte(fmt.Sprintf("%s\n%s\n%d\n", path, group, tokenId)))
+	h.Write(params)
+	return &SemanticCacheRequest{Scope: hex.EncodeToString(h.Sum(nil)), Prompt: prompt}, true
+}
+
+// extractSemanticPrompt 按“角色: 内容”逐行拼接 system、messages 与 prompt 中的文本
+func extractSemanticPrompt(request map[string]any) (string, bool) {
+	var sb strings.Builder
+	if system, ok := request["system"]; ok {
+		text, ok := semanticContentText(system)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString("system: " + text + "\n")
+	}
+	if messages, ok := request["messages"].([]any); ok {
+		for _, item := range messages {
+			message, ok := item.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			text, ok := semanticContentText(message["content"])
+			if !ok {
+				return "", false
+			}
+			role, _ := message["role"].(string)
+			sb.WriteString(role + ": " + text + "\n")
+		}
+	}
+	if prompt, ok := request["prompt"]; ok {
+		text, ok := semanticContentText(prompt)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(text + "\n")
+	}
+	return strings.TrimSpace(sb.String()), true
+}
+
+// semanticContentText 提取字符串或文本分段数组中的文本，出现非文本分段时返回 false
+func semanticContentText(content any) (string, bool) {
+	switch v := content.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			switch part := item.(type) {
+			case string:
+				parts = append(parts, part)
+			case map[string]any:
+				partType, _ := part["type"].(string)
+				text, isText := part["text"].(string)
+				if (partType != "" && partType != "text") || !isText {
+					return "", false
+				}
+				parts = append(parts, text)
+			default:
+				return "", false
+			}
+		}
+		return strings.Join(parts, "\n"), true
+	default:
+		return "", false
+	}
+}
+
+// EmbedSemanticPrompt 调用配置的 OpenAI 兼容向量接口计算提示词向量
+func EmbedSemanticPrompt(ctx context.Context, prompt string) ([]float32, error) {
+	setting := operation_setting.GetSemanticCacheSetting()
+	timeout := defaultSemanticCacheTimeout
+	if setting.TimeoutSeconds > 0 {
+		timeout = time.Duration(setting.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := common.Marshal(map[string]any{
+		"model": setting.EmbeddingModel,
+		"input": prompt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.EmbeddingUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.EmbeddingApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+setting.EmbeddingApiKey)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("embedding request failed with status code %d: %s", resp.StatusCode, string(respBody))
+	}
+	var embeddingResponse struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := common.Unmarshal(respBody, &embeddingResponse); err != nil {
+		return nil, err
+	}
+	if len(embeddingResponse.Data) == 0 || len(embeddingResponse.Data[0].Embedding) == 0 {
+		return nil, errors.New("embedding response contains no vector")
+	}
+	return embeddingResponse.Data[0].Embedding, nil
+}
+
+func vectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a []float32, aNorm float64, b []float32, bNorm float64) float64 {
+	if len(a) != len(b) || aNorm == 0 || bNorm == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (aNorm * bNorm)
+}
+
+// LookupSemanticCache 返回作用域内与向量最相似且相似度不低于阈值的缓存响应
+func LookupSemanticCache(scope string, vector []float32, threshold float64) (*ResponseCacheRecord, float64, bool) {
+	backend := getSemanticCacheBackend()
+	if backend == nil {
+		return nil, 0, false
+	}
+	return backend.lookup(scope, vector, threshold, common.GetTimestamp())
+}
+
+// SaveSemanticCache 保存成功响应及其提示词向量，作用域内超出条目上限时淘汰最早的条目
+func SaveSemanticCache(scope string, vector []float32, modelName string, contentType string, body []byte) {
+	record, ok := newResponseCacheRecord(modelName, contentType, body)
+	if !ok {
+		return
+	}
+	backend := getSemanticCacheBackend()
+	if backend == nil {
+		return
+	}
+	setting := operation_setting.GetSemanticCacheSetting()
+	ttl := setting.TTLSeconds
+	if ttl <= 0 {
+		ttl = 86400
+	}
+	entry := semanticCacheEntry{
+		vector:    vector,
+		norm:      vectorNorm(vector),
+		record:    *record,
+		expiresAt: record.CreatedAt + int64(ttl),
+	}
+	backend.save(scope, entry, setting.MaxEntriesPerScope, record.CreatedAt)
+}
+
+func (s *semanticCacheStore) lookup(scope string, vector []float32, threshold float64, now int64) (*ResponseCacheRecord, float64, bool) {
+	norm := vectorNorm(vector)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best *semanticCacheEntry
+	bestScore := 0.0
+	for i := range s.buckets[scope] {
+		entry := &s.buckets[scope][i]
+		if entry.expiresAt <= now {
+			continue
+		}
+		if score := cosineSimilarity(vector, norm, entry.vector, entry.norm); score >= threshold && score > bestScore {
+			best = entry
+			bestScore = score
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	record := best.record
+	return &record, bestScore, true
+}
+
+func (s *semanticCacheStore) save(scope string, entry semanticCacheEntry, maxEntries int, now int64) {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now-s.lastSweep >= semanticCacheSweepInterval {
+		s.sweep(now)
+	}
+	entries := slices.DeleteFunc(s.buckets[scope], func(e semanticCacheEntry) bool {
+		return e.expiresAt <= now
+	})
+	entries = append(entries, entry)
+	if len(entries) > maxEntries {
+		entries = slices.Clone(entries[len(entries)-maxEntries:])
+	}
+	s.buckets[scope] = entries
+}
+
+func (s *semanticCacheStore) sweep(now int64) {
+	s.lastSweep = now
+	for scope, entries := range s.buckets {
+		entries = slices.DeleteFunc(entries, func(e semanticCacheEntry) bool {
+			return e.expiresAt <= now
+		})
+		if len(entries) == 0 {
+			delete(s.buckets, scope)
+		} else {
+			s.buckets[scope] = entries
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis 语义缓存：每条缓存保存为一个带过期时间的 Hash，由 RediSearch 按向量维度建立 HNSW 索引，
+// 查找时在作用域内做 KNN 检索。每个作用域另用有序集合记录条目，用于按条目上限淘汰最早的条目。需要 Redis Stack
+const (
+	semanticCacheRedisPrefix  = "new-api:semantic_cache:v1:"
+	semanticCacheRedisTimeout = 2 * time.Second
+)
+
+type redisSemanticCacheStore struct {
+	// indexes 已确认存在的索引维度，避免每次请求都执行 FT.CREATE
+	indexes sync.Map
+}
+
+func semanticCacheIndexName(dim int) string {
+	return fmt.Sprintf("%sidx:%d", semanticCacheRedisPrefix, dim)
+}
+
+func semanticCacheEntryPrefix(dim int) string {
+	return fmt.Sprintf("%sentry:%d:", semanticCacheRedisPrefix, dim)
+}
+
+func semanticCacheScopeKey(scope string) string {
+	return semanticCacheRedisPrefix + "scope:" + scope
+}
+
+// encodeSemanticVector 按 RediSearch FLOAT32 向量字段的要求编码为小端字节序
+func encodeSemanticVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func (s *redisSemanticCacheStore) ensureIndex(ctx context.Context, dim int) error {
+	if _, ok := s.indexes.Load(dim); ok {
+		return nil
+	}
+	err := common.RDB.Do(ctx, "FT.CREATE", semanticCacheIndexName(dim),
+		"ON", "HASH", "PREFIX", "1", semanticCacheEntryPrefix(dim),
+		"SCHEMA", "scope", "TAG",
+		"vector", "VECTOR", "HNSW", "6", "TYPE", "FLOAT32", "DIM", dim, "DISTANCE_METRIC", "COSINE",
+	).Err()
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "index already exists") {
+		return err
+	}
+	s.indexes.Store(dim, struct{}{})
+	return nil
+}
+
+func (s *redisSemanticCacheStore) lookup(scope string, vector []float32, threshold float64, now int64) (*ResponseCacheRecord, float64, bool) {
+	if len(vector) == 0 {
+		return nil, 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), semanticCacheRedisTimeout)
+	defer cancel()
+	if err := s.ensureIndex(ctx, len(vector)); err != nil {
+		common.SysError("failed to create semantic cache index: " + err.Error())
+		return nil, 0, false
+	}
+	// 作用域为十六进制摘要，可直接作为 TAG 查询值
+	reply, err := common.RDB.Do(ctx, "FT.SEARCH", semanticCacheIndexName(len(vector)),
+		fmt.Sprintf("(@scope:{%s})=>[KNN 1 @vector $vec AS distance]", scope),
+		"PARAMS", "2", "vec", encodeSemanticVector(vector),
+		"RETURN", "2", "distance", "record",
+		"SORTBY", "distance",
+		"DIALECT", "2",
+	).Result()
+	if err != nil {
+		common.SysError("failed to search semantic cache: " + err.Error())
+		return nil, 0, false
+	}
+	record, similarity, ok := parseSemanticSearchReply(reply)
+	if !ok || similarity < threshold {
+		return nil, 0, false
+	}
+	return record, similarity, true
+}
+
+// parseSemanticSearchReply 解析 FT.SEARCH 的返回：[总数, key, [字段, 值, ...], ...]，COSINE 距离换算为相似度
+func parseSemanticSearchReply(reply any) (*ResponseCacheRecord, float64, bool) {
+	items, ok := reply.([]any)
+	if !ok || len(items) < 3 {
+		return nil, 0, false
+	}
+	fields, ok := items[2].([]any)
+	if !ok {
+		return nil, 0, false
+	}
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		values[name] = value
+	}
+	distance, err := strconv.ParseFloat(values["distance"], 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	var record ResponseCacheRecord
+	if err := common.UnmarshalJsonStr(values["record"], &record); err != nil {
+		return nil, 0, false
+	}
+	return &record, 1 - distance, true
+}
+
+func (s *redisSemanticCacheStore) save(scope string, entry semanticCacheEntry, maxEntries int, now int64) {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	ttl := time.Duration(entry.expiresAt-now) * time.Second
+	if len(entry.vector) == 0 || ttl <= 0 {
+		return
+	}
+	recordJSON, err := common.Marshal(entry.record)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), semanticCacheRedisTimeout)
+	defer cancel()
+	if err := s.ensureIndex(ctx, len(entry.vector)); err != nil {
+		common.SysError("failed to create semantic cache index: " + err.Error())
+		return
+	}
+	key := semanticCacheEntryPrefix(len(entry.vector)) + common.GetUUID()
+	scopeKey := semanticCacheScopeKey(scope)
+	var countCmd *redis.IntCmd
+	_, err = common.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "scope", scope, "vector", encodeSemanticVector(entry.vector), "record", recordJSON)
+		pipe.Expire(ctx, key, ttl)
+		// 有序集合的 score 为条目的过期时间，先清除已过期的条目
+		pipe.ZRemRangeByScore(ctx, scopeKey, "-inf", strconv.FormatInt(now, 10))
+		pipe.ZAdd(ctx, scopeKey, &redis.Z{Score: float64(entry.expiresAt), Member: key})
+		pipe.Expire(ctx, scopeKey, ttl)
+		countCmd = pipe.ZCard(ctx, scopeKey)
+		return nil
+	})
+	if err != nil {
+		common.SysError("failed to save semantic cache: " + err.Error())
+		return
+	}
+	// 超出条目上限时淘汰最早的条目
+	if overflow := countCmd.Val() - int64(maxEntries); overflow > 0 {
+		evicted, err := common.RDB.ZPopMin(ctx, scopeKey, overflow).Result()
+		if err != nil {
+			common.SysError("failed to evict semantic cache: " + err.Error())
+			return
+		}
+		keys := make([]string, 0, len(evicted))
+		for _, z := range evicted {
+			if member, ok := z.Member.(string); ok {
+				keys = append(keys, member)
+			}
+		}
+		if len(keys) > 0 {
+			common.RDB.Del(ctx, keys...)
+		}
+	}
+}
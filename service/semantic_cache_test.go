@@ -0,0 +1,125 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSemanticCacheRequest(t *testing.T) {
+	path := "/v1/chat/completions"
+	request, ok := BuildSemanticCacheRequest(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"system","content":"faq bot"},{"role":"user","content":[{"type":"text","text":"how do I reset my password?"}]}]}`))
+	require.True(t, ok)
+	require.Equal(t, "system: faq bot\nuser: how do I reset my password?", request.Prompt)
+
+	// 提示词不同、其余参数相同的请求属于同一作用域
+	similar, ok := BuildSemanticCacheRequest(path, "default", 1, []byte(`{"temperature":0.7,"model":"gpt-4o","user":"u","messages":[{"role":"system","content":"faq bot"},{"role":"user","content":"how to reset password"}]}`))
+	require.True(t, ok)
+	require.Equal(t, request.Scope, similar.Scope)
+
+	// 模型、参数、令牌或分组不同时不共享作用域
+	other, _ := BuildSemanticCacheRequest(path, "default", 1, []byte(`{"model":"gpt-4o-mini","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, request.Scope, other.Scope)
+	other, _ = BuildSemanticCacheRequest(path, "default", 1, []byte(`{"model":"gpt-4o","temperature":0.2,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, request.Scope, other.Scope)
+	other, _ = BuildSemanticCacheRequest(path, "default", 2, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, request.Scope, other.Scope)
+	other, _ = BuildSemanticCacheRequest(path, "vip", 1, []byte(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEqual(t, request.Scope, other.Scope)
+
+	// 流式请求与含图片的请求不走语义缓存
+	_, ok = BuildSemanticCacheRequest(path, "default", 1, []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	require.False(t, ok)
+	_, ok = BuildSemanticCacheRequest(path, "default", 1, []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`))
+	require.False(t, ok)
+
+	// Claude 格式的 system 字段与 completions 的 prompt 字段
+	claude, ok := BuildSemanticCacheRequest("/v1/messages", "default", 1, []byte(`{"model":"claude","system":[{"type":"text","text":"be brief"}],"messages":[{"role":"user","content":"hi"}]}`))
+	require.True(t, ok)
+	require.Equal(t, "system: be brief\nuser: hi", claude.Prompt)
+	completion, ok := BuildSemanticCacheRequest("/v1/completions", "default", 1, []byte(`{"model":"gpt-3.5-turbo-instruct","prompt":"hello"}`))
+	require.True(t, ok)
+	require.Equal(t, "hello", completion.Prompt)
+}
+
+func TestSemanticCacheStore(t *testing.T) {
+	store := &semanticCacheStore{buckets: make(map[string][]semanticCacheEntry)}
+	newEntry := func(vector []float32, body string, expiresAt int64) semanticCacheEntry {
+		return semanticCacheEntry{
+			vector:    vector,
+			norm:      vectorNorm(vector),
+			record:    ResponseCacheRecord{Body: []byte(body)},
+			expiresAt: expiresAt,
+		}
+	}
+	store.save("scope", newEntry([]float32{1, 0, 0}, "a", 200), 10, 100)
+	store.save("scope", newEntry([]float32{0, 1, 0}, "b", 200), 10, 100)
+
+	record, similarity, found := store.lookup("scope", []float32{0.9, 0.1, 0}, 0.9, 100)
+	require.True(t, found)
+	require.Equal(t, "a", string(record.Body))
+	require.Greater(t, similarity, 0.99)
+
+	// 低于阈值、作用域不同或已过期时不命中
+	_, _, found = store.lookup("scope", []float32{1, 1, 0}, 0.9, 100)
+	require.False(t, found)
+	_, _, found = store.lookup("other", []float32{1, 0, 0}, 0.9, 100)
+	require.False(t, found)
+	_, _, found = store.lookup("scope", []float32{1, 0, 0}, 0.9, 200)
+	require.False(t, found)
+
+	// 超出条目上限时淘汰最早的条目
+	store.save("scope", newEntry([]float32{0, 0, 1}, "c", 300), 2, 100)
+	_, _, found = store.lookup("scope", []float32{1, 0, 0}, 0.9, 100)
+	require.False(t, found)
+	record, _, found = store.lookup("scope", []float32{0, 0, 1}, 0.9, 100)
+	require.True(t, found)
+	require.Equal(t, "c", string(record.Body))
+}
+
+func TestSemanticCacheBackendSelection(t *testing.T) {
+	setting := operation_setting.GetSemanticCacheSetting()
+	saved := *setting
+	redisEnabled := common.RedisEnabled
+	t.Cleanup(func() {
+		*setting = saved
+		common.RedisEnabled = redisEnabled
+	})
+	setting.Enabled = true
+	setting.EmbeddingUrl = "http://127.0.0.1/v1/embeddings"
+	common.RedisEnabled = false
+
+	// 默认使用 Redis 向量检索，未启用 Redis 时不回退到进程内存储
+	setting.Backend = operation_setting.SemanticCacheBackendRedis
+	require.Nil(t, getSemanticCacheBackend())
+	require.False(t, ShouldUseSemanticCache("/v1/chat/completions", 0.9))
+
+	setting.Backend = operation_setting.SemanticCacheBackendMemory
+	require.Equal(t, memorySemanticCache, getSemanticCacheBackend())
+	require.True(t, ShouldUseSemanticCache("/v1/chat/completions", 0.9))
+
+	setting.Backend = "pgvector"
+	require.Nil(t, getSemanticCacheBackend())
+}
+
+func TestParseSemanticSearchReply(t *testing.T) {
+	require.Equal(t, []byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0xc0}, encodeSemanticVector([]float32{1, -2}))
+
+	recordJSON, err := common.Marshal(ResponseCacheRecord{ModelName: "gpt-4o", Body: []byte("cached")})
+	require.NoError(t, err)
+	record, similarity, ok := parseSemanticSearchReply([]any{
+		int64(1),
+		"new-api:semantic_cache:v1:entry:3:abc",
+		[]any{"distance", "0.04", "record", string(recordJSON)},
+	})
+	require.True(t, ok)
+	require.InDelta(t, 0.96, similarity, 1e-9)
+	require.Equal(t, "gpt-4o", record.ModelName)
+	require.Equal(t, "cached", string(record.Body))
+
+	// 没有结果时不命中
+	_, _, ok = parseSemanticSearchReply([]any{int64(0)})
+	require.False(t, ok)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/billing/meterevent"
+)
+
+const (
+	stripeMeteringInterval  = time.Minute
+	stripeMeteringBatchSize = 1000
+)
+
+var (
+	stripeMeteringOnce    sync.Once
+	stripeMeteringRunning atomic.Bool
+)
+
+// sendStripeMeterEvent 上报一条 Meter 事件，identifier 相同的事件在 Stripe 侧去重，保证批次重试不会重复计费
+var sendStripeMeterEvent = func(eventName string, customerId string, value int64, identifier string) error {
+	stripe.Key = setting.StripeApiSecret
+	_, err := meterevent.New(&stripe.BillingMeterEventParams{
+		EventName:  stripe.String(eventName),
+		Identifier: stripe.String(identifier),
+		Payload: map[string]string{
+			"stripe_customer_id": customerId,
+			"value":              strconv.FormatInt(value, 10),
+		},
+	})
+	return err
+}
+
+// StartStripeMeteringTask 在主节点上定期上报按量计费用量
+func StartStripeMeteringTask() {
+	stripeMeteringOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			ticker := time.NewTicker(stripeMeteringInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				runStripeMeteringOnce()
+			}
+		})
+	})
+}
+
+func runStripeMeteringOnce() {
+	meteringSetting := operation_setting.GetStripeMeteringSetting()
+	if !meteringSetting.Enabled || setting.StripeApiSecret == "" || meteringSetting.EventName == "" {
+		return
+	}
+	if !stripeMeteringRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer stripeMeteringRunning.Store(false)
+	if err := reportStripeMeteredUsage(meteringSetting); err != nil {
+		common.SysError("failed to report stripe metered usage: " + err.Error())
+	}
+}
+
+// reportStripeMeteredUsage 上报一批消费日志。批次范围先持久化再上报，失败时下次以相同范围与事件标识重试
+func reportStripeMeteredUsage(meteringSetting *operation_setting.StripeMeteringSetting) error {
+	if meteringSetting.LogCursor == 0 {
+		// 首次启用时从当前位置开始计量，不上报历史消费
+		maxId, err := model.GetMaxLogId()
+		if err != nil {
+			return err
+		}
+		if maxId == 0 {
+			return nil
+		}
+		return model.UpdateOption("stripe_metering_setting.log_cursor", strconv.Itoa(maxId))
+	}
+	endId := meteringSetting.PendingLogId
+	if endId <= meteringSetting.LogCursor {
+		var err error
+		endId, err = model.GetNthConsumeLogId(meteringSetting.LogCursor, stripeMeteringBatchSize)
+		if err != nil || endId == 0 {
+			return err
+		}
+		if err = model.UpdateOption("stripe_metering_setting.pending_log_id", strconv.Itoa(endId)); err != nil {
+			return err
+		}
+	}
+	logs, err := model.GetConsumeLogsInRange(meteringSetting.LogCursor, endId)
+	if err != nil {
+		return err
+	}
+	usage := aggregateMeteredUsage(logs, meteringSetting.ValueType)
+	userIds := make([]int, 0, len(usage))
+	for userId := range usage {
+		userIds = append(userIds, userId)
+	}
+	sort.Ints(userIds)
+	customers, err := model.GetUserStripeCustomers(userIds)
+	if err != nil {
+		return err
+	}
+	for _, userId := range userIds {
+		customerId, ok := customers[userId]
+		if !ok || usage[userId] <= 0 {
+			continue
+		}
+		identifier := fmt.Sprintf("new-api-%d-%d-%d", meteringSetting.LogCursor, endId, userId)
+		if err := sendStripeMeterEvent(meteringSetting.EventName, customerId, usage[userId], identifier); err != nil {
+			return fmt.Errorf("user %d: %w", userId, err)
+		}
+	}
+	if err := model.UpdateOption("stripe_metering_setting.log_cursor", strconv.Itoa(endId)); err != nil {
+		return err
+	}
+	return model.UpdateOption("stripe_metering_setting.pending_log_id", "0")
+}
+
+// aggregateMeteredUsage 按用户汇总待上报的用量
+func aggregateMeteredUsage(logs []*model.Log, valueType string) map[int]int64 {
+	usage := make(map[int]int64)
+	for _, log := range logs {
+		if valueType == operation_setting.StripeMeterValueQuota {
+			usage[log.UserId] += int64(log.Quota)
+		} else {
+			usage[log.UserId] += int64(log.PromptTokens + log.CompletionTokens)
+		}
+	}
+	return usage
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateMeteredUsage(t *testing.T) {
+	logs := []*model.Log{
+		{UserId: 1, PromptTokens: 10, CompletionTokens: 5, Quota: 20},
+		{UserId: 2, PromptTokens: 3, Quota: 4},
+		{UserId: 1, CompletionTokens: 7, Quota: 9},
+	}
+	require.Equal(t, map[int]int64{1: 22, 2: 3}, aggregateMeteredUsage(logs, operation_setting.StripeMeterValueTokens))
+	require.Equal(t, map[int]int64{1: 29, 2: 4}, aggregateMeteredUsage(logs, operation_setting.StripeMeterValueQuota))
+}
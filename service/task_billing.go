@@ -113,6 +113,10 @@ func taskAdjustTokenQuota(ctx context.Context, task *model.Task, delta int) {
 	}
 	if err != nil {
 		logger.LogWarn(ctx, fmt.Sprintf("调整令牌额度失败 (delta=%d, task=%s): %s", delta, task.TaskID, err.Error()))
+		return
+	}
+	if delta > 0 {
+		CheckTokenExhausted(tokenKey)
 	}
 }
 
@@ -174,12 +174,13 @@ func calculateTextQuotaSummary(ctx *gin.Context, relayInfo *relaycommon.RelayInf
 	}
 	summary.IsClaudeUsageSemantic = summary.UsageSemantic == "anthropic"
 
-	if usage == nil {
-		usage = &dto.Usage{
-			PromptTokens:     relayInfo.GetEstimatePromptTokens(),
-			CompletionTokens: 0,
-			TotalTokens:      relayInfo.GetEstimatePromptTokens(),
+	if usage == nil || (relayInfo.IsStream && usage.PromptTokens == 0 && usage.CompletionTokens == 0 && relayInfo.GetEstimatePromptTokens() > 0) {
+		// 上游未返回用量（流式响应结束时缺少 usage 块）时按估算的输入 token 与流中已收到的输出文本计费，避免静默计为 0
+		modelName := relayInfo.OriginModelName
+		if relayInfo.ChannelMeta != nil && relayInfo.UpstreamModelName != "" {
+			modelName = relayInfo.UpstreamModelName
 		}
+		usage = ResponseText2Usage(ctx, relayInfo.StreamStatus.ResponseText(), modelName, relayInfo.GetEstimatePromptTokens())
 	}
 
 	summary.PromptTokens = usage.PromptTokens
@@ -208,6 +209,17 @@ func calculateTextQuotaSummary(ctx *gin.Context, relayInfo *relaycommon.RelayInf
 		summary.PromptTokens -= summary.CacheCreationTokens
 	}
 
+	// 管理员注入的系统提示词不向用户计费，扣除量不超过按原价计费的输入部分
+	if excluded := relayInfo.InjectedSystemPromptTokens; excluded > 0 {
+		billable := summary.PromptTokens - summary.ImageTokens - summary.AudioTokens
+		if !summary.IsClaudeUsageSemantic && !legacyClaudeDerived {
+			billable -= summary.CacheTokens + summary.CacheCreationTokens
+		}
+		excluded = min(excluded, max(billable, 0))
+		summary.PromptTokens -= excluded
+		summary.TotalTokens -= excluded
+	}
+
 	dPromptTokens := decimal.NewFromInt(int64(summary.PromptTokens))
 	dCacheTokens := decimal.NewFromInt(int64(summary.CacheTokens))
 	dImageTokens := decimal.NewFromInt(int64(summary.ImageTokens))
@@ -346,6 +358,11 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 		}
 	}
 
+	volumeMultiplier, volumePricingApplied := 1.0, false
+	if !tieredBillingApplied && summary.TotalTokens > 0 {
+		summary.Quota, volumeMultiplier, volumePricingApplied = applyVolumePricing(relayInfo, summary.Quota, summary.TotalTokens)
+	}
+
 	if summary.WebSearchCallCount > 0 {
 		extraContent = append(extraContent, fmt.Sprintf("Web Search 调用 %d 次，调用花费 %s", summary.WebSearchCallCount, decimal.NewFromFloat(summary.WebSearchPrice).Mul(decimal.NewFromInt(int64(summary.WebSearchCallCount))).Div(decimal.NewFromInt(1000)).Mul(decimal.NewFromFloat(summary.GroupRatio)).Mul(decimal.NewFromFloat(common.QuotaPerUnit)).String()))
 	}
@@ -401,6 +418,9 @@ func PostTextConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 	if adminRejectReason != "" {
 		other["reject_reason"] = adminRejectReason
 	}
+	if volumePricingApplied {
+		other["volume_multiplier"] = volumeMultiplier
+	}
 	if summary.ImageTokens != 0 {
 		other["image"] = true
 		other["image_ratio"] = summary.ImageRatio
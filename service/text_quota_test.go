@@ -439,3 +439,36 @@ func TestComposeTieredTextQuotaErrorFallbackUsesPreConsumedQuota(t *testing.T) {
 	require.Equal(t, int64(12500), summary.ToolCallSurchargeQuota.Round(0).IntPart())
 	require.Equal(t, 14500, quota)
 }
+
+func TestCalculateTextQuotaSummaryEstimatesMissingStreamUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+
+	relayInfo := &relaycommon.RelayInfo{
+		RelayFormat:     types.RelayFormatOpenAI,
+		OriginModelName: "gpt-4o-mini",
+		IsStream:        true,
+		PriceData: types.PriceData{
+			ModelRatio:      1,
+			CompletionRatio: 1,
+			GroupRatioInfo: types.GroupRatioInfo{
+				GroupRatio: 1,
+			},
+		},
+		StartTime: time.Now(),
+	}
+	relayInfo.SetEstimatePromptTokens(120)
+	relayInfo.StreamStatus = relaycommon.NewStreamStatus()
+	responseText := "Hello there, this is a streamed answer without a usage chunk."
+	relayInfo.StreamStatus.AppendResponseText(responseText)
+	completionTokens := CountTextToken(responseText, "gpt-4o-mini")
+	require.Positive(t, completionTokens)
+
+	// 流式响应结束时没有 usage 块，按估算的输入 token 与已收到的输出文本计费并标记为估算
+	summary := calculateTextQuotaSummary(ctx, relayInfo, &dto.Usage{})
+	require.Equal(t, 120, summary.PromptTokens)
+	require.Equal(t, completionTokens, summary.CompletionTokens)
+	require.Equal(t, 120+completionTokens, summary.Quota)
+	require.True(t, IsUsageEstimated(ctx))
+}
@@ -196,6 +196,7 @@ func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *rela
 		if err != nil {
 			return 0, fmt.Errorf("error parsing multipart form: %v", err)
 		}
+		defer multiForm.RemoveAll()
 		fileHeaders := multiForm.File["file"]
 		totalAudioToken := 0
 		for _, fileHeader := range fileHeaders {
@@ -406,3 +407,44 @@ func CountTextToken(text string, model string) int {
 		return EstimateTokenByModel(model, text)
 	}
 }
+
+// CountClaudeRequestTokens 本地估算 Claude Messages 请求的输入 token 数，图片按固定值计算且不下载远程资源
+func CountClaudeRequestTokens(request *dto.ClaudeRequest) int {
+	meta := request.GetTokenCountMeta()
+	tokens := CountTextToken(meta.CombineText, request.Model)
+	tokens += meta.ToolsCount * 8
+	tokens += estimateMediaTokens(meta.Files)
+	return tokens
+}
+
+// CountGeminiRequestTokens 本地估算 Gemini 原生请求的输入 token 数，用于 countTokens 接口
+func CountGeminiRequestTokens(request *dto.GeminiChatRequest, model string) int {
+	meta := request.GetTokenCountMeta()
+	text := meta.CombineText
+	if request.SystemInstructions != nil {
+		for _, part := range request.SystemInstructions.Parts {
+			if part.Text != "" {
+				text += "\n" + part.Text
+			}
+		}
+	}
+	return CountTextToken(text, model) + estimateMediaTokens(meta.Files)
+}
+
+// estimateMediaTokens 按文件类型估算媒体 token，取值与 EstimateRequestToken 中非 OpenAI 模型一致
+func estimateMediaTokens(files []*types.FileMeta) int {
+	tokens := 0
+	for _, file := range files {
+		switch file.FileType {
+		case types.FileTypeImage:
+			tokens += 520
+		case types.FileTypeAudio:
+			tokens += 256
+		case types.FileTypeVideo:
+			tokens += 4096 * 2
+		default:
+			tokens += 4096
+		}
+	}
+	return tokens
+}
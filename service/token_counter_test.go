@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountClaudeRequestTokens(t *testing.T) {
+	var short, long dto.ClaudeRequest
+	require.NoError(t, common.Unmarshal([]byte(`{"model":"claude-sonnet-4","system":"be brief","messages":[{"role":"user","content":"hi"}]}`), &short))
+	require.NoError(t, common.Unmarshal([]byte(`{"model":"claude-sonnet-4","system":[{"type":"text","text":"be brief"}],"messages":[
+		{"role":"user","content":[{"type":"text","text":"describe the attached picture in as much detail as you can"},
+			{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}]}]}`), &long))
+
+	shortTokens := CountClaudeRequestTokens(&short)
+	require.Greater(t, shortTokens, 0)
+	require.Greater(t, CountClaudeRequestTokens(&long), shortTokens+520)
+}
+
+func TestCountGeminiRequestTokens(t *testing.T) {
+	var request dto.GeminiChatRequest
+	require.NoError(t, common.Unmarshal([]byte(`{"systemInstruction":{"parts":[{"text":"be brief"}]},"contents":[{"role":"user","parts":[{"text":"hello there"},{"inlineData":{"mimeType":"image/png","data":"AAAA"}}]}]}`), &request))
+	require.Greater(t, CountGeminiRequestTokens(&request, "gemini-2.5-flash"), 520)
+}
@@ -19,11 +19,12 @@ import (
 //	return 0, errors.New("unknown relay mode")
 //}
 
+// ResponseText2Usage 上游未返回用量时按模型族本地计算输出 token（OpenAI 模型使用 tiktoken），并将日志标记为估算
 func ResponseText2Usage(c *gin.Context, responseText string, modeName string, promptTokens int) *dto.Usage {
-	common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
+	MarkUsageEstimated(c)
 	usage := &dto.Usage{}
 	usage.PromptTokens = promptTokens
-	usage.CompletionTokens = EstimateTokenByModel(modeName, responseText)
+	usage.CompletionTokens = CountTextToken(responseText, modeName)
 	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 	return usage
 }
@@ -31,3 +32,13 @@ func ResponseText2Usage(c *gin.Context, responseText string, modeName string, pr
 func ValidUsage(usage *dto.Usage) bool {
 	return usage != nil && (usage.PromptTokens != 0 || usage.CompletionTokens != 0)
 }
+
+// MarkUsageEstimated 标记本次请求的用量为本地估算，消费日志中会带上 usage_estimated
+func MarkUsageEstimated(c *gin.Context) {
+	common.SetContextKey(c, constant.ContextKeyLocalCountTokens, true)
+}
+
+// IsUsageEstimated 本次请求的用量是否为本地估算
+func IsUsageEstimated(c *gin.Context) bool {
+	return common.GetContextKeyBool(c, constant.ContextKeyLocalCountTokens)
+}
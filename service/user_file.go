@@ -0,0 +1,274 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+// UserFilePurposes 支持上传的文件用途，与 OpenAI Files API 一致
+var UserFilePurposes = map[string]bool{
+	"batch":      true,
+	"fine-tune":  true,
+	"assistants": true,
+	"vision":     true,
+	"user_data":  true,
+}
+
+var ErrUserFileQuotaExceeded = errors.New("file storage quota exceeded")
+
+var fileIdPattern = regexp.MustCompile(`"(file-[A-Za-z0-9_-]+)"`)
+
+// NewUserFileId 生成网关侧的文件 ID
+func NewUserFileId() string {
+	return "file-" + common.GetRandomString(24)
+}
+
+// CheckUserFileQuota 检查用户再存储 size 字节是否超出文件存储配额
+func CheckUserFileQuota(userId int, size int64) error {
+	quotaMB := operation_setting.GetFileSetting().UserQuotaMB
+	if quotaMB <= 0 {
+		return nil
+	}
+	usage, err := model.GetUserFileUsage(userId)
+	if err != nil {
+		return err
+	}
+	if usage+size > int64(quotaMB)<<20 {
+		return ErrUserFileQuotaExceeded
+	}
+	return nil
+}
+
+// SaveUserFile 按当前存储设置保存 size 字节的文件内容并写入记录，调用前需设置 FileId、UserId、Filename 与 Purpose。
+// 本机目录与对象存储流式写入，database 存储需要将内容读入内存
+func SaveUserFile(ctx context.Context, file *model.UserFile, content io.Reader, size int64) error {
+	if err := CheckUserFileQuota(file.UserId, size); err != nil {
+		return err
+	}
+	file.Bytes = size
+	file.Storage = operation_setting.GetFileSetting().StorageType
+	if file.Storage == operation_setting.FileStorageDatabase {
+		data, err := io.ReadAll(io.LimitReader(content, size))
+		if err != nil {
+			return err
+		}
+		file.Content = data
+		return file.Insert()
+	}
+	if file.Storage == "" {
+		file.Storage = operation_setting.FileStorageLocal
+	}
+	storage, err := getUserFileStorage(file.Storage)
+	if err != nil {
+		return err
+	}
+	file.StorageKey = fmt.Sprintf("%d/%s", file.UserId, file.FileId)
+	if err := storage.Put(ctx, file.StorageKey, io.LimitReader(content, size), size); err != nil {
+		return err
+	}
+	if err := file.Insert(); err != nil {
+		_ = storage.Delete(ctx, file.StorageKey)
+		return err
+	}
+	return nil
+}
+
+// LoadUserFileContent 读取文件内容，文件可能保存在数据库、本机目录或对象存储中
+func LoadUserFileContent(ctx context.Context, file *model.UserFile) ([]byte, error) {
+	if file.Storage == "" || file.Storage == operation_setting.FileStorageDatabase {
+		if file.Content != nil {
+			return file.Content, nil
+		}
+		full, err := model.GetUserFile(file.UserId, file.FileId, true)
+		if err != nil {
+			return nil, err
+		}
+		return full.Content, nil
+	}
+	storage, err := getUserFileStorage(file.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Get(ctx, file.StorageKey)
+}
+
+// RemoveUserFile 删除文件记录与存储内容，已转发到渠道的上游副本在后台删除
+func RemoveUserFile(ctx context.Context, file *model.UserFile) error {
+	upstreams, err := model.GetUserFileUpstreams(file.FileId)
+	if err != nil {
+		return err
+	}
+	if err := model.DeleteUserFile(file); err != nil {
+		return err
+	}
+	if file.StorageKey != "" {
+		if storage, err := getUserFileStorage(file.Storage); err == nil {
+			if err := storage.Delete(ctx, file.StorageKey); err != nil {
+				common.SysError("failed to delete stored file: " + err.Error())
+			}
+		}
+	}
+	if len(upstreams) > 0 {
+		gopool.Go(func() {
+			for _, upstream := range upstreams {
+				deleteUpstreamFile(upstream)
+			}
+		})
+	}
+	return nil
+}
+
+// CleanupUserFilesBefore 删除某种用途下上传时间早于 cutoff 的文件
+func CleanupUserFilesBefore(purpose string, cutoff int64) error {
+	for {
+		files, err := model.GetFilesBefore(purpose, cutoff, 100)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := RemoveUserFile(context.Background(), file); err != nil {
+				return err
+			}
+		}
+		if len(files) < 100 {
+			return nil
+		}
+	}
+}
+
+func doChannelRequest(ctx context.Context, channel *model.Channel, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(channel.GetBaseURL(), "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client, err := GetHttpClientWithProxy(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// EnsureUpstreamFile 确保文件已上传到指定渠道，返回上游文件 ID；同一文件在同一渠道只上传一次
+func EnsureUpstreamFile(ctx context.Context, channel *model.Channel, file *model.UserFile) (string, error) {
+	upstream, err := model.GetUserFileUpstream(file.FileId, channel.Id)
+	if err == nil {
+		return upstream.UpstreamFileId, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	content, err := LoadUserFileContent(ctx, file)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("purpose", file.Purpose); err != nil {
+		return "", err
+	}
+	part, err := writer.CreateFormFile("file", file.Filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(content); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	resp, err := doChannelRequest(ctx, channel, http.MethodPost, "/v1/files", &buf, writer.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	upstreamId := gjson.GetBytes(body, "id").String()
+	if resp.StatusCode != http.StatusOK || upstreamId == "" {
+		return "", fmt.Errorf("upload file to channel #%d failed with status %d: %s", channel.Id, resp.StatusCode, string(body))
+	}
+	upstream = &model.UserFileUpstream{FileId: file.FileId, ChannelId: channel.Id, UpstreamFileId: upstreamId}
+	if err := upstream.Insert(); err != nil {
+		return "", err
+	}
+	return upstreamId, nil
+}
+
+func deleteUpstreamFile(upstream *model.UserFileUpstream) {
+	channel, err := model.CacheGetChannel(upstream.ChannelId)
+	if err != nil {
+		return
+	}
+	resp, err := doChannelRequest(context.Background(), channel, http.MethodDelete, "/v1/files/"+upstream.UpstreamFileId, nil, "")
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to delete upstream file %s on channel #%d: %s", upstream.UpstreamFileId, upstream.ChannelId, err.Error()))
+		return
+	}
+	resp.Body.Close()
+}
+
+// ReplaceFileIdsForUpstream 将请求体中引用的用户文件 ID 替换为其在渠道上的文件 ID，必要时先上传文件
+func ReplaceFileIdsForUpstream(ctx context.Context, userId int, channel *model.Channel, body []byte) ([]byte, error) {
+	replaced := make(map[string]bool)
+	for _, match := range fileIdPattern.FindAllSubmatch(body, -1) {
+		fileId := string(match[1])
+		if replaced[fileId] {
+			continue
+		}
+		replaced[fileId] = true
+		file, err := model.GetUserFile(userId, fileId, false)
+		if err != nil {
+			// 不是网关上传的文件，原样转发
+			continue
+		}
+		upstreamId, err := EnsureUpstreamFile(ctx, channel, file)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.ReplaceAll(body, []byte(`"`+fileId+`"`), []byte(`"`+upstreamId+`"`))
+	}
+	return body, nil
+}
+
+// RestoreUserFileIds 将响应中的上游文件 ID 还原为网关文件 ID，未经网关上传的文件保持不变
+func RestoreUserFileIds(channelId int, body []byte) []byte {
+	restored := make(map[string]bool)
+	for _, match := range fileIdPattern.FindAllSubmatch(body, -1) {
+		upstreamId := string(match[1])
+		if restored[upstreamId] {
+			continue
+		}
+		restored[upstreamId] = true
+		upstream, err := model.GetUserFileUpstreamById(channelId, upstreamId)
+		if err != nil {
+			continue
+		}
+		body = bytes.ReplaceAll(body, []byte(`"`+upstreamId+`"`), []byte(`"`+upstream.FileId+`"`))
+	}
+	return body
+}
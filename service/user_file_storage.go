@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// userFileStorage 文件内容的外部存储（本机目录或 S3 兼容对象存储），database 存储直接使用 UserFile.Content
+type userFileStorage interface {
+	// Put 流式写入 size 字节的内容，不在内存中缓冲整个文件
+	Put(ctx context.Context, key string, content io.Reader, size int64) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func getUserFileStorage(storageType string) (userFileStorage, error) {
+	setting := operation_setting.GetFileSetting()
+	switch storageType {
+	case operation_setting.FileStorageLocal:
+		return &localFileStorage{dir: setting.LocalPath}, nil
+	case operation_setting.FileStorageS3:
+		if setting.S3Endpoint == "" || setting.S3Bucket == "" {
+			return nil, errors.New("s3 storage is not configured")
+		}
+		return &s3FileStorage{setting: setting}, nil
+	}
+	return nil, fmt.Errorf("unsupported file storage type: %s", storageType)
+}
+
+type localFileStorage struct {
+	dir string
+}
+
+func (s *localFileStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || filepath.IsAbs(key) {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入同目录下的临时文件再重命名，避免写入中途失败留下不完整的文件
+func (s *localFileStorage) Put(_ context.Context, key string, content io.Reader, _ int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localFileStorage) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (s *localFileStorage) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// s3FileStorage 通过 SigV4 签名的 HTTP 请求访问 S3 兼容存储
+type s3FileStorage struct {
+	setting *operation_setting.FileSetting
+}
+
+func (s *s3FileStorage) objectURL(key string) string {
+	endpoint := strings.TrimSuffix(s.setting.S3Endpoint, "/")
+	escaped := (&url.URL{Path: key}).EscapedPath()
+	if s.setting.S3PathStyle {
+		return endpoint + "/" + s.setting.S3Bucket + "/" + escaped
+	}
+	if scheme, host, ok := strings.Cut(endpoint, "://"); ok {
+		return scheme + "://" + s.setting.S3Bucket + "." + host + "/" + escaped
+	}
+	return "https://" + s.setting.S3Bucket + "." + endpoint + "/" + escaped
+}
+
+// s3EmptyPayloadHash 无请求体时的 SHA-256
+var s3EmptyPayloadHash = func() string {
+	sum := sha256.Sum256(nil)
+	return hex.EncodeToString(sum[:])
+}()
+
+// s3UnsignedPayload 上传时请求体不参与签名，内容可以边读边发送
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+func (s *s3FileStorage) do(ctx context.Context, method string, key string, content io.Reader, size int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key), content)
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := s3EmptyPayloadHash
+	if content != nil {
+		payloadHash = s3UnsignedPayload
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{AccessKeyID: s.setting.S3AccessKey, SecretAccessKey: s.setting.S3SecretKey}
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, payloadHash, "s3", s.setting.S3Region, time.Now()); err != nil {
+		return nil, err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return nil, fmt.Errorf("s3 %s %s failed with status %d: %s", method, key, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (s *s3FileStorage) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	_, err := s.do(ctx, http.MethodPut, key, content, size)
+	return err
+}
+
+func (s *s3FileStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, 0)
+}
+
+func (s *s3FileStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, 0)
+	return err
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage := &localFileStorage{dir: dir}
+	ctx := context.Background()
+	require.NoError(t, storage.Put(ctx, "1/file-abc", strings.NewReader("hello"), 5))
+	content, err := storage.Get(ctx, "1/file-abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), content)
+	// 临时文件已被重命名，不留残余
+	entries, err := os.ReadDir(dir + "/1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NoError(t, storage.Delete(ctx, "1/file-abc"))
+	// 重复删除不报错
+	require.NoError(t, storage.Delete(ctx, "1/file-abc"))
+	_, err = storage.Get(ctx, "1/file-abc")
+	require.Error(t, err)
+
+	require.Error(t, storage.Put(ctx, "../escape", strings.NewReader("x"), 1))
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	setting := &operation_setting.FileSetting{S3Endpoint: "https://s3.example.com/", S3Bucket: "files"}
+	storage := &s3FileStorage{setting: setting}
+	require.Equal(t, "https://files.s3.example.com/1/file-abc", storage.objectURL("1/file-abc"))
+	setting.S3PathStyle = true
+	require.Equal(t, "https://s3.example.com/files/1/file-abc", storage.objectURL("1/file-abc"))
+}
+
+func TestS3FileStorageStreamsUpload(t *testing.T) {
+	var method, path, payloadHash, authorization string
+	var contentLength int64
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		authorization = r.Header.Get("Authorization")
+		contentLength = r.ContentLength
+		body, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(server.Close)
+	if GetHttpClient() == nil {
+		InitHttpClient()
+	}
+
+	storage := &s3FileStorage{setting: &operation_setting.FileSetting{
+		S3Endpoint:  server.URL,
+		S3Bucket:    "files",
+		S3Region:    "us-east-1",
+		S3PathStyle: true,
+		S3AccessKey: "AKID",
+		S3SecretKey: "secret",
+	}}
+	// 仅实现 io.Reader，确认上传不依赖一次性取得全部内容
+	content := io.MultiReader(strings.NewReader("hello "), strings.NewReader("world"))
+	require.NoError(t, storage.Put(context.Background(), "1/file-abc", content, 11))
+	require.Equal(t, http.MethodPut, method)
+	require.Equal(t, "/files/1/file-abc", path)
+	require.Equal(t, s3UnsignedPayload, payloadHash)
+	require.Contains(t, authorization, "Credential=AKID/")
+	require.EqualValues(t, 11, contentLength)
+	require.Equal(t, "hello world", string(body))
+
+	require.NoError(t, storage.Delete(context.Background(), "1/file-abc"))
+	require.Equal(t, http.MethodDelete, method)
+	require.Equal(t, s3EmptyPayloadHash, payloadHash)
+}
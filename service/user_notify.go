@@ -14,15 +14,15 @@ import (
 	"github.com/QuantumNous/new-api/setting/system_setting"
 )
 
-func NotifyRootUser(t string, subject string, content string) {
+func NotifyRootUser(t string, templateName string, data map[string]any) {
 	user := model.GetRootUser().ToBaseUser()
-	err := NotifyUser(user.Id, user.Email, user.GetSetting(), dto.NewNotify(t, subject, content, nil))
+	err := NotifyUserWithTemplate(user.Id, user.Email, user.GetSetting(), t, templateName, data)
 	if err != nil {
 		common.SysLog(fmt.Sprintf("failed to notify root user: %s", err.Error()))
 	}
 }
 
-func NotifyUpstreamModelUpdateWatchers(subject string, content string) {
+func NotifyUpstreamModelUpdateWatchers(data map[string]any) {
 	var users []model.User
 	if err := model.DB.
 		Select("id", "email", "role", "status", "setting").
@@ -32,14 +32,13 @@ func NotifyUpstreamModelUpdateWatchers(subject string, content string) {
 		return
 	}
 
-	notification := dto.NewNotify(dto.NotifyTypeChannelUpdate, subject, content, nil)
 	sentCount := 0
 	for _, user := range users {
 		userSetting := user.GetSetting()
 		if !userSetting.UpstreamModelUpdateNotifyEnabled {
 			continue
 		}
-		if err := NotifyUser(user.Id, user.Email, userSetting, notification); err != nil {
+		if err := NotifyUserWithTemplate(user.Id, user.Email, userSetting, dto.NotifyTypeChannelUpdate, NotifyTemplateUpstreamModelUpdate, data); err != nil {
 			common.SysLog(fmt.Sprintf("failed to notify user %d for upstream model update: %s", user.Id, err.Error()))
 			continue
 		}
@@ -48,6 +47,24 @@ func NotifyUpstreamModelUpdateWatchers(subject string, content string) {
 	common.SysLog(fmt.Sprintf("upstream model update notifications sent: %d", sentCount))
 }
 
+// NotifyUserWithTemplate 使用用户的语言偏好渲染通知模板后发送
+func NotifyUserWithTemplate(userId int, userEmail string, userSetting dto.UserSetting, t string, templateName string, data map[string]any) error {
+	notifyType := userSetting.NotifyType
+	if notifyType == "" {
+		notifyType = dto.NotifyTypeEmail
+	}
+	templateData := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		templateData[k] = v
+	}
+	templateData["NotifyType"] = notifyType
+	subject, content, err := RenderNotifyTemplate(templateName, userSetting.Language, templateData, IsHTMLNotifyTemplate(templateName, notifyType))
+	if err != nil {
+		return err
+	}
+	return NotifyUser(userId, userEmail, userSetting, dto.NewNotify(t, subject, content, nil))
+}
+
 func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data dto.Notify) error {
 	notifyType := userSetting.NotifyType
 	if notifyType == "" {
@@ -85,7 +102,7 @@ func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data
 
 		// 获取 webhook secret
 		webhookSecret := userSetting.WebhookSecret
-		return SendWebhookNotify(webhookURLStr, webhookSecret, data)
+		return SendWebhookNotify(webhookURLStr, webhookSecret, userSetting.Language, data)
 	case dto.NotifyTypeBark:
 		barkURL := userSetting.BarkUrl
 		if barkURL == "" {
@@ -101,6 +118,18 @@ func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data
 			return nil
 		}
 		return sendGotifyNotify(gotifyUrl, gotifyToken, userSetting.GotifyPriority, data)
+	case dto.NotifyTypeTelegram:
+		if userSetting.TelegramBotToken == "" || userSetting.TelegramChatId == "" {
+			common.SysLog(fmt.Sprintf("user %d has no telegram bot token or chat id, skip sending telegram", userId))
+			return nil
+		}
+		return sendTelegramNotify(userSetting.TelegramBotToken, userSetting.TelegramChatId, data)
+	case dto.NotifyTypeSlack:
+		if userSetting.SlackWebhookUrl == "" {
+			common.SysLog(fmt.Sprintf("user %d has no slack webhook url, skip sending slack", userId))
+			return nil
+		}
+		return sendSlackNotify(userSetting.SlackWebhookUrl, data)
 	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+const telegramAPIBaseURL = "https://api.telegram.org"
+
+// notifyPlainContent 替换占位符并返回纯文本内容，聊天类通知不支持 HTML
+func notifyPlainContent(data dto.Notify) string {
+	content := data.Content
+	for _, value := range data.Values {
+		content = strings.Replace(content, dto.ContentValueParam, fmt.Sprintf("%v", value), 1)
+	}
+	return content
+}
+
+func sendTelegramNotify(botToken string, chatId string, data dto.Notify) error {
+	payload, err := common.Marshal(map[string]any{
+		"chat_id": chatId,
+		"text":    data.Title + "\n\n" + notifyPlainContent(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram payload: %v", err)
+	}
+	return postNotifyJSON("telegram", telegramAPIBaseURL+"/bot"+botToken+"/sendMessage", payload)
+}
+
+func sendSlackNotify(webhookURL string, data dto.Notify) error {
+	payload, err := common.Marshal(map[string]any{
+		"text": "*" + data.Title + "*\n" + notifyPlainContent(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack payload: %v", err)
+	}
+	return postNotifyJSON("slack", webhookURL, payload)
+}
+
+// postNotifyJSON 发送 JSON 通知请求，启用 worker 时经 worker 转发，否则直连并做 SSRF 校验
+func postNotifyJSON(name string, targetURL string, payload []byte) error {
+	headers := map[string]string{
+		"Content-Type": "application/json; charset=utf-8",
+		"User-Agent":   "NewAPI-Notify/1.0",
+	}
+	var resp *http.Response
+	var err error
+	if system_setting.EnableWorker() {
+		resp, err = DoWorkerRequest(&WorkerRequest{
+			URL:     targetURL,
+			Key:     system_setting.WorkerValidKey,
+			Method:  http.MethodPost,
+			Headers: headers,
+			Body:    payload,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send %s request through worker: %v", name, err)
+		}
+	} else {
+		fetchSetting := system_setting.GetFetchSetting()
+		if err := common.ValidateURLWithFetchSetting(targetURL, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+			return fmt.Errorf("request reject: %v", err)
+		}
+		req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewBuffer(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create %s request: %v", name, err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err = GetHttpClient().Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send %s request: %v", name, err)
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s request failed with status code: %d", name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/billing_setting"
+
+	"github.com/shopspring/decimal"
+)
+
+// applyVolumePricing 按模型的阶梯价格调整本次消费额度并累计本月用量，未配置阶梯时原样返回
+func applyVolumePricing(relayInfo *relaycommon.RelayInfo, quota int, tokens int) (int, float64, bool) {
+	rule, ok := billing_setting.GetVolumePricing(relayInfo.OriginModelName)
+	if !ok || tokens <= 0 {
+		return quota, 1, false
+	}
+	subject := strconv.Itoa(relayInfo.UserId)
+	if rule.Scope == billing_setting.VolumeScopeGroup {
+		subject = relayInfo.UsingGroup
+	}
+	used, err := model.IncrMonthlyModelVolume(rule.Scope, subject, relayInfo.OriginModelName, int64(tokens))
+	if err != nil {
+		common.SysError("failed to record volume pricing usage: " + err.Error())
+		return quota, 1, false
+	}
+	multiplier := rule.EffectiveMultiplier(used-int64(tokens), int64(tokens))
+	adjusted := int(decimal.NewFromInt(int64(quota)).Mul(decimal.NewFromFloat(multiplier)).Round(0).IntPart())
+	return adjusted, multiplier, true
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -15,15 +14,6 @@ import (
 	"github.com/QuantumNous/new-api/setting/system_setting"
 )
 
-// WebhookPayload webhook 通知的负载数据
-type WebhookPayload struct {
-	Type      string        `json:"type"`
-	Title     string        `json:"title"`
-	Content   string        `json:"content"`
-	Values    []interface{} `json:"values,omitempty"`
-	Timestamp int64         `json:"timestamp"`
-}
-
 // generateSignature 生成 webhook 签名
 func generateSignature(secret string, payload []byte) string {
 	h := hmac.New(sha256.New, []byte(secret))
@@ -32,26 +22,23 @@ func generateSignature(secret string, payload []byte) string {
 }
 
 // SendWebhookNotify 发送 webhook 通知
-func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error {
+func SendWebhookNotify(webhookURL string, secret string, lang string, data dto.Notify) error {
 	// 处理占位符
 	content := data.Content
 	for _, value := range data.Values {
 		content = fmt.Sprintf(content, value)
 	}
 
-	// 构建 webhook 负载
-	payload := WebhookPayload{
-		Type:      data.Type,
-		Title:     data.Title,
-		Content:   content,
-		Values:    data.Values,
-		Timestamp: time.Now().Unix(),
-	}
-
-	// 序列化负载
-	payloadBytes, err := json.Marshal(payload)
+	// 按 webhook_payload 模板构建负载
+	payloadBytes, err := RenderWebhookPayload(lang, map[string]any{
+		"Type":      data.Type,
+		"Title":     data.Title,
+		"Content":   content,
+		"Values":    data.Values,
+		"Timestamp": time.Now().Unix(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+		return fmt.Errorf("failed to render webhook payload: %v", err)
 	}
 
 	// 创建 HTTP 请求
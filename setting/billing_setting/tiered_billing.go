@@ -16,15 +16,22 @@ const (
 )
 
 // BillingSetting is managed by config.GlobalConfig.Register.
-// DB keys: billing_setting.billing_mode, billing_setting.billing_expr
+// DB keys: billing_setting.billing_mode, billing_setting.billing_expr, billing_setting.volume_pricing,
+// billing_setting.time_pricing, billing_setting.time_pricing_timezone
 type BillingSetting struct {
-	BillingMode map[string]string `json:"billing_mode"`
-	BillingExpr map[string]string `json:"billing_expr"`
+	BillingMode         map[string]string            `json:"billing_mode"`
+	BillingExpr         map[string]string            `json:"billing_expr"`
+	VolumePricing       map[string]VolumePricingRule `json:"volume_pricing"`
+	TimePricing         []TimePricingRule            `json:"time_pricing"`
+	TimePricingTimezone string                       `json:"time_pricing_timezone"`
 }
 
 var billingSetting = BillingSetting{
-	BillingMode: make(map[string]string),
-	BillingExpr: make(map[string]string),
+	BillingMode:         make(map[string]string),
+	BillingExpr:         make(map[string]string),
+	VolumePricing:       make(map[string]VolumePricingRule),
+	TimePricing:         make([]TimePricingRule, 0),
+	TimePricingTimezone: "Asia/Shanghai",
 }
 
 func init() {
@@ -0,0 +1,119 @@
+package billing_setting
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// TimePricingRule 分时价格倍数，Start/End 为 HH:MM 格式的本地时间，End 小于 Start 时表示跨越零点；
+// Groups、Models 为空时对所有分组或模型生效
+type TimePricingRule struct {
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Multiplier float64  `json:"multiplier"`
+	Groups     []string `json:"groups,omitempty"`
+	Models     []string `json:"models,omitempty"`
+}
+
+func GetTimePricingRules() []TimePricingRule {
+	return billingSetting.TimePricing
+}
+
+func GetTimePricingTimezone() string {
+	return billingSetting.TimePricingTimezone
+}
+
+// GetTimePricingMultiplier 返回 now 时刻对该分组和模型生效的分时倍数，按配置顺序取第一条命中的规则
+func GetTimePricingMultiplier(group string, model string, now time.Time) (float64, bool) {
+	rules := billingSetting.TimePricing
+	if len(rules) == 0 {
+		return 1, false
+	}
+	local := now.In(timePricingLocation(billingSetting.TimePricingTimezone))
+	minute := local.Hour()*60 + local.Minute()
+	for _, rule := range rules {
+		if len(rule.Groups) > 0 && !slices.Contains(rule.Groups, group) {
+			continue
+		}
+		if len(rule.Models) > 0 && !slices.Contains(rule.Models, model) {
+			continue
+		}
+		start, err := parseClockMinute(rule.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseClockMinute(rule.End)
+		if err != nil {
+			continue
+		}
+		if inClockRange(minute, start, end) {
+			return rule.Multiplier, true
+		}
+	}
+	return 1, false
+}
+
+var timePricingLocations sync.Map
+
+// timePricingLocation 缓存时区解析结果，时区无效时回退到服务器本地时区
+func timePricingLocation(name string) *time.Location {
+	if loc, ok := timePricingLocations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.Local
+	}
+	timePricingLocations.Store(name, loc)
+	return loc
+}
+
+func inClockRange(minute int, start int, end int) bool {
+	if start == end {
+		return true
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func parseClockMinute(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误，应为 HH:MM: %s", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateTimePricing 校验分时价格规则的时间格式与倍数
+func ValidateTimePricing(jsonStr string) error {
+	var rules []TimePricingRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("分时价格格式错误: %v", err)
+	}
+	for i, rule := range rules {
+		if _, err := parseClockMinute(rule.Start); err != nil {
+			return fmt.Errorf("第 %d 条分时规则%v", i+1, err)
+		}
+		if _, err := parseClockMinute(rule.End); err != nil {
+			return fmt.Errorf("第 %d 条分时规则%v", i+1, err)
+		}
+		if rule.Multiplier < 0 {
+			return fmt.Errorf("第 %d 条分时规则的倍数不能为负数", i+1)
+		}
+	}
+	return nil
+}
+
+// ValidateTimePricingTimezone 校验分时价格使用的时区名称，例如 Asia/Shanghai、UTC
+func ValidateTimePricingTimezone(timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("无效的时区: %s", timezone)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package billing_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimePricingMultiplier(t *testing.T) {
+	original := billingSetting.TimePricing
+	originalTimezone := billingSetting.TimePricingTimezone
+	t.Cleanup(func() {
+		billingSetting.TimePricing = original
+		billingSetting.TimePricingTimezone = originalTimezone
+	})
+
+	billingSetting.TimePricingTimezone = "UTC"
+	billingSetting.TimePricing = []TimePricingRule{
+		{Start: "22:00", End: "02:00", Multiplier: 0.5, Models: []string{"gpt-4o"}},
+		{Start: "00:00", End: "08:00", Multiplier: 0.7, Groups: []string{"default"}},
+	}
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	multiplier, ok := GetTimePricingMultiplier("default", "gpt-4o", at(23, 30))
+	require.True(t, ok)
+	require.Equal(t, 0.5, multiplier)
+
+	// 按顺序取第一条命中的规则
+	multiplier, ok = GetTimePricingMultiplier("default", "gpt-4o", at(1, 0))
+	require.True(t, ok)
+	require.Equal(t, 0.5, multiplier)
+
+	multiplier, ok = GetTimePricingMultiplier("default", "claude-3", at(7, 59))
+	require.True(t, ok)
+	require.Equal(t, 0.7, multiplier)
+
+	_, ok = GetTimePricingMultiplier("default", "claude-3", at(8, 0))
+	require.False(t, ok)
+	_, ok = GetTimePricingMultiplier("vip", "claude-3", at(3, 0))
+	require.False(t, ok)
+
+	// 时区换算：UTC 16:00 为上海时间 00:00
+	billingSetting.TimePricingTimezone = "Asia/Shanghai"
+	multiplier, ok = GetTimePricingMultiplier("default", "claude-3", at(16, 0))
+	require.True(t, ok)
+	require.Equal(t, 0.7, multiplier)
+}
+
+func TestValidateTimePricing(t *testing.T) {
+	require.NoError(t, ValidateTimePricing(`[{"start":"00:00","end":"08:00","multiplier":0.7,"groups":["default"]}]`))
+	require.Error(t, ValidateTimePricing(`[{"start":"24:00","end":"08:00","multiplier":0.7}]`))
+	require.Error(t, ValidateTimePricing(`[{"start":"00:00","end":"08:00","multiplier":-1}]`))
+	require.NoError(t, ValidateTimePricingTimezone("Asia/Shanghai"))
+	require.Error(t, ValidateTimePricingTimezone("Mars/Base"))
+}
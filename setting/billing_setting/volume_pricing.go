@@ -0,0 +1,103 @@
+package billing_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	VolumeScopeUser  = "user"
+	VolumeScopeGroup = "group"
+)
+
+// VolumeTier 用量阶梯，UpTo 为本档累计 token 上限（0 表示不封顶），Multiplier 为本档价格相对模型基础价格的倍数
+type VolumeTier struct {
+	UpTo       int64   `json:"up_to"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// VolumePricingRule 模型的按量阶梯价格，按自然月累计用户或分组的 token 用量，每月重置
+type VolumePricingRule struct {
+	Scope string       `json:"scope"`
+	Tiers []VolumeTier `json:"tiers"`
+}
+
+func GetVolumePricing(model string) (*VolumePricingRule, bool) {
+	rule, ok := billingSetting.VolumePricing[model]
+	if !ok || len(rule.Tiers) == 0 {
+		return nil, false
+	}
+	return &rule, true
+}
+
+// EffectiveMultiplier 计算本月已用 used 个 token 后再使用 tokens 个 token 的平均价格倍数，跨档部分按各档价格分别计算
+func (rule *VolumePricingRule) EffectiveMultiplier(used int64, tokens int64) float64 {
+	if tokens <= 0 {
+		return rule.multiplierAt(used)
+	}
+	var weighted float64
+	start := used
+	end := used + tokens
+	lower := int64(0)
+	for _, tier := range rule.Tiers {
+		upper := tier.UpTo
+		if upper <= 0 || upper > end {
+			upper = end
+		}
+		if upper > start && upper > lower {
+			from := max(start, lower)
+			weighted += float64(upper-from) * tier.Multiplier
+		}
+		if tier.UpTo <= 0 || tier.UpTo >= end {
+			return weighted / float64(tokens)
+		}
+		lower = tier.UpTo
+	}
+	// 超出最后一档上限的部分按最后一档计价
+	last := rule.Tiers[len(rule.Tiers)-1]
+	weighted += float64(end-max(start, lower)) * last.Multiplier
+	return weighted / float64(tokens)
+}
+
+func (rule *VolumePricingRule) multiplierAt(used int64) float64 {
+	for _, tier := range rule.Tiers {
+		if tier.UpTo <= 0 || used < tier.UpTo {
+			return tier.Multiplier
+		}
+	}
+	return rule.Tiers[len(rule.Tiers)-1].Multiplier
+}
+
+// ValidateVolumePricing 校验阶梯配置：范围合法、上限递增、只有最后一档可以不封顶
+func ValidateVolumePricing(jsonStr string) error {
+	var rules map[string]VolumePricingRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("阶梯价格格式错误: %v", err)
+	}
+	for model, rule := range rules {
+		if rule.Scope != VolumeScopeUser && rule.Scope != VolumeScopeGroup {
+			return fmt.Errorf("模型 %s 的阶梯统计范围无效: %s", model, rule.Scope)
+		}
+		if len(rule.Tiers) == 0 {
+			return fmt.Errorf("模型 %s 未配置价格阶梯", model)
+		}
+		var prev int64
+		for i, tier := range rule.Tiers {
+			if tier.Multiplier < 0 {
+				return fmt.Errorf("模型 %s 第 %d 档价格倍数不能为负数", model, i+1)
+			}
+			if tier.UpTo <= 0 {
+				if i != len(rule.Tiers)-1 {
+					return fmt.Errorf("模型 %s 只有最后一档可以不设上限", model)
+				}
+				continue
+			}
+			if tier.UpTo <= prev {
+				return fmt.Errorf("模型 %s 的阶梯上限必须递增", model)
+			}
+			prev = tier.UpTo
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package billing_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumePricingEffectiveMultiplier(t *testing.T) {
+	rule := &VolumePricingRule{
+		Scope: VolumeScopeUser,
+		Tiers: []VolumeTier{
+			{UpTo: 1_000_000, Multiplier: 1},
+			{UpTo: 0, Multiplier: 0.8},
+		},
+	}
+	require.InDelta(t, 1, rule.EffectiveMultiplier(0, 1000), 1e-9)
+	require.InDelta(t, 0.8, rule.EffectiveMultiplier(2_000_000, 1000), 1e-9)
+	// 跨档：100k 按 1 倍，100k 按 0.8 倍
+	require.InDelta(t, 0.9, rule.EffectiveMultiplier(900_000, 200_000), 1e-9)
+
+	// 最后一档封顶时，超出部分按最后一档计价
+	bounded := &VolumePricingRule{
+		Scope: VolumeScopeGroup,
+		Tiers: []VolumeTier{
+			{UpTo: 100, Multiplier: 1},
+			{UpTo: 200, Multiplier: 0.5},
+		},
+	}
+	require.InDelta(t, 0.5, bounded.EffectiveMultiplier(300, 50), 1e-9)
+	require.InDelta(t, 0.75, bounded.EffectiveMultiplier(50, 100), 1e-9)
+}
+
+func TestValidateVolumePricing(t *testing.T) {
+	require.NoError(t, ValidateVolumePricing(`{"gpt-4o":{"scope":"user","tiers":[{"up_to":1000000,"multiplier":1},{"up_to":0,"multiplier":0.8}]}}`))
+	require.Error(t, ValidateVolumePricing(`{"gpt-4o":{"scope":"token","tiers":[{"up_to":0,"multiplier":1}]}}`))
+	require.Error(t, ValidateVolumePricing(`{"gpt-4o":{"scope":"user","tiers":[{"up_to":0,"multiplier":1},{"up_to":100,"multiplier":0.8}]}}`))
+	require.Error(t, ValidateVolumePricing(`{"gpt-4o":{"scope":"user","tiers":[{"up_to":200,"multiplier":1},{"up_to":100,"multiplier":0.8}]}}`))
+	require.Error(t, ValidateVolumePricing(`{"gpt-4o":{"scope":"user","tiers":[{"up_to":0,"multiplier":-1}]}}`))
+}
@@ -0,0 +1,100 @@
+package model_setting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ModelCapability 模型能力元数据，用于 /v1/models 的扩展输出；0 表示未知
+type ModelCapability struct {
+	ContextWindow     int      `json:"context_window,omitempty"`
+	MaxOutputTokens   int      `json:"max_output_tokens,omitempty"`
+	InputModalities   []string `json:"input_modalities,omitempty"`
+	OutputModalities  []string `json:"output_modalities,omitempty"`
+	SupportsTools     bool     `json:"supports_tools"`
+	SupportsVision    bool     `json:"supports_vision"`
+	SupportsReasoning bool     `json:"supports_reasoning"`
+}
+
+type ModelCapabilitySettings struct {
+	// Capabilities 按模型名称配置，优先于内置的按前缀匹配的默认值
+	Capabilities map[string]ModelCapability `json:"capabilities"`
+}
+
+var modelCapabilitySettings = ModelCapabilitySettings{
+	Capabilities: map[string]ModelCapability{},
+}
+
+var (
+	textOnly      = []string{"text"}
+	textAndImage  = []string{"text", "image"}
+	geminiModals  = []string{"text", "image", "audio", "video"}
+	embeddingOnly = []string{"embedding"}
+)
+
+// builtinModelCapabilities 常见模型系列的默认能力，按最长前缀匹配
+var builtinModelCapabilities = map[string]ModelCapability{
+	"gpt-5":             {ContextWindow: 400000, MaxOutputTokens: 128000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"gpt-4.1":           {ContextWindow: 1047576, MaxOutputTokens: 32768, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true},
+	"gpt-4o":            {ContextWindow: 128000, MaxOutputTokens: 16384, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true},
+	"gpt-4-turbo":       {ContextWindow: 128000, MaxOutputTokens: 4096, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true},
+	"gpt-3.5-turbo":     {ContextWindow: 16385, MaxOutputTokens: 4096, InputModalities: textOnly, OutputModalities: textOnly, SupportsTools: true},
+	"o1":                {ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"o3":                {ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"o4-mini":           {ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"claude-":           {ContextWindow: 200000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true},
+	"claude-3-7-sonnet": {ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"claude-sonnet-4":   {ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"claude-opus-4":     {ContextWindow: 200000, MaxOutputTokens: 32000, InputModalities: textAndImage, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"gemini-":           {ContextWindow: 1048576, InputModalities: geminiModals, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true},
+	"gemini-2.5":        {ContextWindow: 1048576, MaxOutputTokens: 65536, InputModalities: geminiModals, OutputModalities: textOnly, SupportsTools: true, SupportsVision: true, SupportsReasoning: true},
+	"deepseek-chat":     {ContextWindow: 128000, MaxOutputTokens: 8192, InputModalities: textOnly, OutputModalities: textOnly, SupportsTools: true},
+	"deepseek-reasoner": {ContextWindow: 128000, MaxOutputTokens: 65536, InputModalities: textOnly, OutputModalities: textOnly, SupportsReasoning: true},
+	"text-embedding-":   {ContextWindow: 8191, InputModalities: textOnly, OutputModalities: embeddingOnly},
+	"dall-e-":           {InputModalities: textOnly, OutputModalities: []string{"image"}},
+	"gpt-image-":        {InputModalities: textAndImage, OutputModalities: []string{"image"}, SupportsVision: true},
+	"whisper-":          {InputModalities: []string{"audio"}, OutputModalities: textOnly},
+	"tts-":              {InputModalities: textOnly, OutputModalities: []string{"audio"}},
+}
+
+func init() {
+	config.GlobalConfig.Register("model_capability_setting", &modelCapabilitySettings)
+}
+
+func GetModelCapabilitySettings() *ModelCapabilitySettings {
+	return &modelCapabilitySettings
+}
+
+// GetModelCapability 返回模型的能力元数据：优先使用管理员配置，其次按最长前缀匹配内置默认值
+func GetModelCapability(modelName string) (ModelCapability, bool) {
+	if capability, ok := modelCapabilitySettings.Capabilities[modelName]; ok {
+		return capability, true
+	}
+	lowerName := strings.ToLower(modelName)
+	matched := ""
+	for prefix := range builtinModelCapabilities {
+		if strings.HasPrefix(lowerName, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return ModelCapability{}, false
+	}
+	return builtinModelCapabilities[matched], true
+}
+
+func ValidateModelCapabilities(jsonStr string) error {
+	var capabilities map[string]ModelCapability
+	if err := common.UnmarshalJsonStr(jsonStr, &capabilities); err != nil {
+		return fmt.Errorf("模型能力配置格式错误: %v", err)
+	}
+	for name, capability := range capabilities {
+		if capability.ContextWindow < 0 || capability.MaxOutputTokens < 0 {
+			return fmt.Errorf("模型 %s 的上下文长度与最大输出不能为负数", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package model_setting
+
+import "testing"
+
+func TestGetModelCapability(t *testing.T) {
+	original := modelCapabilitySettings.Capabilities
+	defer func() { modelCapabilitySettings.Capabilities = original }()
+	modelCapabilitySettings.Capabilities = map[string]ModelCapability{
+		"gpt-4o-custom": {ContextWindow: 32000},
+	}
+
+	capability, ok := GetModelCapability("gpt-4o-custom")
+	if !ok || capability.ContextWindow != 32000 || capability.SupportsTools {
+		t.Fatalf("configured capability should take precedence, got %+v", capability)
+	}
+	capability, ok = GetModelCapability("claude-sonnet-4-20250514")
+	if !ok || capability.MaxOutputTokens != 64000 || !capability.SupportsReasoning {
+		t.Fatalf("expected the longest builtin prefix to match, got %+v", capability)
+	}
+	capability, ok = GetModelCapability("claude-3-5-haiku")
+	if !ok || capability.ContextWindow != 200000 || capability.SupportsReasoning {
+		t.Fatalf("expected the generic claude defaults, got %+v", capability)
+	}
+	if _, ok = GetModelCapability("my-private-model"); ok {
+		t.Fatal("unknown models should have no capability metadata")
+	}
+}
+
+func TestValidateModelCapabilities(t *testing.T) {
+	if err := ValidateModelCapabilities(`{"m":{"context_window":8192,"supports_tools":true}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateModelCapabilities(`{"m":{"context_window":-1}}`); err == nil {
+		t.Fatal("negative context window should be rejected")
+	}
+	if err := ValidateModelCapabilities(`[]`); err == nil {
+		t.Fatal("non-object config should be rejected")
+	}
+}
@@ -36,6 +36,9 @@ type GlobalSettings struct {
 	PassThroughRequestEnabled        bool                             `json:"pass_through_request_enabled"`
 	ThinkingModelBlacklist           []string                         `json:"thinking_model_blacklist"`
 	ChatCompletionsToResponsesPolicy ChatCompletionsToResponsesPolicy `json:"chat_completions_to_responses_policy"`
+	// ResponsesToChatCompletionsPolicy 命中的渠道与模型把 /v1/responses 请求转换为 Chat Completions 发往上游，
+	// 用于 OpenAI 类型但只兼容 Chat Completions 的第三方服务；不支持 Responses 的渠道类型总是转换
+	ResponsesToChatCompletionsPolicy ChatCompletionsToResponsesPolicy `json:"responses_to_chat_completions_policy"`
 }
 
 // 默认配置
@@ -49,6 +52,10 @@ var defaultOpenaiSettings = GlobalSettings{
 		Enabled:     false,
 		AllChannels: true,
 	},
+	ResponsesToChatCompletionsPolicy: ChatCompletionsToResponsesPolicy{
+		Enabled:     false,
+		AllChannels: false,
+	},
 }
 
 // 全局实例
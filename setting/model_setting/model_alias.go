@@ -0,0 +1,194 @@
+package model_setting
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 别名存在多个候选模型时的选择策略
+const (
+	// ModelAliasStrategyFirst 按顺序选择第一个可用的模型
+	ModelAliasStrategyFirst = "first"
+	// ModelAliasStrategyCheapest 选择可用模型中价格最低的
+	ModelAliasStrategyCheapest = "cheapest"
+)
+
+// 模型别名规则的匹配方式
+const (
+	// ModelAliasMatchWildcard 通配符匹配，* 匹配任意字符
+	ModelAliasMatchWildcard = "wildcard"
+	// ModelAliasMatchRegex 正则匹配，目标模型中可用 $1 等引用捕获组
+	ModelAliasMatchRegex = "regex"
+)
+
+// ModelAlias 全局模型别名，例如 gpt-4 -> gpt-4o-2024-11-20，或 fast -> 若干候选中最便宜的可用模型
+type ModelAlias struct {
+	Models   []string `json:"models"`
+	Strategy string   `json:"strategy,omitempty"`
+}
+
+type ModelAliasSettings struct {
+	Enabled bool `json:"enabled"`
+	// Aliases 客户端请求的模型名 -> 候选模型，在选择渠道前解析，计费与日志按解析后的模型记录
+	Aliases map[string]ModelAlias `json:"aliases"`
+	// Rules 按模式匹配的别名规则，精确别名未命中时按 Priority 从高到低匹配，同优先级按配置顺序
+	Rules []ModelAliasRule `json:"rules"`
+}
+
+// ModelAliasRule 模式别名，例如 claude-3-5-* -> claude-3-5-sonnet-latest
+type ModelAliasRule struct {
+	Pattern  string   `json:"pattern"`
+	Match    string   `json:"match,omitempty"`
+	Models   []string `json:"models"`
+	Strategy string   `json:"strategy,omitempty"`
+	Priority int      `json:"priority,omitempty"`
+}
+
+var modelAliasSettings = ModelAliasSettings{
+	Enabled: false,
+	Aliases: map[string]ModelAlias{},
+	Rules:   []ModelAliasRule{},
+}
+
+// 已编译的规则模式，键为匹配方式与模式
+var modelAliasPatternCache sync.Map
+
+func init() {
+	config.GlobalConfig.Register("model_alias_setting", &modelAliasSettings)
+}
+
+func GetModelAliasSettings() *ModelAliasSettings {
+	return &modelAliasSettings
+}
+
+// GetModelAlias 返回模型名对应的别名配置，未启用或不存在时返回 false
+func GetModelAlias(modelName string) (ModelAlias, bool) {
+	if !modelAliasSettings.Enabled || modelName == "" {
+		return ModelAlias{}, false
+	}
+	if alias, ok := modelAliasSettings.Aliases[modelName]; ok && len(alias.Models) > 0 {
+		return alias, true
+	}
+	return matchModelAliasRules(modelAliasSettings.Rules, modelName)
+}
+
+func matchModelAliasRules(rules []ModelAliasRule, modelName string) (ModelAlias, bool) {
+	if len(rules) == 0 {
+		return ModelAlias{}, false
+	}
+	ordered := make([]ModelAliasRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	for _, rule := range ordered {
+		if len(rule.Models) == 0 {
+			continue
+		}
+		re, err := compileModelAliasPattern(rule.Match, rule.Pattern)
+		if err != nil {
+			continue
+		}
+		match := re.FindStringSubmatchIndex(modelName)
+		if match == nil {
+			continue
+		}
+		models := rule.Models
+		if rule.Match == ModelAliasMatchRegex {
+			models = make([]string, 0, len(rule.Models))
+			for _, target := range rule.Models {
+				models = append(models, string(re.ExpandString(nil, target, modelName, match)))
+			}
+		}
+		return ModelAlias{Models: models, Strategy: rule.Strategy}, true
+	}
+	return ModelAlias{}, false
+}
+
+// compileModelAliasPattern 将规则模式编译为完整匹配的正则
+func compileModelAliasPattern(match string, pattern string) (*regexp.Regexp, error) {
+	cacheKey := match + ":" + pattern
+	if cached, ok := modelAliasPatternCache.Load(cacheKey); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	var expr string
+	switch match {
+	case "", ModelAliasMatchWildcard:
+		expr = "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	case ModelAliasMatchRegex:
+		expr = "^(?:" + pattern + ")$"
+	default:
+		return nil, fmt.Errorf("不支持的匹配方式：%s", match)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	modelAliasPatternCache.Store(cacheKey, re)
+	return re, nil
+}
+
+func ValidateModelAliases(jsonStr string) error {
+	var aliases map[string]ModelAlias
+	if err := common.UnmarshalJsonStr(jsonStr, &aliases); err != nil {
+		return fmt.Errorf("模型别名配置格式错误: %v", err)
+	}
+	for name, alias := range aliases {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("模型别名不能为空")
+		}
+		if len(alias.Models) == 0 {
+			return fmt.Errorf("模型别名 %s 未配置目标模型", name)
+		}
+		for _, target := range alias.Models {
+			if strings.TrimSpace(target) == "" {
+				return fmt.Errorf("模型别名 %s 的目标模型不能为空", name)
+			}
+			// 只解析一层，避免别名链与循环；允许指向自身，表示优先使用同名模型
+			if _, ok := aliases[target]; ok && target != name {
+				return fmt.Errorf("模型别名 %s 的目标 %s 不能是另一个别名", name, target)
+			}
+		}
+		switch alias.Strategy {
+		case "", ModelAliasStrategyFirst, ModelAliasStrategyCheapest:
+		default:
+			return fmt.Errorf("模型别名 %s 的策略 %s 无效", name, alias.Strategy)
+		}
+	}
+	return nil
+}
+
+func ValidateModelAliasRules(jsonStr string) error {
+	var rules []ModelAliasRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("模型别名规则格式错误: %v", err)
+	}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return fmt.Errorf("第 %d 条模型别名规则的模式不能为空", i+1)
+		}
+		if _, err := compileModelAliasPattern(rule.Match, rule.Pattern); err != nil {
+			return fmt.Errorf("模型别名规则 %s 无效: %v", rule.Pattern, err)
+		}
+		if len(rule.Models) == 0 {
+			return fmt.Errorf("模型别名规则 %s 未配置目标模型", rule.Pattern)
+		}
+		for _, target := range rule.Models {
+			if strings.TrimSpace(target) == "" {
+				return fmt.Errorf("模型别名规则 %s 的目标模型不能为空", rule.Pattern)
+			}
+		}
+		switch rule.Strategy {
+		case "", ModelAliasStrategyFirst, ModelAliasStrategyCheapest:
+		default:
+			return fmt.Errorf("模型别名规则 %s 的策略 %s 无效", rule.Pattern, rule.Strategy)
+		}
+	}
+	return nil
+}
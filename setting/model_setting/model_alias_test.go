@@ -0,0 +1,74 @@
+package model_setting
+
+import "testing"
+
+func TestValidateModelAliases(t *testing.T) {
+	valid := `{"gpt-4":{"models":["gpt-4","gpt-4o-2024-11-20"]},"fast":{"models":["gpt-4o-mini","claude-3-5-haiku"],"strategy":"cheapest"}}`
+	if err := ValidateModelAliases(valid); err != nil {
+		t.Fatalf("expected valid aliases, got %v", err)
+	}
+	invalid := []string{
+		`{"fast":{"models":[]}}`,
+		`{"fast":{"models":[" "]}}`,
+		`{"fast":{"models":["gpt-4o-mini"],"strategy":"random"}}`,
+		`{"fast":{"models":["smart"]},"smart":{"models":["gpt-4o"]}}`,
+	}
+	for _, raw := range invalid {
+		if err := ValidateModelAliases(raw); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestModelAliasRules(t *testing.T) {
+	original := modelAliasSettings
+	t.Cleanup(func() { modelAliasSettings = original })
+	modelAliasSettings = ModelAliasSettings{
+		Enabled: true,
+		Aliases: map[string]ModelAlias{"claude-3-5-haiku": {Models: []string{"claude-3-5-haiku-20241022"}}},
+		Rules: []ModelAliasRule{
+			{Pattern: "claude-3-5-*", Models: []string{"claude-3-5-sonnet-latest"}},
+			{Pattern: `gpt-4o-(\d{4}-\d{2}-\d{2})`, Match: ModelAliasMatchRegex, Models: []string{"azure-gpt-4o-$1"}, Priority: 10},
+			{Pattern: "gpt-4o-*", Models: []string{"gpt-4o"}},
+		},
+	}
+
+	cases := map[string]string{
+		"claude-3-5-sonnet":     "claude-3-5-sonnet-latest",
+		"claude-3-5-haiku":      "claude-3-5-haiku-20241022", // 精确别名优先于规则
+		"gpt-4o-2024-08-06":     "azure-gpt-4o-2024-08-06",   // 高优先级规则先匹配
+		"gpt-4o-mini":           "gpt-4o",
+		"xclaude-3-5-sonnet":    "",
+		"gpt-4o-2024-08-06-foo": "gpt-4o",
+	}
+	for name, want := range cases {
+		alias, ok := GetModelAlias(name)
+		if want == "" {
+			if ok {
+				t.Fatalf("expected %s not to match, got %v", name, alias.Models)
+			}
+			continue
+		}
+		if !ok || alias.Models[0] != want {
+			t.Fatalf("expected %s -> %s, got %v (%v)", name, want, alias.Models, ok)
+		}
+	}
+}
+
+func TestValidateModelAliasRules(t *testing.T) {
+	if err := ValidateModelAliasRules(`[{"pattern":"gpt-4o-*","models":["gpt-4o"]},{"pattern":"o(\\d)-.*","match":"regex","models":["o$1"]}]`); err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+	invalid := []string{
+		`[{"pattern":"","models":["gpt-4o"]}]`,
+		`[{"pattern":"gpt-(","match":"regex","models":["gpt-4o"]}]`,
+		`[{"pattern":"gpt-*","match":"glob","models":["gpt-4o"]}]`,
+		`[{"pattern":"gpt-*","models":[]}]`,
+		`[{"pattern":"gpt-*","models":["gpt-4o"],"strategy":"random"}]`,
+	}
+	for _, raw := range invalid {
+		if err := ValidateModelAliasRules(raw); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
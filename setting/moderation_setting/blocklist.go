@@ -0,0 +1,129 @@
+package moderation_setting
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 黑名单规则匹配方式
+const (
+	BlocklistTypeTerm  = "term"  // 精确词条，按字面匹配
+	BlocklistTypeRegex = "regex" // 正则表达式
+)
+
+// 黑名单命中后的处理动作
+const (
+	BlocklistActionReject  = "reject"  // 拒绝请求（输出阶段仅对非流式响应生效）
+	BlocklistActionReplace = "replace" // 替换命中内容后放行
+	BlocklistActionLog     = "log"     // 仅记录
+)
+
+const defaultBlocklistReplacement = "***"
+
+// BlocklistRule 一条黑名单规则
+type BlocklistRule struct {
+	Name          string   `json:"name"`
+	Enabled       bool     `json:"enabled"`
+	Type          string   `json:"type"`
+	Patterns      []string `json:"patterns"`
+	CaseSensitive bool     `json:"case_sensitive"`
+	Stage         string   `json:"stage"`
+	Action        string   `json:"action"`
+	// Replacement 为空时使用 ***，regex 规则支持 $1 等分组引用
+	Replacement string `json:"replacement,omitempty"`
+	// Groups 生效的用户分组，为空表示全部分组
+	Groups []string `json:"groups"`
+}
+
+type BlocklistSetting struct {
+	Enabled bool            `json:"enabled"`
+	Rules   []BlocklistRule `json:"rules"`
+}
+
+var blocklistSetting = BlocklistSetting{
+	Enabled: false,
+	Rules:   []BlocklistRule{},
+}
+
+func init() {
+	config.GlobalConfig.Register("blocklist_setting", &blocklistSetting)
+}
+
+func GetBlocklistSetting() *BlocklistSetting {
+	return &blocklistSetting
+}
+
+// BlocklistRulesFor 返回分组在指定阶段生效的规则
+func BlocklistRulesFor(group string, stage string) []BlocklistRule {
+	if !blocklistSetting.Enabled {
+		return nil
+	}
+	rules := make([]BlocklistRule, 0, len(blocklistSetting.Rules))
+	for _, rule := range blocklistSetting.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		if rule.Stage != StageBoth && rule.Stage != stage {
+			continue
+		}
+		if len(rule.Groups) > 0 && !common.StringsContains(rule.Groups, group) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// GetReplacement 返回规则的替换文本
+func (r BlocklistRule) GetReplacement() string {
+	if r.Replacement == "" {
+		return defaultBlocklistReplacement
+	}
+	return r.Replacement
+}
+
+// ValidateBlocklistRules 校验 JSON 数组形式的黑名单规则
+func ValidateBlocklistRules(jsonStr string) error {
+	var rules []BlocklistRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("黑名单规则格式错误: %v", err)
+	}
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("黑名单规则名称不能为空")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("黑名单规则名称重复: %s", rule.Name)
+		}
+		names[rule.Name] = true
+		switch rule.Stage {
+		case StageInput, StageOutput, StageBoth:
+		default:
+			return fmt.Errorf("黑名单规则 %s 的阶段无效: %s", rule.Name, rule.Stage)
+		}
+		switch rule.Action {
+		case BlocklistActionReject, BlocklistActionReplace, BlocklistActionLog:
+		default:
+			return fmt.Errorf("黑名单规则 %s 的处理动作无效: %s", rule.Name, rule.Action)
+		}
+		switch rule.Type {
+		case BlocklistTypeTerm:
+		case BlocklistTypeRegex:
+			for _, pattern := range rule.Patterns {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("黑名单规则 %s 的正则无效: %s", rule.Name, err.Error())
+				}
+			}
+		default:
+			return fmt.Errorf("黑名单规则 %s 的类型无效: %s", rule.Name, rule.Type)
+		}
+		if len(rule.Patterns) == 0 {
+			return fmt.Errorf("黑名单规则 %s 未配置匹配内容", rule.Name)
+		}
+	}
+	return nil
+}
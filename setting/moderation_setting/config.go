@@ -0,0 +1,186 @@
+package moderation_setting
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 审核步骤类型
+const (
+	StepTypeKeyword = "keyword" // 本地关键词规则
+	StepTypeOpenAI  = "openai"  // OpenAI 兼容的 /v1/moderations 接口
+	StepTypeHttp    = "http"    // 外部 HTTP 分类服务
+)
+
+// 审核阶段
+const (
+	StageInput  = "input"
+	StageOutput = "output"
+	StageBoth   = "both"
+)
+
+// 命中后的处理动作
+const (
+	ActionBlock = "block" // 拒绝请求（输出阶段仅对非流式响应生效）
+	ActionFlag  = "flag"  // 放行并在日志中标记
+	ActionLog   = "log"   // 仅记录系统日志
+)
+
+// Step 一个审核步骤
+type Step struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Stage   string `json:"stage"`
+	Enabled bool   `json:"enabled"`
+
+	// keyword：Keywords 忽略大小写子串匹配，Patterns 为忽略大小写的正则
+	Keywords []string `json:"keywords,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+
+	// openai：BaseUrl 为空时使用 https://api.openai.com
+	BaseUrl string `json:"base_url,omitempty"`
+	ApiKey  string `json:"api_key,omitempty"`
+	Model   string `json:"model,omitempty"`
+
+	// http：POST {"stage","group","model","text"}，期望返回 {"flagged","categories","reason"}
+	Url     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Policy 分组审核策略
+type Policy struct {
+	InputEnabled  bool   `json:"input_enabled"`
+	OutputEnabled bool   `json:"output_enabled"`
+	Action        string `json:"action"`
+	// Steps 参与审核的步骤名称，为空表示全部已启用步骤
+	Steps []string `json:"steps"`
+}
+
+type ModerationSetting struct {
+	Enabled       bool              `json:"enabled"`
+	Steps         []Step            `json:"steps"`
+	DefaultPolicy Policy            `json:"default_policy"`
+	GroupPolicies map[string]Policy `json:"group_policies"`
+}
+
+var moderationSetting = ModerationSetting{
+	Enabled: false,
+	Steps:   []Step{},
+	DefaultPolicy: Policy{
+		InputEnabled:  true,
+		OutputEnabled: false,
+		Action:        ActionBlock,
+		Steps:         []string{},
+	},
+	GroupPolicies: map[string]Policy{},
+}
+
+func init() {
+	config.GlobalConfig.Register("moderation_setting", &moderationSetting)
+}
+
+func GetModerationSetting() *ModerationSetting {
+	return &moderationSetting
+}
+
+// GetPolicy 返回分组的审核策略，未单独配置时使用默认策略
+func GetPolicy(group string) Policy {
+	if policy, ok := moderationSetting.GroupPolicies[group]; ok {
+		return policy
+	}
+	return moderationSetting.DefaultPolicy
+}
+
+// StepsFor 返回策略在指定阶段需要执行的步骤
+func StepsFor(policy Policy, stage string) []Step {
+	steps := make([]Step, 0, len(moderationSetting.Steps))
+	for _, step := range moderationSetting.Steps {
+		if !step.Enabled {
+			continue
+		}
+		if step.Stage != StageBoth && step.Stage != stage {
+			continue
+		}
+		if len(policy.Steps) > 0 && !common.StringsContains(policy.Steps, step.Name) {
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// ValidateSteps 校验 JSON 数组形式的步骤配置
+func ValidateSteps(jsonStr string) error {
+	var steps []Step
+	if err := common.UnmarshalJsonStr(jsonStr, &steps); err != nil {
+		return fmt.Errorf("审核步骤格式错误: %v", err)
+	}
+	names := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("审核步骤名称不能为空")
+		}
+		if names[step.Name] {
+			return fmt.Errorf("审核步骤名称重复: %s", step.Name)
+		}
+		names[step.Name] = true
+		switch step.Stage {
+		case StageInput, StageOutput, StageBoth:
+		default:
+			return fmt.Errorf("审核步骤 %s 的阶段无效: %s", step.Name, step.Stage)
+		}
+		switch step.Type {
+		case StepTypeKeyword:
+			for _, pattern := range step.Patterns {
+				if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+					return fmt.Errorf("审核步骤 %s 的正则 %s 无效: %v", step.Name, pattern, err)
+				}
+			}
+		case StepTypeOpenAI:
+			if step.ApiKey == "" {
+				return fmt.Errorf("审核步骤 %s 缺少 api_key", step.Name)
+			}
+		case StepTypeHttp:
+			if step.Url == "" {
+				return fmt.Errorf("审核步骤 %s 缺少 url", step.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePolicies 校验分组策略配置
+func ValidatePolicies(jsonStr string) error {
+	var policies map[string]Policy
+	if err := common.UnmarshalJsonStr(jsonStr, &policies); err != nil {
+		return fmt.Errorf("审核策略格式错误: %v", err)
+	}
+	for group, policy := range policies {
+		if err := validateAction(policy.Action); err != nil {
+			return fmt.Errorf("分组 %s: %v", group, err)
+		}
+	}
+	return nil
+}
+
+func ValidatePolicy(jsonStr string) error {
+	var policy Policy
+	if err := common.UnmarshalJsonStr(jsonStr, &policy); err != nil {
+		return fmt.Errorf("审核策略格式错误: %v", err)
+	}
+	return validateAction(policy.Action)
+}
+
+func validateAction(action string) error {
+	switch action {
+	case ActionBlock, ActionFlag, ActionLog:
+		return nil
+	default:
+		return fmt.Errorf("无效的处理动作: %s", action)
+	}
+}
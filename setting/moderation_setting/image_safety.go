@@ -0,0 +1,80 @@
+package moderation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 生成图片命中后的处理动作
+const (
+	ImageActionBlock = "block" // 拒绝整个响应
+	ImageActionBlur  = "blur"  // 对命中的图片做马赛克处理后返回
+	ImageActionLog   = "log"   // 仅记录
+	ImageActionOff   = "off"   // 不扫描
+)
+
+type ImageSafetySetting struct {
+	Enabled bool `json:"enabled"`
+	// UseProviderScores 读取上游返回的安全评分（如 Azure content_filter_results、nsfw_content_detected）
+	UseProviderScores bool `json:"use_provider_scores"`
+	// ClassifierUrl 外部分类服务，POST {"group","model","url"|"b64_json"}，期望返回 {"flagged","score","categories"}
+	ClassifierUrl       string            `json:"classifier_url"`
+	ClassifierHeaders   map[string]string `json:"classifier_headers"`
+	ClassifierThreshold float64           `json:"classifier_threshold"`
+	TimeoutSeconds      int               `json:"timeout_seconds"`
+	DefaultAction       string            `json:"default_action"`
+	GroupActions        map[string]string `json:"group_actions"`
+}
+
+var imageSafetySetting = ImageSafetySetting{
+	Enabled:             false,
+	UseProviderScores:   true,
+	ClassifierHeaders:   map[string]string{},
+	ClassifierThreshold: 0.8,
+	TimeoutSeconds:      15,
+	DefaultAction:       ImageActionBlock,
+	GroupActions:        map[string]string{},
+}
+
+func init() {
+	config.GlobalConfig.Register("image_safety_setting", &imageSafetySetting)
+}
+
+func GetImageSafetySetting() *ImageSafetySetting {
+	return &imageSafetySetting
+}
+
+// GetImageSafetyAction 返回分组的图片安全处理动作，未启用时返回 off
+func GetImageSafetyAction(group string) string {
+	if !imageSafetySetting.Enabled {
+		return ImageActionOff
+	}
+	if action, ok := imageSafetySetting.GroupActions[group]; ok {
+		return action
+	}
+	return imageSafetySetting.DefaultAction
+}
+
+func ValidateImageSafetyAction(action string) error {
+	switch action {
+	case ImageActionBlock, ImageActionBlur, ImageActionLog, ImageActionOff:
+		return nil
+	default:
+		return fmt.Errorf("无效的图片处理动作: %s", action)
+	}
+}
+
+func ValidateImageSafetyGroupActions(jsonStr string) error {
+	var actions map[string]string
+	if err := common.UnmarshalJsonStr(jsonStr, &actions); err != nil {
+		return fmt.Errorf("分组图片处理动作格式错误: %v", err)
+	}
+	for group, action := range actions {
+		if err := ValidateImageSafetyAction(action); err != nil {
+			return fmt.Errorf("分组 %s: %v", group, err)
+		}
+	}
+	return nil
+}
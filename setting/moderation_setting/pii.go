@@ -0,0 +1,106 @@
+package moderation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// PII 类型
+const (
+	PiiTypeEmail      = "email"
+	PiiTypePhone      = "phone"
+	PiiTypeIdNumber   = "id_number"
+	PiiTypeCreditCard = "credit_card"
+)
+
+// PII 脱敏模式
+const (
+	PiiModeMask       = "mask"       // 不可逆，替换为 [EMAIL] 等类型占位符
+	PiiModeReversible = "reversible" // 可逆，替换为 [EMAIL_1] 等编号占位符，并在响应中还原
+)
+
+var AllPiiTypes = []string{PiiTypeEmail, PiiTypePhone, PiiTypeIdNumber, PiiTypeCreditCard}
+
+// PiiPolicy 分组 PII 脱敏策略
+type PiiPolicy struct {
+	Enabled bool   `json:"enabled"`
+	Mode    string `json:"mode"`
+	// Types 需要检测的类型，为空表示全部
+	Types []string `json:"types"`
+}
+
+type PiiSetting struct {
+	Enabled       bool                 `json:"enabled"`
+	DefaultPolicy PiiPolicy            `json:"default_policy"`
+	GroupPolicies map[string]PiiPolicy `json:"group_policies"`
+}
+
+var piiSetting = PiiSetting{
+	Enabled: false,
+	DefaultPolicy: PiiPolicy{
+		Enabled: true,
+		Mode:    PiiModeMask,
+		Types:   []string{},
+	},
+	GroupPolicies: map[string]PiiPolicy{},
+}
+
+func init() {
+	config.GlobalConfig.Register("pii_setting", &piiSetting)
+}
+
+func GetPiiSetting() *PiiSetting {
+	return &piiSetting
+}
+
+// GetPiiPolicy 返回分组生效的 PII 策略，ok 为 false 表示该分组无需脱敏
+func GetPiiPolicy(group string) (PiiPolicy, bool) {
+	if !piiSetting.Enabled {
+		return PiiPolicy{}, false
+	}
+	policy, exists := piiSetting.GroupPolicies[group]
+	if !exists {
+		policy = piiSetting.DefaultPolicy
+	}
+	return policy, policy.Enabled
+}
+
+// PiiTypeEnabled 判断策略是否检测指定类型
+func (p PiiPolicy) PiiTypeEnabled(piiType string) bool {
+	return len(p.Types) == 0 || common.StringsContains(p.Types, piiType)
+}
+
+func ValidatePiiPolicy(jsonStr string) error {
+	var policy PiiPolicy
+	if err := common.UnmarshalJsonStr(jsonStr, &policy); err != nil {
+		return fmt.Errorf("PII 策略格式错误: %v", err)
+	}
+	return validatePiiPolicy(policy)
+}
+
+func ValidatePiiPolicies(jsonStr string) error {
+	var policies map[string]PiiPolicy
+	if err := common.UnmarshalJsonStr(jsonStr, &policies); err != nil {
+		return fmt.Errorf("PII 策略格式错误: %v", err)
+	}
+	for group, policy := range policies {
+		if err := validatePiiPolicy(policy); err != nil {
+			return fmt.Errorf("分组 %s: %v", group, err)
+		}
+	}
+	return nil
+}
+
+func validatePiiPolicy(policy PiiPolicy) error {
+	if policy.Mode != PiiModeMask && policy.Mode != PiiModeReversible {
+		return fmt.Errorf("无效的脱敏模式: %s", policy.Mode)
+	}
+	for _, t := range policy.Types {
+		if !common.StringsContains(AllPiiTypes, t) {
+			return fmt.Errorf("未知的 PII 类型: %s", t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package moderation_setting
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// PromptInjectionRule 自定义的提示词注入检测规则，命中后累加 Weight 分
+type PromptInjectionRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Weight  int    `json:"weight"`
+}
+
+type PromptInjectionSetting struct {
+	Enabled bool `json:"enabled"`
+	// BlockThreshold 风险分（0-100）达到该值时拒绝请求，0 表示只记录不拦截
+	BlockThreshold int `json:"block_threshold"`
+	// GroupThresholds 按分组覆盖拦截阈值
+	GroupThresholds map[string]int `json:"group_thresholds"`
+	// ExtraRules 追加到内置启发式规则之后
+	ExtraRules []PromptInjectionRule `json:"extra_rules"`
+}
+
+var promptInjectionSetting = PromptInjectionSetting{
+	Enabled:         false,
+	BlockThreshold:  0,
+	GroupThresholds: map[string]int{},
+	ExtraRules:      []PromptInjectionRule{},
+}
+
+func init() {
+	config.GlobalConfig.Register("prompt_injection_setting", &promptInjectionSetting)
+}
+
+func GetPromptInjectionSetting() *PromptInjectionSetting {
+	return &promptInjectionSetting
+}
+
+// GetPromptInjectionThreshold 返回分组的拦截阈值
+func GetPromptInjectionThreshold(group string) int {
+	if threshold, ok := promptInjectionSetting.GroupThresholds[group]; ok {
+		return threshold
+	}
+	return promptInjectionSetting.BlockThreshold
+}
+
+// ValidatePromptInjectionRules 校验 JSON 数组形式的自定义规则
+func ValidatePromptInjectionRules(jsonStr string) error {
+	var rules []PromptInjectionRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("提示词注入规则格式错误: %v", err)
+	}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("提示词注入规则名称不能为空")
+		}
+		if rule.Weight <= 0 || rule.Weight > 100 {
+			return fmt.Errorf("提示词注入规则 %s 的分值需在 1-100 之间", rule.Name)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("提示词注入规则 %s 的正则无效: %s", rule.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// ValidatePromptInjectionThresholds 校验分组阈值配置
+func ValidatePromptInjectionThresholds(jsonStr string) error {
+	var thresholds map[string]int
+	if err := common.UnmarshalJsonStr(jsonStr, &thresholds); err != nil {
+		return fmt.Errorf("分组阈值格式错误: %v", err)
+	}
+	for group, threshold := range thresholds {
+		if threshold < 0 || threshold > 100 {
+			return fmt.Errorf("分组 %s 的阈值需在 0-100 之间", group)
+		}
+	}
+	return nil
+}
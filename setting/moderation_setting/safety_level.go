@@ -0,0 +1,116 @@
+package moderation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 安全等级，空字符串表示使用上游默认设置
+const (
+	SafetyLevelStrict   = "strict"
+	SafetyLevelModerate = "moderate"
+	SafetyLevelRelaxed  = "relaxed"
+)
+
+// SafetyLevelSetting 统一管理用户/分组的安全等级，并映射到各上游的安全参数
+type SafetyLevelSetting struct {
+	Enabled      bool              `json:"enabled"`
+	DefaultLevel string            `json:"default_level"`
+	GroupLevels  map[string]string `json:"group_levels"`
+	// GeminiThresholds 等级 -> Gemini safetySettings 的 threshold，应用于全部安全类别
+	GeminiThresholds map[string]string `json:"gemini_thresholds"`
+	// AzurePolicyIds 等级 -> Azure OpenAI 内容筛选配置名称，通过 x-policy-id 请求头在请求时指定
+	AzurePolicyIds map[string]string `json:"azure_policy_ids"`
+	// ClaudeSystemPrompts 等级 -> 追加到 Claude 请求 system 前的安全约束
+	ClaudeSystemPrompts map[string]string `json:"claude_system_prompts"`
+}
+
+var safetyLevelSetting = SafetyLevelSetting{
+	Enabled:      false,
+	DefaultLevel: "",
+	GroupLevels:  map[string]string{},
+	GeminiThresholds: map[string]string{
+		SafetyLevelStrict:   "BLOCK_LOW_AND_ABOVE",
+		SafetyLevelModerate: "BLOCK_MEDIUM_AND_ABOVE",
+		SafetyLevelRelaxed:  "BLOCK_ONLY_HIGH",
+	},
+	AzurePolicyIds: map[string]string{},
+	ClaudeSystemPrompts: map[string]string{
+		SafetyLevelStrict: "You must refuse requests involving violence, self-harm, sexual content, hate speech or illegal activities, and keep all responses suitable for a general audience.",
+	},
+}
+
+func init() {
+	config.GlobalConfig.Register("safety_level_setting", &safetyLevelSetting)
+}
+
+func GetSafetyLevelSetting() *SafetyLevelSetting {
+	return &safetyLevelSetting
+}
+
+// ResolveSafetyLevel 返回生效的安全等级：用户设置优先，其次分组，最后默认等级
+func ResolveSafetyLevel(userLevel string, group string) string {
+	if !safetyLevelSetting.Enabled {
+		return ""
+	}
+	if userLevel != "" {
+		return userLevel
+	}
+	if level, ok := safetyLevelSetting.GroupLevels[group]; ok {
+		return level
+	}
+	return safetyLevelSetting.DefaultLevel
+}
+
+func ValidateSafetyLevel(level string) error {
+	switch level {
+	case "", SafetyLevelStrict, SafetyLevelModerate, SafetyLevelRelaxed:
+		return nil
+	default:
+		return fmt.Errorf("无效的安全等级: %s", level)
+	}
+}
+
+// ValidateSafetyLevelMap 校验以安全等级或分组为键、安全等级为值的配置
+func ValidateSafetyLevelMap(jsonStr string, keyIsLevel bool) error {
+	var m map[string]string
+	if err := common.UnmarshalJsonStr(jsonStr, &m); err != nil {
+		return fmt.Errorf("安全等级配置格式错误: %v", err)
+	}
+	for k, v := range m {
+		level := v
+		if keyIsLevel {
+			level = k
+		}
+		if err := ValidateSafetyLevel(level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetGeminiSafetyThreshold 返回安全等级对应的 Gemini threshold，未配置时返回空字符串
+func GetGeminiSafetyThreshold(level string) string {
+	if level == "" {
+		return ""
+	}
+	return safetyLevelSetting.GeminiThresholds[level]
+}
+
+// GetAzurePolicyId 返回安全等级对应的 Azure 内容筛选配置名称
+func GetAzurePolicyId(level string) string {
+	if level == "" {
+		return ""
+	}
+	return safetyLevelSetting.AzurePolicyIds[level]
+}
+
+// GetClaudeSafetyPrompt 返回安全等级对应的 Claude 安全约束提示词
+func GetClaudeSafetyPrompt(level string) string {
+	if level == "" {
+		return ""
+	}
+	return safetyLevelSetting.ClaudeSystemPrompts[level]
+}
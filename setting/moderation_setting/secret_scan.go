@@ -0,0 +1,84 @@
+package moderation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 检测到密钥后的处理动作
+const (
+	SecretActionBlock  = "block"  // 拒绝请求
+	SecretActionRedact = "redact" // 替换为 [REDACTED_SECRET:类型] 后放行
+	SecretActionLog    = "log"    // 仅记录
+)
+
+type SecretScanSetting struct {
+	Enabled bool   `json:"enabled"`
+	Action  string `json:"action"`
+	// GroupActions 按分组覆盖处理动作
+	GroupActions map[string]string `json:"group_actions"`
+	// ScanFiles 是否扫描请求中以 base64 内联的文本类文件
+	ScanFiles bool `json:"scan_files"`
+	// DetectHighEntropy 是否检测无固定前缀的高熵字符串
+	DetectHighEntropy bool    `json:"detect_high_entropy"`
+	EntropyThreshold  float64 `json:"entropy_threshold"`
+	EntropyMinLength  int     `json:"entropy_min_length"`
+	// 同一用户在 AlertWindowMinutes 内累计命中 AlertThreshold 次时发送告警事件，0 表示不告警
+	AlertThreshold     int `json:"alert_threshold"`
+	AlertWindowMinutes int `json:"alert_window_minutes"`
+}
+
+var secretScanSetting = SecretScanSetting{
+	Enabled:            false,
+	Action:             SecretActionRedact,
+	GroupActions:       map[string]string{},
+	ScanFiles:          true,
+	DetectHighEntropy:  false,
+	EntropyThreshold:   4.5,
+	EntropyMinLength:   32,
+	AlertThreshold:     5,
+	AlertWindowMinutes: 60,
+}
+
+func init() {
+	config.GlobalConfig.Register("secret_scan_setting", &secretScanSetting)
+}
+
+func GetSecretScanSetting() *SecretScanSetting {
+	return &secretScanSetting
+}
+
+// GetSecretScanAction 返回分组的处理动作，未启用时返回空字符串
+func GetSecretScanAction(group string) string {
+	if !secretScanSetting.Enabled {
+		return ""
+	}
+	if action, ok := secretScanSetting.GroupActions[group]; ok {
+		return action
+	}
+	return secretScanSetting.Action
+}
+
+func ValidateSecretScanAction(action string) error {
+	switch action {
+	case SecretActionBlock, SecretActionRedact, SecretActionLog:
+		return nil
+	default:
+		return fmt.Errorf("无效的处理动作: %s", action)
+	}
+}
+
+func ValidateSecretScanGroupActions(jsonStr string) error {
+	var actions map[string]string
+	if err := common.UnmarshalJsonStr(jsonStr, &actions); err != nil {
+		return fmt.Errorf("分组处理动作格式错误: %v", err)
+	}
+	for group, action := range actions {
+		if err := ValidateSecretScanAction(action); err != nil {
+			return fmt.Errorf("分组 %s: %v", group, err)
+		}
+	}
+	return nil
+}
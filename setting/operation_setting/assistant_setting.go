@@ -0,0 +1,29 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// AssistantSetting Assistants/Threads API 透传，资源创建后固定在所选渠道上
+type AssistantSetting struct {
+	Enabled bool `json:"enabled"`
+	// 创建线程时未指定助手且用户尚无助手，按该模型选择渠道
+	DefaultModel string `json:"default_model"`
+	// 创建运行时预扣的额度，运行结束后按上游报告的用量多退少补
+	RunPreConsumeQuota int `json:"run_pre_consume_quota"`
+	// 后台轮询未结算运行状态的间隔（秒）
+	RunPollIntervalSeconds int `json:"run_poll_interval_seconds"`
+}
+
+var assistantSetting = AssistantSetting{
+	Enabled:                false,
+	DefaultModel:           "gpt-4o-mini",
+	RunPreConsumeQuota:     50000,
+	RunPollIntervalSeconds: 30,
+}
+
+func init() {
+	config.GlobalConfig.Register("assistant_setting", &assistantSetting)
+}
+
+func GetAssistantSetting() *AssistantSetting {
+	return &assistantSetting
+}
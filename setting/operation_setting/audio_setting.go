@@ -0,0 +1,48 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// AudioSetting 语音转写/翻译（/v1/audio/transcriptions、/v1/audio/translations）上传限制与语音合成计费
+type AudioSetting struct {
+	// 单个音频文件的默认大小上限（MB），0 表示不限制
+	MaxFileSizeMB int `json:"max_file_size_mb"`
+	// 按分组覆盖的大小上限（MB），未配置的分组使用默认值
+	GroupMaxFileSizeMB map[string]int `json:"group_max_file_size_mb"`
+	// 语音合成（/v1/audio/speech）按输入字符计费的模型价格（美元/百万字符），未配置的模型按音频时长计费
+	SpeechCharacterPrice map[string]float64 `json:"speech_character_price"`
+}
+
+var audioSetting = AudioSetting{
+	MaxFileSizeMB:        25,
+	GroupMaxFileSizeMB:   map[string]int{},
+	SpeechCharacterPrice: map[string]float64{},
+}
+
+func init() {
+	config.GlobalConfig.Register("audio_setting", &audioSetting)
+}
+
+func GetAudioSetting() *AudioSetting {
+	return &audioSetting
+}
+
+// MaxFileBytes 返回分组的音频文件大小上限（字节），0 表示不限制
+func (s *AudioSetting) MaxFileBytes(group string) int64 {
+	size := s.MaxFileSizeMB
+	if groupSize, ok := s.GroupMaxFileSizeMB[group]; ok {
+		size = groupSize
+	}
+	if size <= 0 {
+		return 0
+	}
+	return int64(size) << 20
+}
+
+// SpeechPricePerCharacter 返回模型每个输入字符的价格（美元），未配置时返回 false
+func (s *AudioSetting) SpeechPricePerCharacter(model string) (float64, bool) {
+	price, ok := s.SpeechCharacterPrice[model]
+	if !ok || price <= 0 {
+		return 0, false
+	}
+	return price / 1000000, true
+}
@@ -0,0 +1,32 @@
+package operation_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioSettingMaxFileBytes(t *testing.T) {
+	setting := &AudioSetting{
+		MaxFileSizeMB:      25,
+		GroupMaxFileSizeMB: map[string]int{"vip": 100, "free": 0},
+	}
+	require.Equal(t, int64(25<<20), setting.MaxFileBytes("default"))
+	require.Equal(t, int64(100<<20), setting.MaxFileBytes("vip"))
+	require.Equal(t, int64(0), setting.MaxFileBytes("free"))
+
+	setting.MaxFileSizeMB = 0
+	require.Equal(t, int64(0), setting.MaxFileBytes("default"))
+}
+
+func TestAudioSettingSpeechPricePerCharacter(t *testing.T) {
+	setting := &AudioSetting{SpeechCharacterPrice: map[string]float64{"tts-1": 15, "tts-free": 0}}
+	price, ok := setting.SpeechPricePerCharacter("tts-1")
+	require.True(t, ok)
+	require.InDelta(t, 0.000015, price, 1e-12)
+
+	_, ok = setting.SpeechPricePerCharacter("tts-free")
+	require.False(t, ok)
+	_, ok = setting.SpeechPricePerCharacter("gpt-4o-mini-tts")
+	require.False(t, ok)
+}
@@ -0,0 +1,40 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// BatchSetting 离线批量推理：用户上传 JSONL 后由主节点在后台逐条调用中继接口，按折扣计费
+type BatchSetting struct {
+	Enabled bool `json:"enabled"`
+	// 全局同时执行的请求数上限
+	MaxConcurrency int `json:"max_concurrency"`
+	// 单个批次的最大行数与文件大小（MB）
+	MaxLines       int `json:"max_lines"`
+	MaxInputSizeMB int `json:"max_input_size_mb"`
+	// 单条请求因限流或上游错误失败后的最大尝试次数
+	MaxAttempts int `json:"max_attempts"`
+	// 计费折扣，与分组倍率相乘，例如 0.5 表示半价
+	DiscountRatio float64 `json:"discount_ratio"`
+	// 批次提交后的完成时限（小时），超时未执行的请求标记为过期
+	CompletionWindowHours int `json:"completion_window_hours"`
+	// 已结束批次及其结果的保留天数
+	RetentionDays int `json:"retention_days"`
+}
+
+var batchSetting = BatchSetting{
+	Enabled:               false,
+	MaxConcurrency:        8,
+	MaxLines:              10000,
+	MaxInputSizeMB:        50,
+	MaxAttempts:           3,
+	DiscountRatio:         0.5,
+	CompletionWindowHours: 24,
+	RetentionDays:         7,
+}
+
+func init() {
+	config.GlobalConfig.Register("batch_setting", &batchSetting)
+}
+
+func GetBatchSetting() *BatchSetting {
+	return &batchSetting
+}
@@ -0,0 +1,29 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// BudgetAlertSetting 月度预算提醒配置，用户或令牌本月消费越过阈值时通知用户
+type BudgetAlertSetting struct {
+	Enabled bool `json:"enabled"`
+	// DefaultMonthlyBudgetQuota 用户未设置预算时使用的默认月度预算，0 表示不提醒
+	DefaultMonthlyBudgetQuota int `json:"default_monthly_budget_quota"`
+	// Thresholds 提醒阈值（预算百分比），用户可单独覆盖
+	Thresholds []int `json:"thresholds"`
+	// CooldownMinutes 同一预算同一阈值的提醒冷却时间
+	CooldownMinutes int `json:"cooldown_minutes"`
+}
+
+var budgetAlertSetting = BudgetAlertSetting{
+	Enabled:                   false,
+	DefaultMonthlyBudgetQuota: 0,
+	Thresholds:                []int{50, 80, 100},
+	CooldownMinutes:           1440,
+}
+
+func init() {
+	config.GlobalConfig.Register("budget_alert_setting", &budgetAlertSetting)
+}
+
+func GetBudgetAlertSetting() *BudgetAlertSetting {
+	return &budgetAlertSetting
+}
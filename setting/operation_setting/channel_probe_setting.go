@@ -0,0 +1,72 @@
+package operation_setting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	ChannelProbeActionDisable       = "disable"
+	ChannelProbeActionLowerPriority = "lower_priority"
+)
+
+// ChannelProbeSetting 渠道定时拨测：按间隔向每个启用渠道的每个模型发送测试提示词，记录结果，
+// 某个模型连续失败达到阈值后禁用渠道或降低其优先级
+type ChannelProbeSetting struct {
+	Enabled         bool    `json:"enabled"`
+	IntervalMinutes float64 `json:"interval_minutes"`
+	Prompt          string  `json:"prompt"`
+	// 每个渠道最多拨测的模型数，0 表示全部
+	MaxModelsPerChannel int `json:"max_models_per_channel"`
+	FailureThreshold    int `json:"failure_threshold"`
+	// 达到阈值后的处理方式：disable 或 lower_priority
+	Action string `json:"action"`
+	// lower_priority 时优先级降低的幅度，渠道拨测全部恢复后还原
+	PriorityPenalty int64 `json:"priority_penalty"`
+	// 拨测记录保留天数
+	RetentionDays int `json:"retention_days"`
+}
+
+var channelProbeSetting = ChannelProbeSetting{
+	Enabled:             false,
+	IntervalMinutes:     10,
+	Prompt:              "hi",
+	MaxModelsPerChannel: 0,
+	FailureThreshold:    3,
+	Action:              ChannelProbeActionDisable,
+	PriorityPenalty:     100,
+	RetentionDays:       7,
+}
+
+func init() {
+	config.GlobalConfig.Register("channel_probe_setting", &channelProbeSetting)
+}
+
+func GetChannelProbeSetting() *ChannelProbeSetting {
+	return &channelProbeSetting
+}
+
+func (s *ChannelProbeSetting) Interval() time.Duration {
+	if s.IntervalMinutes <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(s.IntervalMinutes * float64(time.Minute))
+}
+
+func (s *ChannelProbeSetting) GetFailureThreshold() int {
+	if s.FailureThreshold <= 0 {
+		return 3
+	}
+	return s.FailureThreshold
+}
+
+func ValidateChannelProbeAction(action string) error {
+	switch action {
+	case ChannelProbeActionDisable, ChannelProbeActionLowerPriority:
+		return nil
+	default:
+		return fmt.Errorf("不支持的拨测处理方式：%s", action)
+	}
+}
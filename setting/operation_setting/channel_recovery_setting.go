@@ -0,0 +1,55 @@
+package operation_setting
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ChannelRecoverySetting 自动禁用渠道的恢复检测：按指数退避重新测试，测试通过后自动启用
+type ChannelRecoverySetting struct {
+	Enabled bool `json:"enabled"`
+	// 首次重试间隔，之后每次失败乘以 BackoffMultiplier，直到 MaxIntervalSeconds
+	InitialIntervalSeconds int     `json:"initial_interval_seconds"`
+	MaxIntervalSeconds     int     `json:"max_interval_seconds"`
+	BackoffMultiplier      float64 `json:"backoff_multiplier"`
+}
+
+var channelRecoverySetting = ChannelRecoverySetting{
+	Enabled:                false,
+	InitialIntervalSeconds: 60,
+	MaxIntervalSeconds:     3600,
+	BackoffMultiplier:      2,
+}
+
+func init() {
+	config.GlobalConfig.Register("channel_recovery_setting", &channelRecoverySetting)
+}
+
+func GetChannelRecoverySetting() *ChannelRecoverySetting {
+	return &channelRecoverySetting
+}
+
+// BackoffDelay 返回第 attempts 次失败后到下一次重试的间隔，attempts 从 0 开始
+func (s *ChannelRecoverySetting) BackoffDelay(attempts int) time.Duration {
+	initial := time.Duration(s.InitialIntervalSeconds) * time.Second
+	if initial <= 0 {
+		initial = time.Minute
+	}
+	maxDelay := time.Duration(s.MaxIntervalSeconds) * time.Second
+	if maxDelay < initial {
+		maxDelay = initial
+	}
+	multiplier := s.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(initial)
+	for i := 0; i < attempts; i++ {
+		delay *= multiplier
+		if delay >= float64(maxDelay) {
+			return maxDelay
+		}
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,22 @@
+package operation_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelRecoveryBackoffDelay(t *testing.T) {
+	s := &ChannelRecoverySetting{InitialIntervalSeconds: 60, MaxIntervalSeconds: 600, BackoffMultiplier: 2}
+	require.Equal(t, time.Minute, s.BackoffDelay(0))
+	require.Equal(t, 2*time.Minute, s.BackoffDelay(1))
+	require.Equal(t, 8*time.Minute, s.BackoffDelay(3))
+	require.Equal(t, 10*time.Minute, s.BackoffDelay(4))
+	require.Equal(t, 10*time.Minute, s.BackoffDelay(100))
+
+	// 非法配置回退到安全值
+	s = &ChannelRecoverySetting{}
+	require.Equal(t, time.Minute, s.BackoffDelay(0))
+	require.Equal(t, time.Minute, s.BackoffDelay(5))
+}
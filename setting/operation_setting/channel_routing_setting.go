@@ -0,0 +1,52 @@
+package operation_setting
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	// ChannelRoutingModeWeighted 同优先级渠道按权重随机选择
+	ChannelRoutingModeWeighted = "weighted"
+	// ChannelRoutingModeLatency 同优先级渠道优先选择延迟最低的健康渠道
+	ChannelRoutingModeLatency = "latency"
+)
+
+// ChannelRoutingSetting 同优先级渠道的选择方式
+type ChannelRoutingSetting struct {
+	Mode string `json:"mode"`
+	// 统计延迟与错误率的滚动窗口（秒），窗口外的样本不再参与计算
+	WindowSeconds int `json:"window_seconds"`
+	// 渠道在窗口内的最少样本数，有渠道样本不足时按权重随机选择以继续采样
+	MinSamples int `json:"min_samples"`
+	// 健康渠道允许的最大错误率（0-1）
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+var channelRoutingSetting = ChannelRoutingSetting{
+	Mode:          ChannelRoutingModeWeighted,
+	WindowSeconds: 600,
+	MinSamples:    20,
+	MaxErrorRate:  0.2,
+}
+
+func init() {
+	config.GlobalConfig.Register("channel_routing_setting", &channelRoutingSetting)
+}
+
+func GetChannelRoutingSetting() *ChannelRoutingSetting {
+	return &channelRoutingSetting
+}
+
+func (s *ChannelRoutingSetting) IsLatencyMode() bool {
+	return s.Mode == ChannelRoutingModeLatency
+}
+
+// Window 返回滚动窗口时长，未配置时为 10 分钟
+func (s *ChannelRoutingSetting) Window() time.Duration {
+	if s.WindowSeconds <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(s.WindowSeconds) * time.Second
+}
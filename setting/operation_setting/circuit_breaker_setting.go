@@ -0,0 +1,64 @@
+package operation_setting
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// CircuitBreakerSetting 渠道+模型熔断：窗口内连续失败达到阈值后熔断，冷却后放行探测请求
+type CircuitBreakerSetting struct {
+	Enabled bool `json:"enabled"`
+	// 触发熔断的连续失败次数
+	FailureThreshold int `json:"failure_threshold"`
+	// 连续失败需发生在该窗口（秒）内，距上次失败超过窗口时重新计数
+	WindowSeconds int `json:"window_seconds"`
+	// 熔断后等待多久（秒）进入半开状态
+	OpenSeconds int `json:"open_seconds"`
+	// 半开状态下同时放行的探测请求数
+	HalfOpenProbes int `json:"half_open_probes"`
+}
+
+var circuitBreakerSetting = CircuitBreakerSetting{
+	Enabled:          false,
+	FailureThreshold: 5,
+	WindowSeconds:    60,
+	OpenSeconds:      30,
+	HalfOpenProbes:   1,
+}
+
+func init() {
+	config.GlobalConfig.Register("circuit_breaker_setting", &circuitBreakerSetting)
+}
+
+func GetCircuitBreakerSetting() *CircuitBreakerSetting {
+	return &circuitBreakerSetting
+}
+
+func (s *CircuitBreakerSetting) Window() time.Duration {
+	if s.WindowSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(s.WindowSeconds) * time.Second
+}
+
+func (s *CircuitBreakerSetting) OpenDuration() time.Duration {
+	if s.OpenSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.OpenSeconds) * time.Second
+}
+
+func (s *CircuitBreakerSetting) GetFailureThreshold() int {
+	if s.FailureThreshold <= 0 {
+		return 5
+	}
+	return s.FailureThreshold
+}
+
+func (s *CircuitBreakerSetting) GetHalfOpenProbes() int {
+	if s.HalfOpenProbes <= 0 {
+		return 1
+	}
+	return s.HalfOpenProbes
+}
@@ -0,0 +1,45 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// DatasetSetting 对话数据集采集：按采样率记录聊天请求与回复，供管理员筛选、清洗后导出
+type DatasetSetting struct {
+	CaptureEnabled bool `json:"capture_enabled"`
+	// 采样率，取值 0~1
+	SampleRate float64 `json:"sample_rate"`
+	// 仅采集这些模型，为空时采集全部
+	Models []string `json:"models"`
+	// 客户端用于回传评分的请求头，评分为整数
+	RatingHeader string `json:"rating_header"`
+	// 单条对话（请求与回复合计）最大字节数，超出则不采集
+	MaxSampleBytes int `json:"max_sample_bytes"`
+}
+
+var datasetSetting = DatasetSetting{
+	CaptureEnabled: false,
+	SampleRate:     0.1,
+	Models:         []string{},
+	RatingHeader:   "X-Rating",
+	MaxSampleBytes: 256 << 10,
+}
+
+func init() {
+	config.GlobalConfig.Register("dataset_setting", &datasetSetting)
+}
+
+func GetDatasetSetting() *DatasetSetting {
+	return &datasetSetting
+}
+
+// ShouldCaptureModel 判断模型是否在采集范围内
+func (s *DatasetSetting) ShouldCaptureModel(modelName string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, m := range s.Models {
+		if m == modelName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+const (
+	FileStorageDatabase = "database"
+	FileStorageLocal    = "local"
+	FileStorageS3       = "s3"
+)
+
+// FileSetting /v1/files 文件上传与存储
+type FileSetting struct {
+	Enabled bool `json:"enabled"`
+	// 存储位置：local（默认，本机目录）、s3（S3 兼容对象存储，多节点部署推荐）、database（存入数据库，仅适合小文件）
+	StorageType string `json:"storage_type"`
+	LocalPath   string `json:"local_path"`
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3AccessKey string `json:"s3_access_key"`
+	S3SecretKey string `json:"s3_secret_key"`
+	// 使用路径风格（endpoint/bucket/key）访问，MinIO 等自建存储通常需要开启
+	S3PathStyle bool `json:"s3_path_style"`
+	// 单个文件大小上限（MB）
+	MaxFileSizeMB int `json:"max_file_size_mb"`
+	// 每个用户的文件存储总量上限（MB），0 表示不限制
+	UserQuotaMB int `json:"user_quota_mb"`
+}
+
+var fileSetting = FileSetting{
+	Enabled:       false,
+	StorageType:   FileStorageLocal,
+	LocalPath:     "data/files",
+	S3Region:      "us-east-1",
+	MaxFileSizeMB: 100,
+	UserQuotaMB:   1024,
+}
+
+func init() {
+	config.GlobalConfig.Register("file_setting", &fileSetting)
+}
+
+func GetFileSetting() *FileSetting {
+	return &fileSetting
+}
@@ -1,6 +1,10 @@
 package operation_setting
 
-import "github.com/QuantumNous/new-api/setting/config"
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
 
 // 额度展示类型
 const (
@@ -14,22 +18,40 @@ type GeneralSetting struct {
 	DocsLink            string `json:"docs_link"`
 	PingIntervalEnabled bool   `json:"ping_interval_enabled"`
 	PingIntervalSeconds int    `json:"ping_interval_seconds"`
+	// 上游持续无数据超过该时长（秒）后停止发送心跳，交由 STREAMING_TIMEOUT 或中间代理断开连接；0 表示不限制
+	PingMaxSilentSeconds int `json:"ping_max_silent_seconds"`
+	// 非流式请求在上游超过一个 Ping 间隔仍未响应时，提前返回 200 并周期写入空白字符保活（JSON 允许前导空白），
+	// 此后的上游错误将无法再以 HTTP 状态码体现
+	NonStreamKeepAliveEnabled bool `json:"non_stream_keep_alive_enabled"`
+	// 中继接口按 Accept-Encoding 压缩非流式响应，响应体小于阈值（字节）时不压缩
+	ResponseCompressionEnabled  bool `json:"response_compression_enabled"`
+	ResponseCompressionMinBytes int  `json:"response_compression_min_bytes"`
 	// 当前站点额度展示类型：USD / CNY / TOKENS
 	QuotaDisplayType string `json:"quota_display_type"`
 	// 自定义货币符号，用于 CUSTOM 展示类型
 	CustomCurrencySymbol string `json:"custom_currency_symbol"`
 	// 自定义货币与美元汇率（1 USD = X Custom）
 	CustomCurrencyExchangeRate float64 `json:"custom_currency_exchange_rate"`
+	// StrictOpenAIErrors 将中继接口的错误规范化为 OpenAI 官方的状态码与错误对象
+	StrictOpenAIErrors bool `json:"strict_openai_errors"`
+	// ProviderErrorMappingEnabled 将各上游的限流、内容过滤、上下文超长与鉴权错误映射为一致的 OpenAI 错误码，原始错误保留在 metadata.provider_error 中
+	ProviderErrorMappingEnabled bool `json:"provider_error_mapping_enabled"`
 }
 
 // 默认配置
 var generalSetting = GeneralSetting{
-	DocsLink:                   "https://docs.newapi.pro",
-	PingIntervalEnabled:        false,
-	PingIntervalSeconds:        60,
-	QuotaDisplayType:           QuotaDisplayTypeUSD,
-	CustomCurrencySymbol:       "¤",
-	CustomCurrencyExchangeRate: 1.0,
+	DocsLink:                    "https://docs.newapi.pro",
+	PingIntervalEnabled:         false,
+	PingIntervalSeconds:         60,
+	PingMaxSilentSeconds:        0,
+	NonStreamKeepAliveEnabled:   false,
+	ResponseCompressionEnabled:  false,
+	ResponseCompressionMinBytes: 1024,
+	QuotaDisplayType:            QuotaDisplayTypeUSD,
+	CustomCurrencySymbol:        "¤",
+	CustomCurrencyExchangeRate:  1.0,
+	StrictOpenAIErrors:          false,
+	ProviderErrorMappingEnabled: false,
 }
 
 func init() {
@@ -41,6 +63,14 @@ func GetGeneralSetting() *GeneralSetting {
 	return &generalSetting
 }
 
+// PingMaxSilentDuration 上游无数据时持续发送心跳的最长时间，0 表示不限制
+func (s *GeneralSetting) PingMaxSilentDuration() time.Duration {
+	if s.PingMaxSilentSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(s.PingMaxSilentSeconds) * time.Second
+}
+
 // IsCurrencyDisplay 是否以货币形式展示（美元或人民币）
 func IsCurrencyDisplay() bool {
 	return generalSetting.QuotaDisplayType != QuotaDisplayTypeTokens
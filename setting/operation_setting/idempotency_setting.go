@@ -0,0 +1,29 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// IdempotencySetting 中继请求的 Idempotency-Key 支持：同一令牌在有效期内携带相同 Key 重试时直接返回首次结果，不再重复计费
+type IdempotencySetting struct {
+	Enabled bool `json:"enabled"`
+	// 结果保留时长（秒）
+	TTLSeconds int `json:"ttl_seconds"`
+	// 内存缓存（未启用 Redis 时）的最大条目数
+	MaxEntries int `json:"max_entries"`
+	// 可缓存的最大响应体字节数，超出时仅记录已处理状态，重试返回 409
+	MaxResponseBytes int `json:"max_response_bytes"`
+}
+
+var idempotencySetting = IdempotencySetting{
+	Enabled:          true,
+	TTLSeconds:       86400,
+	MaxEntries:       10000,
+	MaxResponseBytes: 1 << 20,
+}
+
+func init() {
+	config.GlobalConfig.Register("idempotency_setting", &idempotencySetting)
+}
+
+func GetIdempotencySetting() *IdempotencySetting {
+	return &idempotencySetting
+}
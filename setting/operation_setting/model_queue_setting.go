@@ -0,0 +1,56 @@
+package operation_setting
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ModelQueueRule 单个模型的排队规则
+type ModelQueueRule struct {
+	// 同时排队等待的最大请求数，超出后直接返回 429
+	MaxWaiting int `json:"max_waiting"`
+	// 排队等待的最长时间（秒），超时后返回 429
+	WaitSeconds int `json:"wait_seconds"`
+}
+
+// ModelQueueSetting 模型请求排队：所有渠道并发已满时请求进入有界队列等待名额，平滑短时流量高峰
+type ModelQueueSetting struct {
+	Enabled bool `json:"enabled"`
+	// 按模型名配置排队规则，"*" 作为未单独配置模型的默认规则
+	Models map[string]ModelQueueRule `json:"models"`
+}
+
+var modelQueueSetting = ModelQueueSetting{
+	Enabled: false,
+	Models: map[string]ModelQueueRule{
+		"*": {MaxWaiting: 100, WaitSeconds: 30},
+	},
+}
+
+func init() {
+	config.GlobalConfig.Register("model_queue_setting", &modelQueueSetting)
+}
+
+func GetModelQueueSetting() *ModelQueueSetting {
+	return &modelQueueSetting
+}
+
+// GetRule 返回模型的排队规则，未启用或未配置时返回 false
+func (s *ModelQueueSetting) GetRule(modelName string) (ModelQueueRule, bool) {
+	if !s.Enabled {
+		return ModelQueueRule{}, false
+	}
+	rule, ok := s.Models[modelName]
+	if !ok {
+		rule, ok = s.Models["*"]
+	}
+	if !ok || rule.MaxWaiting <= 0 || rule.WaitSeconds <= 0 {
+		return ModelQueueRule{}, false
+	}
+	return rule, true
+}
+
+func (r ModelQueueRule) WaitTimeout() time.Duration {
+	return time.Duration(r.WaitSeconds) * time.Second
+}
@@ -0,0 +1,56 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// MultiKeyCooldownSetting 多 Key 渠道的单 Key 冷却：命中指定状态码时让该 Key 暂停一段时间而不是禁用，
+// 冷却期间轮询跳过该 Key；连续冷却时时长翻倍，不超过上限
+type MultiKeyCooldownSetting struct {
+	Enabled bool `json:"enabled"`
+	// 触发冷却的上游状态码，默认 401、429
+	StatusCodes []int `json:"status_codes"`
+	// 首次冷却时长（秒）
+	CooldownSeconds int `json:"cooldown_seconds"`
+	// 连续冷却的最长时长（秒）
+	MaxCooldownSeconds int `json:"max_cooldown_seconds"`
+}
+
+var multiKeyCooldownSetting = MultiKeyCooldownSetting{
+	Enabled:            false,
+	StatusCodes:        []int{401, 429},
+	CooldownSeconds:    60,
+	MaxCooldownSeconds: 1800,
+}
+
+func init() {
+	config.GlobalConfig.Register("multi_key_cooldown_setting", &multiKeyCooldownSetting)
+}
+
+func GetMultiKeyCooldownSetting() *MultiKeyCooldownSetting {
+	return &multiKeyCooldownSetting
+}
+
+// ShouldCooldown 判断状态码是否触发单 Key 冷却
+func (s *MultiKeyCooldownSetting) ShouldCooldown(statusCode int) bool {
+	return s.Enabled && slices.Contains(s.StatusCodes, statusCode)
+}
+
+// CooldownSecondsFor 第 strikes 次连续冷却的时长（秒），strikes 从 1 开始
+func (s *MultiKeyCooldownSetting) CooldownSecondsFor(strikes int) int64 {
+	base := int64(s.CooldownSeconds)
+	if base <= 0 {
+		base = 60
+	}
+	maxSeconds := int64(s.MaxCooldownSeconds)
+	if maxSeconds < base {
+		maxSeconds = base
+	}
+	seconds := base
+	for i := 1; i < strikes && seconds < maxSeconds; i++ {
+		seconds *= 2
+	}
+	return min(seconds, maxSeconds)
+}
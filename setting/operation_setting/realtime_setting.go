@@ -0,0 +1,24 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// RealtimeSetting Realtime（WebSocket）会话限制
+type RealtimeSetting struct {
+	// 每个令牌同时保持的会话数上限，0 表示不限制
+	MaxSessionsPerToken int `json:"max_sessions_per_token"`
+	// 每个用户同时保持的会话数上限，0 表示不限制
+	MaxSessionsPerUser int `json:"max_sessions_per_user"`
+}
+
+var realtimeSetting = RealtimeSetting{
+	MaxSessionsPerToken: 0,
+	MaxSessionsPerUser:  0,
+}
+
+func init() {
+	config.GlobalConfig.Register("realtime_setting", &realtimeSetting)
+}
+
+func GetRealtimeSetting() *RealtimeSetting {
+	return &realtimeSetting
+}
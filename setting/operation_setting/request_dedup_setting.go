@@ -0,0 +1,57 @@
+package operation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	// RequestDedupBillingOnce 仅实际转发上游的请求计费，合并的请求不计费
+	RequestDedupBillingOnce = "once"
+	// RequestDedupBillingPerRequest 合并的请求按上游请求的实际消耗各自计费
+	RequestDedupBillingPerRequest = "per_request"
+)
+
+// RequestDedupSetting 并发相同请求合并：多个相同的非流式请求同时到达时只转发一个到上游，其余请求等待并共享其响应
+type RequestDedupSetting struct {
+	Enabled bool `json:"enabled"`
+	// 合并请求的计费方式：once 或 per_request
+	BillingPolicy string `json:"billing_policy"`
+	// 合并的请求等待上游响应的最长时间（秒），超时后按普通请求转发
+	WaitTimeoutSeconds int `json:"wait_timeout_seconds"`
+	// 可共享的最大响应体字节数
+	MaxResponseBytes int `json:"max_response_bytes"`
+	// SharedAcrossUsers 不同用户的相同请求也合并；默认仅合并同一用户的请求
+	SharedAcrossUsers bool `json:"shared_across_users"`
+	// Groups 仅对这些分组启用，为空时对所有分组启用
+	Groups []string `json:"groups,omitempty"`
+}
+
+var requestDedupSetting = RequestDedupSetting{
+	Enabled:            false,
+	BillingPolicy:      RequestDedupBillingOnce,
+	WaitTimeoutSeconds: 120,
+	MaxResponseBytes:   1 << 20,
+}
+
+func init() {
+	config.GlobalConfig.Register("request_dedup_setting", &requestDedupSetting)
+}
+
+func GetRequestDedupSetting() *RequestDedupSetting {
+	return &requestDedupSetting
+}
+
+func (s *RequestDedupSetting) BillPerRequest() bool {
+	return s.BillingPolicy == RequestDedupBillingPerRequest
+}
+
+func ValidateRequestDedupBillingPolicy(policy string) error {
+	switch policy {
+	case RequestDedupBillingOnce, RequestDedupBillingPerRequest:
+		return nil
+	default:
+		return fmt.Errorf("不支持的合并请求计费方式：%s", policy)
+	}
+}
@@ -0,0 +1,33 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// ResponseCacheSetting 确定性请求（temperature 为 0 的非流式对话请求）的响应缓存：相同请求在有效期内直接返回缓存结果，不转发上游、不计费
+type ResponseCacheSetting struct {
+	Enabled bool `json:"enabled"`
+	// 缓存有效期（秒）
+	TTLSeconds int `json:"ttl_seconds"`
+	// 内存缓存（未启用 Redis 时）的最大条目数
+	MaxEntries int `json:"max_entries"`
+	// 可缓存的最大响应体字节数
+	MaxResponseBytes int `json:"max_response_bytes"`
+	// SharedAcrossUsers 不同用户的相同请求共享缓存；默认按用户隔离，避免通过缓存命中推测他人的请求
+	SharedAcrossUsers bool `json:"shared_across_users"`
+	// Groups 仅对这些分组启用，为空时对所有分组启用
+	Groups []string `json:"groups,omitempty"`
+}
+
+var responseCacheSetting = ResponseCacheSetting{
+	Enabled:          false,
+	TTLSeconds:       3600,
+	MaxEntries:       10000,
+	MaxResponseBytes: 1 << 20,
+}
+
+func init() {
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
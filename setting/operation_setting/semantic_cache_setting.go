@@ -0,0 +1,50 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// SemanticCacheSetting 语义缓存：对请求提示词计算向量，与同一令牌下参数相同的历史请求比较余弦相似度，
+// 达到令牌配置的阈值时直接返回历史响应，不转发上游、不计费。适用于 FAQ 类重复提问的流量
+type SemanticCacheSetting struct {
+	Enabled bool `json:"enabled"`
+	// 向量存储：redis 使用 Redis Stack（RediSearch）的向量检索，多节点共享缓存，未启用 Redis 时语义缓存不生效；
+	// memory 仅保存在当前进程内，重启丢失且各节点互不共享，只用于开发与单节点测试
+	Backend string `json:"backend"`
+	// OpenAI 兼容的向量接口地址，如 https://api.openai.com/v1/embeddings
+	EmbeddingUrl    string `json:"embedding_url"`
+	EmbeddingApiKey string `json:"embedding_api_key"`
+	EmbeddingModel  string `json:"embedding_model"`
+	// 向量接口超时（秒）
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// 缓存有效期（秒）
+	TTLSeconds int `json:"ttl_seconds"`
+	// 每个令牌、模型保留的最大缓存条目数，超出时淘汰最早的条目
+	MaxEntriesPerScope int `json:"max_entries_per_scope"`
+	// 参与向量计算的最大提示词字符数，超出的请求不走语义缓存
+	MaxPromptChars int `json:"max_prompt_chars"`
+	// 可缓存的最大响应体字节数
+	MaxResponseBytes int `json:"max_response_bytes"`
+}
+
+const (
+	SemanticCacheBackendRedis  = "redis"
+	SemanticCacheBackendMemory = "memory"
+)
+
+var semanticCacheSetting = SemanticCacheSetting{
+	Enabled:            false,
+	Backend:            SemanticCacheBackendRedis,
+	EmbeddingModel:     "text-embedding-3-small",
+	TimeoutSeconds:     10,
+	TTLSeconds:         86400,
+	MaxEntriesPerScope: 1000,
+	MaxPromptChars:     8000,
+	MaxResponseBytes:   1 << 20,
+}
+
+func init() {
+	config.GlobalConfig.Register("semantic_cache_setting", &semanticCacheSetting)
+}
+
+func GetSemanticCacheSetting() *SemanticCacheSetting {
+	return &semanticCacheSetting
+}
@@ -0,0 +1,34 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+const (
+	StripeMeterValueTokens = "tokens"
+	StripeMeterValueQuota  = "quota"
+)
+
+// StripeMeteringSetting 按量后付费：主节点定期把已绑定 Stripe 客户的用户的消费上报为 Stripe Billing Meter 事件
+type StripeMeteringSetting struct {
+	Enabled bool `json:"enabled"`
+	// EventName 对应 Stripe Meter 的 event_name
+	EventName string `json:"event_name"`
+	// ValueType 上报的数值：tokens 为输入与输出 token 数之和，quota 为消耗额度
+	ValueType string `json:"value_type"`
+	// LogCursor 已上报的最大日志 ID，PendingLogId 为正在上报批次的结束日志 ID，均由主节点写入
+	LogCursor    int `json:"log_cursor"`
+	PendingLogId int `json:"pending_log_id"`
+}
+
+var stripeMeteringSetting = StripeMeteringSetting{
+	Enabled:   false,
+	EventName: "new_api_usage",
+	ValueType: StripeMeterValueTokens,
+}
+
+func init() {
+	config.GlobalConfig.Register("stripe_metering_setting", &stripeMeteringSetting)
+}
+
+func GetStripeMeteringSetting() *StripeMeteringSetting {
+	return &stripeMeteringSetting
+}
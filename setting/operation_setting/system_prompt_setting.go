@@ -0,0 +1,85 @@
+package operation_setting
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 系统提示词注入位置
+const (
+	SystemPromptPositionPrepend = "prepend"
+	SystemPromptPositionAppend  = "append"
+)
+
+// SystemPromptRule 管理员定义的系统提示词，例如合规声明；Groups 为空时对所有分组生效
+type SystemPromptRule struct {
+	Prompt   string   `json:"prompt"`
+	Position string   `json:"position"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// SystemPromptSetting 向 OpenAI 与 Claude 格式的对话请求注入系统提示词，在渠道的系统提示词之外生效
+type SystemPromptSetting struct {
+	Enabled bool               `json:"enabled"`
+	Rules   []SystemPromptRule `json:"rules"`
+	// ExcludeFromBilling 注入的提示词不计入用户的输入 token
+	ExcludeFromBilling bool `json:"exclude_from_billing"`
+}
+
+var systemPromptSetting = SystemPromptSetting{
+	Enabled:            false,
+	Rules:              []SystemPromptRule{},
+	ExcludeFromBilling: true,
+}
+
+func init() {
+	config.GlobalConfig.Register("system_prompt_setting", &systemPromptSetting)
+}
+
+func GetSystemPromptSetting() *SystemPromptSetting {
+	return &systemPromptSetting
+}
+
+// GetSystemPromptInjection 返回分组需要放在系统提示词前后的内容，多条规则按配置顺序以换行拼接
+func GetSystemPromptInjection(group string) (prepend string, appendPrompt string) {
+	if !systemPromptSetting.Enabled {
+		return "", ""
+	}
+	var prepends, appends []string
+	for _, rule := range systemPromptSetting.Rules {
+		if strings.TrimSpace(rule.Prompt) == "" {
+			continue
+		}
+		if len(rule.Groups) > 0 && !slices.Contains(rule.Groups, group) {
+			continue
+		}
+		if rule.Position == SystemPromptPositionAppend {
+			appends = append(appends, rule.Prompt)
+		} else {
+			prepends = append(prepends, rule.Prompt)
+		}
+	}
+	return strings.Join(prepends, "\n"), strings.Join(appends, "\n")
+}
+
+func ValidateSystemPromptRules(jsonStr string) error {
+	var rules []SystemPromptRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return fmt.Errorf("系统提示词配置格式错误: %v", err)
+	}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Prompt) == "" {
+			return fmt.Errorf("第 %d 条系统提示词不能为空", i+1)
+		}
+		switch rule.Position {
+		case "", SystemPromptPositionPrepend, SystemPromptPositionAppend:
+		default:
+			return fmt.Errorf("第 %d 条系统提示词的位置 %s 无效", i+1, rule.Position)
+		}
+	}
+	return nil
+}
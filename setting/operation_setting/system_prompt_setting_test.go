@@ -0,0 +1,39 @@
+package operation_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSystemPromptInjection(t *testing.T) {
+	original := systemPromptSetting
+	t.Cleanup(func() { systemPromptSetting = original })
+	systemPromptSetting = SystemPromptSetting{
+		Enabled: true,
+		Rules: []SystemPromptRule{
+			{Prompt: "Be safe."},
+			{Prompt: "Enterprise policy applies.", Groups: []string{"enterprise"}},
+			{Prompt: "Responses may be audited.", Position: SystemPromptPositionAppend, Groups: []string{"enterprise"}},
+		},
+	}
+
+	prepend, appendPrompt := GetSystemPromptInjection("default")
+	require.Equal(t, "Be safe.", prepend)
+	require.Empty(t, appendPrompt)
+
+	prepend, appendPrompt = GetSystemPromptInjection("enterprise")
+	require.Equal(t, "Be safe.\nEnterprise policy applies.", prepend)
+	require.Equal(t, "Responses may be audited.", appendPrompt)
+
+	systemPromptSetting.Enabled = false
+	prepend, appendPrompt = GetSystemPromptInjection("enterprise")
+	require.Empty(t, prepend)
+	require.Empty(t, appendPrompt)
+}
+
+func TestValidateSystemPromptRules(t *testing.T) {
+	require.NoError(t, ValidateSystemPromptRules(`[{"prompt":"hi","position":"append","groups":["vip"]}]`))
+	require.Error(t, ValidateSystemPromptRules(`[{"prompt":" "}]`))
+	require.Error(t, ValidateSystemPromptRules(`[{"prompt":"hi","position":"middle"}]`))
+}
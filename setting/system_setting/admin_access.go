@@ -0,0 +1,52 @@
+package system_setting
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// AdminAccessSetting restricts admin routes (/api with AdminAuth/RootAuth) and
+// admin logins to a set of client networks. Relay endpoints are not affected.
+type AdminAccessSetting struct {
+	Enabled    bool     `json:"enabled"`
+	AllowedIps []string `json:"allowed_ips"` // CIDR or single IP, e.g. 10.0.0.0/8, 192.168.1.10
+	// ClientIpHeader 仅当请求的直连地址位于 TrustedProxies 中时才会读取，
+	// 例如 X-Forwarded-For、X-Real-IP、CF-Connecting-IP
+	ClientIpHeader string   `json:"client_ip_header"`
+	TrustedProxies []string `json:"trusted_proxies"` // CIDR or single IP
+}
+
+var defaultAdminAccessSetting = AdminAccessSetting{
+	Enabled:        false,
+	AllowedIps:     []string{},
+	ClientIpHeader: "",
+	TrustedProxies: []string{},
+}
+
+func init() {
+	config.GlobalConfig.Register("admin_access_setting", &defaultAdminAccessSetting)
+}
+
+func GetAdminAccessSetting() *AdminAccessSetting {
+	return &defaultAdminAccessSetting
+}
+
+// ValidateIpList 校验 JSON 数组形式的 IP/CIDR 列表
+func ValidateIpList(jsonStr string) error {
+	var list []string
+	if err := common.UnmarshalJsonStr(jsonStr, &list); err != nil {
+		return fmt.Errorf("IP 列表格式错误: %v", err)
+	}
+	for _, item := range list {
+		if _, _, err := net.ParseCIDR(item); err == nil {
+			continue
+		}
+		if net.ParseIP(item) == nil {
+			return fmt.Errorf("无效的 IP 或 CIDR: %s", item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package system_setting
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// EventWebhookEndpoint 事件 webhook 订阅端点
+type EventWebhookEndpoint struct {
+	Name    string   `json:"name"`
+	Url     string   `json:"url"`
+	Secret  string   `json:"secret"` // 用于 HMAC-SHA256 签名，留空则不签名
+	Events  []string `json:"events"` // 订阅的事件，为空表示订阅全部事件
+	Enabled bool     `json:"enabled"`
+}
+
+// EventWebhookSetting 网关生命周期事件 webhook 配置
+type EventWebhookSetting struct {
+	Enabled    bool                   `json:"enabled"`
+	Endpoints  []EventWebhookEndpoint `json:"endpoints"`
+	MaxRetries int                    `json:"max_retries"` // 投递失败后的最大重试次数
+	// LargeRequestTokenThreshold 预估提示词 token 数达到该值的请求失败时触发 request.large_failed 事件
+	LargeRequestTokenThreshold int `json:"large_request_token_threshold"`
+}
+
+var eventWebhookSetting = EventWebhookSetting{
+	Enabled:                    false,
+	Endpoints:                  []EventWebhookEndpoint{},
+	MaxRetries:                 3,
+	LargeRequestTokenThreshold: 32000,
+}
+
+func init() {
+	config.GlobalConfig.Register("event_webhook_setting", &eventWebhookSetting)
+}
+
+func GetEventWebhookSetting() *EventWebhookSetting {
+	return &eventWebhookSetting
+}
+
+// ValidateEventWebhookEndpoints 校验 JSON 数组形式的端点配置
+func ValidateEventWebhookEndpoints(jsonStr string) error {
+	var endpoints []EventWebhookEndpoint
+	if err := common.UnmarshalJsonStr(jsonStr, &endpoints); err != nil {
+		return fmt.Errorf("webhook 端点格式错误: %v", err)
+	}
+	for _, endpoint := range endpoints {
+		if err := ValidateEventWebhookUrl(endpoint.Url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ValidateEventWebhookUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的 webhook 地址: %s", rawUrl)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package system_setting
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// MCPUpstreamServer 注册到网关的上游 MCP 服务器（Streamable HTTP），其工具以 "名称__工具名" 暴露
+type MCPUpstreamServer struct {
+	Name    string            `json:"name"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"` // 访问上游时附加的请求头，如 Authorization
+	Enabled bool              `json:"enabled"`
+}
+
+// MCPSetting MCP 端点配置
+type MCPSetting struct {
+	Enabled bool `json:"enabled"`
+	// AdminToolsEnabled 是否开放管理类 MCP 端点 /api/mcp，该端点要求管理员登录会话或访问令牌，API 令牌无法使用
+	AdminToolsEnabled bool                `json:"admin_tools_enabled"`
+	UpstreamServers   []MCPUpstreamServer `json:"upstream_servers"`
+	// MaxToolRounds chat 工具使用上游 MCP 工具时，模型与工具交互的最大轮数
+	MaxToolRounds int `json:"max_tool_rounds"`
+	// ToolCacheSeconds 上游工具列表的缓存时间
+	ToolCacheSeconds int `json:"tool_cache_seconds"`
+}
+
+var mcpSetting = MCPSetting{
+	Enabled:           false,
+	AdminToolsEnabled: false,
+	UpstreamServers:   []MCPUpstreamServer{},
+	MaxToolRounds:     5,
+	ToolCacheSeconds:  300,
+}
+
+var mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+func init() {
+	config.GlobalConfig.Register("mcp_setting", &mcpSetting)
+}
+
+func GetMCPSetting() *MCPSetting {
+	return &mcpSetting
+}
+
+// GetMCPUpstreamServer 按名称查找已启用的上游 MCP 服务器
+func GetMCPUpstreamServer(name string) (MCPUpstreamServer, bool) {
+	for _, server := range mcpSetting.UpstreamServers {
+		if server.Enabled && server.Name == name {
+			return server, true
+		}
+	}
+	return MCPUpstreamServer{}, false
+}
+
+// ValidateMCPUpstreamServers 校验 JSON 数组形式的上游服务器配置
+func ValidateMCPUpstreamServers(jsonStr string) error {
+	var servers []MCPUpstreamServer
+	if err := common.UnmarshalJsonStr(jsonStr, &servers); err != nil {
+		return fmt.Errorf("MCP 服务器配置格式错误: %v", err)
+	}
+	names := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		if !mcpServerNamePattern.MatchString(server.Name) {
+			return fmt.Errorf("MCP 服务器名称只能包含字母、数字、下划线与连字符，且不超过 32 个字符: %s", server.Name)
+		}
+		if names[server.Name] {
+			return fmt.Errorf("MCP 服务器名称重复: %s", server.Name)
+		}
+		names[server.Name] = true
+		u, err := url.Parse(server.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("无效的 MCP 服务器地址: %s", server.Url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// NotifyTemplate 通知模板，Subject 与 Content 均为 Go text/template 语法
+type NotifyTemplate struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+type NotifyTemplateSetting struct {
+	// Templates 管理员自定义的模板覆盖：模板名 -> 语言 -> 模板
+	Templates map[string]map[string]NotifyTemplate `json:"templates"`
+}
+
+var notifyTemplateSetting = NotifyTemplateSetting{
+	Templates: map[string]map[string]NotifyTemplate{},
+}
+
+func init() {
+	config.GlobalConfig.Register("notify_template_setting", &notifyTemplateSetting)
+}
+
+func GetNotifyTemplateSetting() *NotifyTemplateSetting {
+	return &notifyTemplateSetting
+}
+
+// GetCustomNotifyTemplate 返回管理员为指定模板与语言配置的覆盖
+func GetCustomNotifyTemplate(name string, locale string) (NotifyTemplate, bool) {
+	locales, ok := notifyTemplateSetting.Templates[name]
+	if !ok {
+		return NotifyTemplate{}, false
+	}
+	tpl, ok := locales[locale]
+	return tpl, ok
+}
+
+// CloneNotifyTemplates 返回自定义模板的副本，便于修改后整体保存
+func CloneNotifyTemplates() map[string]map[string]NotifyTemplate {
+	cloned := make(map[string]map[string]NotifyTemplate, len(notifyTemplateSetting.Templates))
+	for name, locales := range notifyTemplateSetting.Templates {
+		inner := make(map[string]NotifyTemplate, len(locales))
+		for locale, tpl := range locales {
+			inner[locale] = tpl
+		}
+		cloned[name] = inner
+	}
+	return cloned
+}
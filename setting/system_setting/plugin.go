@@ -0,0 +1,93 @@
+package system_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 插件执行阶段
+const (
+	PluginStageRequest  = "request"  // 渠道选择之前，可改写请求或拒绝
+	PluginStageResponse = "response" // 非流式响应写出之前，可改写响应
+)
+
+// Plugin 运营方配置的 Starlark 脚本插件，按列表顺序执行。
+// 脚本需定义 handle(ctx) 函数，在沙箱中运行：不能加载模块、访问文件或网络，执行步数与时长受限
+type Plugin struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Stage   string `json:"stage"`
+	Script  string `json:"script"`
+	// FailClosed 脚本执行出错（包括超时、超出步数）时拒绝请求，用于自定义鉴权类插件；默认跳过该插件
+	FailClosed bool `json:"fail_closed"`
+	// Groups 仅对这些分组生效，为空时对所有分组生效
+	Groups []string `json:"groups,omitempty"`
+	// ChannelIds 仅对这些渠道生效，为空时对所有渠道生效；限定渠道的请求阶段插件在渠道选择之后执行，不能改写模型
+	ChannelIds []int `json:"channel_ids,omitempty"`
+}
+
+// ChannelScoped 插件是否限定了渠道
+func (p Plugin) ChannelScoped() bool {
+	return len(p.ChannelIds) > 0
+}
+
+// InScope 插件是否作用于该分组与渠道，channelId 为 0 表示尚未选择渠道，此时只检查分组
+func (p Plugin) InScope(group string, channelId int) bool {
+	if len(p.Groups) > 0 && !slices.Contains(p.Groups, group) {
+		return false
+	}
+	if channelId != 0 && len(p.ChannelIds) > 0 && !slices.Contains(p.ChannelIds, channelId) {
+		return false
+	}
+	return true
+}
+
+type PluginSetting struct {
+	Enabled bool `json:"enabled"`
+	// MaxSteps 单个插件单次执行的最大计算步数
+	MaxSteps uint64 `json:"max_steps"`
+	// TimeoutMs 单个插件单次执行的最长时间
+	TimeoutMs int      `json:"timeout_ms"`
+	Plugins   []Plugin `json:"plugins"`
+}
+
+var pluginSetting = PluginSetting{
+	Enabled:   false,
+	MaxSteps:  1000000,
+	TimeoutMs: 100,
+	Plugins:   []Plugin{},
+}
+
+func init() {
+	config.GlobalConfig.Register("plugin_setting", &pluginSetting)
+}
+
+func GetPluginSetting() *PluginSetting {
+	return &pluginSetting
+}
+
+// GetActivePlugins 返回指定阶段已启用的插件，未启用插件系统时返回空
+func GetActivePlugins(stage string) []Plugin {
+	if !pluginSetting.Enabled {
+		return nil
+	}
+	var plugins []Plugin
+	for _, plugin := range pluginSetting.Plugins {
+		if plugin.Enabled && plugin.Stage == stage {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+// FilterPlugins 按作用范围过滤插件；channelScoped 为 true 时只保留限定渠道的插件，否则只保留未限定渠道的插件
+func FilterPlugins(plugins []Plugin, group string, channelId int, channelScoped bool) []Plugin {
+	var filtered []Plugin
+	for _, plugin := range plugins {
+		if plugin.ChannelScoped() == channelScoped && plugin.InScope(group, channelId) {
+			filtered = append(filtered, plugin)
+		}
+	}
+	return filtered
+}
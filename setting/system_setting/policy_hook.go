@@ -0,0 +1,59 @@
+package system_setting
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// PolicyHookSetting 外部策略决策点：转发前将请求元数据发送到策略服务（如 OPA 或自建 webhook），
+// 由其返回 allow / deny / modify 决策
+type PolicyHookSetting struct {
+	Enabled bool   `json:"enabled"`
+	Url     string `json:"url"`
+	// AuthHeader 非空时作为 Authorization 请求头发送
+	AuthHeader string `json:"auth_header"`
+	TimeoutMs  int    `json:"timeout_ms"`
+	// FailOpen 策略服务不可用时是否放行
+	FailOpen bool `json:"fail_open"`
+	// OpaFormat 使用 OPA Data API 格式：请求包装为 {"input": ...}，响应从 result 字段读取
+	OpaFormat bool `json:"opa_format"`
+	// Groups 生效的用户分组，为空表示全部分组
+	Groups []string `json:"groups"`
+}
+
+var policyHookSetting = PolicyHookSetting{
+	Enabled:   false,
+	TimeoutMs: 2000,
+	FailOpen:  true,
+	Groups:    []string{},
+}
+
+func init() {
+	config.GlobalConfig.Register("policy_hook_setting", &policyHookSetting)
+}
+
+func GetPolicyHookSetting() *PolicyHookSetting {
+	return &policyHookSetting
+}
+
+// ShouldCallPolicyHook 判断分组的请求是否需要经过外部策略决策
+func ShouldCallPolicyHook(group string) bool {
+	if !policyHookSetting.Enabled || policyHookSetting.Url == "" {
+		return false
+	}
+	return len(policyHookSetting.Groups) == 0 || common.StringsContains(policyHookSetting.Groups, group)
+}
+
+func ValidatePolicyHookUrl(rawUrl string) error {
+	if rawUrl == "" {
+		return nil
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的策略服务地址: %s", rawUrl)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package system_setting
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// Tenant 白标租户：通过自定义域名访问时使用独立的品牌信息、默认分组与可用模型
+type Tenant struct {
+	Name string `json:"name"`
+	// Hostnames 绑定的域名，支持 *.example.com 形式的通配
+	Hostnames    []string `json:"hostnames"`
+	Group        string   `json:"group"` // 通过该域名注册的新用户所属分组
+	SystemName   string   `json:"system_name"`
+	Logo         string   `json:"logo"`
+	Footer       string   `json:"footer"`
+	Announcement string   `json:"announcement"`
+	// AllowedModels 为空表示不限制
+	AllowedModels []string `json:"allowed_models"`
+}
+
+type TenantSetting struct {
+	Enabled bool     `json:"enabled"`
+	Tenants []Tenant `json:"tenants"`
+}
+
+var tenantSetting = TenantSetting{
+	Enabled: false,
+	Tenants: []Tenant{},
+}
+
+func init() {
+	config.GlobalConfig.Register("tenant_setting", &tenantSetting)
+}
+
+func GetTenantSetting() *TenantSetting {
+	return &tenantSetting
+}
+
+func tenantEnabled() bool {
+	return tenantSetting.Enabled && entitlement.IsEnabled(entitlement.FeatureMultiTenancy)
+}
+
+// GetTenantByHost 根据请求 Host（可带端口）匹配租户，精确匹配优先于通配。
+// Host 可由客户端任意指定，只能用于品牌展示与注册分组，访问控制请使用 GetTenantByGroup
+func GetTenantByHost(host string) *Tenant {
+	if host == "" || !tenantEnabled() {
+		return nil
+	}
+	host = normalizeTenantHost(host)
+	var wildcard *Tenant
+	for i := range tenantSetting.Tenants {
+		tenant := &tenantSetting.Tenants[i]
+		for _, pattern := range tenant.Hostnames {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if pattern == host {
+				return tenant
+			}
+			if wildcard == nil && strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+				wildcard = tenant
+			}
+		}
+	}
+	return wildcard
+}
+
+// GetTenantByGroup 按已认证用户的分组匹配租户，依次尝试传入的分组（如用户分组、令牌使用分组），
+// 返回第一个 Group 与之相同的租户
+func GetTenantByGroup(groups ...string) *Tenant {
+	if !tenantEnabled() {
+		return nil
+	}
+	for _, group := range groups {
+		if group == "" {
+			continue
+		}
+		for i := range tenantSetting.Tenants {
+			if tenantSetting.Tenants[i].Group == group {
+				return &tenantSetting.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// IsModelAllowed 判断租户是否允许使用指定模型
+func (t *Tenant) IsModelAllowed(modelName string) bool {
+	if t == nil || len(t.AllowedModels) == 0 {
+		return true
+	}
+	for _, m := range t.AllowedModels {
+		if m == modelName {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTenants 校验 JSON 数组形式的租户配置，域名不可重复绑定
+func ValidateTenants(jsonStr string) error {
+	var tenants []Tenant
+	if err := common.UnmarshalJsonStr(jsonStr, &tenants); err != nil {
+		return fmt.Errorf("租户配置格式错误: %v", err)
+	}
+	seen := make(map[string]string)
+	for _, tenant := range tenants {
+		if strings.TrimSpace(tenant.Name) == "" {
+			return fmt.Errorf("租户名称不能为空")
+		}
+		if len(tenant.Hostnames) == 0 {
+			return fmt.Errorf("租户 %s 未绑定域名", tenant.Name)
+		}
+		for _, hostname := range tenant.Hostnames {
+			hostname = strings.ToLower(strings.TrimSpace(hostname))
+			if hostname == "" || strings.ContainsAny(hostname, "/: ") {
+				return fmt.Errorf("租户 %s 的域名无效: %s", tenant.Name, hostname)
+			}
+			if owner, ok := seen[hostname]; ok {
+				return fmt.Errorf("域名 %s 同时绑定到租户 %s 和 %s", hostname, owner, tenant.Name)
+			}
+			seen[hostname] = tenant.Name
+		}
+	}
+	return nil
+}
+
+func normalizeTenantHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,68 @@
+package system_setting
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/pkg/entitlement"
+	"github.com/stretchr/testify/require"
+)
+
+type grantAllChecker struct{}
+
+func (grantAllChecker) Name() string                 { return "test" }
+func (grantAllChecker) Entitled(feature string) bool { return true }
+
+func TestGetTenantByHost(t *testing.T) {
+	entitlement.RegisterChecker(grantAllChecker{})
+	origin := tenantSetting
+	t.Cleanup(func() { tenantSetting = origin })
+
+	tenantSetting = TenantSetting{
+		Enabled: true,
+		Tenants: []Tenant{
+			{Name: "wildcard", Hostnames: []string{"*.example.com"}},
+			{Name: "exact", Hostnames: []string{"api.example.com"}, AllowedModels: []string{"gpt-4o"}},
+		},
+	}
+
+	require.Equal(t, "exact", GetTenantByHost("API.example.com:443").Name)
+	require.Equal(t, "wildcard", GetTenantByHost("foo.example.com").Name)
+	require.Nil(t, GetTenantByHost("example.org"))
+
+	exact := GetTenantByHost("api.example.com")
+	require.True(t, exact.IsModelAllowed("gpt-4o"))
+	require.False(t, exact.IsModelAllowed("claude-3-haiku"))
+	require.True(t, GetTenantByHost("foo.example.com").IsModelAllowed("claude-3-haiku"))
+
+	tenantSetting.Enabled = false
+	require.Nil(t, GetTenantByHost("api.example.com"))
+}
+
+func TestValidateTenants(t *testing.T) {
+	require.NoError(t, ValidateTenants(`[{"name":"a","hostnames":["a.example.com"]}]`))
+	require.Error(t, ValidateTenants(`[{"name":"a","hostnames":[]}]`))
+	require.Error(t, ValidateTenants(`[{"name":"a","hostnames":["a.example.com"]},{"name":"b","hostnames":["A.example.com"]}]`))
+	require.Error(t, ValidateTenants(`[{"name":"a","hostnames":["https://a.example.com"]}]`))
+}
+
+func TestGetTenantByGroup(t *testing.T) {
+	entitlement.RegisterChecker(grantAllChecker{})
+	origin := tenantSetting
+	t.Cleanup(func() { tenantSetting = origin })
+
+	tenantSetting = TenantSetting{
+		Enabled: true,
+		Tenants: []Tenant{
+			{Name: "acme", Hostnames: []string{"ai.acme.example"}, Group: "acme"},
+			{Name: "brand-only", Hostnames: []string{"ai.brand.example"}},
+		},
+	}
+
+	require.Equal(t, "acme", GetTenantByGroup("acme").Name)
+	require.Equal(t, "acme", GetTenantByGroup("default", "acme").Name)
+	require.Nil(t, GetTenantByGroup("default", ""))
+	require.Nil(t, GetTenantByGroup(""))
+
+	tenantSetting.Enabled = false
+	require.Nil(t, GetTenantByGroup("acme"))
+}
@@ -38,9 +38,13 @@ const (
 type ErrorCode string
 
 const (
-	ErrorCodeInvalidRequest         ErrorCode = "invalid_request"
-	ErrorCodeSensitiveWordsDetected ErrorCode = "sensitive_words_detected"
-	ErrorCodeViolationFeeGrokCSAM   ErrorCode = "violation_fee.grok.csam"
+	ErrorCodeInvalidRequest          ErrorCode = "invalid_request"
+	ErrorCodeSensitiveWordsDetected  ErrorCode = "sensitive_words_detected"
+	ErrorCodeModerationBlocked       ErrorCode = "moderation_blocked"
+	ErrorCodeBlocklistRejected       ErrorCode = "blocklist_rejected"
+	ErrorCodePromptInjectionDetected ErrorCode = "prompt_injection_detected"
+	ErrorCodeSecretLeakDetected      ErrorCode = "secret_leak_detected"
+	ErrorCodeViolationFeeGrokCSAM    ErrorCode = "violation_fee.grok.csam"
 
 	// new api error
 	ErrorCodeCountTokenFailed   ErrorCode = "count_token_failed"
@@ -59,11 +63,16 @@ const (
 	ErrorCodeChannelAwsClientError        ErrorCode = "channel:aws_client_error"
 	ErrorCodeChannelInvalidKey            ErrorCode = "channel:invalid_key"
 	ErrorCodeChannelResponseTimeExceeded  ErrorCode = "channel:response_time_exceeded"
+	ErrorCodeChannelConcurrencyLimited    ErrorCode = "channel:concurrency_limited"
 
 	// client request error
 	ErrorCodeReadRequestBodyFailed ErrorCode = "read_request_body_failed"
 	ErrorCodeConvertRequestFailed  ErrorCode = "convert_request_failed"
 	ErrorCodeAccessDenied          ErrorCode = "access_denied"
+	ErrorCodeIdempotencyKeyInUse   ErrorCode = "idempotency_key_in_use"
+	ErrorCodeIdempotencyKeyReused  ErrorCode = "idempotency_key_reused"
+	ErrorCodeModelQueueFull        ErrorCode = "model_queue_full"
+	ErrorCodeModelQueueTimeout     ErrorCode = "model_queue_timeout"
 
 	// request error
 	ErrorCodeBadRequestBody ErrorCode = "bad_request_body"
@@ -96,6 +105,7 @@ type NewAPIError struct {
 	errorCode      ErrorCode
 	StatusCode     int
 	Metadata       json.RawMessage
+	providerError  *ProviderErrorDetail
 }
 
 // Unwrap enables errors.Is / errors.As to work with NewAPIError by exposing the underlying error.
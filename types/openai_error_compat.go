@@ -0,0 +1,120 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// OpenAI 官方错误对象使用的 type 取值
+const (
+	OpenAIErrorTypeInvalidRequest    = "invalid_request_error"
+	OpenAIErrorTypeInsufficientQuota = "insufficient_quota"
+	OpenAIErrorTypeRequests          = "requests"
+	OpenAIErrorTypeTokens            = "tokens"
+	OpenAIErrorTypeServer            = "server_error"
+)
+
+// StrictOpenAIError 与 OpenAI 官方完全一致的错误对象，param 与 code 缺省时为 null
+type StrictOpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// openAICompatErrorCodes 网关错误码对应的 OpenAI 状态码、type 与 code，code 为空时输出 null
+var openAICompatErrorCodes = map[ErrorCode]struct {
+	status    int
+	errorType string
+	code      string
+}{
+	ErrorCodeInsufficientUserQuota:      {http.StatusTooManyRequests, OpenAIErrorTypeInsufficientQuota, "insufficient_quota"},
+	ErrorCodePreConsumeTokenQuotaFailed: {http.StatusTooManyRequests, OpenAIErrorTypeInsufficientQuota, "insufficient_quota"},
+	ErrorCodeModelNotFound:              {http.StatusNotFound, OpenAIErrorTypeInvalidRequest, "model_not_found"},
+	ErrorCodeSensitiveWordsDetected:     {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+	ErrorCodeModerationBlocked:          {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+	ErrorCodeBlocklistRejected:          {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+	ErrorCodePromptInjectionDetected:    {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+	ErrorCodeSecretLeakDetected:         {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+	ErrorCodePromptBlocked:              {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation"},
+}
+
+// knownOpenAIErrorTypes 上游返回的官方 type 保持不变
+var knownOpenAIErrorTypes = map[string]bool{
+	OpenAIErrorTypeInvalidRequest:    true,
+	OpenAIErrorTypeInsufficientQuota: true,
+	OpenAIErrorTypeRequests:          true,
+	OpenAIErrorTypeTokens:            true,
+	OpenAIErrorTypeServer:            true,
+}
+
+// NormalizeOpenAIError 将任意错误规范化为 OpenAI 官方的状态码与错误对象，使官方 SDK 的错误分类与重试逻辑按预期工作：
+// 额度不足为 429 insufficient_quota，鉴权失败为 401 invalid_api_key，限流为 429 rate_limit_exceeded，5xx 统一为 server_error
+func NormalizeOpenAIError(statusCode int, err OpenAIError) (int, StrictOpenAIError) {
+	result := StrictOpenAIError{Message: err.Message}
+	if err.Param != "" {
+		result.Param = &err.Param
+	}
+	code := ""
+	switch v := err.Code.(type) {
+	case nil:
+	case string:
+		code = v
+	case ErrorCode:
+		code = string(v)
+	default:
+		code = fmt.Sprintf("%v", v)
+	}
+
+	if mapped, ok := openAICompatErrorCodes[ErrorCode(code)]; ok {
+		result.Type = mapped.errorType
+		result.Code = &mapped.code
+		return mapped.status, result
+	}
+
+	if statusCode < 400 || statusCode > 599 {
+		statusCode = http.StatusInternalServerError
+	}
+	switch {
+	case statusCode >= 500:
+		// 网关内部错误码对客户端没有意义，与官方一致输出 null
+		result.Type = OpenAIErrorTypeServer
+		return statusCode, result
+	case statusCode == http.StatusUnauthorized:
+		result.Type = OpenAIErrorTypeInvalidRequest
+		result.Code = common.GetPointer("invalid_api_key")
+	case statusCode == http.StatusTooManyRequests:
+		result.Type = OpenAIErrorTypeRequests
+		if err.Type == OpenAIErrorTypeTokens || err.Type == OpenAIErrorTypeInsufficientQuota {
+			result.Type = err.Type
+		}
+		if result.Type == OpenAIErrorTypeInsufficientQuota {
+			result.Code = common.GetPointer("insufficient_quota")
+		} else {
+			result.Code = common.GetPointer("rate_limit_exceeded")
+		}
+	default:
+		result.Type = OpenAIErrorTypeInvalidRequest
+		if knownOpenAIErrorTypes[err.Type] && err.Type != OpenAIErrorTypeServer {
+			result.Type = err.Type
+		}
+		if code != "" {
+			result.Code = &code
+		}
+	}
+	return statusCode, result
+}
+
+// OpenAIShouldRetry 对应官方 x-should-retry 响应头：额度不足等无法通过重试恢复的错误返回 false
+func OpenAIShouldRetry(statusCode int, err StrictOpenAIError) bool {
+	if err.Type == OpenAIErrorTypeInsufficientQuota {
+		return false
+	}
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return statusCode >= 500
+}
@@ -6,6 +6,8 @@ type GroupRatioInfo struct {
 	GroupRatio        float64
 	GroupSpecialRatio float64
 	HasSpecialRatio   bool
+	// TimeMultiplier 命中的分时价格倍数，已乘入 GroupRatio，0 表示未命中
+	TimeMultiplier float64
 }
 
 type PriceData struct {
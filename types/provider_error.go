@@ -0,0 +1,183 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// ProviderErrorCategory 上游错误的归类，不同供应商的同类错误映射为一致的 OpenAI 错误码
+type ProviderErrorCategory string
+
+const (
+	ProviderErrorCategoryNone            ProviderErrorCategory = ""
+	ProviderErrorCategoryRateLimit       ProviderErrorCategory = "rate_limit"
+	ProviderErrorCategoryContentFilter   ProviderErrorCategory = "content_filter"
+	ProviderErrorCategoryContextOverflow ProviderErrorCategory = "context_length_exceeded"
+	ProviderErrorCategoryAuthentication  ProviderErrorCategory = "authentication"
+)
+
+// ProviderErrorDetail 上游返回的原始错误信息，映射后保留在错误对象的 metadata.provider_error 中
+type ProviderErrorDetail struct {
+	StatusCode int             `json:"status_code"`
+	Type       string          `json:"type,omitempty"`
+	Code       any             `json:"code,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+}
+
+// providerErrorMappings 各类上游错误对应的 OpenAI 状态码、type、code 与 param；
+// 上游鉴权失败属于渠道配置问题而非客户端令牌无效，按服务端错误返回，避免客户端误判自身密钥失效
+var providerErrorMappings = map[ProviderErrorCategory]struct {
+	status    int
+	errorType string
+	code      string
+	param     string
+}{
+	ProviderErrorCategoryRateLimit:       {http.StatusTooManyRequests, OpenAIErrorTypeRequests, "rate_limit_exceeded", ""},
+	ProviderErrorCategoryContentFilter:   {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "content_policy_violation", ""},
+	ProviderErrorCategoryContextOverflow: {http.StatusBadRequest, OpenAIErrorTypeInvalidRequest, "context_length_exceeded", "messages"},
+	ProviderErrorCategoryAuthentication:  {http.StatusInternalServerError, OpenAIErrorTypeServer, "upstream_authentication_failed", ""},
+}
+
+var (
+	contextOverflowCodes    = []string{"context_length_exceeded", "string_above_max_length", "request_too_large"}
+	contextOverflowMessages = []string{
+		"context length", "context_length", "maximum context", "context window", "prompt is too long",
+		"input is too long", "exceeds the maximum number of tokens", "input token count", "reduce the length",
+	}
+	contentFilterCodes    = []string{"content_filter", "content_policy_violation", "responsible_ai_policy_violation", "prompt_blocked", "safety"}
+	contentFilterMessages = []string{
+		"content filter", "content_filter", "content policy", "content_policy", "content management policy",
+		"safety system", "safety settings", "blocked due to safety", "usage policies",
+	}
+	rateLimitCodes = []string{"rate_limit_error", "rate_limit_exceeded", "resource_exhausted", "throttlingexception", "too_many_requests"}
+	authCodes      = []string{"authentication_error", "invalid_api_key", "unauthenticated", "permission_error", "permission_denied", "unrecognizedclientexception"}
+	authMessages   = []string{"api key not valid", "invalid api key", "incorrect api key", "invalid x-api-key", "invalid authentication"}
+)
+
+// NewProviderErrorDetail 根据上游状态码与解析出的错误对象记录原始错误信息
+func NewProviderErrorDetail(statusCode int, relayError any) *ProviderErrorDetail {
+	detail := &ProviderErrorDetail{StatusCode: statusCode}
+	switch v := relayError.(type) {
+	case OpenAIError:
+		detail.Type = v.Type
+		detail.Code = v.Code
+		detail.Message = common.MaskSensitiveInfo(v.Message)
+		detail.Metadata = v.Metadata
+	case ClaudeError:
+		detail.Type = v.Type
+		detail.Message = common.MaskSensitiveInfo(v.Message)
+	}
+	return detail
+}
+
+// Category 按错误码、type、状态码与错误信息归类上游错误，无法归类时返回空
+func (d *ProviderErrorDetail) Category() ProviderErrorCategory {
+	if d == nil {
+		return ProviderErrorCategoryNone
+	}
+	identifiers := []string{strings.ToLower(d.Type)}
+	if code := providerErrorCodeString(d.Code); code != "" {
+		identifiers = append(identifiers, strings.ToLower(code))
+	}
+	message := strings.ToLower(d.Message)
+
+	// 上游账户额度耗尽不是客户端可重试的限流，保持原样
+	if matchAny(identifiers, []string{OpenAIErrorTypeInsufficientQuota}) {
+		return ProviderErrorCategoryNone
+	}
+	switch {
+	case matchAny(identifiers, contextOverflowCodes) || containsAny(message, contextOverflowMessages):
+		return ProviderErrorCategoryContextOverflow
+	case matchAny(identifiers, contentFilterCodes) || containsAny(message, contentFilterMessages):
+		return ProviderErrorCategoryContentFilter
+	case d.StatusCode == http.StatusTooManyRequests || matchAny(identifiers, rateLimitCodes):
+		return ProviderErrorCategoryRateLimit
+	case d.StatusCode == http.StatusUnauthorized || matchAny(identifiers, authCodes) || containsAny(message, authMessages):
+		return ProviderErrorCategoryAuthentication
+	}
+	return ProviderErrorCategoryNone
+}
+
+// ErrOptionWithProviderError 标记错误来自上游响应，并记录原始错误信息供映射使用
+func ErrOptionWithProviderError(statusCode int) NewAPIErrorOptions {
+	return func(e *NewAPIError) {
+		e.providerError = NewProviderErrorDetail(statusCode, e.RelayError)
+	}
+}
+
+// GetProviderError 返回上游原始错误信息，非上游错误返回 nil
+func (e *NewAPIError) GetProviderError() *ProviderErrorDetail {
+	if e == nil {
+		return nil
+	}
+	return e.providerError
+}
+
+// MapProviderError 将可归类的上游错误改写为一致的 OpenAI 错误对象，原始错误信息放入 metadata.provider_error；
+// 仅在最终写出响应前调用，不影响渠道重试与自动禁用对原始状态码的判断。返回是否发生了映射
+func (e *NewAPIError) MapProviderError() bool {
+	if e == nil || e.providerError == nil {
+		return false
+	}
+	category := e.providerError.Category()
+	mapping, ok := providerErrorMappings[category]
+	if !ok {
+		return false
+	}
+	metadata, err := common.Marshal(map[string]any{
+		"category":       category,
+		"provider_error": e.providerError,
+	})
+	if err != nil {
+		return false
+	}
+	e.RelayError = OpenAIError{
+		Message:  e.Error(),
+		Type:     mapping.errorType,
+		Param:    mapping.param,
+		Code:     mapping.code,
+		Metadata: metadata,
+	}
+	e.errorType = ErrorTypeOpenAIError
+	e.errorCode = ErrorCode(mapping.code)
+	e.StatusCode = mapping.status
+	return true
+}
+
+func providerErrorCodeString(code any) string {
+	switch v := code.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case ErrorCode:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func matchAny(values []string, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}